@@ -78,14 +78,18 @@ COMMENT ON FUNCTION uuidv7_boundary (timestamptz) IS 'Generate a non-random uuid
 -- ============================================================================
 -- USERS TABLE
 -- ============================================================================
--- This table stores persistent user data synchronized with Auth0
--- Auth0 handles authentication, we store business-specific user information
+-- This table stores persistent user data. Identities from external providers
+-- such as Auth0 and GitHub are linked in user_identities.
 CREATE TABLE users (
   id UUID PRIMARY KEY DEFAULT uuidv7 (),
-  auth0_user_id VARCHAR(255) UNIQUE,
-  email VARCHAR(255) UNIQUE NOT NULL,
+  -- Deprecated: superseded by user_identities
+  -- Unique among active customers, see idx_users_auth0_user_id_active
+  auth0_user_id VARCHAR(255),
+  -- Unique among active customers, see idx_users_email_active
+  email VARCHAR(255) NOT NULL,
   email_verified BOOLEAN DEFAULT FALSE,
-  password_hash TEXT NOT NULL,
+  -- NULL for customers who only sign in through an identity provider
+  password_hash TEXT,
   total_purchases_amount INTEGER DEFAULT 0,
   total_purchases_count INTEGER DEFAULT 0,
   created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
@@ -93,6 +97,9 @@ CREATE TABLE users (
   last_login_at TIMESTAMP WITH TIME ZONE,
   -- Soft delete support (for GDPR compliance and data recovery)
   deleted_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
+  -- Set once the personal data of a deleted customer is anonymized, after
+  -- a grace period during which the account can still be restored
+  erased_at TIMESTAMP WITH TIME ZONE DEFAULT NULL,
   CONSTRAINT users_email_format CHECK (
     email ~* '^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$'
   )
@@ -163,6 +170,7 @@ CREATE TABLE orders (
       'processing',
       'completed',
       'failed',
+      'refunding',
       'refunded',
       'cancelled'
     )
@@ -196,6 +204,7 @@ CREATE TABLE order_items (
       'processing',
       'completed',
       'failed',
+      'refunding',
       'refunded',
       'cancelled'
     )
@@ -217,6 +226,391 @@ CREATE TABLE user_downloads (
   CONSTRAINT downloads_count_non_negative CHECK (download_count >= 0)
 );
 
+-- ============================================================================
+-- USER SESSIONS TABLE
+-- ============================================================================
+-- Login sessions. Only a SHA-256 hash of the session secret is stored, so a
+-- leak of this table does not leak usable session tokens.
+CREATE TABLE user_sessions (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  secret_hash BYTEA NOT NULL,
+  -- The previous secret stays valid for a short grace period after rotation
+  previous_secret_hash BYTEA,
+  rotated_at TIMESTAMP WITH TIME ZONE,
+  created_ip VARCHAR(45),
+  user_agent TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  -- Idle expiry, pushed back as the session is used
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  -- Hard expiry, after which the customer must sign in again
+  absolute_expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  authenticated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT user_sessions_secret_hash_length CHECK (octet_length(secret_hash) = 32),
+  CONSTRAINT user_sessions_expiry_bounded CHECK (expires_at <= absolute_expires_at)
+);
+
+-- ============================================================================
+-- USER IDENTITIES TABLE
+-- ============================================================================
+-- External identities linked to a customer, one per provider. The subject is
+-- the provider's stable ID of the customer, taken from a verified ID token or
+-- the provider's API, never from the client.
+CREATE TABLE user_identities (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  provider VARCHAR(32) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  email VARCHAR(255),
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_used_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT user_identities_provider_subject_unique UNIQUE (provider, subject),
+  CONSTRAINT user_identities_provider_valid CHECK (provider IN ('auth0', 'github'))
+);
+
+-- ============================================================================
+-- OAUTH FLOWS TABLE
+-- ============================================================================
+-- OAuth2 authorization code flows between the redirect to a provider and the
+-- callback. Rows are deleted when the flow completes or expires.
+CREATE TABLE oauth_flows (
+  state VARCHAR(64) PRIMARY KEY,
+  provider VARCHAR(32) NOT NULL,
+  code_verifier VARCHAR(128) NOT NULL,
+  nonce VARCHAR(64) NOT NULL,
+  -- Set when a signed-in customer links a new identity
+  link_user_id UUID REFERENCES users (id) ON DELETE CASCADE,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- ============================================================================
+-- USER TOKENS TABLE
+-- ============================================================================
+-- One-time tokens sent to customers by email, such as email verification and
+-- password reset links. Only a SHA-256 hash of the token secret is stored.
+-- Used tokens are kept until they expire, so that rate limits can count them.
+CREATE TABLE user_tokens (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  purpose VARCHAR(32) NOT NULL,
+  secret_hash BYTEA NOT NULL,
+  -- The address the token was sent to
+  email VARCHAR(255) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  used_at TIMESTAMP WITH TIME ZONE,
+  CONSTRAINT user_tokens_secret_hash_length CHECK (octet_length(secret_hash) = 32),
+  CONSTRAINT user_tokens_purpose_valid CHECK (purpose IN ('email_verification', 'password_reset', 'account_unlock', 'mfa_challenge'))
+);
+
+-- ============================================================================
+-- EMAIL OUTBOX TABLE
+-- ============================================================================
+-- Rendered transactional email waiting to be delivered. Emails are inserted
+-- in the same transaction as the change that caused them, and a dispatcher
+-- delivers them once that transaction commits. The body is cleared once an
+-- email is sent, since it may carry a secret link.
+CREATE TABLE email_outbox (
+  id UUID PRIMARY KEY,
+  recipient VARCHAR(255) NOT NULL,
+  template VARCHAR(32) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  text_body TEXT NOT NULL,
+  html_body TEXT NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  -- Pushed back while a dispatcher delivers the email, and after a failure
+  next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  sent_at TIMESTAMP WITH TIME ZONE,
+  CONSTRAINT email_outbox_status_valid CHECK (status IN ('pending', 'sent', 'dead')),
+  CONSTRAINT email_outbox_attempts_positive CHECK (attempts >= 0)
+);
+
+-- ============================================================================
+-- EVENT OUTBOX TABLE
+-- ============================================================================
+-- Domain events, such as an order being paid, waiting to be published to
+-- in-process subscribers. Events are inserted in the same transaction as the
+-- change they describe, and a relay publishes them once that transaction
+-- commits, at least once. Payloads carry IDs rather than personal data. The
+-- trace and span IDs link the handling of an event to the request that
+-- emitted it.
+CREATE TABLE event_outbox (
+  id UUID PRIMARY KEY,
+  type VARCHAR(64) NOT NULL,
+  aggregate_id VARCHAR(255) NOT NULL,
+  payload JSONB NOT NULL,
+  trace_id VARCHAR(32),
+  span_id VARCHAR(16),
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  -- Pushed back while a relay publishes the event, and after a failure
+  next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  published_at TIMESTAMP WITH TIME ZONE,
+  CONSTRAINT event_outbox_status_valid CHECK (status IN ('pending', 'published', 'dead')),
+  CONSTRAINT event_outbox_attempts_positive CHECK (attempts >= 0)
+);
+
+-- ============================================================================
+-- JOBS TABLE
+-- ============================================================================
+-- Deferred and scheduled work, such as reaping expired sessions. Runners
+-- claim due jobs with FOR UPDATE SKIP LOCKED and push run_at back while a
+-- job runs, so that a job whose runner died is run again. Failed jobs are
+-- retried with backoff, and given up on jobs are kept as dead letters. A key
+-- makes enqueueing idempotent, such as one job per run of a schedule.
+CREATE TABLE jobs (
+  id UUID PRIMARY KEY,
+  type VARCHAR(64) NOT NULL,
+  key VARCHAR(255),
+  payload JSONB NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  finished_at TIMESTAMP WITH TIME ZONE,
+  CONSTRAINT jobs_status_valid CHECK (status IN ('pending', 'succeeded', 'dead')),
+  CONSTRAINT jobs_attempts_positive CHECK (attempts >= 0)
+);
+
+-- ============================================================================
+-- JOB SCHEDULES TABLE
+-- ============================================================================
+-- When each recurring schedule is due next. Only the runner holding the
+-- scheduler advisory lock enqueues the jobs of due schedules.
+CREATE TABLE job_schedules (
+  name VARCHAR(64) PRIMARY KEY,
+  next_run_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- ============================================================================
+-- WEBHOOK ENDPOINTS TABLE
+-- ============================================================================
+-- URLs registered by admins to hear about domain events. Deliveries are
+-- signed with the secret of the endpoint, which is encrypted with the
+-- application key. Endpoints that keep failing are disabled.
+CREATE TABLE webhook_endpoints (
+  id UUID PRIMARY KEY,
+  url TEXT NOT NULL,
+  description TEXT NOT NULL DEFAULT '',
+  event_types VARCHAR(64) [] NOT NULL,
+  encrypted_secret BYTEA NOT NULL,
+  enabled BOOLEAN NOT NULL DEFAULT TRUE,
+  consecutive_failures INTEGER NOT NULL DEFAULT 0,
+  failing_since TIMESTAMP WITH TIME ZONE,
+  disabled_at TIMESTAMP WITH TIME ZONE,
+  disabled_reason TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT webhook_endpoints_url_https CHECK (url LIKE 'https://%'),
+  CONSTRAINT webhook_endpoints_event_types_not_empty CHECK (cardinality(event_types) > 0)
+);
+
+-- ============================================================================
+-- WEBHOOK DELIVERIES TABLE
+-- ============================================================================
+-- Events waiting to be delivered to an endpoint, or that were. Dispatchers
+-- claim due deliveries with FOR UPDATE SKIP LOCKED, like the other outboxes.
+-- An event is delivered to an endpoint once, even if the relay hands it over
+-- twice.
+CREATE TABLE webhook_deliveries (
+  id UUID PRIMARY KEY,
+  endpoint_id UUID NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+  event_id UUID NOT NULL,
+  event_type VARCHAR(64) NOT NULL,
+  payload JSONB NOT NULL,
+  status VARCHAR(16) NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_error TEXT,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  delivered_at TIMESTAMP WITH TIME ZONE,
+  CONSTRAINT webhook_deliveries_event_unique UNIQUE (endpoint_id, event_id),
+  CONSTRAINT webhook_deliveries_status_valid CHECK (status IN ('pending', 'delivered', 'dead')),
+  CONSTRAINT webhook_deliveries_attempts_positive CHECK (attempts >= 0)
+);
+
+-- ============================================================================
+-- WEBHOOK ATTEMPTS TABLE
+-- ============================================================================
+-- The request and response of every attempt at a delivery, so that admins
+-- can see why an endpoint fails. Response bodies are truncated.
+CREATE TABLE webhook_attempts (
+  id UUID PRIMARY KEY,
+  delivery_id UUID NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+  number INTEGER NOT NULL,
+  request_headers JSONB NOT NULL,
+  response_status INTEGER NOT NULL DEFAULT 0,
+  response_body TEXT NOT NULL DEFAULT '',
+  error TEXT NOT NULL DEFAULT '',
+  duration_ms INTEGER NOT NULL,
+  attempted_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  CONSTRAINT webhook_attempts_number_unique UNIQUE (delivery_id, number)
+);
+
+-- ============================================================================
+-- LOGIN THROTTLES TABLE
+-- ============================================================================
+-- Recent failed sign in attempts per account and per IP address, used to slow
+-- down and lock out password guessing. Keys are namespaced, such as
+-- 'account:<email>' and 'ip:<address>'. Accounts are keyed by email rather
+-- than by user, so that unknown addresses are throttled like known ones.
+CREATE TABLE login_throttles (
+  key VARCHAR(320) PRIMARY KEY,
+  failures INTEGER NOT NULL,
+  last_failure_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  locked_until TIMESTAMP WITH TIME ZONE,
+  -- When neither the failures nor the lockout count any longer
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  CONSTRAINT login_throttles_failures_positive CHECK (failures > 0)
+);
+
+-- ============================================================================
+-- USER MFA TABLE
+-- ============================================================================
+-- TOTP authenticators of customers who enabled multi-factor authentication.
+-- The secret is encrypted with AES-GCM under a key from configuration, bound
+-- to the user ID. An authenticator is pending until enabled_at is set.
+CREATE TABLE user_mfa (
+  user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+  encrypted_secret BYTEA NOT NULL,
+  -- The time step of the last accepted code, so that codes are not replayed
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  enabled_at TIMESTAMP WITH TIME ZONE
+);
+
+-- ============================================================================
+-- USER RECOVERY CODES TABLE
+-- ============================================================================
+-- One-time codes that stand in for a TOTP code when a customer loses their
+-- authenticator. Only a SHA-256 hash of each code is stored.
+CREATE TABLE user_recovery_codes (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  code_hash BYTEA NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  used_at TIMESTAMP WITH TIME ZONE,
+  CONSTRAINT user_recovery_codes_code_hash_length CHECK (octet_length(code_hash) = 32)
+);
+
+-- ============================================================================
+-- USER PASSKEYS TABLE
+-- ============================================================================
+-- WebAuthn credentials customers sign in with. A customer can have several.
+CREATE TABLE user_passkeys (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  credential_id BYTEA NOT NULL UNIQUE,
+  -- COSE encoded public key
+  public_key BYTEA NOT NULL,
+  algorithm INTEGER NOT NULL,
+  sign_count BIGINT NOT NULL DEFAULT 0,
+  backed_up BOOLEAN NOT NULL DEFAULT FALSE,
+  name VARCHAR(64) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_used_at TIMESTAMP WITH TIME ZONE,
+  CONSTRAINT user_passkeys_credential_id_length CHECK (octet_length(credential_id) BETWEEN 1 AND 1023),
+  CONSTRAINT user_passkeys_algorithm_check CHECK (algorithm IN (-7, -8))
+);
+
+-- ============================================================================
+-- PASSKEY CEREMONIES TABLE
+-- ============================================================================
+-- WebAuthn ceremonies between the options sent to the browser and its
+-- response. The user ID has no foreign key, since a sign up ceremony holds
+-- the ID of a customer who does not exist yet.
+CREATE TABLE passkey_ceremonies (
+  id VARCHAR(64) PRIMARY KEY,
+  purpose VARCHAR(32) NOT NULL CHECK (purpose IN ('registration', 'sign_up', 'sign_in')),
+  challenge BYTEA NOT NULL,
+  user_id UUID,
+  email VARCHAR(255),
+  created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- ============================================================================
+-- ROLES TABLE
+-- ============================================================================
+-- Roles customers can hold. Every customer implicitly has the customer role;
+-- the other roles are assigned to staff in user_roles.
+CREATE TABLE roles (
+  name VARCHAR(32) PRIMARY KEY,
+  description TEXT NOT NULL
+);
+
+-- ============================================================================
+-- PERMISSIONS TABLE
+-- ============================================================================
+-- Actions on kinds of resources, named '<resource>.<action>'.
+CREATE TABLE permissions (
+  name VARCHAR(64) PRIMARY KEY,
+  description TEXT NOT NULL
+);
+
+-- ============================================================================
+-- ROLE PERMISSIONS TABLE
+-- ============================================================================
+-- Grants of permissions to roles. A grant can be limited to resources the
+-- customer owns, and to amounts up to a limit in the smallest currency unit,
+-- such as the largest order a support agent may refund.
+CREATE TABLE role_permissions (
+  role VARCHAR(32) NOT NULL REFERENCES roles (name) ON DELETE CASCADE,
+  permission VARCHAR(64) NOT NULL REFERENCES permissions (name) ON DELETE CASCADE,
+  own_only BOOLEAN NOT NULL DEFAULT FALSE,
+  -- NULL when the grant has no limit
+  amount_limit INTEGER CHECK (amount_limit >= 0),
+  PRIMARY KEY (role, permission)
+);
+
+-- ============================================================================
+-- USER ROLES TABLE
+-- ============================================================================
+-- Roles assigned to customers, such as support agents and admins.
+CREATE TABLE user_roles (
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  role VARCHAR(32) NOT NULL REFERENCES roles (name) ON DELETE CASCADE CHECK (role <> 'customer'),
+  -- NULL when the role was assigned outside of the application
+  granted_by UUID REFERENCES users (id) ON DELETE SET NULL,
+  granted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, role)
+);
+
+-- ============================================================================
+-- AUDIT LOG TABLE
+-- ============================================================================
+-- Security relevant actions, such as sign ins and refunds. The table is
+-- append-only: every entry stores the SHA-256 hash of its content and of the
+-- previous entry, so that deleted or altered entries break the chain. IDs
+-- are not foreign keys, so that entries outlive the customers they name.
+CREATE TABLE audit_log (
+  sequence BIGINT PRIMARY KEY CHECK (sequence > 0),
+  action VARCHAR(64) NOT NULL,
+  outcome VARCHAR(16) NOT NULL,
+  -- The customer who performed the action, NULL when unknown
+  actor_id VARCHAR(64),
+  -- The customer the action concerns, NULL when unknown
+  customer_id VARCHAR(64),
+  -- What else the action concerns, such as an order, NULL when nothing
+  target_type VARCHAR(32),
+  target_id VARCHAR(64),
+  reason VARCHAR(64),
+  request_id VARCHAR(64),
+  trace_id VARCHAR(32),
+  occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+  -- Hex encoded, all zeros for the first entry
+  prev_hash CHAR(64) NOT NULL,
+  hash CHAR(64) NOT NULL UNIQUE
+);
+
 -- ============================================================================
 -- INDEXES FOR PERFORMANCE
 -- ============================================================================
@@ -226,8 +620,29 @@ CREATE INDEX idx_users_auth0_user_id ON users (auth0_user_id);
 
 CREATE INDEX idx_users_email ON users (email);
 
+-- Soft deleted rows do not count towards uniqueness, so that a customer can
+-- sign up again with the email address of their deleted account
+CREATE UNIQUE INDEX idx_users_email_active ON users (email)
+WHERE
+  deleted_at IS NULL;
+
+CREATE UNIQUE INDEX idx_users_auth0_user_id_active ON users (auth0_user_id)
+WHERE
+  deleted_at IS NULL;
+
+-- Deleted customers, for admins and purges
+CREATE INDEX idx_users_deleted_at ON users (deleted_at)
+WHERE
+  deleted_at IS NOT NULL;
+
 CREATE INDEX idx_users_created_at ON users (created_at);
 
+-- Deleted customers waiting to be erased
+CREATE INDEX idx_users_pending_erasure ON users (deleted_at)
+WHERE
+  deleted_at IS NOT NULL
+  AND erased_at IS NULL;
+
 -- Product search and browsing indexes
 CREATE INDEX idx_products_category ON products (category_id);
 
@@ -261,6 +676,81 @@ CREATE INDEX idx_user_downloads_product_id ON user_downloads (product_id);
 
 CREATE INDEX idx_user_downloads_order_id ON user_downloads (order_id);
 
+-- Session lookup per customer and expired session reaping
+CREATE INDEX idx_user_sessions_user_id ON user_sessions (user_id);
+
+CREATE INDEX idx_user_sessions_expires_at ON user_sessions (expires_at);
+
+CREATE INDEX idx_user_sessions_absolute_expires_at ON user_sessions (absolute_expires_at);
+
+-- Identity lookup per customer and expired OAuth flow reaping
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+
+CREATE INDEX idx_oauth_flows_expires_at ON oauth_flows (expires_at);
+
+-- One-time token rate limiting per customer and expired token reaping
+CREATE INDEX idx_user_tokens_user_id_purpose ON user_tokens (user_id, purpose, created_at);
+
+CREATE INDEX idx_user_tokens_expires_at ON user_tokens (expires_at);
+
+-- Due email lookup for the dispatcher and old email reaping
+CREATE INDEX idx_email_outbox_due ON email_outbox (next_attempt_at)
+WHERE
+  status = 'pending';
+
+CREATE INDEX idx_email_outbox_created_at ON email_outbox (created_at);
+
+-- Due event lookup for the relay and old event reaping
+CREATE INDEX idx_event_outbox_due ON event_outbox (next_attempt_at)
+WHERE
+  status = 'pending';
+
+CREATE INDEX idx_event_outbox_created_at ON event_outbox (created_at);
+
+-- Due job lookup for runners, idempotent enqueueing and finished job reaping
+CREATE INDEX idx_jobs_due ON jobs (run_at)
+WHERE
+  status = 'pending';
+
+CREATE UNIQUE INDEX idx_jobs_key ON jobs (key)
+WHERE
+  key IS NOT NULL;
+
+CREATE INDEX idx_jobs_finished_at ON jobs (finished_at)
+WHERE
+  status <> 'pending';
+
+-- Due webhook delivery lookup for dispatchers, delivery browsing per
+-- endpoint and old delivery reaping
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at)
+WHERE
+  status = 'pending';
+
+CREATE INDEX idx_webhook_deliveries_endpoint_id ON webhook_deliveries (endpoint_id, created_at DESC);
+
+CREATE INDEX idx_webhook_deliveries_created_at ON webhook_deliveries (created_at);
+
+-- Expired login throttle reaping
+CREATE INDEX idx_login_throttles_expires_at ON login_throttles (expires_at);
+
+-- Recovery code lookup per customer
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes (user_id, code_hash);
+
+-- Passkey listing per customer
+CREATE INDEX idx_user_passkeys_user_id ON user_passkeys (user_id);
+
+-- Expired passkey ceremony reaping
+CREATE INDEX idx_passkey_ceremonies_expires_at ON passkey_ceremonies (expires_at);
+
+-- Staff listing per role
+CREATE INDEX idx_user_roles_role ON user_roles (role);
+
+CREATE INDEX idx_audit_log_actor_id ON audit_log (actor_id, occurred_at DESC);
+
+CREATE INDEX idx_audit_log_customer_id ON audit_log (customer_id, occurred_at DESC);
+
+CREATE INDEX idx_audit_log_occurred_at ON audit_log (occurred_at DESC);
+
 -- ============================================================================
 -- FUNCTIONS AND TRIGGERS
 -- ============================================================================
@@ -295,6 +785,10 @@ CREATE TRIGGER update_order_items_updated_at BEFORE
 UPDATE ON order_items FOR EACH ROW
 EXECUTE FUNCTION update_updated_at_column ();
 
+CREATE TRIGGER update_webhook_endpoints_updated_at BEFORE
+UPDATE ON webhook_endpoints FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column ();
+
 -- Keep this order number generation in the application layer, lol.
 --
 -- Function to generate human-readable order numbers
@@ -330,6 +824,21 @@ AFTER
 UPDATE ON orders FOR EACH ROW
 EXECUTE FUNCTION update_user_purchase_stats ();
 
+-- Function to keep the audit log append-only
+CREATE OR REPLACE FUNCTION reject_audit_log_change () RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reject_audit_log_update_or_delete BEFORE
+UPDATE
+OR DELETE ON audit_log FOR EACH ROW
+EXECUTE FUNCTION reject_audit_log_change ();
+
+CREATE TRIGGER reject_audit_log_truncate BEFORE TRUNCATE ON audit_log FOR EACH STATEMENT
+EXECUTE FUNCTION reject_audit_log_change ();
+
 -- ============================================================================
 -- VIEWS FOR COMMON QUERIES
 -- ============================================================================
@@ -355,3 +864,63 @@ FROM
   JOIN order_items oi ON o.id = oi.order_id
 WHERE
   u.deleted_at IS NULL;
+
+-- ============================================================================
+-- AUTHORIZATION REFERENCE DATA
+-- ============================================================================
+-- Roles, permissions and their grants. Keep these in sync with
+-- domain.DefaultGrants.
+INSERT INTO
+  roles (name, description)
+VALUES
+  ('customer', 'Every customer'),
+  ('support', 'Support agents'),
+  ('admin', 'Administrators');
+
+INSERT INTO
+  permissions (name, description)
+VALUES
+  ('customers.view', 'View customer accounts'),
+  ('customers.update', 'Update customer accounts'),
+  ('customers.delete', 'Delete customer accounts'),
+  ('orders.view', 'View orders'),
+  ('orders.refund', 'Refund orders'),
+  ('roles.assign', 'Assign and revoke roles'),
+  ('backoffice.access', 'Use the back office API'),
+  ('customers.verify_email', 'Mark the email of customers as verified'),
+  ('customers.restore', 'Restore deleted customer accounts'),
+  ('customers.mfa_reset', 'Turn off the second factor of customers who lost it'),
+  ('orders.resend_receipt', 'Send the receipt of orders again'),
+  ('downloads.view', 'View download history'),
+  ('downloads.reset', 'Reset download counters'),
+  ('audit.view', 'View the audit log'),
+  ('webhooks.manage', 'Manage outgoing webhooks and their deliveries');
+
+INSERT INTO
+  role_permissions (role, permission, own_only, amount_limit)
+VALUES
+  ('customer', 'customers.view', TRUE, NULL),
+  ('customer', 'customers.update', TRUE, NULL),
+  ('customer', 'customers.delete', TRUE, NULL),
+  ('customer', 'orders.view', TRUE, NULL),
+  ('customer', 'downloads.view', TRUE, NULL),
+  ('support', 'backoffice.access', FALSE, NULL),
+  ('support', 'customers.view', FALSE, NULL),
+  ('support', 'customers.verify_email', FALSE, NULL),
+  ('support', 'customers.mfa_reset', FALSE, NULL),
+  ('support', 'orders.view', FALSE, NULL),
+  ('support', 'orders.refund', FALSE, 5000),
+  ('support', 'orders.resend_receipt', FALSE, NULL),
+  ('support', 'downloads.view', FALSE, NULL),
+  ('support', 'downloads.reset', FALSE, NULL);
+
+-- Admins are granted every permission.
+INSERT INTO
+  role_permissions (role, permission, own_only, amount_limit)
+SELECT
+  'admin',
+  name,
+  FALSE,
+  NULL
+FROM
+  permissions;
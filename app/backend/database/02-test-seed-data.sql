@@ -219,13 +219,13 @@ INSERT INTO
     created_at,
     last_login_at
   )
-VALUES -- Auth0 users (no password required, placeholder hash used)
+VALUES -- Auth0 users (no password)
   (
     '01947f3e-8b2a-7123-b456-323456789001',
     'auth0|64a7b8c9d0e1f2345678901a',
     'producer@islandbeats.com',
     TRUE,
-    'AUTH0_USER_NO_PASSWORD_HASH',
+    NULL,
     144.97,
     4,
     '2024-06-15 14:30:00-10:00',
@@ -236,7 +236,7 @@ VALUES -- Auth0 users (no password required, placeholder hash used)
     'auth0|64a7b8c9d0e1f2345678901b',
     'beats@honolulusound.net',
     TRUE,
-    'AUTH0_USER_NO_PASSWORD_HASH',
+    NULL,
     79.99,
     1,
     '2024-08-22 16:20:00-10:00',
@@ -282,13 +282,65 @@ VALUES -- Auth0 users (no password required, placeholder hash used)
     'auth0|64a7b8c9d0e1f2345678901f',
     'sounds@konabeats.io',
     FALSE,
-    'AUTH0_USER_NO_PASSWORD_HASH',
+    NULL,
     0.00,
     0,
     '2024-12-20 16:45:00-10:00',
     '2024-12-20 16:50:00-10:00'
   );
 
+-- ============================================================================
+-- USER IDENTITIES
+-- ============================================================================
+INSERT INTO
+  user_identities (
+    id,
+    user_id,
+    provider,
+    subject,
+    email,
+    created_at,
+    last_used_at
+  )
+VALUES
+  (
+    '01947f3e-8b2a-7123-b456-a23456789001',
+    '01947f3e-8b2a-7123-b456-323456789001',
+    'auth0',
+    'auth0|64a7b8c9d0e1f2345678901a',
+    'producer@islandbeats.com',
+    '2024-06-15 14:30:00-10:00',
+    '2024-12-20 09:45:00-10:00'
+  ),
+  (
+    '01947f3e-8b2a-7123-b456-a23456789002',
+    '01947f3e-8b2a-7123-b456-323456789002',
+    'auth0',
+    'auth0|64a7b8c9d0e1f2345678901b',
+    'beats@honolulusound.net',
+    '2024-08-22 16:20:00-10:00',
+    '2024-12-19 15:30:00-10:00'
+  ),
+  -- A customer with both a password and a linked GitHub account
+  (
+    '01947f3e-8b2a-7123-b456-a23456789003',
+    '01947f3e-8b2a-7123-b456-323456789005',
+    'github',
+    '5318008',
+    'studio@mauivibes.com',
+    '2024-11-06 09:00:00-10:00',
+    '2024-12-22 11:30:00-10:00'
+  ),
+  (
+    '01947f3e-8b2a-7123-b456-a23456789004',
+    '01947f3e-8b2a-7123-b456-323456789006',
+    'auth0',
+    'auth0|64a7b8c9d0e1f2345678901f',
+    'sounds@konabeats.io',
+    '2024-12-20 16:45:00-10:00',
+    '2024-12-20 16:50:00-10:00'
+  );
+
 -- ============================================================================
 -- ORDERS
 -- ============================================================================
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Command pwnedimport builds the offline pwned password dataset from the SHA-1
+// dump of the Have I Been Pwned Pwned Passwords list, ordered by hash. The
+// dump can be fetched with the official PwnedPasswordsDownloader:
+//
+//	haveibeenpwned-downloader pwnedpasswords
+//	pwnedimport -in pwnedpasswords.txt -out pwned.bin
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.brokedaear.com/internal/adapters/pwned"
+)
+
+func main() {
+	in := flag.String("in", "-", "path of the dump, or - for stdin")
+	out := flag.String("out", "pwned.bin", "path of the dataset to write")
+	suffixLength := flag.Int("suffix-bytes", 6, "bytes of every hash to keep after the 2 byte prefix")
+	minCount := flag.Int("min-count", 1, "leave out hashes seen fewer times than this")
+	flag.Parse()
+
+	err := run(*in, *out, pwned.ImportOptions{SuffixLength: *suffixLength, MinCount: *minCount})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pwnedimport:", err)
+		os.Exit(1)
+	}
+}
+
+// run imports the dump into a temporary file next to the dataset, and moves
+// it into place once it is complete, so that a running service never opens
+// half a dataset.
+func run(in, out string, opts pwned.ImportOptions) error {
+	var r io.Reader = os.Stdin
+	if in != "-" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(out), filepath.Base(out)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	stats, err := pwned.Import(r, tmp, opts)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	// Check the result before replacing the old dataset.
+	d, err := pwned.Open(tmp.Name())
+	if err != nil {
+		return err
+	}
+	_ = d.Close()
+
+	err = os.Rename(tmp.Name(), out)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("read %d hashes, wrote %d, skipped %d below the minimum count\n", stats.Read, stats.Written, stats.Skipped)
+	return nil
+}
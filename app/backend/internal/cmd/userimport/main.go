@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Command userimport loads customers migrated from an older store into the
+// users table. The input is a CSV file with the columns email, password_hash
+// and, optionally, email_verified, and an optional header row:
+//
+//	email,password_hash,email_verified
+//	ada@example.com,$2b$12$...,true
+//
+// Password hashes are stored without re-hashing, and must be of an algorithm
+// the backend can verify, such as bcrypt or scrypt. They are upgraded to
+// Argon2id the next time each customer signs in. Customers whose email is
+// already taken are skipped, so an import can be run again after a failure.
+//
+//	userimport -in legacy_users.csv -dsn postgres://localhost/brokedaear_shop
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/adapters/postgres"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+)
+
+func main() {
+	in := flag.String("in", "-", "path of the CSV file, or - for stdin")
+	dsn := flag.String("dsn", os.Getenv("DATABASE_URL"), "connection string of the database, defaults to $DATABASE_URL")
+	flag.Parse()
+
+	err := run(context.Background(), *in, *dsn)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "userimport:", err)
+		os.Exit(1)
+	}
+}
+
+// importer stores a customer whose password is already hashed.
+type importer interface {
+	ImportHashed(ctx context.Context, customer *domain.Customer) (bool, error)
+}
+
+// stats counts the outcome of every row of an import.
+type stats struct {
+	Imported int
+	Skipped  int
+	Invalid  int
+}
+
+func run(ctx context.Context, in, dsn string) error {
+	if dsn == "" {
+		return errors.New("no database given, set -dsn or DATABASE_URL")
+	}
+	var r io.Reader = os.Stdin
+	if in != "-" {
+		f, err := os.Open(in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return err
+	}
+	logger, err := loggers.NewZap(&loggers.ZapConfig{
+		Env:          domain.EnvDevelopment,
+		Telemetry:    nil,
+		CustomZapper: nil,
+	})
+	if err != nil {
+		return err
+	}
+	defer logger.Sync() //nolint:errcheck // Nothing left to do if this fails.
+	customers, err := postgres.NewCustomerRepository(ctx, cfg, logger, nil, crypto.DefaultArgon2Params())
+	if err != nil {
+		return err
+	}
+	defer customers.Close()
+
+	s, err := importCSV(ctx, r, customers)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("imported %d customers, skipped %d already present, rejected %d invalid rows\n", s.Imported, s.Skipped, s.Invalid)
+	return nil
+}
+
+// importCSV imports every row of a CSV file. Rows that cannot be imported,
+// such as rows with an unsupported hash, are reported and counted, while
+// database failures stop the import.
+func importCSV(ctx context.Context, r io.Reader, customers importer) (stats, error) {
+	var s stats
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	for line := 1; ; line++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return s, nil
+		}
+		if err != nil {
+			return s, err
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "email") {
+			continue
+		}
+
+		customer, err := parseRecord(record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "userimport: line %d: %v\n", line, err)
+			s.Invalid++
+			continue
+		}
+		inserted, err := customers.ImportHashed(ctx, customer)
+		switch {
+		case isInvalidHash(err):
+			fmt.Fprintf(os.Stderr, "userimport: line %d: %v\n", line, err)
+			s.Invalid++
+		case err != nil:
+			return s, errors.Wrapf(err, "line %d", line)
+		case inserted:
+			s.Imported++
+		default:
+			s.Skipped++
+		}
+	}
+}
+
+// parseRecord makes a customer out of a CSV row.
+func parseRecord(record []string) (*domain.Customer, error) {
+	if len(record) < 2 || len(record) > 3 {
+		return nil, errors.New("expected email, password_hash and optionally email_verified")
+	}
+	// Email addresses are kept as given, since signing in matches them
+	// exactly, like it does for customers who signed up.
+	email := strings.TrimSpace(record[0])
+	hash := strings.TrimSpace(record[1])
+	if email == "" || hash == "" {
+		return nil, errors.New("email and password_hash must not be empty")
+	}
+	verified := false
+	if len(record) == 3 && strings.TrimSpace(record[2]) != "" {
+		var err error
+		verified, err = strconv.ParseBool(strings.TrimSpace(record[2]))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid email_verified")
+		}
+	}
+	customer, err := domain.NewCustomer(email, "", []byte(hash))
+	if err != nil {
+		return nil, err
+	}
+	customer.EmailVerified = verified
+	return customer, nil
+}
+
+func isInvalidHash(err error) bool {
+	return errors.Is(err, crypto.ErrInvalidHash) ||
+		errors.Is(err, crypto.ErrUnsupportedHash) ||
+		errors.Is(err, crypto.ErrInvalidParams)
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+// bcryptHash is the bcrypt hash of "password".
+const bcryptHash = "$2a$04$8YbjLGk9.7XRwMKI3I2Dr.ItWkGUu/jZ0JpoQ6oFCh4eBqCcMvmVK"
+
+// fakeImporter mimics postgres.CustomerRepository.ImportHashed, including
+// checking hashes and skipping taken email addresses.
+type fakeImporter struct {
+	customers map[string]*domain.Customer
+	err       error
+}
+
+func (f *fakeImporter) ImportHashed(_ context.Context, customer *domain.Customer) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	err := crypto.CheckHash(string(customer.PasswordHash))
+	if err != nil {
+		return false, err
+	}
+	if _, ok := f.customers[customer.Email]; ok {
+		return false, nil
+	}
+	f.customers[customer.Email] = customer
+	return true, nil
+}
+
+func TestParseRecord(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		record       []string
+		wantEmail    string
+		wantVerified bool
+	}{
+		{
+			CaseBase:     test.NewCaseBase("verified", nil, false),
+			record:       []string{"ada@example.com", bcryptHash, "true"},
+			wantEmail:    "ada@example.com",
+			wantVerified: true,
+		},
+		{
+			CaseBase:     test.NewCaseBase("no email_verified", nil, false),
+			record:       []string{" ada@example.com ", bcryptHash},
+			wantEmail:    "ada@example.com",
+			wantVerified: false,
+		},
+		{
+			CaseBase:     test.NewCaseBase("mixed case kept", nil, false),
+			record:       []string{"Ada.Lovelace@Example.com", bcryptHash, ""},
+			wantEmail:    "Ada.Lovelace@Example.com",
+			wantVerified: false,
+		},
+		{
+			CaseBase:     test.NewCaseBase("too few columns", nil, true),
+			record:       []string{"ada@example.com"},
+			wantEmail:    "",
+			wantVerified: false,
+		},
+		{
+			CaseBase:     test.NewCaseBase("empty hash", nil, true),
+			record:       []string{"ada@example.com", " "},
+			wantEmail:    "",
+			wantVerified: false,
+		},
+		{
+			CaseBase:     test.NewCaseBase("invalid email_verified", nil, true),
+			record:       []string{"ada@example.com", bcryptHash, "maybe"},
+			wantEmail:    "",
+			wantVerified: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				customer, err := parseRecord(tt.record)
+				assert.ErrorAndWant(t, err, tt.WantErr)
+				if tt.WantErr {
+					return
+				}
+				assert.Equal(t, customer.Email, tt.wantEmail)
+				assert.Equal(t, customer.EmailVerified, tt.wantVerified)
+				assert.Equal(t, string(customer.PasswordHash), bcryptHash)
+			},
+		)
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	ctx := context.Background()
+	input := strings.Join([]string{
+		"email,password_hash,email_verified",
+		"ada@example.com," + bcryptHash + ",true",
+		"grace@example.com," + bcryptHash,
+		// A malformed row.
+		"alan@example.com",
+		// A duplicate email address.
+		"ada@example.com," + bcryptHash + ",false",
+		// An unsupported hash.
+		"linus@example.com,$md5$abcdef",
+	}, "\n")
+
+	importer := &fakeImporter{customers: make(map[string]*domain.Customer), err: nil}
+	s, err := importCSV(ctx, strings.NewReader(input), importer)
+	assert.NoError(t, err)
+	assert.Equal(t, s, stats{Imported: 2, Skipped: 1, Invalid: 2})
+	assert.True(t, importer.customers["ada@example.com"].EmailVerified)
+
+	// Running the import again skips everyone.
+	s, err = importCSV(ctx, strings.NewReader(input), importer)
+	assert.NoError(t, err)
+	assert.Equal(t, s, stats{Imported: 0, Skipped: 3, Invalid: 2})
+
+	// Database failures stop the import.
+	failure := errors.New("connection reset")
+	importer.err = failure
+	_, err = importCSV(ctx, strings.NewReader(input), importer)
+	assert.Error(t, err, failure)
+}
@@ -0,0 +1,708 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package grpcapi exposes the services of the backend over gRPC. Handlers
+// translate between Protobuf messages and the domain, and leave every
+// decision to the services.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/internal/core/server"
+	adminv1 "go.brokedaear.com/internal/core/server/proto/admin/v1"
+	"go.brokedaear.com/internal/core/service"
+	"go.brokedaear.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// backOffice is what AdminServer needs of service.AdminService.
+type backOffice interface {
+	SearchCustomers(ctx context.Context, principal *domain.Principal, query string) ([]*domain.Customer, error)
+	Customer(ctx context.Context, principal *domain.Principal, customerID string) (*domain.Customer, error)
+	Orders(ctx context.Context, principal *domain.Principal, customerID string) ([]*domain.Order, error)
+	Downloads(ctx context.Context, principal *domain.Principal, customerID string) ([]*domain.Download, error)
+	RefundOrder(ctx context.Context, principal *domain.Principal, orderID string) (*domain.Order, error)
+	ResendReceipt(ctx context.Context, principal *domain.Principal, orderID string) error
+	ResetDownloads(
+		ctx context.Context,
+		principal *domain.Principal,
+		customerID string,
+		downloadIDs ...string,
+	) ([]*domain.Download, error)
+	VerifyEmail(ctx context.Context, principal *domain.Principal, customerID string) (*domain.Customer, error)
+	DeleteCustomer(ctx context.Context, principal *domain.Principal, customerID string) error
+	RestoreCustomer(ctx context.Context, principal *domain.Principal, customerID string) (*domain.Customer, error)
+	ResetMFA(ctx context.Context, principal *domain.Principal, customerID string) error
+}
+
+// auditLog is what AdminServer needs of service.AuditLogService.
+type auditLog interface {
+	Query(ctx context.Context, principal *domain.Principal, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+}
+
+// dataExporter is what AdminServer needs of service.PrivacyService.
+type dataExporter interface {
+	Export(ctx context.Context, principal *domain.Principal, customerID string) (*domain.CustomerExport, error)
+}
+
+// webhookAdmin is what AdminServer needs of service.WebhookService.
+type webhookAdmin interface {
+	CreateEndpoint(
+		ctx context.Context,
+		principal *domain.Principal,
+		rawURL, description string,
+		eventTypes []domain.EventType,
+	) (*domain.WebhookEndpoint, string, error)
+	Endpoints(ctx context.Context, principal *domain.Principal) ([]*domain.WebhookEndpoint, error)
+	UpdateEndpoint(
+		ctx context.Context,
+		principal *domain.Principal,
+		id, rawURL, description string,
+		eventTypes []domain.EventType,
+		enabled bool,
+	) (*domain.WebhookEndpoint, error)
+	DeleteEndpoint(ctx context.Context, principal *domain.Principal, id string) error
+	RotateSecret(ctx context.Context, principal *domain.Principal, id string) (string, error)
+	Deliveries(
+		ctx context.Context,
+		principal *domain.Principal,
+		endpointID string,
+		limit int,
+	) ([]*domain.WebhookDelivery, error)
+	Delivery(
+		ctx context.Context,
+		principal *domain.Principal,
+		id string,
+	) (*domain.WebhookDelivery, []*domain.WebhookAttempt, error)
+	Redeliver(ctx context.Context, principal *domain.Principal, id string) (*domain.WebhookDelivery, error)
+}
+
+// AdminServer serves the back office of support staff over gRPC. It must be
+// registered on a server configured with server.WithAuthorization and the
+// rules of AdminAccessRules, so that every call carries a principal.
+type AdminServer struct {
+	adminv1.UnimplementedAdminServiceServer
+	logger   loggers.Logger
+	svc      backOffice
+	audit    auditLog
+	privacy  dataExporter
+	webhooks webhookAdmin
+}
+
+// NewAdminServer creates a new AdminServer, usually with a
+// service.AdminService, a service.AuditLogService, a service.PrivacyService
+// and a service.WebhookService.
+func NewAdminServer(
+	logger loggers.Logger,
+	svc backOffice,
+	audit auditLog,
+	privacy dataExporter,
+	webhooks webhookAdmin,
+) *AdminServer {
+	return &AdminServer{
+		UnimplementedAdminServiceServer: adminv1.UnimplementedAdminServiceServer{},
+		logger:                          logger,
+		svc:                             svc,
+		audit:                           audit,
+		privacy:                         privacy,
+		webhooks:                        webhooks,
+	}
+}
+
+// Register registers the admin service with a gRPC server.
+func (a *AdminServer) Register(srv server.GRPCServer) {
+	srv.RegisterService(&adminv1.AdminService_ServiceDesc, a)
+}
+
+// AdminAccessRules returns the access rules of the admin service. Only
+// principals with the back office permission may call it at all; each
+// method is then authorized by the service itself.
+func AdminAccessRules() server.AccessRules {
+	return server.AccessRules{
+		"/" + adminv1.AdminService_ServiceDesc.ServiceName + "/": {
+			Public:     false,
+			Permission: domain.PermissionBackOfficeAccess,
+		},
+	}
+}
+
+func (a *AdminServer) SearchCustomers(
+	ctx context.Context,
+	req *adminv1.SearchCustomersRequest,
+) (*adminv1.SearchCustomersResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customers, err := a.svc.SearchCustomers(ctx, principal, req.GetQuery())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	resp := &adminv1.SearchCustomersResponse{Customers: make([]*adminv1.Customer, len(customers))}
+	for i, c := range customers {
+		resp.Customers[i] = customerToProto(c)
+	}
+	return resp, nil
+}
+
+func (a *AdminServer) GetCustomer(
+	ctx context.Context,
+	req *adminv1.GetCustomerRequest,
+) (*adminv1.GetCustomerResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.svc.Customer(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.GetCustomerResponse{Customer: customerToProto(customer)}, nil
+}
+
+func (a *AdminServer) ListOrders(
+	ctx context.Context,
+	req *adminv1.ListOrdersRequest,
+) (*adminv1.ListOrdersResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := a.svc.Orders(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	resp := &adminv1.ListOrdersResponse{Orders: make([]*adminv1.Order, len(orders))}
+	for i, o := range orders {
+		resp.Orders[i] = orderToProto(o)
+	}
+	return resp, nil
+}
+
+func (a *AdminServer) ListDownloads(
+	ctx context.Context,
+	req *adminv1.ListDownloadsRequest,
+) (*adminv1.ListDownloadsResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := a.svc.Downloads(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.ListDownloadsResponse{Downloads: downloadsToProto(downloads)}, nil
+}
+
+func (a *AdminServer) RefundOrder(
+	ctx context.Context,
+	req *adminv1.RefundOrderRequest,
+) (*adminv1.RefundOrderResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	order, err := a.svc.RefundOrder(ctx, principal, req.GetOrderId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.RefundOrderResponse{Order: orderToProto(order)}, nil
+}
+
+func (a *AdminServer) ResendReceipt(
+	ctx context.Context,
+	req *adminv1.ResendReceiptRequest,
+) (*adminv1.ResendReceiptResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = a.svc.ResendReceipt(ctx, principal, req.GetOrderId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.ResendReceiptResponse{}, nil
+}
+
+func (a *AdminServer) ResetDownloads(
+	ctx context.Context,
+	req *adminv1.ResetDownloadsRequest,
+) (*adminv1.ResetDownloadsResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := a.svc.ResetDownloads(ctx, principal, req.GetCustomerId(), req.GetDownloadIds()...)
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.ResetDownloadsResponse{Downloads: downloadsToProto(downloads)}, nil
+}
+
+func (a *AdminServer) VerifyEmail(
+	ctx context.Context,
+	req *adminv1.VerifyEmailRequest,
+) (*adminv1.VerifyEmailResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.svc.VerifyEmail(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.VerifyEmailResponse{Customer: customerToProto(customer)}, nil
+}
+
+func (a *AdminServer) DeleteCustomer(
+	ctx context.Context,
+	req *adminv1.DeleteCustomerRequest,
+) (*adminv1.DeleteCustomerResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = a.svc.DeleteCustomer(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.DeleteCustomerResponse{}, nil
+}
+
+func (a *AdminServer) RestoreCustomer(
+	ctx context.Context,
+	req *adminv1.RestoreCustomerRequest,
+) (*adminv1.RestoreCustomerResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.svc.RestoreCustomer(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.RestoreCustomerResponse{Customer: customerToProto(customer)}, nil
+}
+
+func (a *AdminServer) ResetMFA(
+	ctx context.Context,
+	req *adminv1.ResetMFARequest,
+) (*adminv1.ResetMFAResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = a.svc.ResetMFA(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.ResetMFAResponse{}, nil
+}
+
+func (a *AdminServer) ListAuditEntries(
+	ctx context.Context,
+	req *adminv1.ListAuditEntriesRequest,
+) (*adminv1.ListAuditEntriesResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := a.audit.Query(ctx, principal, domain.AuditFilter{
+		ActorID:        req.GetActorId(),
+		CustomerID:     req.GetCustomerId(),
+		Since:          optionalTime(req.GetSince()),
+		Until:          optionalTime(req.GetUntil()),
+		BeforeSequence: req.GetBeforeSequence(),
+		Limit:          int(req.GetLimit()),
+	})
+	if err != nil {
+		return nil, a.status(err)
+	}
+	resp := &adminv1.ListAuditEntriesResponse{Entries: make([]*adminv1.AuditEntry, len(entries))}
+	for i, e := range entries {
+		resp.Entries[i] = auditEntryToProto(e)
+	}
+	return resp, nil
+}
+
+func (a *AdminServer) ExportCustomerData(
+	ctx context.Context,
+	req *adminv1.ExportCustomerDataRequest,
+) (*adminv1.ExportCustomerDataResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	export, err := a.privacy.Export(ctx, principal, req.GetCustomerId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	archive, err := json.Marshal(export)
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.ExportCustomerDataResponse{Archive: archive}, nil
+}
+
+func (a *AdminServer) CreateWebhookEndpoint(
+	ctx context.Context,
+	req *adminv1.CreateWebhookEndpointRequest,
+) (*adminv1.CreateWebhookEndpointResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	eventTypes, err := eventTypesFromProto(req.GetEventTypes())
+	if err != nil {
+		return nil, err
+	}
+	endpoint, secret, err := a.webhooks.CreateEndpoint(
+		ctx,
+		principal,
+		req.GetUrl(),
+		req.GetDescription(),
+		eventTypes,
+	)
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.CreateWebhookEndpointResponse{
+		Endpoint: webhookEndpointToProto(endpoint),
+		Secret:   secret,
+	}, nil
+}
+
+func (a *AdminServer) ListWebhookEndpoints(
+	ctx context.Context,
+	_ *adminv1.ListWebhookEndpointsRequest,
+) (*adminv1.ListWebhookEndpointsResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := a.webhooks.Endpoints(ctx, principal)
+	if err != nil {
+		return nil, a.status(err)
+	}
+	resp := &adminv1.ListWebhookEndpointsResponse{Endpoints: make([]*adminv1.WebhookEndpoint, len(endpoints))}
+	for i, e := range endpoints {
+		resp.Endpoints[i] = webhookEndpointToProto(e)
+	}
+	return resp, nil
+}
+
+func (a *AdminServer) UpdateWebhookEndpoint(
+	ctx context.Context,
+	req *adminv1.UpdateWebhookEndpointRequest,
+) (*adminv1.UpdateWebhookEndpointResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	eventTypes, err := eventTypesFromProto(req.GetEventTypes())
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := a.webhooks.UpdateEndpoint(
+		ctx,
+		principal,
+		req.GetEndpointId(),
+		req.GetUrl(),
+		req.GetDescription(),
+		eventTypes,
+		req.GetEnabled(),
+	)
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.UpdateWebhookEndpointResponse{Endpoint: webhookEndpointToProto(endpoint)}, nil
+}
+
+func (a *AdminServer) DeleteWebhookEndpoint(
+	ctx context.Context,
+	req *adminv1.DeleteWebhookEndpointRequest,
+) (*adminv1.DeleteWebhookEndpointResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = a.webhooks.DeleteEndpoint(ctx, principal, req.GetEndpointId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.DeleteWebhookEndpointResponse{}, nil
+}
+
+func (a *AdminServer) RotateWebhookSecret(
+	ctx context.Context,
+	req *adminv1.RotateWebhookSecretRequest,
+) (*adminv1.RotateWebhookSecretResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := a.webhooks.RotateSecret(ctx, principal, req.GetEndpointId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.RotateWebhookSecretResponse{Secret: secret}, nil
+}
+
+func (a *AdminServer) ListWebhookDeliveries(
+	ctx context.Context,
+	req *adminv1.ListWebhookDeliveriesRequest,
+) (*adminv1.ListWebhookDeliveriesResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := a.webhooks.Deliveries(ctx, principal, req.GetEndpointId(), int(req.GetLimit()))
+	if err != nil {
+		return nil, a.status(err)
+	}
+	resp := &adminv1.ListWebhookDeliveriesResponse{Deliveries: make([]*adminv1.WebhookDelivery, len(deliveries))}
+	for i, d := range deliveries {
+		resp.Deliveries[i] = webhookDeliveryToProto(d)
+	}
+	return resp, nil
+}
+
+func (a *AdminServer) GetWebhookDelivery(
+	ctx context.Context,
+	req *adminv1.GetWebhookDeliveryRequest,
+) (*adminv1.GetWebhookDeliveryResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delivery, attempts, err := a.webhooks.Delivery(ctx, principal, req.GetDeliveryId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	resp := &adminv1.GetWebhookDeliveryResponse{
+		Delivery: webhookDeliveryToProto(delivery),
+		Attempts: make([]*adminv1.WebhookAttempt, len(attempts)),
+	}
+	for i, at := range attempts {
+		resp.Attempts[i] = webhookAttemptToProto(at)
+	}
+	return resp, nil
+}
+
+func (a *AdminServer) RedeliverWebhook(
+	ctx context.Context,
+	req *adminv1.RedeliverWebhookRequest,
+) (*adminv1.RedeliverWebhookResponse, error) {
+	principal, err := a.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := a.webhooks.Redeliver(ctx, principal, req.GetDeliveryId())
+	if err != nil {
+		return nil, a.status(err)
+	}
+	return &adminv1.RedeliverWebhookResponse{Delivery: webhookDeliveryToProto(delivery)}, nil
+}
+
+// principal returns the principal the authorization interceptor resolved
+// for a call.
+func (a *AdminServer) principal(ctx context.Context) (*domain.Principal, error) {
+	principal, ok := server.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+	return principal, nil
+}
+
+// status converts an error of the admin service to a gRPC status error.
+// Unexpected errors are logged and hidden from the caller.
+func (a *AdminServer) status(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrForbidden):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	case errors.Is(err, service.ErrReauthenticationRequired):
+		return status.Error(codes.PermissionDenied, "reauthentication required")
+	case errors.Is(err, domain.ErrCustomerNotFound):
+		return status.Error(codes.NotFound, "customer not found")
+	case errors.Is(err, domain.ErrOrderNotFound):
+		return status.Error(codes.NotFound, "order not found")
+	case errors.Is(err, domain.ErrDownloadNotFound):
+		return status.Error(codes.NotFound, "download not found")
+	case errors.Is(err, domain.ErrWebhookEndpointNotFound):
+		return status.Error(codes.NotFound, "webhook endpoint not found")
+	case errors.Is(err, domain.ErrWebhookDeliveryNotFound):
+		return status.Error(codes.NotFound, "webhook delivery not found")
+	case errors.Is(err, domain.ErrEmailTaken):
+		return status.Error(codes.AlreadyExists, "email address already taken")
+	case errors.Is(err, service.ErrEmptySearchQuery),
+		errors.Is(err, domain.ErrInvalidWebhookURL),
+		errors.Is(err, domain.ErrInvalidWebhookEventTypes):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrOrderNotRefundable),
+		errors.Is(err, service.ErrMFANotEnrolled):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		a.logger.Error("admin call failed", "error", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func customerToProto(c *domain.Customer) *adminv1.Customer {
+	return &adminv1.Customer{
+		Id:                   c.ID,
+		Email:                c.Email,
+		EmailVerified:        c.EmailVerified,
+		TotalPurchasesAmount: int64(c.TotalPurchasesAmount),
+		TotalPurchasesCount:  int64(c.TotalPurchasesCount),
+		CreatedAt:            timestamppb.New(c.CreatedAt),
+		LastLoginAt:          timestamppb.New(c.LastLoginAt),
+		DeletedAt:            optionalTimestamp(c.DeletedAt),
+	}
+}
+
+func orderToProto(o *domain.Order) *adminv1.Order {
+	items := make([]*adminv1.OrderItem, len(o.Items))
+	for i, item := range o.Items {
+		items[i] = &adminv1.OrderItem{
+			Id:           item.ID,
+			ProductId:    item.Product.ID,
+			ProductName:  item.Product.Name,
+			ProductPrice: int64(item.Product.Price),
+			Quantity:     int64(item.Quantity),
+			Status:       item.Status.String(),
+		}
+	}
+	return &adminv1.Order{
+		Id:           o.ID,
+		CustomerId:   o.UserID,
+		OrderNumber:  o.OrderNumber,
+		BillingEmail: o.BillingEmail,
+		BillingName:  o.BillingName,
+		GrandTotal:   int64(o.GrandTotal),
+		Currency:     o.CurrencyID,
+		Status:       o.Status.String(),
+		Items:        items,
+		CreatedAt:    timestamppb.New(o.CreatedAt),
+		CompletedAt:  optionalTimestamp(o.CompletedAt),
+	}
+}
+
+func downloadsToProto(downloads []*domain.Download) []*adminv1.Download {
+	out := make([]*adminv1.Download, len(downloads))
+	for i, d := range downloads {
+		out[i] = &adminv1.Download{
+			Id:               d.ID,
+			CustomerId:       d.UserID,
+			ProductId:        d.ProductID,
+			OrderId:          d.OrderID,
+			DownloadCount:    int64(d.DownloadCount),
+			LastDownloadedAt: optionalTimestamp(d.LastDownloadedAt),
+			CreatedAt:        timestamppb.New(d.CreatedAt),
+		}
+	}
+	return out
+}
+
+func auditEntryToProto(e domain.AuditEntry) *adminv1.AuditEntry {
+	return &adminv1.AuditEntry{
+		Sequence:   e.Sequence,
+		Action:     e.Event.Action.String(),
+		Outcome:    e.Event.Outcome.String(),
+		ActorId:    e.Event.ActorID,
+		CustomerId: e.Event.CustomerID,
+		TargetType: e.Event.TargetType.String(),
+		TargetId:   e.Event.TargetID,
+		Reason:     e.Event.Reason,
+		RequestId:  e.Event.RequestID,
+		TraceId:    e.Event.TraceID,
+		OccurredAt: timestamppb.New(e.Event.OccurredAt),
+		PrevHash:   e.PrevHash,
+		Hash:       e.Hash,
+	}
+}
+
+func webhookEndpointToProto(e *domain.WebhookEndpoint) *adminv1.WebhookEndpoint {
+	eventTypes := make([]string, len(e.EventTypes))
+	for i, typ := range e.EventTypes {
+		eventTypes[i] = typ.String()
+	}
+	return &adminv1.WebhookEndpoint{
+		Id:                  e.ID,
+		Url:                 e.URL,
+		Description:         e.Description,
+		EventTypes:          eventTypes,
+		Enabled:             e.Enabled,
+		ConsecutiveFailures: int32(e.ConsecutiveFailures), //nolint:gosec // Failures are counted in an INTEGER column.
+		FailingSince:        optionalTimestamp(e.FailingSince),
+		DisabledAt:          optionalTimestamp(e.DisabledAt),
+		DisabledReason:      e.DisabledReason,
+		CreatedAt:           timestamppb.New(e.CreatedAt),
+		UpdatedAt:           timestamppb.New(e.UpdatedAt),
+	}
+}
+
+func webhookDeliveryToProto(d *domain.WebhookDelivery) *adminv1.WebhookDelivery {
+	return &adminv1.WebhookDelivery{
+		Id:            d.ID,
+		EndpointId:    d.EndpointID,
+		EventId:       d.EventID,
+		EventType:     d.EventType.String(),
+		Payload:       d.Payload,
+		Status:        d.Status.String(),
+		Attempts:      int32(d.Attempts), //nolint:gosec // Attempts are bounded by the delivery policy.
+		NextAttemptAt: timestamppb.New(d.NextAttemptAt),
+		LastError:     d.LastError,
+		CreatedAt:     timestamppb.New(d.CreatedAt),
+		DeliveredAt:   optionalTimestamp(d.DeliveredAt),
+	}
+}
+
+func webhookAttemptToProto(a *domain.WebhookAttempt) *adminv1.WebhookAttempt {
+	return &adminv1.WebhookAttempt{
+		Id:             a.ID,
+		Number:         int32(a.Number), //nolint:gosec // Attempts are bounded by the delivery policy.
+		RequestHeaders: a.RequestHeaders,
+		ResponseStatus: int32(a.ResponseStatus), //nolint:gosec // HTTP status codes are three digits.
+		ResponseBody:   a.ResponseBody,
+		Error:          a.Error,
+		DurationMs:     a.Duration.Milliseconds(),
+		AttemptedAt:    timestamppb.New(a.AttemptedAt),
+	}
+}
+
+// eventTypesFromProto parses the names of event types.
+func eventTypesFromProto(names []string) ([]domain.EventType, error) {
+	eventTypes := make([]domain.EventType, len(names))
+	for i, name := range names {
+		typ, err := domain.NewEventType(name)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid event type %q", name)
+		}
+		eventTypes[i] = typ
+	}
+	return eventTypes, nil
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// optionalTime returns the time of a timestamp, or the zero time if it is
+// unset.
+func optionalTime(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
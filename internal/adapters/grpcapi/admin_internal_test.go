@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/internal/core/server"
+	adminv1 "go.brokedaear.com/internal/core/server/proto/admin/v1"
+	"go.brokedaear.com/internal/core/service"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeBackOffice returns a customer for every call, or err if it is set.
+type fakeBackOffice struct {
+	backOffice
+	err error
+}
+
+func (f fakeBackOffice) Customer(_ context.Context, _ *domain.Principal, id string) (*domain.Customer, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	deletedAt := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	return &domain.Customer{ID: id, Email: "kai@brokedaear.com", DeletedAt: &deletedAt}, nil
+}
+
+func TestAdminServer_GetCustomer(t *testing.T) {
+	principal := &domain.Principal{CustomerID: "support"}
+
+	tests := []struct {
+		test.CaseBase
+		err error
+	}{
+		{CaseBase: test.NewCaseBase("ok", codes.OK, false), err: nil},
+		{CaseBase: test.NewCaseBase("forbidden", codes.PermissionDenied, true), err: domain.ErrForbidden},
+		{CaseBase: test.NewCaseBase("not found", codes.NotFound, true), err: domain.ErrCustomerNotFound},
+		{
+			CaseBase: test.NewCaseBase("reauthentication", codes.PermissionDenied, true),
+			err:      service.ErrReauthenticationRequired,
+		},
+		{CaseBase: test.NewCaseBase("unexpected", codes.Internal, true), err: errors.New("connection reset")},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				srv := NewAdminServer(test.NewMockLogger(), fakeBackOffice{backOffice: nil, err: tt.err}, nil, nil, nil)
+				ctx := server.ContextWithPrincipal(context.Background(), principal)
+				resp, err := srv.GetCustomer(ctx, &adminv1.GetCustomerRequest{CustomerId: "kai"})
+				assert.Equal(t, status.Code(err), tt.Want.(codes.Code))
+				if tt.WantErr {
+					return
+				}
+				assert.Equal(t, resp.GetCustomer().GetId(), "kai")
+				assert.True(t, resp.GetCustomer().GetDeletedAt().IsValid())
+			},
+		)
+	}
+}
+
+func TestAdminServer_RequiresPrincipal(t *testing.T) {
+	srv := NewAdminServer(test.NewMockLogger(), fakeBackOffice{backOffice: nil, err: nil}, nil, nil, nil)
+	_, err := srv.GetCustomer(context.Background(), &adminv1.GetCustomerRequest{CustomerId: "kai"})
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+func TestAdminAccessRules(t *testing.T) {
+	rules := AdminAccessRules()
+	rule, ok := rules["/brokedaear.admin.v1.AdminService/"]
+	assert.True(t, ok)
+	assert.False(t, rule.Public)
+	assert.Equal(t, rule.Permission, domain.PermissionBackOfficeAccess)
+}
+
+// fakeAuditLog records the filter of the last query.
+type fakeAuditLog struct {
+	filter domain.AuditFilter
+	err    error
+}
+
+func (f *fakeAuditLog) Query(_ context.Context, _ *domain.Principal, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	f.filter = filter
+	if f.err != nil {
+		return nil, f.err
+	}
+	event := domain.NewActorAuditEvent(domain.AuditActionAdminView, domain.AuditOutcomeSuccess, "lea", "kai", "")
+	return []domain.AuditEntry{domain.NewAuditEntry(nil, event)}, nil
+}
+
+func TestAdminServer_ListAuditEntries(t *testing.T) {
+	principal := &domain.Principal{CustomerID: "admin"}
+	ctx := server.ContextWithPrincipal(context.Background(), principal)
+	since := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	audit := &fakeAuditLog{}
+	srv := NewAdminServer(test.NewMockLogger(), fakeBackOffice{backOffice: nil, err: nil}, audit, nil, nil)
+
+	resp, err := srv.ListAuditEntries(ctx, &adminv1.ListAuditEntriesRequest{
+		ActorId:        "lea",
+		Since:          timestamppb.New(since),
+		BeforeSequence: 10,
+		Limit:          5,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, audit.filter.ActorID, "lea")
+	assert.Equal(t, audit.filter.Since, since)
+	assert.True(t, audit.filter.Until.IsZero())
+	assert.Equal(t, audit.filter.BeforeSequence, int64(10))
+	assert.Equal(t, audit.filter.Limit, 5)
+	assert.Equal(t, len(resp.GetEntries()), 1)
+	assert.Equal(t, resp.GetEntries()[0].GetAction(), "admin.view")
+	assert.Equal(t, resp.GetEntries()[0].GetPrevHash(), domain.GenesisAuditHash)
+
+	audit.err = domain.ErrForbidden
+	_, err = srv.ListAuditEntries(ctx, &adminv1.ListAuditEntriesRequest{})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+}
+
+// fakeExporter exports a customer without data, or fails with err.
+type fakeExporter struct {
+	err error
+}
+
+func (f fakeExporter) Export(_ context.Context, _ *domain.Principal, id string) (*domain.CustomerExport, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	customer := &domain.Customer{ID: id, Email: "kai@brokedaear.com"}
+	return domain.NewCustomerExport(customer, nil, nil, nil), nil
+}
+
+func TestAdminServer_ExportCustomerData(t *testing.T) {
+	ctx := server.ContextWithPrincipal(context.Background(), &domain.Principal{CustomerID: "support"})
+	srv := NewAdminServer(test.NewMockLogger(), fakeBackOffice{backOffice: nil, err: nil}, nil, fakeExporter{err: nil}, nil)
+
+	resp, err := srv.ExportCustomerData(ctx, &adminv1.ExportCustomerDataRequest{CustomerId: "kai"})
+	assert.NoError(t, err)
+	var export domain.CustomerExport
+	assert.NoError(t, json.Unmarshal(resp.GetArchive(), &export))
+	assert.Equal(t, export.Version, domain.CustomerExportVersion)
+	assert.Equal(t, export.Profile.ID, "kai")
+
+	srv = NewAdminServer(
+		test.NewMockLogger(), fakeBackOffice{backOffice: nil, err: nil}, nil,
+		fakeExporter{err: domain.ErrCustomerNotFound}, nil,
+	)
+	_, err = srv.ExportCustomerData(ctx, &adminv1.ExportCustomerDataRequest{CustomerId: "kai"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+}
+
+// fakeWebhooks creates endpoints, or fails with err if it is set.
+type fakeWebhooks struct {
+	webhookAdmin
+	err error
+}
+
+func (f fakeWebhooks) CreateEndpoint(
+	_ context.Context,
+	_ *domain.Principal,
+	rawURL, description string,
+	eventTypes []domain.EventType,
+) (*domain.WebhookEndpoint, string, error) {
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	endpoint, err := domain.NewWebhookEndpoint(rawURL, description, eventTypes)
+	if err != nil {
+		return nil, "", err
+	}
+	return endpoint, "whsec_test", nil
+}
+
+func TestAdminServer_CreateWebhookEndpoint(t *testing.T) {
+	ctx := server.ContextWithPrincipal(context.Background(), &domain.Principal{CustomerID: "support"})
+
+	tests := []struct {
+		test.CaseBase
+		url        string
+		eventTypes []string
+		err        error
+	}{
+		{
+			CaseBase:   test.NewCaseBase("ok", codes.OK, false),
+			url:        "https://hooks.example.com/brokedaear",
+			eventTypes: []string{"order.paid", "order.refunded"},
+			err:        nil,
+		},
+		{
+			CaseBase:   test.NewCaseBase("unknown event type", codes.InvalidArgument, true),
+			url:        "https://hooks.example.com/brokedaear",
+			eventTypes: []string{"order.shipped"},
+			err:        nil,
+		},
+		{
+			CaseBase:   test.NewCaseBase("plain http", codes.InvalidArgument, true),
+			url:        "http://hooks.example.com/brokedaear",
+			eventTypes: []string{"order.paid"},
+			err:        nil,
+		},
+		{
+			CaseBase:   test.NewCaseBase("forbidden", codes.PermissionDenied, true),
+			url:        "https://hooks.example.com/brokedaear",
+			eventTypes: []string{"order.paid"},
+			err:        domain.ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				srv := NewAdminServer(
+					test.NewMockLogger(), fakeBackOffice{backOffice: nil, err: nil}, nil, nil,
+					fakeWebhooks{webhookAdmin: nil, err: tt.err},
+				)
+				resp, err := srv.CreateWebhookEndpoint(ctx, &adminv1.CreateWebhookEndpointRequest{
+					Url:         tt.url,
+					Description: "fulfilment",
+					EventTypes:  tt.eventTypes,
+				})
+				assert.Equal(t, status.Code(err), tt.Want.(codes.Code))
+				if tt.WantErr {
+					return
+				}
+				assert.Equal(t, resp.GetSecret(), "whsec_test")
+				assert.Equal(t, resp.GetEndpoint().GetUrl(), tt.url)
+				assert.Equal(t, len(resp.GetEndpoint().GetEventTypes()), len(tt.eventTypes))
+				assert.True(t, resp.GetEndpoint().GetEnabled())
+			},
+		)
+	}
+}
@@ -0,0 +1,173 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mail renders transactional email and delivers it over SMTP.
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Branding is how email looks in an environment. Email sent outside of
+// production is marked, so that it is never mistaken for the real thing.
+type Branding struct {
+	// Name is the name of the shop.
+	Name string
+	// SiteURL is the URL of the shop's frontend.
+	SiteURL string
+	// SupportEmail is the address customers can write to for help.
+	SupportEmail string
+	// SubjectPrefix is prepended to the subject of every email.
+	SubjectPrefix string
+	// Banner is shown above the body of every email, if it is not empty.
+	Banner string
+	// AccentColor is the CSS color of headers and buttons.
+	AccentColor string
+}
+
+// BrandingFor returns the branding of an environment.
+func BrandingFor(env domain.Environment) (Branding, error) {
+	b := Branding{
+		Name:          "BROKE DA EAR",
+		SiteURL:       "https://brokedaear.com",
+		SupportEmail:  "support@brokedaear.com",
+		SubjectPrefix: "",
+		Banner:        "",
+		AccentColor:   "#111111",
+	}
+	switch env {
+	case domain.EnvProduction:
+	case domain.EnvStaging:
+		b.SiteURL = "https://staging.brokedaear.com"
+		b.SubjectPrefix = "[Staging] "
+		b.Banner = "This email was sent from the staging environment."
+		b.AccentColor = "#c2410c"
+	case domain.EnvDevelopment:
+		b.SiteURL = "http://localhost:3000"
+		b.SubjectPrefix = "[Dev] "
+		b.Banner = "This email was sent from a development environment."
+		b.AccentColor = "#6d28d9"
+	default:
+		return Branding{}, errors.New("invalid environment")
+	}
+	return b, nil
+}
+
+// templateData is what every template is rendered with.
+type templateData struct {
+	Brand   Branding
+	Subject string
+	Data    map[string]any
+}
+
+// emailTemplates are the parsed templates of one email.
+type emailTemplates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Renderer renders transactional email with its templates. Every email has a
+// plain text and an HTML body.
+type Renderer struct {
+	branding  Branding
+	templates map[domain.EmailTemplate]emailTemplates
+}
+
+//nolint:gochecknoglobals // The list of templates to parse.
+var allTemplates = []domain.EmailTemplate{
+	domain.EmailTemplateEmailVerification,
+	domain.EmailTemplatePasswordReset,
+	domain.EmailTemplatePasswordChanged,
+	domain.EmailTemplateOrderReceipt,
+	domain.EmailTemplateDownloadLinks,
+	domain.EmailTemplateAccountLocked,
+}
+
+// NewRenderer creates a new Renderer with a branding. Every template is
+// parsed up front, so that a broken template fails at startup.
+func NewRenderer(branding Branding) (*Renderer, error) {
+	funcs := map[string]any{"duration": formatDuration}
+	r := &Renderer{branding: branding, templates: make(map[domain.EmailTemplate]emailTemplates)}
+	for _, t := range allTemplates {
+		text, err := texttemplate.New("").Funcs(funcs).ParseFS(
+			templateFS, "templates/layout.txt.tmpl", "templates/"+t.String()+".txt.tmpl",
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse text template %s", t)
+		}
+		html, err := htmltemplate.New("").Funcs(funcs).ParseFS(
+			templateFS, "templates/layout.html.tmpl", "templates/"+t.String()+".html.tmpl",
+		)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse html template %s", t)
+		}
+		r.templates[t] = emailTemplates{text: text, html: html}
+	}
+	return r, nil
+}
+
+// Render renders an email with its template.
+func (r *Renderer) Render(msg domain.EmailMessage) (*domain.RenderedEmail, error) {
+	if msg.To == "" {
+		return nil, errors.New("email has no recipient")
+	}
+	t, ok := r.templates[msg.Template]
+	if !ok {
+		return nil, errors.Errorf("unknown email template %q", msg.Template)
+	}
+
+	data := templateData{Brand: r.branding, Subject: "", Data: msg.Data}
+	var subject, text, html bytes.Buffer
+	err := t.text.ExecuteTemplate(&subject, "subject", data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render subject")
+	}
+	data.Subject = r.branding.SubjectPrefix + strings.TrimSpace(subject.String())
+	err = t.text.ExecuteTemplate(&text, "layout", data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render text body")
+	}
+	err = t.html.ExecuteTemplate(&html, "layout", data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render html body")
+	}
+
+	return &domain.RenderedEmail{
+		To:      msg.To,
+		Subject: data.Subject,
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// formatDuration formats a duration for people to read, such as "24 hours"
+// or "30 minutes".
+func formatDuration(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return "1 " + unit
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return plural(int64(d/time.Hour), "hour")
+	case d >= time.Minute:
+		return plural(int64(d/time.Minute), "minute")
+	default:
+		return plural(int64(d/time.Second), "second")
+	}
+}
@@ -0,0 +1,294 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mail
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/mail/mailtest"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+const testRecipient = "kai@brokedaear.com"
+
+func newTestRenderer(t *testing.T, env domain.Environment) *Renderer {
+	t.Helper()
+	branding, err := BrandingFor(env)
+	assert.NoError(t, err)
+	r, err := NewRenderer(branding)
+	assert.NoError(t, err)
+	return r
+}
+
+// testData returns data that every template can be rendered with.
+func testData() map[string]any {
+	return map[string]any{
+		"Link":      "https://brokedaear.com/verify?token=abc.def",
+		"ExpiresIn": 24 * time.Hour,
+		"LockedFor": 30 * time.Minute,
+		"OrderID":   "BDE-1001",
+		"Items":     []map[string]string{{"Name": "Reverb Pack", "Price": "$19.00"}},
+		"Total":     "$19.00",
+		"Downloads": []map[string]string{{"Name": "Reverb Pack", "Link": "https://r2.brokedaear.com/x"}},
+	}
+}
+
+func TestRenderer_RenderEveryTemplate(t *testing.T) {
+	r := newTestRenderer(t, domain.EnvProduction)
+
+	for _, tmpl := range allTemplates {
+		t.Run(
+			tmpl.String(), func(t *testing.T) {
+				email, err := r.Render(domain.EmailMessage{To: testRecipient, Template: tmpl, Data: testData()})
+				assert.NoError(t, err)
+				assert.Equal(t, email.To, testRecipient)
+				assert.NotEqual(t, email.Subject, "")
+				assert.False(t, strings.Contains(email.Text, "<no value>"))
+				assert.False(t, strings.Contains(email.HTML, "<no value>"))
+				assert.True(t, strings.Contains(email.HTML, "support@brokedaear.com"))
+			},
+		)
+	}
+}
+
+func TestRenderer_Branding(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		env    domain.Environment
+		banner bool
+	}{
+		{
+			CaseBase: test.NewCaseBase("production", "Verify your email address", false),
+			env:      domain.EnvProduction,
+			banner:   false,
+		},
+		{
+			CaseBase: test.NewCaseBase("staging", "[Staging] Verify your email address", false),
+			env:      domain.EnvStaging,
+			banner:   true,
+		},
+		{
+			CaseBase: test.NewCaseBase("development", "[Dev] Verify your email address", false),
+			env:      domain.EnvDevelopment,
+			banner:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				r := newTestRenderer(t, tt.env)
+				email, err := r.Render(domain.EmailMessage{
+					To:       testRecipient,
+					Template: domain.EmailTemplateEmailVerification,
+					Data:     testData(),
+				})
+				assert.NoError(t, err)
+				assert.Equal(t, email.Subject, tt.Want.(string))
+				assert.Equal(t, strings.Contains(email.Text, "environment."), tt.banner)
+				assert.Equal(t, strings.Contains(email.HTML, "environment."), tt.banner)
+				assert.True(t, strings.Contains(email.Text, "24 hours"))
+			},
+		)
+	}
+
+	_, err := BrandingFor(domain.Environment{})
+	assert.ErrorAndWant(t, err, true)
+}
+
+func TestRenderer_EscapesHTML(t *testing.T) {
+	r := newTestRenderer(t, domain.EnvProduction)
+	data := testData()
+	data["Link"] = `https://brokedaear.com/verify?token="><script>alert(1)</script>`
+
+	email, err := r.Render(domain.EmailMessage{
+		To:       testRecipient,
+		Template: domain.EmailTemplateEmailVerification,
+		Data:     data,
+	})
+	assert.NoError(t, err)
+	assert.False(t, strings.Contains(email.HTML, "<script>"))
+}
+
+func TestRenderer_RenderFailures(t *testing.T) {
+	r := newTestRenderer(t, domain.EnvProduction)
+
+	_, err := r.Render(domain.EmailMessage{To: "", Template: domain.EmailTemplatePasswordChanged, Data: nil})
+	assert.ErrorAndWant(t, err, true)
+
+	_, err = r.Render(domain.EmailMessage{To: testRecipient, Template: domain.InvalidEmailTemplate, Data: nil})
+	assert.ErrorAndWant(t, err, true)
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		d time.Duration
+	}{
+		{CaseBase: test.NewCaseBase("hours", "24 hours", false), d: 24 * time.Hour},
+		{CaseBase: test.NewCaseBase("one hour", "1 hour", false), d: time.Hour},
+		{CaseBase: test.NewCaseBase("minutes", "30 minutes", false), d: 30 * time.Minute},
+		{CaseBase: test.NewCaseBase("uneven hours", "90 minutes", false), d: 90 * time.Minute},
+		{CaseBase: test.NewCaseBase("seconds", "45 seconds", false), d: 45 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				assert.Equal(t, formatDuration(tt.d), tt.Want.(string))
+			},
+		)
+	}
+}
+
+func newTestConfig(srv *mailtest.Server) SMTPConfig {
+	return SMTPConfig{
+		Host:      srv.Host(),
+		Port:      srv.Port(),
+		Username:  srv.Username,
+		Password:  srv.Password,
+		From:      "BROKE DA EAR <no-reply@brokedaear.com>",
+		TLS:       TLSModeNone,
+		TLSConfig: nil,
+		Timeout:   5 * time.Second,
+	}
+}
+
+func newTestTransport(t *testing.T, cfg SMTPConfig) *SMTPTransport {
+	t.Helper()
+	transport, err := NewSMTPTransport(cfg)
+	assert.NoError(t, err)
+	return transport
+}
+
+func TestSMTPTransport_Deliver(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+	srv.Username = "mailer"
+	srv.Password = "hunter22"
+
+	email, err := newTestRenderer(t, domain.EnvStaging).Render(domain.EmailMessage{
+		To:       testRecipient,
+		Template: domain.EmailTemplatePasswordReset,
+		Data:     testData(),
+	})
+	assert.NoError(t, err)
+
+	err = newTestTransport(t, newTestConfig(srv)).Deliver(context.Background(), email)
+	assert.NoError(t, err)
+
+	messages := srv.Messages()
+	assert.Equal(t, len(messages), 1)
+	assert.Equal(t, messages[0].From, "no-reply@brokedaear.com")
+	assert.Equal(t, messages[0].To[0], testRecipient)
+
+	msg, err := mail.ReadMessage(strings.NewReader(messages[0].Data))
+	assert.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	assert.NoError(t, err)
+	assert.Equal(t, subject, email.Subject)
+	assert.NotEqual(t, msg.Header.Get("Message-ID"), "")
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, mediaType, "multipart/alternative")
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := parts.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		assert.NoError(t, err)
+		body, err := io.ReadAll(part)
+		assert.NoError(t, err)
+		bodies = append(bodies, string(body))
+	}
+	assert.Equal(t, len(bodies), 2)
+	assert.Equal(t, bodies[0], email.Text)
+	assert.Equal(t, bodies[1], email.HTML)
+}
+
+func TestSMTPTransport_DeliverFailures(t *testing.T) {
+	email := &domain.RenderedEmail{To: testRecipient, Subject: "Hello", Text: "Hello", HTML: "<p>Hello</p>"}
+
+	tests := []struct {
+		test.CaseBase
+		configure func(*mailtest.Server, *SMTPConfig, *domain.RenderedEmail)
+		rejected  bool
+	}{
+		{
+			CaseBase: test.NewCaseBase("unknown recipient", nil, true),
+			configure: func(srv *mailtest.Server, _ *SMTPConfig, _ *domain.RenderedEmail) {
+				srv.Reject[testRecipient] = true
+			},
+			rejected: true,
+		},
+		{
+			CaseBase: test.NewCaseBase("busy server", nil, true),
+			configure: func(srv *mailtest.Server, _ *SMTPConfig, _ *domain.RenderedEmail) {
+				srv.FailNext = 1
+			},
+			rejected: false,
+		},
+		{
+			CaseBase: test.NewCaseBase("wrong credentials", nil, true),
+			configure: func(srv *mailtest.Server, cfg *SMTPConfig, _ *domain.RenderedEmail) {
+				srv.Username = "mailer"
+				srv.Password = "hunter22"
+				cfg.Username = "mailer"
+				cfg.Password = "wrong"
+			},
+			rejected: false,
+		},
+		{
+			CaseBase: test.NewCaseBase("header injection", nil, true),
+			configure: func(_ *mailtest.Server, _ *SMTPConfig, e *domain.RenderedEmail) {
+				e.Subject = "Hi\r\nBcc: x@y.z"
+			},
+			rejected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				srv := mailtest.NewServer()
+				defer srv.Close()
+				cfg := newTestConfig(srv)
+				e := *email
+				tt.configure(srv, &cfg, &e)
+
+				err := newTestTransport(t, cfg).Deliver(context.Background(), &e)
+				assert.ErrorAndWant(t, err, tt.WantErr)
+				assert.Equal(t, errors.Is(err, domain.ErrEmailRejected), tt.rejected)
+				assert.Equal(t, len(srv.Messages()), 0)
+			},
+		)
+	}
+}
+
+func TestSMTPTransport_DeliverHonorsContext(t *testing.T) {
+	srv := mailtest.NewServer()
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := newTestTransport(t, newTestConfig(srv)).Deliver(ctx, &domain.RenderedEmail{
+		To: testRecipient, Subject: "Hello", Text: "Hello", HTML: "<p>Hello</p>",
+	})
+	assert.Error(t, err, context.Canceled)
+}
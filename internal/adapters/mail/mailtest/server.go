@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mailtest provides a local SMTP sink for testing mail delivery.
+package mailtest
+
+import (
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Message is an email received by the sink.
+type Message struct {
+	From string
+	To   []string
+	// Data is the raw message, with headers.
+	Data string
+}
+
+// Server is an SMTP sink that keeps every message it receives. It speaks
+// just enough SMTP for net/smtp, without TLS.
+type Server struct {
+	// Username and Password, if set, must be given with AUTH PLAIN before
+	// mail is accepted.
+	Username string
+	Password string
+	// Reject lists recipients that are refused with a permanent failure.
+	Reject map[string]bool
+	// FailNext is how many messages are refused with a temporary failure
+	// before mail is accepted again.
+	FailNext int
+
+	listener net.Listener
+	mu       sync.Mutex
+	messages []Message
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a new sink on a random local port.
+func NewServer() *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("mailtest: failed to listen: " + err.Error())
+	}
+	s := &Server{Reject: make(map[string]bool), listener: l}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Host returns the host the sink listens on.
+func (s *Server) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+// Port returns the port the sink listens on.
+func (s *Server) Port() int {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return p
+}
+
+// Messages returns every message received so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Close stops the sink.
+func (s *Server) Close() {
+	_ = s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+// session is the state of one SMTP conversation.
+type session struct {
+	authenticated bool
+	from          string
+	to            []string
+}
+
+func (s *Server) handle(conn *textproto.Conn) {
+	reply := func(code int, msg string) bool {
+		return conn.PrintfLine("%d %s", code, msg) == nil
+	}
+	if !reply(220, "mailtest ready") {
+		return
+	}
+
+	var sess session
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			_ = conn.PrintfLine("250-mailtest")
+			reply(250, "AUTH PLAIN")
+		case "HELO":
+			reply(250, "mailtest")
+		case "AUTH":
+			sess.authenticated = s.auth(arg)
+			if sess.authenticated {
+				reply(235, "authenticated")
+			} else {
+				reply(535, "authentication failed")
+			}
+		case "MAIL":
+			if s.Username != "" && !sess.authenticated {
+				reply(530, "authentication required")
+				continue
+			}
+			sess.from = address(arg)
+			reply(250, "ok")
+		case "RCPT":
+			to := address(arg)
+			if s.rejects(to) {
+				reply(550, "no such user")
+				continue
+			}
+			sess.to = append(sess.to, to)
+			reply(250, "ok")
+		case "DATA":
+			if !s.data(conn, &sess) {
+				return
+			}
+		case "RSET":
+			sess = session{authenticated: sess.authenticated}
+			reply(250, "ok")
+		case "NOOP":
+			reply(250, "ok")
+		case "QUIT":
+			reply(221, "bye")
+			return
+		default:
+			reply(502, "not implemented")
+		}
+	}
+}
+
+// data reads a message and keeps it, unless a temporary failure is due.
+func (s *Server) data(conn *textproto.Conn, sess *session) bool {
+	if conn.PrintfLine("354 go ahead") != nil {
+		return false
+	}
+	data, err := conn.ReadDotBytes()
+	if err != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	fail := s.FailNext > 0
+	if fail {
+		s.FailNext--
+	} else {
+		s.messages = append(s.messages, Message{From: sess.from, To: sess.to, Data: string(data)})
+	}
+	s.mu.Unlock()
+
+	*sess = session{authenticated: sess.authenticated}
+	if fail {
+		return conn.PrintfLine("451 try again later") == nil
+	}
+	return conn.PrintfLine("250 queued") == nil
+}
+
+func (s *Server) rejects(to string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Reject[to]
+}
+
+// auth checks an AUTH PLAIN argument.
+func (s *Server) auth(arg string) bool {
+	mechanism, encoded, _ := strings.Cut(arg, " ")
+	if !strings.EqualFold(mechanism, "PLAIN") {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(decoded), "\x00")
+	const plainParts = 3
+	return len(parts) == plainParts && parts[1] == s.Username && parts[2] == s.Password
+}
+
+// address extracts the address from a `FROM:<address>` or `TO:<address>`
+// argument.
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(addr, " ")
+	return strings.Trim(addr, "<>")
+}
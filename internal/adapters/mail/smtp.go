@@ -0,0 +1,260 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// TLSMode is how an SMTP connection is secured.
+type TLSMode uint8
+
+const (
+	// TLSModeStartTLS upgrades a plain connection with STARTTLS, and fails
+	// if the server does not support it.
+	TLSModeStartTLS TLSMode = iota
+	// TLSModeImplicit connects with TLS from the start, usually on port 465.
+	TLSModeImplicit
+	// TLSModeNone never uses TLS. It is only meant for a local SMTP sink.
+	TLSModeNone
+)
+
+// SMTPConfig configures an SMTP connection.
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with PLAIN. Authentication is
+	// skipped if Username is empty.
+	Username string
+	Password string
+	// From is the sender of every email, such as
+	// `BROKE DA EAR <no-reply@brokedaear.com>`.
+	From string
+	TLS  TLSMode
+	// TLSConfig overrides the TLS configuration. If nil, the server
+	// certificate is verified against Host.
+	TLSConfig *tls.Config
+	// Timeout bounds a delivery when the context has no deadline.
+	Timeout time.Duration
+}
+
+// SMTPTransport delivers email over SMTP. A new connection is made for every
+// email, since transactional email is sent rarely.
+type SMTPTransport struct {
+	cfg  SMTPConfig
+	from *mail.Address
+}
+
+// NewSMTPTransport creates a new SMTPTransport.
+func NewSMTPTransport(cfg SMTPConfig) (*SMTPTransport, error) {
+	if cfg.Host == "" || cfg.Port == 0 {
+		return nil, errors.New("smtp host and port are required")
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid smtp sender")
+	}
+	if cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12}
+	}
+	if cfg.Timeout == 0 {
+		const defaultTimeout = 30 * time.Second
+		cfg.Timeout = defaultTimeout
+	}
+	return &SMTPTransport{cfg: cfg, from: from}, nil
+}
+
+// Deliver delivers an email. Permanent failures, such as an unknown
+// recipient, wrap domain.ErrEmailRejected.
+func (s *SMTPTransport) Deliver(ctx context.Context, email *domain.RenderedEmail) error {
+	to, err := mail.ParseAddress(email.To)
+	if err != nil {
+		return errors.Wrap(domain.ErrEmailRejected, "invalid recipient")
+	}
+	msg, err := s.buildMessage(to, email)
+	if err != nil {
+		return errors.Wrap(domain.ErrEmailRejected, err.Error())
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = s.send(client, to.Address, msg)
+	if err != nil {
+		return classifySMTPError(err)
+	}
+	return nil
+}
+
+// dial connects and says hello to the server. The connection is closed when
+// the context is done.
+func (s *SMTPTransport) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to smtp server")
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if s.cfg.TLS == TLSModeImplicit {
+		conn = tls.Client(conn, s.cfg.TLSConfig)
+	}
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.Wrap(err, "failed to greet smtp server")
+	}
+	if s.cfg.TLS == TLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			_ = client.Close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+		err = client.StartTLS(s.cfg.TLSConfig)
+		if err != nil {
+			_ = client.Close()
+			return nil, errors.Wrap(err, "failed to start tls")
+		}
+	}
+	if s.cfg.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host))
+		if err != nil {
+			_ = client.Close()
+			return nil, errors.Wrap(err, "failed to authenticate with smtp server")
+		}
+	}
+	return client, nil
+}
+
+func (s *SMTPTransport) send(client *smtp.Client, to string, msg []byte) error {
+	err := client.Mail(s.from.Address)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(to)
+	if err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(msg)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMessage builds a multipart/alternative message with a plain text and
+// an HTML body.
+func (s *SMTPTransport) buildMessage(to *mail.Address, email *domain.RenderedEmail) ([]byte, error) {
+	if strings.ContainsAny(email.Subject, "\r\n") {
+		return nil, errors.New("subject contains a line break")
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		_, err = qp.Write([]byte(part.content))
+		if err != nil {
+			return nil, err
+		}
+		err = qp.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := parts.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	messageID, err := newMessageID(s.from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", s.from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("utf-8", email.Subject)},
+		{"Date", time.Now().UTC().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	} {
+		msg.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// newMessageID returns a random message ID in the domain of the sender.
+func newMessageID(from string) (string, error) {
+	const idBytes = 16
+	b := make([]byte, idBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to make message id")
+	}
+	_, host, _ := strings.Cut(from, "@")
+	return "<" + hex.EncodeToString(b) + "@" + host + ">", nil
+}
+
+// classifySMTPError marks permanent SMTP failures, those with a 5xx reply,
+// as rejected. Everything else, such as a busy server, is worth retrying.
+func classifySMTPError(err error) error {
+	var protoErr *textproto.Error
+	const permanentFailure = 500
+	if errors.As(err, &protoErr) && protoErr.Code >= permanentFailure {
+		return errors.Wrap(domain.ErrEmailRejected, protoErr.Error())
+	}
+	return errors.Wrap(err, "failed to deliver email")
+}
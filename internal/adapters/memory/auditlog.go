@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// AuditLogRepository stores the audit log in memory.
+type AuditLogRepository struct {
+	mu      sync.RWMutex
+	entries []domain.AuditEntry
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository.
+func NewAuditLogRepository() *AuditLogRepository {
+	return &AuditLogRepository{
+		mu:      sync.RWMutex{},
+		entries: make([]domain.AuditEntry, 0),
+	}
+}
+
+// Append adds an event to the end of the log, chained to the last entry.
+func (ar *AuditLogRepository) Append(_ context.Context, event domain.AuditEvent) (*domain.AuditEntry, error) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	var prev *domain.AuditEntry
+	if len(ar.entries) > 0 {
+		prev = &ar.entries[len(ar.entries)-1]
+	}
+	entry := domain.NewAuditEntry(prev, event)
+	ar.entries = append(ar.entries, entry)
+	return &entry, nil
+}
+
+// List retrieves the entries a filter matches, most recent first.
+func (ar *AuditLogRepository) List(_ context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	entries := make([]domain.AuditEntry, 0)
+	for i := len(ar.entries) - 1; i >= 0 && len(entries) < filter.Limit; i-- {
+		if filter.Matches(ar.entries[i]) {
+			entries = append(entries, ar.entries[i])
+		}
+	}
+	return entries, nil
+}
+
+// ListAfter retrieves up to limit entries that follow a sequence, in order.
+func (ar *AuditLogRepository) ListAfter(_ context.Context, sequence int64, limit int) ([]domain.AuditEntry, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	entries := make([]domain.AuditEntry, 0)
+	for _, e := range ar.entries {
+		if e.Sequence > sequence && len(entries) < limit {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// AuthorizationRepository stores the grants of roles and the roles of
+// customers in memory.
+type AuthorizationRepository struct {
+	mu     sync.RWMutex
+	grants []domain.Grant
+	roles  map[string][]domain.Role
+}
+
+// NewAuthorizationRepository creates a new AuthorizationRepository with a
+// set of grants, such as domain.DefaultGrants().
+func NewAuthorizationRepository(grants ...domain.Grant) *AuthorizationRepository {
+	return &AuthorizationRepository{
+		mu:     sync.RWMutex{},
+		grants: slices.Clone(grants),
+		roles:  make(map[string][]domain.Role),
+	}
+}
+
+// ListRoles retrieves the roles assigned to a customer. The implicit
+// customer role is not included.
+func (ar *AuthorizationRepository) ListRoles(_ context.Context, customerID string) ([]domain.Role, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	return slices.Clone(ar.roles[customerID]), nil
+}
+
+// ListGrants retrieves the grants of a set of roles.
+func (ar *AuthorizationRepository) ListGrants(_ context.Context, roles []domain.Role) ([]domain.Grant, error) {
+	ar.mu.RLock()
+	defer ar.mu.RUnlock()
+	grants := make([]domain.Grant, 0)
+	for _, g := range ar.grants {
+		if slices.Contains(roles, g.Role) {
+			grants = append(grants, g)
+		}
+	}
+	return grants, nil
+}
+
+// AssignRole assigns a role to a customer. Assigning a role the customer
+// already has does nothing.
+func (ar *AuthorizationRepository) AssignRole(
+	_ context.Context,
+	customerID string,
+	role domain.Role,
+	_ string,
+) error {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	if !slices.Contains(ar.roles[customerID], role) {
+		ar.roles[customerID] = append(ar.roles[customerID], role)
+	}
+	return nil
+}
+
+// RevokeRole removes a role from a customer. Revoking a role the customer
+// does not have does nothing.
+func (ar *AuthorizationRepository) RevokeRole(_ context.Context, customerID string, role domain.Role) error {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.roles[customerID] = slices.DeleteFunc(ar.roles[customerID], func(r domain.Role) bool {
+		return r == role
+	})
+	return nil
+}
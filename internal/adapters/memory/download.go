@@ -0,0 +1,64 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// DownloadRepository stores the downloads of customers in memory.
+type DownloadRepository struct {
+	mu        sync.RWMutex
+	downloads map[string]domain.Download
+}
+
+// NewDownloadRepository creates a new DownloadRepository.
+func NewDownloadRepository() *DownloadRepository {
+	return &DownloadRepository{
+		mu:        sync.RWMutex{},
+		downloads: make(map[string]domain.Download),
+	}
+}
+
+// Insert adds a new download.
+func (dr *DownloadRepository) Insert(_ context.Context, download *domain.Download) error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.downloads[download.ID] = *download
+	return nil
+}
+
+// ListByCustomer retrieves every download of a customer, most recent first.
+func (dr *DownloadRepository) ListByCustomer(_ context.Context, customerID string) ([]*domain.Download, error) {
+	dr.mu.RLock()
+	defer dr.mu.RUnlock()
+	downloads := make([]*domain.Download, 0)
+	for _, d := range dr.downloads {
+		if d.UserID == customerID {
+			downloads = append(downloads, &d)
+		}
+	}
+	slices.SortFunc(downloads, func(a, b *domain.Download) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return downloads, nil
+}
+
+// ResetCount sets the download count of a download back to zero.
+func (dr *DownloadRepository) ResetCount(_ context.Context, id string) error {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	d, ok := dr.downloads[id]
+	if !ok {
+		return domain.ErrDownloadNotFound
+	}
+	d.DownloadCount = 0
+	dr.downloads[id] = d
+	return nil
+}
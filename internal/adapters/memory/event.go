@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// EventOutboxRepository stores domain events waiting to be published in
+// memory.
+type EventOutboxRepository struct {
+	mu     sync.Mutex
+	events map[string]domain.Event
+}
+
+// NewEventOutboxRepository creates a new EventOutboxRepository.
+func NewEventOutboxRepository() *EventOutboxRepository {
+	return &EventOutboxRepository{
+		mu:     sync.Mutex{},
+		events: make(map[string]domain.Event),
+	}
+}
+
+// Insert adds a new event to the outbox.
+func (er *EventOutboxRepository) Insert(_ context.Context, event *domain.Event) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.events[event.ID] = *event
+	return nil
+}
+
+// List returns every event in the outbox, whatever its status, oldest first.
+func (er *EventOutboxRepository) List() []*domain.Event {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	events := make([]*domain.Event, 0, len(er.events))
+	for _, e := range er.events {
+		events = append(events, &e)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	return events
+}
+
+// ClaimDue returns up to limit pending events that are due at a point in
+// time, oldest first, and pushes their next attempt back by lease.
+func (er *EventOutboxRepository) ClaimDue(
+	_ context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.Event, error) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	var due []domain.Event
+	for _, e := range er.events {
+		if e.Status == domain.EventStatusPending && !e.NextAttemptAt.After(at) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]*domain.Event, 0, len(due))
+	for _, e := range due {
+		e.NextAttemptAt = at.Add(lease)
+		er.events[e.ID] = e
+		claimed = append(claimed, &e)
+	}
+	return claimed, nil
+}
+
+// MarkPublished marks an event as handled by every subscriber.
+func (er *EventOutboxRepository) MarkPublished(_ context.Context, id string, at time.Time) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	e, ok := er.events[id]
+	if !ok {
+		return domain.ErrEventNotFound
+	}
+	e.Status = domain.EventStatusPublished
+	e.Attempts++
+	e.PublishedAt = &at
+	er.events[id] = e
+	return nil
+}
+
+// MarkFailed records a failed attempt. A nil next attempt marks the event as
+// dead.
+func (er *EventOutboxRepository) MarkFailed(
+	_ context.Context,
+	id string,
+	attempts int,
+	next *time.Time,
+	lastError string,
+) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	e, ok := er.events[id]
+	if !ok {
+		return domain.ErrEventNotFound
+	}
+	e.Attempts = attempts
+	e.LastError = lastError
+	if next == nil {
+		e.Status = domain.EventStatusDead
+	} else {
+		e.NextAttemptAt = *next
+	}
+	er.events[id] = e
+	return nil
+}
+
+// DeleteExpired removes every published or dead event created before a point
+// in time and returns the number of removed events.
+func (er *EventOutboxRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	var n int64
+	for id, e := range er.events {
+		if e.Status != domain.EventStatusPending && e.CreatedAt.Before(before) {
+			delete(er.events, id)
+			n++
+		}
+	}
+	return n, nil
+}
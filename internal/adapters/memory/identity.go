@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// IdentityRepository stores the identities linked to customers in memory.
+type IdentityRepository struct {
+	mu         sync.RWMutex
+	identities map[string]domain.Identity
+}
+
+// NewIdentityRepository creates a new IdentityRepository.
+func NewIdentityRepository() *IdentityRepository {
+	return &IdentityRepository{
+		mu:         sync.RWMutex{},
+		identities: make(map[string]domain.Identity),
+	}
+}
+
+// Insert links a new identity. An identity can only be linked once.
+func (ir *IdentityRepository) Insert(_ context.Context, identity *domain.Identity) error {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	for _, i := range ir.identities {
+		if i.Provider == identity.Provider && i.Subject == identity.Subject {
+			return domain.ErrIdentityExists
+		}
+	}
+	ir.identities[identity.ID] = *identity
+	return nil
+}
+
+// GetBySubject retrieves an identity by its provider and subject.
+func (ir *IdentityRepository) GetBySubject(
+	_ context.Context,
+	provider domain.IdentityProvider,
+	subject string,
+) (*domain.Identity, error) {
+	ir.mu.RLock()
+	defer ir.mu.RUnlock()
+	for _, i := range ir.identities {
+		if i.Provider == provider && i.Subject == subject {
+			return &i, nil
+		}
+	}
+	return nil, domain.ErrIdentityNotFound
+}
+
+// ListByCustomer retrieves the identities linked to a customer, oldest
+// first.
+func (ir *IdentityRepository) ListByCustomer(
+	_ context.Context,
+	customerID string,
+) ([]*domain.Identity, error) {
+	ir.mu.RLock()
+	defer ir.mu.RUnlock()
+	identities := make([]*domain.Identity, 0)
+	for _, i := range ir.identities {
+		if i.UserID == customerID {
+			identities = append(identities, &i)
+		}
+	}
+	slices.SortFunc(identities, func(a, b *domain.Identity) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return identities, nil
+}
+
+// UpdateLastUsed records the email and the time an identity was last used
+// with.
+func (ir *IdentityRepository) UpdateLastUsed(_ context.Context, identity *domain.Identity) error {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	i, ok := ir.identities[identity.ID]
+	if !ok {
+		return domain.ErrIdentityNotFound
+	}
+	i.Email = identity.Email
+	i.LastUsedAt = identity.LastUsedAt
+	ir.identities[i.ID] = i
+	return nil
+}
+
+// Delete unlinks an identity.
+func (ir *IdentityRepository) Delete(_ context.Context, id string) error {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	delete(ir.identities, id)
+	return nil
+}
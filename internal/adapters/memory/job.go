@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// JobRepository stores the job queue and the recurring schedules of jobs in
+// memory.
+type JobRepository struct {
+	mu        sync.Mutex
+	jobs      map[string]domain.Job
+	schedules map[string]time.Time
+	// leader is held while a runner enqueues the jobs of due schedules.
+	leader sync.Mutex
+}
+
+// NewJobRepository creates a new JobRepository.
+func NewJobRepository() *JobRepository {
+	return &JobRepository{
+		mu:        sync.Mutex{},
+		jobs:      make(map[string]domain.Job),
+		schedules: make(map[string]time.Time),
+		leader:    sync.Mutex{},
+	}
+}
+
+// Insert adds a new job to the queue. A job whose key is taken by another
+// job is not added, and no error is returned.
+func (jr *JobRepository) Insert(_ context.Context, job *domain.Job) error {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	if job.Key != "" {
+		for _, j := range jr.jobs {
+			if j.Key == job.Key {
+				return nil
+			}
+		}
+	}
+	jr.jobs[job.ID] = *job
+	return nil
+}
+
+// Update replaces a job, such as to make it due in tests.
+func (jr *JobRepository) Update(job *domain.Job) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	jr.jobs[job.ID] = *job
+}
+
+// GetByID retrieves a job by its ID.
+func (jr *JobRepository) GetByID(_ context.Context, id string) (*domain.Job, error) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	j, ok := jr.jobs[id]
+	if !ok {
+		return nil, domain.ErrJobNotFound
+	}
+	return &j, nil
+}
+
+// List returns every job in the queue, whatever its status, oldest first.
+func (jr *JobRepository) List() []*domain.Job {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	jobs := make([]*domain.Job, 0, len(jr.jobs))
+	for _, j := range jr.jobs {
+		jobs = append(jobs, &j)
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].CreatedAt.Before(jobs[k].CreatedAt) })
+	return jobs
+}
+
+// ClaimDue returns up to limit pending jobs that are due at a point in time,
+// oldest first, and pushes their next run back by lease.
+func (jr *JobRepository) ClaimDue(
+	_ context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.Job, error) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	var due []domain.Job
+	for _, j := range jr.jobs {
+		if j.Status == domain.JobStatusPending && !j.RunAt.After(at) {
+			due = append(due, j)
+		}
+	}
+	sort.Slice(due, func(i, k int) bool { return due[i].RunAt.Before(due[k].RunAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]*domain.Job, 0, len(due))
+	for _, j := range due {
+		j.RunAt = at.Add(lease)
+		jr.jobs[j.ID] = j
+		claimed = append(claimed, &j)
+	}
+	return claimed, nil
+}
+
+// MarkSucceeded marks a job as done.
+func (jr *JobRepository) MarkSucceeded(_ context.Context, id string, at time.Time) error {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	j, ok := jr.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	j.Status = domain.JobStatusSucceeded
+	j.Attempts++
+	j.FinishedAt = &at
+	jr.jobs[id] = j
+	return nil
+}
+
+// MarkFailed records a failed run at a point in time. A nil next run marks
+// the job as dead.
+func (jr *JobRepository) MarkFailed(
+	_ context.Context,
+	id string,
+	attempts int,
+	next *time.Time,
+	lastError string,
+	at time.Time,
+) error {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	j, ok := jr.jobs[id]
+	if !ok {
+		return domain.ErrJobNotFound
+	}
+	j.Attempts = attempts
+	j.LastError = lastError
+	if next == nil {
+		j.Status = domain.JobStatusDead
+		j.FinishedAt = &at
+	} else {
+		j.RunAt = *next
+	}
+	jr.jobs[id] = j
+	return nil
+}
+
+// Retry makes a dead job pending again, due at a point in time, with its
+// attempts reset.
+func (jr *JobRepository) Retry(_ context.Context, id string, at time.Time) error {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	j, ok := jr.jobs[id]
+	if !ok || j.Status != domain.JobStatusDead {
+		return domain.ErrJobNotFound
+	}
+	j.Status = domain.JobStatusPending
+	j.Attempts = 0
+	j.RunAt = at
+	j.FinishedAt = nil
+	jr.jobs[id] = j
+	return nil
+}
+
+// DeleteExpired removes every succeeded or dead job that finished before a
+// point in time and returns the number of removed jobs.
+func (jr *JobRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	var n int64
+	for id, j := range jr.jobs {
+		if j.Status != domain.JobStatusPending && j.FinishedAt != nil && j.FinishedAt.Before(before) {
+			delete(jr.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// WithLeaderLock runs fn if no other runner is running it, and reports
+// whether it did.
+func (jr *JobRepository) WithLeaderLock(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	if !jr.leader.TryLock() {
+		return false, nil
+	}
+	defer jr.leader.Unlock()
+	return true, fn(ctx)
+}
+
+// NextRun retrieves when a schedule is due next.
+func (jr *JobRepository) NextRun(_ context.Context, schedule string) (time.Time, error) {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	next, ok := jr.schedules[schedule]
+	if !ok {
+		return time.Time{}, domain.ErrJobScheduleNotFound
+	}
+	return next, nil
+}
+
+// SetNextRun records when a schedule is due next.
+func (jr *JobRepository) SetNextRun(_ context.Context, schedule string, at time.Time) error {
+	jr.mu.Lock()
+	defer jr.mu.Unlock()
+	jr.schedules[schedule] = at
+	return nil
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// MFARepository stores TOTP authenticators and recovery codes in memory.
+type MFARepository struct {
+	mu    sync.Mutex
+	mfas  map[string]domain.UserMFA
+	codes map[string][]domain.RecoveryCode
+}
+
+// NewMFARepository creates a new MFARepository.
+func NewMFARepository() *MFARepository {
+	return &MFARepository{
+		mu:    sync.Mutex{},
+		mfas:  make(map[string]domain.UserMFA),
+		codes: make(map[string][]domain.RecoveryCode),
+	}
+}
+
+// Get retrieves the authenticator of a customer.
+func (mr *MFARepository) Get(_ context.Context, userID string) (*domain.UserMFA, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	m, ok := mr.mfas[userID]
+	if !ok {
+		return nil, domain.ErrMFANotFound
+	}
+	return &m, nil
+}
+
+// Upsert stores the authenticator of a customer, replacing any other.
+func (mr *MFARepository) Upsert(_ context.Context, mfa *domain.UserMFA) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.mfas[mfa.UserID] = *mfa
+	return nil
+}
+
+// Enable enables the authenticator of a customer, and records the time step
+// of the code that confirmed it.
+func (mr *MFARepository) Enable(_ context.Context, userID string, step int64, at time.Time) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	m, ok := mr.mfas[userID]
+	if !ok {
+		return domain.ErrMFANotFound
+	}
+	m.EnabledAt = &at
+	m.LastUsedStep = step
+	mr.mfas[userID] = m
+	return nil
+}
+
+// UseStep records that a code of a time step was accepted. It returns
+// domain.ErrMFACodeUsed if a code of that step or a later one already was.
+func (mr *MFARepository) UseStep(_ context.Context, userID string, step int64) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	m, ok := mr.mfas[userID]
+	switch {
+	case !ok:
+		return domain.ErrMFANotFound
+	case m.LastUsedStep >= step:
+		return domain.ErrMFACodeUsed
+	}
+	m.LastUsedStep = step
+	mr.mfas[userID] = m
+	return nil
+}
+
+// Delete removes the authenticator and the recovery codes of a customer.
+func (mr *MFARepository) Delete(_ context.Context, userID string) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if _, ok := mr.mfas[userID]; !ok {
+		return domain.ErrMFANotFound
+	}
+	delete(mr.mfas, userID)
+	delete(mr.codes, userID)
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of a customer.
+func (mr *MFARepository) ReplaceRecoveryCodes(
+	_ context.Context,
+	userID string,
+	codes []*domain.RecoveryCode,
+) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	stored := make([]domain.RecoveryCode, len(codes))
+	for i, c := range codes {
+		stored[i] = *c
+	}
+	mr.codes[userID] = stored
+	return nil
+}
+
+// UseRecoveryCode marks the unused recovery code of a customer with a hash
+// as used. It returns domain.ErrRecoveryCodeNotFound if there is none.
+func (mr *MFARepository) UseRecoveryCode(
+	_ context.Context,
+	userID string,
+	codeHash []byte,
+	at time.Time,
+) error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	codes := mr.codes[userID]
+	for i := range codes {
+		if codes[i].UsedAt == nil && bytes.Equal(codes[i].CodeHash, codeHash) {
+			codes[i].UsedAt = &at
+			return nil
+		}
+	}
+	return domain.ErrRecoveryCodeNotFound
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a customer.
+func (mr *MFARepository) CountRecoveryCodes(_ context.Context, userID string) (int, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	n := 0
+	for _, c := range mr.codes[userID] {
+		if c.UsedAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// OAuthFlowRepository stores OAuth2 flows in memory.
+type OAuthFlowRepository struct {
+	mu    sync.Mutex
+	flows map[string]domain.OAuthFlow
+}
+
+// NewOAuthFlowRepository creates a new OAuthFlowRepository.
+func NewOAuthFlowRepository() *OAuthFlowRepository {
+	return &OAuthFlowRepository{
+		mu:    sync.Mutex{},
+		flows: make(map[string]domain.OAuthFlow),
+	}
+}
+
+// Insert adds a new flow.
+func (fr *OAuthFlowRepository) Insert(_ context.Context, flow *domain.OAuthFlow) error {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	fr.flows[flow.State] = *flow
+	return nil
+}
+
+// Take removes a flow and returns it.
+func (fr *OAuthFlowRepository) Take(_ context.Context, state string) (*domain.OAuthFlow, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	f, ok := fr.flows[state]
+	if !ok {
+		return nil, domain.ErrOAuthFlowNotFound
+	}
+	delete(fr.flows, state)
+	return &f, nil
+}
+
+// DeleteExpired removes every flow that expired before a point in time and
+// returns the number of removed flows.
+func (fr *OAuthFlowRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	fr.mu.Lock()
+	defer fr.mu.Unlock()
+	var n int64
+	for state, f := range fr.flows {
+		if f.Expired(before) {
+			delete(fr.flows, state)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// OrderRepository stores orders in memory.
+type OrderRepository struct {
+	mu     sync.RWMutex
+	orders map[string]domain.Order
+}
+
+// NewOrderRepository creates a new OrderRepository.
+func NewOrderRepository() *OrderRepository {
+	return &OrderRepository{
+		mu:     sync.RWMutex{},
+		orders: make(map[string]domain.Order),
+	}
+}
+
+// Insert adds a new order along with its items.
+func (or *OrderRepository) Insert(_ context.Context, order *domain.Order) error {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	o := *order
+	o.Items = slices.Clone(order.Items)
+	or.orders[o.ID] = o
+	return nil
+}
+
+// GetByID retrieves an order and its items by the order's ID.
+func (or *OrderRepository) GetByID(_ context.Context, id string) (*domain.Order, error) {
+	or.mu.RLock()
+	defer or.mu.RUnlock()
+	o, ok := or.orders[id]
+	if !ok {
+		return nil, domain.ErrOrderNotFound
+	}
+	return cloneOrder(o), nil
+}
+
+// GetByOrderNumber retrieves an order and its items by its public order
+// number.
+func (or *OrderRepository) GetByOrderNumber(_ context.Context, number string) (*domain.Order, error) {
+	or.mu.RLock()
+	defer or.mu.RUnlock()
+	for _, o := range or.orders {
+		if o.OrderNumber == number {
+			return cloneOrder(o), nil
+		}
+	}
+	return nil, domain.ErrOrderNotFound
+}
+
+// ListByCustomer retrieves every order of a customer along with its items,
+// most recent first.
+func (or *OrderRepository) ListByCustomer(_ context.Context, customerID string) ([]*domain.Order, error) {
+	or.mu.RLock()
+	defer or.mu.RUnlock()
+	orders := make([]*domain.Order, 0)
+	for _, o := range or.orders {
+		if o.UserID == customerID {
+			orders = append(orders, cloneOrder(o))
+		}
+	}
+	slices.SortFunc(orders, func(a, b *domain.Order) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return orders, nil
+}
+
+// UpdateStatus changes the fulfillment status of an order and of all of its
+// items.
+func (or *OrderRepository) UpdateStatus(_ context.Context, id string, status domain.FulfillmentStatus) error {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	o, ok := or.orders[id]
+	if !ok {
+		return domain.ErrOrderNotFound
+	}
+	or.setStatus(o, status)
+	return nil
+}
+
+// UpdateStatusFrom changes the status of an order and of all of its items
+// from one status to another. It returns domain.ErrOrderStatusChanged if the
+// order is not in the status from.
+func (or *OrderRepository) UpdateStatusFrom(_ context.Context, id string, from, to domain.FulfillmentStatus) error {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	o, ok := or.orders[id]
+	if !ok {
+		return domain.ErrOrderNotFound
+	}
+	if o.Status != from {
+		return domain.ErrOrderStatusChanged
+	}
+	or.setStatus(o, to)
+	return nil
+}
+
+// setStatus stores o with the status of it and of all of its items set to
+// status. The caller must hold the lock.
+func (or *OrderRepository) setStatus(o domain.Order, status domain.FulfillmentStatus) {
+	now := time.Now().UTC()
+	o.Status = status
+	o.UpdatedAt = now
+	o.Items = slices.Clone(o.Items)
+	for i := range o.Items {
+		o.Items[i].Status = status
+		o.Items[i].UpdatedAt = now
+	}
+	or.orders[o.ID] = o
+}
+
+// AnonymizeByCustomer replaces the billing details of every order of a
+// customer: the billing email by email, and the billing name by nothing.
+// Amounts and items are kept for accounting.
+func (or *OrderRepository) AnonymizeByCustomer(_ context.Context, customerID, email string) error {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	now := time.Now().UTC()
+	for id, o := range or.orders {
+		if o.UserID == customerID {
+			o.BillingEmail = email
+			o.BillingName = ""
+			o.UpdatedAt = now
+			or.orders[id] = o
+		}
+	}
+	return nil
+}
+
+func cloneOrder(o domain.Order) *domain.Order {
+	o.Items = slices.Clone(o.Items)
+	return &o
+}
@@ -0,0 +1,134 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// EmailOutboxRepository stores emails waiting to be delivered in memory.
+type EmailOutboxRepository struct {
+	mu     sync.Mutex
+	emails map[string]domain.OutboxEmail
+}
+
+// NewEmailOutboxRepository creates a new EmailOutboxRepository.
+func NewEmailOutboxRepository() *EmailOutboxRepository {
+	return &EmailOutboxRepository{
+		mu:     sync.Mutex{},
+		emails: make(map[string]domain.OutboxEmail),
+	}
+}
+
+// Insert adds a new email to the outbox.
+func (er *EmailOutboxRepository) Insert(_ context.Context, email *domain.OutboxEmail) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	er.emails[email.ID] = *email
+	return nil
+}
+
+// List returns every email in the outbox, whatever its status.
+func (er *EmailOutboxRepository) List() []*domain.OutboxEmail {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	emails := make([]*domain.OutboxEmail, 0, len(er.emails))
+	for _, e := range er.emails {
+		emails = append(emails, &e)
+	}
+	return emails
+}
+
+// ClaimDue returns up to limit pending emails that are due at a point in
+// time, oldest first, and pushes their next attempt back by lease.
+func (er *EmailOutboxRepository) ClaimDue(
+	_ context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.OutboxEmail, error) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	var due []domain.OutboxEmail
+	for _, e := range er.emails {
+		if e.Status == domain.EmailStatusPending && !e.NextAttemptAt.After(at) {
+			due = append(due, e)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]*domain.OutboxEmail, 0, len(due))
+	for _, e := range due {
+		e.NextAttemptAt = at.Add(lease)
+		er.emails[e.ID] = e
+		claimed = append(claimed, &e)
+	}
+	return claimed, nil
+}
+
+// MarkSent marks an email as delivered and discards its body.
+func (er *EmailOutboxRepository) MarkSent(_ context.Context, id string, at time.Time) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	e, ok := er.emails[id]
+	if !ok {
+		return domain.ErrEmailNotFound
+	}
+	e.Status = domain.EmailStatusSent
+	e.Attempts++
+	e.SentAt = &at
+	e.Text = ""
+	e.HTML = ""
+	er.emails[id] = e
+	return nil
+}
+
+// MarkFailed records a failed attempt. A nil next attempt marks the email as
+// dead.
+func (er *EmailOutboxRepository) MarkFailed(
+	_ context.Context,
+	id string,
+	attempts int,
+	next *time.Time,
+	lastError string,
+) error {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	e, ok := er.emails[id]
+	if !ok {
+		return domain.ErrEmailNotFound
+	}
+	e.Attempts = attempts
+	e.LastError = lastError
+	if next == nil {
+		e.Status = domain.EmailStatusDead
+	} else {
+		e.NextAttemptAt = *next
+	}
+	er.emails[id] = e
+	return nil
+}
+
+// DeleteExpired removes every sent or dead email created before a point in
+// time and returns the number of removed emails.
+func (er *EmailOutboxRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	er.mu.Lock()
+	defer er.mu.Unlock()
+	var n int64
+	for id, e := range er.emails {
+		if e.Status != domain.EmailStatusPending && e.CreatedAt.Before(before) {
+			delete(er.emails, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// PasskeyRepository stores the passkeys of customers in memory.
+type PasskeyRepository struct {
+	mu       sync.RWMutex
+	passkeys map[string]domain.Passkey
+}
+
+// NewPasskeyRepository creates a new PasskeyRepository.
+func NewPasskeyRepository() *PasskeyRepository {
+	return &PasskeyRepository{
+		mu:       sync.RWMutex{},
+		passkeys: make(map[string]domain.Passkey),
+	}
+}
+
+// Insert adds a new passkey. A credential can only be registered once.
+func (pr *PasskeyRepository) Insert(_ context.Context, passkey *domain.Passkey) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	for _, p := range pr.passkeys {
+		if bytes.Equal(p.CredentialID, passkey.CredentialID) {
+			return domain.ErrPasskeyExists
+		}
+	}
+	pr.passkeys[passkey.ID] = *passkey
+	return nil
+}
+
+// GetByCredentialID retrieves a passkey by its credential ID.
+func (pr *PasskeyRepository) GetByCredentialID(
+	_ context.Context,
+	credentialID []byte,
+) (*domain.Passkey, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	for _, p := range pr.passkeys {
+		if bytes.Equal(p.CredentialID, credentialID) {
+			return &p, nil
+		}
+	}
+	return nil, domain.ErrPasskeyNotFound
+}
+
+// ListByCustomer retrieves the passkeys of a customer, oldest first.
+func (pr *PasskeyRepository) ListByCustomer(
+	_ context.Context,
+	customerID string,
+) ([]*domain.Passkey, error) {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	passkeys := make([]*domain.Passkey, 0)
+	for _, p := range pr.passkeys {
+		if p.UserID == customerID {
+			passkeys = append(passkeys, &p)
+		}
+	}
+	slices.SortFunc(passkeys, func(a, b *domain.Passkey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return passkeys, nil
+}
+
+// UpdateUsage records the signature counter a passkey last reported and the
+// time it was used at.
+func (pr *PasskeyRepository) UpdateUsage(
+	_ context.Context,
+	id string,
+	signCount uint32,
+	at time.Time,
+) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	p, ok := pr.passkeys[id]
+	if !ok {
+		return domain.ErrPasskeyNotFound
+	}
+	p.SignCount = signCount
+	p.LastUsedAt = &at
+	pr.passkeys[id] = p
+	return nil
+}
+
+// Delete removes a passkey.
+func (pr *PasskeyRepository) Delete(_ context.Context, id string) error {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+	delete(pr.passkeys, id)
+	return nil
+}
+
+// PasskeyCeremonyRepository stores WebAuthn ceremonies in memory.
+type PasskeyCeremonyRepository struct {
+	mu         sync.Mutex
+	ceremonies map[string]domain.PasskeyCeremony
+}
+
+// NewPasskeyCeremonyRepository creates a new PasskeyCeremonyRepository.
+func NewPasskeyCeremonyRepository() *PasskeyCeremonyRepository {
+	return &PasskeyCeremonyRepository{
+		mu:         sync.Mutex{},
+		ceremonies: make(map[string]domain.PasskeyCeremony),
+	}
+}
+
+// Insert adds a new ceremony.
+func (cr *PasskeyCeremonyRepository) Insert(_ context.Context, ceremony *domain.PasskeyCeremony) error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.ceremonies[ceremony.ID] = *ceremony
+	return nil
+}
+
+// Take removes a ceremony and returns it.
+func (cr *PasskeyCeremonyRepository) Take(_ context.Context, id string) (*domain.PasskeyCeremony, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	c, ok := cr.ceremonies[id]
+	if !ok {
+		return nil, domain.ErrPasskeyCeremonyNotFound
+	}
+	delete(cr.ceremonies, id)
+	return &c, nil
+}
+
+// DeleteExpired removes every ceremony that expired before a point in time
+// and returns the number of removed ceremonies.
+func (cr *PasskeyCeremonyRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	var n int64
+	for id, c := range cr.ceremonies {
+		if c.Expired(before) {
+			delete(cr.ceremonies, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,156 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package memory implements in-memory adapters. They are meant for tests and
+// local development, and lose all data when the process exits.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// SessionRepository stores customer sessions in memory.
+type SessionRepository struct {
+	mu       sync.RWMutex
+	sessions map[string]domain.UserSession
+}
+
+// NewSessionRepository creates a new SessionRepository.
+func NewSessionRepository() *SessionRepository {
+	return &SessionRepository{
+		mu:       sync.RWMutex{},
+		sessions: make(map[string]domain.UserSession),
+	}
+}
+
+// Insert adds a new session. The token is never stored.
+func (sr *SessionRepository) Insert(_ context.Context, session *domain.UserSession) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	s := *session
+	s.Token = ""
+	sr.sessions[s.ID] = s
+	return nil
+}
+
+// GetByID retrieves a session by its ID.
+func (sr *SessionRepository) GetByID(_ context.Context, id string) (*domain.UserSession, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	s, ok := sr.sessions[id]
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+	return &s, nil
+}
+
+// ListByCustomer retrieves all unexpired sessions of a customer, most
+// recently used first.
+func (sr *SessionRepository) ListByCustomer(
+	_ context.Context,
+	customerID string,
+) ([]*domain.UserSession, error) {
+	sr.mu.RLock()
+	defer sr.mu.RUnlock()
+	now := time.Now()
+	sessions := make([]*domain.UserSession, 0)
+	for _, s := range sr.sessions {
+		if s.UserID == customerID && !s.Expired(now) {
+			sessions = append(sessions, &s)
+		}
+	}
+	slices.SortFunc(sessions, func(a, b *domain.UserSession) int {
+		return b.LastSeenAt.Compare(a.LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// Touch records when a session was last used.
+func (sr *SessionRepository) Touch(_ context.Context, id string, lastSeenAt time.Time) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	s, ok := sr.sessions[id]
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+	s.LastSeenAt = lastSeenAt
+	sr.sessions[id] = s
+	return nil
+}
+
+// Rotate writes the renewed secret, expiry and last seen time of a session,
+// if the stored secret is still the one it was renewed from.
+func (sr *SessionRepository) Rotate(_ context.Context, session *domain.UserSession) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	s, ok := sr.sessions[session.ID]
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+	if !bytes.Equal(s.SecretHash, session.PreviousSecretHash) {
+		return domain.ErrSessionRotated
+	}
+	s.SecretHash = session.SecretHash
+	s.PreviousSecretHash = session.PreviousSecretHash
+	s.RotatedAt = session.RotatedAt
+	s.LastSeenAt = session.LastSeenAt
+	s.ExpiresAt = session.ExpiresAt
+	sr.sessions[s.ID] = s
+	return nil
+}
+
+// MarkAuthenticated records when the customer last proved their
+// credentials in a session.
+func (sr *SessionRepository) MarkAuthenticated(_ context.Context, id string, at time.Time) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	s, ok := sr.sessions[id]
+	if !ok {
+		return domain.ErrSessionNotFound
+	}
+	s.AuthenticatedAt = at
+	sr.sessions[id] = s
+	return nil
+}
+
+// Delete removes a session.
+func (sr *SessionRepository) Delete(_ context.Context, id string) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	delete(sr.sessions, id)
+	return nil
+}
+
+// DeleteByCustomer removes every session of a customer.
+func (sr *SessionRepository) DeleteByCustomer(_ context.Context, customerID string) error {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for id, s := range sr.sessions {
+		if s.UserID == customerID {
+			delete(sr.sessions, id)
+		}
+	}
+	return nil
+}
+
+// DeleteExpired removes every session that expired before a point in time
+// and returns the number of removed sessions.
+func (sr *SessionRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	var n int64
+	for id, s := range sr.sessions {
+		if s.Expired(before) {
+			delete(sr.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
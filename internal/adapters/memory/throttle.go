@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// LoginThrottleRepository stores login throttles in memory.
+type LoginThrottleRepository struct {
+	mu        sync.Mutex
+	throttles map[string]domain.LoginThrottle
+}
+
+// NewLoginThrottleRepository creates a new LoginThrottleRepository.
+func NewLoginThrottleRepository() *LoginThrottleRepository {
+	return &LoginThrottleRepository{
+		mu:        sync.Mutex{},
+		throttles: make(map[string]domain.LoginThrottle),
+	}
+}
+
+// Get retrieves the throttle of a key.
+func (lr *LoginThrottleRepository) Get(_ context.Context, key string) (*domain.LoginThrottle, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	l, ok := lr.throttles[key]
+	if !ok {
+		return nil, domain.ErrThrottleNotFound
+	}
+	return &l, nil
+}
+
+// RecordFailure counts a failed attempt with a key at a point in time.
+// Failures older than the window are forgotten first.
+func (lr *LoginThrottleRepository) RecordFailure(
+	_ context.Context,
+	key string,
+	at time.Time,
+	window time.Duration,
+) (*domain.LoginThrottle, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	l, ok := lr.throttles[key]
+	if !ok || !l.LastFailureAt.After(at.Add(-window)) {
+		l.Key = key
+		l.Failures = 0
+	}
+	l.Failures++
+	l.LastFailureAt = at
+	l.ExpiresAt = at.Add(window)
+	if l.LockedUntil.After(l.ExpiresAt) {
+		l.ExpiresAt = l.LockedUntil
+	}
+	lr.throttles[key] = l
+	return &l, nil
+}
+
+// Lock locks a key out until a point in time.
+func (lr *LoginThrottleRepository) Lock(_ context.Context, key string, until time.Time) error {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	l, ok := lr.throttles[key]
+	if !ok {
+		return domain.ErrThrottleNotFound
+	}
+	l.LockedUntil = until
+	if until.After(l.ExpiresAt) {
+		l.ExpiresAt = until
+	}
+	lr.throttles[key] = l
+	return nil
+}
+
+// Reset forgets the failures and the lockout of a key.
+func (lr *LoginThrottleRepository) Reset(_ context.Context, key string) error {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	delete(lr.throttles, key)
+	return nil
+}
+
+// DeleteExpired removes every throttle that expired before a point in time
+// and returns the number of removed throttles.
+func (lr *LoginThrottleRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	var n int64
+	for key, l := range lr.throttles {
+		if !l.ExpiresAt.After(before) {
+			delete(lr.throttles, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,117 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// TokenRepository stores one-time tokens in memory.
+type TokenRepository struct {
+	mu     sync.Mutex
+	tokens map[string]domain.OneTimeToken
+}
+
+// NewTokenRepository creates a new TokenRepository.
+func NewTokenRepository() *TokenRepository {
+	return &TokenRepository{
+		mu:     sync.Mutex{},
+		tokens: make(map[string]domain.OneTimeToken),
+	}
+}
+
+// Insert adds a new token. The token itself is never stored.
+func (tr *TokenRepository) Insert(_ context.Context, token *domain.OneTimeToken) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	t := *token
+	t.Token = ""
+	tr.tokens[t.ID] = t
+	return nil
+}
+
+// GetByID retrieves a token by its ID.
+func (tr *TokenRepository) GetByID(_ context.Context, id string) (*domain.OneTimeToken, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	t, ok := tr.tokens[id]
+	if !ok {
+		return nil, domain.ErrTokenNotFound
+	}
+	return &t, nil
+}
+
+// MarkUsed marks an unused token as used.
+func (tr *TokenRepository) MarkUsed(_ context.Context, id string, at time.Time) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	t, ok := tr.tokens[id]
+	switch {
+	case !ok:
+		return domain.ErrTokenNotFound
+	case t.UsedAt != nil:
+		return domain.ErrTokenUsed
+	}
+	t.UsedAt = &at
+	tr.tokens[id] = t
+	return nil
+}
+
+// RevokeByCustomer marks every unused token of a customer for a purpose as
+// used.
+func (tr *TokenRepository) RevokeByCustomer(
+	_ context.Context,
+	customerID string,
+	purpose domain.TokenPurpose,
+	at time.Time,
+) error {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	for id, t := range tr.tokens {
+		if t.UserID == customerID && t.Purpose == purpose && t.UsedAt == nil {
+			t.UsedAt = &at
+			tr.tokens[id] = t
+		}
+	}
+	return nil
+}
+
+// CountSince counts the tokens issued to a customer for a purpose since a
+// point in time.
+func (tr *TokenRepository) CountSince(
+	_ context.Context,
+	customerID string,
+	purpose domain.TokenPurpose,
+	since time.Time,
+) (int, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	n := 0
+	for _, t := range tr.tokens {
+		if t.UserID == customerID && t.Purpose == purpose && !t.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// DeleteExpired removes every token that expired before a point in time and
+// returns the number of removed tokens.
+func (tr *TokenRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	var n int64
+	for id, t := range tr.tokens {
+		if t.Expired(before) {
+			delete(tr.tokens, id)
+			n++
+		}
+	}
+	return n, nil
+}
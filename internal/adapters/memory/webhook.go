@@ -0,0 +1,394 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+)
+
+// WebhookEndpointRepository stores webhook endpoints in memory.
+type WebhookEndpointRepository struct {
+	mu        sync.Mutex
+	endpoints map[string]domain.WebhookEndpoint
+	// deliveries, if set, loses the deliveries of deleted endpoints, like
+	// the foreign key of the database.
+	deliveries *WebhookDeliveryRepository
+}
+
+// NewWebhookEndpointRepository creates a new WebhookEndpointRepository.
+// Deleting an endpoint deletes its deliveries from a delivery repository,
+// if one is given.
+func NewWebhookEndpointRepository(deliveries *WebhookDeliveryRepository) *WebhookEndpointRepository {
+	return &WebhookEndpointRepository{
+		mu:         sync.Mutex{},
+		endpoints:  make(map[string]domain.WebhookEndpoint),
+		deliveries: deliveries,
+	}
+}
+
+// Insert adds a new endpoint.
+func (wr *WebhookEndpointRepository) Insert(_ context.Context, endpoint *domain.WebhookEndpoint) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.endpoints[endpoint.ID] = copyWebhookEndpoint(endpoint)
+	return nil
+}
+
+// GetByID retrieves an endpoint by its ID.
+func (wr *WebhookEndpointRepository) GetByID(_ context.Context, id string) (*domain.WebhookEndpoint, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	e, ok := wr.endpoints[id]
+	if !ok {
+		return nil, domain.ErrWebhookEndpointNotFound
+	}
+	e = copyWebhookEndpoint(&e)
+	return &e, nil
+}
+
+// List retrieves every endpoint, oldest first.
+func (wr *WebhookEndpointRepository) List(_ context.Context) ([]*domain.WebhookEndpoint, error) {
+	return wr.list(func(*domain.WebhookEndpoint) bool { return true }), nil
+}
+
+// ListSubscribed retrieves the enabled endpoints that subscribe to a type of
+// event, oldest first.
+func (wr *WebhookEndpointRepository) ListSubscribed(
+	_ context.Context,
+	typ domain.EventType,
+) ([]*domain.WebhookEndpoint, error) {
+	return wr.list(func(e *domain.WebhookEndpoint) bool { return e.Enabled && e.Subscribes(typ) }), nil
+}
+
+func (wr *WebhookEndpointRepository) list(keep func(*domain.WebhookEndpoint) bool) []*domain.WebhookEndpoint {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	endpoints := make([]*domain.WebhookEndpoint, 0, len(wr.endpoints))
+	for _, e := range wr.endpoints {
+		if keep(&e) {
+			e = copyWebhookEndpoint(&e)
+			endpoints = append(endpoints, &e)
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt) })
+	return endpoints
+}
+
+// Update replaces an endpoint.
+func (wr *WebhookEndpointRepository) Update(_ context.Context, endpoint *domain.WebhookEndpoint) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	if _, ok := wr.endpoints[endpoint.ID]; !ok {
+		return domain.ErrWebhookEndpointNotFound
+	}
+	wr.endpoints[endpoint.ID] = copyWebhookEndpoint(endpoint)
+	return nil
+}
+
+// Delete removes an endpoint along with its deliveries.
+func (wr *WebhookEndpointRepository) Delete(_ context.Context, id string) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	if _, ok := wr.endpoints[id]; !ok {
+		return domain.ErrWebhookEndpointNotFound
+	}
+	delete(wr.endpoints, id)
+	if wr.deliveries != nil {
+		wr.deliveries.deleteByEndpoint(id)
+	}
+	return nil
+}
+
+// RecordSuccess forgets the failures of an endpoint.
+func (wr *WebhookEndpointRepository) RecordSuccess(_ context.Context, id string) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	e, ok := wr.endpoints[id]
+	if !ok {
+		return domain.ErrWebhookEndpointNotFound
+	}
+	e.ConsecutiveFailures = 0
+	e.FailingSince = nil
+	wr.endpoints[id] = e
+	return nil
+}
+
+// RecordFailure counts a failed attempt at a point in time, and returns the
+// endpoint with its failures counted.
+func (wr *WebhookEndpointRepository) RecordFailure(
+	_ context.Context,
+	id string,
+	at time.Time,
+) (*domain.WebhookEndpoint, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	e, ok := wr.endpoints[id]
+	if !ok {
+		return nil, domain.ErrWebhookEndpointNotFound
+	}
+	e.ConsecutiveFailures++
+	if e.FailingSince == nil {
+		e.FailingSince = &at
+	}
+	wr.endpoints[id] = e
+	e = copyWebhookEndpoint(&e)
+	return &e, nil
+}
+
+// copyWebhookEndpoint copies an endpoint, so that callers cannot change the
+// stored one through its slices.
+func copyWebhookEndpoint(e *domain.WebhookEndpoint) domain.WebhookEndpoint {
+	c := *e
+	c.EventTypes = slices.Clone(e.EventTypes)
+	c.EncryptedSecret = slices.Clone(e.EncryptedSecret)
+	return c
+}
+
+// WebhookDeliveryRepository stores webhook deliveries and the logs of their
+// attempts in memory.
+type WebhookDeliveryRepository struct {
+	mu         sync.Mutex
+	deliveries map[string]domain.WebhookDelivery
+	attempts   map[string][]domain.WebhookAttempt
+}
+
+// NewWebhookDeliveryRepository creates a new WebhookDeliveryRepository.
+func NewWebhookDeliveryRepository() *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{
+		mu:         sync.Mutex{},
+		deliveries: make(map[string]domain.WebhookDelivery),
+		attempts:   make(map[string][]domain.WebhookAttempt),
+	}
+}
+
+// Insert adds a new delivery. A delivery of an event that was already queued
+// for the endpoint is not added, and no error is returned.
+func (wr *WebhookDeliveryRepository) Insert(_ context.Context, delivery *domain.WebhookDelivery) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	for _, d := range wr.deliveries {
+		if d.EndpointID == delivery.EndpointID && d.EventID == delivery.EventID {
+			return nil
+		}
+	}
+	wr.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+// Update replaces a delivery, such as to make it due in tests.
+func (wr *WebhookDeliveryRepository) Update(delivery *domain.WebhookDelivery) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.deliveries[delivery.ID] = *delivery
+}
+
+// GetByID retrieves a delivery by its ID.
+func (wr *WebhookDeliveryRepository) GetByID(_ context.Context, id string) (*domain.WebhookDelivery, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	d, ok := wr.deliveries[id]
+	if !ok {
+		return nil, domain.ErrWebhookDeliveryNotFound
+	}
+	return &d, nil
+}
+
+// List returns every delivery, whatever its status, oldest first.
+func (wr *WebhookDeliveryRepository) List() []*domain.WebhookDelivery {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	deliveries := make([]*domain.WebhookDelivery, 0, len(wr.deliveries))
+	for _, d := range wr.deliveries {
+		deliveries = append(deliveries, &d)
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.Before(deliveries[j].CreatedAt) })
+	return deliveries
+}
+
+// ListByEndpoint retrieves up to limit deliveries to an endpoint, most recent
+// first.
+func (wr *WebhookDeliveryRepository) ListByEndpoint(
+	_ context.Context,
+	endpointID string,
+	limit int,
+) ([]*domain.WebhookDelivery, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	var deliveries []*domain.WebhookDelivery
+	for _, d := range wr.deliveries {
+		if d.EndpointID == endpointID {
+			deliveries = append(deliveries, &d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// ClaimDue returns up to limit pending deliveries that are due at a point in
+// time, oldest first, and pushes their next attempt back by lease.
+func (wr *WebhookDeliveryRepository) ClaimDue(
+	_ context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.WebhookDelivery, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	var due []domain.WebhookDelivery
+	for _, d := range wr.deliveries {
+		if d.Status == domain.WebhookDeliveryStatusPending && !d.NextAttemptAt.After(at) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]*domain.WebhookDelivery, 0, len(due))
+	for _, d := range due {
+		d.NextAttemptAt = at.Add(lease)
+		wr.deliveries[d.ID] = d
+		claimed = append(claimed, &d)
+	}
+	return claimed, nil
+}
+
+// MarkDelivered marks a delivery as accepted by its endpoint, if it is
+// still leased until leasedUntil.
+func (wr *WebhookDeliveryRepository) MarkDelivered(_ context.Context, id string, leasedUntil, at time.Time) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	d, err := wr.leased(id, leasedUntil)
+	if err != nil {
+		return err
+	}
+	d.Status = domain.WebhookDeliveryStatusDelivered
+	d.Attempts++
+	d.LastError = ""
+	d.DeliveredAt = &at
+	wr.deliveries[id] = d
+	return nil
+}
+
+// MarkFailed records a failed attempt, if the delivery is still leased until
+// leasedUntil. A nil next attempt marks the delivery as dead.
+func (wr *WebhookDeliveryRepository) MarkFailed(
+	_ context.Context,
+	id string,
+	leasedUntil time.Time,
+	attempts int,
+	next *time.Time,
+	lastError string,
+) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	d, err := wr.leased(id, leasedUntil)
+	if err != nil {
+		return err
+	}
+	d.Attempts = attempts
+	d.LastError = lastError
+	if next == nil {
+		d.Status = domain.WebhookDeliveryStatusDead
+	} else {
+		d.NextAttemptAt = *next
+	}
+	wr.deliveries[id] = d
+	return nil
+}
+
+// leased returns a delivery that is pending and leased until leasedUntil.
+// The caller must hold the lock.
+func (wr *WebhookDeliveryRepository) leased(id string, leasedUntil time.Time) (domain.WebhookDelivery, error) {
+	d, ok := wr.deliveries[id]
+	if !ok {
+		return d, domain.ErrWebhookDeliveryNotFound
+	}
+	if d.Status != domain.WebhookDeliveryStatusPending || !d.NextAttemptAt.Equal(leasedUntil) {
+		return d, domain.ErrWebhookLeaseExpired
+	}
+	return d, nil
+}
+
+// Redeliver makes a delivery pending again, due at a point in time, with its
+// attempts reset.
+func (wr *WebhookDeliveryRepository) Redeliver(_ context.Context, id string, at time.Time) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	d, ok := wr.deliveries[id]
+	if !ok {
+		return domain.ErrWebhookDeliveryNotFound
+	}
+	d.Status = domain.WebhookDeliveryStatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = at
+	d.DeliveredAt = nil
+	wr.deliveries[id] = d
+	return nil
+}
+
+// InsertAttempt logs an attempt at a delivery, and numbers it after the
+// earlier attempts.
+func (wr *WebhookDeliveryRepository) InsertAttempt(_ context.Context, attempt *domain.WebhookAttempt) error {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	if _, ok := wr.deliveries[attempt.DeliveryID]; !ok {
+		return domain.ErrWebhookDeliveryNotFound
+	}
+	attempt.Number = len(wr.attempts[attempt.DeliveryID]) + 1
+	wr.attempts[attempt.DeliveryID] = append(wr.attempts[attempt.DeliveryID], *attempt)
+	return nil
+}
+
+// ListAttempts retrieves the attempts at a delivery, in order.
+func (wr *WebhookDeliveryRepository) ListAttempts(
+	_ context.Context,
+	deliveryID string,
+) ([]*domain.WebhookAttempt, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	attempts := make([]*domain.WebhookAttempt, 0, len(wr.attempts[deliveryID]))
+	for _, a := range wr.attempts[deliveryID] {
+		attempts = append(attempts, &a)
+	}
+	return attempts, nil
+}
+
+// DeleteExpired removes every delivered or dead delivery created before a
+// point in time, along with its attempts, and returns the number of removed
+// deliveries.
+func (wr *WebhookDeliveryRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	var n int64
+	for id, d := range wr.deliveries {
+		if d.Status != domain.WebhookDeliveryStatusPending && d.CreatedAt.Before(before) {
+			delete(wr.deliveries, id)
+			delete(wr.attempts, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (wr *WebhookDeliveryRepository) deleteByEndpoint(endpointID string) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	for id, d := range wr.deliveries {
+		if d.EndpointID == endpointID {
+			delete(wr.deliveries, id)
+			delete(wr.attempts, id)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// GitHub endpoints.
+const (
+	GitHubAuthURL  = "https://github.com/login/oauth/authorize"
+	GitHubTokenURL = "https://github.com/login/oauth/access_token"
+	GitHubAPIURL   = "https://api.github.com"
+)
+
+// GitHub is the GitHub identity provider. GitHub issues no ID tokens, so the
+// identity is read from the GitHub API with the access token of the flow. The
+// numeric GitHub user ID is used as the subject, since logins can be renamed.
+type GitHub struct {
+	cfg      Config
+	client   *http.Client
+	authURL  string
+	tokenURL string
+	apiURL   string
+}
+
+// NewGitHub creates a new GitHub provider. The issuer of the config is
+// ignored.
+func NewGitHub(cfg Config) *GitHub {
+	return NewGitHubWithEndpoints(cfg, GitHubAuthURL, GitHubTokenURL, GitHubAPIURL)
+}
+
+// NewGitHubWithEndpoints creates a new GitHub provider that talks to custom
+// endpoints, such as a GitHub Enterprise server.
+func NewGitHubWithEndpoints(cfg Config, authURL, tokenURL, apiURL string) *GitHub {
+	return &GitHub{
+		cfg:      cfg,
+		client:   cfg.httpClient(),
+		authURL:  authURL,
+		tokenURL: tokenURL,
+		apiURL:   apiURL,
+	}
+}
+
+// Provider returns the identity provider.
+func (g *GitHub) Provider() domain.IdentityProvider {
+	return domain.IdentityProviderGitHub
+}
+
+// AuthCodeURL returns the URL the customer is sent to in order to
+// authenticate with GitHub.
+func (g *GitHub) AuthCodeURL(flow *domain.OAuthFlow) string {
+	scopes := append([]string{"read:user", "user:email"}, g.cfg.Scopes...)
+	return authCodeURL(g.authURL, g.cfg, flow, scopes, nil)
+}
+
+type gitHubUser struct {
+	ID int64 `json:"id"`
+}
+
+type gitHubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// Exchange exchanges an authorization code for an access token and reads the
+// customer's identity from the GitHub API.
+func (g *GitHub) Exchange(
+	ctx context.Context,
+	code string,
+	flow *domain.OAuthFlow,
+) (*domain.ExternalIdentity, error) {
+	token, err := exchangeCode(ctx, g.client, g.tokenURL, g.cfg, code, flow)
+	if err != nil {
+		return nil, err
+	}
+
+	var user gitHubUser
+	err = g.get(ctx, token.AccessToken, "/user", &user)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get github user")
+	}
+	if user.ID == 0 {
+		return nil, errors.New("github returned no user ID")
+	}
+
+	var emails []gitHubEmail
+	err = g.get(ctx, token.AccessToken, "/user/emails", &emails)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get github user emails")
+	}
+
+	identity := &domain.ExternalIdentity{
+		Provider:      domain.IdentityProviderGitHub,
+		Subject:       strconv.FormatInt(user.ID, 10),
+		Email:         "",
+		EmailVerified: false,
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+	return identity, nil
+}
+
+func (g *GitHub) get(ctx context.Context, accessToken, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.apiURL+path, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return doJSON(g.client, req, v)
+}
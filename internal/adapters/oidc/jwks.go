@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// KeySet is a JSON Web Key Set fetched from a provider and cached.
+//
+// Providers rotate their signing keys by publishing the new key next to the
+// old one before signing with it. The cache is refreshed when it is older
+// than maxAge, or when a token names a key ID the cache does not know. The
+// latter refresh is rate limited by minRefresh, so that tokens with made up
+// key IDs cannot make the backend hammer the provider.
+type KeySet struct {
+	uri        string
+	client     *http.Client
+	maxAge     time.Duration
+	minRefresh time.Duration
+	now        func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+}
+
+const (
+	defaultKeySetMaxAge     = time.Hour
+	defaultKeySetMinRefresh = 30 * time.Second
+)
+
+// NewKeySet creates a new KeySet that fetches keys from a JWKS URI.
+func NewKeySet(uri string, client *http.Client) *KeySet {
+	return &KeySet{
+		uri:        uri,
+		client:     client,
+		maxAge:     defaultKeySetMaxAge,
+		minRefresh: defaultKeySetMinRefresh,
+		now:        time.Now,
+		mu:         sync.Mutex{},
+		keys:       nil,
+		fetchedAt:  time.Time{},
+	}
+}
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key returns the public key with a key ID.
+func (k *KeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	key, known := k.keys[kid]
+	stale := now.Sub(k.fetchedAt) >= k.maxAge
+	if known && !stale {
+		return key, nil
+	}
+	if !stale && now.Sub(k.fetchedAt) < k.minRefresh {
+		return nil, errors.Wrapf(ErrUnknownKey, "key ID %q", kid)
+	}
+
+	err := k.refresh(ctx)
+	if err != nil {
+		if known {
+			// Keep using a known key while the provider is unreachable.
+			return key, nil
+		}
+		return nil, err
+	}
+
+	key, known = k.keys[kid]
+	if !known {
+		return nil, errors.Wrapf(ErrUnknownKey, "key ID %q", kid)
+	}
+	return key, nil
+}
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// refresh replaces the cached keys with the keys the provider publishes. Keys
+// the provider no longer publishes are dropped.
+func (k *KeySet) refresh(ctx context.Context) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := getJSON(ctx, k.client, k.uri, &set)
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch key set")
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip keys of unsupported types rather than failing the whole
+			// set.
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	k.keys = keys
+	k.fetchedAt = k.now()
+	return nil
+}
+
+func (j jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch j.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(j.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(j.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if j.Curve != "P-256" {
+			return nil, errors.New("unsupported curve")
+		}
+		x, err := decodeBigInt(j.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(j.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		//nolint:staticcheck // There is no replacement that takes coordinates.
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+		return key, nil
+	case "OKP":
+		if j.Curve != "Ed25519" {
+			return nil, errors.New("unsupported curve")
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// maxResponseSize bounds how much of a provider response is read.
+const maxResponseSize = 1 << 20
+
+func getJSON(ctx context.Context, client *http.Client, uri string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "application/json")
+	return doJSON(client, req, v)
+}
+
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to do request")
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseSize))
+	if err != nil {
+		return errors.Wrap(err, "failed to read response body")
+	}
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("unexpected status %d from %s", res.StatusCode, req.URL.Redacted())
+	}
+	return errors.Wrap(json.Unmarshal(body, v), "failed to decode response body")
+}
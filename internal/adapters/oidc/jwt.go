@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// Claims are the ID token claims the backend relies on.
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	AuthorizedBy  string   `json:"azp"`
+	ExpiresAt     int64    `json:"exp"`
+	IssuedAt      int64    `json:"iat"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified bool     `json:"email_verified"`
+}
+
+// audience is the `aud` claim, which is either a single string or an array of
+// strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return errors.Wrap(err, "invalid audience")
+	}
+	*a = many
+	return nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// keySource returns the public key an ID token was signed with.
+type keySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// Verifier verifies ID tokens issued to a client.
+type Verifier struct {
+	keys     keySource
+	issuer   string
+	clientID string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// clockLeeway is the clock skew tolerated between the backend and the
+// provider when checking token times.
+const clockLeeway = time.Minute
+
+// NewVerifier creates a new Verifier of ID tokens from an issuer for a
+// client.
+func NewVerifier(keys keySource, issuer, clientID string) *Verifier {
+	return &Verifier{
+		keys:     keys,
+		issuer:   issuer,
+		clientID: clientID,
+		leeway:   clockLeeway,
+		now:      time.Now,
+	}
+}
+
+var (
+	ErrInvalidToken     = errors.New("invalid id token")
+	ErrInvalidSignature = errors.New("invalid id token signature")
+	ErrTokenExpired     = errors.New("id token expired")
+)
+
+// Verify checks the signature and claims of an ID token and returns its
+// claims. The nonce must match the nonce of the flow the token was issued
+// for.
+func (v *Verifier) Verify(ctx context.Context, token, nonce string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token")
+	}
+
+	var h header
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed header")
+	}
+
+	key, err := v.keys.Key(ctx, h.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed signature")
+	}
+	err = verifySignature(h.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed claims")
+	}
+
+	return &claims, v.checkClaims(&claims, nonce)
+}
+
+func (v *Verifier) checkClaims(claims *Claims, nonce string) error {
+	now := v.now()
+	switch {
+	case claims.Issuer != v.issuer:
+		return errors.Wrap(ErrInvalidToken, "unexpected issuer")
+	case claims.Subject == "":
+		return errors.Wrap(ErrInvalidToken, "missing subject")
+	case !slices.Contains(claims.Audience, v.clientID):
+		return errors.Wrap(ErrInvalidToken, "unexpected audience")
+	case len(claims.Audience) > 1 && claims.AuthorizedBy != v.clientID:
+		return errors.Wrap(ErrInvalidToken, "unexpected authorized party")
+	case nonce == "" || claims.Nonce != nonce:
+		return errors.Wrap(ErrInvalidToken, "nonce mismatch")
+	case !now.Before(time.Unix(claims.ExpiresAt, 0).Add(v.leeway)):
+		return ErrTokenExpired
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(v.leeway)):
+		return errors.Wrap(ErrInvalidToken, "issued in the future")
+	}
+	return nil
+}
+
+// verifySignature verifies a JWS signature. The algorithm must match the key
+// type, so that a token cannot pick a weaker algorithm than the key is meant
+// for. "none" is never accepted.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg != "RS256" {
+			break
+		}
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		const p256SignatureSize = 64
+		if alg != "ES256" || len(signature) != p256SignatureSize {
+			break
+		}
+		r := new(big.Int).SetBytes(signature[:p256SignatureSize/2])
+		s := new(big.Int).SetBytes(signature[p256SignatureSize/2:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case ed25519.PublicKey:
+		if alg != "EdDSA" {
+			break
+		}
+		if !ed25519.Verify(k, signed, signature) {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return errors.Wrapf(ErrInvalidSignature, "algorithm %q does not match key", alg)
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,224 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package oidc implements identity provider adapters that run the OAuth2
+// authorization code flow with PKCE. OpenID Connect providers, such as Auth0,
+// are trusted through ID tokens verified against the provider's published
+// keys. GitHub does not speak OpenID Connect, so its identities are read from
+// its API with the access token the flow yields.
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// Config configures an OAuth2 client registered with an identity provider.
+type Config struct {
+	// Issuer is the issuer URL of the provider. For Auth0, this is
+	// `https://<tenant>.auth0.com/`.
+	Issuer string
+	// ClientID is the ID of the client registered with the provider.
+	ClientID string
+	// ClientSecret is the secret of the client registered with the provider.
+	ClientSecret string
+	// RedirectURL is the callback URL the provider sends the customer back to.
+	// It must be registered with the provider.
+	RedirectURL string
+	// Scopes are the scopes requested from the provider. The "openid" scope is
+	// always requested by OpenID Connect providers.
+	Scopes []string
+	// HTTPClient is the client used to reach the provider. A client with a
+	// timeout is used if nil.
+	HTTPClient *http.Client
+}
+
+func (c Config) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	const timeout = 10 * time.Second
+	return &http.Client{Timeout: timeout}
+}
+
+// Provider is an OpenID Connect identity provider.
+type Provider struct {
+	provider domain.IdentityProvider
+	cfg      Config
+	client   *http.Client
+	authURL  string
+	tokenURL string
+	verifier *Verifier
+}
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// NewProvider creates a new OpenID Connect provider by reading the provider's
+// discovery document from its issuer URL.
+func NewProvider(
+	ctx context.Context,
+	provider domain.IdentityProvider,
+	cfg Config,
+) (*Provider, error) {
+	client := cfg.httpClient()
+
+	var doc discoveryDocument
+	wellKnown := strings.TrimSuffix(cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	err := getJSON(ctx, client, wellKnown, &doc)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to discover provider")
+	}
+	if doc.Issuer != cfg.Issuer {
+		return nil, errors.Errorf("issuer %q does not match configured issuer %q", doc.Issuer, cfg.Issuer)
+	}
+
+	keys := NewKeySet(doc.JWKSURI, client)
+	return &Provider{
+		provider: provider,
+		cfg:      cfg,
+		client:   client,
+		authURL:  doc.AuthorizationEndpoint,
+		tokenURL: doc.TokenEndpoint,
+		verifier: NewVerifier(keys, doc.Issuer, cfg.ClientID),
+	}, nil
+}
+
+// NewAuth0 creates a new Auth0 provider.
+func NewAuth0(ctx context.Context, cfg Config) (*Provider, error) {
+	return NewProvider(ctx, domain.IdentityProviderAuth0, cfg)
+}
+
+// Provider returns the identity provider.
+func (p *Provider) Provider() domain.IdentityProvider {
+	return p.provider
+}
+
+// AuthCodeURL returns the URL the customer is sent to in order to
+// authenticate with the provider.
+func (p *Provider) AuthCodeURL(flow *domain.OAuthFlow) string {
+	scopes := []string{"openid", "email", "profile"}
+	for _, s := range p.cfg.Scopes {
+		if s != "openid" && s != "email" && s != "profile" {
+			scopes = append(scopes, s)
+		}
+	}
+	return authCodeURL(p.authURL, p.cfg, flow, scopes, url.Values{"nonce": {flow.Nonce}})
+}
+
+// Exchange exchanges an authorization code for an ID token, verifies the ID
+// token and returns the identity it asserts.
+func (p *Provider) Exchange(
+	ctx context.Context,
+	code string,
+	flow *domain.OAuthFlow,
+) (*domain.ExternalIdentity, error) {
+	token, err := exchangeCode(ctx, p.client, p.tokenURL, p.cfg, code, flow)
+	if err != nil {
+		return nil, err
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("provider returned no id token")
+	}
+
+	claims, err := p.verifier.Verify(ctx, token.IDToken, flow.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ExternalIdentity{
+		Provider:      p.provider,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}, nil
+}
+
+func authCodeURL(
+	endpoint string,
+	cfg Config,
+	flow *domain.OAuthFlow,
+	scopes []string,
+	extra url.Values,
+) string {
+	v := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {cfg.ClientID},
+		"redirect_uri":          {cfg.RedirectURL},
+		"scope":                 {strings.Join(scopes, " ")},
+		"state":                 {flow.State},
+		"code_challenge":        {flow.CodeChallenge()},
+		"code_challenge_method": {"S256"},
+	}
+	for k, vs := range extra {
+		v[k] = vs
+	}
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + v.Encode()
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+var ErrExchangeFailed = errors.New("authorization code exchange failed")
+
+// exchangeCode redeems an authorization code at a token endpoint, presenting
+// the flow's PKCE verifier.
+func exchangeCode(
+	ctx context.Context,
+	client *http.Client,
+	tokenURL string,
+	cfg Config,
+	code string,
+	flow *domain.OAuthFlow,
+) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"code_verifier": {flow.CodeVerifier},
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+	err = doJSON(client, req, &token)
+	if err != nil {
+		return nil, errors.Wrap(ErrExchangeFailed, err.Error())
+	}
+	// Some providers, such as GitHub, report errors with a 200 status.
+	if token.Error != "" {
+		return nil, errors.Wrapf(ErrExchangeFailed, "%s: %s", token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.Wrap(ErrExchangeFailed, "provider returned no access token")
+	}
+	return &token, nil
+}
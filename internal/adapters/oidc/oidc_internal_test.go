@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/oidc/oidctest"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+const (
+	testClientID     = "client"
+	testClientSecret = "secret"
+	testRedirectURL  = "https://brokedaear.com/auth/callback"
+)
+
+func newTestProvider(t *testing.T, srv *oidctest.Server) *Provider {
+	t.Helper()
+	p, err := NewAuth0(context.Background(), Config{
+		Issuer:       srv.Issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       nil,
+		HTTPClient:   srv.Client(),
+	})
+	assert.NoError(t, err)
+	return p
+}
+
+// signIn runs a flow against the provider up to the callback, and returns
+// the flow with the code the provider redirected back with.
+func signIn(t *testing.T, srv *oidctest.Server, p *Provider) (*domain.OAuthFlow, string) {
+	t.Helper()
+	flow, err := domain.NewOAuthFlow(domain.IdentityProviderAuth0, "", time.Minute)
+	assert.NoError(t, err)
+	code, state, err := srv.Authorize(p.AuthCodeURL(flow))
+	assert.NoError(t, err)
+	assert.Equal(t, state, flow.State)
+	return flow, code
+}
+
+func TestProvider_AuthCodeURL(t *testing.T) {
+	srv := oidctest.NewServer(testClientID, testClientSecret)
+	defer srv.Close()
+	p := newTestProvider(t, srv)
+
+	flow, err := domain.NewOAuthFlow(domain.IdentityProviderAuth0, "", time.Minute)
+	assert.NoError(t, err)
+	u, err := url.Parse(p.AuthCodeURL(flow))
+	assert.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, q.Get("state"), flow.State)
+	assert.Equal(t, q.Get("nonce"), flow.Nonce)
+	assert.Equal(t, q.Get("code_challenge"), flow.CodeChallenge())
+	assert.Equal(t, q.Get("code_challenge_method"), "S256")
+	assert.Equal(t, q.Get("scope"), "openid email profile")
+	// The verifier itself never leaves the backend.
+	assert.Equal(t, q.Has("code_verifier"), false)
+}
+
+func TestProvider_Exchange(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		configure func(*oidctest.Server)
+	}{
+		{
+			CaseBase:  test.NewCaseBase("rs256", nil, false),
+			configure: func(*oidctest.Server) {},
+		},
+		{
+			CaseBase: test.NewCaseBase("es256", nil, false),
+			configure: func(s *oidctest.Server) {
+				s.Algorithm = "ES256"
+				s.RotateKey(false)
+			},
+		},
+		{
+			CaseBase: test.NewCaseBase("eddsa", nil, false),
+			configure: func(s *oidctest.Server) {
+				s.Algorithm = "EdDSA"
+				s.RotateKey(false)
+			},
+		},
+		{
+			CaseBase:  test.NewCaseBase("nonce mismatch", ErrInvalidToken, true),
+			configure: func(s *oidctest.Server) { s.Nonce = "replayed" },
+		},
+		{
+			CaseBase:  test.NewCaseBase("wrong audience", ErrInvalidToken, true),
+			configure: func(s *oidctest.Server) { s.Audience = "someone-else" },
+		},
+		{
+			CaseBase:  test.NewCaseBase("expired", ErrTokenExpired, true),
+			configure: func(s *oidctest.Server) { s.TokenLifetime = -time.Hour },
+		},
+		{
+			CaseBase:  test.NewCaseBase("forged signature", ErrInvalidSignature, true),
+			configure: func(s *oidctest.Server) { s.Forge = true },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				srv := oidctest.NewServer(testClientID, testClientSecret)
+				defer srv.Close()
+				tt.configure(srv)
+				p := newTestProvider(t, srv)
+
+				flow, code := signIn(t, srv, p)
+				identity, err := p.Exchange(context.Background(), code, flow)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, identity.Provider, domain.IdentityProviderAuth0)
+				assert.Equal(t, identity.Subject, srv.User.Subject)
+				assert.Equal(t, identity.Email, srv.User.Email)
+				assert.True(t, identity.EmailVerified)
+			},
+		)
+	}
+}
+
+func TestProvider_ExchangeRequiresVerifier(t *testing.T) {
+	srv := oidctest.NewServer(testClientID, testClientSecret)
+	defer srv.Close()
+	p := newTestProvider(t, srv)
+
+	flow, code := signIn(t, srv, p)
+	other, err := domain.NewOAuthFlow(domain.IdentityProviderAuth0, "", time.Minute)
+	assert.NoError(t, err)
+	flow.CodeVerifier = other.CodeVerifier
+	_, err = p.Exchange(context.Background(), code, flow)
+	assert.Error(t, err, ErrExchangeFailed)
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	ctx := context.Background()
+	srv := oidctest.NewServer(testClientID, testClientSecret)
+	defer srv.Close()
+	p := newTestProvider(t, srv)
+
+	keys, ok := p.verifier.keys.(*KeySet)
+	assert.True(t, ok)
+	now := time.Now()
+	keys.now = func() time.Time { return now }
+
+	exchange := func() error {
+		flow, code := signIn(t, srv, p)
+		_, err := p.Exchange(ctx, code, flow)
+		return err
+	}
+
+	assert.NoError(t, exchange())
+	assert.NoError(t, exchange())
+	assert.Equal(t, srv.KeySetRequests, 1)
+
+	// A token signed with a new key is rejected until the refresh rate limit
+	// has passed, then the new key is fetched.
+	srv.RotateKey(true)
+	assert.Error(t, exchange(), ErrUnknownKey)
+	assert.Equal(t, srv.KeySetRequests, 1)
+	now = now.Add(defaultKeySetMinRefresh)
+	assert.NoError(t, exchange())
+	assert.Equal(t, srv.KeySetRequests, 2)
+
+	// Keys that are no longer published are dropped once the cache is stale.
+	srv.RotateKey(false)
+	now = now.Add(defaultKeySetMaxAge)
+	assert.NoError(t, exchange())
+	assert.Equal(t, srv.KeySetRequests, 3)
+	assert.Equal(t, len(keys.keys), 1)
+}
@@ -0,0 +1,335 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package oidctest provides a local OpenID Connect provider for tests. It
+// implements discovery, the authorization endpoint, the token endpoint with
+// PKCE, and a JWKS endpoint whose keys can be rotated.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// User is the user the provider authenticates.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// Server is a local OpenID Connect provider. The zero values of the exported
+// knobs make the server behave like a well-behaved provider.
+type Server struct {
+	*httptest.Server
+
+	ClientID     string
+	ClientSecret string
+
+	mu sync.Mutex
+	// User is the user the next authorization authenticates.
+	User User
+	// Algorithm is the signing algorithm of keys created by RotateKey. It is
+	// one of RS256, ES256 or EdDSA.
+	Algorithm string
+	// Audience overrides the `aud` claim of issued ID tokens.
+	Audience string
+	// Nonce overrides the `nonce` claim of issued ID tokens.
+	Nonce string
+	// TokenLifetime is the lifetime of issued ID tokens. Negative lifetimes
+	// issue expired tokens.
+	TokenLifetime time.Duration
+	// Forge signs ID tokens with a key that is not published.
+	Forge bool
+	// KeySetRequests is the number of times the key set was fetched.
+	KeySetRequests int
+
+	keys  []signingKey
+	codes map[string]authorization
+}
+
+type signingKey struct {
+	id     string
+	alg    string
+	signer crypto.Signer
+}
+
+type authorization struct {
+	user          User
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+}
+
+// NewServer starts a new provider with a client registered. The server signs
+// with an RS256 key by default.
+func NewServer(clientID, clientSecret string) *Server {
+	s := &Server{
+		ClientID:      clientID,
+		ClientSecret:  clientSecret,
+		User:          User{Subject: "auth0|test-user", Email: "test@brokedaear.com", EmailVerified: true},
+		Algorithm:     "RS256",
+		TokenLifetime: time.Hour,
+		codes:         make(map[string]authorization),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", s.discovery)
+	mux.HandleFunc("GET /authorize", s.authorize)
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /jwks", s.jwks)
+	s.Server = httptest.NewServer(mux)
+	s.RotateKey(true)
+	return s
+}
+
+// Issuer returns the issuer URL of the provider.
+func (s *Server) Issuer() string {
+	return s.URL + "/"
+}
+
+// RotateKey creates a new signing key. If keepOld is true, the previous keys
+// are still published, like a provider in the middle of a key rotation.
+func (s *Server) RotateKey(keepOld bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := newSigningKey(s.Algorithm, strconv.Itoa(len(s.keys)+1))
+	if keepOld {
+		s.keys = append(s.keys, key)
+	} else {
+		s.keys = []signingKey{key}
+	}
+}
+
+// Authorize follows an authorization URL as a customer that signs in, and
+// returns the code and state the provider redirects back with.
+func (s *Server) Authorize(authURL string) (code, state string, err error) {
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	res, err := client.Get(authURL) //nolint:noctx // Test helper.
+	if err != nil {
+		return "", "", err
+	}
+	defer res.Body.Close()
+	location, err := res.Location()
+	if err != nil {
+		return "", "", err
+	}
+	q := location.Query()
+	return q.Get("code"), q.Get("state"), nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                 s.Issuer(),
+		"authorization_endpoint": s.URL + "/authorize",
+		"token_endpoint":         s.URL + "/token",
+		"jwks_uri":               s.URL + "/jwks",
+	})
+}
+
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != s.ClientID ||
+		q.Get("response_type") != "code" ||
+		q.Get("code_challenge_method") != "S256" ||
+		q.Get("code_challenge") == "" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect uri", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	s.mu.Lock()
+	s.codes[code] = authorization{
+		user:          s.User,
+		redirectURI:   q.Get("redirect_uri"),
+		codeChallenge: q.Get("code_challenge"),
+		nonce:         q.Get("nonce"),
+	}
+	s.mu.Unlock()
+
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	code := r.PostForm.Get("code")
+	auth, ok := s.codes[code]
+	// Codes are single use.
+	delete(s.codes, code)
+
+	switch {
+	case r.PostForm.Get("client_id") != s.ClientID ||
+		r.PostForm.Get("client_secret") != s.ClientSecret:
+		tokenError(w, "invalid_client")
+		return
+	case r.PostForm.Get("grant_type") != "authorization_code" || !ok:
+		tokenError(w, "invalid_grant")
+		return
+	case r.PostForm.Get("redirect_uri") != auth.redirectURI:
+		tokenError(w, "invalid_grant")
+		return
+	case challenge(r.PostForm.Get("code_verifier")) != auth.codeChallenge:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	nonce := auth.nonce
+	if s.Nonce != "" {
+		nonce = s.Nonce
+	}
+	aud := s.ClientID
+	if s.Audience != "" {
+		aud = s.Audience
+	}
+	now := time.Now()
+	claims := map[string]any{
+		"iss":            s.Issuer(),
+		"sub":            auth.user.Subject,
+		"aud":            aud,
+		"iat":            now.Unix(),
+		"exp":            now.Add(s.TokenLifetime).Unix(),
+		"nonce":          nonce,
+		"email":          auth.user.Email,
+		"email_verified": auth.user.EmailVerified,
+	}
+
+	key := s.keys[len(s.keys)-1]
+	if s.Forge {
+		key = newSigningKey(key.alg, key.id)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"id_token":     sign(key, claims),
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.KeySetRequests++
+	keys := make([]map[string]string, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, publicJWK(k))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+func newSigningKey(alg, id string) signingKey {
+	var signer crypto.Signer
+	var err error
+	switch alg {
+	case "ES256":
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		const bits = 2048
+		signer, err = rsa.GenerateKey(rand.Reader, bits)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return signingKey{id: id, alg: alg, signer: signer}
+}
+
+func publicJWK(k signingKey) map[string]string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwk := map[string]string{"kid": k.id, "alg": k.alg, "use": "sig"}
+	switch pub := k.signer.Public().(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = b64(pub.N.Bytes())
+		jwk["e"] = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		const size = 32
+		jwk["kty"] = "EC"
+		jwk["crv"] = "P-256"
+		jwk["x"] = b64(pub.X.FillBytes(make([]byte, size)))
+		jwk["y"] = b64(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = b64(pub)
+	}
+	return jwk
+}
+
+func sign(k signingKey, claims map[string]any) string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": k.alg, "kid": k.id, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+
+	var signature []byte
+	var err error
+	digest := sha256.Sum256([]byte(signed))
+	switch key := k.signer.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, key, digest[:])
+		if err == nil {
+			const size = 32
+			signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signed))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return signed + "." + b64(signature)
+}
+
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func tokenError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
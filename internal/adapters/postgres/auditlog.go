@@ -0,0 +1,216 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// AuditLogRepository stores the audit log in the append-only audit_log
+// table.
+type AuditLogRepository struct {
+	*Postgres[domain.AuditEntry]
+}
+
+// NewAuditLogRepository creates a new AuditLogRepository.
+func NewAuditLogRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*AuditLogRepository, error) {
+	pg, err := NewPostgresDB[domain.AuditEntry](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLogRepository{Postgres: pg}, nil
+}
+
+// auditLogLock is the key of the advisory lock that serializes appends, so
+// that every entry is chained to the one before it.
+const auditLogLock = 0x61756469746c6f67 // "auditlog"
+
+const auditLogColumns = `
+	sequence, action, outcome, actor_id, customer_id, target_type, target_id,
+	reason, request_id, trace_id, occurred_at, prev_hash, hash`
+
+// Append adds an event to the end of the log, chained to the last entry.
+// Appends are serialized with a transaction level advisory lock. Append
+// never joins a transaction carried by ctx, so that the event is kept even
+// if the operation it records is rolled back.
+func (ar *AuditLogRepository) Append(ctx context.Context, event domain.AuditEvent) (*domain.AuditEntry, error) {
+	tx, err := ar.db.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, auditLogLock)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lock audit log")
+	}
+	prev, err := scanAuditEntry(tx.QueryRow(ctx, `
+		SELECT `+auditLogColumns+`
+		FROM audit_log
+		ORDER BY sequence DESC
+		LIMIT 1`))
+	if errors.Is(err, pgx.ErrNoRows) {
+		prev, err = nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get last audit log entry")
+	}
+
+	entry := domain.NewAuditEntry(prev, event)
+	query := `
+		INSERT INTO audit_log (` + auditLogColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	_, err = tx.Exec(ctx, query,
+		entry.Sequence,
+		entry.Event.Action.String(),
+		entry.Event.Outcome.String(),
+		nullString(entry.Event.ActorID),
+		nullString(entry.Event.CustomerID),
+		nullString(entry.Event.TargetType.String()),
+		nullString(entry.Event.TargetID),
+		nullString(entry.Event.Reason),
+		nullString(entry.Event.RequestID),
+		nullString(entry.Event.TraceID),
+		entry.Event.OccurredAt,
+		entry.PrevHash,
+		entry.Hash,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to append audit log entry")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to commit transaction")
+	}
+	return &entry, nil
+}
+
+// List retrieves the entries a filter matches, most recent first.
+func (ar *AuditLogRepository) List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.ActorID != "" {
+		where("actor_id = $%d", filter.ActorID)
+	}
+	if filter.CustomerID != "" {
+		where("customer_id = $%d", filter.CustomerID)
+	}
+	if !filter.Since.IsZero() {
+		where("occurred_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("occurred_at < $%d", filter.Until)
+	}
+	if filter.BeforeSequence != 0 {
+		where("sequence < $%d", filter.BeforeSequence)
+	}
+
+	query := `SELECT ` + auditLogColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += ` WHERE ` + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(` ORDER BY sequence DESC LIMIT $%d`, len(args))
+
+	return ar.list(ctx, query, args...)
+}
+
+// ListAfter retrieves up to limit entries that follow a sequence, in order.
+func (ar *AuditLogRepository) ListAfter(ctx context.Context, sequence int64, limit int) ([]domain.AuditEntry, error) {
+	query := `
+		SELECT ` + auditLogColumns + `
+		FROM audit_log
+		WHERE sequence > $1
+		ORDER BY sequence
+		LIMIT $2`
+	return ar.list(ctx, query, sequence, limit)
+}
+
+func (ar *AuditLogRepository) list(ctx context.Context, query string, args ...any) ([]domain.AuditEntry, error) {
+	rows, err := ar.q(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list audit log entries")
+	}
+	defer rows.Close()
+
+	entries := make([]domain.AuditEntry, 0)
+	for rows.Next() {
+		entry, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan audit log entry")
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, errors.Wrap(rows.Err(), "failed to list audit log entries")
+}
+
+// scanAuditEntry scans a row of auditLogColumns into a domain.AuditEntry.
+func scanAuditEntry(row pgx.Row) (*domain.AuditEntry, error) {
+	var entry domain.AuditEntry
+	var action, outcome string
+	var actorID, customerID, targetType, targetID, reason, requestID, traceID sql.NullString
+
+	err := row.Scan(
+		&entry.Sequence,
+		&action,
+		&outcome,
+		&actorID,
+		&customerID,
+		&targetType,
+		&targetID,
+		&reason,
+		&requestID,
+		&traceID,
+		&entry.Event.OccurredAt,
+		&entry.PrevHash,
+		&entry.Hash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Event.Action, err = domain.NewAuditAction(action)
+	if err != nil {
+		return nil, errors.Wrapf(err, "entry %d", entry.Sequence)
+	}
+	entry.Event.Outcome, err = domain.NewAuditOutcome(outcome)
+	if err != nil {
+		return nil, errors.Wrapf(err, "entry %d", entry.Sequence)
+	}
+	entry.Event.TargetType, err = domain.NewAuditTargetType(targetType.String)
+	if err != nil {
+		return nil, errors.Wrapf(err, "entry %d", entry.Sequence)
+	}
+	entry.Event.ActorID = actorID.String
+	entry.Event.CustomerID = customerID.String
+	entry.Event.TargetID = targetID.String
+	entry.Event.Reason = reason.String
+	entry.Event.RequestID = requestID.String
+	entry.Event.TraceID = traceID.String
+	entry.Event.OccurredAt = entry.Event.OccurredAt.UTC()
+	return &entry, nil
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// AuthorizationRepository reads the grants of roles from the
+// role_permissions table and stores the roles of customers in the
+// user_roles table.
+type AuthorizationRepository struct {
+	*Postgres[domain.Grant]
+}
+
+// NewAuthorizationRepository creates a new AuthorizationRepository.
+func NewAuthorizationRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*AuthorizationRepository, error) {
+	pg, err := NewPostgresDB[domain.Grant](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthorizationRepository{Postgres: pg}, nil
+}
+
+// foreignKeyViolation is the SQLSTATE of a foreign key constraint violation.
+const foreignKeyViolation = "23503"
+
+// ListRoles retrieves the roles assigned to a customer. The implicit
+// customer role is not included.
+func (ar *AuthorizationRepository) ListRoles(ctx context.Context, customerID string) ([]domain.Role, error) {
+	rows, err := ar.q(ctx).Query(ctx, `SELECT role FROM user_roles WHERE user_id = $1 ORDER BY role`, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list roles")
+	}
+	defer rows.Close()
+
+	roles := make([]domain.Role, 0)
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan role")
+		}
+		role, err := domain.NewRole(name)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+	return roles, errors.Wrap(rows.Err(), "failed to list roles")
+}
+
+// ListGrants retrieves the grants of a set of roles.
+func (ar *AuthorizationRepository) ListGrants(ctx context.Context, roles []domain.Role) ([]domain.Grant, error) {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = role.String()
+	}
+
+	query := `
+		SELECT role, permission, own_only, amount_limit
+		FROM role_permissions
+		WHERE role = ANY($1)
+		ORDER BY role, permission`
+
+	rows, err := ar.q(ctx).Query(ctx, query, names)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list grants")
+	}
+	defer rows.Close()
+
+	grants := make([]domain.Grant, 0)
+	for rows.Next() {
+		var role, permission string
+		var amountLimit sql.NullInt32
+		var grant domain.Grant
+		err = rows.Scan(&role, &permission, &grant.OwnOnly, &amountLimit)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan grant")
+		}
+		grant.Role, err = domain.NewRole(role)
+		if err != nil {
+			return nil, err
+		}
+		grant.Permission, err = domain.NewPermission(permission)
+		if err != nil {
+			return nil, err
+		}
+		if amountLimit.Valid {
+			limit := int(amountLimit.Int32)
+			grant.AmountLimit = &limit
+		}
+		grants = append(grants, grant)
+	}
+	return grants, errors.Wrap(rows.Err(), "failed to list grants")
+}
+
+// AssignRole assigns a role to a customer on behalf of another customer.
+// Assigning a role the customer already has does nothing.
+func (ar *AuthorizationRepository) AssignRole(
+	ctx context.Context,
+	customerID string,
+	role domain.Role,
+	grantedBy string,
+) error {
+	query := `
+		INSERT INTO user_roles (user_id, role, granted_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, role) DO NOTHING`
+
+	_, err := ar.q(ctx).Exec(ctx, query, customerID, role.String(), nullString(grantedBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return domain.ErrCustomerNotFound
+		}
+		return errors.Wrap(err, "failed to assign role")
+	}
+	return nil
+}
+
+// RevokeRole removes a role from a customer. Revoking a role the customer
+// does not have does nothing.
+func (ar *AuthorizationRepository) RevokeRole(ctx context.Context, customerID string, role domain.Role) error {
+	_, err := ar.q(ctx).Exec(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND role = $2`, customerID, role.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke role")
+	}
+	return nil
+}
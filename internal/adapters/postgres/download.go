@@ -0,0 +1,100 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// DownloadRepository stores the downloads of customers in the
+// user_downloads table.
+type DownloadRepository struct {
+	*Postgres[domain.Download]
+}
+
+// NewDownloadRepository creates a new DownloadRepository.
+func NewDownloadRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*DownloadRepository, error) {
+	pg, err := NewPostgresDB[domain.Download](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &DownloadRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new download.
+func (dr *DownloadRepository) Insert(ctx context.Context, download *domain.Download) error {
+	query := `
+		INSERT INTO user_downloads (id, user_id, product_id, order_id, download_count,
+			last_downloaded_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := dr.q(ctx).Exec(ctx, query,
+		download.ID,
+		download.UserID,
+		download.ProductID,
+		download.OrderID,
+		download.DownloadCount,
+		download.LastDownloadedAt,
+		download.CreatedAt,
+	)
+	return errors.Wrap(err, "failed to insert download")
+}
+
+// ListByCustomer retrieves every download of a customer, most recent first.
+func (dr *DownloadRepository) ListByCustomer(ctx context.Context, customerID string) ([]*domain.Download, error) {
+	query := `
+		SELECT id, user_id, product_id, order_id, download_count,
+			   last_downloaded_at, created_at
+		FROM user_downloads
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+
+	rows, err := dr.q(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list downloads")
+	}
+	defer rows.Close()
+
+	downloads := make([]*domain.Download, 0)
+	for rows.Next() {
+		var d domain.Download
+		var count sql.NullInt32
+		var lastDownloadedAt sql.NullTime
+		err = rows.Scan(&d.ID, &d.UserID, &d.ProductID, &d.OrderID, &count, &lastDownloadedAt, &d.CreatedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan download")
+		}
+		d.DownloadCount = int(count.Int32)
+		if lastDownloadedAt.Valid {
+			d.LastDownloadedAt = &lastDownloadedAt.Time
+		}
+		downloads = append(downloads, &d)
+	}
+	return downloads, errors.Wrap(rows.Err(), "failed to list downloads")
+}
+
+// ResetCount sets the download count of a download back to zero.
+func (dr *DownloadRepository) ResetCount(ctx context.Context, id string) error {
+	result, err := dr.q(ctx).Exec(ctx, `UPDATE user_downloads SET download_count = 0 WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to reset download count")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrDownloadNotFound
+	}
+	return nil
+}
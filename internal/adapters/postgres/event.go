@@ -0,0 +1,192 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// EventOutboxRepository stores domain events waiting to be published in the
+// event_outbox table. Events are inserted in the transaction carried by the
+// context, if any, so an event is only published once the change it
+// describes is committed.
+type EventOutboxRepository struct {
+	*Postgres[domain.Event]
+}
+
+// NewEventOutboxRepository creates a new EventOutboxRepository.
+func NewEventOutboxRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*EventOutboxRepository, error) {
+	pg, err := NewPostgresDB[domain.Event](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &EventOutboxRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new event to the outbox.
+func (er *EventOutboxRepository) Insert(ctx context.Context, event *domain.Event) error {
+	query := `
+		INSERT INTO event_outbox (
+			id, type, aggregate_id, payload, trace_id, span_id, status,
+			attempts, next_attempt_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := er.q(ctx).Exec(ctx, query,
+		event.ID,
+		event.Type.String(),
+		event.AggregateID,
+		string(event.Payload),
+		nullString(event.TraceID),
+		nullString(event.SpanID),
+		event.Status.String(),
+		event.Attempts,
+		event.NextAttemptAt,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert event")
+	}
+	return nil
+}
+
+// ClaimDue returns up to limit pending events that are due at a point in
+// time, oldest first, and pushes their next attempt back by lease. Rows
+// claimed by another relay at the same time are skipped.
+func (er *EventOutboxRepository) ClaimDue(
+	ctx context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.Event, error) {
+	query := `
+		UPDATE event_outbox
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id
+			FROM event_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, type, aggregate_id, payload, trace_id, span_id, status,
+				  attempts, next_attempt_at, last_error, created_at, published_at`
+
+	rows, err := er.q(ctx).Query(ctx, query, at, at.Add(lease), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to claim events")
+	}
+	defer rows.Close()
+
+	var events []*domain.Event
+	for rows.Next() {
+		var event domain.Event
+		var typ, payload, status string
+		var traceID, spanID, lastError sql.NullString
+		var publishedAt sql.NullTime
+		err = rows.Scan(
+			&event.ID,
+			&typ,
+			&event.AggregateID,
+			&payload,
+			&traceID,
+			&spanID,
+			&status,
+			&event.Attempts,
+			&event.NextAttemptAt,
+			&lastError,
+			&event.CreatedAt,
+			&publishedAt,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan event")
+		}
+		event.Type, err = domain.NewEventType(typ)
+		if err != nil {
+			return nil, err
+		}
+		event.Status, err = domain.NewEventStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		event.Payload = []byte(payload)
+		event.TraceID = traceID.String
+		event.SpanID = spanID.String
+		event.LastError = lastError.String
+		if publishedAt.Valid {
+			event.PublishedAt = &publishedAt.Time
+		}
+		events = append(events, &event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to claim events")
+	}
+	return events, nil
+}
+
+// MarkPublished marks an event as handled by every subscriber.
+func (er *EventOutboxRepository) MarkPublished(ctx context.Context, id string, at time.Time) error {
+	result, err := er.q(ctx).Exec(ctx, `
+		UPDATE event_outbox
+		SET status = 'published', attempts = attempts + 1, published_at = $2
+		WHERE id = $1`, id, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark event published")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrEventNotFound
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt. A nil next attempt marks the event as
+// dead.
+func (er *EventOutboxRepository) MarkFailed(
+	ctx context.Context,
+	id string,
+	attempts int,
+	next *time.Time,
+	lastError string,
+) error {
+	result, err := er.q(ctx).Exec(ctx, `
+		UPDATE event_outbox
+		SET attempts = $2,
+			last_error = $3,
+			status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE status END,
+			next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE id = $1`, id, attempts, lastError, next)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark event failed")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrEventNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes every published or dead event created before a point
+// in time and returns the number of removed events.
+func (er *EventOutboxRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := er.q(ctx).Exec(ctx, `
+		DELETE FROM event_outbox
+		WHERE status <> 'pending' AND created_at < $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete old events")
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// IdentityRepository stores the identities linked to customers in the
+// user_identities table.
+type IdentityRepository struct {
+	*Postgres[domain.Identity]
+}
+
+// NewIdentityRepository creates a new IdentityRepository.
+func NewIdentityRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*IdentityRepository, error) {
+	pg, err := NewPostgresDB[domain.Identity](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &IdentityRepository{Postgres: pg}, nil
+}
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation.
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether an error is a unique constraint
+// violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
+
+// Insert links a new identity. An identity can only be linked once.
+func (ir *IdentityRepository) Insert(ctx context.Context, identity *domain.Identity) error {
+	query := `
+		INSERT INTO user_identities (
+			id, user_id, provider, subject, email, created_at, last_used_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := ir.q(ctx).Exec(ctx, query,
+		identity.ID,
+		identity.UserID,
+		identity.Provider.String(),
+		identity.Subject,
+		nullString(identity.Email),
+		identity.CreatedAt,
+		identity.LastUsedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return domain.ErrIdentityExists
+		}
+		return errors.Wrap(err, "failed to insert identity")
+	}
+	return nil
+}
+
+// GetBySubject retrieves an identity by its provider and subject.
+func (ir *IdentityRepository) GetBySubject(
+	ctx context.Context,
+	provider domain.IdentityProvider,
+	subject string,
+) (*domain.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_used_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2`
+
+	identity, err := scanIdentity(ir.q(ctx).QueryRow(ctx, query, provider.String(), subject))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrIdentityNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get identity by subject")
+	}
+	return identity, nil
+}
+
+// ListByCustomer retrieves the identities linked to a customer, oldest
+// first.
+func (ir *IdentityRepository) ListByCustomer(
+	ctx context.Context,
+	customerID string,
+) ([]*domain.Identity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_used_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := ir.q(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list identities")
+	}
+	defer rows.Close()
+
+	identities := make([]*domain.Identity, 0)
+	for rows.Next() {
+		identity, err := scanIdentity(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan identity")
+		}
+		identities = append(identities, identity)
+	}
+	return identities, errors.Wrap(rows.Err(), "failed to list identities")
+}
+
+// UpdateLastUsed records the email and the time an identity was last used
+// with.
+func (ir *IdentityRepository) UpdateLastUsed(ctx context.Context, identity *domain.Identity) error {
+	query := `
+		UPDATE user_identities
+		SET email = $2, last_used_at = $3
+		WHERE id = $1`
+
+	result, err := ir.q(ctx).Exec(ctx, query,
+		identity.ID,
+		nullString(identity.Email),
+		identity.LastUsedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update identity")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrIdentityNotFound
+	}
+	return nil
+}
+
+// Delete unlinks an identity.
+func (ir *IdentityRepository) Delete(ctx context.Context, id string) error {
+	_, err := ir.q(ctx).Exec(ctx, `DELETE FROM user_identities WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete identity")
+	}
+	return nil
+}
+
+// scanIdentity scans a database row into a domain.Identity struct.
+func scanIdentity(row pgx.Row) (*domain.Identity, error) {
+	var identity domain.Identity
+	var provider string
+	var email sql.NullString
+
+	err := row.Scan(
+		&identity.ID,
+		&identity.UserID,
+		&provider,
+		&identity.Subject,
+		&email,
+		&identity.CreatedAt,
+		&identity.LastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	identity.Provider, err = domain.NewIdentityProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	identity.Email = email.String
+
+	return &identity, nil
+}
@@ -0,0 +1,283 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// jobSchedulerLock is the key of the advisory lock held by the runner that
+// enqueues the jobs of due schedules, the leader.
+const jobSchedulerLock = 0x6a6f627363686564 // "jobsched"
+
+const jobColumns = `
+	id, type, key, payload, status, attempts, run_at, last_error, created_at,
+	finished_at`
+
+// JobRepository stores the job queue in the jobs table, and the recurring
+// schedules of jobs in the job_schedules table.
+type JobRepository struct {
+	*Postgres[domain.Job]
+}
+
+// NewJobRepository creates a new JobRepository.
+func NewJobRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*JobRepository, error) {
+	pg, err := NewPostgresDB[domain.Job](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &JobRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new job to the queue. A job whose key is taken by another
+// job is not added, and no error is returned.
+func (jr *JobRepository) Insert(ctx context.Context, job *domain.Job) error {
+	query := `
+		INSERT INTO jobs (
+			id, type, key, payload, status, attempts, run_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (key) WHERE key IS NOT NULL DO NOTHING`
+
+	_, err := jr.q(ctx).Exec(ctx, query,
+		job.ID,
+		job.Type,
+		nullString(job.Key),
+		string(job.Payload),
+		job.Status.String(),
+		job.Attempts,
+		job.RunAt,
+		job.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert job")
+	}
+	return nil
+}
+
+// GetByID retrieves a job by its ID.
+func (jr *JobRepository) GetByID(ctx context.Context, id string) (*domain.Job, error) {
+	job, err := scanJob(jr.q(ctx).QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrJobNotFound
+	}
+	return job, err
+}
+
+// ClaimDue returns up to limit pending jobs that are due at a point in time,
+// oldest first, and pushes their next run back by lease. Rows claimed by
+// another runner at the same time are skipped.
+func (jr *JobRepository) ClaimDue(
+	ctx context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.Job, error) {
+	query := `
+		UPDATE jobs
+		SET run_at = $2
+		WHERE id IN (
+			SELECT id
+			FROM jobs
+			WHERE status = 'pending' AND run_at <= $1
+			ORDER BY run_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	rows, err := jr.q(ctx).Query(ctx, query, at, at.Add(lease), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to claim jobs")
+	}
+	defer rows.Close()
+
+	var jobs []*domain.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to claim jobs")
+	}
+	return jobs, nil
+}
+
+// MarkSucceeded marks a job as done.
+func (jr *JobRepository) MarkSucceeded(ctx context.Context, id string, at time.Time) error {
+	result, err := jr.q(ctx).Exec(ctx, `
+		UPDATE jobs
+		SET status = 'succeeded', attempts = attempts + 1, finished_at = $2
+		WHERE id = $1`, id, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark job succeeded")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrJobNotFound
+	}
+	return nil
+}
+
+// MarkFailed records a failed run at a point in time. A nil next run marks
+// the job as dead.
+func (jr *JobRepository) MarkFailed(
+	ctx context.Context,
+	id string,
+	attempts int,
+	next *time.Time,
+	lastError string,
+	at time.Time,
+) error {
+	result, err := jr.q(ctx).Exec(ctx, `
+		UPDATE jobs
+		SET attempts = $2,
+			last_error = $3,
+			status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE status END,
+			finished_at = CASE WHEN $4::timestamptz IS NULL THEN $5 ELSE finished_at END,
+			run_at = COALESCE($4, run_at)
+		WHERE id = $1`, id, attempts, lastError, next, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark job failed")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrJobNotFound
+	}
+	return nil
+}
+
+// Retry makes a dead job pending again, due at a point in time, with its
+// attempts reset. It returns domain.ErrJobNotFound if the job is missing or
+// not dead.
+func (jr *JobRepository) Retry(ctx context.Context, id string, at time.Time) error {
+	result, err := jr.q(ctx).Exec(ctx, `
+		UPDATE jobs
+		SET status = 'pending', attempts = 0, run_at = $2, finished_at = NULL
+		WHERE id = $1 AND status = 'dead'`, id, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to retry job")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrJobNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes every succeeded or dead job that finished before a
+// point in time and returns the number of removed jobs.
+func (jr *JobRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := jr.q(ctx).Exec(ctx, `
+		DELETE FROM jobs
+		WHERE status <> 'pending' AND finished_at < $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete old jobs")
+	}
+	return result.RowsAffected(), nil
+}
+
+// WithLeaderLock runs fn inside a transaction holding the scheduler advisory
+// lock, if no other runner holds it, and reports whether it did. The lock is
+// released when the transaction ends, so leadership is only held for as long
+// as fn runs.
+func (jr *JobRepository) WithLeaderLock(ctx context.Context, fn func(ctx context.Context) error) (bool, error) {
+	tx, err := jr.q(ctx).Begin(ctx)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var leader bool
+	err = tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, jobSchedulerLock).Scan(&leader)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to lock job scheduler")
+	}
+	if !leader {
+		return false, nil
+	}
+	err = fn(contextWithTx(ctx, tx))
+	if err != nil {
+		return true, err
+	}
+	return true, errors.Wrap(tx.Commit(ctx), "failed to commit transaction")
+}
+
+// NextRun retrieves when a schedule is due next. It returns
+// domain.ErrJobScheduleNotFound if the schedule never ran.
+func (jr *JobRepository) NextRun(ctx context.Context, schedule string) (time.Time, error) {
+	var next time.Time
+	err := jr.q(ctx).QueryRow(ctx, `
+		SELECT next_run_at FROM job_schedules WHERE name = $1`, schedule).Scan(&next)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, domain.ErrJobScheduleNotFound
+	}
+	if err != nil {
+		return time.Time{}, errors.Wrap(err, "failed to get next run")
+	}
+	return next, nil
+}
+
+// SetNextRun records when a schedule is due next.
+func (jr *JobRepository) SetNextRun(ctx context.Context, schedule string, at time.Time) error {
+	_, err := jr.q(ctx).Exec(ctx, `
+		INSERT INTO job_schedules (name, next_run_at) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET next_run_at = EXCLUDED.next_run_at`, schedule, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to set next run")
+	}
+	return nil
+}
+
+func scanJob(row pgx.Row) (*domain.Job, error) {
+	var job domain.Job
+	var payload, status string
+	var key, lastError sql.NullString
+	var finishedAt sql.NullTime
+	err := row.Scan(
+		&job.ID,
+		&job.Type,
+		&key,
+		&payload,
+		&status,
+		&job.Attempts,
+		&job.RunAt,
+		&lastError,
+		&job.CreatedAt,
+		&finishedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scan job")
+	}
+	job.Status, err = domain.NewJobStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	job.Key = key.String
+	job.Payload = []byte(payload)
+	job.LastError = lastError.String
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	return &job, nil
+}
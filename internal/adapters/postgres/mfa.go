@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// MFARepository stores TOTP authenticators in the user_mfa table, and
+// recovery codes in the user_recovery_codes table.
+type MFARepository struct {
+	*Postgres[domain.UserMFA]
+}
+
+// NewMFARepository creates a new MFARepository.
+func NewMFARepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*MFARepository, error) {
+	pg, err := NewPostgresDB[domain.UserMFA](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &MFARepository{Postgres: pg}, nil
+}
+
+// Get retrieves the authenticator of a customer.
+func (mr *MFARepository) Get(ctx context.Context, userID string) (*domain.UserMFA, error) {
+	query := `
+		SELECT user_id, encrypted_secret, last_used_step, created_at, enabled_at
+		FROM user_mfa
+		WHERE user_id = $1`
+
+	var mfa domain.UserMFA
+	var enabledAt sql.NullTime
+	err := mr.q(ctx).QueryRow(ctx, query, userID).Scan(
+		&mfa.UserID,
+		&mfa.EncryptedSecret,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+		&enabledAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrMFANotFound
+		}
+		return nil, errors.Wrap(err, "failed to get mfa")
+	}
+	if enabledAt.Valid {
+		mfa.EnabledAt = &enabledAt.Time
+	}
+	return &mfa, nil
+}
+
+// Upsert stores the authenticator of a customer, replacing any other.
+func (mr *MFARepository) Upsert(ctx context.Context, mfa *domain.UserMFA) error {
+	query := `
+		INSERT INTO user_mfa (
+			user_id, encrypted_secret, last_used_step, created_at, enabled_at
+		) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			encrypted_secret = EXCLUDED.encrypted_secret,
+			last_used_step = EXCLUDED.last_used_step,
+			created_at = EXCLUDED.created_at,
+			enabled_at = EXCLUDED.enabled_at`
+
+	_, err := mr.q(ctx).Exec(ctx, query,
+		mfa.UserID,
+		mfa.EncryptedSecret,
+		mfa.LastUsedStep,
+		mfa.CreatedAt,
+		mfa.EnabledAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to upsert mfa")
+	}
+	return nil
+}
+
+// Enable enables the authenticator of a customer, and records the time step
+// of the code that confirmed it.
+func (mr *MFARepository) Enable(ctx context.Context, userID string, step int64, at time.Time) error {
+	result, err := mr.q(ctx).Exec(ctx, `
+		UPDATE user_mfa
+		SET enabled_at = $3, last_used_step = $2
+		WHERE user_id = $1`, userID, step, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to enable mfa")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrMFANotFound
+	}
+	return nil
+}
+
+// UseStep records that a code of a time step was accepted. The check and the
+// update happen in one statement, so a code presented twice at once is only
+// accepted once.
+func (mr *MFARepository) UseStep(ctx context.Context, userID string, step int64) error {
+	result, err := mr.q(ctx).Exec(ctx, `
+		UPDATE user_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2`, userID, step)
+	if err != nil {
+		return errors.Wrap(err, "failed to use mfa step")
+	}
+	if result.RowsAffected() == 0 {
+		_, err = mr.Get(ctx, userID)
+		if err != nil {
+			return err
+		}
+		return domain.ErrMFACodeUsed
+	}
+	return nil
+}
+
+// Delete removes the authenticator and the recovery codes of a customer.
+func (mr *MFARepository) Delete(ctx context.Context, userID string) error {
+	tx, err := mr.q(ctx).Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete recovery codes")
+	}
+	result, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete mfa")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrMFANotFound
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// ReplaceRecoveryCodes replaces every recovery code of a customer.
+func (mr *MFARepository) ReplaceRecoveryCodes(
+	ctx context.Context,
+	userID string,
+	codes []*domain.RecoveryCode,
+) error {
+	tx, err := mr.q(ctx).Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete recovery codes")
+	}
+	for _, code := range codes {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO user_recovery_codes (id, user_id, code_hash, created_at)
+			VALUES ($1, $2, $3, $4)`,
+			code.ID, code.UserID, code.CodeHash, code.CreatedAt)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert recovery code")
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// UseRecoveryCode marks the unused recovery code of a customer with a hash
+// as used. It returns domain.ErrRecoveryCodeNotFound if there is none.
+func (mr *MFARepository) UseRecoveryCode(
+	ctx context.Context,
+	userID string,
+	codeHash []byte,
+	at time.Time,
+) error {
+	result, err := mr.q(ctx).Exec(ctx, `
+		UPDATE user_recovery_codes
+		SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, codeHash, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to use recovery code")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrRecoveryCodeNotFound
+	}
+	return nil
+}
+
+// CountRecoveryCodes counts the unused recovery codes of a customer.
+func (mr *MFARepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	var n int
+	err := mr.q(ctx).QueryRow(ctx, `
+		SELECT count(*)
+		FROM user_recovery_codes
+		WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&n)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to count recovery codes")
+	}
+	return n, nil
+}
@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// OAuthFlowRepository stores OAuth2 flows in the oauth_flows table.
+type OAuthFlowRepository struct {
+	*Postgres[domain.OAuthFlow]
+}
+
+// NewOAuthFlowRepository creates a new OAuthFlowRepository.
+func NewOAuthFlowRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*OAuthFlowRepository, error) {
+	pg, err := NewPostgresDB[domain.OAuthFlow](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthFlowRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new flow.
+func (fr *OAuthFlowRepository) Insert(ctx context.Context, flow *domain.OAuthFlow) error {
+	query := `
+		INSERT INTO oauth_flows (
+			state, provider, code_verifier, nonce, link_user_id, created_at,
+			expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := fr.q(ctx).Exec(ctx, query,
+		flow.State,
+		flow.Provider.String(),
+		flow.CodeVerifier,
+		flow.Nonce,
+		nullString(flow.LinkUserID),
+		flow.CreatedAt,
+		flow.ExpiresAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert oauth flow")
+	}
+	return nil
+}
+
+// Take removes a flow and returns it. Deleting and reading happen in one
+// statement, so two concurrent callbacks cannot both complete the flow.
+func (fr *OAuthFlowRepository) Take(ctx context.Context, state string) (*domain.OAuthFlow, error) {
+	query := `
+		DELETE FROM oauth_flows
+		WHERE state = $1
+		RETURNING state, provider, code_verifier, nonce, link_user_id,
+				  created_at, expires_at`
+
+	var flow domain.OAuthFlow
+	var provider string
+	var linkUserID sql.NullString
+	err := fr.q(ctx).QueryRow(ctx, query, state).Scan(
+		&flow.State,
+		&provider,
+		&flow.CodeVerifier,
+		&flow.Nonce,
+		&linkUserID,
+		&flow.CreatedAt,
+		&flow.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrOAuthFlowNotFound
+		}
+		return nil, errors.Wrap(err, "failed to take oauth flow")
+	}
+
+	flow.Provider, err = domain.NewIdentityProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+	flow.LinkUserID = linkUserID.String
+	return &flow, nil
+}
+
+// DeleteExpired removes every flow that expired before a point in time and
+// returns the number of removed flows.
+func (fr *OAuthFlowRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := fr.q(ctx).Exec(ctx, `DELETE FROM oauth_flows WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete expired oauth flows")
+	}
+	return result.RowsAffected(), nil
+}
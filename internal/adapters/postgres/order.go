@@ -0,0 +1,338 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// OrderRepository stores orders and their items in the orders and
+// order_items tables.
+type OrderRepository struct {
+	*Postgres[domain.Order]
+}
+
+// NewOrderRepository creates a new OrderRepository.
+func NewOrderRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*OrderRepository, error) {
+	pg, err := NewPostgresDB[domain.Order](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &OrderRepository{Postgres: pg}, nil
+}
+
+const orderColumns = `
+	id, user_id, stripe_payment_intent_id, stripe_customer_id, order_number,
+	billing_email, billing_name, total_amount, currency, status, created_at,
+	updated_at, completed_at`
+
+// Insert adds a new order along with its items. The price of each item is
+// the price of its product at the time of purchase.
+func (or *OrderRepository) Insert(ctx context.Context, order *domain.Order) error {
+	tx, err := or.q(ctx).Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO orders (`+orderColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`,
+		order.ID,
+		order.UserID,
+		order.StripePaymentID,
+		nullString(order.StripeCustomerID),
+		order.OrderNumber,
+		order.BillingEmail,
+		nullString(order.BillingName),
+		order.GrandTotal,
+		order.CurrencyID,
+		order.Status.String(),
+		order.CreatedAt,
+		order.UpdatedAt,
+		order.CompletedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert order")
+	}
+	for _, item := range order.Items {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO order_items (
+				id, order_id, product_id, product_name, product_price, quantity,
+				status, line_total, created_at, updated_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			item.ID,
+			order.ID,
+			item.Product.ID,
+			item.Product.Name,
+			item.Product.Price,
+			item.Quantity,
+			item.Status.String(),
+			item.Product.Price*item.Quantity,
+			item.CreatedAt,
+			item.UpdatedAt,
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to insert order item")
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// GetByID retrieves an order and its items by the order's ID.
+func (or *OrderRepository) GetByID(ctx context.Context, id string) (*domain.Order, error) {
+	row := or.q(ctx).QueryRow(ctx, `SELECT `+orderColumns+` FROM orders WHERE id = $1`, id)
+	return or.getOrder(ctx, row)
+}
+
+// GetByOrderNumber retrieves an order and its items by its public order
+// number.
+func (or *OrderRepository) GetByOrderNumber(ctx context.Context, number string) (*domain.Order, error) {
+	row := or.q(ctx).QueryRow(ctx, `SELECT `+orderColumns+` FROM orders WHERE order_number = $1`, number)
+	return or.getOrder(ctx, row)
+}
+
+func (or *OrderRepository) getOrder(ctx context.Context, row pgx.Row) (*domain.Order, error) {
+	order, err := scanOrder(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrOrderNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get order")
+	}
+	order.Items, err = or.listItems(ctx, order.ID)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// ListByCustomer retrieves every order of a customer along with its items,
+// most recent first.
+func (or *OrderRepository) ListByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error) {
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := or.q(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list orders")
+	}
+	defer rows.Close()
+
+	orders := make([]*domain.Order, 0)
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan order")
+		}
+		orders = append(orders, order)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list orders")
+	}
+
+	for _, order := range orders {
+		order.Items, err = or.listItems(ctx, order.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+// UpdateStatus changes the fulfillment status of an order and of all of its
+// items.
+func (or *OrderRepository) UpdateStatus(ctx context.Context, id string, status domain.FulfillmentStatus) error {
+	tx, err := or.q(ctx).Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	result, err := tx.Exec(ctx, `
+		UPDATE orders
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, id, status.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to update order status")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrOrderNotFound
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE order_items
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $1 AND deleted_at IS NULL`, id, status.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to update order item status")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// UpdateStatusFrom changes the fulfillment status of an order and of all of
+// its items from one status to another. It returns
+// domain.ErrOrderStatusChanged if the order is not in the status from, so
+// that only one of two concurrent callers moves the order.
+func (or *OrderRepository) UpdateStatusFrom(ctx context.Context, id string, from, to domain.FulfillmentStatus) error {
+	tx, err := or.q(ctx).Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	result, err := tx.Exec(ctx, `
+		UPDATE orders
+		SET status = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $2`, id, from.String(), to.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to update order status")
+	}
+	if result.RowsAffected() == 0 {
+		var exists bool
+		err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM orders WHERE id = $1)`, id).Scan(&exists)
+		if err != nil {
+			return errors.Wrap(err, "failed to update order status")
+		}
+		if !exists {
+			return domain.ErrOrderNotFound
+		}
+		return domain.ErrOrderStatusChanged
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE order_items
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE order_id = $1 AND deleted_at IS NULL`, id, to.String())
+	if err != nil {
+		return errors.Wrap(err, "failed to update order item status")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	return nil
+}
+
+// AnonymizeByCustomer replaces the billing details of every order of a
+// customer: the billing email by email, and the billing name by nothing.
+// Amounts and items are kept for accounting.
+func (or *OrderRepository) AnonymizeByCustomer(ctx context.Context, customerID, email string) error {
+	_, err := or.q(ctx).Exec(ctx, `
+		UPDATE orders
+		SET billing_email = $2, billing_name = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1`, customerID, email)
+	return errors.Wrap(err, "failed to anonymize orders")
+}
+
+// listItems retrieves the items of an order. Product details are the ones
+// at the time of purchase.
+func (or *OrderRepository) listItems(ctx context.Context, orderID string) ([]domain.LineItem, error) {
+	query := `
+		SELECT id, product_id, product_name, product_price, quantity, status,
+			   created_at, updated_at
+		FROM order_items
+		WHERE order_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at`
+
+	rows, err := or.q(ctx).Query(ctx, query, orderID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list order items")
+	}
+	defer rows.Close()
+
+	items := make([]domain.LineItem, 0)
+	for rows.Next() {
+		var item domain.LineItem
+		var status string
+		err = rows.Scan(
+			&item.ID,
+			&item.Product.ID,
+			&item.Product.Name,
+			&item.Product.Price,
+			&item.Quantity,
+			&status,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan order item")
+		}
+		item.Status, err = domain.NewFulfillmentStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, errors.Wrap(rows.Err(), "failed to list order items")
+}
+
+// scanOrder scans a row of orderColumns into a domain.Order, without its
+// items.
+func scanOrder(row pgx.Row) (*domain.Order, error) {
+	var order domain.Order
+	var stripeCustomerID, billingName sql.NullString
+	var status string
+	var completedAt sql.NullTime
+
+	err := row.Scan(
+		&order.ID,
+		&order.UserID,
+		&order.StripePaymentID,
+		&stripeCustomerID,
+		&order.OrderNumber,
+		&order.BillingEmail,
+		&billingName,
+		&order.GrandTotal,
+		&order.CurrencyID,
+		&status,
+		&order.CreatedAt,
+		&order.UpdatedAt,
+		&completedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	order.StripeCustomerID = stripeCustomerID.String
+	order.BillingName = billingName.String
+	if completedAt.Valid {
+		order.CompletedAt = &completedAt.Time
+	}
+	order.Status, err = domain.NewFulfillmentStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
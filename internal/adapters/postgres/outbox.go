@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// EmailOutboxRepository stores emails waiting to be delivered in the
+// email_outbox table. Emails are inserted with the change that caused them,
+// so an email is only delivered once that change is committed.
+type EmailOutboxRepository struct {
+	*Postgres[domain.OutboxEmail]
+}
+
+// NewEmailOutboxRepository creates a new EmailOutboxRepository.
+func NewEmailOutboxRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*EmailOutboxRepository, error) {
+	pg, err := NewPostgresDB[domain.OutboxEmail](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailOutboxRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new email to the outbox.
+func (er *EmailOutboxRepository) Insert(ctx context.Context, email *domain.OutboxEmail) error {
+	query := `
+		INSERT INTO email_outbox (
+			id, recipient, template, subject, text_body, html_body, status,
+			attempts, next_attempt_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := er.q(ctx).Exec(ctx, query,
+		email.ID,
+		email.To,
+		email.Template.String(),
+		email.Subject,
+		email.Text,
+		email.HTML,
+		email.Status.String(),
+		email.Attempts,
+		email.NextAttemptAt,
+		email.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert email")
+	}
+	return nil
+}
+
+// ClaimDue returns up to limit pending emails that are due at a point in
+// time, oldest first, and pushes their next attempt back by lease. Rows
+// claimed by another dispatcher at the same time are skipped.
+func (er *EmailOutboxRepository) ClaimDue(
+	ctx context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.OutboxEmail, error) {
+	query := `
+		UPDATE email_outbox
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id
+			FROM email_outbox
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, template, subject, text_body, html_body, status,
+				  attempts, next_attempt_at, last_error, created_at, sent_at`
+
+	rows, err := er.q(ctx).Query(ctx, query, at, at.Add(lease), limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to claim emails")
+	}
+	defer rows.Close()
+
+	var emails []*domain.OutboxEmail
+	for rows.Next() {
+		var email domain.OutboxEmail
+		var template, status string
+		var lastError sql.NullString
+		var sentAt sql.NullTime
+		err = rows.Scan(
+			&email.ID,
+			&email.To,
+			&template,
+			&email.Subject,
+			&email.Text,
+			&email.HTML,
+			&status,
+			&email.Attempts,
+			&email.NextAttemptAt,
+			&lastError,
+			&email.CreatedAt,
+			&sentAt,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan email")
+		}
+		email.Template, err = domain.NewEmailTemplate(template)
+		if err != nil {
+			return nil, err
+		}
+		email.Status, err = domain.NewEmailStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		email.LastError = lastError.String
+		if sentAt.Valid {
+			email.SentAt = &sentAt.Time
+		}
+		emails = append(emails, &email)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to claim emails")
+	}
+	return emails, nil
+}
+
+// MarkSent marks an email as delivered and discards its body.
+func (er *EmailOutboxRepository) MarkSent(ctx context.Context, id string, at time.Time) error {
+	result, err := er.q(ctx).Exec(ctx, `
+		UPDATE email_outbox
+		SET status = 'sent', attempts = attempts + 1, sent_at = $2,
+			text_body = '', html_body = ''
+		WHERE id = $1`, id, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark email sent")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrEmailNotFound
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt. A nil next attempt marks the email as
+// dead.
+func (er *EmailOutboxRepository) MarkFailed(
+	ctx context.Context,
+	id string,
+	attempts int,
+	next *time.Time,
+	lastError string,
+) error {
+	result, err := er.q(ctx).Exec(ctx, `
+		UPDATE email_outbox
+		SET attempts = $2,
+			last_error = $3,
+			status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE status END,
+			next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE id = $1`, id, attempts, lastError, next)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark email failed")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrEmailNotFound
+	}
+	return nil
+}
+
+// DeleteExpired removes every sent or dead email created before a point in
+// time and returns the number of removed emails.
+func (er *EmailOutboxRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := er.q(ctx).Exec(ctx, `
+		DELETE FROM email_outbox
+		WHERE status <> 'pending' AND created_at < $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete old emails")
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,266 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// PasskeyRepository stores the passkeys of customers in the user_passkeys
+// table.
+type PasskeyRepository struct {
+	*Postgres[domain.Passkey]
+}
+
+// NewPasskeyRepository creates a new PasskeyRepository.
+func NewPasskeyRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*PasskeyRepository, error) {
+	pg, err := NewPostgresDB[domain.Passkey](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new passkey. A credential can only be registered once.
+func (pr *PasskeyRepository) Insert(ctx context.Context, passkey *domain.Passkey) error {
+	query := `
+		INSERT INTO user_passkeys (
+			id, user_id, credential_id, public_key, algorithm, sign_count,
+			backed_up, name, created_at, last_used_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := pr.q(ctx).Exec(ctx, query,
+		passkey.ID,
+		passkey.UserID,
+		passkey.CredentialID,
+		passkey.PublicKey,
+		passkey.Algorithm,
+		int64(passkey.SignCount),
+		passkey.BackedUp,
+		passkey.Name,
+		passkey.CreatedAt,
+		passkey.LastUsedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return domain.ErrPasskeyExists
+		}
+		return errors.Wrap(err, "failed to insert passkey")
+	}
+	return nil
+}
+
+// GetByCredentialID retrieves a passkey by its credential ID.
+func (pr *PasskeyRepository) GetByCredentialID(
+	ctx context.Context,
+	credentialID []byte,
+) (*domain.Passkey, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, algorithm, sign_count,
+			   backed_up, name, created_at, last_used_at
+		FROM user_passkeys
+		WHERE credential_id = $1`
+
+	passkey, err := scanPasskey(pr.q(ctx).QueryRow(ctx, query, credentialID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrPasskeyNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get passkey by credential id")
+	}
+	return passkey, nil
+}
+
+// ListByCustomer retrieves the passkeys of a customer, oldest first.
+func (pr *PasskeyRepository) ListByCustomer(
+	ctx context.Context,
+	customerID string,
+) ([]*domain.Passkey, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, algorithm, sign_count,
+			   backed_up, name, created_at, last_used_at
+		FROM user_passkeys
+		WHERE user_id = $1
+		ORDER BY created_at`
+
+	rows, err := pr.q(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list passkeys")
+	}
+	defer rows.Close()
+
+	passkeys := make([]*domain.Passkey, 0)
+	for rows.Next() {
+		passkey, err := scanPasskey(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan passkey")
+		}
+		passkeys = append(passkeys, passkey)
+	}
+	return passkeys, errors.Wrap(rows.Err(), "failed to list passkeys")
+}
+
+// UpdateUsage records the signature counter a passkey last reported and the
+// time it was used at.
+func (pr *PasskeyRepository) UpdateUsage(
+	ctx context.Context,
+	id string,
+	signCount uint32,
+	at time.Time,
+) error {
+	result, err := pr.q(ctx).Exec(ctx, `
+		UPDATE user_passkeys
+		SET sign_count = $2, last_used_at = $3
+		WHERE id = $1`, id, int64(signCount), at)
+	if err != nil {
+		return errors.Wrap(err, "failed to update passkey")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// Delete removes a passkey.
+func (pr *PasskeyRepository) Delete(ctx context.Context, id string) error {
+	_, err := pr.q(ctx).Exec(ctx, `DELETE FROM user_passkeys WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete passkey")
+	}
+	return nil
+}
+
+// scanPasskey scans a database row into a domain.Passkey struct.
+func scanPasskey(row pgx.Row) (*domain.Passkey, error) {
+	var passkey domain.Passkey
+	var signCount int64
+	var lastUsedAt sql.NullTime
+
+	err := row.Scan(
+		&passkey.ID,
+		&passkey.UserID,
+		&passkey.CredentialID,
+		&passkey.PublicKey,
+		&passkey.Algorithm,
+		&signCount,
+		&passkey.BackedUp,
+		&passkey.Name,
+		&passkey.CreatedAt,
+		&lastUsedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	passkey.SignCount = uint32(signCount) //nolint:gosec // The column only holds uint32 values.
+	if lastUsedAt.Valid {
+		passkey.LastUsedAt = &lastUsedAt.Time
+	}
+	return &passkey, nil
+}
+
+// PasskeyCeremonyRepository stores WebAuthn ceremonies in the
+// passkey_ceremonies table.
+type PasskeyCeremonyRepository struct {
+	*Postgres[domain.PasskeyCeremony]
+}
+
+// NewPasskeyCeremonyRepository creates a new PasskeyCeremonyRepository.
+func NewPasskeyCeremonyRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*PasskeyCeremonyRepository, error) {
+	pg, err := NewPostgresDB[domain.PasskeyCeremony](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyCeremonyRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new ceremony.
+func (cr *PasskeyCeremonyRepository) Insert(ctx context.Context, ceremony *domain.PasskeyCeremony) error {
+	query := `
+		INSERT INTO passkey_ceremonies (
+			id, purpose, challenge, user_id, email, created_at, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := cr.q(ctx).Exec(ctx, query,
+		ceremony.ID,
+		ceremony.Purpose.String(),
+		ceremony.Challenge,
+		nullString(ceremony.UserID),
+		nullString(ceremony.Email),
+		ceremony.CreatedAt,
+		ceremony.ExpiresAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert passkey ceremony")
+	}
+	return nil
+}
+
+// Take removes a ceremony and returns it. Deleting and reading happen in one
+// statement, so a ceremony cannot be completed twice.
+func (cr *PasskeyCeremonyRepository) Take(ctx context.Context, id string) (*domain.PasskeyCeremony, error) {
+	query := `
+		DELETE FROM passkey_ceremonies
+		WHERE id = $1
+		RETURNING id, purpose, challenge, user_id, email, created_at, expires_at`
+
+	var ceremony domain.PasskeyCeremony
+	var purpose string
+	var userID, email sql.NullString
+	err := cr.q(ctx).QueryRow(ctx, query, id).Scan(
+		&ceremony.ID,
+		&purpose,
+		&ceremony.Challenge,
+		&userID,
+		&email,
+		&ceremony.CreatedAt,
+		&ceremony.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrPasskeyCeremonyNotFound
+		}
+		return nil, errors.Wrap(err, "failed to take passkey ceremony")
+	}
+
+	ceremony.Purpose, err = domain.NewPasskeyCeremonyPurpose(purpose)
+	if err != nil {
+		return nil, err
+	}
+	ceremony.UserID = userID.String
+	ceremony.Email = email.String
+	return &ceremony, nil
+}
+
+// DeleteExpired removes every ceremony that expired before a point in time
+// and returns the number of removed ceremonies.
+func (cr *PasskeyCeremonyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := cr.q(ctx).Exec(ctx, `DELETE FROM passkey_ceremonies WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete expired passkey ceremonies")
+	}
+	return result.RowsAffected(), nil
+}
@@ -8,6 +8,8 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -47,38 +49,58 @@ func (p *Postgres[T]) Close() error {
 // CustomerRepository implements the CustomerRepository interface for domain.Customer.
 type CustomerRepository struct {
 	*Postgres[domain.Customer]
+	// hashParams are the parameters passwords are hashed with.
+	hashParams crypto.Argon2Params
 }
 
-// NewCustomerRepository creates a new CustomerRepository.
+// NewCustomerRepository creates a new CustomerRepository that hashes
+// passwords with hashParams, such as crypto.DefaultArgon2Params().
 func NewCustomerRepository(
 	ctx context.Context,
 	cfg *pgxpool.Config,
 	logger loggers.Logger,
 	tel telemetry.Telemetry,
+	hashParams crypto.Argon2Params,
 ) (*CustomerRepository, error) {
+	err := hashParams.Validate()
+	if err != nil {
+		return nil, err
+	}
 	pg, err := NewPostgresDB[domain.Customer](ctx, cfg, logger, tel)
 	if err != nil {
 		return nil, err
 	}
-	return &CustomerRepository{Postgres: pg}, nil
+	return &CustomerRepository{Postgres: pg, hashParams: hashParams}, nil
 }
 
-// Insert adds a new customer to the database.
-func (cr *CustomerRepository) Insert(customer *domain.Customer) error {
-	ctx := context.Background()
+// HashParams returns the parameters passwords are hashed with.
+func (cr *CustomerRepository) HashParams() crypto.Argon2Params {
+	return cr.hashParams
+}
 
-	id, err := uuid.New()
-	if err != nil {
-		return errors.Wrap(err, "failed to generate customer ID")
+// Insert adds a new customer to the database. It returns
+// domain.ErrEmailTaken if an active customer has the same email address.
+func (cr *CustomerRepository) Insert(ctx context.Context, customer *domain.Customer) error {
+	// The ID chosen by domain.NewCustomer is kept, since a passkey may
+	// already have been created for it.
+	var err error
+	if customer.ID == "" {
+		customer.ID, err = uuid.New()
+		if err != nil {
+			return errors.Wrap(err, "failed to generate customer ID")
+		}
 	}
-	customer.ID = id
 
-	hashedPassword, err := crypto.GenerateHashedPassword(customer.PasswordHash)
-	if err != nil {
-		return errors.Wrap(err, "failed to hash password")
+	// Customers who sign in through an identity provider have no password.
+	hashedPassword := ""
+	if customer.HasPassword() {
+		hashedPassword, err = crypto.GenerateHashedPasswordWithParams(customer.PasswordHash, cr.hashParams)
+		if err != nil {
+			return errors.Wrap(err, "failed to hash password")
+		}
 	}
 
-	tx, err := cr.db.Begin(ctx)
+	tx, err := cr.q(ctx).Begin(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to begin transaction")
 	}
@@ -98,13 +120,16 @@ func (cr *CustomerRepository) Insert(customer *domain.Customer) error {
 		nullString(customer.AuthZeroUserID),
 		customer.Email,
 		customer.EmailVerified,
-		hashedPassword,
+		nullString(hashedPassword),
 		customer.TotalPurchasesAmount,
 		customer.TotalPurchasesCount,
 		customer.CreatedAt,
 		customer.UpdatedAt,
 		customer.LastLoginAt,
 	)
+	if isUniqueViolation(err) {
+		return domain.ErrEmailTaken
+	}
 	if err != nil {
 		return errors.Wrap(err, "failed to insert customer")
 	}
@@ -118,11 +143,52 @@ func (cr *CustomerRepository) Insert(customer *domain.Customer) error {
 	return nil
 }
 
-// Delete soft deletes a customer by setting deleted_at timestamp.
-func (cr *CustomerRepository) Delete(customer *domain.Customer) error {
-	ctx := context.Background()
+// ImportHashed adds a customer migrated from another store. Unlike Insert,
+// the PasswordHash of the customer is stored as-is: it must already be a
+// hash of an algorithm crypto.ValidatePassword supports, such as bcrypt,
+// and is upgraded to Argon2id the next time the customer signs in. A
+// customer whose email is already taken by an active customer is skipped,
+// and ImportHashed reports whether the customer was inserted.
+func (cr *CustomerRepository) ImportHashed(ctx context.Context, customer *domain.Customer) (bool, error) {
+	hashedPassword := ""
+	if customer.HasPassword() {
+		hashedPassword = string(customer.PasswordHash)
+		err := crypto.CheckHash(hashedPassword)
+		if err != nil {
+			return false, err
+		}
+	}
 
-	tx, err := cr.db.Begin(ctx)
+	query := `
+		INSERT INTO users (
+			id, auth0_user_id, email, email_verified, password_hash,
+			total_purchases_amount, total_purchases_count, created_at,
+			updated_at, last_login_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (email) WHERE deleted_at IS NULL DO NOTHING`
+
+	result, err := cr.q(ctx).Exec(ctx, query,
+		customer.ID,
+		nullString(customer.AuthZeroUserID),
+		customer.Email,
+		customer.EmailVerified,
+		nullString(hashedPassword),
+		customer.TotalPurchasesAmount,
+		customer.TotalPurchasesCount,
+		customer.CreatedAt,
+		customer.UpdatedAt,
+		customer.LastLoginAt,
+	)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to import customer")
+	}
+	return result.RowsAffected() == 1, nil
+}
+
+// Delete soft deletes a customer by setting deleted_at timestamp. It returns
+// domain.ErrCustomerNotFound if the customer is missing or already deleted.
+func (cr *CustomerRepository) Delete(ctx context.Context, customer *domain.Customer) error {
+	tx, err := cr.q(ctx).Begin(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to begin transaction")
 	}
@@ -142,7 +208,7 @@ func (cr *CustomerRepository) Delete(customer *domain.Customer) error {
 
 	rowsAffected := result.RowsAffected()
 	if rowsAffected == 0 {
-		return errors.New("customer not found or already deleted")
+		return domain.ErrCustomerNotFound
 	}
 
 	err = tx.Commit(ctx)
@@ -154,11 +220,11 @@ func (cr *CustomerRepository) Delete(customer *domain.Customer) error {
 	return nil
 }
 
-// UpdateInformation updates customer information (excluding password).
-func (cr *CustomerRepository) UpdateInformation(customer *domain.Customer) error {
-	ctx := context.Background()
-
-	tx, err := cr.db.Begin(ctx)
+// UpdateInformation updates customer information (excluding password). It
+// returns domain.ErrEmailTaken if an active customer already has the new
+// email address.
+func (cr *CustomerRepository) UpdateInformation(ctx context.Context, customer *domain.Customer) error {
+	tx, err := cr.q(ctx).Begin(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to begin transaction")
 	}
@@ -176,13 +242,16 @@ func (cr *CustomerRepository) UpdateInformation(customer *domain.Customer) error
 		customer.Email,
 		customer.EmailVerified,
 	)
+	if isUniqueViolation(err) {
+		return domain.ErrEmailTaken
+	}
 	if err != nil {
 		return errors.Wrap(err, "failed to update customer information")
 	}
 
 	rowsAffected := result.RowsAffected()
 	if rowsAffected == 0 {
-		return errors.New("customer not found")
+		return domain.ErrCustomerNotFound
 	}
 
 	err = tx.Commit(ctx)
@@ -195,15 +264,13 @@ func (cr *CustomerRepository) UpdateInformation(customer *domain.Customer) error
 }
 
 // UpdatePassword updates the customer's password.
-func (cr *CustomerRepository) UpdatePassword(customer *domain.Customer) error {
-	ctx := context.Background()
-
-	hashedPassword, err := crypto.GenerateHashedPassword(customer.PasswordHash)
+func (cr *CustomerRepository) UpdatePassword(ctx context.Context, customer *domain.Customer) error {
+	hashedPassword, err := crypto.GenerateHashedPasswordWithParams(customer.PasswordHash, cr.hashParams)
 	if err != nil {
 		return errors.Wrap(err, "failed to hash password")
 	}
 
-	tx, err := cr.db.Begin(ctx)
+	tx, err := cr.q(ctx).Begin(ctx)
 	if err != nil {
 		return errors.Wrap(err, "failed to begin transaction")
 	}
@@ -223,7 +290,7 @@ func (cr *CustomerRepository) UpdatePassword(customer *domain.Customer) error {
 
 	rowsAffected := result.RowsAffected()
 	if rowsAffected == 0 {
-		return errors.New("customer not found")
+		return domain.ErrCustomerNotFound
 	}
 
 	err = tx.Commit(ctx)
@@ -236,84 +303,258 @@ func (cr *CustomerRepository) UpdatePassword(customer *domain.Customer) error {
 	return nil
 }
 
-// GetByID retrieves a customer by their ID.
-func (cr *CustomerRepository) GetByID(id string) (*domain.Customer, error) {
-	ctx := context.Background()
-
+// UpdateLastLogin records the time the customer last signed in.
+func (cr *CustomerRepository) UpdateLastLogin(ctx context.Context, customer *domain.Customer) error {
 	query := `
-		SELECT id, auth0_user_id, email, email_verified, password_hash,
-			   total_purchases_amount, total_purchases_count, created_at,
-			   updated_at, last_login_at, deleted_at
-		FROM users 
+		UPDATE users 
+		SET last_login_at = $2
 		WHERE id = $1 AND deleted_at IS NULL`
 
-	row := cr.db.QueryRow(ctx, query, id)
-
-	customer, err := cr.scanCustomer(row)
+	result, err := cr.q(ctx).Exec(ctx, query, customer.ID, customer.LastLoginAt)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("customer not found")
-		}
-		return nil, errors.Wrap(err, "failed to get customer by ID")
+		return errors.Wrap(err, "failed to update customer last login")
 	}
 
-	return customer, nil
+	if result.RowsAffected() == 0 {
+		return domain.ErrCustomerNotFound
+	}
+
+	return nil
+}
+
+// GetByID retrieves a customer by their ID.
+func (cr *CustomerRepository) GetByID(ctx context.Context, id string) (*domain.Customer, error) {
+	return cr.getBy(ctx, "id", id, activeRows)
 }
 
 // GetByOAuthID retrieves a customer by their OAuth ID.
-func (cr *CustomerRepository) GetByOAuthID(oauthID string) (*domain.Customer, error) {
-	ctx := context.Background()
+func (cr *CustomerRepository) GetByOAuthID(ctx context.Context, oauthID string) (*domain.Customer, error) {
+	return cr.getBy(ctx, "auth0_user_id", oauthID, activeRows)
+}
 
+// GetByEmail retrieves a customer by their email address.
+func (cr *CustomerRepository) GetByEmail(ctx context.Context, email string) (*domain.Customer, error) {
+	return cr.getBy(ctx, "email", email, activeRows)
+}
+
+// SearchByEmail retrieves up to limit customers whose email address contains
+// a query, ignoring case, ordered by email address. Deleted customers are
+// included, so that support can find and restore them.
+func (cr *CustomerRepository) SearchByEmail(
+	ctx context.Context,
+	query string,
+	limit int,
+) ([]*domain.Customer, error) {
+	sqlQuery := `
+		SELECT ` + customerColumns + `
+		FROM users
+		WHERE email ILIKE '%' || $1 || '%' ESCAPE '\' AND ` + allRows.where() + `
+		ORDER BY email
+		LIMIT $2`
+
+	return cr.list(ctx, sqlQuery, escapeLike(query), limit)
+}
+
+// GetByIDIncludingDeleted retrieves a customer by their ID, even if they
+// were deleted.
+func (cr *CustomerRepository) GetByIDIncludingDeleted(ctx context.Context, id string) (*domain.Customer, error) {
+	return cr.getBy(ctx, "id", id, allRows)
+}
+
+// ListDeleted retrieves up to limit deleted customers, most recently deleted
+// first, so that support can find accounts to restore.
+func (cr *CustomerRepository) ListDeleted(ctx context.Context, limit int) ([]*domain.Customer, error) {
 	query := `
-		SELECT id, auth0_user_id, email, email_verified, password_hash,
-			   total_purchases_amount, total_purchases_count, created_at,
-			   updated_at, last_login_at, deleted_at
-		FROM users 
-		WHERE auth0_user_id = $1 AND deleted_at IS NULL`
+		SELECT ` + customerColumns + `
+		FROM users
+		WHERE ` + deletedRows.where() + `
+		ORDER BY deleted_at DESC
+		LIMIT $1`
+
+	return cr.list(ctx, query, limit)
+}
 
-	row := cr.db.QueryRow(ctx, query, oauthID)
+// Restore undoes the soft deletion of a customer. It returns
+// domain.ErrCustomerNotFound if there is no deleted customer with the ID, or
+// if their personal data was already erased, and domain.ErrEmailTaken if
+// another customer signed up with their email address in the meantime.
+func (cr *CustomerRepository) Restore(ctx context.Context, id string) error {
+	query := `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NOT NULL AND erased_at IS NULL`
 
-	customer, err := cr.scanCustomer(row)
+	result, err := cr.q(ctx).Exec(ctx, query, id)
+	if isUniqueViolation(err) {
+		return domain.ErrEmailTaken
+	}
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("customer not found")
-		}
-		return nil, errors.Wrap(err, "failed to get customer by OAuth ID")
+		return errors.Wrap(err, "failed to restore customer")
 	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrCustomerNotFound
+	}
+	cr.logger.Info("customer restored successfully", "customer_id", id)
+	return nil
+}
 
-	return customer, nil
+// ListErasable retrieves up to limit customers deleted before a point in
+// time whose personal data was not erased yet, deleted first.
+func (cr *CustomerRepository) ListErasable(
+	ctx context.Context,
+	deletedBefore time.Time,
+	limit int,
+) ([]*domain.Customer, error) {
+	query := `
+		SELECT ` + customerColumns + `
+		FROM users
+		WHERE deleted_at < $1 AND erased_at IS NULL
+		ORDER BY deleted_at
+		LIMIT $2`
+
+	return cr.list(ctx, query, deletedBefore, limit)
 }
 
-// GetByEmail retrieves a customer by their email address.
-func (cr *CustomerRepository) GetByEmail(email string) (*domain.Customer, error) {
-	ctx := context.Background()
+// Erase anonymizes the account of a deleted customer: their email address
+// is replaced by domain.ErasedEmail, and their credentials, linked
+// identities, one-time tokens, roles and pending email are removed. Their
+// row is kept, since orders still reference it. Erase returns
+// domain.ErrCustomerNotFound if there is no deleted customer with the ID
+// whose data was not erased yet.
+func (cr *CustomerRepository) Erase(ctx context.Context, id string) error {
+	tx, err := cr.q(ctx).Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var email string
+	err = tx.QueryRow(ctx, `
+		SELECT email FROM users
+		WHERE id = $1 AND deleted_at IS NOT NULL AND erased_at IS NULL
+		FOR UPDATE`, id).Scan(&email)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return domain.ErrCustomerNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to lock customer")
+	}
 
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET email = $2, email_verified = FALSE, password_hash = NULL,
+			auth0_user_id = NULL, erased_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, id, domain.ErasedEmail(id))
+	if err != nil {
+		return errors.Wrap(err, "failed to anonymize customer")
+	}
+
+	// Rows keyed by the customer, then rows keyed by their email address.
+	for _, table := range []string{
+		"user_sessions", "user_identities", "user_tokens", "user_mfa",
+		"user_recovery_codes", "user_passkeys", "user_roles",
+	} {
+		_, err = tx.Exec(ctx, "DELETE FROM "+table+" WHERE user_id = $1", id)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete from "+table)
+		}
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM oauth_flows WHERE link_user_id = $1", id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete OAuth flows")
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM login_throttles WHERE key = $1", domain.AccountThrottleKey(email))
+	if err != nil {
+		return errors.Wrap(err, "failed to delete login throttle")
+	}
+	_, err = tx.Exec(ctx, "DELETE FROM email_outbox WHERE recipient = $1", email)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete email")
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to commit transaction")
+	}
+	cr.logger.Info("customer erased successfully", "customer_id", id)
+	return nil
+}
+
+// Purge hard deletes the customers deleted before a point in time, along
+// with their sessions, identities and other rows that cascade, and returns
+// how many were purged. Customers with orders or downloads are kept, since
+// those are financial records; their personal data is erased instead.
+func (cr *CustomerRepository) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
 	query := `
-		SELECT id, auth0_user_id, email, email_verified, password_hash,
+		DELETE FROM users
+		WHERE deleted_at < $1
+		  AND NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.id)
+		  AND NOT EXISTS (SELECT 1 FROM user_downloads WHERE user_downloads.user_id = users.id)`
+
+	result, err := cr.q(ctx).Exec(ctx, query, deletedBefore)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to purge customers")
+	}
+	return result.RowsAffected(), nil
+}
+
+// customerColumns are the columns of the users table scanCustomer scans, in
+// order.
+const customerColumns = `id, auth0_user_id, email, email_verified, password_hash,
 			   total_purchases_amount, total_purchases_count, created_at,
-			   updated_at, last_login_at, deleted_at
-		FROM users 
-		WHERE email = $1 AND deleted_at IS NULL`
+			   updated_at, last_login_at, deleted_at, erased_at`
 
-	row := cr.db.QueryRow(ctx, query, email)
+// getBy retrieves the customer whose column has a value among the rows of a
+// scope. The column must be unique among them.
+func (cr *CustomerRepository) getBy(
+	ctx context.Context,
+	column, value string,
+	scope softDeleteScope,
+) (*domain.Customer, error) {
+	query := `
+		SELECT ` + customerColumns + `
+		FROM users
+		WHERE ` + column + ` = $1 AND ` + scope.where()
 
-	customer, err := cr.scanCustomer(row)
+	customer, err := cr.scanCustomer(cr.q(ctx).QueryRow(ctx, query, value))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("customer not found")
+			return nil, domain.ErrCustomerNotFound
 		}
-		return nil, errors.Wrap(err, "failed to get customer by email")
+		return nil, errors.Wrap(err, "failed to get customer by "+column)
 	}
-
 	return customer, nil
 }
 
+// list retrieves the customers a query selects.
+func (cr *CustomerRepository) list(ctx context.Context, query string, args ...any) ([]*domain.Customer, error) {
+	rows, err := cr.q(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list customers")
+	}
+	defer rows.Close()
+
+	customers := make([]*domain.Customer, 0)
+	for rows.Next() {
+		customer, err := cr.scanCustomer(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan customer")
+		}
+		customers = append(customers, customer)
+	}
+	return customers, errors.Wrap(rows.Err(), "failed to list customers")
+}
+
 // scanCustomer scans a database row into a domain.Customer struct.
 func (cr *CustomerRepository) scanCustomer(row pgx.Row) (*domain.Customer, error) {
 	var customer domain.Customer
 	var auth0UserID sql.NullString
-	var passwordHash string
+	var passwordHash sql.NullString
 	var deletedAt sql.NullTime
+	var erasedAt sql.NullTime
 
 	err := row.Scan(
 		&customer.ID,
@@ -327,6 +568,7 @@ func (cr *CustomerRepository) scanCustomer(row pgx.Row) (*domain.Customer, error
 		&customer.UpdatedAt,
 		&customer.LastLoginAt,
 		&deletedAt,
+		&erasedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -341,8 +583,14 @@ func (cr *CustomerRepository) scanCustomer(row pgx.Row) (*domain.Customer, error
 		customer.DeletedAt = &deletedAt.Time
 	}
 
+	if erasedAt.Valid {
+		customer.ErasedAt = &erasedAt.Time
+	}
+
 	// Store the hashed password as bytes
-	customer.PasswordHash = []byte(passwordHash)
+	if passwordHash.Valid {
+		customer.PasswordHash = []byte(passwordHash.String)
+	}
 
 	return &customer, nil
 }
@@ -354,3 +602,9 @@ func nullString(s string) sql.NullString {
 	}
 	return sql.NullString{String: s, Valid: true}
 }
+
+// escapeLike escapes the wildcards of a LIKE pattern, so that a string is
+// matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,242 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// SessionRepository stores customer sessions in the user_sessions table.
+type SessionRepository struct {
+	*Postgres[domain.UserSession]
+}
+
+// NewSessionRepository creates a new SessionRepository.
+func NewSessionRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*SessionRepository, error) {
+	pg, err := NewPostgresDB[domain.UserSession](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new session to the database.
+func (sr *SessionRepository) Insert(ctx context.Context, session *domain.UserSession) error {
+	query := `
+		INSERT INTO user_sessions (
+			id, user_id, secret_hash, created_ip, user_agent,
+			created_at, last_seen_at, expires_at, absolute_expires_at,
+			authenticated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err := sr.q(ctx).Exec(ctx, query,
+		session.ID,
+		session.UserID,
+		session.SecretHash,
+		nullString(session.CreatedIP),
+		nullString(session.UserAgent),
+		session.CreatedAt,
+		session.LastSeenAt,
+		session.ExpiresAt,
+		session.AbsoluteExpiresAt,
+		session.AuthenticatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert session")
+	}
+	return nil
+}
+
+// GetByID retrieves a session by its ID.
+func (sr *SessionRepository) GetByID(ctx context.Context, id string) (*domain.UserSession, error) {
+	query := `
+		SELECT id, user_id, secret_hash, previous_secret_hash, rotated_at,
+			   created_ip, user_agent, created_at, last_seen_at, expires_at,
+			   absolute_expires_at, authenticated_at
+		FROM user_sessions
+		WHERE id = $1`
+
+	session, err := scanSession(sr.q(ctx).QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrSessionNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get session by ID")
+	}
+	return session, nil
+}
+
+// ListByCustomer retrieves all unexpired sessions of a customer, most
+// recently used first.
+func (sr *SessionRepository) ListByCustomer(
+	ctx context.Context,
+	customerID string,
+) ([]*domain.UserSession, error) {
+	query := `
+		SELECT id, user_id, secret_hash, previous_secret_hash, rotated_at,
+			   created_ip, user_agent, created_at, last_seen_at, expires_at,
+			   absolute_expires_at, authenticated_at
+		FROM user_sessions
+		WHERE user_id = $1
+		  AND expires_at > CURRENT_TIMESTAMP
+		  AND absolute_expires_at > CURRENT_TIMESTAMP
+		ORDER BY last_seen_at DESC`
+
+	rows, err := sr.q(ctx).Query(ctx, query, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sessions")
+	}
+	defer rows.Close()
+
+	sessions := make([]*domain.UserSession, 0)
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan session")
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, errors.Wrap(rows.Err(), "failed to list sessions")
+}
+
+// Touch records when a session was last used.
+func (sr *SessionRepository) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	result, err := sr.q(ctx).Exec(ctx, `
+		UPDATE user_sessions SET last_seen_at = $2 WHERE id = $1`, id, lastSeenAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to touch session")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
+
+// Rotate writes the renewed secret, expiry and last seen time of a session,
+// if the stored secret is still the one it was renewed from.
+func (sr *SessionRepository) Rotate(ctx context.Context, session *domain.UserSession) error {
+	// The secret the session was renewed from is its previous secret now.
+	query := `
+		UPDATE user_sessions
+		SET secret_hash = $2, previous_secret_hash = $3, rotated_at = $4,
+			last_seen_at = $5, expires_at = $6
+		WHERE id = $1 AND secret_hash = $3`
+
+	result, err := sr.q(ctx).Exec(ctx, query,
+		session.ID,
+		session.SecretHash,
+		session.PreviousSecretHash,
+		session.RotatedAt,
+		session.LastSeenAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate session")
+	}
+	if result.RowsAffected() > 0 {
+		return nil
+	}
+	var exists bool
+	err = sr.q(ctx).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM user_sessions WHERE id = $1)`, session.ID).
+		Scan(&exists)
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate session")
+	}
+	if !exists {
+		return domain.ErrSessionNotFound
+	}
+	return domain.ErrSessionRotated
+}
+
+// MarkAuthenticated records when the customer last proved their
+// credentials in a session.
+func (sr *SessionRepository) MarkAuthenticated(ctx context.Context, id string, at time.Time) error {
+	result, err := sr.q(ctx).Exec(ctx, `
+		UPDATE user_sessions SET authenticated_at = $2 WHERE id = $1`, id, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark session authenticated")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
+
+// Delete removes a session.
+func (sr *SessionRepository) Delete(ctx context.Context, id string) error {
+	_, err := sr.q(ctx).Exec(ctx, `DELETE FROM user_sessions WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete session")
+	}
+	return nil
+}
+
+// DeleteByCustomer removes every session of a customer.
+func (sr *SessionRepository) DeleteByCustomer(ctx context.Context, customerID string) error {
+	_, err := sr.q(ctx).Exec(ctx, `DELETE FROM user_sessions WHERE user_id = $1`, customerID)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete customer sessions")
+	}
+	return nil
+}
+
+// DeleteExpired removes every session that expired before a point in time
+// and returns the number of removed sessions.
+func (sr *SessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := sr.q(ctx).Exec(ctx, `
+		DELETE FROM user_sessions
+		WHERE expires_at <= $1 OR absolute_expires_at <= $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete expired sessions")
+	}
+	return result.RowsAffected(), nil
+}
+
+// scanSession scans a database row into a domain.UserSession struct.
+func scanSession(row pgx.Row) (*domain.UserSession, error) {
+	var session domain.UserSession
+	var createdIP, userAgent sql.NullString
+	var rotatedAt sql.NullTime
+
+	err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.SecretHash,
+		&session.PreviousSecretHash,
+		&rotatedAt,
+		&createdIP,
+		&userAgent,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+		&session.AbsoluteExpiresAt,
+		&session.AuthenticatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.CreatedIP = createdIP.String
+	session.UserAgent = userAgent.String
+	if rotatedAt.Valid {
+		session.RotatedAt = &rotatedAt.Time
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,37 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+// softDeleteScope chooses which rows of a table with a deleted_at column a
+// query sees. Rows are soft deleted by setting deleted_at, and only hard
+// deleted by a purge once their retention is over.
+//
+// Lookups only see active rows. Methods that see deleted rows, which are
+// meant for admins, say so in their name, such as GetByIDIncludingDeleted.
+// Unique constraints on such tables are partial indexes on active rows, so
+// that a deleted row does not block a new one, such as a customer signing
+// up again with the email address of their deleted account.
+type softDeleteScope int
+
+const (
+	// activeRows are the rows that were not deleted.
+	activeRows softDeleteScope = iota
+	// allRows are the rows, deleted or not.
+	allRows
+	// deletedRows are the rows that were deleted.
+	deletedRows
+)
+
+// where returns a condition that matches the rows of the scope.
+func (s softDeleteScope) where() string {
+	switch s {
+	case allRows:
+		return "TRUE"
+	case deletedRows:
+		return "deleted_at IS NOT NULL"
+	default:
+		return "deleted_at IS NULL"
+	}
+}
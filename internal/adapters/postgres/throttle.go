@@ -0,0 +1,131 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// LoginThrottleRepository stores login throttles in the login_throttles
+// table.
+type LoginThrottleRepository struct {
+	*Postgres[domain.LoginThrottle]
+}
+
+// NewLoginThrottleRepository creates a new LoginThrottleRepository.
+func NewLoginThrottleRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*LoginThrottleRepository, error) {
+	pg, err := NewPostgresDB[domain.LoginThrottle](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginThrottleRepository{Postgres: pg}, nil
+}
+
+const loginThrottleColumns = `key, failures, last_failure_at, locked_until, expires_at`
+
+// Get retrieves the throttle of a key.
+func (lr *LoginThrottleRepository) Get(ctx context.Context, key string) (*domain.LoginThrottle, error) {
+	row := lr.q(ctx).QueryRow(ctx, `
+		SELECT `+loginThrottleColumns+`
+		FROM login_throttles
+		WHERE key = $1`, key)
+	l, err := scanLoginThrottle(row)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrThrottleNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get login throttle")
+	}
+	return l, nil
+}
+
+// RecordFailure counts a failed attempt with a key at a point in time.
+// Failures older than the window are forgotten first. The count happens in
+// one statement, so concurrent attempts are all counted.
+func (lr *LoginThrottleRepository) RecordFailure(
+	ctx context.Context,
+	key string,
+	at time.Time,
+	window time.Duration,
+) (*domain.LoginThrottle, error) {
+	row := lr.q(ctx).QueryRow(ctx, `
+		INSERT INTO login_throttles (key, failures, last_failure_at, expires_at)
+		VALUES ($1, 1, $2, $4)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_throttles.last_failure_at <= $3 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = $2,
+			expires_at = GREATEST($4, login_throttles.locked_until)
+		RETURNING `+loginThrottleColumns,
+		key, at, at.Add(-window), at.Add(window))
+	l, err := scanLoginThrottle(row)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to record login failure")
+	}
+	return l, nil
+}
+
+// Lock locks a key out until a point in time.
+func (lr *LoginThrottleRepository) Lock(ctx context.Context, key string, until time.Time) error {
+	result, err := lr.q(ctx).Exec(ctx, `
+		UPDATE login_throttles
+		SET locked_until = $2, expires_at = GREATEST(expires_at, $2)
+		WHERE key = $1`, key, until)
+	if err != nil {
+		return errors.Wrap(err, "failed to lock login throttle")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrThrottleNotFound
+	}
+	return nil
+}
+
+// Reset forgets the failures and the lockout of a key.
+func (lr *LoginThrottleRepository) Reset(ctx context.Context, key string) error {
+	_, err := lr.q(ctx).Exec(ctx, `DELETE FROM login_throttles WHERE key = $1`, key)
+	if err != nil {
+		return errors.Wrap(err, "failed to reset login throttle")
+	}
+	return nil
+}
+
+// DeleteExpired removes every throttle that expired before a point in time
+// and returns the number of removed throttles.
+func (lr *LoginThrottleRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := lr.q(ctx).Exec(ctx, `DELETE FROM login_throttles WHERE expires_at <= $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete expired login throttles")
+	}
+	return result.RowsAffected(), nil
+}
+
+func scanLoginThrottle(row pgx.Row) (*domain.LoginThrottle, error) {
+	var l domain.LoginThrottle
+	var lockedUntil sql.NullTime
+	err := row.Scan(&l.Key, &l.Failures, &l.LastFailureAt, &lockedUntil, &l.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		l.LockedUntil = lockedUntil.Time
+	}
+	return &l, nil
+}
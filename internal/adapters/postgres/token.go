@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// TokenRepository stores one-time tokens in the user_tokens table.
+type TokenRepository struct {
+	*Postgres[domain.OneTimeToken]
+}
+
+// NewTokenRepository creates a new TokenRepository.
+func NewTokenRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*TokenRepository, error) {
+	pg, err := NewPostgresDB[domain.OneTimeToken](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new token to the database.
+func (tr *TokenRepository) Insert(ctx context.Context, token *domain.OneTimeToken) error {
+	query := `
+		INSERT INTO user_tokens (
+			id, user_id, purpose, secret_hash, email, created_at, expires_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := tr.q(ctx).Exec(ctx, query,
+		token.ID,
+		token.UserID,
+		token.Purpose.String(),
+		token.SecretHash,
+		token.Email,
+		token.CreatedAt,
+		token.ExpiresAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert token")
+	}
+	return nil
+}
+
+// GetByID retrieves a token by its ID.
+func (tr *TokenRepository) GetByID(ctx context.Context, id string) (*domain.OneTimeToken, error) {
+	query := `
+		SELECT id, user_id, purpose, secret_hash, email, created_at, expires_at,
+			   used_at
+		FROM user_tokens
+		WHERE id = $1`
+
+	var token domain.OneTimeToken
+	var purpose string
+	var usedAt sql.NullTime
+	err := tr.q(ctx).QueryRow(ctx, query, id).Scan(
+		&token.ID,
+		&token.UserID,
+		&purpose,
+		&token.SecretHash,
+		&token.Email,
+		&token.CreatedAt,
+		&token.ExpiresAt,
+		&usedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTokenNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get token by ID")
+	}
+
+	token.Purpose, err = domain.NewTokenPurpose(purpose)
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid {
+		token.UsedAt = &usedAt.Time
+	}
+	return &token, nil
+}
+
+// MarkUsed marks an unused token as used. The check and the update happen in
+// one statement, so a token presented twice at once is only accepted once.
+func (tr *TokenRepository) MarkUsed(ctx context.Context, id string, at time.Time) error {
+	result, err := tr.q(ctx).Exec(ctx, `
+		UPDATE user_tokens
+		SET used_at = $2
+		WHERE id = $1 AND used_at IS NULL`, id, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark token used")
+	}
+	if result.RowsAffected() == 0 {
+		_, err = tr.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		return domain.ErrTokenUsed
+	}
+	return nil
+}
+
+// RevokeByCustomer marks every unused token of a customer for a purpose as
+// used.
+func (tr *TokenRepository) RevokeByCustomer(
+	ctx context.Context,
+	customerID string,
+	purpose domain.TokenPurpose,
+	at time.Time,
+) error {
+	_, err := tr.q(ctx).Exec(ctx, `
+		UPDATE user_tokens
+		SET used_at = $3
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`,
+		customerID, purpose.String(), at)
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke tokens")
+	}
+	return nil
+}
+
+// CountSince counts the tokens issued to a customer for a purpose since a
+// point in time.
+func (tr *TokenRepository) CountSince(
+	ctx context.Context,
+	customerID string,
+	purpose domain.TokenPurpose,
+	since time.Time,
+) (int, error) {
+	var n int
+	err := tr.q(ctx).QueryRow(ctx, `
+		SELECT count(*)
+		FROM user_tokens
+		WHERE user_id = $1 AND purpose = $2 AND created_at >= $3`,
+		customerID, purpose.String(), since).Scan(&n)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to count tokens")
+	}
+	return n, nil
+}
+
+// DeleteExpired removes every token that expired before a point in time and
+// returns the number of removed tokens. Tokens are kept for a day past their
+// expiry, so that rate limits that count them still see them.
+func (tr *TokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	const retention = 24 * time.Hour
+	result, err := tr.q(ctx).Exec(ctx, `DELETE FROM user_tokens WHERE expires_at <= $1`, before.Add(-retention))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete expired tokens")
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/pkg/errors"
+)
+
+const (
+	// serializationFailure is the SQLSTATE of a transaction that could not
+	// be serialized with concurrent transactions.
+	serializationFailure = "40001"
+	// deadlockDetected is the SQLSTATE of a transaction that was chosen to
+	// break a deadlock.
+	deadlockDetected = "40P01"
+)
+
+// querier runs queries on the database. It is satisfied by both a pool and
+// a transaction, so that repositories run the same queries whether or not
+// they are inside a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	// Begin starts a transaction on a pool, or a savepoint in a
+	// transaction.
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type txKey struct{}
+
+// contextWithTx returns a copy of ctx that carries a transaction.
+func contextWithTx(ctx context.Context, tx pgx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// txFromContext returns the transaction carried by ctx, if any.
+func txFromContext(ctx context.Context) (pgx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(pgx.Tx)
+	return tx, ok
+}
+
+// q returns the transaction carried by ctx, started by TxManager.WithinTx,
+// or the pool of the repository outside of one. Every query of a repository
+// goes through q, so that repositories transparently join the ambient
+// transaction. A repository that begins its own transaction on q gets a
+// savepoint inside the ambient one.
+func (p *Postgres[T]) q(ctx context.Context) querier {
+	if tx, ok := txFromContext(ctx); ok {
+		return tx
+	}
+	return p.db
+}
+
+// defaultTxAttempts is how many times a transaction is attempted before a
+// serialization failure or a deadlock is returned.
+const defaultTxAttempts = 3
+
+// TxManager runs functions inside a database transaction spanning any
+// number of repositories, as a unit of work. The transaction is carried
+// through the context passed to the function, and every repository of this
+// package queries through it.
+type TxManager struct {
+	*Postgres[struct{}]
+	// attempts is how many times a transaction is attempted.
+	attempts int
+	// backoff is how long to wait before the second attempt. It doubles
+	// with every attempt.
+	backoff time.Duration
+}
+
+// NewTxManager creates a new TxManager.
+func NewTxManager(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*TxManager, error) {
+	pg, err := NewPostgresDB[struct{}](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &TxManager{
+		Postgres: pg,
+		attempts: defaultTxAttempts,
+		backoff:  10 * time.Millisecond,
+	}, nil
+}
+
+// WithinTx runs fn inside a transaction, which is committed if fn returns
+// nil and rolled back otherwise, including when fn panics.
+//
+// If ctx already carries a transaction, fn runs inside a savepoint of it
+// instead, which is released or rolled back in the same way, leaving the
+// outer transaction to its owner.
+//
+// The outermost transaction is attempted again when it fails because of a
+// serialization failure or a deadlock, so fn must not have effects outside
+// the database that cannot be repeated.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := txFromContext(ctx); ok {
+		return runTx(ctx, tx, fn)
+	}
+
+	backoff := m.backoff
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, m.db, fn)
+		if err == nil || !isRetryable(err) || attempt == m.attempts {
+			return err
+		}
+		m.logger.Warn("retrying transaction", "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// runTx begins a transaction on q, or a savepoint if q is a transaction,
+// and runs fn inside of it.
+func runTx(ctx context.Context, q querier, fn func(ctx context.Context) error) error {
+	tx, err := q.Begin(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to begin transaction")
+	}
+	defer func() {
+		// Rolling back a committed transaction does nothing.
+		_ = tx.Rollback(ctx)
+	}()
+
+	err = fn(contextWithTx(ctx, tx))
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(tx.Commit(ctx), "failed to commit transaction")
+}
+
+// isRetryable reports whether a transaction failed because of concurrent
+// transactions, and may succeed if attempted again.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == serializationFailure || pgErr.Code == deadlockDetected
+}
@@ -0,0 +1,586 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+const webhookEndpointColumns = `
+	id, url, description, event_types, encrypted_secret, enabled,
+	consecutive_failures, failing_since, disabled_at, disabled_reason,
+	created_at, updated_at`
+
+const webhookDeliveryColumns = `
+	id, endpoint_id, event_id, event_type, payload, status, attempts,
+	next_attempt_at, last_error, created_at, delivered_at`
+
+// WebhookEndpointRepository stores webhook endpoints in the
+// webhook_endpoints table. Deleting an endpoint deletes its deliveries.
+type WebhookEndpointRepository struct {
+	*Postgres[domain.WebhookEndpoint]
+}
+
+// NewWebhookEndpointRepository creates a new WebhookEndpointRepository.
+func NewWebhookEndpointRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*WebhookEndpointRepository, error) {
+	pg, err := NewPostgresDB[domain.WebhookEndpoint](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookEndpointRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new endpoint.
+func (wr *WebhookEndpointRepository) Insert(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	query := `
+		INSERT INTO webhook_endpoints (` + webhookEndpointColumns + `
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err := wr.q(ctx).Exec(ctx, query,
+		endpoint.ID,
+		endpoint.URL,
+		endpoint.Description,
+		eventTypeNames(endpoint.EventTypes),
+		endpoint.EncryptedSecret,
+		endpoint.Enabled,
+		endpoint.ConsecutiveFailures,
+		endpoint.FailingSince,
+		endpoint.DisabledAt,
+		endpoint.DisabledReason,
+		endpoint.CreatedAt,
+		endpoint.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert webhook endpoint")
+	}
+	return nil
+}
+
+// GetByID retrieves an endpoint by its ID.
+func (wr *WebhookEndpointRepository) GetByID(ctx context.Context, id string) (*domain.WebhookEndpoint, error) {
+	endpoint, err := scanWebhookEndpoint(wr.q(ctx).QueryRow(ctx, `
+		SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrWebhookEndpointNotFound
+	}
+	return endpoint, err
+}
+
+// List retrieves every endpoint, oldest first.
+func (wr *WebhookEndpointRepository) List(ctx context.Context) ([]*domain.WebhookEndpoint, error) {
+	return wr.list(ctx, `
+		SELECT `+webhookEndpointColumns+` FROM webhook_endpoints ORDER BY created_at`)
+}
+
+// ListSubscribed retrieves the enabled endpoints that subscribe to a type of
+// event, oldest first.
+func (wr *WebhookEndpointRepository) ListSubscribed(
+	ctx context.Context,
+	typ domain.EventType,
+) ([]*domain.WebhookEndpoint, error) {
+	return wr.list(ctx, `
+		SELECT `+webhookEndpointColumns+`
+		FROM webhook_endpoints
+		WHERE enabled AND $1 = ANY (event_types)
+		ORDER BY created_at`, typ.String())
+}
+
+func (wr *WebhookEndpointRepository) list(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]*domain.WebhookEndpoint, error) {
+	rows, err := wr.q(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook endpoints")
+	}
+	defer rows.Close()
+
+	var endpoints []*domain.WebhookEndpoint
+	for rows.Next() {
+		endpoint, err := scanWebhookEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook endpoints")
+	}
+	return endpoints, nil
+}
+
+// Update replaces an endpoint.
+func (wr *WebhookEndpointRepository) Update(ctx context.Context, endpoint *domain.WebhookEndpoint) error {
+	result, err := wr.q(ctx).Exec(ctx, `
+		UPDATE webhook_endpoints
+		SET url = $2,
+			description = $3,
+			event_types = $4,
+			encrypted_secret = $5,
+			enabled = $6,
+			consecutive_failures = $7,
+			failing_since = $8,
+			disabled_at = $9,
+			disabled_reason = $10
+		WHERE id = $1`,
+		endpoint.ID,
+		endpoint.URL,
+		endpoint.Description,
+		eventTypeNames(endpoint.EventTypes),
+		endpoint.EncryptedSecret,
+		endpoint.Enabled,
+		endpoint.ConsecutiveFailures,
+		endpoint.FailingSince,
+		endpoint.DisabledAt,
+		endpoint.DisabledReason,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to update webhook endpoint")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+// Delete removes an endpoint along with its deliveries.
+func (wr *WebhookEndpointRepository) Delete(ctx context.Context, id string) error {
+	result, err := wr.q(ctx).Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete webhook endpoint")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+// RecordSuccess forgets the failures of an endpoint.
+func (wr *WebhookEndpointRepository) RecordSuccess(ctx context.Context, id string) error {
+	result, err := wr.q(ctx).Exec(ctx, `
+		UPDATE webhook_endpoints
+		SET consecutive_failures = 0, failing_since = NULL
+		WHERE id = $1`, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to record webhook success")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrWebhookEndpointNotFound
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt at a point in time, and returns the
+// endpoint with its failures counted. Failures are counted in the database,
+// so that dispatchers failing at the same time do not lose each other's
+// counts.
+func (wr *WebhookEndpointRepository) RecordFailure(
+	ctx context.Context,
+	id string,
+	at time.Time,
+) (*domain.WebhookEndpoint, error) {
+	endpoint, err := scanWebhookEndpoint(wr.q(ctx).QueryRow(ctx, `
+		UPDATE webhook_endpoints
+		SET consecutive_failures = consecutive_failures + 1,
+			failing_since = COALESCE(failing_since, $2)
+		WHERE id = $1
+		RETURNING `+webhookEndpointColumns, id, at))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrWebhookEndpointNotFound
+	}
+	return endpoint, err
+}
+
+func scanWebhookEndpoint(row pgx.Row) (*domain.WebhookEndpoint, error) {
+	var endpoint domain.WebhookEndpoint
+	var eventTypes []string
+	var failingSince, disabledAt sql.NullTime
+	err := row.Scan(
+		&endpoint.ID,
+		&endpoint.URL,
+		&endpoint.Description,
+		&eventTypes,
+		&endpoint.EncryptedSecret,
+		&endpoint.Enabled,
+		&endpoint.ConsecutiveFailures,
+		&failingSince,
+		&disabledAt,
+		&endpoint.DisabledReason,
+		&endpoint.CreatedAt,
+		&endpoint.UpdatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scan webhook endpoint")
+	}
+	endpoint.EventTypes = make([]domain.EventType, len(eventTypes))
+	for i, name := range eventTypes {
+		endpoint.EventTypes[i], err = domain.NewEventType(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if failingSince.Valid {
+		endpoint.FailingSince = &failingSince.Time
+	}
+	if disabledAt.Valid {
+		endpoint.DisabledAt = &disabledAt.Time
+	}
+	return &endpoint, nil
+}
+
+func eventTypeNames(types []domain.EventType) []string {
+	names := make([]string, len(types))
+	for i, typ := range types {
+		names[i] = typ.String()
+	}
+	return names
+}
+
+// WebhookDeliveryRepository stores webhook deliveries in the
+// webhook_deliveries table, and the logs of their attempts in the
+// webhook_attempts table.
+type WebhookDeliveryRepository struct {
+	*Postgres[domain.WebhookDelivery]
+}
+
+// NewWebhookDeliveryRepository creates a new WebhookDeliveryRepository.
+func NewWebhookDeliveryRepository(
+	ctx context.Context,
+	cfg *pgxpool.Config,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*WebhookDeliveryRepository, error) {
+	pg, err := NewPostgresDB[domain.WebhookDelivery](ctx, cfg, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &WebhookDeliveryRepository{Postgres: pg}, nil
+}
+
+// Insert adds a new delivery. A delivery of an event that was already queued
+// for the endpoint is not added, and no error is returned.
+func (wr *WebhookDeliveryRepository) Insert(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (
+			id, endpoint_id, event_id, event_type, payload, status, attempts,
+			next_attempt_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (endpoint_id, event_id) DO NOTHING`
+
+	_, err := wr.q(ctx).Exec(ctx, query,
+		delivery.ID,
+		delivery.EndpointID,
+		delivery.EventID,
+		delivery.EventType.String(),
+		string(delivery.Payload),
+		delivery.Status.String(),
+		delivery.Attempts,
+		delivery.NextAttemptAt,
+		delivery.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert webhook delivery")
+	}
+	return nil
+}
+
+// GetByID retrieves a delivery by its ID.
+func (wr *WebhookDeliveryRepository) GetByID(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	delivery, err := scanWebhookDelivery(wr.q(ctx).QueryRow(ctx, `
+		SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrWebhookDeliveryNotFound
+	}
+	return delivery, err
+}
+
+// ListByEndpoint retrieves up to limit deliveries to an endpoint, most recent
+// first.
+func (wr *WebhookDeliveryRepository) ListByEndpoint(
+	ctx context.Context,
+	endpointID string,
+	limit int,
+) ([]*domain.WebhookDelivery, error) {
+	return wr.list(ctx, `
+		SELECT `+webhookDeliveryColumns+`
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2`, endpointID, limit)
+}
+
+// ClaimDue returns up to limit pending deliveries that are due at a point in
+// time, oldest first, and pushes their next attempt back by lease. Rows
+// claimed by another dispatcher at the same time are skipped.
+func (wr *WebhookDeliveryRepository) ClaimDue(
+	ctx context.Context,
+	at time.Time,
+	limit int,
+	lease time.Duration,
+) ([]*domain.WebhookDelivery, error) {
+	return wr.list(ctx, `
+		UPDATE webhook_deliveries
+		SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+webhookDeliveryColumns, at, at.Add(lease), limit)
+}
+
+func (wr *WebhookDeliveryRepository) list(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]*domain.WebhookDelivery, error) {
+	rows, err := wr.q(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook deliveries")
+	}
+	defer rows.Close()
+
+	var deliveries []*domain.WebhookDelivery
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook deliveries")
+	}
+	return deliveries, nil
+}
+
+// MarkDelivered marks a delivery as accepted by its endpoint, if it is
+// still pending and leased until leasedUntil.
+func (wr *WebhookDeliveryRepository) MarkDelivered(ctx context.Context, id string, leasedUntil, at time.Time) error {
+	result, err := wr.q(ctx).Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_error = NULL, delivered_at = $3
+		WHERE id = $1 AND status = 'pending' AND next_attempt_at = $2`, id, leasedUntil, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark webhook delivered")
+	}
+	if result.RowsAffected() == 0 {
+		return wr.leaseLost(ctx, id)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt, if the delivery is still pending and
+// leased until leasedUntil. A nil next attempt marks the delivery as dead.
+func (wr *WebhookDeliveryRepository) MarkFailed(
+	ctx context.Context,
+	id string,
+	leasedUntil time.Time,
+	attempts int,
+	next *time.Time,
+	lastError string,
+) error {
+	result, err := wr.q(ctx).Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET attempts = $3,
+			last_error = $4,
+			status = CASE WHEN $5::timestamptz IS NULL THEN 'dead' ELSE status END,
+			next_attempt_at = COALESCE($5, next_attempt_at)
+		WHERE id = $1 AND status = 'pending' AND next_attempt_at = $2`, id, leasedUntil, attempts, lastError, next)
+	if err != nil {
+		return errors.Wrap(err, "failed to mark webhook delivery failed")
+	}
+	if result.RowsAffected() == 0 {
+		return wr.leaseLost(ctx, id)
+	}
+	return nil
+}
+
+// leaseLost tells why a delivery could not be marked: it is gone, or it is
+// no longer leased by the caller.
+func (wr *WebhookDeliveryRepository) leaseLost(ctx context.Context, id string) error {
+	var exists bool
+	err := wr.q(ctx).QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM webhook_deliveries WHERE id = $1)`, id).
+		Scan(&exists)
+	if err != nil {
+		return errors.Wrap(err, "failed to get webhook delivery")
+	}
+	if !exists {
+		return domain.ErrWebhookDeliveryNotFound
+	}
+	return domain.ErrWebhookLeaseExpired
+}
+
+// Redeliver makes a delivery pending again, due at a point in time, with its
+// attempts reset. The logs of earlier attempts are kept.
+func (wr *WebhookDeliveryRepository) Redeliver(ctx context.Context, id string, at time.Time) error {
+	result, err := wr.q(ctx).Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = $2, delivered_at = NULL
+		WHERE id = $1`, id, at)
+	if err != nil {
+		return errors.Wrap(err, "failed to redeliver webhook")
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrWebhookDeliveryNotFound
+	}
+	return nil
+}
+
+// InsertAttempt logs an attempt at a delivery, and numbers it after the
+// earlier attempts. Only the dispatcher holding the lease of a delivery
+// attempts it; should two attempt it at once after a lease ran out, the
+// unique number of attempts rejects the second to log its attempt.
+func (wr *WebhookDeliveryRepository) InsertAttempt(ctx context.Context, attempt *domain.WebhookAttempt) error {
+	headers, err := json.Marshal(attempt.RequestHeaders)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode request headers")
+	}
+	err = wr.q(ctx).QueryRow(ctx, `
+		INSERT INTO webhook_attempts (
+			id, delivery_id, number, request_headers, response_status,
+			response_body, error, duration_ms, attempted_at
+		)
+		SELECT $1, $2, COALESCE(MAX(number), 0) + 1, $3, $4, $5, $6, $7, $8
+		FROM webhook_attempts
+		WHERE delivery_id = $2
+		RETURNING number`,
+		attempt.ID,
+		attempt.DeliveryID,
+		string(headers),
+		attempt.ResponseStatus,
+		attempt.ResponseBody,
+		attempt.Error,
+		attempt.Duration.Milliseconds(),
+		attempt.AttemptedAt,
+	).Scan(&attempt.Number)
+	if err != nil {
+		return errors.Wrap(err, "failed to insert webhook attempt")
+	}
+	return nil
+}
+
+// ListAttempts retrieves the attempts at a delivery, in order.
+func (wr *WebhookDeliveryRepository) ListAttempts(
+	ctx context.Context,
+	deliveryID string,
+) ([]*domain.WebhookAttempt, error) {
+	rows, err := wr.q(ctx).Query(ctx, `
+		SELECT id, delivery_id, number, request_headers, response_status,
+			   response_body, error, duration_ms, attempted_at
+		FROM webhook_attempts
+		WHERE delivery_id = $1
+		ORDER BY number`, deliveryID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook attempts")
+	}
+	defer rows.Close()
+
+	var attempts []*domain.WebhookAttempt
+	for rows.Next() {
+		var attempt domain.WebhookAttempt
+		var headers string
+		var durationMillis int64
+		err = rows.Scan(
+			&attempt.ID,
+			&attempt.DeliveryID,
+			&attempt.Number,
+			&headers,
+			&attempt.ResponseStatus,
+			&attempt.ResponseBody,
+			&attempt.Error,
+			&durationMillis,
+			&attempt.AttemptedAt,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan webhook attempt")
+		}
+		err = json.Unmarshal([]byte(headers), &attempt.RequestHeaders)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode request headers")
+		}
+		attempt.Duration = time.Duration(durationMillis) * time.Millisecond
+		attempts = append(attempts, &attempt)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to list webhook attempts")
+	}
+	return attempts, nil
+}
+
+// DeleteExpired removes every delivered or dead delivery created before a
+// point in time, along with its attempts, and returns the number of removed
+// deliveries.
+func (wr *WebhookDeliveryRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := wr.q(ctx).Exec(ctx, `
+		DELETE FROM webhook_deliveries
+		WHERE status <> 'pending' AND created_at < $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to delete old webhook deliveries")
+	}
+	return result.RowsAffected(), nil
+}
+
+func scanWebhookDelivery(row pgx.Row) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	var eventType, payload, status string
+	var lastError sql.NullString
+	var deliveredAt sql.NullTime
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EndpointID,
+		&delivery.EventID,
+		&eventType,
+		&payload,
+		&status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&lastError,
+		&delivery.CreatedAt,
+		&deliveredAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scan webhook delivery")
+	}
+	delivery.EventType, err = domain.NewEventType(eventType)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Status, err = domain.NewWebhookDeliveryStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = []byte(payload)
+	delivery.LastError = lastError.String
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pwned
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1" //nolint:gosec // The dataset is keyed by SHA-1.
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// ImportOptions configures how a dataset is built.
+type ImportOptions struct {
+	// SuffixLength is how many bytes of every hash, after the two byte
+	// prefix, are kept. Longer suffixes make false positives rarer and the
+	// file bigger. It defaults to 6.
+	SuffixLength int
+	// MinCount leaves out hashes seen fewer times than it in breaches. It
+	// defaults to 1, which keeps every hash.
+	MinCount int
+}
+
+// ImportStats describes an imported dataset.
+type ImportStats struct {
+	// Read is the number of hashes read.
+	Read uint64
+	// Written is the number of hashes written.
+	Written uint64
+	// Skipped is the number of hashes left out for their count.
+	Skipped uint64
+}
+
+// Import builds a dataset from the SHA-1 dump of the Pwned Passwords list,
+// which has a `HASH:COUNT` line for every hash, ordered by hash. The dump is
+// read once and never held in memory, so it may be streamed.
+func Import(r io.Reader, w io.Writer, opts ImportOptions) (ImportStats, error) {
+	var stats ImportStats
+	if opts.SuffixLength == 0 {
+		opts.SuffixLength = defaultSuffix
+	}
+	if opts.SuffixLength < 1 || opts.SuffixLength > maxSuffix {
+		return stats, errors.Errorf("suffix length must be between 1 and %d", maxSuffix)
+	}
+	if opts.MinCount < 1 {
+		opts.MinCount = 1
+	}
+
+	out := bufio.NewWriter(w)
+	header := make([]byte, headerLength)
+	copy(header, magic)
+	header[len(magic)] = version
+	header[len(magic)+1] = byte(opts.SuffixLength)
+	_, err := out.Write(header)
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to write header")
+	}
+
+	counts := make([]uint64, ranges)
+	var previous, last []byte
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		hash, count, err := parseLine(text)
+		if err != nil {
+			return stats, errors.Wrapf(err, "line %d", line)
+		}
+		if previous != nil && bytes.Compare(hash, previous) <= 0 {
+			return stats, errors.Errorf("line %d: hashes are not in ascending order", line)
+		}
+		previous = hash
+		stats.Read++
+
+		if count < opts.MinCount {
+			stats.Skipped++
+			continue
+		}
+		// Hashes that share a truncated suffix are stored once.
+		entry := hash[:prefixLength+opts.SuffixLength]
+		if bytes.Equal(entry, last) {
+			continue
+		}
+		last = entry
+		_, err = out.Write(entry[prefixLength:])
+		if err != nil {
+			return stats, errors.Wrap(err, "failed to write hash")
+		}
+		counts[binary.BigEndian.Uint16(entry)]++
+		stats.Written++
+	}
+	err = scanner.Err()
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to read dump")
+	}
+
+	index := make([]byte, indexLength)
+	var total uint64
+	for p := range ranges {
+		binary.LittleEndian.PutUint64(index[p*8:], total)
+		total += counts[p]
+	}
+	binary.LittleEndian.PutUint64(index[ranges*8:], total)
+	_, err = out.Write(index)
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to write index")
+	}
+	err = out.Flush()
+	if err != nil {
+		return stats, errors.Wrap(err, "failed to write dataset")
+	}
+	return stats, nil
+}
+
+// parseLine parses a `HASH:COUNT` line. The count is optional.
+func parseLine(text string) ([]byte, int, error) {
+	hexHash, rawCount, hasCount := strings.Cut(text, ":")
+	if len(hexHash) != 2*sha1.Size {
+		return nil, 0, errors.New("not a SHA-1 hash")
+	}
+	hash, err := hex.DecodeString(hexHash)
+	if err != nil {
+		return nil, 0, errors.New("not a SHA-1 hash")
+	}
+	count := 1
+	if hasCount {
+		count, err = strconv.Atoi(rawCount)
+		if err != nil || count < 0 {
+			return nil, 0, errors.New("invalid count")
+		}
+	}
+	return hash, count, nil
+}
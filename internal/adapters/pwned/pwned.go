@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pwned checks passwords against a local copy of the Have I Been
+// Pwned password dataset, so that sign-up does not depend on the Pwned
+// Passwords API.
+//
+// The dataset is a single file built with Import from the SHA-1 dump of the
+// Pwned Passwords list. Hashes are grouped by their first two bytes, like the
+// k-anonymity ranges of the API, and only a few bytes of the rest of every
+// hash are kept, which shrinks the file about threefold. The layout is:
+//
+//	header   magic "BDEPWNED", version, suffix length, 6 reserved bytes
+//	entries  the sorted hash suffixes, suffix length bytes each
+//	index    65537 little endian uint64s, where index[p] is the number of
+//	         entries in the ranges before p
+//
+// With 6 byte suffixes, a hash that is not in the dump is taken for one that
+// is about once in every 10^10 checks.
+package pwned
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec // The dataset is keyed by SHA-1.
+	"encoding/binary"
+	"io"
+	"os"
+	"sort"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+const (
+	magic         = "BDEPWNED"
+	version       = 1
+	headerLength  = 16
+	prefixLength  = 2
+	ranges        = 1 << (8 * prefixLength)
+	indexEntries  = ranges + 1
+	indexLength   = indexEntries * 8
+	maxSuffix     = sha1.Size - prefixLength
+	defaultSuffix = 6
+)
+
+// ErrInvalidDataset is returned when a file is not a pwned password dataset.
+var ErrInvalidDataset = errors.New("invalid pwned password dataset")
+
+// Dataset is an opened pwned password dataset. It is safe for concurrent
+// use.
+type Dataset struct {
+	file       *os.File
+	suffixLen  int
+	index      []uint64
+	entryCount uint64
+}
+
+// Open opens a dataset built with Import. Only the index is read into
+// memory. Every check reads one range from the file.
+func Open(path string) (*Dataset, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open pwned password dataset")
+	}
+	d, err := load(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func load(file *os.File) (*Dataset, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat pwned password dataset")
+	}
+
+	header := make([]byte, headerLength)
+	_, err = file.ReadAt(header, 0)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidDataset, "short header")
+	}
+	if string(header[:len(magic)]) != magic || header[len(magic)] != version {
+		return nil, errors.Wrap(ErrInvalidDataset, "unknown format")
+	}
+	suffixLen := int(header[len(magic)+1])
+	if suffixLen < 1 || suffixLen > maxSuffix {
+		return nil, errors.Wrap(ErrInvalidDataset, "invalid suffix length")
+	}
+
+	entriesLength := info.Size() - headerLength - indexLength
+	if entriesLength < 0 || entriesLength%int64(suffixLen) != 0 {
+		return nil, errors.Wrap(ErrInvalidDataset, "truncated file")
+	}
+	raw := make([]byte, indexLength)
+	_, err = file.ReadAt(raw, headerLength+entriesLength)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read pwned password index")
+	}
+	index := make([]uint64, indexEntries)
+	for i := range index {
+		index[i] = binary.LittleEndian.Uint64(raw[i*8:])
+		if i > 0 && index[i] < index[i-1] {
+			return nil, errors.Wrap(ErrInvalidDataset, "unsorted index")
+		}
+	}
+	entryCount := uint64(entriesLength) / uint64(suffixLen)
+	if index[0] != 0 || index[ranges] != entryCount {
+		return nil, errors.Wrap(ErrInvalidDataset, "index does not match entries")
+	}
+
+	return &Dataset{file: file, suffixLen: suffixLen, index: index, entryCount: entryCount}, nil
+}
+
+// Check reports whether a password is in the dataset. A check only reads a
+// local file, so the context is not used.
+func (d *Dataset) Check(_ context.Context, password string) (bool, error) {
+	return d.Contains(sha1.Sum([]byte(password))) //nolint:gosec // The dataset is keyed by SHA-1.
+}
+
+// Contains reports whether a SHA-1 hash is in the dataset.
+func (d *Dataset) Contains(hash [sha1.Size]byte) (bool, error) {
+	prefix := binary.BigEndian.Uint16(hash[:prefixLength])
+	lo, hi := d.index[prefix], d.index[prefix+1]
+	if lo == hi {
+		return false, nil
+	}
+
+	entries := make([]byte, (hi-lo)*uint64(d.suffixLen))
+	_, err := d.file.ReadAt(entries, headerLength+int64(lo)*int64(d.suffixLen))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return false, errors.Wrap(err, "failed to read pwned password range")
+	}
+
+	suffix := hash[prefixLength : prefixLength+d.suffixLen]
+	n := int(hi - lo)
+	i := sort.Search(n, func(i int) bool {
+		return bytes.Compare(d.entry(entries, i), suffix) >= 0
+	})
+	return i < n && bytes.Equal(d.entry(entries, i), suffix), nil
+}
+
+func (d *Dataset) entry(entries []byte, i int) []byte {
+	return entries[i*d.suffixLen : (i+1)*d.suffixLen]
+}
+
+// Len returns the number of hashes in the dataset.
+func (d *Dataset) Len() uint64 {
+	return d.entryCount
+}
+
+// Close closes the dataset file.
+func (d *Dataset) Close() error {
+	return d.file.Close()
+}
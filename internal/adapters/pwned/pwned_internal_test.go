@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pwned
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1" //nolint:gosec // The dataset is keyed by SHA-1.
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+// dump returns a Pwned Passwords dump of passwords, each seen as many times
+// as given, ordered by hash like the real one.
+func dump(passwords map[string]int) string {
+	lines := make([]string, 0, len(passwords))
+	for password, count := range passwords {
+		sum := sha1.Sum([]byte(password)) //nolint:gosec // The dataset is keyed by SHA-1.
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), count))
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// build imports a dump into a dataset file and opens it.
+func build(t *testing.T, raw string, opts ImportOptions) (*Dataset, ImportStats) {
+	t.Helper()
+	var buf bytes.Buffer
+	stats, err := Import(strings.NewReader(raw), &buf, opts)
+	assert.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "pwned.bin")
+	assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0o600))
+	d, err := Open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = d.Close() })
+	return d, stats
+}
+
+var leaked = map[string]int{ //nolint:gochecknoglobals // makes more sense like this.
+	"password":    9_545_824,
+	"123456":      37_359_195,
+	"Tr0ub4dor&3": 100,
+	"hunter22":    3,
+	"rarely used": 1,
+}
+
+func TestDataset_Check(t *testing.T) {
+	d, stats := build(t, dump(leaked), ImportOptions{SuffixLength: 0, MinCount: 0})
+	assert.Equal(t, stats.Read, uint64(len(leaked)))
+	assert.Equal(t, stats.Written, uint64(len(leaked)))
+	assert.Equal(t, d.Len(), uint64(len(leaked)))
+
+	tests := []struct {
+		test.CaseBase
+		password string
+	}{
+		{CaseBase: test.NewCaseBase("common", true, false), password: "password"},
+		{CaseBase: test.NewCaseBase("leaked once", true, false), password: "rarely used"},
+		{CaseBase: test.NewCaseBase("case matters", false, false), password: "Password"},
+		{CaseBase: test.NewCaseBase("never leaked", false, false), password: "correct horse battery staple"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				pwned, err := d.Check(context.Background(), tt.password)
+				assert.ErrorAndWant(t, err, tt.WantErr)
+				assert.Equal(t, pwned, tt.Want.(bool))
+			},
+		)
+	}
+}
+
+func TestImport_MinCount(t *testing.T) {
+	d, stats := build(t, dump(leaked), ImportOptions{SuffixLength: 4, MinCount: 10})
+	assert.Equal(t, stats.Skipped, uint64(2))
+	assert.Equal(t, d.Len(), uint64(3))
+
+	pwned, err := d.Check(context.Background(), "Tr0ub4dor&3")
+	assert.NoError(t, err)
+	assert.True(t, pwned)
+	pwned, err = d.Check(context.Background(), "hunter22")
+	assert.NoError(t, err)
+	assert.False(t, pwned)
+}
+
+func TestImport_Failures(t *testing.T) {
+	sorted := dump(leaked)
+	lines := strings.Split(strings.TrimSpace(sorted), "\r\n")
+
+	tests := []struct {
+		test.CaseBase
+		raw  string
+		opts ImportOptions
+	}{
+		{
+			CaseBase: test.NewCaseBase("unsorted", nil, true),
+			raw:      lines[1] + "\n" + lines[0] + "\n",
+			opts:     ImportOptions{SuffixLength: 0, MinCount: 0},
+		},
+		{
+			CaseBase: test.NewCaseBase("not a hash", nil, true),
+			raw:      "5BAA61E4C9B93F3F:3\n",
+			opts:     ImportOptions{SuffixLength: 0, MinCount: 0},
+		},
+		{
+			CaseBase: test.NewCaseBase("invalid count", nil, true),
+			raw:      strings.Split(lines[0], ":")[0] + ":many\n",
+			opts:     ImportOptions{SuffixLength: 0, MinCount: 0},
+		},
+		{
+			CaseBase: test.NewCaseBase("suffix too long", nil, true),
+			raw:      sorted,
+			opts:     ImportOptions{SuffixLength: 19, MinCount: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				_, err := Import(strings.NewReader(tt.raw), &bytes.Buffer{}, tt.opts)
+				assert.ErrorAndWant(t, err, tt.WantErr)
+			},
+		)
+	}
+}
+
+func TestOpen_InvalidDataset(t *testing.T) {
+	dir := t.TempDir()
+
+	var valid bytes.Buffer
+	_, err := Import(strings.NewReader(dump(leaked)), &valid, ImportOptions{SuffixLength: 0, MinCount: 0})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		test.CaseBase
+		content []byte
+	}{
+		{CaseBase: test.NewCaseBase("empty", nil, true), content: nil},
+		{CaseBase: test.NewCaseBase("wrong magic", nil, true), content: []byte("NOTPWNED" + strings.Repeat("\x00", 8))},
+		{CaseBase: test.NewCaseBase("truncated", nil, true), content: valid.Bytes()[:valid.Len()-1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				path := filepath.Join(dir, tt.Name)
+				assert.NoError(t, os.WriteFile(path, tt.content, 0o600))
+				_, err := Open(path)
+				assert.Error(t, err, ErrInvalidDataset)
+			},
+		)
+	}
+}
@@ -0,0 +1,42 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package redis implements a Redis adapter.
+package redis
+
+import (
+	"context"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/pkg/errors"
+)
+
+type Redis struct {
+	client *goredis.Client
+	logger loggers.Logger
+	tel    telemetry.Telemetry
+}
+
+// NewRedis connects to a Redis server and verifies the connection.
+func NewRedis(
+	ctx context.Context,
+	opts *goredis.Options,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*Redis, error) {
+	client := goredis.NewClient(opts)
+	err := client.Ping(ctx).Err()
+	if err != nil {
+		_ = client.Close()
+		return nil, errors.Wrap(err, "failed to connect to redis")
+	}
+	return &Redis{client: client, logger: logger, tel: tel}, nil
+}
+
+func (r *Redis) Close() error {
+	r.logger.Info("closing redis connection")
+	return r.client.Close()
+}
@@ -0,0 +1,325 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redis
+
+import (
+	"context"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// SessionRepository stores customer sessions in Redis.
+//
+// Each session is a hash under `session:<id>` that expires together with the
+// session, so Redis reaps expired sessions on its own. The IDs of a
+// customer's sessions are indexed in a set under `customer_sessions:<id>`.
+// The index may briefly reference sessions that Redis already expired; those
+// references are skipped on read and pruned by DeleteExpired.
+type SessionRepository struct {
+	*Redis
+}
+
+// NewSessionRepository creates a new SessionRepository.
+func NewSessionRepository(
+	ctx context.Context,
+	opts *goredis.Options,
+	logger loggers.Logger,
+	tel telemetry.Telemetry,
+) (*SessionRepository, error) {
+	r, err := NewRedis(ctx, opts, logger, tel)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionRepository{Redis: r}, nil
+}
+
+const (
+	sessionKeyPrefix         = "session:"
+	customerSessionKeyPrefix = "customer_sessions:"
+)
+
+func sessionKey(id string) string {
+	return sessionKeyPrefix + id
+}
+
+func customerSessionsKey(customerID string) string {
+	return customerSessionKeyPrefix + customerID
+}
+
+// Insert adds a new session.
+func (sr *SessionRepository) Insert(ctx context.Context, session *domain.UserSession) error {
+	return sr.write(ctx, session)
+}
+
+// GetByID retrieves a session by its ID.
+func (sr *SessionRepository) GetByID(ctx context.Context, id string) (*domain.UserSession, error) {
+	fields, err := sr.client.HGetAll(ctx, sessionKey(id)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get session by ID")
+	}
+	if len(fields) == 0 {
+		return nil, domain.ErrSessionNotFound
+	}
+	return decodeSession(id, fields)
+}
+
+// ListByCustomer retrieves all unexpired sessions of a customer.
+func (sr *SessionRepository) ListByCustomer(
+	ctx context.Context,
+	customerID string,
+) ([]*domain.UserSession, error) {
+	ids, err := sr.client.SMembers(ctx, customerSessionsKey(customerID)).Result()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sessions")
+	}
+
+	pipe := sr.client.Pipeline()
+	cmds := make([]*goredis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, sessionKey(id))
+	}
+	_, err = pipe.Exec(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sessions")
+	}
+
+	sessions := make([]*domain.UserSession, 0, len(ids))
+	for i, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 {
+			continue
+		}
+		session, err := decodeSession(ids[i], fields)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// updateSessionScript sets fields of the session hash under KEYS[1] only if
+// the session still exists, and returns whether it did. Checking and writing
+// in one script keeps a session deleted in the meantime, such as by a sign
+// out, from being brought back.
+var updateSessionScript = goredis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], unpack(ARGV))
+return 1
+`)
+
+// rotateSessionScript sets fields of the session hash under KEYS[1] and
+// expires it at ARGV[2], only if its secret hash is still ARGV[1]. It
+// returns 1 if it did, 0 if the session does not exist and -1 if another
+// renewal rotated it first. The customer index under KEYS[2] is extended to
+// the new expiry.
+var rotateSessionScript = goredis.NewScript(`
+local hash = redis.call('HGET', KEYS[1], 'secret_hash')
+if not hash then
+	return 0
+end
+if hash ~= ARGV[1] then
+	return -1
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 3))
+redis.call('PEXPIREAT', KEYS[1], ARGV[2])
+redis.call('PEXPIREAT', KEYS[2], ARGV[2], 'GT')
+return 1
+`)
+
+// Touch records when a session was last used.
+func (sr *SessionRepository) Touch(ctx context.Context, id string, lastSeenAt time.Time) error {
+	return sr.update(ctx, id, "last_seen_at", lastSeenAt.Format(time.RFC3339Nano))
+}
+
+// Rotate writes the renewed secret, expiry and last seen time of a session,
+// if the stored secret is still the one it was renewed from.
+func (sr *SessionRepository) Rotate(ctx context.Context, session *domain.UserSession) error {
+	fields := encodeSession(session)
+	args := []any{session.PreviousSecretHash, session.ExpiresAt.UnixMilli()}
+	for _, name := range []string{"secret_hash", "previous_secret_hash", "rotated_at", "last_seen_at", "expires_at"} {
+		args = append(args, name, fields[name])
+	}
+	keys := []string{sessionKey(session.ID), customerSessionsKey(session.UserID)}
+	n, err := rotateSessionScript.Run(ctx, sr.client, keys, args...).Int()
+	if err != nil {
+		return errors.Wrap(err, "failed to rotate session")
+	}
+	switch n {
+	case 0:
+		return domain.ErrSessionNotFound
+	case -1:
+		return domain.ErrSessionRotated
+	default:
+		return nil
+	}
+}
+
+// MarkAuthenticated records when the customer last proved their
+// credentials in a session.
+func (sr *SessionRepository) MarkAuthenticated(ctx context.Context, id string, at time.Time) error {
+	return sr.update(ctx, id, "authenticated_at", at.Format(time.RFC3339Nano))
+}
+
+// update sets a field of a session, if the session still exists.
+func (sr *SessionRepository) update(ctx context.Context, id, field string, value any) error {
+	n, err := updateSessionScript.Run(ctx, sr.client, []string{sessionKey(id)}, field, value).Int()
+	if err != nil {
+		return errors.Wrapf(err, "failed to update session %s", field)
+	}
+	if n == 0 {
+		return domain.ErrSessionNotFound
+	}
+	return nil
+}
+
+// Delete removes a session.
+func (sr *SessionRepository) Delete(ctx context.Context, id string) error {
+	customerID, err := sr.client.HGet(ctx, sessionKey(id), "user_id").Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to delete session")
+	}
+	pipe := sr.client.TxPipeline()
+	pipe.Del(ctx, sessionKey(id))
+	pipe.SRem(ctx, customerSessionsKey(customerID), id)
+	_, err = pipe.Exec(ctx)
+	return errors.Wrap(err, "failed to delete session")
+}
+
+// DeleteByCustomer removes every session of a customer.
+func (sr *SessionRepository) DeleteByCustomer(ctx context.Context, customerID string) error {
+	ids, err := sr.client.SMembers(ctx, customerSessionsKey(customerID)).Result()
+	if err != nil {
+		return errors.Wrap(err, "failed to delete customer sessions")
+	}
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, sessionKey(id))
+	}
+	keys = append(keys, customerSessionsKey(customerID))
+	err = sr.client.Del(ctx, keys...).Err()
+	return errors.Wrap(err, "failed to delete customer sessions")
+}
+
+// DeleteExpired prunes customer session indexes of sessions that Redis has
+// already expired, and returns the number of pruned references. The sessions
+// themselves are expired by Redis.
+func (sr *SessionRepository) DeleteExpired(ctx context.Context, _ time.Time) (int64, error) {
+	var pruned int64
+	iter := sr.client.Scan(ctx, 0, customerSessionKeyPrefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		indexKey := iter.Val()
+		ids, err := sr.client.SMembers(ctx, indexKey).Result()
+		if err != nil {
+			return pruned, errors.Wrap(err, "failed to prune session index")
+		}
+		for _, id := range ids {
+			n, err := sr.client.Exists(ctx, sessionKey(id)).Result()
+			if err != nil {
+				return pruned, errors.Wrap(err, "failed to prune session index")
+			}
+			if n > 0 {
+				continue
+			}
+			removed, err := sr.client.SRem(ctx, indexKey, id).Result()
+			if err != nil {
+				return pruned, errors.Wrap(err, "failed to prune session index")
+			}
+			pruned += removed
+		}
+	}
+	return pruned, errors.Wrap(iter.Err(), "failed to scan session indexes")
+}
+
+// write stores a session and indexes it under its customer. The session
+// expires when the session does, and the index lives as long as the
+// customer's longest lived session.
+func (sr *SessionRepository) write(ctx context.Context, session *domain.UserSession) error {
+	ttl := time.Until(session.ExpiresAt)
+	if ttl <= 0 {
+		return errors.New("session already expired")
+	}
+	key := sessionKey(session.ID)
+	indexKey := customerSessionsKey(session.UserID)
+
+	pipe := sr.client.TxPipeline()
+	pipe.HSet(ctx, key, encodeSession(session))
+	pipe.PExpireAt(ctx, key, session.ExpiresAt)
+	pipe.SAdd(ctx, indexKey, session.ID)
+	pipe.ExpireNX(ctx, indexKey, ttl)
+	pipe.ExpireGT(ctx, indexKey, ttl)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to write session")
+	}
+	return nil
+}
+
+func encodeSession(session *domain.UserSession) map[string]any {
+	rotatedAt := ""
+	if session.RotatedAt != nil {
+		rotatedAt = session.RotatedAt.Format(time.RFC3339Nano)
+	}
+	return map[string]any{
+		"user_id":              session.UserID,
+		"secret_hash":          session.SecretHash,
+		"previous_secret_hash": session.PreviousSecretHash,
+		"rotated_at":           rotatedAt,
+		"created_ip":           session.CreatedIP,
+		"user_agent":           session.UserAgent,
+		"created_at":           session.CreatedAt.Format(time.RFC3339Nano),
+		"last_seen_at":         session.LastSeenAt.Format(time.RFC3339Nano),
+		"expires_at":           session.ExpiresAt.Format(time.RFC3339Nano),
+		"absolute_expires_at":  session.AbsoluteExpiresAt.Format(time.RFC3339Nano),
+		"authenticated_at":     session.AuthenticatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+func decodeSession(id string, fields map[string]string) (*domain.UserSession, error) {
+	var err error
+	names := []string{
+		"created_at", "last_seen_at", "expires_at", "absolute_expires_at", "authenticated_at",
+	}
+	times := make([]time.Time, len(names))
+	for i, name := range names {
+		times[i], err = time.Parse(time.RFC3339Nano, fields[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode session %s", name)
+		}
+	}
+	var rotatedAt *time.Time
+	if v := fields["rotated_at"]; v != "" {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decode session rotated_at")
+		}
+		rotatedAt = &t
+	}
+	return &domain.UserSession{
+		ID:                 id,
+		Token:              "",
+		SecretHash:         []byte(fields["secret_hash"]),
+		PreviousSecretHash: []byte(fields["previous_secret_hash"]),
+		RotatedAt:          rotatedAt,
+		UserID:             fields["user_id"],
+		CreatedIP:          fields["created_ip"],
+		UserAgent:          fields["user_agent"],
+		CreatedAt:          times[0],
+		LastSeenAt:         times[1],
+		ExpiresAt:          times[2],
+		AbsoluteExpiresAt:  times[3],
+		AuthenticatedAt:    times[4],
+	}, nil
+}
@@ -47,3 +47,168 @@ var MetricGRPCHealthWatchers = Metric{ //nolint:gochecknoglobals // makes more s
 	Unit:        "{count}",
 	Description: "Number of active health check watchers.",
 }
+
+// MetricEmailsSentTotal is a metric that counts delivered emails.
+var MetricEmailsSentTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "emails_sent_total",
+	Unit:        "{count}",
+	Description: "Total number of emails delivered.",
+}
+
+// MetricEmailDeliveryFailuresTotal is a metric that counts failed email
+// delivery attempts that are retried.
+var MetricEmailDeliveryFailuresTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "email_delivery_failures_total",
+	Unit:        "{count}",
+	Description: "Total number of failed email delivery attempts that are retried.",
+}
+
+// MetricEmailsDeadTotal is a metric that counts emails that were given up on.
+var MetricEmailsDeadTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "emails_dead_total",
+	Unit:        "{count}",
+	Description: "Total number of emails that could not be delivered and were given up on.",
+}
+
+// MetricEmailDeliveryDurationMillis is a metric that measures how long email
+// delivery attempts take, in milliseconds.
+var MetricEmailDeliveryDurationMillis = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "email_delivery_duration_millis",
+	Unit:        "ms",
+	Description: "Measures how long email delivery attempts take, in milliseconds.",
+}
+
+// MetricPwnCacheHitsTotal is a metric that counts pwned password checks
+// answered from the range cache.
+var MetricPwnCacheHitsTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "pwn_cache_hits_total",
+	Unit:        "{count}",
+	Description: "Total number of pwned password checks answered from the range cache.",
+}
+
+// MetricPwnCacheMissesTotal is a metric that counts pwned password checks
+// that had to ask the Pwned Passwords API.
+var MetricPwnCacheMissesTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "pwn_cache_misses_total",
+	Unit:        "{count}",
+	Description: "Total number of pwned password checks that had to ask the Pwned Passwords API.",
+}
+
+// MetricPwnAPIFailuresTotal is a metric that counts failed requests to the
+// Pwned Passwords API, including those refused by the circuit breaker.
+var MetricPwnAPIFailuresTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "pwn_api_failures_total",
+	Unit:        "{count}",
+	Description: "Total number of failed Pwned Passwords API requests, including those refused by the circuit breaker.",
+}
+
+// MetricPwnAPIDurationMillis is a metric that measures how long requests to
+// the Pwned Passwords API take, in milliseconds.
+var MetricPwnAPIDurationMillis = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "pwn_api_duration_millis",
+	Unit:        "ms",
+	Description: "Measures how long Pwned Passwords API requests take, in milliseconds.",
+}
+
+// MetricPwnCircuitOpen is a metric that is 1 while the circuit breaker of
+// the Pwned Passwords API is open, and 0 otherwise.
+var MetricPwnCircuitOpen = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "pwn_circuit_open",
+	Unit:        "{state}",
+	Description: "Whether the circuit breaker of the Pwned Passwords API is open, 1 if it is and 0 if not.",
+}
+
+// MetricLoginFailuresTotal is a metric that counts failed sign in attempts.
+var MetricLoginFailuresTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "login_failures_total",
+	Unit:        "{count}",
+	Description: "Total number of failed sign in attempts.",
+}
+
+// MetricLoginThrottledTotal is a metric that counts sign in attempts that
+// were refused because of earlier failures, by the kind of throttle key.
+var MetricLoginThrottledTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "login_throttled_total",
+	Unit:        "{count}",
+	Description: "Total number of sign in attempts refused because of earlier failures.",
+}
+
+// MetricLoginLockoutsTotal is a metric that counts accounts and IP addresses
+// that were locked out, by the kind of throttle key.
+var MetricLoginLockoutsTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "login_lockouts_total",
+	Unit:        "{count}",
+	Description: "Total number of accounts and IP addresses locked out after too many failed sign in attempts.",
+}
+
+// MetricJobsSucceededTotal is a metric that counts jobs that succeeded, by
+// the type of job.
+var MetricJobsSucceededTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "jobs_succeeded_total",
+	Unit:        "{count}",
+	Description: "Total number of jobs that succeeded.",
+}
+
+// MetricJobFailuresTotal is a metric that counts failed job runs that are
+// retried, by the type of job.
+var MetricJobFailuresTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "job_failures_total",
+	Unit:        "{count}",
+	Description: "Total number of failed job runs that are retried.",
+}
+
+// MetricJobsDeadTotal is a metric that counts jobs that were given up on, by
+// the type of job.
+var MetricJobsDeadTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "jobs_dead_total",
+	Unit:        "{count}",
+	Description: "Total number of jobs that failed too often and were given up on.",
+}
+
+// MetricJobDurationMillis is a metric that measures how long job runs take,
+// in milliseconds, by the type of job.
+var MetricJobDurationMillis = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "job_duration_millis",
+	Unit:        "ms",
+	Description: "Measures how long job runs take, in milliseconds.",
+}
+
+// MetricWebhooksDeliveredTotal is a metric that counts webhook deliveries
+// that endpoints accepted, by the type of event.
+var MetricWebhooksDeliveredTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "webhooks_delivered_total",
+	Unit:        "{count}",
+	Description: "Total number of webhook deliveries that endpoints accepted.",
+}
+
+// MetricWebhookDeliveryFailuresTotal is a metric that counts failed webhook
+// delivery attempts that are retried, by the type of event.
+var MetricWebhookDeliveryFailuresTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "webhook_delivery_failures_total",
+	Unit:        "{count}",
+	Description: "Total number of failed webhook delivery attempts that are retried.",
+}
+
+// MetricWebhooksDeadTotal is a metric that counts webhook deliveries that
+// were given up on, by the type of event.
+var MetricWebhooksDeadTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "webhooks_dead_total",
+	Unit:        "{count}",
+	Description: "Total number of webhook deliveries that failed too often and were given up on.",
+}
+
+// MetricWebhookEndpointsDisabledTotal is a metric that counts webhook
+// endpoints that were disabled after sustained failures.
+var MetricWebhookEndpointsDisabledTotal = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "webhook_endpoints_disabled_total",
+	Unit:        "{count}",
+	Description: "Total number of webhook endpoints disabled after sustained failures.",
+}
+
+// MetricWebhookDeliveryDurationMillis is a metric that measures how long
+// webhook delivery attempts take, in milliseconds, by the type of event.
+var MetricWebhookDeliveryDurationMillis = Metric{ //nolint:gochecknoglobals // makes more sense like this.
+	Name:        "webhook_delivery_duration_millis",
+	Unit:        "ms",
+	Description: "Measures how long webhook delivery attempts take, in milliseconds.",
+}
@@ -61,6 +61,90 @@ func TestMetricStructFields(t *testing.T) {
 			name:   "requests in flight metric",
 			metric: telemetry.MetricRequestsInFlight,
 		},
+		{
+			name:   "emails sent metric",
+			metric: telemetry.MetricEmailsSentTotal,
+		},
+		{
+			name:   "email delivery failures metric",
+			metric: telemetry.MetricEmailDeliveryFailuresTotal,
+		},
+		{
+			name:   "emails dead metric",
+			metric: telemetry.MetricEmailsDeadTotal,
+		},
+		{
+			name:   "email delivery duration metric",
+			metric: telemetry.MetricEmailDeliveryDurationMillis,
+		},
+		{
+			name:   "pwn cache hits metric",
+			metric: telemetry.MetricPwnCacheHitsTotal,
+		},
+		{
+			name:   "pwn cache misses metric",
+			metric: telemetry.MetricPwnCacheMissesTotal,
+		},
+		{
+			name:   "pwn api failures metric",
+			metric: telemetry.MetricPwnAPIFailuresTotal,
+		},
+		{
+			name:   "pwn api duration metric",
+			metric: telemetry.MetricPwnAPIDurationMillis,
+		},
+		{
+			name:   "pwn circuit open metric",
+			metric: telemetry.MetricPwnCircuitOpen,
+		},
+		{
+			name:   "login failures metric",
+			metric: telemetry.MetricLoginFailuresTotal,
+		},
+		{
+			name:   "login throttled metric",
+			metric: telemetry.MetricLoginThrottledTotal,
+		},
+		{
+			name:   "login lockouts metric",
+			metric: telemetry.MetricLoginLockoutsTotal,
+		},
+		{
+			name:   "jobs succeeded metric",
+			metric: telemetry.MetricJobsSucceededTotal,
+		},
+		{
+			name:   "job failures metric",
+			metric: telemetry.MetricJobFailuresTotal,
+		},
+		{
+			name:   "jobs dead metric",
+			metric: telemetry.MetricJobsDeadTotal,
+		},
+		{
+			name:   "job duration metric",
+			metric: telemetry.MetricJobDurationMillis,
+		},
+		{
+			name:   "webhooks delivered metric",
+			metric: telemetry.MetricWebhooksDeliveredTotal,
+		},
+		{
+			name:   "webhook delivery failures metric",
+			metric: telemetry.MetricWebhookDeliveryFailuresTotal,
+		},
+		{
+			name:   "webhooks dead metric",
+			metric: telemetry.MetricWebhooksDeadTotal,
+		},
+		{
+			name:   "webhook endpoints disabled metric",
+			metric: telemetry.MetricWebhookEndpointsDisabledTotal,
+		},
+		{
+			name:   "webhook delivery duration metric",
+			metric: telemetry.MetricWebhookDeliveryDurationMillis,
+		},
 	}
 
 	for _, tt := range tests {
@@ -27,7 +27,7 @@ type Instruments interface {
 	Histogram(Metric) (otelmetric.Int64Histogram, error)
 	UpDownCounter(Metric) (otelmetric.Int64UpDownCounter, error)
 	Gauge(Metric) (otelmetric.Int64Gauge, error)
-	TraceStart(context.Context, string) (context.Context, oteltrace.Span)
+	TraceStart(context.Context, string, ...oteltrace.SpanStartOption) (context.Context, oteltrace.Span)
 }
 
 type OtelConfig interface {
@@ -144,13 +144,14 @@ func (t *otelTelemetry) Gauge(metric Metric) (otelmetric.Int64Gauge, error) {
 	return gauge, nil
 }
 
-// TraceStart starts a new span with the given name. The span must be ended by calling End.
-func (t *otelTelemetry) TraceStart(ctx context.Context, name string) (
+// TraceStart starts a new span with the given name and options, such as links
+// to other spans. The span must be ended by calling End.
+func (t *otelTelemetry) TraceStart(ctx context.Context, name string, opts ...oteltrace.SpanStartOption) (
 	context.Context,
 	oteltrace.Span,
 ) { //nolint:ireturn // interface requires returning concrete type
 	//nolint:spancheck // span is intentionally returned for caller to manage
-	return t.tracer.Start(ctx, name)
+	return t.tracer.Start(ctx, name, opts...)
 }
 
 // LoggerProvider returns the OpenTelemetry logger provider for log integration.
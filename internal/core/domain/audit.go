@@ -0,0 +1,323 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionSignUp = AuditAction{name: "auth.sign_up"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionSignIn = AuditAction{name: "auth.sign_in"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionSignOut = AuditAction{name: "auth.sign_out"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionSessionIssued = AuditAction{name: "auth.session_issued"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionReauthenticate = AuditAction{name: "auth.reauthenticate"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionPasswordChange = AuditAction{name: "auth.password_change"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionIdentityLink = AuditAction{name: "auth.identity_link"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionIdentityUnlink = AuditAction{name: "auth.identity_unlink"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionEmailVerification = AuditAction{name: "auth.email_verification"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionPasswordResetRequest = AuditAction{name: "auth.password_reset_request"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionPasswordReset = AuditAction{name: "auth.password_reset"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAccountLocked = AuditAction{name: "auth.account_locked"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAccountUnlock = AuditAction{name: "auth.account_unlock"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionMFAEnroll = AuditAction{name: "auth.mfa_enroll"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionMFAChallenge = AuditAction{name: "auth.mfa_challenge"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionMFADisable = AuditAction{name: "auth.mfa_disable"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionMFAReset = AuditAction{name: "auth.mfa_reset"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionRecoveryCodes = AuditAction{name: "auth.recovery_codes"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionPasskeyRegister = AuditAction{name: "auth.passkey_register"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionPasskeyRemove = AuditAction{name: "auth.passkey_remove"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAccessDenied = AuditAction{name: "authz.access_denied"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionRoleAssign = AuditAction{name: "authz.role_assign"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionRoleRevoke = AuditAction{name: "authz.role_revoke"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminSearch = AuditAction{name: "admin.search"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminView = AuditAction{name: "admin.view"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminRefund = AuditAction{name: "admin.refund"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminResendReceipt = AuditAction{name: "admin.resend_receipt"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminResetDownloads = AuditAction{name: "admin.reset_downloads"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminVerifyEmail = AuditAction{name: "admin.verify_email"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminDeleteCustomer = AuditAction{name: "admin.delete_customer"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAdminRestoreCustomer = AuditAction{name: "admin.restore_customer"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionAuditQuery = AuditAction{name: "audit.query"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionCustomerExport = AuditAction{name: "privacy.export"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionCustomerErase = AuditAction{name: "privacy.erase"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionWebhookView = AuditAction{name: "webhook.view"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionWebhookCreate = AuditAction{name: "webhook.create"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionWebhookUpdate = AuditAction{name: "webhook.update"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionWebhookDelete = AuditAction{name: "webhook.delete"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionWebhookRotateSecret = AuditAction{name: "webhook.rotate_secret"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditActionWebhookRedeliver = AuditAction{name: "webhook.redeliver"}
+)
+
+// AuditAction is a pseudo-enum that names a security relevant action, such as
+// signing in or signing out.
+type AuditAction struct {
+	name string
+}
+
+// NewAuditAction returns an audit action given its name.
+func NewAuditAction(name string) (AuditAction, error) {
+	for _, a := range allAuditActions() {
+		if a.name == name {
+			return a, nil
+		}
+	}
+	return AuditAction{name: ""}, errors.New("invalid audit action")
+}
+
+// allAuditActions returns every audit action.
+func allAuditActions() []AuditAction {
+	return []AuditAction{
+		AuditActionSignUp,
+		AuditActionSignIn,
+		AuditActionSignOut,
+		AuditActionSessionIssued,
+		AuditActionReauthenticate,
+		AuditActionPasswordChange,
+		AuditActionIdentityLink,
+		AuditActionIdentityUnlink,
+		AuditActionEmailVerification,
+		AuditActionPasswordResetRequest,
+		AuditActionPasswordReset,
+		AuditActionAccountLocked,
+		AuditActionAccountUnlock,
+		AuditActionMFAEnroll,
+		AuditActionMFAChallenge,
+		AuditActionMFADisable,
+		AuditActionMFAReset,
+		AuditActionRecoveryCodes,
+		AuditActionPasskeyRegister,
+		AuditActionPasskeyRemove,
+		AuditActionAccessDenied,
+		AuditActionRoleAssign,
+		AuditActionRoleRevoke,
+		AuditActionAdminSearch,
+		AuditActionAdminView,
+		AuditActionAdminRefund,
+		AuditActionAdminResendReceipt,
+		AuditActionAdminResetDownloads,
+		AuditActionAdminVerifyEmail,
+		AuditActionAdminDeleteCustomer,
+		AuditActionAdminRestoreCustomer,
+		AuditActionAuditQuery,
+		AuditActionCustomerExport,
+		AuditActionCustomerErase,
+		AuditActionWebhookView,
+		AuditActionWebhookCreate,
+		AuditActionWebhookUpdate,
+		AuditActionWebhookDelete,
+		AuditActionWebhookRotateSecret,
+		AuditActionWebhookRedeliver,
+	}
+}
+
+func (a AuditAction) String() string {
+	return a.name
+}
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditOutcomeSuccess = AuditOutcome{name: "success"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditOutcomeFailure = AuditOutcome{name: "failure"}
+)
+
+// AuditOutcome is a pseudo-enum that describes whether an audited action
+// succeeded or failed.
+type AuditOutcome struct {
+	name string
+}
+
+// NewAuditOutcome returns an audit outcome given its name.
+func NewAuditOutcome(name string) (AuditOutcome, error) {
+	switch name {
+	case "success":
+		return AuditOutcomeSuccess, nil
+	case "failure":
+		return AuditOutcomeFailure, nil
+	default:
+		return AuditOutcome{name: ""}, errors.New("invalid audit outcome")
+	}
+}
+
+func (a AuditOutcome) String() string {
+	return a.name
+}
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditTargetNone = AuditTargetType{name: ""}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditTargetOrder = AuditTargetType{name: "order"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditTargetDownload = AuditTargetType{name: "download"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditTargetWebhookEndpoint = AuditTargetType{name: "webhook_endpoint"}
+	//nolint:gochecknoglobals // These simulate enums.
+	AuditTargetWebhookDelivery = AuditTargetType{name: "webhook_delivery"}
+)
+
+// AuditTargetType is a pseudo-enum that describes what kind of thing other
+// than a customer an audited action concerns. AuditTargetNone, the zero
+// value, means the action concerns no such thing.
+type AuditTargetType struct {
+	name string
+}
+
+// NewAuditTargetType returns an audit target type given its name. The
+// empty name is AuditTargetNone.
+func NewAuditTargetType(name string) (AuditTargetType, error) {
+	switch name {
+	case "":
+		return AuditTargetNone, nil
+	case "order":
+		return AuditTargetOrder, nil
+	case "download":
+		return AuditTargetDownload, nil
+	case "webhook_endpoint":
+		return AuditTargetWebhookEndpoint, nil
+	case "webhook_delivery":
+		return AuditTargetWebhookDelivery, nil
+	default:
+		return AuditTargetType{name: ""}, errors.New("invalid audit target type")
+	}
+}
+
+func (a AuditTargetType) String() string {
+	return a.name
+}
+
+// AuditEvent is a structured record of a security relevant action. Events are
+// emitted for every authentication outcome, successful or not, so that an
+// operator can reconstruct who did what and when.
+type AuditEvent struct {
+	// Action is the action that was attempted.
+	Action AuditAction
+	// Outcome is whether the action succeeded.
+	Outcome AuditOutcome
+	// ActorID is the ID of the customer who performed the action, such as
+	// a support agent changing another customer's account. It is the same
+	// as CustomerID when customers act on their own account.
+	ActorID string
+	// CustomerID is the ID of the customer the action concerns. It is empty
+	// when the customer could not be identified, such as a sign in with an
+	// unknown email.
+	CustomerID string
+	// TargetType is the kind of thing the action concerns, such as an order
+	// being refunded. It is AuditTargetNone if the action concerns nothing
+	// but a customer.
+	TargetType AuditTargetType
+	// TargetID is the ID of the thing the action concerns.
+	TargetID string
+	// Reason is a short, machine readable explanation of a failure, such as
+	// "invalid_credentials". It is empty on success.
+	Reason string
+	// RequestID is the ID of the request the action was performed in, if
+	// any. See ContextWithRequestID.
+	RequestID string
+	// TraceID is the hex encoded ID of the trace the action was performed
+	// in, if any.
+	TraceID string
+	// OccurredAt is when the action happened.
+	OccurredAt time.Time
+}
+
+// NewAuditEvent creates a new audit event, of an action customers performed
+// on their own account, that occurred now.
+func NewAuditEvent(
+	action AuditAction,
+	outcome AuditOutcome,
+	customerID, reason string,
+) AuditEvent {
+	return NewActorAuditEvent(action, outcome, customerID, customerID, reason)
+}
+
+// NewActorAuditEvent creates a new audit event, of an action a customer
+// performed on the account of another customer, that occurred now.
+func NewActorAuditEvent(
+	action AuditAction,
+	outcome AuditOutcome,
+	actorID, customerID, reason string,
+) AuditEvent {
+	return AuditEvent{
+		Action:     action,
+		Outcome:    outcome,
+		ActorID:    actorID,
+		CustomerID: customerID,
+		TargetType: AuditTargetNone,
+		TargetID:   "",
+		Reason:     reason,
+		RequestID:  "",
+		TraceID:    "",
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+// WithTarget returns a copy of the event that concerns a thing of a type
+// and ID.
+func (e AuditEvent) WithTarget(typ AuditTargetType, id string) AuditEvent {
+	e.TargetType = typ
+	e.TargetID = id
+	return e
+}
+
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of a context that carries the ID of
+// the request it belongs to, so that audit events can be correlated with
+// the request.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID a context carries, or an
+// empty string.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// GenesisAuditHash is the previous hash of the first entry of the audit log.
+//
+//nolint:gochecknoglobals // makes more sense like this.
+var GenesisAuditHash = strings.Repeat("0", sha256.Size*2)
+
+// AuditEntry is an audit event stored in the append-only audit log. Entries
+// form a hash chain: each one stores the hash of the previous entry, and a
+// hash of its own content and of the previous hash. Altering an entry
+// changes its hash, and deleting one breaks the link of the entry after it,
+// so both are detected by VerifyAuditChain.
+type AuditEntry struct {
+	// Sequence is the position of the entry in the log, starting at 1.
+	// Sequences have no gaps.
+	Sequence int64
+	// Event is the recorded event.
+	Event AuditEvent
+	// PrevHash is the hex encoded hash of the previous entry, or
+	// GenesisAuditHash for the first entry.
+	PrevHash string
+	// Hash is the hex encoded SHA-256 hash of the entry.
+	Hash string
+}
+
+// NewAuditEntry creates the entry of an event that follows prev in the
+// audit log. prev is nil for the first entry. The time of the event is
+// truncated to microseconds, the precision of the database.
+func NewAuditEntry(prev *AuditEntry, event AuditEvent) AuditEntry {
+	entry := AuditEntry{
+		Sequence: 1,
+		Event:    event,
+		PrevHash: GenesisAuditHash,
+		Hash:     "",
+	}
+	if prev != nil {
+		entry.Sequence = prev.Sequence + 1
+		entry.PrevHash = prev.Hash
+	}
+	entry.Event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+	return entry
+}
+
+// ComputeHash computes the hash the entry should have given its content and
+// previous hash. Every field is length prefixed, so that no two different
+// entries hash the same content. The target is hashed last and only if the
+// event has one, so that entries recorded before events had targets keep
+// their hashes.
+func (e AuditEntry) ComputeHash() string {
+	h := sha256.New()
+	write := func(s string) {
+		var n [binary.MaxVarintLen64]byte
+		h.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))])
+		h.Write([]byte(s))
+	}
+	write(e.PrevHash)
+	write(fmt.Sprint(e.Sequence))
+	write(e.Event.Action.String())
+	write(e.Event.Outcome.String())
+	write(e.Event.ActorID)
+	write(e.Event.CustomerID)
+	write(e.Event.Reason)
+	write(e.Event.RequestID)
+	write(e.Event.TraceID)
+	write(e.Event.OccurredAt.UTC().Format(time.RFC3339Nano))
+	if e.Event.TargetType != AuditTargetNone || e.Event.TargetID != "" {
+		write(e.Event.TargetType.String())
+		write(e.Event.TargetID)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyAuditChain checks that entries, ordered by sequence, follow prev in
+// the audit log and that none of them was altered. prev is nil if entries
+// start at the first entry of the log. It returns an *AuditChainError for
+// the first entry that breaks the chain.
+func VerifyAuditChain(prev *AuditEntry, entries []AuditEntry) error {
+	for _, e := range entries {
+		wantSequence, wantPrevHash := int64(1), GenesisAuditHash
+		if prev != nil {
+			wantSequence, wantPrevHash = prev.Sequence+1, prev.Hash
+		}
+		switch {
+		case e.Sequence != wantSequence:
+			return &AuditChainError{Sequence: wantSequence, Reason: "entry is missing"}
+		case e.PrevHash != wantPrevHash:
+			return &AuditChainError{Sequence: e.Sequence, Reason: "previous entry was altered or removed"}
+		case e.Hash != e.ComputeHash():
+			return &AuditChainError{Sequence: e.Sequence, Reason: "entry was altered"}
+		}
+		prev = &e
+	}
+	return nil
+}
+
+// AuditChainError tells where the hash chain of the audit log is broken.
+type AuditChainError struct {
+	// Sequence is the sequence of the first entry that does not fit the
+	// chain.
+	Sequence int64
+	// Reason describes what is wrong with the entry.
+	Reason string
+}
+
+func (e *AuditChainError) Error() string {
+	return fmt.Sprintf("audit log broken at entry %d: %s", e.Sequence, e.Reason)
+}
+
+func (e *AuditChainError) Is(target error) bool {
+	return target == ErrAuditChainBroken
+}
+
+// AuditFilter selects entries of the audit log. Zero fields match every
+// entry.
+type AuditFilter struct {
+	// ActorID matches entries of actions performed by a customer.
+	ActorID string
+	// CustomerID matches entries of actions that concern a customer.
+	CustomerID string
+	// Since matches entries that occurred at or after a time.
+	Since time.Time
+	// Until matches entries that occurred before a time.
+	Until time.Time
+	// BeforeSequence matches entries before a sequence, for paging through
+	// results. Zero means the latest entry.
+	BeforeSequence int64
+	// Limit is the largest number of entries to return.
+	Limit int
+}
+
+// Matches reports whether the filter matches an entry, ignoring Limit.
+func (f AuditFilter) Matches(e AuditEntry) bool {
+	return (f.ActorID == "" || e.Event.ActorID == f.ActorID) &&
+		(f.CustomerID == "" || e.Event.CustomerID == f.CustomerID) &&
+		(f.Since.IsZero() || !e.Event.OccurredAt.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Event.OccurredAt.Before(f.Until)) &&
+		(f.BeforeSequence == 0 || e.Sequence < f.BeforeSequence)
+}
+
+// ErrAuditChainBroken is returned when entries of the audit log were
+// deleted or altered.
+var ErrAuditChainBroken = errors.New("audit log chain is broken")
@@ -0,0 +1,148 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+// auditChain makes a chain of n entries.
+func auditChain(n int) []domain.AuditEntry {
+	entries := make([]domain.AuditEntry, 0, n)
+	var prev *domain.AuditEntry
+	for range n {
+		entry := domain.NewAuditEntry(prev, domain.NewActorAuditEvent(
+			domain.AuditActionAdminRefund, domain.AuditOutcomeSuccess, "support", "kai", "",
+		).WithTarget(domain.AuditTargetOrder, "order-1"))
+		entries = append(entries, entry)
+		prev = &entry
+	}
+	return entries
+}
+
+func TestVerifyAuditChain(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		tamper       func([]domain.AuditEntry) []domain.AuditEntry
+		wantSequence int64
+	}{
+		{
+			CaseBase:     test.NewCaseBase("intact", nil, false),
+			tamper:       func(e []domain.AuditEntry) []domain.AuditEntry { return e },
+			wantSequence: 0,
+		},
+		{
+			CaseBase: test.NewCaseBase("altered entry", domain.ErrAuditChainBroken, true),
+			tamper: func(e []domain.AuditEntry) []domain.AuditEntry {
+				e[2].Event.CustomerID = "someone else"
+				return e
+			},
+			wantSequence: 3,
+		},
+		{
+			CaseBase: test.NewCaseBase("altered target", domain.ErrAuditChainBroken, true),
+			tamper: func(e []domain.AuditEntry) []domain.AuditEntry {
+				e[1].Event.TargetID = "order-2"
+				return e
+			},
+			wantSequence: 2,
+		},
+		{
+			CaseBase: test.NewCaseBase("removed target", domain.ErrAuditChainBroken, true),
+			tamper: func(e []domain.AuditEntry) []domain.AuditEntry {
+				e[3].Event.TargetType = domain.AuditTargetNone
+				e[3].Event.TargetID = ""
+				return e
+			},
+			wantSequence: 4,
+		},
+		{
+			CaseBase: test.NewCaseBase("altered and rehashed entry", domain.ErrAuditChainBroken, true),
+			tamper: func(e []domain.AuditEntry) []domain.AuditEntry {
+				e[1].Event.Outcome = domain.AuditOutcomeFailure
+				e[1].Hash = e[1].ComputeHash()
+				return e
+			},
+			wantSequence: 3,
+		},
+		{
+			CaseBase: test.NewCaseBase("deleted entry", domain.ErrAuditChainBroken, true),
+			tamper: func(e []domain.AuditEntry) []domain.AuditEntry {
+				return slices.Delete(e, 1, 2)
+			},
+			wantSequence: 2,
+		},
+		{
+			CaseBase: test.NewCaseBase("deleted and renumbered entry", domain.ErrAuditChainBroken, true),
+			tamper: func(e []domain.AuditEntry) []domain.AuditEntry {
+				e = slices.Delete(e, 1, 2)
+				for i := 1; i < len(e); i++ {
+					e[i].Sequence--
+				}
+				return e
+			},
+			wantSequence: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				err := domain.VerifyAuditChain(nil, tt.tamper(auditChain(4)))
+				if !tt.WantErr {
+					assert.NoError(t, err)
+					return
+				}
+				assert.Error(t, err, tt.Want.(error))
+				assert.Equal(t, err.(*domain.AuditChainError).Sequence, tt.wantSequence)
+			},
+		)
+	}
+}
+
+func TestVerifyAuditChain_FromEntry(t *testing.T) {
+	entries := auditChain(4)
+	assert.NoError(t, domain.VerifyAuditChain(&entries[1], entries[2:]))
+	err := domain.VerifyAuditChain(&entries[0], entries[2:])
+	assert.Error(t, err, domain.ErrAuditChainBroken)
+}
+
+func TestAuditEntry_ComputeHashWithoutTarget(t *testing.T) {
+	// Entries without a target hash as they did before events had targets.
+	entry := domain.AuditEntry{
+		Sequence: 1,
+		Event: domain.AuditEvent{
+			Action:     domain.AuditActionAdminRefund,
+			Outcome:    domain.AuditOutcomeSuccess,
+			ActorID:    "support",
+			CustomerID: "kai",
+			TargetType: domain.AuditTargetNone,
+			TargetID:   "",
+			Reason:     "",
+			RequestID:  "request-1",
+			TraceID:    "",
+			OccurredAt: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		},
+		PrevHash: domain.GenesisAuditHash,
+		Hash:     "",
+	}
+	assert.Equal(t, entry.ComputeHash(), "800c0b9c68bfb0db78a8a231235eda6eed2bda396e82f298181197bd8509ef38")
+}
+
+func TestAuditFilter_Matches(t *testing.T) {
+	entry := auditChain(1)[0]
+	assert.True(t, domain.AuditFilter{}.Matches(entry))
+	assert.True(t, domain.AuditFilter{ActorID: "support", CustomerID: "kai"}.Matches(entry))
+	assert.False(t, domain.AuditFilter{ActorID: "kai"}.Matches(entry))
+	assert.False(t, domain.AuditFilter{Since: entry.Event.OccurredAt.Add(1)}.Matches(entry))
+	assert.False(t, domain.AuditFilter{Until: entry.Event.OccurredAt}.Matches(entry))
+	assert.False(t, domain.AuditFilter{BeforeSequence: 1}.Matches(entry))
+}
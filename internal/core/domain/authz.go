@@ -0,0 +1,261 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"slices"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	RoleCustomer = Role{name: "customer"}
+	//nolint:gochecknoglobals // These simulate enums.
+	RoleSupport = Role{name: "support"}
+	//nolint:gochecknoglobals // These simulate enums.
+	RoleAdmin = Role{name: "admin"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidRole = Role{name: ""}
+)
+
+// Role is a pseudo-enum that names a set of permissions a customer holds.
+// Every customer has the customer role; the other roles are assigned to
+// staff.
+type Role struct {
+	name string
+}
+
+// NewRole returns a role given its name.
+func NewRole(name string) (Role, error) {
+	switch name {
+	case "customer":
+		return RoleCustomer, nil
+	case "support":
+		return RoleSupport, nil
+	case "admin":
+		return RoleAdmin, nil
+	default:
+		return InvalidRole, errors.New("invalid role")
+	}
+}
+
+func (r Role) String() string {
+	return r.name
+}
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionCustomersView = Permission{name: "customers.view"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionCustomersUpdate = Permission{name: "customers.update"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionCustomersDelete = Permission{name: "customers.delete"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionOrdersView = Permission{name: "orders.view"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionOrdersRefund = Permission{name: "orders.refund"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionRolesAssign = Permission{name: "roles.assign"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionBackOfficeAccess = Permission{name: "backoffice.access"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionCustomersVerifyEmail = Permission{name: "customers.verify_email"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionCustomersRestore = Permission{name: "customers.restore"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionCustomersMFAReset = Permission{name: "customers.mfa_reset"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionOrdersResendReceipt = Permission{name: "orders.resend_receipt"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionDownloadsView = Permission{name: "downloads.view"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionDownloadsReset = Permission{name: "downloads.reset"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionAuditView = Permission{name: "audit.view"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PermissionWebhooksManage = Permission{name: "webhooks.manage"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidPermission = Permission{name: ""}
+)
+
+// Permission is a pseudo-enum that names an action on a kind of resource,
+// such as viewing orders.
+type Permission struct {
+	name string
+}
+
+// NewPermission returns a permission given its name.
+func NewPermission(name string) (Permission, error) {
+	switch name {
+	case "customers.view":
+		return PermissionCustomersView, nil
+	case "customers.update":
+		return PermissionCustomersUpdate, nil
+	case "customers.delete":
+		return PermissionCustomersDelete, nil
+	case "orders.view":
+		return PermissionOrdersView, nil
+	case "orders.refund":
+		return PermissionOrdersRefund, nil
+	case "roles.assign":
+		return PermissionRolesAssign, nil
+	case "backoffice.access":
+		return PermissionBackOfficeAccess, nil
+	case "customers.verify_email":
+		return PermissionCustomersVerifyEmail, nil
+	case "customers.restore":
+		return PermissionCustomersRestore, nil
+	case "customers.mfa_reset":
+		return PermissionCustomersMFAReset, nil
+	case "orders.resend_receipt":
+		return PermissionOrdersResendReceipt, nil
+	case "downloads.view":
+		return PermissionDownloadsView, nil
+	case "downloads.reset":
+		return PermissionDownloadsReset, nil
+	case "audit.view":
+		return PermissionAuditView, nil
+	case "webhooks.manage":
+		return PermissionWebhooksManage, nil
+	default:
+		return InvalidPermission, errors.New("invalid permission")
+	}
+}
+
+func (p Permission) String() string {
+	return p.name
+}
+
+// Grant gives a role a permission, optionally under conditions. A role
+// holds a permission once at most.
+type Grant struct {
+	// Role is the role that is granted the permission.
+	Role Role
+	// Permission is the granted permission.
+	Permission Permission
+	// OwnOnly limits the grant to resources that belong to the principal,
+	// such as a customer viewing their own orders.
+	OwnOnly bool
+	// AmountLimit is the largest amount, in the smallest unit of the
+	// currency, the permission may be used for, such as the largest order
+	// a support agent may refund. Nil means there is no limit.
+	AmountLimit *int
+}
+
+// DefaultGrants returns the grants the database schema is seeded with.
+// Customers may see and change their own account and see their own orders
+// and downloads. Support agents may use the back office to see every
+// customer, order and download, help customers with their receipts,
+// downloads, email verification and lost second factors, and refund orders
+// of up to 50.00 in any currency. Admins may do anything.
+func DefaultGrants() []Grant {
+	supportRefundLimit := 5000
+	grants := []Grant{
+		{Role: RoleCustomer, Permission: PermissionCustomersView, OwnOnly: true, AmountLimit: nil},
+		{Role: RoleCustomer, Permission: PermissionCustomersUpdate, OwnOnly: true, AmountLimit: nil},
+		{Role: RoleCustomer, Permission: PermissionCustomersDelete, OwnOnly: true, AmountLimit: nil},
+		{Role: RoleCustomer, Permission: PermissionOrdersView, OwnOnly: true, AmountLimit: nil},
+		{Role: RoleCustomer, Permission: PermissionDownloadsView, OwnOnly: true, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionBackOfficeAccess, OwnOnly: false, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionCustomersView, OwnOnly: false, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionCustomersVerifyEmail, OwnOnly: false, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionCustomersMFAReset, OwnOnly: false, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionOrdersView, OwnOnly: false, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionOrdersRefund, OwnOnly: false, AmountLimit: &supportRefundLimit},
+		{Role: RoleSupport, Permission: PermissionOrdersResendReceipt, OwnOnly: false, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionDownloadsView, OwnOnly: false, AmountLimit: nil},
+		{Role: RoleSupport, Permission: PermissionDownloadsReset, OwnOnly: false, AmountLimit: nil},
+	}
+	for _, p := range allPermissions() {
+		grants = append(grants, Grant{Role: RoleAdmin, Permission: p, OwnOnly: false, AmountLimit: nil})
+	}
+	return grants
+}
+
+// allPermissions returns every permission.
+func allPermissions() []Permission {
+	return []Permission{
+		PermissionCustomersView,
+		PermissionCustomersUpdate,
+		PermissionCustomersDelete,
+		PermissionCustomersVerifyEmail,
+		PermissionCustomersRestore,
+		PermissionCustomersMFAReset,
+		PermissionOrdersView,
+		PermissionOrdersRefund,
+		PermissionOrdersResendReceipt,
+		PermissionDownloadsView,
+		PermissionDownloadsReset,
+		PermissionRolesAssign,
+		PermissionBackOfficeAccess,
+		PermissionAuditView,
+		PermissionWebhooksManage,
+	}
+}
+
+// AccessRequest describes an action a principal attempts, so that it can be
+// checked against the grants of the principal.
+type AccessRequest struct {
+	// Permission is the permission the action requires.
+	Permission Permission
+	// OwnerID is the ID of the customer the resource belongs to, such as
+	// the customer who placed an order. Grants limited to own resources
+	// only match when it is the principal's ID.
+	OwnerID string
+	// Amount is the amount the action is for, in the smallest unit of the
+	// currency, such as the total of a refunded order. Zero for actions
+	// without an amount.
+	Amount int
+}
+
+// Principal is the authenticated customer behind a request, along with
+// everything they may do.
+type Principal struct {
+	// CustomerID is the ID of the customer.
+	CustomerID string
+	// Session is the session the customer authenticated with.
+	Session *UserSession
+	// Roles are the roles of the customer, including the customer role.
+	Roles []Role
+	// Grants are the grants of every role of the customer.
+	Grants []Grant
+}
+
+// HasRole reports whether the principal has a role.
+func (p *Principal) HasRole(role Role) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// Holds reports whether the principal is granted a permission at all,
+// whatever its conditions. It is a coarse check, suited to reject requests
+// early; the action itself must still be checked with Allows.
+func (p *Principal) Holds(permission Permission) bool {
+	return slices.ContainsFunc(p.Grants, func(g Grant) bool {
+		return g.Permission == permission
+	})
+}
+
+// Allows reports whether one of the grants of the principal allows an
+// action.
+func (p *Principal) Allows(req AccessRequest) bool {
+	return slices.ContainsFunc(p.Grants, func(g Grant) bool {
+		if g.Permission != req.Permission {
+			return false
+		}
+		if g.OwnOnly && (req.OwnerID == "" || req.OwnerID != p.CustomerID) {
+			return false
+		}
+		return g.AmountLimit == nil || req.Amount <= *g.AmountLimit
+	})
+}
+
+var (
+	// ErrUnauthenticated is returned when a request carries no valid
+	// session.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when a principal may not perform an action.
+	ErrForbidden = errors.New("forbidden")
+)
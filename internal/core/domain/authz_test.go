@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain_test
+
+import (
+	"testing"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+func principalWith(id string, roles ...domain.Role) *domain.Principal {
+	var grants []domain.Grant
+	for _, g := range domain.DefaultGrants() {
+		for _, r := range roles {
+			if g.Role == r {
+				grants = append(grants, g)
+			}
+		}
+	}
+	return &domain.Principal{CustomerID: id, Session: nil, Roles: roles, Grants: grants}
+}
+
+func TestPrincipal_Allows(t *testing.T) {
+	customer := principalWith("kai", domain.RoleCustomer)
+	support := principalWith("lea", domain.RoleCustomer, domain.RoleSupport)
+	admin := principalWith("max", domain.RoleCustomer, domain.RoleAdmin)
+
+	tests := []struct {
+		test.CaseBase
+		principal *domain.Principal
+		req       domain.AccessRequest
+	}{
+		{
+			CaseBase:  test.NewCaseBase("customer views own order", true, false),
+			principal: customer,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersView, OwnerID: "kai", Amount: 0},
+		},
+		{
+			CaseBase:  test.NewCaseBase("customer views other order", false, false),
+			principal: customer,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersView, OwnerID: "lea", Amount: 0},
+		},
+		{
+			CaseBase:  test.NewCaseBase("customer without owner", false, false),
+			principal: customer,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersView, OwnerID: "", Amount: 0},
+		},
+		{
+			CaseBase:  test.NewCaseBase("customer refunds own order", false, false),
+			principal: customer,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersRefund, OwnerID: "kai", Amount: 100},
+		},
+		{
+			CaseBase:  test.NewCaseBase("support views other order", true, false),
+			principal: support,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersView, OwnerID: "kai", Amount: 0},
+		},
+		{
+			CaseBase:  test.NewCaseBase("support refunds at limit", true, false),
+			principal: support,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersRefund, OwnerID: "kai", Amount: 5000},
+		},
+		{
+			CaseBase:  test.NewCaseBase("support refunds above limit", false, false),
+			principal: support,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersRefund, OwnerID: "kai", Amount: 5001},
+		},
+		{
+			CaseBase:  test.NewCaseBase("support assigns roles", false, false),
+			principal: support,
+			req:       domain.AccessRequest{Permission: domain.PermissionRolesAssign, OwnerID: "", Amount: 0},
+		},
+		{
+			CaseBase:  test.NewCaseBase("admin refunds above limit", true, false),
+			principal: admin,
+			req:       domain.AccessRequest{Permission: domain.PermissionOrdersRefund, OwnerID: "kai", Amount: 100000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				assert.Equal(t, tt.principal.Allows(tt.req), tt.Want.(bool))
+			},
+		)
+	}
+}
+
+func TestPrincipal_Holds(t *testing.T) {
+	customer := principalWith("kai", domain.RoleCustomer)
+	assert.True(t, customer.Holds(domain.PermissionOrdersView))
+	assert.False(t, customer.Holds(domain.PermissionOrdersRefund))
+	assert.True(t, customer.HasRole(domain.RoleCustomer))
+	assert.False(t, customer.HasRole(domain.RoleAdmin))
+}
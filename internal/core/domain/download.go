@@ -0,0 +1,51 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// Download tracks how often a customer downloaded a product they purchased.
+// A download is created for every downloadable product of an order.
+type Download struct {
+	// ID is the unique UUID v7 of the download.
+	ID string
+	// UserID is the ID of the customer who purchased the product.
+	UserID string
+	// ProductID is the ID of the purchased product.
+	ProductID string
+	// OrderID is the ID of the order the product was purchased in.
+	OrderID string
+	// DownloadCount is how many times the product was downloaded. Support
+	// can reset it, such as when a customer hits the download limit after
+	// losing their files.
+	DownloadCount int
+	// LastDownloadedAt is when the product was last downloaded, or nil if it
+	// never was.
+	LastDownloadedAt *time.Time
+	// CreatedAt is when the download was created.
+	CreatedAt time.Time
+}
+
+// NewDownload creates the download of a product a customer purchased in an
+// order.
+func NewDownload(customerID, productID, orderID string) (*Download, error) {
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new download")
+	}
+	return &Download{
+		ID:               id,
+		UserID:           customerID,
+		ProductID:        productID,
+		OrderID:          orderID,
+		DownloadCount:    0,
+		LastDownloadedAt: nil,
+		CreatedAt:        *now,
+	}, nil
+}
@@ -0,0 +1,163 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailTemplateEmailVerification = EmailTemplate{name: "email_verification"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailTemplatePasswordReset = EmailTemplate{name: "password_reset"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailTemplatePasswordChanged = EmailTemplate{name: "password_changed"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailTemplateOrderReceipt = EmailTemplate{name: "order_receipt"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailTemplateDownloadLinks = EmailTemplate{name: "download_links"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailTemplateAccountLocked = EmailTemplate{name: "account_locked"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidEmailTemplate = EmailTemplate{name: ""}
+)
+
+// EmailTemplate is a pseudo-enum that names the template a transactional
+// email is rendered with.
+type EmailTemplate struct {
+	name string
+}
+
+// NewEmailTemplate returns an email template given its name.
+func NewEmailTemplate(name string) (EmailTemplate, error) {
+	switch name {
+	case "email_verification":
+		return EmailTemplateEmailVerification, nil
+	case "password_reset":
+		return EmailTemplatePasswordReset, nil
+	case "password_changed":
+		return EmailTemplatePasswordChanged, nil
+	case "order_receipt":
+		return EmailTemplateOrderReceipt, nil
+	case "download_links":
+		return EmailTemplateDownloadLinks, nil
+	case "account_locked":
+		return EmailTemplateAccountLocked, nil
+	default:
+		return InvalidEmailTemplate, errors.New("invalid email template")
+	}
+}
+
+func (e EmailTemplate) String() string {
+	return e.name
+}
+
+// EmailMessage is a transactional email sent to a customer, before it is
+// rendered.
+type EmailMessage struct {
+	// To is the email address of the recipient.
+	To string
+	// Template is the template the email is rendered with.
+	Template EmailTemplate
+	// Data is what the template is rendered with, such as a link.
+	Data map[string]any
+}
+
+// RenderedEmail is a transactional email that is ready to be delivered.
+type RenderedEmail struct {
+	// To is the email address of the recipient.
+	To string
+	// Subject is the subject line of the email.
+	Subject string
+	// Text is the plain text body of the email.
+	Text string
+	// HTML is the HTML body of the email.
+	HTML string
+}
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailStatusPending = EmailStatus{name: "pending"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailStatusSent = EmailStatus{name: "sent"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EmailStatusDead = EmailStatus{name: "dead"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidEmailStatus = EmailStatus{name: ""}
+)
+
+// EmailStatus is a pseudo-enum that describes where an email in the outbox is
+// in its delivery.
+type EmailStatus struct {
+	name string
+}
+
+// NewEmailStatus returns an email status given its name.
+func NewEmailStatus(name string) (EmailStatus, error) {
+	switch name {
+	case "pending":
+		return EmailStatusPending, nil
+	case "sent":
+		return EmailStatusSent, nil
+	case "dead":
+		return EmailStatusDead, nil
+	default:
+		return InvalidEmailStatus, errors.New("invalid email status")
+	}
+}
+
+func (e EmailStatus) String() string {
+	return e.name
+}
+
+// OutboxEmail is a rendered email waiting in the outbox to be delivered.
+// Emails are written to the outbox alongside the change that caused them, and
+// are delivered in the background, so that an email is never sent for a
+// change that was rolled back.
+type OutboxEmail struct {
+	RenderedEmail
+	// ID is the unique UUID v7 of the email.
+	ID string
+	// Template is the template the email was rendered with.
+	Template EmailTemplate
+	// Status is where the email is in its delivery.
+	Status EmailStatus
+	// Attempts is how many times delivery was attempted.
+	Attempts int
+	// NextAttemptAt is the time at which delivery is attempted next.
+	NextAttemptAt time.Time
+	// LastError is the error of the last failed attempt.
+	LastError string
+	// CreatedAt is the time the email was written to the outbox at.
+	CreatedAt time.Time
+	// SentAt is the time the email was delivered at. It is nil until then.
+	SentAt *time.Time
+}
+
+// NewOutboxEmail creates a new pending email, due for delivery right away.
+func NewOutboxEmail(template EmailTemplate, email *RenderedEmail) (*OutboxEmail, error) {
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new outbox email")
+	}
+	return &OutboxEmail{
+		RenderedEmail: *email,
+		ID:            id,
+		Template:      template,
+		Status:        EmailStatusPending,
+		Attempts:      0,
+		NextAttemptAt: *now,
+		LastError:     "",
+		CreatedAt:     *now,
+		SentAt:        nil,
+	}, nil
+}
+
+// ErrEmailRejected is returned when an email cannot be delivered, however
+// often it is retried, such as when the recipient does not exist.
+var ErrEmailRejected = errors.New("email rejected")
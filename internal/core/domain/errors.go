@@ -0,0 +1,45 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import "go.brokedaear.com/pkg/errors"
+
+// These errors are returned by repositories so that the service layer can
+// tell a missing record apart from a failing adapter, without knowing which
+// adapter is in use.
+var (
+	ErrCustomerNotFound  = errors.New("customer not found")
+	ErrEmailTaken        = errors.New("email address already taken")
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrIdentityNotFound  = errors.New("identity not found")
+	ErrIdentityExists    = errors.New("identity already linked")
+	ErrOAuthFlowNotFound = errors.New("oauth flow not found")
+	ErrTokenNotFound     = errors.New("token not found")
+	ErrEmailNotFound     = errors.New("email not found")
+	ErrThrottleNotFound  = errors.New("login throttle not found")
+	ErrMFANotFound       = errors.New("mfa not found")
+	// ErrRecoveryCodeNotFound is also returned for recovery codes that were
+	// already used.
+	ErrRecoveryCodeNotFound    = errors.New("recovery code not found")
+	ErrPasskeyNotFound         = errors.New("passkey not found")
+	ErrPasskeyExists           = errors.New("passkey already registered")
+	ErrPasskeyCeremonyNotFound = errors.New("passkey ceremony not found")
+	ErrOrderNotFound           = errors.New("order not found")
+	ErrDownloadNotFound        = errors.New("download not found")
+	ErrEventNotFound           = errors.New("event not found")
+	ErrJobNotFound             = errors.New("job not found")
+	ErrJobScheduleNotFound     = errors.New("job schedule not found")
+	ErrWebhookEndpointNotFound = errors.New("webhook endpoint not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	// ErrSessionRotated is returned when a session is renewed from a secret
+	// that another renewal already replaced.
+	ErrSessionRotated = errors.New("session already rotated")
+	// ErrOrderStatusChanged is returned when an order is moved from a status
+	// it is no longer in.
+	ErrOrderStatusChanged = errors.New("order status changed")
+	// ErrWebhookLeaseExpired is returned when a webhook delivery is marked
+	// by a dispatcher whose lease on it ran out.
+	ErrWebhookLeaseExpired = errors.New("webhook delivery lease expired")
+)
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	EventTypeOrderPaid = EventType{name: "order.paid"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EventTypeOrderRefunded = EventType{name: "order.refunded"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EventTypeCustomerSignedUp = EventType{name: "customer.signed_up"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EventTypeCustomerDeleted = EventType{name: "customer.deleted"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidEventType = EventType{name: ""}
+)
+
+// EventType is a pseudo-enum that describes what happened in a domain event.
+type EventType struct {
+	name string
+}
+
+// NewEventType returns an event type given its name.
+func NewEventType(name string) (EventType, error) {
+	switch name {
+	case "order.paid":
+		return EventTypeOrderPaid, nil
+	case "order.refunded":
+		return EventTypeOrderRefunded, nil
+	case "customer.signed_up":
+		return EventTypeCustomerSignedUp, nil
+	case "customer.deleted":
+		return EventTypeCustomerDeleted, nil
+	default:
+		return InvalidEventType, errors.New("invalid event type")
+	}
+}
+
+func (e EventType) String() string {
+	return e.name
+}
+
+// EventTypes returns every event type.
+func EventTypes() []EventType {
+	return []EventType{
+		EventTypeOrderPaid,
+		EventTypeOrderRefunded,
+		EventTypeCustomerSignedUp,
+		EventTypeCustomerDeleted,
+	}
+}
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	EventStatusPending = EventStatus{name: "pending"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EventStatusPublished = EventStatus{name: "published"}
+	//nolint:gochecknoglobals // These simulate enums.
+	EventStatusDead = EventStatus{name: "dead"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidEventStatus = EventStatus{name: ""}
+)
+
+// EventStatus is a pseudo-enum that describes where an event in the outbox is
+// in its publication.
+type EventStatus struct {
+	name string
+}
+
+// NewEventStatus returns an event status given its name.
+func NewEventStatus(name string) (EventStatus, error) {
+	switch name {
+	case "pending":
+		return EventStatusPending, nil
+	case "published":
+		return EventStatusPublished, nil
+	case "dead":
+		return EventStatusDead, nil
+	default:
+		return InvalidEventStatus, errors.New("invalid event status")
+	}
+}
+
+func (e EventStatus) String() string {
+	return e.name
+}
+
+// Event is a domain event waiting in the outbox to be published to its
+// subscribers. Events are written to the outbox in the same transaction as
+// the change they describe, so that subscribers never hear of a change that
+// was rolled back. Events are published at least once, so subscribers must
+// tolerate hearing of the same event twice.
+type Event struct {
+	// ID is the unique UUID v7 of the event.
+	ID string
+	// Type is what happened.
+	Type EventType
+	// AggregateID is the ID of what the event happened to, such as a
+	// customer or an order.
+	AggregateID string
+	// Payload is the JSON encoded payload of the event, one of the payload
+	// types of its event type, such as OrderPaid.
+	Payload []byte
+	// TraceID is the hex encoded ID of the trace the event was emitted in,
+	// if any.
+	TraceID string
+	// SpanID is the hex encoded ID of the span the event was emitted in, if
+	// any.
+	SpanID string
+	// Status is where the event is in its publication.
+	Status EventStatus
+	// Attempts is how many times publication was attempted.
+	Attempts int
+	// NextAttemptAt is the time at which publication is attempted next.
+	NextAttemptAt time.Time
+	// LastError is the error of the last failed attempt.
+	LastError string
+	// CreatedAt is the time the event was written to the outbox at.
+	CreatedAt time.Time
+	// PublishedAt is the time every subscriber handled the event at. It is
+	// nil until then.
+	PublishedAt *time.Time
+}
+
+// NewEvent creates a new pending event, due for publication right away. The
+// payload is encoded as JSON.
+func NewEvent(typ EventType, aggregateID string, payload any) (*Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode event payload")
+	}
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new event")
+	}
+	return &Event{
+		ID:            id,
+		Type:          typ,
+		AggregateID:   aggregateID,
+		Payload:       data,
+		TraceID:       "",
+		SpanID:        "",
+		Status:        EventStatusPending,
+		Attempts:      0,
+		NextAttemptAt: *now,
+		LastError:     "",
+		CreatedAt:     *now,
+		PublishedAt:   nil,
+	}, nil
+}
+
+// Decode decodes the payload of the event into v, which should be the
+// payload type of its event type.
+func (e *Event) Decode(v any) error {
+	return errors.Wrap(json.Unmarshal(e.Payload, v), "failed to decode event payload")
+}
+
+// Payloads of events carry IDs rather than personal data, such as email
+// addresses, so that erasing a customer never has to reach into the outbox.
+// Subscribers look up what they need.
+
+// OrderPaid is the payload of an EventTypeOrderPaid event.
+type OrderPaid struct {
+	OrderID    string `json:"order_id"`
+	CustomerID string `json:"customer_id"`
+	GrandTotal int    `json:"grand_total"`
+	Currency   string `json:"currency"`
+}
+
+// OrderRefunded is the payload of an EventTypeOrderRefunded event.
+type OrderRefunded struct {
+	OrderID    string `json:"order_id"`
+	CustomerID string `json:"customer_id"`
+	GrandTotal int    `json:"grand_total"`
+	Currency   string `json:"currency"`
+	// RefundedBy is the ID of the customer, such as a support agent, who
+	// refunded the order.
+	RefundedBy string `json:"refunded_by"`
+}
+
+// CustomerSignedUp is the payload of an EventTypeCustomerSignedUp event.
+type CustomerSignedUp struct {
+	CustomerID string `json:"customer_id"`
+	// Method is how the customer signed up, such as "password", "oauth" or
+	// "passkey".
+	Method string `json:"method"`
+}
+
+// CustomerDeleted is the payload of an EventTypeCustomerDeleted event.
+type CustomerDeleted struct {
+	CustomerID string `json:"customer_id"`
+	// DeletedBy is the ID of the customer who deleted the account, which is
+	// the customer themselves unless support staff deleted it.
+	DeletedBy string `json:"deleted_by"`
+}
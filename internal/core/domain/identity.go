@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	IdentityProviderAuth0 = IdentityProvider{name: "auth0"}
+	//nolint:gochecknoglobals // These simulate enums.
+	IdentityProviderGitHub = IdentityProvider{name: "github"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidIdentityProvider = IdentityProvider{name: ""}
+)
+
+// IdentityProvider is a pseudo-enum that names an external identity provider
+// a customer can sign in with, such as Auth0 or GitHub.
+type IdentityProvider struct {
+	name string
+}
+
+// NewIdentityProvider returns an identity provider given its name.
+func NewIdentityProvider(name string) (IdentityProvider, error) {
+	switch name {
+	case "auth0":
+		return IdentityProviderAuth0, nil
+	case "github":
+		return IdentityProviderGitHub, nil
+	default:
+		return InvalidIdentityProvider, errors.New("invalid identity provider")
+	}
+}
+
+func (i IdentityProvider) String() string {
+	return i.name
+}
+
+// ExternalIdentity is an identity asserted by an identity provider after the
+// provider has authenticated the customer. It is only ever produced by the
+// backend itself, from a verified ID token or a provider API response, and
+// never from data sent by the client.
+type ExternalIdentity struct {
+	// Provider is the provider that asserted the identity.
+	Provider IdentityProvider
+	// Subject is the provider's stable, unique ID of the customer, such as the
+	// `sub` claim of an ID token.
+	Subject string
+	// Email is the email address the provider has on file.
+	Email string
+	// EmailVerified is whether the provider has verified the email address.
+	EmailVerified bool
+}
+
+// Identity is an external identity linked to a customer. A customer can have
+// one identity per provider, and an identity belongs to exactly one customer.
+type Identity struct {
+	// ID is the unique UUID v7 of the identity.
+	ID string
+	// UserID is the ID of the customer the identity is linked to.
+	UserID string
+	// Provider is the provider of the identity.
+	Provider IdentityProvider
+	// Subject is the provider's unique ID of the customer.
+	Subject string
+	// Email is the email address the provider had on file when the identity
+	// was last used.
+	Email string
+	// CreatedAt is the time the identity was linked at.
+	CreatedAt time.Time
+	// LastUsedAt is the time the identity was last used to sign in.
+	LastUsedAt time.Time
+}
+
+// NewIdentity links an external identity to a customer.
+func NewIdentity(userID string, external ExternalIdentity) (*Identity, error) {
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new identity")
+	}
+	return &Identity{
+		ID:         id,
+		UserID:     userID,
+		Provider:   external.Provider,
+		Subject:    external.Subject,
+		Email:      external.Email,
+		CreatedAt:  *now,
+		LastUsedAt: *now,
+	}, nil
+}
+
+// OAuthFlow is an OAuth2 authorization code flow that has been started but
+// not yet completed. It holds the secrets that bind the provider's callback to
+// the request that started the flow:
+//
+//   - State is sent to the provider and must come back unchanged, which
+//     prevents login CSRF.
+//   - CodeVerifier is the PKCE verifier. Only its S256 challenge is sent to the
+//     provider, and the verifier is presented when the code is exchanged, so
+//     an intercepted code is useless on its own.
+//   - Nonce is embedded in the ID token by OpenID Connect providers, which
+//     prevents an ID token from being replayed into another flow.
+//
+// A flow can only be completed once.
+type OAuthFlow struct {
+	// State is the random state parameter of the flow.
+	State string
+	// Provider is the provider the flow was started with.
+	Provider IdentityProvider
+	// CodeVerifier is the PKCE code verifier.
+	CodeVerifier string
+	// Nonce is the OpenID Connect nonce.
+	Nonce string
+	// LinkUserID is the ID of a signed-in customer who is linking a new
+	// identity to their account. It is empty when the flow signs a customer
+	// in or up.
+	LinkUserID string
+	// CreatedAt is the time the flow was started at.
+	CreatedAt time.Time
+	// ExpiresAt is the time after which the flow can no longer be completed.
+	ExpiresAt time.Time
+}
+
+// NewOAuthFlow starts a new OAuth2 flow with a provider. The flow must be
+// completed within ttl.
+func NewOAuthFlow(
+	provider IdentityProvider,
+	linkUserID string,
+	ttl time.Duration,
+) (*OAuthFlow, error) {
+	secrets := make([]string, 3)
+	for i := range secrets {
+		v, err := crypto.GenerateToken()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to make new oauth flow")
+		}
+		secrets[i] = v
+	}
+	now := time.Now().UTC()
+	return &OAuthFlow{
+		State:        secrets[0],
+		Provider:     provider,
+		CodeVerifier: secrets[1],
+		Nonce:        secrets[2],
+		LinkUserID:   linkUserID,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(ttl),
+	}, nil
+}
+
+// CodeChallenge returns the S256 PKCE code challenge of the flow's verifier.
+func (o *OAuthFlow) CodeChallenge() string {
+	sum := sha256.Sum256([]byte(o.CodeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Expired reports whether the flow can no longer be completed at a point in
+// time.
+func (o *OAuthFlow) Expired(at time.Time) bool {
+	return !at.Before(o.ExpiresAt)
+}
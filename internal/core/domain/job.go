@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	JobStatusPending = JobStatus{name: "pending"}
+	//nolint:gochecknoglobals // These simulate enums.
+	JobStatusSucceeded = JobStatus{name: "succeeded"}
+	//nolint:gochecknoglobals // These simulate enums.
+	JobStatusDead = JobStatus{name: "dead"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidJobStatus = JobStatus{name: ""}
+)
+
+// JobStatus is a pseudo-enum that describes where a job in the queue is in
+// its execution.
+type JobStatus struct {
+	name string
+}
+
+// NewJobStatus returns a job status given its name.
+func NewJobStatus(name string) (JobStatus, error) {
+	switch name {
+	case "pending":
+		return JobStatusPending, nil
+	case "succeeded":
+		return JobStatusSucceeded, nil
+	case "dead":
+		return JobStatusDead, nil
+	default:
+		return InvalidJobStatus, errors.New("invalid job status")
+	}
+}
+
+func (j JobStatus) String() string {
+	return j.name
+}
+
+// Job is a unit of deferred or scheduled work in the job queue, such as
+// reaping expired sessions. A job is run by the handler of its type, and
+// retried with backoff until it succeeds or is given up on. A job that was
+// given up on is dead, and stays in the queue, as a dead letter, until it is
+// retried by hand or reaped.
+type Job struct {
+	// ID is the unique UUID v7 of the job.
+	ID string
+	// Type is the name of the type of job, which chooses its handler, such
+	// as "sessions.reap".
+	Type string
+	// Key makes the job unique among the jobs with the same key, if it is
+	// not empty. A job whose key is taken is not enqueued, which makes
+	// enqueueing idempotent.
+	Key string
+	// Payload is the JSON encoded payload of the job.
+	Payload []byte
+	// Status is where the job is in its execution.
+	Status JobStatus
+	// Attempts is how many times the job was run.
+	Attempts int
+	// RunAt is the time at which the job is run next.
+	RunAt time.Time
+	// LastError is the error of the last failed run.
+	LastError string
+	// CreatedAt is the time the job was enqueued at.
+	CreatedAt time.Time
+	// FinishedAt is the time the job succeeded or was given up on at. It is
+	// nil until then.
+	FinishedAt *time.Time
+}
+
+// NewJob creates a new pending job of a type, due at runAt. The payload is
+// encoded as JSON.
+func NewJob(typ string, payload any, runAt time.Time) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode job payload")
+	}
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new job")
+	}
+	return &Job{
+		ID:         id,
+		Type:       typ,
+		Key:        "",
+		Payload:    data,
+		Status:     JobStatusPending,
+		Attempts:   0,
+		RunAt:      runAt.UTC(),
+		LastError:  "",
+		CreatedAt:  *now,
+		FinishedAt: nil,
+	}, nil
+}
+
+// ErrJobRejected is returned by a job handler when a job cannot succeed,
+// however often it is retried, such as when its payload is malformed. The
+// job is given up on right away.
+var ErrJobRejected = errors.New("job rejected")
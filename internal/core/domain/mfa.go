@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"time"
+
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/uuid"
+)
+
+// UserMFA is the TOTP authenticator of a customer. A customer has at most
+// one. It is pending from enrollment until the customer proves that their
+// authenticator app works by entering a code, and only then is it asked for
+// at sign in.
+type UserMFA struct {
+	// UserID is the ID of the customer.
+	UserID string
+	// EncryptedSecret is the TOTP secret, encrypted with the MFA encryption
+	// key and bound to the customer ID.
+	EncryptedSecret []byte
+	// LastUsedStep is the time step of the last code that was accepted.
+	// Codes of that step and earlier ones are refused, so that a code cannot
+	// be used twice.
+	LastUsedStep int64
+	// CreatedAt is when the customer enrolled.
+	CreatedAt time.Time
+	// EnabledAt is when the customer confirmed the enrollment. It is nil
+	// while the enrollment is pending.
+	EnabledAt *time.Time
+}
+
+// Enabled reports whether the authenticator is asked for at sign in.
+func (m *UserMFA) Enabled() bool {
+	return m.EnabledAt != nil
+}
+
+// RecoveryCode is a one-time code that a customer can enter instead of a TOTP
+// code, for when they lose their authenticator. Like one-time tokens, only a
+// SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	// ID is the unique UUID v7 of the code.
+	ID string
+	// UserID is the ID of the customer the code belongs to.
+	UserID string
+	// CodeHash is the SHA-256 hash of the normalized code.
+	CodeHash []byte
+	// CreatedAt is when the code was generated.
+	CreatedAt time.Time
+	// UsedAt is when the code was used, or nil if it was not.
+	UsedAt *time.Time
+}
+
+// NewRecoveryCode creates a new recovery code for a customer from a
+// normalized code.
+func NewRecoveryCode(userID, code string) (*RecoveryCode, error) {
+	id, err := uuid.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new recovery code")
+	}
+	return &RecoveryCode{
+		ID:        id,
+		UserID:    userID,
+		CodeHash:  crypto.HashToken(code),
+		CreatedAt: time.Now().UTC(),
+		UsedAt:    nil,
+	}, nil
+}
+
+// ErrMFACodeUsed is returned when a TOTP code of a time step, or of a later
+// one, was already accepted.
+var ErrMFACodeUsed = errors.New("mfa code already used")
@@ -20,6 +20,10 @@ type Customer struct {
 	// ID is the unique UUID v7 of the customer's account in a database.
 	ID string `json:"user_id"`
 	// AuthZeroUserID is the Auth0 ID of the customer.
+	//
+	// Deprecated: External identities, including Auth0, are linked to a
+	// customer through an Identity. This field is only read for accounts
+	// created before identities existed.
 	AuthZeroUserID string `json:"-"`
 	// Email is the email address of the customer.
 	Email string `json:"email"`
@@ -27,7 +31,8 @@ type Customer struct {
 	// If the customer's email is not verified, they are not able to make
 	// purchases with their account.
 	EmailVerified bool `json:"email_verified"`
-	// PasswordHash is the password hash of the customer's account.
+	// PasswordHash is the password hash of the customer's account. It is empty
+	// when the customer only signs in through an identity provider.
 	PasswordHash []byte `json:"-"`
 	// TotalPurchasesAmount is the total amount a customer has spent
 	// in purchases for their account's lifespan.
@@ -42,6 +47,10 @@ type Customer struct {
 	// DeletedAt is the time the customer's account was deleted at, which is
 	// useful and necessary for auditing purposes.
 	DeletedAt *time.Time `json:"-"`
+	// ErasedAt is the time the personal data of a deleted customer was
+	// erased at, after which the account can no longer be restored. See
+	// ErasedEmail.
+	ErasedAt *time.Time `json:"-"`
 	// LastLoginAt is the time the customer's account was last accessed at,
 	// which is useful and necessary for auditing purposes.
 	LastLoginAt time.Time `json:"last_login_at"`
@@ -64,6 +73,7 @@ func NewCustomer(email, auth0ID string, passwordHash []byte) (*Customer, error)
 		CreatedAt:            currentTime,
 		UpdatedAt:            currentTime,
 		DeletedAt:            nil,
+		ErasedAt:             nil,
 		// TODO: might need to change LastLoginAt to when the user is actually
 		// validated. Also, the user must be informed of their last login date
 		// when the enter the application.
@@ -71,6 +81,11 @@ func NewCustomer(email, auth0ID string, passwordHash []byte) (*Customer, error)
 	}, err
 }
 
+// HasPassword reports whether the customer can sign in with a password.
+func (c *Customer) HasPassword() bool {
+	return len(c.PasswordHash) > 0
+}
+
 // Order represents an entire customer order, as a result of a purchase funnel.
 type Order struct {
 	ID string `json:"-"`
@@ -84,6 +99,11 @@ type Order struct {
 	// "YYYY-MM-DD" signifies the order date, and "X..." signifies the order
 	// number.
 	OrderNumber string `json:"order_number"`
+	// BillingEmail is the email address the receipt of the order is sent to.
+	BillingEmail string `json:"billing_email"`
+	// BillingName is the name the order is billed to, if the customer gave
+	// one.
+	BillingName string `json:"billing_name"`
 	// Items are the items in the order.
 	Items []LineItem `json:"items"`
 	// GrandTotal is the total amount to be paid.
@@ -118,16 +138,18 @@ func NewOrder(currency string, items ...LineItem) (*Order, error) {
 		return total + item.Product.Price
 	}, 0)
 	return &Order{
-		ID:          id,
-		Items:       items,
-		OrderNumber: orderID,
-		CurrencyID:  currency,
-		GrandTotal:  grandTotal,
-		Status:      PendingStatus,
-		CreatedAt:   *now,
-		UpdatedAt:   *now,
-		CompletedAt: nil,
-		DeletedAt:   nil,
+		ID:           id,
+		BillingEmail: "",
+		BillingName:  "",
+		Items:        items,
+		OrderNumber:  orderID,
+		CurrencyID:   currency,
+		GrandTotal:   grandTotal,
+		Status:       PendingStatus,
+		CreatedAt:    *now,
+		UpdatedAt:    *now,
+		CompletedAt:  nil,
+		DeletedAt:    nil,
 	}, nil
 }
 
@@ -189,6 +211,8 @@ var (
 	//nolint:gochecknoglobals // These simulate enums.
 	FailedStatus = FulfillmentStatus{status: "failed"}
 	//nolint:gochecknoglobals // These simulate enums.
+	RefundingStatus = FulfillmentStatus{status: "refunding"}
+	//nolint:gochecknoglobals // These simulate enums.
 	RefundedStatus = FulfillmentStatus{status: "refunded"}
 	//nolint:gochecknoglobals // These simulate enums.
 	CancelledStatus = FulfillmentStatus{status: "cancelled"}
@@ -199,6 +223,28 @@ type FulfillmentStatus struct {
 	status string
 }
 
+// NewFulfillmentStatus returns a fulfillment status given its name.
+func NewFulfillmentStatus(name string) (FulfillmentStatus, error) {
+	switch name {
+	case "pending":
+		return PendingStatus, nil
+	case "processing":
+		return ProcessingStats, nil
+	case "completed":
+		return CompletedStatus, nil
+	case "failed":
+		return FailedStatus, nil
+	case "refunding":
+		return RefundingStatus, nil
+	case "refunded":
+		return RefundedStatus, nil
+	case "cancelled":
+		return CancelledStatus, nil
+	default:
+		return FulfillmentStatus{status: ""}, errors.New("invalid fulfillment status")
+	}
+}
+
 func (f FulfillmentStatus) String() string {
 	return f.status
 }
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"time"
+
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/webauthn"
+)
+
+// Passkey is a WebAuthn credential a customer signs in with. A customer can
+// register several, one per device or passkey provider, and a customer
+// without a password can sign in with passkeys only.
+type Passkey struct {
+	// ID is the unique UUID v7 of the passkey.
+	ID string
+	// UserID is the ID of the customer the passkey belongs to.
+	UserID string
+	// CredentialID is the credential ID the authenticator chose. It is
+	// unique across customers.
+	CredentialID []byte
+	// PublicKey is the COSE encoded public key of the credential.
+	PublicKey []byte
+	// Algorithm is the COSE algorithm of the public key.
+	Algorithm int64
+	// SignCount is the signature counter the authenticator last reported.
+	SignCount uint32
+	// BackedUp is whether the credential is synced to other devices.
+	BackedUp bool
+	// Name is the name the customer gave the passkey, such as "iPhone".
+	Name string
+	// CreatedAt is the time the passkey was registered at.
+	CreatedAt time.Time
+	// LastUsedAt is the time the passkey was last used to sign in, or nil if
+	// it never was.
+	LastUsedAt *time.Time
+}
+
+// NewPasskey creates a new passkey for a customer from a registered
+// credential.
+func NewPasskey(userID, name string, credential *webauthn.Credential) (*Passkey, error) {
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new passkey")
+	}
+	return &Passkey{
+		ID:           id,
+		UserID:       userID,
+		CredentialID: credential.ID,
+		PublicKey:    credential.PublicKey,
+		Algorithm:    credential.Algorithm,
+		SignCount:    credential.SignCount,
+		BackedUp:     credential.BackedUp,
+		Name:         name,
+		CreatedAt:    *now,
+		LastUsedAt:   nil,
+	}, nil
+}
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	PasskeyCeremonyRegistration = PasskeyCeremonyPurpose{name: "registration"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasskeyCeremonySignUp = PasskeyCeremonyPurpose{name: "sign_up"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasskeyCeremonySignIn = PasskeyCeremonyPurpose{name: "sign_in"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidPasskeyCeremonyPurpose = PasskeyCeremonyPurpose{name: ""}
+)
+
+// PasskeyCeremonyPurpose is a pseudo-enum that names what a WebAuthn
+// ceremony was started for.
+type PasskeyCeremonyPurpose struct {
+	name string
+}
+
+// NewPasskeyCeremonyPurpose returns a ceremony purpose given its name.
+func NewPasskeyCeremonyPurpose(name string) (PasskeyCeremonyPurpose, error) {
+	switch name {
+	case "registration":
+		return PasskeyCeremonyRegistration, nil
+	case "sign_up":
+		return PasskeyCeremonySignUp, nil
+	case "sign_in":
+		return PasskeyCeremonySignIn, nil
+	default:
+		return InvalidPasskeyCeremonyPurpose, errors.New("invalid passkey ceremony purpose")
+	}
+}
+
+func (p PasskeyCeremonyPurpose) String() string {
+	return p.name
+}
+
+// PasskeyCeremony is a WebAuthn ceremony that has been started but not yet
+// completed. Like an OAuthFlow, it holds the challenge the authenticator must
+// sign, which binds the response to the request that started the ceremony,
+// and it can only be completed once.
+type PasskeyCeremony struct {
+	// ID is the random ID the client completes the ceremony with.
+	ID string
+	// Purpose is what the ceremony was started for.
+	Purpose PasskeyCeremonyPurpose
+	// Challenge is the random challenge of the ceremony.
+	Challenge []byte
+	// UserID is the ID of the customer who registers a passkey. For sign up
+	// ceremonies, it is the ID the new customer will get, and it is empty
+	// for sign in ceremonies.
+	UserID string
+	// Email is the email address of the customer who signs up. It is empty
+	// for other ceremonies.
+	Email string
+	// CreatedAt is the time the ceremony was started at.
+	CreatedAt time.Time
+	// ExpiresAt is the time after which the ceremony can no longer be
+	// completed.
+	ExpiresAt time.Time
+}
+
+// NewPasskeyCeremony starts a new WebAuthn ceremony. The ceremony must be
+// completed within ttl.
+func NewPasskeyCeremony(
+	purpose PasskeyCeremonyPurpose,
+	userID, email string,
+	ttl time.Duration,
+) (*PasskeyCeremony, error) {
+	id, err := crypto.GenerateToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new passkey ceremony")
+	}
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new passkey ceremony")
+	}
+	now := time.Now().UTC()
+	return &PasskeyCeremony{
+		ID:        id,
+		Purpose:   purpose,
+		Challenge: challenge,
+		UserID:    userID,
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+// Expired reports whether the ceremony can no longer be completed at a point
+// in time.
+func (p *PasskeyCeremony) Expired(at time.Time) bool {
+	return !at.Before(p.ExpiresAt)
+}
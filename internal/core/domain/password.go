@@ -0,0 +1,72 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackTooShort = PasswordFeedback{code: "too_short"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackTooLong = PasswordFeedback{code: "too_long"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackTooGuessable = PasswordFeedback{code: "too_guessable"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackCommonPassword = PasswordFeedback{code: "common_password"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackKeyboardPattern = PasswordFeedback{code: "keyboard_pattern"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackRepeatedCharacters = PasswordFeedback{code: "repeated_characters"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackSequence = PasswordFeedback{code: "sequence"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackDate = PasswordFeedback{code: "date"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackContainsEmail = PasswordFeedback{code: "contains_email"}
+	//nolint:gochecknoglobals // These simulate enums.
+	PasswordFeedbackContainsBrand = PasswordFeedback{code: "contains_brand"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidPasswordFeedback = PasswordFeedback{code: ""}
+)
+
+// PasswordFeedback is a pseudo-enum that says why a password was rejected.
+// Its code is stable, so that the frontend can render a message for it.
+type PasswordFeedback struct {
+	code string
+}
+
+// NewPasswordFeedback returns password feedback given its code.
+func NewPasswordFeedback(code string) (PasswordFeedback, error) {
+	switch code {
+	case "too_short":
+		return PasswordFeedbackTooShort, nil
+	case "too_long":
+		return PasswordFeedbackTooLong, nil
+	case "too_guessable":
+		return PasswordFeedbackTooGuessable, nil
+	case "common_password":
+		return PasswordFeedbackCommonPassword, nil
+	case "keyboard_pattern":
+		return PasswordFeedbackKeyboardPattern, nil
+	case "repeated_characters":
+		return PasswordFeedbackRepeatedCharacters, nil
+	case "sequence":
+		return PasswordFeedbackSequence, nil
+	case "date":
+		return PasswordFeedbackDate, nil
+	case "contains_email":
+		return PasswordFeedbackContainsEmail, nil
+	case "contains_brand":
+		return PasswordFeedbackContainsBrand, nil
+	default:
+		return InvalidPasswordFeedback, errors.New("invalid password feedback")
+	}
+}
+
+func (p PasswordFeedback) String() string {
+	return p.code
+}
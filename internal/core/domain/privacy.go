@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import "time"
+
+// CustomerExportVersion is the version of the format of CustomerExport. It
+// changes whenever a field is removed or changes meaning.
+const CustomerExportVersion = 1
+
+// ErasedEmail returns the address that replaces the email address of a
+// customer whose personal data was erased, in their account and in their
+// orders. It is unique per customer, so that the account keeps satisfying
+// the constraints of the users table, and it can never receive email.
+func ErasedEmail(customerID string) string {
+	return "erased-" + customerID + "@erased.invalid"
+}
+
+// CustomerExport is a machine-readable archive of the personal data of a
+// customer, as handed to them when they exercise their right of access or
+// of data portability. It is meant to be encoded as JSON. Secrets, such as
+// password and session hashes, are left out.
+type CustomerExport struct {
+	Version    int                      `json:"version"`
+	ExportedAt time.Time                `json:"exported_at"`
+	Profile    CustomerExportProfile    `json:"profile"`
+	Sessions   []CustomerExportSession  `json:"sessions"`
+	Orders     []CustomerExportOrder    `json:"orders"`
+	Downloads  []CustomerExportDownload `json:"downloads"`
+}
+
+// CustomerExportProfile is the account of a customer in a CustomerExport.
+type CustomerExportProfile struct {
+	ID                   string     `json:"id"`
+	Email                string     `json:"email"`
+	EmailVerified        bool       `json:"email_verified"`
+	HasPassword          bool       `json:"has_password"`
+	TotalPurchasesAmount int        `json:"total_purchases_amount"`
+	TotalPurchasesCount  int        `json:"total_purchases_count"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+	LastLoginAt          time.Time  `json:"last_login_at"`
+	DeletedAt            *time.Time `json:"deleted_at,omitempty"`
+}
+
+// CustomerExportSession is a session of a customer in a CustomerExport.
+type CustomerExportSession struct {
+	ID         string    `json:"id"`
+	CreatedIP  string    `json:"created_ip"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// CustomerExportOrder is an order of a customer in a CustomerExport.
+type CustomerExportOrder struct {
+	OrderNumber  string                    `json:"order_number"`
+	BillingEmail string                    `json:"billing_email"`
+	BillingName  string                    `json:"billing_name"`
+	Items        []CustomerExportOrderItem `json:"items"`
+	GrandTotal   int                       `json:"grand_total"`
+	Currency     string                    `json:"currency"`
+	Status       string                    `json:"status"`
+	CreatedAt    time.Time                 `json:"created_at"`
+	CompletedAt  *time.Time                `json:"completed_at,omitempty"`
+}
+
+// CustomerExportOrderItem is an item of an order in a CustomerExport.
+type CustomerExportOrderItem struct {
+	ProductID   string `json:"product_id"`
+	ProductName string `json:"product_name"`
+	Price       int    `json:"price"`
+	Quantity    int    `json:"quantity"`
+	Status      string `json:"status"`
+}
+
+// CustomerExportDownload is a download of a customer in a CustomerExport.
+type CustomerExportDownload struct {
+	ProductID        string     `json:"product_id"`
+	OrderID          string     `json:"order_id"`
+	DownloadCount    int        `json:"download_count"`
+	LastDownloadedAt *time.Time `json:"last_downloaded_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// NewCustomerExport assembles the export of a customer's data.
+func NewCustomerExport(
+	customer *Customer,
+	sessions []*UserSession,
+	orders []*Order,
+	downloads []*Download,
+) *CustomerExport {
+	export := &CustomerExport{
+		Version:    CustomerExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Profile: CustomerExportProfile{
+			ID:                   customer.ID,
+			Email:                customer.Email,
+			EmailVerified:        customer.EmailVerified,
+			HasPassword:          customer.HasPassword(),
+			TotalPurchasesAmount: customer.TotalPurchasesAmount,
+			TotalPurchasesCount:  customer.TotalPurchasesCount,
+			CreatedAt:            customer.CreatedAt,
+			UpdatedAt:            customer.UpdatedAt,
+			LastLoginAt:          customer.LastLoginAt,
+			DeletedAt:            customer.DeletedAt,
+		},
+		Sessions:  make([]CustomerExportSession, len(sessions)),
+		Orders:    make([]CustomerExportOrder, len(orders)),
+		Downloads: make([]CustomerExportDownload, len(downloads)),
+	}
+	for i, s := range sessions {
+		export.Sessions[i] = CustomerExportSession{
+			ID:         s.ID,
+			CreatedIP:  s.CreatedIP,
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			ExpiresAt:  s.ExpiresAt,
+		}
+	}
+	for i, o := range orders {
+		items := make([]CustomerExportOrderItem, len(o.Items))
+		for j, item := range o.Items {
+			items[j] = CustomerExportOrderItem{
+				ProductID:   item.Product.ID,
+				ProductName: item.Product.Name,
+				Price:       item.Product.Price,
+				Quantity:    item.Quantity,
+				Status:      item.Status.String(),
+			}
+		}
+		export.Orders[i] = CustomerExportOrder{
+			OrderNumber:  o.OrderNumber,
+			BillingEmail: o.BillingEmail,
+			BillingName:  o.BillingName,
+			Items:        items,
+			GrandTotal:   o.GrandTotal,
+			Currency:     o.CurrencyID,
+			Status:       o.Status.String(),
+			CreatedAt:    o.CreatedAt,
+			CompletedAt:  o.CompletedAt,
+		}
+	}
+	for i, d := range downloads {
+		export.Downloads[i] = CustomerExportDownload{
+			ProductID:        d.ProductID,
+			OrderID:          d.OrderID,
+			DownloadCount:    d.DownloadCount,
+			LastDownloadedAt: d.LastDownloadedAt,
+			CreatedAt:        d.CreatedAt,
+		}
+	}
+	return export
+}
@@ -4,22 +4,187 @@
 
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
 
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/uuid"
+)
+
+// ClientInfo describes the client that makes a request, as seen by the
+// server.
+type ClientInfo struct {
+	// IP is the IP address of the client.
+	IP string
+	// UserAgent is the User-Agent header sent by the client.
+	UserAgent string
+}
+
+// UserSession is a customer's login session.
+//
+// A session token takes the form of `<id>.<secret>`. The ID is used to look
+// the session up, while only a hash of the secret is stored. This way, a leak
+// of the session store does not leak usable tokens, and the secret can be
+// compared in constant time.
 type UserSession struct {
-	Token     string
-	UserID    string
+	// ID is the unique UUID v7 of the session.
+	ID string
+	// Token is the session token handed to the client. It is only populated
+	// when the session is issued or renewed, and is never stored.
+	Token string
+	// SecretHash is the SHA-256 hash of the secret part of the token.
+	SecretHash []byte
+	// PreviousSecretHash is the hash of the secret the token had before it
+	// was last rotated. It is accepted for a short grace period after
+	// rotation, so that requests already in flight with the old token do not
+	// sign the customer out.
+	PreviousSecretHash []byte
+	// RotatedAt is the time the token was last rotated at.
+	RotatedAt *time.Time
+	// UserID is the ID of the customer the session belongs to.
+	UserID string
+	// CreatedIP is the IP address of the client that created the session.
+	CreatedIP string
+	// UserAgent is the user agent of the client that created the session.
+	UserAgent string
+	// CreatedAt is the time the session was created at.
 	CreatedAt time.Time
+	// LastSeenAt is the time the session was last used at.
+	LastSeenAt time.Time
+	// ExpiresAt is the time the session expires at if it is left idle. It is
+	// pushed back as the session is used, up to AbsoluteExpiresAt.
 	ExpiresAt time.Time
+	// AbsoluteExpiresAt is the time after which the session can no longer be
+	// renewed, no matter how active it is. The customer must sign in again.
+	AbsoluteExpiresAt time.Time
+	// AuthenticatedAt is the time the customer last proved their credentials
+	// in this session, either by signing in or by re-authenticating.
+	AuthenticatedAt time.Time
 }
 
-func NewUserSession(userID string, validFor time.Duration) *UserSession {
+// NewUserSession creates a new session for a user. The session expires after
+// being idle for idleTimeout, and can be renewed until absoluteLifetime has
+// passed. The session is issued a random token that the caller hands to the
+// client.
+func NewUserSession(
+	userID string,
+	client ClientInfo,
+	idleTimeout, absoluteLifetime time.Duration,
+) (*UserSession, error) {
+	id, err := uuid.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new user session")
+	}
 	now := time.Now().UTC()
-	d := now.Add(validFor)
-	return &UserSession{
-		Token:     "",
-		UserID:    userID,
-		CreatedAt: now,
-		ExpiresAt: d,
+	session := &UserSession{
+		ID:                 id,
+		Token:              "",
+		SecretHash:         nil,
+		PreviousSecretHash: nil,
+		RotatedAt:          nil,
+		UserID:             userID,
+		CreatedIP:          client.IP,
+		UserAgent:          client.UserAgent,
+		CreatedAt:          now,
+		LastSeenAt:         now,
+		ExpiresAt:          now.Add(idleTimeout),
+		AbsoluteExpiresAt:  now.Add(absoluteLifetime),
+		AuthenticatedAt:    now,
+	}
+	if session.ExpiresAt.After(session.AbsoluteExpiresAt) {
+		session.ExpiresAt = session.AbsoluteExpiresAt
+	}
+	err = session.newSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new user session")
+	}
+	return session, nil
+}
+
+// Renew extends the idle expiry of the session, without going past its
+// absolute expiry, and rotates its token. The new token is set on the
+// session and must be handed to the client.
+func (s *UserSession) Renew(now time.Time, idleTimeout time.Duration) error {
+	previous := s.SecretHash
+	err := s.newSecret()
+	if err != nil {
+		return errors.Wrap(err, "failed to renew user session")
+	}
+	s.PreviousSecretHash = previous
+	s.RotatedAt = &now
+	s.ExpiresAt = now.Add(idleTimeout)
+	if s.ExpiresAt.After(s.AbsoluteExpiresAt) {
+		s.ExpiresAt = s.AbsoluteExpiresAt
+	}
+	return nil
+}
+
+func (s *UserSession) newSecret() error {
+	secret, err := crypto.GenerateToken()
+	if err != nil {
+		return err
+	}
+	s.Token = s.ID + sessionTokenSeparator + secret
+	s.SecretHash = crypto.HashToken(secret)
+	return nil
+}
+
+const sessionTokenSeparator = "."
+
+// ParseSessionToken splits a session token into its ID and secret.
+func ParseSessionToken(token string) (string, string, error) {
+	id, secret, ok := strings.Cut(token, sessionTokenSeparator)
+	if !ok || id == "" || secret == "" {
+		return "", "", ErrMalformedSessionToken
+	}
+	return id, secret, nil
+}
+
+// VerifySecret checks, in constant time, whether a secret belongs to the
+// session.
+func (s *UserSession) VerifySecret(secret string) bool {
+	return crypto.CompareTokenHash(secret, s.SecretHash)
+}
+
+// VerifyPreviousSecret checks, in constant time, whether a secret is the one
+// the session had before its last rotation, and whether the rotation
+// happened within a grace period.
+func (s *UserSession) VerifyPreviousSecret(
+	secret string,
+	now time.Time,
+	grace time.Duration,
+) bool {
+	if s.RotatedAt == nil || len(s.PreviousSecretHash) == 0 {
+		return false
+	}
+	if now.Sub(*s.RotatedAt) > grace {
+		return false
 	}
+	return crypto.CompareTokenHash(secret, s.PreviousSecretHash)
 }
+
+// Expired reports whether the session is expired at a point in time, either
+// because it was left idle or because its absolute lifetime is over.
+func (s *UserSession) Expired(at time.Time) bool {
+	return !at.Before(s.ExpiresAt) || !at.Before(s.AbsoluteExpiresAt)
+}
+
+// NeedsRenewal reports whether more than half of the idle timeout has passed
+// since the session was last renewed. Renewing only past half-life keeps the
+// number of token rotations low for active sessions.
+func (s *UserSession) NeedsRenewal(at time.Time, idleTimeout time.Duration) bool {
+	if !s.ExpiresAt.Before(s.AbsoluteExpiresAt) {
+		return false
+	}
+	return at.After(s.ExpiresAt.Add(-idleTimeout / 2))
+}
+
+// AuthenticatedWithin reports whether the customer proved their credentials
+// within a window before a point in time.
+func (s *UserSession) AuthenticatedWithin(at time.Time, window time.Duration) bool {
+	return at.Sub(s.AuthenticatedAt) <= window
+}
+
+var ErrMalformedSessionToken = errors.New("malformed session token")
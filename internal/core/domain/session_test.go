@@ -0,0 +1,96 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+func TestNewUserSession(t *testing.T) {
+	client := domain.ClientInfo{IP: "203.0.113.7", UserAgent: "test-agent"}
+
+	session, err := domain.NewUserSession("customer", client, time.Hour, 2*time.Hour)
+	assert.NoError(t, err)
+
+	id, secret, err := domain.ParseSessionToken(session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, id, session.ID)
+	assert.True(t, session.VerifySecret(secret))
+	assert.False(t, session.VerifySecret(secret+"x"))
+	assert.Equal(t, session.CreatedIP, client.IP)
+	assert.Equal(t, session.UserAgent, client.UserAgent)
+	assert.False(t, session.Expired(time.Now()))
+	assert.True(t, session.Expired(time.Now().Add(time.Hour)))
+
+	other, err := domain.NewUserSession("customer", client, time.Hour, 2*time.Hour)
+	assert.NoError(t, err)
+	assert.NotEqual(t, other.Token, session.Token)
+}
+
+func TestUserSession_Renew(t *testing.T) {
+	session, err := domain.NewUserSession("customer", domain.ClientInfo{}, time.Hour, 90*time.Minute)
+	assert.NoError(t, err)
+	_, oldSecret, err := domain.ParseSessionToken(session.Token)
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.False(t, session.NeedsRenewal(now, time.Hour))
+	assert.True(t, session.NeedsRenewal(now.Add(31*time.Minute), time.Hour))
+
+	err = session.Renew(now.Add(31*time.Minute), time.Hour)
+	assert.NoError(t, err)
+	_, newSecret, err := domain.ParseSessionToken(session.Token)
+	assert.NoError(t, err)
+	assert.True(t, session.VerifySecret(newSecret))
+	assert.False(t, session.VerifySecret(oldSecret))
+	assert.True(t, session.VerifyPreviousSecret(oldSecret, now.Add(32*time.Minute), time.Minute))
+	assert.False(t, session.VerifyPreviousSecret(oldSecret, now.Add(33*time.Minute), time.Minute))
+
+	// Renewal never extends past the absolute expiry.
+	assert.Equal(t, session.ExpiresAt, session.AbsoluteExpiresAt)
+	assert.False(t, session.NeedsRenewal(now.Add(80*time.Minute), time.Hour))
+}
+
+func TestParseSessionToken(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		token string
+	}{
+		{
+			CaseBase: test.NewCaseBase("valid token", nil, false),
+			token:    "id.secret",
+		},
+		{
+			CaseBase: test.NewCaseBase("missing separator", nil, true),
+			token:    "idsecret",
+		},
+		{
+			CaseBase: test.NewCaseBase("missing id", nil, true),
+			token:    ".secret",
+		},
+		{
+			CaseBase: test.NewCaseBase("missing secret", nil, true),
+			token:    "id.",
+		},
+		{
+			CaseBase: test.NewCaseBase("empty token", nil, true),
+			token:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				_, _, err := domain.ParseSessionToken(tt.token)
+				assert.ErrorOrNoError(t, err, tt.WantErr)
+			},
+		)
+	}
+}
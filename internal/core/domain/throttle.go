@@ -0,0 +1,47 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// LoginThrottle counts the recent failed sign in attempts made with a key,
+// such as an email address or an IP address, so that password guessing can
+// be slowed down and locked out.
+type LoginThrottle struct {
+	// Key identifies what the attempts were made with, such as
+	// "account:kai@brokedaear.com" or "ip:198.51.100.4".
+	Key string
+	// Failures is the number of failed attempts since the counter was last
+	// reset.
+	Failures int
+	// LastFailureAt is when the last attempt failed.
+	LastFailureAt time.Time
+	// LockedUntil is when a lockout of the key ends. It is the zero time if
+	// the key was never locked.
+	LockedUntil time.Time
+	// ExpiresAt is when the throttle may be forgotten, because neither its
+	// failures nor its lockout count any longer.
+	ExpiresAt time.Time
+}
+
+// AccountThrottleKey returns the throttle key of the account with an email
+// address. Accounts are keyed by email rather than by customer ID, so that
+// attempts on unknown addresses are throttled like attempts on known ones.
+func AccountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPThrottleKey returns the throttle key of an IP address.
+func IPThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Locked reports whether the key is locked out at a point in time.
+func (l *LoginThrottle) Locked(at time.Time) bool {
+	return at.Before(l.LockedUntil)
+}
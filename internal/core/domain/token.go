@@ -0,0 +1,147 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"strings"
+	"time"
+
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+)
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	TokenPurposeEmailVerification = TokenPurpose{name: "email_verification"}
+	//nolint:gochecknoglobals // These simulate enums.
+	TokenPurposePasswordReset = TokenPurpose{name: "password_reset"}
+	//nolint:gochecknoglobals // These simulate enums.
+	TokenPurposeAccountUnlock = TokenPurpose{name: "account_unlock"}
+	//nolint:gochecknoglobals // These simulate enums.
+	TokenPurposeMFAChallenge = TokenPurpose{name: "mfa_challenge"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidTokenPurpose = TokenPurpose{name: ""}
+)
+
+// TokenPurpose is a pseudo-enum that names what a one-time token may be used
+// for. A token issued for one purpose is never accepted for another.
+type TokenPurpose struct {
+	name string
+}
+
+// NewTokenPurpose returns a token purpose given its name.
+func NewTokenPurpose(name string) (TokenPurpose, error) {
+	switch name {
+	case "email_verification":
+		return TokenPurposeEmailVerification, nil
+	case "password_reset":
+		return TokenPurposePasswordReset, nil
+	case "account_unlock":
+		return TokenPurposeAccountUnlock, nil
+	case "mfa_challenge":
+		return TokenPurposeMFAChallenge, nil
+	default:
+		return InvalidTokenPurpose, errors.New("invalid token purpose")
+	}
+}
+
+func (t TokenPurpose) String() string {
+	return t.name
+}
+
+// OneTimeToken is a secret that is sent to a customer, usually by email, and
+// proves that the customer received it. It can be used once, before it
+// expires.
+//
+// Like session tokens, a one-time token takes the form of `<id>.<secret>`,
+// and only a hash of the secret is stored.
+type OneTimeToken struct {
+	// ID is the unique UUID v7 of the token.
+	ID string
+	// Token is the token handed to the customer. It is only populated when the
+	// token is issued, and is never stored.
+	Token string
+	// SecretHash is the SHA-256 hash of the secret part of the token.
+	SecretHash []byte
+	// UserID is the ID of the customer the token was issued to.
+	UserID string
+	// Purpose is what the token may be used for.
+	Purpose TokenPurpose
+	// Email is the email address the token was sent to.
+	Email string
+	// CreatedAt is the time the token was issued at.
+	CreatedAt time.Time
+	// ExpiresAt is the time after which the token is no longer accepted.
+	ExpiresAt time.Time
+	// UsedAt is the time the token was used at. It is nil while the token is
+	// unused.
+	UsedAt *time.Time
+}
+
+// NewOneTimeToken issues a new token to a customer for a purpose. The token
+// is sent to email and must be used within ttl.
+func NewOneTimeToken(
+	userID string,
+	purpose TokenPurpose,
+	email string,
+	ttl time.Duration,
+) (*OneTimeToken, error) {
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new one-time token")
+	}
+	secret, err := crypto.GenerateToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new one-time token")
+	}
+	return &OneTimeToken{
+		ID:         id,
+		Token:      id + tokenSeparator + secret,
+		SecretHash: crypto.HashToken(secret),
+		UserID:     userID,
+		Purpose:    purpose,
+		Email:      email,
+		CreatedAt:  *now,
+		ExpiresAt:  now.Add(ttl),
+		UsedAt:     nil,
+	}, nil
+}
+
+const tokenSeparator = "."
+
+// ParseOneTimeToken splits a one-time token into its ID and secret.
+func ParseOneTimeToken(token string) (string, string, error) {
+	id, secret, ok := strings.Cut(token, tokenSeparator)
+	if !ok || id == "" || secret == "" {
+		return "", "", ErrInvalidToken
+	}
+	return id, secret, nil
+}
+
+// Verify checks whether a secret belongs to the token, and whether the token
+// may be used for a purpose at a point in time. The secret is compared in
+// constant time.
+func (t *OneTimeToken) Verify(secret string, purpose TokenPurpose, at time.Time) error {
+	switch {
+	case t.Purpose != purpose || !crypto.CompareTokenHash(secret, t.SecretHash):
+		return ErrInvalidToken
+	case t.UsedAt != nil:
+		return ErrTokenUsed
+	case t.Expired(at):
+		return ErrTokenExpired
+	}
+	return nil
+}
+
+// Expired reports whether the token is expired at a point in time.
+func (t *OneTimeToken) Expired(at time.Time) bool {
+	return !at.Before(t.ExpiresAt)
+}
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenUsed    = errors.New("token already used")
+	ErrTokenExpired = errors.New("token expired")
+)
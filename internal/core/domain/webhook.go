@@ -0,0 +1,414 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// WebhookEndpoint is a URL registered by an admin to hear about domain
+// events, such as a chat bot announcing sales. Every event of a type the
+// endpoint subscribes to is delivered to it as a signed HTTP POST request.
+// An endpoint that keeps failing is disabled, and hears of nothing until an
+// admin enables it again.
+type WebhookEndpoint struct {
+	// ID is the unique UUID v7 of the endpoint.
+	ID string
+	// URL is the HTTPS URL deliveries are posted to.
+	URL string
+	// Description tells admins what the endpoint is for.
+	Description string
+	// EventTypes are the types of events delivered to the endpoint.
+	EventTypes []EventType
+	// EncryptedSecret is the secret deliveries are signed with, encrypted
+	// at rest. The secret itself is only shown when it is generated.
+	EncryptedSecret []byte
+	// Enabled reports whether events are delivered to the endpoint.
+	Enabled bool
+	// ConsecutiveFailures is how many delivery attempts in a row failed
+	// since the last successful one.
+	ConsecutiveFailures int
+	// FailingSince is the time of the first of the consecutive failures. It
+	// is nil while the endpoint is healthy.
+	FailingSince *time.Time
+	// DisabledAt is the time the endpoint was disabled at, if it is.
+	DisabledAt *time.Time
+	// DisabledReason tells why the endpoint was disabled, such as after
+	// sustained failures.
+	DisabledReason string
+	// CreatedAt is the time the endpoint was registered at.
+	CreatedAt time.Time
+	// UpdatedAt is the time the endpoint was last changed at.
+	UpdatedAt time.Time
+}
+
+// NewWebhookEndpoint creates a new enabled endpoint for a URL, subscribed to
+// some types of events. The secret must be set before it is stored.
+func NewWebhookEndpoint(rawURL, description string, eventTypes []EventType) (*WebhookEndpoint, error) {
+	err := ValidateWebhookURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	eventTypes, err = validateWebhookEventTypes(eventTypes)
+	if err != nil {
+		return nil, err
+	}
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new webhook endpoint")
+	}
+	return &WebhookEndpoint{
+		ID:                  id,
+		URL:                 rawURL,
+		Description:         description,
+		EventTypes:          eventTypes,
+		EncryptedSecret:     nil,
+		Enabled:             true,
+		ConsecutiveFailures: 0,
+		FailingSince:        nil,
+		DisabledAt:          nil,
+		DisabledReason:      "",
+		CreatedAt:           *now,
+		UpdatedAt:           *now,
+	}, nil
+}
+
+// Update changes what the endpoint is, and which types of events it
+// subscribes to.
+func (e *WebhookEndpoint) Update(rawURL, description string, eventTypes []EventType) error {
+	err := ValidateWebhookURL(rawURL)
+	if err != nil {
+		return err
+	}
+	eventTypes, err = validateWebhookEventTypes(eventTypes)
+	if err != nil {
+		return err
+	}
+	e.URL = rawURL
+	e.Description = description
+	e.EventTypes = eventTypes
+	e.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// Enable makes the endpoint hear of events again, and forgets its failures.
+func (e *WebhookEndpoint) Enable() {
+	e.Enabled = true
+	e.ConsecutiveFailures = 0
+	e.FailingSince = nil
+	e.DisabledAt = nil
+	e.DisabledReason = ""
+	e.UpdatedAt = time.Now().UTC()
+}
+
+// Disable stops delivering events to the endpoint.
+func (e *WebhookEndpoint) Disable(reason string, at time.Time) {
+	e.Enabled = false
+	e.DisabledAt = &at
+	e.DisabledReason = reason
+	e.UpdatedAt = at
+}
+
+// Subscribes reports whether events of a type are delivered to the endpoint.
+func (e *WebhookEndpoint) Subscribes(typ EventType) bool {
+	return slices.Contains(e.EventTypes, typ)
+}
+
+// ValidateWebhookURL checks that a URL can receive deliveries. Deliveries
+// carry order data, so the URL must use HTTPS. Deliveries are sent from
+// inside our network, so the URL must not name a host on it: localhost and
+// addresses that are not public are rejected, see IsPublicWebhookAddr. A
+// host name can still resolve to such an address, so the dispatcher checks
+// the address again when it connects.
+func ValidateWebhookURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(ErrInvalidWebhookURL, err.Error())
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return errors.Wrap(ErrInvalidWebhookURL, "must be an absolute https URL")
+	}
+	if u.User != nil {
+		return errors.Wrap(ErrInvalidWebhookURL, "must not carry credentials")
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errors.Wrap(ErrInvalidWebhookURL, "must not be local")
+	}
+	addr, err := netip.ParseAddr(host)
+	if err == nil && !IsPublicWebhookAddr(addr) {
+		return errors.Wrap(ErrInvalidWebhookURL, "must not be a private address")
+	}
+	return nil
+}
+
+// IsPublicWebhookAddr reports whether deliveries may be sent to an address.
+// Loopback, private, link-local, multicast and unspecified addresses are
+// not public, including when mapped into IPv6.
+func IsPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified()
+}
+
+// validateWebhookEventTypes checks that an endpoint subscribes to some valid
+// types of events, and removes duplicates.
+func validateWebhookEventTypes(eventTypes []EventType) ([]EventType, error) {
+	unique := make([]EventType, 0, len(eventTypes))
+	for _, typ := range eventTypes {
+		if typ == InvalidEventType {
+			return nil, errors.Wrap(ErrInvalidWebhookEventTypes, "invalid event type")
+		}
+		if !slices.Contains(unique, typ) {
+			unique = append(unique, typ)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, errors.Wrap(ErrInvalidWebhookEventTypes, "no event types")
+	}
+	return unique, nil
+}
+
+var (
+	//nolint:gochecknoglobals // These simulate enums.
+	WebhookDeliveryStatusPending = WebhookDeliveryStatus{name: "pending"}
+	//nolint:gochecknoglobals // These simulate enums.
+	WebhookDeliveryStatusDelivered = WebhookDeliveryStatus{name: "delivered"}
+	//nolint:gochecknoglobals // These simulate enums.
+	WebhookDeliveryStatusDead = WebhookDeliveryStatus{name: "dead"}
+	//nolint:gochecknoglobals // These simulate enums.
+	InvalidWebhookDeliveryStatus = WebhookDeliveryStatus{name: ""}
+)
+
+// WebhookDeliveryStatus is a pseudo-enum that describes where a delivery is
+// in its delivery.
+type WebhookDeliveryStatus struct {
+	name string
+}
+
+// NewWebhookDeliveryStatus returns a delivery status given its name.
+func NewWebhookDeliveryStatus(name string) (WebhookDeliveryStatus, error) {
+	switch name {
+	case "pending":
+		return WebhookDeliveryStatusPending, nil
+	case "delivered":
+		return WebhookDeliveryStatusDelivered, nil
+	case "dead":
+		return WebhookDeliveryStatusDead, nil
+	default:
+		return InvalidWebhookDeliveryStatus, errors.New("invalid webhook delivery status")
+	}
+}
+
+func (w WebhookDeliveryStatus) String() string {
+	return w.name
+}
+
+// WebhookDelivery is an event waiting to be delivered to an endpoint, or
+// that was. A failed delivery is retried with backoff until it succeeds or
+// is given up on. An event is delivered to an endpoint once at most, unless
+// an admin redelivers it.
+type WebhookDelivery struct {
+	// ID is the unique UUID v7 of the delivery. Endpoints receive it, so
+	// that they can tell a retried delivery apart from a new one.
+	ID string
+	// EndpointID is the ID of the endpoint the event is delivered to.
+	EndpointID string
+	// EventID is the ID of the delivered event.
+	EventID string
+	// EventType is the type of the delivered event.
+	EventType EventType
+	// Payload is the JSON encoded body of the request, a WebhookPayload.
+	Payload []byte
+	// Status is where the delivery is in its delivery.
+	Status WebhookDeliveryStatus
+	// Attempts is how many times delivery was attempted.
+	Attempts int
+	// NextAttemptAt is the time at which delivery is attempted next.
+	NextAttemptAt time.Time
+	// LastError is the error of the last failed attempt.
+	LastError string
+	// CreatedAt is the time the event was queued for the endpoint at.
+	CreatedAt time.Time
+	// DeliveredAt is the time the endpoint accepted the event at. It is nil
+	// until then.
+	DeliveredAt *time.Time
+}
+
+// WebhookPayload is the body of every delivery.
+type WebhookPayload struct {
+	// ID is the ID of the event, the same for every endpoint.
+	ID string `json:"id"`
+	// Type is the type of the event, such as "order.paid".
+	Type string `json:"type"`
+	// CreatedAt is when the event happened.
+	CreatedAt time.Time `json:"created_at"`
+	// Data is the payload of the event, such as an OrderPaid.
+	Data json.RawMessage `json:"data"`
+}
+
+// NewWebhookDelivery creates a new pending delivery of an event to an
+// endpoint, due right away.
+func NewWebhookDelivery(endpoint *WebhookEndpoint, event *Event) (*WebhookDelivery, error) {
+	payload, err := json.Marshal(WebhookPayload{
+		ID:        event.ID,
+		Type:      event.Type.String(),
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encode webhook payload")
+	}
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new webhook delivery")
+	}
+	return &WebhookDelivery{
+		ID:            id,
+		EndpointID:    endpoint.ID,
+		EventID:       event.ID,
+		EventType:     event.Type,
+		Payload:       payload,
+		Status:        WebhookDeliveryStatusPending,
+		Attempts:      0,
+		NextAttemptAt: *now,
+		LastError:     "",
+		CreatedAt:     *now,
+		DeliveredAt:   nil,
+	}, nil
+}
+
+// WebhookAttempt is the log of one attempt at a delivery, kept so that
+// admins can see what an endpoint was sent and what it answered.
+type WebhookAttempt struct {
+	// ID is the unique UUID v7 of the attempt.
+	ID string
+	// DeliveryID is the ID of the attempted delivery.
+	DeliveryID string
+	// Number is which attempt of the delivery it was, starting at 1.
+	Number int
+	// RequestHeaders are the headers the request was sent with, signature
+	// included. The body is the payload of the delivery.
+	RequestHeaders map[string]string
+	// ResponseStatus is the HTTP status code of the response, or 0 if there
+	// was none, such as after a timeout.
+	ResponseStatus int
+	// ResponseBody is the start of the body of the response.
+	ResponseBody string
+	// Error tells why the attempt failed, if it did.
+	Error string
+	// Duration is how long the attempt took.
+	Duration time.Duration
+	// AttemptedAt is the time the request was sent at.
+	AttemptedAt time.Time
+}
+
+// NewWebhookAttempt creates the log of an attempt at a delivery, made now.
+// Its number is assigned once it is stored.
+func NewWebhookAttempt(delivery *WebhookDelivery) (*WebhookAttempt, error) {
+	now, id, err := newTimeWithID()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make new webhook attempt")
+	}
+	return &WebhookAttempt{
+		ID:             id,
+		DeliveryID:     delivery.ID,
+		Number:         0,
+		RequestHeaders: map[string]string{},
+		ResponseStatus: 0,
+		ResponseBody:   "",
+		Error:          "",
+		Duration:       0,
+		AttemptedAt:    *now,
+	}, nil
+}
+
+// Succeeded reports whether the endpoint accepted the delivery.
+func (a *WebhookAttempt) Succeeded() bool {
+	return a.Error == ""
+}
+
+const (
+	// WebhookHeaderID carries the ID of the delivery.
+	WebhookHeaderID = "Webhook-Id"
+	// WebhookHeaderEvent carries the type of the event.
+	WebhookHeaderEvent = "Webhook-Event"
+	// WebhookHeaderSignature carries the signature of the delivery, see
+	// SignWebhook.
+	WebhookHeaderSignature = "Webhook-Signature"
+)
+
+// SignWebhook signs the body of a delivery sent at a point in time, and
+// returns the value of the signature header, such as "t=1700000000,v1=5f3c...".
+// The signature is the hex encoded HMAC-SHA256, keyed with the secret of the
+// endpoint, of the Unix timestamp, a dot, and the body. Signing the
+// timestamp lets endpoints reject replayed deliveries.
+func SignWebhook(secret string, at time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + webhookMAC(secret, timestamp, body)
+}
+
+// VerifyWebhookSignature checks the signature header of a delivery received
+// at a point in time, as an endpoint would. Signatures older than tolerance
+// are rejected, so that a captured delivery cannot be replayed later.
+func VerifyWebhookSignature(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for part := range strings.SplitSeq(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.Wrap(ErrInvalidWebhookSignature, "missing timestamp")
+	}
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return errors.Wrap(ErrInvalidWebhookSignature, "timestamp out of tolerance")
+	}
+	want := webhookMAC(secret, timestamp, body)
+	for _, s := range signatures {
+		if hmac.Equal([]byte(s), []byte(want)) {
+			return nil
+		}
+	}
+	return ErrInvalidWebhookSignature
+}
+
+func webhookMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+var (
+	ErrInvalidWebhookURL        = errors.New("invalid webhook url")
+	ErrInvalidWebhookEventTypes = errors.New("invalid webhook event types")
+	ErrInvalidWebhookSignature  = errors.New("invalid webhook signature")
+)
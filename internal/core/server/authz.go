@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator resolves the principal behind a session token. Invalid and
+// expired sessions return an error wrapping domain.ErrUnauthenticated. When
+// the session was renewed, the Token of the principal's session is set and
+// is handed back to the client in the SessionTokenHeader header.
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*domain.Principal, error)
+}
+
+// AccessRule is what a gRPC method or an HTTP route requires of its caller.
+type AccessRule struct {
+	// Public lets anyone call, with or without a session.
+	Public bool
+	// Permission is a permission the principal must hold. It is only a
+	// coarse check: services still decide whether the principal may act on
+	// a given resource. domain.InvalidPermission only requires a valid
+	// session.
+	Permission domain.Permission
+}
+
+// AccessRules maps gRPC methods and HTTP route patterns to their rule. gRPC
+// rules are keyed by full method, such as "/shop.v1.Orders/Refund", or by
+// service, such as "/shop.v1.Orders/", which applies to every method of the
+// service without a rule of its own. Calls without a rule are rejected.
+type AccessRules map[string]AccessRule
+
+// SessionTokenHeader is the header a renewed session token is sent to the
+// client in. The client must use it in place of its current token.
+const SessionTokenHeader = "x-session-token"
+
+// publicServices are served without a session by every gRPC server.
+//
+//nolint:gochecknoglobals // makes more sense like this.
+var publicServices = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+// rule finds the rule of a gRPC method.
+func (r AccessRules) rule(fullMethod string) (AccessRule, bool) {
+	for _, s := range publicServices {
+		if strings.HasPrefix(fullMethod, s) {
+			return AccessRule{Public: true, Permission: domain.InvalidPermission}, true
+		}
+	}
+	rule, ok := r[fullMethod]
+	if ok {
+		return rule, true
+	}
+	i := strings.LastIndex(fullMethod, "/")
+	if i < 0 {
+		return AccessRule{}, false
+	}
+	rule, ok = r[fullMethod[:i+1]]
+	return rule, ok
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of a context that carries a
+// principal.
+func ContextWithPrincipal(ctx context.Context, principal *domain.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal the authorization middleware
+// resolved for a request. It returns false for public calls without a
+// session.
+func PrincipalFromContext(ctx context.Context) (*domain.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*domain.Principal)
+	return principal, ok && principal != nil
+}
+
+// authorize applies a rule to a request that carries an Authorization
+// header value. It returns the principal of the request, which is nil for
+// public calls without a session.
+func authorize(
+	ctx context.Context,
+	auth Authenticator,
+	rule AccessRule,
+	authorization string,
+) (*domain.Principal, error) {
+	token, ok := bearerToken(authorization)
+	if !ok {
+		if rule.Public {
+			return nil, nil
+		}
+		return nil, domain.ErrUnauthenticated
+	}
+	principal, err := auth.Authenticate(ctx, token)
+	if err != nil {
+		if rule.Public && errors.Is(err, domain.ErrUnauthenticated) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !rule.Public && rule.Permission != domain.InvalidPermission && !principal.Holds(rule.Permission) {
+		return nil, domain.ErrForbidden
+	}
+	return principal, nil
+}
+
+// bearerToken extracts the token of an Authorization header value of the
+// Bearer scheme.
+func bearerToken(authorization string) (string, bool) {
+	const scheme = "bearer "
+	if len(authorization) <= len(scheme) || !strings.EqualFold(authorization[:len(scheme)], scheme) {
+		return "", false
+	}
+	token := strings.TrimSpace(authorization[len(scheme):])
+	return token, token != ""
+}
+
+// renewedToken returns the token a principal's session was renewed with,
+// if any.
+func renewedToken(principal *domain.Principal) string {
+	if principal == nil || principal.Session == nil {
+		return ""
+	}
+	return principal.Session.Token
+}
+
+// grpcStatus converts an authorization error to a gRPC status error.
+func grpcStatus(logger Logger, fullMethod string, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrUnauthenticated):
+		return status.Error(codes.Unauthenticated, "unauthenticated")
+	case errors.Is(err, domain.ErrForbidden):
+		return status.Error(codes.PermissionDenied, "permission denied")
+	default:
+		logger.Error("failed to authorize call", "method", fullMethod, "error", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// authorizeGRPC applies the rule of a gRPC method to a call and returns the
+// context the handler is called with.
+func authorizeGRPC(
+	ctx context.Context,
+	logger Logger,
+	auth Authenticator,
+	rules AccessRules,
+	fullMethod string,
+) (context.Context, error) {
+	rule, ok := rules.rule(fullMethod)
+	if !ok {
+		logger.Warn("rejected call without an access rule", "method", fullMethod)
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+	authorization := ""
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		values := md.Get("authorization")
+		if len(values) > 0 {
+			authorization = values[0]
+		}
+	}
+	principal, err := authorize(ctx, auth, rule, authorization)
+	if err != nil {
+		return nil, grpcStatus(logger, fullMethod, err)
+	}
+	token := renewedToken(principal)
+	if token != "" {
+		err = grpc.SetHeader(ctx, metadata.Pairs(SessionTokenHeader, token))
+		if err != nil {
+			logger.Warn("failed to send renewed session token", "method", fullMethod, "error", err)
+		}
+	}
+	return ContextWithPrincipal(ctx, principal), nil
+}
+
+// authUnaryInterceptor returns a unary server interceptor that rejects
+// calls the access rules do not allow, and passes the principal of the
+// call to the handler in its context.
+func authUnaryInterceptor(logger Logger, auth Authenticator, rules AccessRules) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		ctx, err := authorizeGRPC(ctx, logger, auth, rules, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStreamInterceptor is the stream counterpart of authUnaryInterceptor.
+func authStreamInterceptor(logger Logger, auth Authenticator, rules AccessRules) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, err := authorizeGRPC(ss.Context(), logger, auth, rules, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, principalStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// principalStream is a server stream with a context that carries the
+// principal of the call.
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context //nolint:containedctx // The stream must return it.
+}
+
+func (p principalStream) Context() context.Context {
+	return p.ctx
+}
+
+// RequireAccess wraps an HTTP handler so that it is only called for
+// requests a rule allows. The principal of the request is passed to the
+// handler in the request context. Requests without a valid session are
+// rejected with 401 Unauthorized, and requests the principal may not make
+// with 403 Forbidden.
+func RequireAccess(logger Logger, auth Authenticator, rule AccessRule, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authorize(r.Context(), auth, rule, r.Header.Get("Authorization"))
+		switch {
+		case errors.Is(err, domain.ErrUnauthenticated):
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		case errors.Is(err, domain.ErrForbidden):
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		case err != nil:
+			logger.Error("failed to authorize request", "path", r.URL.Path, "error", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		token := renewedToken(principal)
+		if token != "" {
+			w.Header().Set(SessionTokenHeader, token)
+		}
+		next.ServeHTTP(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+	})
+}
@@ -0,0 +1,220 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+// fakeAuthenticator knows a fixed set of tokens.
+type fakeAuthenticator struct {
+	principals map[string]*domain.Principal
+	err        error
+}
+
+func (f fakeAuthenticator) Authenticate(_ context.Context, token string) (*domain.Principal, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	principal, ok := f.principals[token]
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+	return principal, nil
+}
+
+func newFakeAuthenticator() fakeAuthenticator {
+	grants := func(role domain.Role) []domain.Grant {
+		var gs []domain.Grant
+		for _, g := range domain.DefaultGrants() {
+			if g.Role == role {
+				gs = append(gs, g)
+			}
+		}
+		return gs
+	}
+	renewed := &domain.UserSession{Token: "renewed"}
+	return fakeAuthenticator{
+		principals: map[string]*domain.Principal{
+			"customer": {CustomerID: "kai", Session: nil, Roles: nil, Grants: grants(domain.RoleCustomer)},
+			"support":  {CustomerID: "lea", Session: renewed, Roles: nil, Grants: grants(domain.RoleSupport)},
+		},
+		err: nil,
+	}
+}
+
+// headerStream records the headers a handler sends.
+type headerStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (h *headerStream) SetHeader(md metadata.MD) error {
+	h.header = metadata.Join(h.header, md)
+	return nil
+}
+
+func TestAuthUnaryInterceptor(t *testing.T) {
+	rules := AccessRules{
+		"/shop.v1.Orders/":       {Public: false, Permission: domain.PermissionOrdersView},
+		"/shop.v1.Orders/Refund": {Public: false, Permission: domain.PermissionOrdersRefund},
+		"/shop.v1.Catalog/List":  {Public: true, Permission: domain.InvalidPermission},
+	}
+	interceptor := authUnaryInterceptor(test.NewMockLogger(), newFakeAuthenticator(), rules)
+
+	tests := []struct {
+		test.CaseBase
+		method        string
+		authorization string
+		customerID    string
+	}{
+		{
+			CaseBase: test.NewCaseBase("public without session", codes.OK, false),
+			method:   "/shop.v1.Catalog/List",
+		},
+		{
+			CaseBase:      test.NewCaseBase("public with session", codes.OK, false),
+			method:        "/shop.v1.Catalog/List",
+			authorization: "Bearer customer",
+			customerID:    "kai",
+		},
+		{
+			CaseBase: test.NewCaseBase("health check", codes.OK, false),
+			method:   "/grpc.health.v1.Health/Check",
+		},
+		{
+			CaseBase: test.NewCaseBase("no session", codes.Unauthenticated, true),
+			method:   "/shop.v1.Orders/Get",
+		},
+		{
+			CaseBase:      test.NewCaseBase("unknown session", codes.Unauthenticated, true),
+			method:        "/shop.v1.Orders/Get",
+			authorization: "Bearer nobody",
+		},
+		{
+			CaseBase:      test.NewCaseBase("service rule", codes.OK, false),
+			method:        "/shop.v1.Orders/Get",
+			authorization: "bearer customer",
+			customerID:    "kai",
+		},
+		{
+			CaseBase:      test.NewCaseBase("missing permission", codes.PermissionDenied, true),
+			method:        "/shop.v1.Orders/Refund",
+			authorization: "Bearer customer",
+		},
+		{
+			CaseBase:      test.NewCaseBase("method rule", codes.OK, false),
+			method:        "/shop.v1.Orders/Refund",
+			authorization: "Bearer support",
+			customerID:    "lea",
+		},
+		{
+			CaseBase:      test.NewCaseBase("no rule", codes.PermissionDenied, true),
+			method:        "/shop.v1.Admin/Delete",
+			authorization: "Bearer support",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				stream := &headerStream{ServerTransportStream: nil, header: nil}
+				ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.authorization))
+
+				called := false
+				_, err := interceptor(
+					ctx, nil, &grpc.UnaryServerInfo{Server: nil, FullMethod: tt.method},
+					func(ctx context.Context, _ any) (any, error) {
+						called = true
+						principal, ok := PrincipalFromContext(ctx)
+						assert.Equal(t, ok, tt.customerID != "")
+						if ok {
+							assert.Equal(t, principal.CustomerID, tt.customerID)
+						}
+						return nil, nil
+					},
+				)
+				assert.Equal(t, status.Code(err), tt.Want.(codes.Code))
+				assert.Equal(t, called, !tt.WantErr)
+				if tt.customerID == "lea" {
+					assert.Equal(t, stream.header.Get(SessionTokenHeader)[0], "renewed")
+				}
+			},
+		)
+	}
+}
+
+func TestRequireAccess(t *testing.T) {
+	auth := newFakeAuthenticator()
+	rule := AccessRule{Public: false, Permission: domain.PermissionOrdersRefund}
+
+	tests := []struct {
+		test.CaseBase
+		auth          Authenticator
+		authorization string
+	}{
+		{CaseBase: test.NewCaseBase("no session", http.StatusUnauthorized, true), auth: auth},
+		{
+			CaseBase:      test.NewCaseBase("other scheme", http.StatusUnauthorized, true),
+			auth:          auth,
+			authorization: "Basic c3VwcG9ydA==",
+		},
+		{
+			CaseBase:      test.NewCaseBase("missing permission", http.StatusForbidden, true),
+			auth:          auth,
+			authorization: "Bearer customer",
+		},
+		{
+			CaseBase:      test.NewCaseBase("allowed", http.StatusOK, false),
+			auth:          auth,
+			authorization: "Bearer support",
+		},
+		{
+			CaseBase:      test.NewCaseBase("failing authenticator", http.StatusInternalServerError, true),
+			auth:          fakeAuthenticator{principals: nil, err: errors.New("database is down")},
+			authorization: "Bearer support",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				handler := RequireAccess(test.NewMockLogger(), tt.auth, rule, http.HandlerFunc(
+					func(w http.ResponseWriter, r *http.Request) {
+						principal, ok := PrincipalFromContext(r.Context())
+						assert.True(t, ok)
+						assert.Equal(t, principal.CustomerID, "lea")
+						w.WriteHeader(http.StatusOK)
+					},
+				))
+				req := httptest.NewRequest(http.MethodPost, "/orders/refund", nil)
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				assert.Equal(t, rec.Code, tt.Want.(int))
+				if !tt.WantErr {
+					assert.Equal(t, rec.Header().Get(SessionTokenHeader), "renewed")
+				}
+			},
+		)
+	}
+}
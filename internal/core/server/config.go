@@ -30,6 +30,13 @@ type config struct {
 	version Version
 
 	telemetry telemetry.Telemetry
+
+	// authenticator resolves the principal of calls. Calls are not
+	// authorized when it is nil.
+	authenticator Authenticator
+
+	// accessRules are the rules calls are authorized against.
+	accessRules AccessRules
 }
 
 func newConfig(opts ...ConfigOpts) (*config, error) {
@@ -61,6 +68,9 @@ func newDefaultConfig() *config {
 		socketPath: "",
 		version:    defaultVersion,
 		telemetry:  nil,
+
+		authenticator: nil,
+		accessRules:   nil,
 	}
 }
 
@@ -128,6 +138,20 @@ func WithTelemetry(t telemetry.Telemetry) ConfigOpts {
 	}
 }
 
+// WithAuthorization makes the server reject calls that the access rules do
+// not allow. The principal of every call is resolved with auth and passed to
+// handlers in the call context. See PrincipalFromContext.
+func WithAuthorization(auth Authenticator, rules AccessRules) ConfigOpts {
+	return func(c *config) error {
+		if auth == nil {
+			return errors.New("authenticator must not be nil")
+		}
+		c.authenticator = auth
+		c.accessRules = rules
+		return nil
+	}
+}
+
 // Address represents a layer 4 OSI Address. An address must only be either an
 // IP address, a domain name followed by a TLD, or a path to a Unix socket.
 type Address string
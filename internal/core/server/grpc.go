@@ -63,12 +63,26 @@ func NewGRPCServer(logger Logger, configOpts ...ConfigOpts) (GRPCServer, error)
 		return nil, err
 	}
 
-	// The UnaryInterceptor and the StreamInterceptor here are used for panic
-	// recovery.
+	// The first interceptors are used for panic recovery. Request IDs and
+	// authorization come after them, so that a panic in them is recovered
+	// too, and authorization failures are audited with the request ID.
+
+	unary := []grpc.UnaryServerInterceptor{
+		panicRecoveryUnaryInterceptor(b.logger),
+		requestIDUnaryInterceptor(b.logger),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		panicRecoveryStreamInterceptor(b.logger),
+		requestIDStreamInterceptor(b.logger),
+	}
+	if b.config.authenticator != nil {
+		unary = append(unary, authUnaryInterceptor(b.logger, b.config.authenticator, b.config.accessRules))
+		stream = append(stream, authStreamInterceptor(b.logger, b.config.authenticator, b.config.accessRules))
+	}
 
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(panicRecoveryUnaryInterceptor(b.logger)),
-		grpc.StreamInterceptor(panicRecoveryStreamInterceptor(b.logger)),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
 	}
 
 	if b.config.telemetry != nil {
@@ -151,14 +151,31 @@ func (s httpServer) registerRoutes(routes ...HTTPRoute) http.Handler {
 	}
 
 	for _, v := range routes {
-		handleFunc(v.String(), v.Route())
+		route := v.Route()
+		if s.config.authenticator != nil {
+			rule, ok := s.config.accessRules[v.String()]
+			if ok {
+				route = RequireAccess(s.logger, s.config.authenticator, rule, route).ServeHTTP
+			} else {
+				// Routes without a rule are rejected, like gRPC methods.
+				s.logger.Warn("route has no access rule and is rejected", "route", v.String())
+				route = forbidden
+			}
+		}
+		handleFunc(v.String(), route)
 	}
 
+	handler := WithRequestID(s.logger, mux)
+
 	if s.config.telemetry != nil {
-		return otelhttp.NewHandler(mux, "/")
+		return otelhttp.NewHandler(handler, "/")
 	}
 
-	return mux
+	return handler
+}
+
+func forbidden(w http.ResponseWriter, _ *http.Request) {
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 }
 
 // BodyParser parses a body returned from an HTTP request and returns
@@ -247,6 +264,10 @@ func (h httpClient[T]) Get(ctx context.Context, url string, headers map[string]s
 		return v, errors.Wrap(err, "failed to read response body")
 	}
 
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return v, errors.Errorf("unexpected response status %d", res.StatusCode)
+	}
+
 	return h.parser.Parse(resBody)
 }
 
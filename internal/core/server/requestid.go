@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"net/http"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the header that carries the ID of a request. A valid ID
+// sent by the client, such as one set by a proxy, is kept; otherwise a new
+// one is made. The ID is sent back to the client in the same header, and is
+// recorded with the audit events of the request.
+const RequestIDHeader = "x-request-id"
+
+// maxRequestIDLength is the length of the longest request ID accepted from
+// a client.
+const maxRequestIDLength = 64
+
+// requestID returns the ID of a request given the ID the client sent, if
+// any.
+func requestID(logger Logger, sent string) string {
+	if validRequestID(sent) {
+		return sent
+	}
+	id, err := uuid.New()
+	if err != nil {
+		logger.Warn("failed to make request ID", "error", err)
+		return ""
+	}
+	return id
+}
+
+// validRequestID reports whether a request ID sent by a client is short and
+// only made of letters, digits, '-', '_' and '.', so that it is safe to log.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		ok := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.'
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDGRPC returns the context of a gRPC call with its request ID.
+func requestIDGRPC(ctx context.Context, logger Logger) context.Context {
+	sent := ""
+	md, ok := metadata.FromIncomingContext(ctx)
+	if ok {
+		values := md.Get(RequestIDHeader)
+		if len(values) > 0 {
+			sent = values[0]
+		}
+	}
+	id := requestID(logger, sent)
+	if id == "" {
+		return ctx
+	}
+	err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+	if err != nil {
+		logger.Warn("failed to send request ID", "error", err)
+	}
+	return domain.ContextWithRequestID(ctx, id)
+}
+
+// requestIDUnaryInterceptor returns a unary server interceptor that passes
+// the request ID of a call to the handler in its context.
+func requestIDUnaryInterceptor(logger Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		_ *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		return handler(requestIDGRPC(ctx, logger), req)
+	}
+}
+
+// requestIDStreamInterceptor is the stream counterpart of
+// requestIDUnaryInterceptor.
+func requestIDStreamInterceptor(logger Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		_ *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, principalStream{ServerStream: ss, ctx: requestIDGRPC(ss.Context(), logger)})
+	}
+}
+
+// WithRequestID wraps an HTTP handler so that the request ID of every
+// request is passed to it in the request context.
+func WithRequestID(logger Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := requestID(logger, r.Header.Get(RequestIDHeader))
+		if id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(domain.ContextWithRequestID(r.Context(), id)))
+	})
+}
@@ -0,0 +1,80 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+func TestRequestIDUnaryInterceptor(t *testing.T) {
+	interceptor := requestIDUnaryInterceptor(test.NewMockLogger())
+
+	tests := []struct {
+		test.CaseBase
+		sent string
+	}{
+		{CaseBase: test.NewCaseBase("kept", "req-42.a_b", false), sent: "req-42.a_b"},
+		{CaseBase: test.NewCaseBase("missing", "", false), sent: ""},
+		{CaseBase: test.NewCaseBase("unsafe", "", false), sent: "req 42\nforged log line"},
+		{CaseBase: test.NewCaseBase("too long", "", false), sent: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				stream := &headerStream{ServerTransportStream: nil, header: nil}
+				ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(RequestIDHeader, tt.sent))
+
+				var got string
+				_, err := interceptor(
+					ctx, nil, &grpc.UnaryServerInfo{Server: nil, FullMethod: "/shop.v1.Orders/Get"},
+					func(ctx context.Context, _ any) (any, error) {
+						got = domain.RequestIDFromContext(ctx)
+						return nil, nil
+					},
+				)
+				assert.NoError(t, err)
+				if tt.Want.(string) != "" {
+					assert.Equal(t, got, tt.Want.(string))
+				}
+				assert.True(t, validRequestID(got))
+				assert.Equal(t, stream.header.Get(RequestIDHeader)[0], got)
+			},
+		)
+	}
+}
+
+func TestWithRequestID(t *testing.T) {
+	var got string
+	handler := WithRequestID(test.NewMockLogger(), http.HandlerFunc(
+		func(_ http.ResponseWriter, r *http.Request) {
+			got = domain.RequestIDFromContext(r.Context())
+		},
+	))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, got, "req-42")
+	assert.Equal(t, rec.Header().Get(RequestIDHeader), "req-42")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.True(t, got != "" && got != "req-42")
+	assert.Equal(t, rec.Header().Get(RequestIDHeader), got)
+}
@@ -0,0 +1,585 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// adminCustomerRepository is a customerRepository that can also find and
+// restore deleted customers, which only the back office needs.
+type adminCustomerRepository interface {
+	customerRepository
+	// SearchByEmail retrieves up to limit customers whose email address
+	// contains a query, deleted customers included.
+	SearchByEmail(ctx context.Context, query string, limit int) ([]*domain.Customer, error)
+	GetByIDIncludingDeleted(ctx context.Context, id string) (*domain.Customer, error)
+	Restore(ctx context.Context, id string) error
+}
+
+// orderRepository reads orders along with their items.
+type orderRepository interface {
+	// Insert adds a new order along with its items.
+	Insert(ctx context.Context, order *domain.Order) error
+	GetByID(ctx context.Context, id string) (*domain.Order, error)
+	GetByOrderNumber(ctx context.Context, number string) (*domain.Order, error)
+	ListByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error)
+	// UpdateStatusFrom changes the status of an order and of all of its
+	// items from one status to another. It returns
+	// domain.ErrOrderStatusChanged if the order is not in the status from.
+	UpdateStatusFrom(ctx context.Context, id string, from, to domain.FulfillmentStatus) error
+}
+
+// downloadRepository tracks how often customers downloaded their products.
+type downloadRepository interface {
+	// Insert adds a new download.
+	Insert(ctx context.Context, download *domain.Download) error
+	ListByCustomer(ctx context.Context, customerID string) ([]*domain.Download, error)
+	ResetCount(ctx context.Context, id string) error
+}
+
+// AdminSearchLimit is the largest number of customers a search returns.
+const AdminSearchLimit = 50
+
+// AdminService is the back office of support staff. It lets them look up
+// customers, their orders and their downloads, and fix what customers cannot
+// fix themselves. Every method acts on behalf of a principal whose grants
+// must allow the action, and every action, allowed or not, is recorded as
+// an audit event naming the principal as its actor.
+type AdminService struct {
+	*ServiceBase
+	customers adminCustomerRepository
+	orders    orderRepository
+	downloads downloadRepository
+	sessions  *SessionService
+	authz     *AuthorizationService
+	webshop   *WebshopService
+	mfa       *MFAService
+	mailer    Mailer
+	audit     auditRecorder
+}
+
+// NewAdminService creates a new AdminService.
+func NewAdminService(
+	svcBase *ServiceBase,
+	customers adminCustomerRepository,
+	orders orderRepository,
+	downloads downloadRepository,
+	sessions *SessionService,
+	authz *AuthorizationService,
+	webshop *WebshopService,
+	mfa *MFAService,
+	mailer Mailer,
+) *AdminService {
+	return &AdminService{
+		ServiceBase: svcBase,
+		customers:   customers,
+		orders:      orders,
+		downloads:   downloads,
+		sessions:    sessions,
+		authz:       authz,
+		webshop:     webshop,
+		mfa:         mfa,
+		mailer:      mailer,
+		audit:       svcBase.auditRecorder(),
+	}
+}
+
+// SearchCustomers finds customers, deleted ones included, by a part of
+// their email address or by the exact number of one of their orders, such
+// as "BDE-2025-01-31-...". At most AdminSearchLimit customers are returned.
+func (a *AdminService) SearchCustomers(
+	ctx context.Context,
+	principal *domain.Principal,
+	query string,
+) ([]*domain.Customer, error) {
+	err := a.authorize(ctx, principal, domain.AuditActionAdminSearch, domain.AccessRequest{
+		Permission: domain.PermissionCustomersView,
+		OwnerID:    "",
+		Amount:     0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	var customers []*domain.Customer
+	if strings.HasPrefix(strings.ToUpper(query), "BDE-") {
+		customers, err = a.searchByOrderNumber(ctx, strings.ToUpper(query))
+	} else {
+		customers, err = a.customers.SearchByEmail(ctx, strings.ToLower(query), AdminSearchLimit)
+	}
+	if err != nil {
+		a.record(ctx, principal, domain.AuditActionAdminSearch, domain.AuditOutcomeFailure, "", "repository_error")
+		return nil, errors.Wrap(err, "failed to search customers")
+	}
+	a.record(ctx, principal, domain.AuditActionAdminSearch, domain.AuditOutcomeSuccess, "", "")
+	return customers, nil
+}
+
+func (a *AdminService) searchByOrderNumber(ctx context.Context, number string) ([]*domain.Customer, error) {
+	order, err := a.orders.GetByOrderNumber(ctx, number)
+	if errors.Is(err, domain.ErrOrderNotFound) {
+		return []*domain.Customer{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.customers.GetByIDIncludingDeleted(ctx, order.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return []*domain.Customer{customer}, nil
+}
+
+// Customer returns a customer, even if they were deleted.
+func (a *AdminService) Customer(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) (*domain.Customer, error) {
+	err := a.authorizeView(ctx, principal, domain.PermissionCustomersView, customerID)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.customers.GetByIDIncludingDeleted(ctx, customerID)
+	if err != nil {
+		return nil, a.fail(ctx, principal, domain.AuditActionAdminView, customerID, err)
+	}
+	a.record(ctx, principal, domain.AuditActionAdminView, domain.AuditOutcomeSuccess, customerID, "")
+	return customer, nil
+}
+
+// Orders returns every order of a customer along with its items, most
+// recent first.
+func (a *AdminService) Orders(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) ([]*domain.Order, error) {
+	err := a.authorizeView(ctx, principal, domain.PermissionOrdersView, customerID)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := a.orders.ListByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, a.fail(ctx, principal, domain.AuditActionAdminView, customerID, err)
+	}
+	a.record(ctx, principal, domain.AuditActionAdminView, domain.AuditOutcomeSuccess, customerID, "")
+	return orders, nil
+}
+
+// Downloads returns the download history of a customer, most recent first.
+func (a *AdminService) Downloads(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) ([]*domain.Download, error) {
+	err := a.authorizeView(ctx, principal, domain.PermissionDownloadsView, customerID)
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := a.downloads.ListByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, a.fail(ctx, principal, domain.AuditActionAdminView, customerID, err)
+	}
+	a.record(ctx, principal, domain.AuditActionAdminView, domain.AuditOutcomeSuccess, customerID, "")
+	return downloads, nil
+}
+
+// RefundOrder refunds a completed order through the payment processor and
+// marks it and its items as refunded. The grants of the principal must
+// allow refunding the order's grand total.
+func (a *AdminService) RefundOrder(
+	ctx context.Context,
+	principal *domain.Principal,
+	orderID string,
+) (*domain.Order, error) {
+	action := domain.AuditActionAdminRefund
+	// The principal must be allowed to refund at all before learning
+	// whether the order exists or what state it is in. The grand total is
+	// checked once the order is known.
+	err := a.authorize(ctx, principal, action, domain.AccessRequest{
+		Permission: domain.PermissionOrdersRefund,
+		OwnerID:    "",
+		Amount:     0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	order, err := a.orders.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, a.failOn(ctx, principal, action, "", domain.AuditTargetOrder, orderID, err)
+	}
+	if order.Status != domain.CompletedStatus {
+		a.recordOn(
+			ctx, principal, action, domain.AuditOutcomeFailure, order.UserID,
+			domain.AuditTargetOrder, order.ID, "not_refundable",
+		)
+		return nil, ErrOrderNotRefundable
+	}
+	err = a.webshop.Refund(ctx, principal, order)
+	if err != nil {
+		return nil, a.failOn(ctx, principal, action, order.UserID, domain.AuditTargetOrder, order.ID, err)
+	}
+	a.recordOn(ctx, principal, action, domain.AuditOutcomeSuccess, order.UserID, domain.AuditTargetOrder, order.ID, "")
+	return order, nil
+}
+
+// ResendReceipt sends the receipt of an order again, to the billing email
+// address of the order, or to the customer's email address if the order
+// has none.
+func (a *AdminService) ResendReceipt(
+	ctx context.Context,
+	principal *domain.Principal,
+	orderID string,
+) error {
+	action := domain.AuditActionAdminResendReceipt
+	err := a.authorize(ctx, principal, action, domain.AccessRequest{
+		Permission: domain.PermissionOrdersResendReceipt,
+		OwnerID:    "",
+		Amount:     0,
+	})
+	if err != nil {
+		return err
+	}
+	order, err := a.orders.GetByID(ctx, orderID)
+	if err != nil {
+		return a.failOn(ctx, principal, action, "", domain.AuditTargetOrder, orderID, err)
+	}
+	to := order.BillingEmail
+	if to == "" {
+		customer, err := a.customers.GetByIDIncludingDeleted(ctx, order.UserID)
+		if err != nil {
+			return a.failOn(ctx, principal, action, order.UserID, domain.AuditTargetOrder, order.ID, err)
+		}
+		to = customer.Email
+	}
+	err = a.mailer.Send(ctx, receiptMessage(to, order))
+	if err != nil {
+		return a.failOn(ctx, principal, action, order.UserID, domain.AuditTargetOrder, order.ID, err)
+	}
+	a.recordOn(ctx, principal, action, domain.AuditOutcomeSuccess, order.UserID, domain.AuditTargetOrder, order.ID, "")
+	return nil
+}
+
+// receiptMessage makes the receipt email of an order.
+func receiptMessage(to string, order *domain.Order) domain.EmailMessage {
+	items := make([]map[string]string, len(order.Items))
+	for i, item := range order.Items {
+		items[i] = map[string]string{
+			"Name":  item.Product.Name,
+			"Price": formatAmount(item.Product.Price*item.Quantity, order.CurrencyID),
+		}
+	}
+	return domain.EmailMessage{
+		To:       to,
+		Template: domain.EmailTemplateOrderReceipt,
+		Data: map[string]any{
+			"OrderID": order.OrderNumber,
+			"Items":   items,
+			"Total":   formatAmount(order.GrandTotal, order.CurrencyID),
+		},
+	}
+}
+
+// formatAmount formats an amount in the smallest unit of a currency with
+// two decimals, such as "19.00 USD".
+func formatAmount(amount int, currency string) string {
+	return fmt.Sprintf("%d.%02d %s", amount/100, amount%100, currency)
+}
+
+// ResetDownloads sets the download counters of a customer back to zero,
+// such as when they hit the download limit after losing their files. Only
+// the downloads with the given IDs are reset, or every download of the
+// customer if none are given. It returns the downloads of the customer.
+func (a *AdminService) ResetDownloads(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+	downloadIDs ...string,
+) ([]*domain.Download, error) {
+	action := domain.AuditActionAdminResetDownloads
+	err := a.authorize(ctx, principal, action, domain.AccessRequest{
+		Permission: domain.PermissionDownloadsReset,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := a.downloads.ListByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, a.fail(ctx, principal, action, customerID, err)
+	}
+	for _, id := range downloadIDs {
+		if !slices.ContainsFunc(downloads, func(d *domain.Download) bool { return d.ID == id }) {
+			return nil, a.failOn(
+				ctx, principal, action, customerID, domain.AuditTargetDownload, id, domain.ErrDownloadNotFound,
+			)
+		}
+	}
+	// Every download reset is recorded on its own, or the action once if
+	// the customer has no downloads.
+	reset := 0
+	for _, d := range downloads {
+		if len(downloadIDs) > 0 && !slices.Contains(downloadIDs, d.ID) {
+			continue
+		}
+		err = a.downloads.ResetCount(ctx, d.ID)
+		if err != nil {
+			return nil, a.failOn(ctx, principal, action, customerID, domain.AuditTargetDownload, d.ID, err)
+		}
+		d.DownloadCount = 0
+		a.recordOn(ctx, principal, action, domain.AuditOutcomeSuccess, customerID, domain.AuditTargetDownload, d.ID, "")
+		reset++
+	}
+	if reset == 0 {
+		a.record(ctx, principal, action, domain.AuditOutcomeSuccess, customerID, "")
+	}
+	return downloads, nil
+}
+
+// VerifyEmail marks the email address of a customer as verified, such as
+// when the customer proved it to support by other means. Verifying an
+// address that is already verified does nothing.
+func (a *AdminService) VerifyEmail(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) (*domain.Customer, error) {
+	action := domain.AuditActionAdminVerifyEmail
+	err := a.authorize(ctx, principal, action, domain.AccessRequest{
+		Permission: domain.PermissionCustomersVerifyEmail,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	customer, err := a.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, a.fail(ctx, principal, action, customerID, err)
+	}
+	if !customer.EmailVerified {
+		customer.EmailVerified = true
+		err = a.customers.UpdateInformation(ctx, customer)
+		if err != nil {
+			return nil, a.fail(ctx, principal, action, customerID, err)
+		}
+	}
+	a.record(ctx, principal, action, domain.AuditOutcomeSuccess, customerID, "")
+	return customer, nil
+}
+
+// DeleteCustomer soft deletes the account of a customer and signs them out
+// everywhere. Deleting an account is sensitive, so the principal must have
+// authenticated recently.
+func (a *AdminService) DeleteCustomer(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) error {
+	action := domain.AuditActionAdminDeleteCustomer
+	err := a.authorize(ctx, principal, action, domain.AccessRequest{
+		Permission: domain.PermissionCustomersDelete,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+	if err != nil {
+		return err
+	}
+	err = a.sessions.RequireRecentAuth(principal.Session)
+	if err != nil {
+		a.record(ctx, principal, action, domain.AuditOutcomeFailure, customerID, "reauthentication_required")
+		return err
+	}
+	customer, err := a.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return a.fail(ctx, principal, action, customerID, err)
+	}
+	err = a.sessions.RevokeAll(ctx, customer.ID)
+	if err != nil {
+		return a.fail(ctx, principal, action, customerID, err)
+	}
+	err = a.deleteCustomer(ctx, a.customers, customer, principal.CustomerID)
+	if err != nil {
+		return a.fail(ctx, principal, action, customerID, err)
+	}
+	a.record(ctx, principal, action, domain.AuditOutcomeSuccess, customerID, "")
+	return nil
+}
+
+// ResetMFA turns MFA off for a customer who lost both their authenticator
+// and their recovery codes, once support verified their identity some other
+// way. Turning off a second factor is sensitive, so the principal must have
+// authenticated recently.
+func (a *AdminService) ResetMFA(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) error {
+	action := domain.AuditActionMFAReset
+	err := a.authorize(ctx, principal, action, domain.AccessRequest{
+		Permission: domain.PermissionCustomersMFAReset,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+	if err != nil {
+		return err
+	}
+	err = a.sessions.RequireRecentAuth(principal.Session)
+	if err != nil {
+		a.record(ctx, principal, action, domain.AuditOutcomeFailure, customerID, "reauthentication_required")
+		return err
+	}
+	err = a.mfa.reset(ctx, customerID)
+	if err != nil {
+		return a.fail(ctx, principal, action, customerID, err)
+	}
+	a.record(ctx, principal, action, domain.AuditOutcomeSuccess, customerID, "")
+	return nil
+}
+
+// RestoreCustomer undoes the deletion of a customer's account. The customer
+// signs in again with their old credentials. Accounts can only be restored
+// until their personal data is erased, see PrivacyService, and not once
+// someone else signed up with their email address.
+func (a *AdminService) RestoreCustomer(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) (*domain.Customer, error) {
+	action := domain.AuditActionAdminRestoreCustomer
+	err := a.authorize(ctx, principal, action, domain.AccessRequest{
+		Permission: domain.PermissionCustomersRestore,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = a.customers.Restore(ctx, customerID)
+	if err != nil {
+		return nil, a.fail(ctx, principal, action, customerID, err)
+	}
+	customer, err := a.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, a.fail(ctx, principal, action, customerID, err)
+	}
+	a.record(ctx, principal, action, domain.AuditOutcomeSuccess, customerID, "")
+	return customer, nil
+}
+
+// authorizeView authorizes looking at a resource of a customer.
+func (a *AdminService) authorizeView(
+	ctx context.Context,
+	principal *domain.Principal,
+	permission domain.Permission,
+	customerID string,
+) error {
+	return a.authorize(ctx, principal, domain.AuditActionAdminView, domain.AccessRequest{
+		Permission: permission,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+}
+
+// authorize authorizes an action and records a failed audit event if the
+// principal may not perform it.
+func (a *AdminService) authorize(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	req domain.AccessRequest,
+) error {
+	err := a.authz.Authorize(ctx, principal, req)
+	if err != nil {
+		a.record(ctx, principal, action, domain.AuditOutcomeFailure, req.OwnerID, "forbidden")
+		return err
+	}
+	return nil
+}
+
+// fail records a failed audit event for an error and returns the error.
+func (a *AdminService) fail(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	customerID string,
+	err error,
+) error {
+	return a.failOn(ctx, principal, action, customerID, domain.AuditTargetNone, "", err)
+}
+
+// failOn is fail for actions that also concern a thing, such as an order.
+func (a *AdminService) failOn(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	customerID string,
+	target domain.AuditTargetType,
+	targetID string,
+	err error,
+) error {
+	reason := "repository_error"
+	switch {
+	case errors.Is(err, domain.ErrForbidden):
+		reason = "forbidden"
+	case errors.Is(err, domain.ErrCustomerNotFound):
+		reason = "customer_not_found"
+	case errors.Is(err, domain.ErrEmailTaken):
+		reason = "email_taken"
+	case errors.Is(err, domain.ErrOrderNotFound):
+		reason = "order_not_found"
+	case errors.Is(err, domain.ErrDownloadNotFound):
+		reason = "download_not_found"
+	case errors.Is(err, ErrMFANotEnrolled):
+		reason = "not_enrolled"
+	case errors.Is(err, ErrOrderNotRefundable):
+		reason = "not_refundable"
+	}
+	a.recordOn(ctx, principal, action, domain.AuditOutcomeFailure, customerID, target, targetID, reason)
+	return err
+}
+
+func (a *AdminService) record(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	outcome domain.AuditOutcome,
+	customerID, reason string,
+) {
+	a.recordOn(ctx, principal, action, outcome, customerID, domain.AuditTargetNone, "", reason)
+}
+
+// recordOn is record for actions that also concern a thing, such as an
+// order.
+func (a *AdminService) recordOn(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	outcome domain.AuditOutcome,
+	customerID string,
+	target domain.AuditTargetType,
+	targetID, reason string,
+) {
+	event := domain.NewActorAuditEvent(action, outcome, actorOf(principal), customerID, reason)
+	a.audit.Record(ctx, event.WithTarget(target, targetID))
+}
+
+var (
+	ErrEmptySearchQuery   = errors.New("search query is empty")
+	ErrOrderNotRefundable = errors.New("only completed orders can be refunded")
+)
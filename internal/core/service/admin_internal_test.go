@@ -0,0 +1,416 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+type adminFixture struct {
+	authFixture
+	admin     *AdminService
+	orders    *memory.OrderRepository
+	downloads *memory.DownloadRepository
+	// buyer is a customer with a completed order of 4200 and a download.
+	buyer    *AuthResult
+	order    *domain.Order
+	download *domain.Download
+}
+
+func newAdminFixture(t *testing.T) adminFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	orders := memory.NewOrderRepository()
+	downloads := memory.NewDownloadRepository()
+	shop := NewWebshopService(base, &fakePaymentProcessor{}, f.customers, orders, downloads, f.authz)
+	admin := NewAdminService(base, f.customers, orders, downloads, f.sessions, f.authz, shop, f.mfa, f.mailer)
+	admin.audit = f.audit
+
+	buyer := f.signUpWithRoles(t, testEmail)
+	product := domain.NewProduct(domain.PluginProduct, "product-1", "Reverb Pack")
+	product.Price = 4200
+	item, err := domain.NewLineItem(*product, 1)
+	assert.NoError(t, err)
+	order, err := domain.NewOrder("USD", *item)
+	assert.NoError(t, err)
+	order.UserID = buyer.Customer.ID
+	order.BillingEmail = "billing@brokedaear.com"
+	order.Status = domain.CompletedStatus
+	assert.NoError(t, orders.Insert(ctx, order))
+	download := &domain.Download{
+		ID:               "download-1",
+		UserID:           buyer.Customer.ID,
+		ProductID:        product.ID,
+		OrderID:          order.ID,
+		DownloadCount:    5,
+		LastDownloadedAt: nil,
+		CreatedAt:        time.Now().UTC(),
+	}
+	assert.NoError(t, downloads.Insert(ctx, download))
+
+	return adminFixture{
+		authFixture: f,
+		admin:       admin,
+		orders:      orders,
+		downloads:   downloads,
+		buyer:       buyer,
+		order:       order,
+		download:    download,
+	}
+}
+
+// principal signs a customer up with roles and returns their principal.
+func (f adminFixture) principal(t *testing.T, email string, roles ...domain.Role) *domain.Principal {
+	t.Helper()
+	result := f.signUpWithRoles(t, email, roles...)
+	principal, err := f.authz.Principal(context.Background(), result.Session)
+	assert.NoError(t, err)
+	return principal
+}
+
+func TestAdminService_SearchCustomers(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	buyer, err := f.authz.Principal(ctx, f.buyer.Session)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		test.CaseBase
+		principal *domain.Principal
+		query     string
+		wantCount int
+	}{
+		{CaseBase: test.NewCaseBase("by email", nil, false), principal: support, query: "KAI@", wantCount: 1},
+		{CaseBase: test.NewCaseBase("by domain", nil, false), principal: support, query: "brokedaear", wantCount: 2},
+		{CaseBase: test.NewCaseBase("by order number", nil, false), principal: support, query: f.order.OrderNumber, wantCount: 1},
+		{CaseBase: test.NewCaseBase("unknown order number", nil, false), principal: support, query: "BDE-2025-01-01-x", wantCount: 0},
+		{CaseBase: test.NewCaseBase("empty", ErrEmptySearchQuery, true), principal: support, query: " ", wantCount: 0},
+		{CaseBase: test.NewCaseBase("customer", domain.ErrForbidden, true), principal: buyer, query: "kai", wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				got, err := f.admin.SearchCustomers(ctx, tt.principal, tt.query)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, len(got), tt.wantCount)
+				assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminSearch)
+				assert.Equal(t, f.audit.last().ActorID, tt.principal.CustomerID)
+			},
+		)
+	}
+}
+
+func TestAdminService_ViewCustomer(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	customerID := f.buyer.Customer.ID
+
+	customer, err := f.admin.Customer(ctx, support, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, customer.Email, testEmail)
+	event := f.audit.last()
+	assert.Equal(t, event.Action, domain.AuditActionAdminView)
+	assert.Equal(t, event.ActorID, support.CustomerID)
+	assert.Equal(t, event.CustomerID, customerID)
+
+	orders, err := f.admin.Orders(ctx, support, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, len(orders), 1)
+	assert.Equal(t, orders[0].OrderNumber, f.order.OrderNumber)
+	assert.Equal(t, len(orders[0].Items), 1)
+
+	downloads, err := f.admin.Downloads(ctx, support, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, len(downloads), 1)
+	assert.Equal(t, downloads[0].DownloadCount, 5)
+
+	_, err = f.admin.Customer(ctx, support, "unknown")
+	assert.Error(t, err, domain.ErrCustomerNotFound)
+	assert.Equal(t, f.audit.last().Reason, "customer_not_found")
+
+	// Customers may look at their own downloads, but not at anyone else's.
+	other := f.principal(t, "other@brokedaear.com")
+	_, err = f.admin.Downloads(ctx, other, customerID)
+	assert.Error(t, err, domain.ErrForbidden)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeFailure)
+}
+
+func TestAdminService_RefundOrder(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+
+	refunded, err := f.admin.RefundOrder(ctx, support, f.order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, refunded.Status, domain.RefundedStatus)
+	stored, err := f.orders.GetByID(ctx, f.order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, stored.Status, domain.RefundedStatus)
+	assert.Equal(t, stored.Items[0].Status, domain.RefundedStatus)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminRefund)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	assert.Equal(t, f.audit.last().TargetType, domain.AuditTargetOrder)
+	assert.Equal(t, f.audit.last().TargetID, f.order.ID)
+
+	_, err = f.admin.RefundOrder(ctx, support, f.order.ID)
+	assert.Error(t, err, ErrOrderNotRefundable)
+
+	_, err = f.admin.RefundOrder(ctx, support, "unknown")
+	assert.Error(t, err, domain.ErrOrderNotFound)
+}
+
+// racingOrderRepository holds the first reads of an order back until all
+// of them arrived, so that that many requests read the order before any of
+// them writes it. Later reads are not held back.
+type racingOrderRepository struct {
+	*memory.OrderRepository
+	mu      sync.Mutex
+	held    int
+	readers sync.WaitGroup
+}
+
+func newRacingOrderRepository(repo *memory.OrderRepository, readers int) *racingOrderRepository {
+	r := &racingOrderRepository{OrderRepository: repo, mu: sync.Mutex{}, held: readers, readers: sync.WaitGroup{}}
+	r.readers.Add(readers)
+	return r
+}
+
+func (r *racingOrderRepository) GetByID(ctx context.Context, id string) (*domain.Order, error) {
+	order, err := r.OrderRepository.GetByID(ctx, id)
+	r.mu.Lock()
+	hold := r.held > 0
+	r.held--
+	r.mu.Unlock()
+	if hold {
+		r.readers.Done()
+		r.readers.Wait()
+	}
+	return order, err
+}
+
+func TestAdminService_ConcurrentRefunds(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+
+	// Two agents refund the same order at once, and both see it completed.
+	// Only one of them gets to refund it.
+	racing := newRacingOrderRepository(f.orders, 2)
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	processor := &fakePaymentProcessor{}
+	shop := NewWebshopService(base, processor, f.customers, racing, f.downloads, f.authz)
+	admin := NewAdminService(base, f.customers, racing, f.downloads, f.sessions, f.authz, shop, f.mfa, f.mailer)
+	admin.audit = f.audit
+
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = admin.RefundOrder(ctx, support, f.order.ID)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, len(processor.refunded), 1)
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			assert.Error(t, err, ErrOrderNotRefundable)
+			failed++
+		}
+	}
+	assert.Equal(t, failed, 1)
+	stored, err := f.orders.GetByID(ctx, f.order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, stored.Status, domain.RefundedStatus)
+}
+
+func TestAdminService_RefundOrderAuthorizesFirst(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	buyer, err := f.authz.Principal(ctx, f.buyer.Session)
+	assert.NoError(t, err)
+
+	// A principal who may not refund learns nothing about the order, and
+	// whether it exists.
+	for _, orderID := range []string{f.order.ID, "unknown"} {
+		_, err = f.admin.RefundOrder(ctx, buyer, orderID)
+		assert.Error(t, err, domain.ErrForbidden)
+		assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminRefund)
+		assert.Equal(t, f.audit.last().Reason, "forbidden")
+		assert.Equal(t, f.audit.last().CustomerID, "")
+	}
+	stored, err := f.orders.GetByID(ctx, f.order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, stored.Status, domain.CompletedStatus)
+}
+
+func TestAdminService_ResendReceipt(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+
+	err := f.admin.ResendReceipt(ctx, support, f.order.ID)
+	assert.NoError(t, err)
+	msg := f.mailer.last()
+	assert.Equal(t, msg.To, "billing@brokedaear.com")
+	assert.Equal(t, msg.Template, domain.EmailTemplateOrderReceipt)
+	assert.Equal(t, msg.Data["OrderID"], any(f.order.OrderNumber))
+	assert.Equal(t, msg.Data["Total"], any("42.00 USD"))
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminResendReceipt)
+	assert.Equal(t, f.audit.last().TargetType, domain.AuditTargetOrder)
+	assert.Equal(t, f.audit.last().TargetID, f.order.ID)
+}
+
+func TestAdminService_ResetDownloads(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	customerID := f.buyer.Customer.ID
+
+	_, err := f.admin.ResetDownloads(ctx, support, customerID, "unknown")
+	assert.Error(t, err, domain.ErrDownloadNotFound)
+	assert.Equal(t, f.audit.last().TargetType, domain.AuditTargetDownload)
+	assert.Equal(t, f.audit.last().TargetID, "unknown")
+
+	downloads, err := f.admin.ResetDownloads(ctx, support, customerID, f.download.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, downloads[0].DownloadCount, 0)
+	stored, err := f.downloads.ListByCustomer(ctx, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, stored[0].DownloadCount, 0)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminResetDownloads)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	assert.Equal(t, f.audit.last().TargetType, domain.AuditTargetDownload)
+	assert.Equal(t, f.audit.last().TargetID, f.download.ID)
+}
+
+func TestAdminService_VerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+
+	customer, err := f.admin.VerifyEmail(ctx, support, f.buyer.Customer.ID)
+	assert.NoError(t, err)
+	assert.True(t, customer.EmailVerified)
+	stored, err := f.customers.GetByID(ctx, f.buyer.Customer.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.EmailVerified)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminVerifyEmail)
+}
+
+func TestAdminService_ResetMFA(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	other := f.principal(t, "other@brokedaear.com")
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	customerID := f.buyer.Customer.ID
+	enrollMFAIn(t, f.authFixture, f.buyer.Session)
+
+	err := f.admin.ResetMFA(ctx, other, customerID)
+	assert.Error(t, err, domain.ErrForbidden)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionMFAReset)
+	assert.Equal(t, f.audit.last().Reason, "forbidden")
+
+	// Turning off a second factor is sensitive.
+	support.Session.AuthenticatedAt = time.Now().Add(-time.Hour)
+	err = f.admin.ResetMFA(ctx, support, customerID)
+	assert.Error(t, err, ErrReauthenticationRequired)
+	assert.Equal(t, f.audit.last().Reason, "reauthentication_required")
+	enabled, err := f.mfa.enabled(ctx, customerID)
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	support.Session.AuthenticatedAt = time.Now()
+	err = f.admin.ResetMFA(ctx, support, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionMFAReset)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	assert.Equal(t, f.audit.last().ActorID, support.CustomerID)
+	assert.Equal(t, f.audit.last().CustomerID, customerID)
+	enabled, err = f.mfa.enabled(ctx, customerID)
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+func TestAdminService_DeleteAndRestoreCustomer(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	admin := f.principal(t, "admin@brokedaear.com", domain.RoleAdmin)
+	customerID := f.buyer.Customer.ID
+
+	err := f.admin.DeleteCustomer(ctx, support, customerID)
+	assert.Error(t, err, domain.ErrForbidden)
+
+	err = f.admin.DeleteCustomer(ctx, admin, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminDeleteCustomer)
+	_, err = f.customers.GetByID(ctx, customerID)
+	assert.Error(t, err, domain.ErrCustomerNotFound)
+	_, err = f.sessions.Validate(ctx, f.buyer.Session.Token)
+	assert.Error(t, err, ErrInvalidSession)
+
+	// Deleted customers can still be found by support.
+	found, err := f.admin.SearchCustomers(ctx, support, testEmail)
+	assert.NoError(t, err)
+	assert.Equal(t, len(found), 1)
+	assert.True(t, found[0].DeletedAt != nil)
+
+	_, err = f.admin.RestoreCustomer(ctx, support, customerID)
+	assert.Error(t, err, domain.ErrForbidden)
+
+	restored, err := f.admin.RestoreCustomer(ctx, admin, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, restored.ID, customerID)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionAdminRestoreCustomer)
+	assert.Equal(t, f.audit.last().ActorID, admin.CustomerID)
+
+	_, err = f.admin.RestoreCustomer(ctx, admin, customerID)
+	assert.Error(t, err, domain.ErrCustomerNotFound)
+
+	// Deleting an account is sensitive.
+	admin.Session.AuthenticatedAt = time.Now().Add(-time.Hour)
+	err = f.admin.DeleteCustomer(ctx, admin, customerID)
+	assert.Error(t, err, ErrReauthenticationRequired)
+}
+
+func TestAdminService_RestoreCustomerWithTakenEmail(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	admin := f.principal(t, "admin@brokedaear.com", domain.RoleAdmin)
+	customerID := f.buyer.Customer.ID
+
+	err := f.admin.DeleteCustomer(ctx, admin, customerID)
+	assert.NoError(t, err)
+
+	// A deleted account does not block signing up again.
+	again, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.NotEqual(t, again.Customer.ID, customerID)
+
+	_, err = f.admin.RestoreCustomer(ctx, admin, customerID)
+	assert.Error(t, err, domain.ErrEmailTaken)
+	assert.Equal(t, f.audit.last().Reason, "email_taken")
+}
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// auditRecorder records audit events. Recording must never fail the action
+// being audited, so implementations are responsible for handling their own
+// errors.
+type auditRecorder interface {
+	Record(ctx context.Context, event domain.AuditEvent)
+}
+
+// logAuditRecorder writes audit events as structured log lines.
+type logAuditRecorder struct {
+	logger loggers.Logger
+}
+
+func newLogAuditRecorder(logger loggers.Logger) logAuditRecorder {
+	return logAuditRecorder{logger: logger}
+}
+
+func (l logAuditRecorder) Record(_ context.Context, event domain.AuditEvent) {
+	l.logger.Info(
+		"audit",
+		"action", event.Action.String(),
+		"outcome", event.Outcome.String(),
+		"actor_id", event.ActorID,
+		"customer_id", event.CustomerID,
+		"target_type", event.TargetType.String(),
+		"target_id", event.TargetID,
+		"reason", event.Reason,
+		"request_id", event.RequestID,
+		"trace_id", event.TraceID,
+		"occurred_at", event.OccurredAt,
+	)
+}
+
+// auditRecorder returns the recorder of the services created with the base.
+// It logs events and, once the base uses an audit log, appends them to it.
+func (s *ServiceBase) auditRecorder() auditRecorder {
+	return baseAuditRecorder{base: s}
+}
+
+// baseAuditRecorder records audit events as configured on a ServiceBase.
+// Events are tagged with the request and trace they were recorded in.
+type baseAuditRecorder struct {
+	base *ServiceBase
+}
+
+func (b baseAuditRecorder) Record(ctx context.Context, event domain.AuditEvent) {
+	if event.RequestID == "" {
+		event.RequestID = domain.RequestIDFromContext(ctx)
+	}
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if event.TraceID == "" && spanCtx.HasTraceID() {
+		event.TraceID = spanCtx.TraceID().String()
+	}
+	newLogAuditRecorder(b.base.logger).Record(ctx, event)
+	if b.base.auditLog != nil {
+		b.base.auditLog.Record(ctx, event)
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// auditLogRepository stores the append-only audit log.
+type auditLogRepository interface {
+	// Append adds an event to the end of the log, chained to the last entry.
+	Append(ctx context.Context, event domain.AuditEvent) (*domain.AuditEntry, error)
+	// List retrieves up to filter.Limit entries a filter matches, most
+	// recent first.
+	List(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEntry, error)
+	// ListAfter retrieves up to limit entries that follow a sequence, in
+	// order.
+	ListAfter(ctx context.Context, sequence int64, limit int) ([]domain.AuditEntry, error)
+}
+
+const (
+	// DefaultAuditQueryLimit is the number of entries a query returns when it
+	// sets no limit.
+	DefaultAuditQueryLimit = 100
+	// MaxAuditQueryLimit is the largest number of entries a query returns.
+	MaxAuditQueryLimit = 1000
+
+	// auditVerifyBatch is the number of entries verified at a time.
+	auditVerifyBatch = 1000
+)
+
+// AuditLogService keeps a tamper-evident log of security relevant actions.
+// Entries are chained by hash, so that deleting or altering one is detected
+// by Verify. Services append to it once their ServiceBase uses it, see
+// ServiceBase.UseAuditLog.
+type AuditLogService struct {
+	*ServiceBase
+	repo  auditLogRepository
+	authz *AuthorizationService
+	audit auditRecorder
+}
+
+// NewAuditLogService creates a new AuditLogService.
+func NewAuditLogService(
+	svcBase *ServiceBase,
+	repo auditLogRepository,
+	authz *AuthorizationService,
+) *AuditLogService {
+	return &AuditLogService{
+		ServiceBase: svcBase,
+		repo:        repo,
+		authz:       authz,
+		audit:       svcBase.auditRecorder(),
+	}
+}
+
+// Record appends an event to the audit log. Like every audit recorder, it
+// never fails: errors are logged.
+func (a *AuditLogService) Record(ctx context.Context, event domain.AuditEvent) {
+	_, err := a.repo.Append(ctx, event)
+	if err != nil {
+		a.logger.Error("failed to append to audit log", "action", event.Action.String(), "error", err)
+	}
+}
+
+// Query returns the entries of the audit log a filter matches, most recent
+// first. The principal must be allowed to view the audit log. The filter
+// returns DefaultAuditQueryLimit entries if it sets no limit, and at most
+// MaxAuditQueryLimit.
+func (a *AuditLogService) Query(
+	ctx context.Context,
+	principal *domain.Principal,
+	filter domain.AuditFilter,
+) ([]domain.AuditEntry, error) {
+	err := a.authz.Authorize(ctx, principal, domain.AccessRequest{
+		Permission: domain.PermissionAuditView,
+		OwnerID:    "",
+		Amount:     0,
+	})
+	if err != nil {
+		a.audit.Record(ctx, domain.NewActorAuditEvent(
+			domain.AuditActionAuditQuery, domain.AuditOutcomeFailure, actorOf(principal), filter.CustomerID, "forbidden",
+		))
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditQueryLimit
+	}
+	filter.Limit = min(filter.Limit, MaxAuditQueryLimit)
+
+	entries, err := a.repo.List(ctx, filter)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query audit log")
+	}
+	a.audit.Record(ctx, domain.NewActorAuditEvent(
+		domain.AuditActionAuditQuery, domain.AuditOutcomeSuccess, principal.CustomerID, filter.CustomerID, "",
+	))
+	return entries, nil
+}
+
+// AuditVerification is the result of a verification of the audit log.
+type AuditVerification struct {
+	// Entries is the number of verified entries.
+	Entries int64
+	// Head is the last entry of the log, or nil if the log is empty.
+	// Keeping its sequence and hash somewhere else lets a later
+	// verification detect entries deleted from the end of the log, which
+	// the chain alone cannot tell apart from entries never written.
+	Head *domain.AuditEntry
+}
+
+// Verify walks the whole audit log and checks its hash chain. It returns an
+// error wrapping domain.ErrAuditChainBroken, such as a
+// *domain.AuditChainError, if an entry was deleted or altered. If head is
+// not nil, the log must still contain an entry with its sequence and hash,
+// such as the head of an earlier verification.
+func (a *AuditLogService) Verify(ctx context.Context, head *domain.AuditEntry) (*AuditVerification, error) {
+	result := &AuditVerification{Entries: 0, Head: nil}
+	headFound := head == nil
+	for {
+		entries, err := a.repo.ListAfter(ctx, result.lastSequence(), auditVerifyBatch)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read audit log")
+		}
+		if len(entries) == 0 {
+			break
+		}
+		err = domain.VerifyAuditChain(result.Head, entries)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if head != nil && e.Sequence == head.Sequence {
+				if e.Hash != head.Hash {
+					return nil, &domain.AuditChainError{Sequence: e.Sequence, Reason: "entry does not match the expected head"}
+				}
+				headFound = true
+			}
+		}
+		result.Entries += int64(len(entries))
+		result.Head = &entries[len(entries)-1]
+	}
+	if !headFound {
+		return nil, &domain.AuditChainError{Sequence: head.Sequence, Reason: "entry is missing"}
+	}
+	return result, nil
+}
+
+func (v *AuditVerification) lastSequence() int64 {
+	if v.Head == nil {
+		return 0
+	}
+	return v.Head.Sequence
+}
+
+// actorOf returns the ID of the customer behind a principal, or an empty
+// string if there is none.
+func actorOf(principal *domain.Principal) string {
+	if principal == nil {
+		return ""
+	}
+	return principal.CustomerID
+}
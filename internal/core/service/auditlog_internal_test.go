@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+type auditLogFixture struct {
+	authFixture
+	log  *AuditLogService
+	repo *memory.AuditLogRepository
+}
+
+func newAuditLogFixture(t *testing.T) auditLogFixture {
+	t.Helper()
+	f := newAuthFixture(t)
+	repo := memory.NewAuditLogRepository()
+	log := NewAuditLogService(NewServiceBase(test.NewMockLogger(), nil), repo, f.authz)
+	log.audit = f.audit
+	return auditLogFixture{authFixture: f, log: log, repo: repo}
+}
+
+// principal signs a customer up with roles and returns their principal.
+func (f auditLogFixture) principal(t *testing.T, email string, roles ...domain.Role) *domain.Principal {
+	t.Helper()
+	result := f.signUpWithRoles(t, email, roles...)
+	principal, err := f.authz.Principal(context.Background(), result.Session)
+	assert.NoError(t, err)
+	return principal
+}
+
+// record appends an event by a support agent on a customer to the log.
+func (f auditLogFixture) record(t *testing.T, actorID, customerID string) {
+	t.Helper()
+	f.log.Record(context.Background(), domain.NewActorAuditEvent(
+		domain.AuditActionAdminView, domain.AuditOutcomeSuccess, actorID, customerID, "",
+	))
+}
+
+func TestAuditLogService_RecordThroughServiceBase(t *testing.T) {
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	repo := memory.NewAuditLogRepository()
+	base.UseAuditLog(NewAuditLogService(base, repo, nil))
+
+	ctx := domain.ContextWithRequestID(context.Background(), "req-42")
+	base.auditRecorder().Record(ctx, domain.NewAuditEvent(domain.AuditActionSignIn, domain.AuditOutcomeSuccess, "kai", ""))
+
+	entries, err := repo.ListAfter(ctx, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, len(entries), 1)
+	assert.Equal(t, entries[0].Sequence, int64(1))
+	assert.Equal(t, entries[0].PrevHash, domain.GenesisAuditHash)
+	assert.Equal(t, entries[0].Event.CustomerID, "kai")
+	assert.Equal(t, entries[0].Event.RequestID, "req-42")
+}
+
+func TestAuditLogService_Query(t *testing.T) {
+	ctx := context.Background()
+	f := newAuditLogFixture(t)
+	admin := f.principal(t, "admin@brokedaear.com", domain.RoleAdmin)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	f.record(t, "lea", "kai")
+	f.record(t, "lea", "ada")
+	f.record(t, "max", "kai")
+
+	tests := []struct {
+		test.CaseBase
+		principal *domain.Principal
+		filter    domain.AuditFilter
+		wantSeqs  []int64
+	}{
+		{CaseBase: test.NewCaseBase("all", nil, false), principal: admin, wantSeqs: []int64{3, 2, 1}},
+		{
+			CaseBase:  test.NewCaseBase("by actor", nil, false),
+			principal: admin,
+			filter:    domain.AuditFilter{ActorID: "lea"},
+			wantSeqs:  []int64{2, 1},
+		},
+		{
+			CaseBase:  test.NewCaseBase("by customer", nil, false),
+			principal: admin,
+			filter:    domain.AuditFilter{CustomerID: "kai"},
+			wantSeqs:  []int64{3, 1},
+		},
+		{
+			CaseBase:  test.NewCaseBase("paged", nil, false),
+			principal: admin,
+			filter:    domain.AuditFilter{BeforeSequence: 3, Limit: 1},
+			wantSeqs:  []int64{2},
+		},
+		{CaseBase: test.NewCaseBase("support", domain.ErrForbidden, true), principal: support},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				got, err := f.log.Query(ctx, tt.principal, tt.filter)
+				assert.Equal(t, f.audit.last().Action, domain.AuditActionAuditQuery)
+				assert.Equal(t, f.audit.last().ActorID, tt.principal.CustomerID)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeFailure)
+					return
+				}
+				assert.NoError(t, err)
+				seqs := make([]int64, len(got))
+				for i, e := range got {
+					seqs[i] = e.Sequence
+				}
+				assert.True(t, slices.Equal(seqs, tt.wantSeqs))
+			},
+		)
+	}
+}
+
+func TestAuditLogService_Verify(t *testing.T) {
+	ctx := context.Background()
+	f := newAuditLogFixture(t)
+
+	result, err := f.log.Verify(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Entries, int64(0))
+	assert.True(t, result.Head == nil)
+
+	for range 3 {
+		f.record(t, "lea", "kai")
+	}
+	result, err = f.log.Verify(ctx, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Entries, int64(3))
+	assert.Equal(t, result.Head.Sequence, int64(3))
+	head := *result.Head
+
+	f.record(t, "lea", "kai")
+	result, err = f.log.Verify(ctx, &head)
+	assert.NoError(t, err)
+	assert.Equal(t, result.Entries, int64(4))
+
+	altered := head
+	altered.Hash = domain.GenesisAuditHash
+	_, err = f.log.Verify(ctx, &altered)
+	assert.Error(t, err, domain.ErrAuditChainBroken)
+
+	// The entries after the head of a previous run were deleted.
+	missing := head
+	missing.Sequence = 7
+	_, err = f.log.Verify(ctx, &missing)
+	assert.Error(t, err, domain.ErrAuditChainBroken)
+}
@@ -3,3 +3,578 @@
 // SPDX-License-Identifier: Apache-2.0
 
 package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// AuthResult is the result of a successful authentication. The session
+// carries the token that must be handed to the client. The token is only
+// available at issuance.
+//
+// If the customer has MFA enabled, a sign in with a password only results in
+// an MFA challenge instead of a session. The customer completes the sign in
+// by answering the challenge with a code, see AuthService.CompleteSignIn.
+type AuthResult struct {
+	Customer     *domain.Customer
+	Session      *domain.UserSession
+	MFAChallenge string
+}
+
+// AuthService authenticates customers. It owns sign-up, sign-in, sign-out and
+// the issuance of sessions. Every authentication outcome, successful or not,
+// is recorded as an audit event.
+type AuthService struct {
+	*ServiceBase
+	customers    customerRepository
+	sessions     *SessionService
+	verification *EmailVerificationService
+	passwords    PasswordPolicy
+	pwnChecker   PwnChecker[[]string]
+	throttle     *LoginThrottleService
+	mfa          *MFAService
+	audit        auditRecorder
+}
+
+// NewAuthService creates a new AuthService. New passwords are checked with
+// the pwn checker, such as one made with NewPwnCheckChain, sign in attempts
+// are throttled with the throttle, and customers with MFA enabled are asked
+// for a code by mfa.
+func NewAuthService(
+	svcBase *ServiceBase,
+	customers customerRepository,
+	sessions *SessionService,
+	verification *EmailVerificationService,
+	passwords PasswordPolicy,
+	pwnChecker PwnChecker[[]string],
+	throttle *LoginThrottleService,
+	mfa *MFAService,
+) *AuthService {
+	return &AuthService{
+		ServiceBase:  svcBase,
+		customers:    customers,
+		sessions:     sessions,
+		verification: verification,
+		passwords:    passwords,
+		pwnChecker:   pwnChecker,
+		throttle:     throttle,
+		mfa:          mfa,
+		audit:        svcBase.auditRecorder(),
+	}
+}
+
+// SignIn signs a customer into the application with their email and
+// password. On success, the customer's last login time is updated and a new
+// session is issued. Customers who sign in through an identity provider do
+// so with OAuthService.
+//
+// Failed attempts are throttled per account and per IP address. An attempt
+// made too soon after too many failures returns a LoginThrottledError, which
+// says how long to wait. A customer with MFA enabled is not issued a session
+// yet, but an MFA challenge to complete the sign in with.
+//
+// A stored hash made with other parameters than the customer repository
+// hashes new passwords with is replaced once the password is verified, so
+// that raising the parameters upgrades every hash as customers sign in.
+func (a *AuthService) SignIn(
+	ctx context.Context,
+	email, password string,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	if email == "" {
+		a.signInFailed(ctx, "", "missing_credentials")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	now := time.Now().UTC()
+	err := a.throttle.check(ctx, email, client.IP, now)
+	if errors.Is(err, ErrTooManyAttempts) {
+		a.signInFailed(ctx, "", "throttled")
+		return nil, err
+	}
+	if err != nil {
+		a.logger.Error("failed to check login throttle", "error", err)
+		a.signInFailed(ctx, "", "throttle_unavailable")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	customer, err := a.customers.GetByEmail(ctx, email)
+	if err != nil {
+		// Hash the password anyway, so that an unknown email takes as long
+		// to reject as a wrong password.
+		rejectPassword(password)
+		if errors.Is(err, domain.ErrCustomerNotFound) {
+			a.throttle.failed(ctx, email, client.IP, nil, now)
+		}
+		a.signInFailed(ctx, "", reasonFromLookup(err))
+		return nil, ErrCustomerLoginFailed
+	}
+	if !customer.HasPassword() {
+		rejectPassword(password)
+		a.throttle.failed(ctx, email, client.IP, customer, now)
+		a.signInFailed(ctx, customer.ID, "password_not_set")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	storedHash := string(customer.PasswordHash)
+
+	ok, err := crypto.ValidatePassword(password, storedHash)
+	if err != nil {
+		a.logger.Error("failed to validate password", "customer_id", customer.ID, "error", err)
+		a.signInFailed(ctx, customer.ID, "invalid_password_hash")
+		return nil, ErrCustomerLoginFailed
+	}
+	if !ok {
+		a.logger.Warn("incorrect user password", "customer_id", customer.ID)
+		a.throttle.failed(ctx, email, client.IP, customer, now)
+		a.signInFailed(ctx, customer.ID, "invalid_credentials")
+		return nil, ErrCustomerLoginFailed
+	}
+	a.rehashPassword(ctx, customer, password)
+
+	enabled, err := a.mfa.enabled(ctx, customer.ID)
+	if err != nil {
+		a.logger.Error("failed to check mfa", "customer_id", customer.ID, "error", err)
+		a.signInFailed(ctx, customer.ID, "repository_error")
+		return nil, ErrCustomerLoginFailed
+	}
+	if enabled {
+		// The failures of the account are only forgotten once the sign in is
+		// complete, so that guessing codes is throttled like guessing
+		// passwords.
+		challenge, err := a.mfa.challenge(ctx, customer)
+		if err != nil {
+			a.logger.Error("failed to issue mfa challenge", "customer_id", customer.ID, "error", err)
+			a.signInFailed(ctx, customer.ID, "challenge_issue_failed")
+			return nil, ErrCustomerLoginFailed
+		}
+		return &AuthResult{Customer: customer, Session: nil, MFAChallenge: challenge}, nil
+	}
+
+	return a.completeSignIn(ctx, customer, client, now)
+}
+
+// CompleteSignIn completes the sign in of a customer with MFA enabled, who
+// answers the challenge returned by SignIn with a code from their
+// authenticator app or a recovery code. A code can only be used once. Wrong
+// codes are throttled like wrong passwords, and the challenge can be
+// answered again until it expires.
+func (a *AuthService) CompleteSignIn(
+	ctx context.Context,
+	challenge, code string,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	fail := func(customerID, reason string, err error) (*AuthResult, error) {
+		a.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionMFAChallenge, domain.AuditOutcomeFailure, customerID, reason,
+		))
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	token, err := findToken(ctx, a.mfa.tokens, challenge, domain.TokenPurposeMFAChallenge, now)
+	if err != nil {
+		if isRepositoryError(err) {
+			a.logger.Error("failed to get mfa challenge", "error", err)
+		}
+		return fail(tokenUserID(token), tokenFailureReason(err), publicTokenError(err))
+	}
+	customer, err := a.customers.GetByID(ctx, token.UserID)
+	if err != nil {
+		return fail(token.UserID, tokenFailureReason(err), ErrCustomerLoginFailed)
+	}
+
+	err = a.throttle.check(ctx, customer.Email, client.IP, now)
+	if errors.Is(err, ErrTooManyAttempts) {
+		return fail(customer.ID, "throttled", err)
+	}
+	if err != nil {
+		a.logger.Error("failed to check login throttle", "error", err)
+		return fail(customer.ID, "throttle_unavailable", ErrCustomerLoginFailed)
+	}
+
+	reason, err := a.mfa.verify(ctx, customer.ID, code, now)
+	if errors.Is(err, ErrInvalidMFACode) {
+		a.throttle.failed(ctx, customer.Email, client.IP, customer, now)
+		return fail(customer.ID, reason, err)
+	}
+	if err != nil {
+		a.logger.Error("failed to verify mfa code", "customer_id", customer.ID, "error", err)
+		return fail(customer.ID, reason, ErrCustomerLoginFailed)
+	}
+	err = a.mfa.tokens.MarkUsed(ctx, token.ID, now)
+	if err != nil {
+		return fail(customer.ID, tokenFailureReason(err), publicTokenError(err))
+	}
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionMFAChallenge, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+
+	return a.completeSignIn(ctx, customer, client, now)
+}
+
+// completeSignIn signs in a customer who proved who they are.
+func (a *AuthService) completeSignIn(
+	ctx context.Context,
+	customer *domain.Customer,
+	client domain.ClientInfo,
+	now time.Time,
+) (*AuthResult, error) {
+	a.throttle.succeeded(ctx, customer.Email)
+
+	customer.LastLoginAt = now
+	err := a.customers.UpdateLastLogin(ctx, customer)
+	if err != nil {
+		// The customer has proven who they are, so a failure to record the
+		// login time should not lock them out.
+		a.logger.Warn("failed to update last login", "customer_id", customer.ID, "error", err)
+	}
+
+	session, err := a.issueSession(ctx, customer, client)
+	if err != nil {
+		a.signInFailed(ctx, customer.ID, "session_issue_failed")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignIn, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+
+	return &AuthResult{Customer: customer, Session: session, MFAChallenge: ""}, nil
+}
+
+// rehashPassword replaces the stored hash of a customer who just proved
+// their password, if the hash was made with other parameters than the
+// repository hashes with. A failure only leaves the old hash in place.
+func (a *AuthService) rehashPassword(ctx context.Context, customer *domain.Customer, password string) {
+	if !crypto.NeedsRehash(string(customer.PasswordHash), a.customers.HashParams()) {
+		return
+	}
+	// The repository hashes the password before storing it.
+	upgraded := *customer
+	upgraded.PasswordHash = []byte(password)
+	err := a.customers.UpdatePassword(ctx, &upgraded)
+	if err != nil {
+		a.logger.Warn("failed to rehash password", "customer_id", customer.ID, "error", err)
+		return
+	}
+	a.logger.Info("rehashed password", "customer_id", customer.ID)
+}
+
+// dummyPasswordHash is verified in place of the password hash of a customer
+// who cannot sign in with a password.
+var dummyPasswordHash = sync.OnceValues(func() (string, error) { //nolint:gochecknoglobals // makes more sense like this.
+	password, err := crypto.GenerateRandomString()
+	if err != nil {
+		return "", err
+	}
+	return crypto.GenerateHashedPassword([]byte(password))
+})
+
+// rejectPassword verifies a password against a dummy hash, so that a sign
+// in without a stored password does as much work as one with a wrong
+// password, and the time it takes does not tell whether an account exists.
+func rejectPassword(password string) {
+	hash, err := dummyPasswordHash()
+	if err != nil {
+		return
+	}
+	_, _ = crypto.ValidatePassword(password, hash)
+}
+
+func (a *AuthService) signInFailed(ctx context.Context, customerID, reason string) {
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignIn, domain.AuditOutcomeFailure, customerID, reason,
+	))
+}
+
+// SignOut signs a customer out by invalidating the session the token
+// belongs to.
+func (a *AuthService) SignOut(ctx context.Context, token string) error {
+	session, err := a.sessions.Revoke(ctx, token)
+	if err != nil {
+		customerID := ""
+		if session != nil {
+			customerID = session.UserID
+		}
+		a.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionSignOut, domain.AuditOutcomeFailure, customerID, "revoke_failed",
+		))
+		return err
+	}
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignOut, domain.AuditOutcomeSuccess, session.UserID, "",
+	))
+	return nil
+}
+
+// SignUp creates a user account for a possible customer with an email and a
+// password, and signs them in. It returns the new customer with their
+// session, and an error, if there is one.
+//
+// SignUp has several responsibilities. The first order of business is to
+// check if a customer with the email already exists. If the customer does not
+// exist:
+//  1. The password field is checked against the password policy. The
+//     password must be long enough but not longer than 256 bytes, must be
+//     hard enough to guess, and cannot contain the email address or the name
+//     of the store. A rejected password returns a PasswordRejectedError with
+//     feedback for the customer. Also, the password cannot be pwned--that
+//     means it cannot exist in the "haveibeenpwned" database of leaked
+//     password hashes.
+//  2. The email is validated. The characters preceding the `@` symbol cannot
+//     longer than 256 bytes.
+//  3. If all is well, the new user is inserted into the repository.
+//  4. A verification email is sent. The customer cannot make purchases until
+//     they have confirmed their email address.
+//
+// Customers who sign up through an identity provider, such as Auth0 or
+// GitHub, do so with OAuthService, which verifies the identity with the
+// provider itself.
+func (a *AuthService) SignUp(
+	ctx context.Context,
+	email, password string,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	if email == "" {
+		a.signUpFailed(ctx, "", "missing_credentials")
+		return nil, ErrEmailEmpty
+	}
+
+	// First, check if the user exists or not. If the user exists, don't
+	// allow the sign up.
+	customer, err := a.customers.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		a.signUpFailed(ctx, customer.ID, "customer_exists")
+		return nil, ErrCustomerAlreadyExists
+	case !errors.Is(err, domain.ErrCustomerNotFound):
+		a.logger.Error("signup failed", "error", err)
+		a.signUpFailed(ctx, "", "repository_error")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	reason, err := a.checkNewPassword(ctx, password, email)
+	if err != nil {
+		a.logger.Error("signup failed", "error", err)
+		a.signUpFailed(ctx, "", reason)
+		return nil, err
+	}
+
+	customer, err = domain.NewCustomer(email, "", []byte(password))
+	if err != nil {
+		a.logger.Error("signup failed", "error", err)
+		a.signUpFailed(ctx, "", "invalid_customer")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	err = a.insertCustomer(ctx, a.customers, customer, "password")
+	if errors.Is(err, domain.ErrEmailTaken) {
+		// Someone signed up with the address since it was checked.
+		a.signUpFailed(ctx, "", "customer_exists")
+		return nil, ErrCustomerAlreadyExists
+	}
+	if err != nil {
+		a.logger.Error("signup failed", "error", err)
+		a.signUpFailed(ctx, "", "repository_error")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignUp, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+
+	// Whether the email address exists is only known once the customer
+	// follows the link in the verification email.
+	err = a.verification.Send(ctx, customer)
+	if err != nil {
+		// The customer can ask for another verification email.
+		a.logger.Error("failed to send verification email", "customer_id", customer.ID, "error", err)
+	}
+
+	session, err := a.issueSession(ctx, customer, client)
+	if err != nil {
+		// The account exists at this point, so the customer can still sign
+		// in normally.
+		return &AuthResult{Customer: customer, Session: nil, MFAChallenge: ""}, err
+	}
+
+	return &AuthResult{Customer: customer, Session: session, MFAChallenge: ""}, nil
+}
+
+// checkNewPassword checks whether a password may be used for the account of
+// a customer with an email address. It returns an audit reason along with an
+// error if the password is rejected. The password policy is checked first,
+// since it is cheap and does not leave the process.
+func (a *AuthService) checkNewPassword(ctx context.Context, password, email string) (string, error) {
+	feedback := a.passwords.Check(password, email)
+	if len(feedback) > 0 {
+		err := &PasswordRejectedError{Feedback: feedback}
+		return err.reason(), err
+	}
+
+	pwned, err := a.pwnChecker.Check(ctx, password)
+	if err != nil {
+		return "pwn_check_failed", errors.Wrap(ErrCustomerSignUpFailed, err.Error())
+	}
+	if pwned {
+		return "password_pwned", ErrCustomerPasswordFailed
+	}
+	return "", nil
+}
+
+// Reauthenticate asks a signed-in customer to prove their credentials again,
+// which is required before sensitive operations such as deleting their account
+// or changing their password.
+// Customers without a password re-authenticate by signing in with their
+// identity provider or a passkey again, which issues a fresh session.
+func (a *AuthService) Reauthenticate(
+	ctx context.Context,
+	session *domain.UserSession,
+	password string,
+) error {
+	customer, err := a.verifyPassword(ctx, session.UserID, password)
+	if err != nil {
+		a.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionReauthenticate, domain.AuditOutcomeFailure, session.UserID, "invalid_credentials",
+		))
+		return err
+	}
+	err = a.sessions.MarkAuthenticated(ctx, session)
+	if err != nil {
+		return err
+	}
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionReauthenticate, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return nil
+}
+
+// ChangePassword changes the password of a signed-in customer. The customer
+// must have authenticated recently and must provide their current password.
+// Every other session of the customer is revoked, so that someone who
+// learned the old password is signed out.
+func (a *AuthService) ChangePassword(
+	ctx context.Context,
+	session *domain.UserSession,
+	currentPassword, newPassword string,
+) error {
+	fail := func(reason string, err error) error {
+		a.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionPasswordChange, domain.AuditOutcomeFailure, session.UserID, reason,
+		))
+		return err
+	}
+
+	err := a.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return fail("reauthentication_required", err)
+	}
+
+	customer, err := a.verifyPassword(ctx, session.UserID, currentPassword)
+	if err != nil {
+		return fail("invalid_credentials", err)
+	}
+
+	reason, err := a.checkNewPassword(ctx, newPassword, customer.Email)
+	if err != nil {
+		return fail(reason, err)
+	}
+
+	// The repository hashes the password before storing it.
+	customer.PasswordHash = []byte(newPassword)
+	err = a.customers.UpdatePassword(ctx, customer)
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to change password"))
+	}
+
+	err = a.sessions.RevokeOthers(ctx, session)
+	if err != nil {
+		a.logger.Error("failed to revoke sessions after password change", "customer_id", customer.ID, "error", err)
+	}
+
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionPasswordChange, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return nil
+}
+
+// verifyPassword checks the password of a customer given their ID.
+func (a *AuthService) verifyPassword(
+	ctx context.Context,
+	customerID, password string,
+) (*domain.Customer, error) {
+	customer, err := a.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get customer")
+	}
+	if !customer.HasPassword() {
+		return nil, ErrInvalidCredentials
+	}
+	ok, err := crypto.ValidatePassword(password, string(customer.PasswordHash))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to validate password")
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	return customer, nil
+}
+
+func (a *AuthService) signUpFailed(ctx context.Context, customerID, reason string) {
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignUp, domain.AuditOutcomeFailure, customerID, reason,
+	))
+}
+
+// issueSession creates a new session for a customer.
+func (a *AuthService) issueSession(
+	ctx context.Context,
+	customer *domain.Customer,
+	client domain.ClientInfo,
+) (*domain.UserSession, error) {
+	session, err := a.sessions.NewSession(ctx, customer.ID, client)
+	if err != nil {
+		a.logger.Error("failed to issue session", "customer_id", customer.ID, "error", err)
+		a.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionSessionIssued, domain.AuditOutcomeFailure, customer.ID, "session_store_failed",
+		))
+		return nil, err
+	}
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSessionIssued, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return session, nil
+}
+
+// reasonFromLookup maps a customer lookup error to an audit reason.
+func reasonFromLookup(err error) string {
+	switch {
+	case errors.Is(err, ErrEmailAndAuthEmpty):
+		return "missing_credentials"
+	case errors.Is(err, domain.ErrCustomerNotFound):
+		return "unknown_customer"
+	default:
+		return "repository_error"
+	}
+}
+
+var (
+	ErrCustomerLoginFailed    = errors.New("customer login failed")
+	ErrCustomerSignUpFailed   = errors.New("customer signup failed")
+	ErrEmailAndAuthEmpty      = errors.New("email and auth0ID both zero value")
+	ErrEmailEmpty             = errors.New("email is empty")
+	ErrCustomerAlreadyExists  = errors.New("customer already exists")
+	ErrCustomerPasswordFailed = errors.New("password found in database leak")
+	ErrInvalidCredentials     = errors.New("invalid credentials")
+	ErrPasswordTooLong        = errors.New("password too long")
+	ErrPasswordTooShort       = errors.New("password too short")
+	ErrPasswordTooWeak        = errors.New("password too weak")
+	ErrTooManyAttempts        = errors.New("too many sign in attempts")
+)
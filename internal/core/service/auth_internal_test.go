@@ -0,0 +1,816 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/test"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeCustomerRepository mimics the postgres CustomerRepository, including
+// hashing the password on insert and soft deleting customers.
+type fakeCustomerRepository struct {
+	mu        sync.Mutex
+	customers map[string]*domain.Customer
+	// params are the parameters passwords are hashed with.
+	params crypto.Argon2Params
+}
+
+func newFakeCustomerRepository() *fakeCustomerRepository {
+	return &fakeCustomerRepository{
+		mu:        sync.Mutex{},
+		customers: make(map[string]*domain.Customer),
+		params:    crypto.DefaultArgon2Params(),
+	}
+}
+
+func (f *fakeCustomerRepository) Insert(_ context.Context, customer *domain.Customer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.emailTaken(customer.Email) {
+		return domain.ErrEmailTaken
+	}
+	c := *customer
+	if customer.HasPassword() {
+		hash, err := crypto.GenerateHashedPasswordWithParams(customer.PasswordHash, f.params)
+		if err != nil {
+			return err
+		}
+		c.PasswordHash = []byte(hash)
+	}
+	f.customers[c.ID] = &c
+	return nil
+}
+
+func (f *fakeCustomerRepository) HashParams() crypto.Argon2Params {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.params
+}
+
+func (f *fakeCustomerRepository) Delete(_ context.Context, customer *domain.Customer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.customers[customer.ID]
+	if !ok || c.DeletedAt != nil {
+		return domain.ErrCustomerNotFound
+	}
+	now := time.Now().UTC()
+	c.DeletedAt = &now
+	return nil
+}
+
+func (f *fakeCustomerRepository) UpdateInformation(_ context.Context, customer *domain.Customer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := *customer
+	f.customers[c.ID] = &c
+	return nil
+}
+
+func (f *fakeCustomerRepository) UpdatePassword(_ context.Context, customer *domain.Customer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.customers[customer.ID]
+	if !ok {
+		return domain.ErrCustomerNotFound
+	}
+	hash, err := crypto.GenerateHashedPasswordWithParams(customer.PasswordHash, f.params)
+	if err != nil {
+		return err
+	}
+	c.PasswordHash = []byte(hash)
+	return nil
+}
+
+func (f *fakeCustomerRepository) UpdateLastLogin(_ context.Context, customer *domain.Customer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.customers[customer.ID]
+	if !ok {
+		return domain.ErrCustomerNotFound
+	}
+	c.LastLoginAt = customer.LastLoginAt
+	return nil
+}
+
+func (f *fakeCustomerRepository) GetByID(_ context.Context, id string) (*domain.Customer, error) {
+	return f.find(func(c *domain.Customer) bool { return c.ID == id })
+}
+
+func (f *fakeCustomerRepository) GetByOAuthID(_ context.Context, id string) (*domain.Customer, error) {
+	return f.find(func(c *domain.Customer) bool { return c.AuthZeroUserID == id })
+}
+
+func (f *fakeCustomerRepository) GetByEmail(_ context.Context, email string) (*domain.Customer, error) {
+	return f.find(func(c *domain.Customer) bool { return c.Email == email })
+}
+
+func (f *fakeCustomerRepository) SearchByEmail(
+	_ context.Context,
+	query string,
+	limit int,
+) ([]*domain.Customer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	customers := make([]*domain.Customer, 0)
+	for _, c := range f.customers {
+		if strings.Contains(c.Email, strings.ToLower(query)) {
+			customer := *c
+			customers = append(customers, &customer)
+		}
+	}
+	slices.SortFunc(customers, func(a, b *domain.Customer) int {
+		return strings.Compare(a.Email, b.Email)
+	})
+	return customers[:min(limit, len(customers))], nil
+}
+
+func (f *fakeCustomerRepository) GetByIDIncludingDeleted(_ context.Context, id string) (*domain.Customer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.customers[id]
+	if !ok {
+		return nil, domain.ErrCustomerNotFound
+	}
+	customer := *c
+	return &customer, nil
+}
+
+func (f *fakeCustomerRepository) Restore(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.customers[id]
+	if !ok || c.DeletedAt == nil || c.ErasedAt != nil {
+		return domain.ErrCustomerNotFound
+	}
+	if f.emailTaken(c.Email) {
+		return domain.ErrEmailTaken
+	}
+	c.DeletedAt = nil
+	return nil
+}
+
+// emailTaken mimics the unique index on the email of active customers.
+func (f *fakeCustomerRepository) emailTaken(email string) bool {
+	for _, c := range f.customers {
+		if c.DeletedAt == nil && c.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeCustomerRepository) ListErasable(
+	_ context.Context,
+	deletedBefore time.Time,
+	limit int,
+) ([]*domain.Customer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	customers := make([]*domain.Customer, 0)
+	for _, c := range f.customers {
+		if c.DeletedAt != nil && c.DeletedAt.Before(deletedBefore) && c.ErasedAt == nil {
+			customer := *c
+			customers = append(customers, &customer)
+		}
+	}
+	return customers[:min(limit, len(customers))], nil
+}
+
+func (f *fakeCustomerRepository) Erase(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.customers[id]
+	if !ok || c.DeletedAt == nil || c.ErasedAt != nil {
+		return domain.ErrCustomerNotFound
+	}
+	now := time.Now().UTC()
+	c.Email = domain.ErasedEmail(id)
+	c.EmailVerified = false
+	c.PasswordHash = nil
+	c.AuthZeroUserID = ""
+	c.ErasedAt = &now
+	return nil
+}
+
+func (f *fakeCustomerRepository) find(match func(*domain.Customer) bool) (*domain.Customer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range f.customers {
+		if c.DeletedAt == nil && match(c) {
+			customer := *c
+			return &customer, nil
+		}
+	}
+	return nil, domain.ErrCustomerNotFound
+}
+
+type fakePwnChecker struct {
+	pwned map[string]bool
+	err   error
+}
+
+func (f fakePwnChecker) Check(_ context.Context, password string) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	return f.pwned[password], nil
+}
+
+type recordingAuditor struct {
+	mu     sync.Mutex
+	events []domain.AuditEvent
+}
+
+func (r *recordingAuditor) Record(_ context.Context, event domain.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recordingAuditor) last() domain.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.events[len(r.events)-1]
+}
+
+// recordingMailer keeps every email it is asked to send.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []domain.EmailMessage
+}
+
+func (r *recordingMailer) Send(_ context.Context, msg domain.EmailMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func (r *recordingMailer) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.sent)
+}
+
+func (r *recordingMailer) last() domain.EmailMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sent[len(r.sent)-1]
+}
+
+type authFixture struct {
+	auth         *AuthService
+	sessions     *SessionService
+	customers    *fakeCustomerRepository
+	verification *EmailVerificationService
+	throttle     *LoginThrottleService
+	throttles    *memory.LoginThrottleRepository
+	mfa          *MFAService
+	tokens       *memory.TokenRepository
+	mailer       *recordingMailer
+	audit        *recordingAuditor
+	authz        *AuthorizationService
+	roles        *memory.AuthorizationRepository
+}
+
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	customers := newFakeCustomerRepository()
+	sessions := NewSessionService(base, memory.NewSessionRepository(), DefaultSessionPolicy())
+	tokens := memory.NewTokenRepository()
+	mailer := &recordingMailer{}
+	verification := NewEmailVerificationService(
+		base, customers, tokens, mailer, DefaultEmailVerificationPolicy("https://brokedaear.com/verify"),
+	)
+	throttles := memory.NewLoginThrottleRepository()
+	throttle, err := NewLoginThrottleService(
+		base, throttles, customers, tokens, mailer, DefaultLoginThrottlePolicy("https://brokedaear.com/unlock"),
+	)
+	assert.NoError(t, err)
+	mfa, err := NewMFAService(
+		base, customers, sessions, memory.NewMFARepository(), tokens, DefaultMFAPolicy(testEncryptionKey),
+	)
+	assert.NoError(t, err)
+	auth := NewAuthService(
+		base, customers, sessions, verification, DefaultPasswordPolicy(domain.EnvProduction),
+		fakePwnChecker{pwned: map[string]bool{pwnedTestPassword: true}, err: nil}, throttle, mfa,
+	)
+	roles := memory.NewAuthorizationRepository(domain.DefaultGrants()...)
+	authz := NewAuthorizationService(base, sessions, roles)
+	audit := &recordingAuditor{}
+	auth.audit = audit
+	verification.audit = audit
+	throttle.audit = audit
+	mfa.audit = audit
+	authz.audit = audit
+	return authFixture{
+		auth:         auth,
+		sessions:     sessions,
+		customers:    customers,
+		verification: verification,
+		throttle:     throttle,
+		throttles:    throttles,
+		mfa:          mfa,
+		tokens:       tokens,
+		mailer:       mailer,
+		audit:        audit,
+		authz:        authz,
+		roles:        roles,
+	}
+}
+
+const (
+	testEmail    = "kai@brokedaear.com"
+	testPassword = "correct horse battery staple"
+	// pwnedTestPassword meets the password policy, but is known to the fake
+	// pwn checker.
+	pwnedTestPassword = "Tr0ub4dor&3"
+)
+
+func TestAuthService_SignUpAndSignIn(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	client := domain.ClientInfo{IP: "198.51.100.4", UserAgent: "test"}
+
+	signedUp, err := f.auth.SignUp(ctx, testEmail, testPassword, client)
+	assert.NoError(t, err)
+	assert.NotEqual(t, signedUp.Session.Token, "")
+
+	before := time.Now().UTC()
+	signedIn, err := f.auth.SignIn(ctx, testEmail, testPassword, client)
+	assert.NoError(t, err)
+	assert.Equal(t, signedIn.Customer.ID, signedUp.Customer.ID)
+	assert.NotEqual(t, signedIn.Session.Token, signedUp.Session.Token)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionSignIn)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+
+	stored, err := f.customers.GetByID(ctx, signedIn.Customer.ID)
+	assert.NoError(t, err)
+	assert.False(t, stored.LastLoginAt.Before(before))
+
+	session, err := f.sessions.Validate(ctx, signedIn.Session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, session.UserID, signedIn.Customer.ID)
+	assert.Equal(t, session.CreatedIP, client.IP)
+
+	err = f.auth.SignOut(ctx, signedIn.Session.Token)
+	assert.NoError(t, err)
+	_, err = f.sessions.Validate(ctx, signedIn.Session.Token)
+	assert.Error(t, err, ErrInvalidSession)
+
+	// The sign up session is unaffected by signing out of another session.
+	_, err = f.sessions.Validate(ctx, signedUp.Session.Token)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_SignUpFailures(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	_, err := f.auth.SignUp(ctx, testEmail, pwnedTestPassword, domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerPasswordFailed)
+	assert.Equal(t, f.audit.last().Reason, "password_pwned")
+
+	_, err = f.auth.SignUp(ctx, testEmail, "password123", domain.ClientInfo{})
+	assert.Error(t, err, ErrPasswordTooWeak)
+	assert.Equal(t, f.audit.last().Reason, "password_too_weak")
+
+	_, err = f.auth.SignUp(ctx, "", testPassword, domain.ClientInfo{})
+	assert.Error(t, err, ErrEmailEmpty)
+
+	_, err = f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	_, err = f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerAlreadyExists)
+}
+
+func TestAuthService_SignInFailures(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	_, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		test.CaseBase
+		email    string
+		password string
+	}{
+		{
+			CaseBase: test.NewCaseBase("wrong password", "invalid_credentials", true),
+			email:    testEmail,
+			password: "not the password",
+		},
+		{
+			CaseBase: test.NewCaseBase("unknown customer", "unknown_customer", true),
+			email:    "nobody@brokedaear.com",
+			password: testPassword,
+		},
+		{
+			CaseBase: test.NewCaseBase("missing credentials", "missing_credentials", true),
+			email:    "",
+			password: testPassword,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				_, err := f.auth.SignIn(ctx, tt.email, tt.password, domain.ClientInfo{})
+				assert.Error(t, err, ErrCustomerLoginFailed)
+				assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeFailure)
+				assert.Equal(t, f.audit.last().Reason, tt.Want.(string))
+			},
+		)
+	}
+}
+
+func TestSessionService_ListAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	first, err := f.sessions.NewSession(ctx, "customer", domain.ClientInfo{})
+	assert.NoError(t, err)
+	second, err := f.sessions.NewSession(ctx, "customer", domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	sessions, err := f.sessions.List(ctx, "customer")
+	assert.NoError(t, err)
+	assert.Equal(t, len(sessions), 2)
+
+	err = f.sessions.RevokeByID(ctx, "someone else", first.ID)
+	assert.Error(t, err, ErrInvalidSession)
+
+	err = f.sessions.RevokeByID(ctx, "customer", first.ID)
+	assert.NoError(t, err)
+	_, err = f.sessions.Validate(ctx, first.Token)
+	assert.Error(t, err, ErrInvalidSession)
+	_, err = f.sessions.Validate(ctx, second.Token)
+	assert.NoError(t, err)
+
+	err = f.sessions.RevokeAll(ctx, "customer")
+	assert.NoError(t, err)
+	sessions, err = f.sessions.List(ctx, "customer")
+	assert.NoError(t, err)
+	assert.Equal(t, len(sessions), 0)
+}
+
+func TestSessionService_RejectsForgedSecret(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	session, err := f.sessions.NewSession(ctx, "customer", domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	_, err = f.sessions.Validate(ctx, session.ID+".forged")
+	assert.Error(t, err, ErrInvalidSession)
+	_, err = f.sessions.Validate(ctx, "garbage")
+	assert.Error(t, err, ErrInvalidSession)
+}
+
+func TestReaper_Reap(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewSessionRepository()
+	base := NewServiceBase(test.NewMockLogger(), nil)
+
+	expired, err := domain.NewUserSession("customer", domain.ClientInfo{}, -time.Minute, time.Hour)
+	assert.NoError(t, err)
+	live, err := domain.NewUserSession("customer", domain.ClientInfo{}, time.Hour, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Insert(ctx, expired))
+	assert.NoError(t, repo.Insert(ctx, live))
+
+	reaper := NewReaper(base, "sessions", repo, time.Hour)
+	n, err := reaper.Reap(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(1))
+
+	_, err = repo.GetByID(ctx, live.ID)
+	assert.NoError(t, err)
+
+	reaper.Start(ctx)
+	assert.NoError(t, reaper.Close())
+}
+
+func TestSessionService_SlidingRenewal(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewSessionRepository()
+	policy := DefaultSessionPolicy()
+	sessions := NewSessionService(NewServiceBase(test.NewMockLogger(), nil), repo, policy)
+
+	session, err := sessions.NewSession(ctx, "customer", domain.ClientInfo{})
+	assert.NoError(t, err)
+	oldToken := session.Token
+
+	// Fresh sessions are not renewed.
+	validated, err := sessions.Validate(ctx, oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, validated.Token, "")
+
+	// Pretend the session is past its half-life.
+	stored, err := repo.GetByID(ctx, session.ID)
+	assert.NoError(t, err)
+	stored.ExpiresAt = time.Now().Add(policy.IdleTimeout / 4)
+	assert.NoError(t, repo.Insert(ctx, stored))
+
+	renewed, err := sessions.Validate(ctx, oldToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, renewed.Token, "")
+	assert.NotEqual(t, renewed.Token, oldToken)
+	assert.True(t, renewed.ExpiresAt.After(stored.ExpiresAt))
+
+	// The old token is accepted within the grace period but cannot renew.
+	again, err := sessions.Validate(ctx, oldToken)
+	assert.NoError(t, err)
+	assert.Equal(t, again.Token, "")
+
+	// Past the grace period, the old token is rejected.
+	stored, err = repo.GetByID(ctx, session.ID)
+	assert.NoError(t, err)
+	rotatedAt := time.Now().Add(-2 * policy.RotationGrace)
+	stored.RotatedAt = &rotatedAt
+	assert.NoError(t, repo.Insert(ctx, stored))
+	_, err = sessions.Validate(ctx, oldToken)
+	assert.Error(t, err, ErrInvalidSession)
+	_, err = sessions.Validate(ctx, renewed.Token)
+	assert.NoError(t, err)
+}
+
+// racingSessionRepository holds the first reads of a session back until
+// all of them arrived, so that that many requests read the session before
+// any of them writes it. Later reads are not held back.
+type racingSessionRepository struct {
+	*memory.SessionRepository
+	mu      sync.Mutex
+	held    int
+	readers sync.WaitGroup
+}
+
+func newRacingSessionRepository(repo *memory.SessionRepository, readers int) *racingSessionRepository {
+	r := &racingSessionRepository{SessionRepository: repo, mu: sync.Mutex{}, held: readers, readers: sync.WaitGroup{}}
+	r.readers.Add(readers)
+	return r
+}
+
+func (r *racingSessionRepository) GetByID(ctx context.Context, id string) (*domain.UserSession, error) {
+	session, err := r.SessionRepository.GetByID(ctx, id)
+	r.mu.Lock()
+	hold := r.held > 0
+	r.held--
+	r.mu.Unlock()
+	if hold {
+		r.readers.Done()
+		r.readers.Wait()
+	}
+	return session, err
+}
+
+func TestSessionService_ConcurrentRenewal(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewSessionRepository()
+	policy := DefaultSessionPolicy()
+	sessions := NewSessionService(NewServiceBase(test.NewMockLogger(), nil), repo, policy)
+
+	session, err := sessions.NewSession(ctx, "customer", domain.ClientInfo{})
+	assert.NoError(t, err)
+	stored, err := repo.GetByID(ctx, session.ID)
+	assert.NoError(t, err)
+	stored.ExpiresAt = time.Now().Add(policy.IdleTimeout / 4)
+	assert.NoError(t, repo.Insert(ctx, stored))
+
+	// Two requests in flight with the current token both see it is time to
+	// renew. One rotates the session, the other keeps the token it has.
+	racing := newRacingSessionRepository(repo, 2)
+	sessions = NewSessionService(NewServiceBase(test.NewMockLogger(), nil), racing, policy)
+	results := make([]*domain.UserSession, 2)
+	errs := make([]error, 2)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = sessions.Validate(ctx, session.Token)
+		}()
+	}
+	wg.Wait()
+
+	renewed := make([]string, 0)
+	for i := range results {
+		assert.NoError(t, errs[i])
+		if results[i].Token != "" {
+			renewed = append(renewed, results[i].Token)
+		}
+	}
+	assert.Equal(t, len(renewed), 1)
+
+	// Both tokens handed out still work.
+	sessions = NewSessionService(NewServiceBase(test.NewMockLogger(), nil), repo, policy)
+	_, err = sessions.Validate(ctx, renewed[0])
+	assert.NoError(t, err)
+	again, err := sessions.Validate(ctx, session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, again.Token, "")
+}
+
+func TestSessionService_TouchKeepsReauthentication(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewSessionRepository()
+	sessions := NewSessionService(NewServiceBase(test.NewMockLogger(), nil), repo, DefaultSessionPolicy())
+
+	session, err := sessions.NewSession(ctx, "customer", domain.ClientInfo{})
+	assert.NoError(t, err)
+	stale, err := repo.GetByID(ctx, session.ID)
+	assert.NoError(t, err)
+
+	// A request that read the session before the customer reauthenticated
+	// records that it was seen, and nothing else.
+	reauthenticated := session.AuthenticatedAt.Add(time.Hour)
+	assert.NoError(t, repo.MarkAuthenticated(ctx, session.ID, reauthenticated))
+	assert.NoError(t, repo.Touch(ctx, stale.ID, time.Now().Add(time.Hour)))
+
+	stored, err := repo.GetByID(ctx, session.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.AuthenticatedAt.Equal(reauthenticated))
+}
+
+func TestSessionService_AbsoluteLifetime(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewSessionRepository()
+	policy := DefaultSessionPolicy()
+	sessions := NewSessionService(NewServiceBase(test.NewMockLogger(), nil), repo, policy)
+
+	session, err := sessions.NewSession(ctx, "customer", domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	stored, err := repo.GetByID(ctx, session.ID)
+	assert.NoError(t, err)
+	// The absolute expiry is immutable, so overwrite the stored session.
+	stored.AbsoluteExpiresAt = time.Now().Add(-time.Second)
+	assert.NoError(t, repo.Insert(ctx, stored))
+	_, err = sessions.Validate(ctx, session.Token)
+	assert.Error(t, err, ErrSessionExpired)
+}
+
+func TestAuthService_SensitiveOperationsRequireRecentAuth(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	other, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	session := result.Session
+	session.AuthenticatedAt = time.Now().Add(-time.Hour)
+
+	const newPassword = "a much better passphrase"
+	err = f.auth.ChangePassword(ctx, session, testPassword, newPassword)
+	assert.Error(t, err, ErrReauthenticationRequired)
+
+	err = f.auth.Reauthenticate(ctx, session, "wrong")
+	assert.Error(t, err, ErrInvalidCredentials)
+	err = f.auth.Reauthenticate(ctx, session, testPassword)
+	assert.NoError(t, err)
+
+	err = f.auth.ChangePassword(ctx, session, "wrong", newPassword)
+	assert.Error(t, err, ErrInvalidCredentials)
+	err = f.auth.ChangePassword(ctx, session, testPassword, newPassword)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionPasswordChange)
+
+	// Other sessions are revoked, the current one is kept.
+	_, err = f.sessions.Validate(ctx, other.Session.Token)
+	assert.Error(t, err, ErrInvalidSession)
+	_, err = f.sessions.Validate(ctx, session.Token)
+	assert.NoError(t, err)
+
+	_, err = f.auth.SignIn(ctx, testEmail, newPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	customers := NewCustomerService(NewServiceBase(test.NewMockLogger(), nil), f.customers, f.sessions, f.verification)
+	session.AuthenticatedAt = time.Now().Add(-time.Hour)
+	err = customers.Delete(ctx, session)
+	assert.Error(t, err, ErrReauthenticationRequired)
+	session.AuthenticatedAt = time.Now()
+	err = customers.Delete(ctx, session)
+	assert.NoError(t, err)
+	_, err = f.customers.GetByEmail(ctx, testEmail)
+	assert.Error(t, err, domain.ErrCustomerNotFound)
+}
+
+func TestAuthService_RehashOnSignIn(t *testing.T) {
+	ctx := context.Background()
+	weak := crypto.Argon2Params{Memory: 8 * 1024, Iterations: 1, Threads: 1, KeyLength: 32}
+
+	tests := []struct {
+		test.CaseBase
+		// hashedWith are the parameters of the stored hash.
+		hashedWith crypto.Argon2Params
+		// repoParams are the parameters the repository hashes with from
+		// then on.
+		repoParams crypto.Argon2Params
+		password   string
+	}{
+		{
+			CaseBase:   test.NewCaseBase("raised parameters", true, false),
+			hashedWith: weak,
+			repoParams: crypto.DefaultArgon2Params(),
+			password:   testPassword,
+		},
+		{
+			CaseBase:   test.NewCaseBase("current parameters", false, false),
+			hashedWith: crypto.DefaultArgon2Params(),
+			repoParams: crypto.DefaultArgon2Params(),
+			password:   testPassword,
+		},
+		{
+			// A repository configured apart from the defaults is not
+			// fought with on every sign in.
+			CaseBase:   test.NewCaseBase("non-default parameters", false, false),
+			hashedWith: weak,
+			repoParams: weak,
+			password:   testPassword,
+		},
+		{
+			CaseBase:   test.NewCaseBase("wrong password", false, true),
+			hashedWith: weak,
+			repoParams: crypto.DefaultArgon2Params(),
+			password:   "not the password",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newAuthFixture(t)
+				f.customers.params = tt.hashedWith
+				result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+				assert.NoError(t, err)
+				before, err := f.customers.GetByID(ctx, result.Customer.ID)
+				assert.NoError(t, err)
+
+				f.customers.params = tt.repoParams
+				_, err = f.auth.SignIn(ctx, testEmail, tt.password, domain.ClientInfo{})
+				assert.ErrorAndWant(t, err, tt.WantErr)
+
+				after, err := f.customers.GetByID(ctx, result.Customer.ID)
+				assert.NoError(t, err)
+				rehashed := string(after.PasswordHash) != string(before.PasswordHash)
+				assert.Equal(t, rehashed, tt.Want.(bool))
+
+				// A rehashed password is not rehashed again.
+				_, err = f.auth.SignIn(ctx, testEmail, tt.password, domain.ClientInfo{})
+				assert.ErrorAndWant(t, err, tt.WantErr)
+				again, err := f.customers.GetByID(ctx, result.Customer.ID)
+				assert.NoError(t, err)
+				assert.Equal(t, string(again.PasswordHash), string(after.PasswordHash))
+
+				// The password still works.
+				ok, err := crypto.ValidatePassword(testPassword, string(after.PasswordHash))
+				assert.NoError(t, err)
+				assert.True(t, ok)
+			},
+		)
+	}
+}
+
+func TestAuthService_UpgradeLegacyHashOnSignIn(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	// Imported customers keep the bcrypt hash of the store they came from.
+	legacy, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	assert.NoError(t, err)
+	f.customers.mu.Lock()
+	f.customers.customers[result.Customer.ID].PasswordHash = legacy
+	f.customers.mu.Unlock()
+
+	_, err = f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	after, err := f.customers.GetByID(ctx, result.Customer.ID)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(after.PasswordHash), "$argon2id$"))
+	assert.False(t, crypto.NeedsRehash(string(after.PasswordHash), f.customers.HashParams()))
+	ok, err := crypto.ValidatePassword(testPassword, string(after.PasswordHash))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
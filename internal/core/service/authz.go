@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// authorizationRepository stores the grants of roles and the roles assigned
+// to customers. The customer role is implicit and never assigned.
+type authorizationRepository interface {
+	ListRoles(ctx context.Context, customerID string) ([]domain.Role, error)
+	ListGrants(ctx context.Context, roles []domain.Role) ([]domain.Grant, error)
+	AssignRole(ctx context.Context, customerID string, role domain.Role, grantedBy string) error
+	RevokeRole(ctx context.Context, customerID string, role domain.Role) error
+}
+
+// AuthorizationService decides what authenticated customers may do. It
+// resolves the principal behind a session token, with the roles and grants
+// stored for them, and evaluates actions against those grants. Denied
+// actions are recorded as audit events.
+type AuthorizationService struct {
+	*ServiceBase
+	sessions *SessionService
+	repo     authorizationRepository
+	audit    auditRecorder
+}
+
+// NewAuthorizationService creates a new AuthorizationService.
+func NewAuthorizationService(
+	svcBase *ServiceBase,
+	sessions *SessionService,
+	repo authorizationRepository,
+) *AuthorizationService {
+	return &AuthorizationService{
+		ServiceBase: svcBase,
+		sessions:    sessions,
+		repo:        repo,
+		audit:       svcBase.auditRecorder(),
+	}
+}
+
+// Authenticate validates a session token and returns the principal it
+// belongs to. Invalid and expired sessions return an error wrapping
+// domain.ErrUnauthenticated. If the session was renewed, the Token of the
+// principal's session is set and must be handed to the client, like with
+// SessionService.Validate.
+func (a *AuthorizationService) Authenticate(ctx context.Context, token string) (*domain.Principal, error) {
+	session, err := a.sessions.Validate(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSession) || errors.Is(err, ErrSessionExpired) {
+			return nil, errors.Join(domain.ErrUnauthenticated, err)
+		}
+		return nil, err
+	}
+	return a.Principal(ctx, session)
+}
+
+// Principal returns the principal of a session that was already validated.
+func (a *AuthorizationService) Principal(
+	ctx context.Context,
+	session *domain.UserSession,
+) (*domain.Principal, error) {
+	assigned, err := a.repo.ListRoles(ctx, session.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list roles")
+	}
+	roles := append([]domain.Role{domain.RoleCustomer}, assigned...)
+	grants, err := a.repo.ListGrants(ctx, roles)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list grants")
+	}
+	return &domain.Principal{
+		CustomerID: session.UserID,
+		Session:    session,
+		Roles:      roles,
+		Grants:     grants,
+	}, nil
+}
+
+// Authorize returns domain.ErrForbidden unless the grants of a principal
+// allow an action. Services call it before acting on behalf of a principal,
+// with the owner and amount of the resource at hand, such as:
+//
+//	err := authz.Authorize(ctx, principal, domain.AccessRequest{
+//		Permission: domain.PermissionOrdersRefund,
+//		OwnerID:    order.UserID,
+//		Amount:     order.GrandTotal,
+//	})
+func (a *AuthorizationService) Authorize(
+	ctx context.Context,
+	principal *domain.Principal,
+	req domain.AccessRequest,
+) error {
+	if principal != nil && principal.Allows(req) {
+		return nil
+	}
+	customerID := ""
+	if principal != nil {
+		customerID = principal.CustomerID
+	}
+	a.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionAccessDenied, domain.AuditOutcomeFailure, customerID, req.Permission.String(),
+	))
+	return domain.ErrForbidden
+}
+
+// AssignRole assigns a role to a customer. The principal must be allowed to
+// assign roles and must have authenticated recently. The customer role
+// cannot be assigned, since every customer has it.
+func (a *AuthorizationService) AssignRole(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+	role domain.Role,
+) error {
+	return a.changeRole(ctx, principal, customerID, role, domain.AuditActionRoleAssign, func() error {
+		return a.repo.AssignRole(ctx, customerID, role, principal.CustomerID)
+	})
+}
+
+// RevokeRole removes a role from a customer, under the same conditions as
+// AssignRole. A principal cannot revoke their own admin role, so that the
+// last admin cannot lock everyone out by mistake.
+func (a *AuthorizationService) RevokeRole(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+	role domain.Role,
+) error {
+	if principal.CustomerID == customerID && role == domain.RoleAdmin {
+		a.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionRoleRevoke, domain.AuditOutcomeFailure, customerID, "own_admin_role",
+		))
+		return ErrOwnAdminRole
+	}
+	return a.changeRole(ctx, principal, customerID, role, domain.AuditActionRoleRevoke, func() error {
+		return a.repo.RevokeRole(ctx, customerID, role)
+	})
+}
+
+func (a *AuthorizationService) changeRole(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+	role domain.Role,
+	action domain.AuditAction,
+	change func() error,
+) error {
+	fail := func(reason string, err error) error {
+		a.audit.Record(ctx, domain.NewActorAuditEvent(
+			action, domain.AuditOutcomeFailure, principal.CustomerID, customerID, reason,
+		))
+		return err
+	}
+
+	err := a.Authorize(ctx, principal, domain.AccessRequest{
+		Permission: domain.PermissionRolesAssign,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+	if err != nil {
+		return fail("forbidden", err)
+	}
+	err = a.sessions.RequireRecentAuth(principal.Session)
+	if err != nil {
+		return fail("reauthentication_required", err)
+	}
+	if role == domain.RoleCustomer || role == domain.InvalidRole {
+		return fail("invalid_role", ErrInvalidRole)
+	}
+
+	err = change()
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to change role"))
+	}
+	a.audit.Record(ctx, domain.NewActorAuditEvent(
+		action, domain.AuditOutcomeSuccess, principal.CustomerID, customerID, "",
+	))
+	a.logger.Info(
+		"changed role", "action", action.String(), "customer_id", customerID,
+		"role", role.String(), "by", principal.CustomerID,
+	)
+	return nil
+}
+
+var (
+	ErrInvalidRole  = errors.New("role cannot be assigned")
+	ErrOwnAdminRole = errors.New("cannot revoke own admin role")
+)
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+// signUpWithRoles signs a customer up and assigns them roles, returning the
+// result of the sign up.
+func (f authFixture) signUpWithRoles(t *testing.T, email string, roles ...domain.Role) *AuthResult {
+	t.Helper()
+	ctx := context.Background()
+	result, err := f.auth.SignUp(ctx, email, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	for _, role := range roles {
+		err = f.roles.AssignRole(ctx, result.Customer.ID, role, "")
+		assert.NoError(t, err)
+	}
+	return result
+}
+
+func TestAuthorizationService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	result := f.signUpWithRoles(t, testEmail, domain.RoleSupport)
+
+	principal, err := f.authz.Authenticate(ctx, result.Session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, principal.CustomerID, result.Customer.ID)
+	assert.True(t, principal.HasRole(domain.RoleCustomer))
+	assert.True(t, principal.HasRole(domain.RoleSupport))
+	assert.False(t, principal.HasRole(domain.RoleAdmin))
+	assert.True(t, principal.Holds(domain.PermissionOrdersRefund))
+
+	_, err = f.authz.Authenticate(ctx, "not.a-token")
+	assert.Error(t, err, domain.ErrUnauthenticated)
+	_, err = f.authz.Authenticate(ctx, "")
+	assert.Error(t, err, domain.ErrUnauthenticated)
+}
+
+func TestWebshopService_Refund(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	processor := &fakePaymentProcessor{}
+	orders := memory.NewOrderRepository()
+	shop := NewWebshopService(
+		NewServiceBase(test.NewMockLogger(), nil), processor, f.customers, orders, memory.NewDownloadRepository(), f.authz,
+	)
+
+	buyer := f.signUpWithRoles(t, testEmail)
+	support := f.signUpWithRoles(t, "support@brokedaear.com", domain.RoleSupport)
+	admin := f.signUpWithRoles(t, "admin@brokedaear.com", domain.RoleAdmin)
+
+	tests := []struct {
+		test.CaseBase
+		session *domain.UserSession
+		total   int
+		// passed is the grand total of the order passed to Refund, which
+		// must not matter.
+		passed int
+	}{
+		{CaseBase: test.NewCaseBase("buyer", domain.ErrForbidden, true), session: buyer.Session, total: 100, passed: 100},
+		{
+			CaseBase: test.NewCaseBase("support within limit", nil, false),
+			session:  support.Session,
+			total:    5000,
+			passed:   5000,
+		},
+		{
+			CaseBase: test.NewCaseBase("support above limit", domain.ErrForbidden, true),
+			session:  support.Session,
+			total:    5001,
+			passed:   5001,
+		},
+		{
+			CaseBase: test.NewCaseBase("support understating total", domain.ErrForbidden, true),
+			session:  support.Session,
+			total:    5001,
+			passed:   100,
+		},
+		{CaseBase: test.NewCaseBase("admin above limit", nil, false), session: admin.Session, total: 50000, passed: 50000},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				principal, err := f.authz.Principal(ctx, tt.session)
+				assert.NoError(t, err)
+				order := &domain.Order{
+					ID:         tt.Name,
+					UserID:     buyer.Customer.ID,
+					GrandTotal: tt.total,
+					Status:     domain.CompletedStatus,
+				}
+				assert.NoError(t, orders.Insert(ctx, order))
+				passed := *order
+				passed.GrandTotal = tt.passed
+				err = shop.Refund(ctx, principal, &passed)
+				stored, getErr := orders.GetByID(ctx, order.ID)
+				assert.NoError(t, getErr)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Equal(t, f.audit.last().Action, domain.AuditActionAccessDenied)
+					assert.Equal(t, f.audit.last().CustomerID, principal.CustomerID)
+					assert.Equal(t, stored.Status, domain.CompletedStatus)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, stored.Status, domain.RefundedStatus)
+			},
+		)
+	}
+}
+
+func TestAuthorizationService_AssignRole(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	admin := f.signUpWithRoles(t, "admin@brokedaear.com", domain.RoleAdmin)
+	support := f.signUpWithRoles(t, "support@brokedaear.com", domain.RoleSupport)
+	customer := f.signUpWithRoles(t, testEmail)
+
+	adminPrincipal, err := f.authz.Principal(ctx, admin.Session)
+	assert.NoError(t, err)
+	supportPrincipal, err := f.authz.Principal(ctx, support.Session)
+	assert.NoError(t, err)
+
+	// Support agents cannot hand out roles.
+	err = f.authz.AssignRole(ctx, supportPrincipal, customer.Customer.ID, domain.RoleSupport)
+	assert.Error(t, err, domain.ErrForbidden)
+
+	err = f.authz.AssignRole(ctx, adminPrincipal, customer.Customer.ID, domain.RoleCustomer)
+	assert.Error(t, err, ErrInvalidRole)
+
+	err = f.authz.AssignRole(ctx, adminPrincipal, customer.Customer.ID, domain.RoleSupport)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionRoleAssign)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	principal, err := f.authz.Principal(ctx, customer.Session)
+	assert.NoError(t, err)
+	assert.True(t, principal.HasRole(domain.RoleSupport))
+
+	err = f.authz.RevokeRole(ctx, adminPrincipal, customer.Customer.ID, domain.RoleSupport)
+	assert.NoError(t, err)
+	principal, err = f.authz.Principal(ctx, customer.Session)
+	assert.NoError(t, err)
+	assert.False(t, principal.HasRole(domain.RoleSupport))
+
+	err = f.authz.RevokeRole(ctx, adminPrincipal, admin.Customer.ID, domain.RoleAdmin)
+	assert.Error(t, err, ErrOwnAdminRole)
+
+	// Changing roles is sensitive.
+	admin.Session.AuthenticatedAt = time.Now().Add(-time.Hour)
+	err = f.authz.AssignRole(ctx, adminPrincipal, customer.Customer.ID, domain.RoleSupport)
+	assert.Error(t, err, ErrReauthenticationRequired)
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"sync"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned instead of calling a dependency that has failed
+// too often recently.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// circuitBreaker stops calls to a failing dependency, so that callers fail
+// fast instead of waiting on it. It opens after a number of failures in a
+// row, and after a while lets a single trial call through. The circuit
+// closes again if the trial succeeds.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	openFor   time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, openFor time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		mu:        sync.Mutex{},
+		threshold: threshold,
+		openFor:   openFor,
+		failures:  0,
+		openUntil: time.Time{},
+		trial:     false,
+		now:       time.Now,
+	}
+}
+
+// allow returns ErrCircuitOpen if a call may not be made now. Every allowed
+// call must be followed by success, failure or release.
+func (c *circuitBreaker) allow() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures < c.threshold {
+		return nil
+	}
+	if c.trial || c.now().Before(c.openUntil) {
+		return ErrCircuitOpen
+	}
+	c.trial = true
+	return nil
+}
+
+func (c *circuitBreaker) success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures = 0
+	c.trial = false
+}
+
+func (c *circuitBreaker) failure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures++
+	c.trial = false
+	if c.failures >= c.threshold {
+		c.openUntil = c.now().Add(c.openFor)
+	}
+}
+
+// open reports whether calls are currently refused.
+func (c *circuitBreaker) open() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failures >= c.threshold
+}
+
+// release ends an allowed call whose outcome says nothing about the
+// dependency, such as one the caller cancelled.
+func (c *circuitBreaker) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trial = false
+}
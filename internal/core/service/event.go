@@ -0,0 +1,293 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// eventWriter writes domain events to the outbox.
+type eventWriter interface {
+	Insert(ctx context.Context, event *domain.Event) error
+}
+
+// eventOutboxRepository stores domain events waiting to be published.
+type eventOutboxRepository interface {
+	eventWriter
+	// ClaimDue returns up to limit pending events that are due at a point in
+	// time, and pushes their next attempt back by lease, so that no other
+	// relay claims them while they are published.
+	ClaimDue(ctx context.Context, at time.Time, limit int, lease time.Duration) ([]*domain.Event, error)
+	// MarkPublished marks an event as handled by every subscriber.
+	MarkPublished(ctx context.Context, id string, at time.Time) error
+	// MarkFailed records a failed attempt. A nil next attempt marks the
+	// event as dead, and it is never attempted again.
+	MarkFailed(ctx context.Context, id string, attempts int, next *time.Time, lastError string) error
+}
+
+// emit writes a domain event to the outbox, if the base has one, tagged
+// with the trace it is emitted in. It joins the transaction carried by ctx,
+// so it should be called inside inTx along with the change the event
+// describes. An error should roll that change back.
+func (s *ServiceBase) emit(ctx context.Context, typ domain.EventType, aggregateID string, payload any) error {
+	if s.events == nil {
+		return nil
+	}
+	event, err := domain.NewEvent(typ, aggregateID, payload)
+	if err != nil {
+		return err
+	}
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if spanCtx.IsValid() {
+		event.TraceID = spanCtx.TraceID().String()
+		event.SpanID = spanCtx.SpanID().String()
+	}
+	return errors.Wrap(s.events.Insert(ctx, event), "failed to write event to outbox")
+}
+
+// insertCustomer inserts a new customer along with a CustomerSignedUp event
+// in one transaction. method is how the customer signed up.
+func (s *ServiceBase) insertCustomer(
+	ctx context.Context,
+	customers customerRepository,
+	customer *domain.Customer,
+	method string,
+) error {
+	return s.inTx(ctx, func(ctx context.Context) error {
+		err := customers.Insert(ctx, customer)
+		if err != nil {
+			return err
+		}
+		return s.emit(ctx, domain.EventTypeCustomerSignedUp, customer.ID, domain.CustomerSignedUp{
+			CustomerID: customer.ID,
+			Method:     method,
+		})
+	})
+}
+
+// deleteCustomer soft deletes a customer along with a CustomerDeleted event
+// in one transaction. deletedBy is the ID of the customer who deleted the
+// account.
+func (s *ServiceBase) deleteCustomer(
+	ctx context.Context,
+	customers customerRepository,
+	customer *domain.Customer,
+	deletedBy string,
+) error {
+	return s.inTx(ctx, func(ctx context.Context) error {
+		err := customers.Delete(ctx, customer)
+		if err != nil {
+			return err
+		}
+		return s.emit(ctx, domain.EventTypeCustomerDeleted, customer.ID, domain.CustomerDeleted{
+			CustomerID: customer.ID,
+			DeletedBy:  deletedBy,
+		})
+	})
+}
+
+// EventHandler handles a domain event published by an EventRelay. Events are
+// published at least once, so a handler must tolerate handling the same
+// event twice. A handler decodes the payload of the event with
+// domain.Event.Decode.
+type EventHandler func(ctx context.Context, event *domain.Event) error
+
+// eventSubscriber is a named handler subscribed to a type of event.
+type eventSubscriber struct {
+	name   string
+	handle EventHandler
+}
+
+// EventRelay publishes domain events from the outbox to in-process
+// subscribers in the background, once the transactions that emitted them
+// are committed. An event is published once every subscriber to its type
+// handled it. If any subscriber fails, the event is published again to
+// every subscriber with exponential backoff, so delivery is at least once.
+//
+// The span of each publication is linked to the span the event was emitted
+// in, so that the handling of an event can be traced back to the request
+// that caused it. EventRelay implements io.Closer, so it can take part in a
+// global teardown.
+type EventRelay struct {
+	*ServiceBase
+	outbox      eventOutboxRepository
+	policy      DeliveryPolicy
+	subscribers map[domain.EventType][]eventSubscriber
+	stop        chan struct{}
+	done        chan struct{}
+	once        sync.Once
+}
+
+// NewEventRelay creates a new EventRelay. Spans are recorded if the service
+// base has telemetry.
+func NewEventRelay(
+	svcBase *ServiceBase,
+	outbox eventOutboxRepository,
+	policy DeliveryPolicy,
+) *EventRelay {
+	return &EventRelay{
+		ServiceBase: svcBase,
+		outbox:      outbox,
+		policy:      policy,
+		subscribers: make(map[domain.EventType][]eventSubscriber),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		once:        sync.Once{},
+	}
+}
+
+// Subscribe makes a handler handle every event of a type. The name of the
+// subscriber shows in logs and errors. Subscribe must be called before the
+// relay is started.
+func (r *EventRelay) Subscribe(typ domain.EventType, name string, handler EventHandler) {
+	r.subscribers[typ] = append(r.subscribers[typ], eventSubscriber{name: name, handle: handler})
+}
+
+// Start starts publishing in the background until the context is cancelled
+// or the relay is closed.
+func (r *EventRelay) Start(ctx context.Context) {
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.policy.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				_, err := r.Relay(ctx)
+				if err != nil {
+					r.logger.Error("failed to relay events", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the relay and waits for it to finish. Close is safe to call
+// more than once, but the relay must have been started.
+func (r *EventRelay) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+	return nil
+}
+
+// Relay publishes one batch of due events, and returns how many events were
+// published.
+func (r *EventRelay) Relay(ctx context.Context) (int, error) {
+	events, err := r.outbox.ClaimDue(ctx, time.Now().UTC(), r.policy.BatchSize, r.policy.Lease)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to claim events")
+	}
+	published := 0
+	for _, event := range events {
+		if r.publish(ctx, event) {
+			published++
+		}
+	}
+	return published, nil
+}
+
+// publish hands an event to its subscribers and records the outcome. It
+// reports whether every subscriber handled the event.
+func (r *EventRelay) publish(ctx context.Context, event *domain.Event) bool {
+	ctx, span := r.startSpan(ctx, event)
+	defer span.End()
+
+	var errs []error
+	for _, sub := range r.subscribers[event.Type] {
+		err := sub.handle(ctx, event)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "subscriber %s failed", sub.name))
+		}
+	}
+	err := errors.Join(errs...)
+
+	now := time.Now().UTC()
+	if err == nil {
+		err = r.outbox.MarkPublished(ctx, event.ID, now)
+		if err != nil {
+			// The event will be published again once its lease runs out.
+			r.logger.Error("failed to mark event published", "event_id", event.ID, "error", err)
+		}
+		return true
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "failed to handle event")
+
+	attempts := event.Attempts + 1
+	var next *time.Time
+	if attempts < r.policy.MaxAttempts {
+		at := now.Add(r.policy.backoff(attempts))
+		next = &at
+		r.logger.Warn("failed to handle event, retrying",
+			"event_id", event.ID, "type", event.Type.String(), "attempts", attempts,
+			"next_attempt_at", at, "error", err)
+	} else {
+		r.logger.Error("failed to handle event, giving up",
+			"event_id", event.ID, "type", event.Type.String(), "attempts", attempts, "error", err)
+	}
+
+	err = r.outbox.MarkFailed(ctx, event.ID, attempts, next, err.Error())
+	if err != nil {
+		r.logger.Error("failed to mark event failed", "event_id", event.ID, "error", err)
+	}
+	return false
+}
+
+// startSpan starts the span of the publication of an event, linked to the
+// span the event was emitted in. Without telemetry, the span is discarded.
+func (r *EventRelay) startSpan(ctx context.Context, event *domain.Event) (context.Context, trace.Span) {
+	name := "event " + event.Type.String()
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("event.id", event.ID),
+			attribute.String("event.type", event.Type.String()),
+			attribute.Int("event.attempt", event.Attempts+1),
+		),
+	}
+	if link, ok := eventLink(event); ok {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	if r.tel == nil {
+		return noop.NewTracerProvider().Tracer("").Start(ctx, name, opts...)
+	}
+	return r.tel.TraceStart(ctx, name, opts...)
+}
+
+// eventLink returns a link to the span an event was emitted in, if it was
+// emitted in one.
+func eventLink(event *domain.Event) (trace.Link, bool) {
+	traceID, err := trace.TraceIDFromHex(event.TraceID)
+	if err != nil {
+		return trace.Link{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(event.SpanID)
+	if err != nil {
+		return trace.Link{}, false
+	}
+	return trace.Link{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: trace.FlagsSampled,
+			TraceState: trace.TraceState{},
+			Remote:     true,
+		}),
+		Attributes: nil,
+	}, true
+}
@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// tracingTelemetry is telemetry that only records spans.
+type tracingTelemetry struct {
+	telemetry.Telemetry
+	tracer oteltrace.Tracer
+}
+
+func (f tracingTelemetry) TraceStart(
+	ctx context.Context,
+	name string,
+	opts ...oteltrace.SpanStartOption,
+) (context.Context, oteltrace.Span) {
+	return f.tracer.Start(ctx, name, opts...)
+}
+
+// recordingHandler is an event handler that records the events it handles,
+// and fails with its failures first.
+type recordingHandler struct {
+	handled  []*domain.Event
+	failures []error
+}
+
+func (r *recordingHandler) handle(_ context.Context, event *domain.Event) error {
+	r.handled = append(r.handled, event)
+	if len(r.failures) > 0 {
+		err := r.failures[0]
+		r.failures = r.failures[1:]
+		return err
+	}
+	return nil
+}
+
+type eventFixture struct {
+	base    *ServiceBase
+	outbox  *memory.EventOutboxRepository
+	relay   *EventRelay
+	handler *recordingHandler
+}
+
+func newEventFixture(t *testing.T, tel telemetry.Telemetry) eventFixture {
+	t.Helper()
+	base := NewServiceBase(test.NewMockLogger(), tel)
+	outbox := memory.NewEventOutboxRepository()
+	base.UseEvents(outbox)
+	policy := DefaultDeliveryPolicy()
+	policy.MaxAttempts = 3
+	relay := NewEventRelay(base, outbox, policy)
+	handler := &recordingHandler{}
+	relay.Subscribe(domain.EventTypeOrderPaid, "receipts", handler.handle)
+	return eventFixture{base: base, outbox: outbox, relay: relay, handler: handler}
+}
+
+// emit writes an order paid event to the outbox.
+func (f eventFixture) emit(ctx context.Context, t *testing.T) {
+	t.Helper()
+	err := f.base.emit(ctx, domain.EventTypeOrderPaid, "order-1", domain.OrderPaid{
+		OrderID:    "order-1",
+		CustomerID: "customer-1",
+		GrandTotal: 4200,
+		Currency:   "USD",
+	})
+	assert.NoError(t, err)
+}
+
+// only returns the only event in the outbox.
+func (f eventFixture) only(t *testing.T) *domain.Event {
+	t.Helper()
+	events := f.outbox.List()
+	assert.Equal(t, len(events), 1)
+	return events[0]
+}
+
+func TestEventRelay_Publishes(t *testing.T) {
+	ctx := context.Background()
+	f := newEventFixture(t, nil)
+	other := &recordingHandler{}
+	f.relay.Subscribe(domain.EventTypeOrderPaid, "analytics", other.handle)
+	unrelated := &recordingHandler{}
+	f.relay.Subscribe(domain.EventTypeOrderRefunded, "refunds", unrelated.handle)
+
+	f.emit(ctx, t)
+	published, err := f.relay.Relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, published, 1)
+	assert.Equal(t, len(f.handler.handled), 1)
+	assert.Equal(t, len(other.handled), 1)
+	assert.Equal(t, len(unrelated.handled), 0)
+
+	var paid domain.OrderPaid
+	assert.NoError(t, f.handler.handled[0].Decode(&paid))
+	assert.Equal(t, paid.OrderID, "order-1")
+	assert.Equal(t, paid.GrandTotal, 4200)
+
+	// A published event is not published again.
+	published, err = f.relay.Relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, published, 0)
+	event := f.only(t)
+	assert.Equal(t, event.Status, domain.EventStatusPublished)
+	assert.True(t, event.PublishedAt != nil)
+}
+
+func TestEventRelay_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	f := newEventFixture(t, nil)
+	f.handler.failures = []error{errors.New("connection refused")}
+
+	f.emit(ctx, t)
+	before := time.Now()
+	published, err := f.relay.Relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, published, 0)
+
+	event := f.only(t)
+	assert.Equal(t, event.Status, domain.EventStatusPending)
+	assert.Equal(t, event.Attempts, 1)
+	assert.True(t, strings.Contains(event.LastError, "receipts"))
+	base := f.relay.policy.BaseBackoff
+	assert.False(t, event.NextAttemptAt.Before(before.Add(base)))
+
+	// The event is not retried before it is due.
+	published, err = f.relay.Relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, published, 0)
+
+	event.NextAttemptAt = time.Now()
+	assert.NoError(t, f.outbox.Insert(ctx, event))
+	published, err = f.relay.Relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, published, 1)
+	assert.Equal(t, len(f.handler.handled), 2)
+	assert.Equal(t, f.handler.handled[0].ID, f.handler.handled[1].ID)
+}
+
+func TestEventRelay_GivesUp(t *testing.T) {
+	ctx := context.Background()
+	f := newEventFixture(t, nil)
+	f.handler.failures = []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")}
+
+	f.emit(ctx, t)
+	for range f.handler.failures {
+		event := f.only(t)
+		event.NextAttemptAt = time.Now()
+		assert.NoError(t, f.outbox.Insert(ctx, event))
+		_, err := f.relay.Relay(ctx)
+		assert.NoError(t, err)
+	}
+
+	event := f.only(t)
+	assert.Equal(t, event.Status, domain.EventStatusDead)
+	assert.Equal(t, event.Attempts, 3)
+}
+
+func TestEventRelay_LinksToEmittingSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	f := newEventFixture(t, tracingTelemetry{Telemetry: nil, tracer: tracer})
+
+	ctx, request := tracer.Start(context.Background(), "request")
+	f.emit(ctx, t)
+	request.End()
+	event := f.only(t)
+	assert.Equal(t, event.TraceID, request.SpanContext().TraceID().String())
+
+	published, err := f.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, published, 1)
+
+	spans := recorder.Ended()
+	assert.Equal(t, len(spans), 2)
+	handled := spans[1]
+	assert.Equal(t, handled.Name(), "event order.paid")
+	assert.Equal(t, handled.SpanKind(), oteltrace.SpanKindConsumer)
+	assert.Equal(t, len(handled.Links()), 1)
+	assert.Equal(t, handled.Links()[0].SpanContext.SpanID(), request.SpanContext().SpanID())
+	assert.Equal(t, handled.Links()[0].SpanContext.TraceID(), request.SpanContext().TraceID())
+}
+
+func TestServiceBase_EmitsCustomerEvents(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	admin := f.principal(t, "admin@brokedaear.com", domain.RoleAdmin)
+	outbox := memory.NewEventOutboxRepository()
+	tx := &fakeTransactor{calls: 0, err: nil}
+	// The fixture creates the admin service with a base of its own.
+	for _, base := range []*ServiceBase{f.auth.ServiceBase, f.admin.ServiceBase} {
+		base.UseEvents(outbox)
+		base.UseTransactions(tx)
+	}
+
+	result, err := f.auth.SignUp(ctx, "new@brokedaear.com", testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	err = f.admin.DeleteCustomer(ctx, admin, result.Customer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, tx.calls, 2)
+
+	events := outbox.List()
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, events[0].Type, domain.EventTypeCustomerSignedUp)
+	assert.Equal(t, events[0].AggregateID, result.Customer.ID)
+	var signedUp domain.CustomerSignedUp
+	assert.NoError(t, events[0].Decode(&signedUp))
+	assert.Equal(t, signedUp.Method, "password")
+
+	assert.Equal(t, events[1].Type, domain.EventTypeCustomerDeleted)
+	var deleted domain.CustomerDeleted
+	assert.NoError(t, events[1].Decode(&deleted))
+	assert.Equal(t, deleted.CustomerID, result.Customer.ID)
+	assert.Equal(t, deleted.DeletedBy, admin.CustomerID)
+
+	// A change whose transaction fails emits no event.
+	tx.err = errors.New("failed to begin transaction")
+	_, err = f.auth.SignUp(ctx, "other@brokedaear.com", testPassword, domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerSignUpFailed)
+	assert.Equal(t, len(outbox.List()), 2)
+}
@@ -0,0 +1,531 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/cron"
+	"go.brokedaear.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// jobRepository stores the job queue.
+type jobRepository interface {
+	// Insert adds a new job to the queue, unless its key is taken by
+	// another job. It joins the transaction carried by ctx, if any.
+	Insert(ctx context.Context, job *domain.Job) error
+	// ClaimDue returns up to limit pending jobs that are due at a point in
+	// time, and pushes their next run back by lease, so that no other runner
+	// claims them while they run.
+	ClaimDue(ctx context.Context, at time.Time, limit int, lease time.Duration) ([]*domain.Job, error)
+	// MarkSucceeded marks a job as done.
+	MarkSucceeded(ctx context.Context, id string, at time.Time) error
+	// MarkFailed records a failed run. A nil next run marks the job as
+	// dead, and it is never run again unless retried.
+	MarkFailed(ctx context.Context, id string, attempts int, next *time.Time, lastError string, at time.Time) error
+	// Retry makes a dead job pending again, due at a point in time. It
+	// returns domain.ErrJobNotFound if the job is missing or not dead.
+	Retry(ctx context.Context, id string, at time.Time) error
+}
+
+// jobScheduleRepository records when recurring schedules are due, and
+// elects the runner that enqueues their jobs.
+type jobScheduleRepository interface {
+	// WithLeaderLock runs fn if no other runner is running it, and reports
+	// whether it did.
+	WithLeaderLock(ctx context.Context, fn func(ctx context.Context) error) (bool, error)
+	// NextRun retrieves when a schedule is due next. It returns
+	// domain.ErrJobScheduleNotFound if the schedule never ran.
+	NextRun(ctx context.Context, schedule string) (time.Time, error)
+	// SetNextRun records when a schedule is due next.
+	SetNextRun(ctx context.Context, schedule string, at time.Time) error
+}
+
+// jobQueueRepository stores the job queue along with its schedules.
+type jobQueueRepository interface {
+	jobRepository
+	jobScheduleRepository
+}
+
+// JobPolicy configures how a JobRunner runs jobs.
+type JobPolicy struct {
+	// PollInterval is how often the queue is checked for due jobs, and
+	// schedules for due runs.
+	PollInterval time.Duration
+	// BatchSize is how many jobs are claimed at once.
+	BatchSize int
+	// Concurrency is how many jobs run at the same time.
+	Concurrency int
+	// Lease is how long a claimed job is hidden from other runners. It
+	// must be longer than a run takes, or the job runs twice.
+	Lease time.Duration
+	// MaxAttempts is how many times a job is run before it is given up on.
+	MaxAttempts int
+	// BaseBackoff is how long to wait after the first failed run. The wait
+	// doubles with every run, up to MaxBackoff.
+	BaseBackoff time.Duration
+	// MaxBackoff is the longest wait between two runs.
+	MaxBackoff time.Duration
+	// DrainTimeout is how long closing the runner waits for running jobs to
+	// finish before cancelling them.
+	DrainTimeout time.Duration
+}
+
+// DefaultJobPolicy returns a policy that checks the queue every few seconds,
+// runs four jobs at a time and retries a failed job for about eight hours.
+func DefaultJobPolicy() JobPolicy {
+	return JobPolicy{
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+		Concurrency:  4,
+		Lease:        5 * time.Minute,
+		MaxAttempts:  10,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   4 * time.Hour,
+		DrainTimeout: 30 * time.Second,
+	}
+}
+
+// JobType is a type of job whose payload is a T. Its name is stored with
+// every job, so it must not change once jobs of the type were enqueued.
+type JobType[T any] struct {
+	name string
+}
+
+// NewJobType creates a type of job with a unique name, such as
+// "sessions.reap".
+func NewJobType[T any](name string) JobType[T] {
+	return JobType[T]{name: name}
+}
+
+// Name returns the name of the type of job.
+func (t JobType[T]) Name() string {
+	return t.name
+}
+
+// jobHandler runs a job of any type.
+type jobHandler func(ctx context.Context, job *domain.Job) error
+
+// jobSchedule is a recurring schedule whose runs each enqueue a job.
+type jobSchedule struct {
+	name     string
+	schedule *cron.Schedule
+	typ      string
+	payload  json.RawMessage
+}
+
+// JobRunner runs jobs from a queue in the background, such as deferred work
+// enqueued by services, and jobs enqueued on recurring schedules. Many
+// runners can share a queue: each job is claimed by one runner at a time,
+// and only one runner at a time, the leader, enqueues the jobs of due
+// schedules. Failed jobs are retried with exponential backoff, and jobs that
+// failed too often are dead lettered.
+//
+// JobRunner implements io.Closer, so it can take part in a global teardown.
+// Closing the runner drains it: running jobs are given time to finish before
+// they are cancelled.
+type JobRunner struct {
+	*ServiceBase
+	repo      jobQueueRepository
+	policy    JobPolicy
+	handlers  map[string]jobHandler
+	schedules []jobSchedule
+	metrics   *jobMetrics
+	cancel    context.CancelFunc
+	stop      chan struct{}
+	done      chan struct{}
+	once      sync.Once
+}
+
+// NewJobRunner creates a new JobRunner. Metrics are recorded per type of job
+// if the service base has telemetry.
+func NewJobRunner(svcBase *ServiceBase, repo jobQueueRepository, policy JobPolicy) (*JobRunner, error) {
+	metrics, err := newJobMetrics(svcBase.tel)
+	if err != nil {
+		return nil, err
+	}
+	return &JobRunner{
+		ServiceBase: svcBase,
+		repo:        repo,
+		policy:      policy,
+		handlers:    make(map[string]jobHandler),
+		schedules:   nil,
+		metrics:     metrics,
+		cancel:      func() {},
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		once:        sync.Once{},
+	}, nil
+}
+
+// HandleJob makes a handler run every job of a type, with the payload of the
+// job. A handler returns an error wrapping domain.ErrJobRejected if the job
+// cannot succeed, however often it is retried. Jobs run at least once, so a
+// handler must tolerate running the same job twice. HandleJob must be called
+// before the runner is started.
+func HandleJob[T any](r *JobRunner, typ JobType[T], handler func(ctx context.Context, payload T) error) {
+	r.handlers[typ.name] = func(ctx context.Context, job *domain.Job) error {
+		var payload T
+		err := json.Unmarshal(job.Payload, &payload)
+		if err != nil {
+			return errors.Wrap(domain.ErrJobRejected, err.Error())
+		}
+		return handler(ctx, payload)
+	}
+}
+
+// EnqueueJob adds a job of a type to the queue, due at runAt. A job with a
+// non-empty key is not added if another job has the same key. The job joins
+// the transaction carried by ctx, if any, so that work can be deferred along
+// with the change that calls for it.
+func EnqueueJob[T any](
+	ctx context.Context,
+	r *JobRunner,
+	typ JobType[T],
+	key string,
+	payload T,
+	runAt time.Time,
+) (*domain.Job, error) {
+	job, err := domain.NewJob(typ.name, payload, runAt)
+	if err != nil {
+		return nil, err
+	}
+	job.Key = key
+	err = r.repo.Insert(ctx, job)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to enqueue job")
+	}
+	return job, nil
+}
+
+// ScheduleJob makes the runner enqueue a job of a type whenever a cron
+// expression, such as "*/15 * * * *", is due. The name of the schedule must
+// be unique and stable, since the next run of the schedule is recorded
+// under it. Runs missed while no runner was up are made up for by a single
+// job. ScheduleJob must be called before the runner is started.
+func ScheduleJob[T any](r *JobRunner, name, expr string, typ JobType[T], payload T) error {
+	schedule, err := cron.Parse(expr)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return errors.Wrapf(cron.ErrInvalidExpression, "%q is never due", expr)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode job payload")
+	}
+	r.schedules = append(r.schedules, jobSchedule{name: name, schedule: schedule, typ: typ.name, payload: data})
+	return nil
+}
+
+// ScheduleReaping makes the runner delete expired records from a repository,
+// such as sessions, whenever a cron expression is due. Unlike a Reaper, the
+// records are reaped by a single instance at a time.
+func ScheduleReaping(r *JobRunner, name, expr string, repo expiredDeleter) error {
+	typ := NewJobType[struct{}]("reap." + name)
+	HandleJob(r, typ, func(ctx context.Context, _ struct{}) error {
+		n, err := repo.DeleteExpired(ctx, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			r.logger.Info("reaped expired records", "records", name, "count", n)
+		}
+		return nil
+	})
+	return ScheduleJob(r, typ.name, expr, typ, struct{}{})
+}
+
+// MaintenanceJobs are the recurring jobs that keep the backend tidy. A nil
+// field leaves its job unscheduled, such as when a Reaper or a background
+// dispatcher does the work instead.
+type MaintenanceJobs struct {
+	// Sessions are the sessions whose expired ones are reaped every 15
+	// minutes.
+	Sessions expiredDeleter
+	// Privacy erases the customers whose grace period is over, every hour.
+	Privacy *PrivacyService
+	// Mail retries the email that is due again, every minute.
+	Mail *MailDispatcher
+}
+
+// ScheduleMaintenance registers the handlers and schedules of the
+// maintenance jobs with the runner, so that each runs on one instance at a
+// time. It must be called before the runner is started.
+func ScheduleMaintenance(r *JobRunner, jobs MaintenanceJobs) error {
+	if jobs.Sessions != nil {
+		err := ScheduleReaping(r, "sessions", "*/15 * * * *", jobs.Sessions)
+		if err != nil {
+			return errors.Wrap(err, "failed to schedule session reaping")
+		}
+	}
+	if jobs.Privacy != nil {
+		err := ScheduleReaping(r, "erasures", "0 * * * *", ExpiredDeleterFunc(jobs.Privacy.EraseDue))
+		if err != nil {
+			return errors.Wrap(err, "failed to schedule customer erasures")
+		}
+	}
+	if jobs.Mail != nil {
+		typ := NewJobType[struct{}]("mail.dispatch")
+		HandleJob(r, typ, func(ctx context.Context, _ struct{}) error {
+			_, err := jobs.Mail.Dispatch(ctx)
+			return err
+		})
+		err := ScheduleJob(r, typ.name, "* * * * *", typ, struct{}{})
+		if err != nil {
+			return errors.Wrap(err, "failed to schedule email retries")
+		}
+	}
+	return nil
+}
+
+// Start starts running jobs in the background until the context is
+// cancelled or the runner is closed.
+func (r *JobRunner) Start(ctx context.Context) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	r.cancel = cancel
+	go func() {
+		defer close(r.done)
+		defer cancel()
+		ticker := time.NewTicker(r.policy.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				if len(r.schedules) > 0 {
+					_, err := r.ScheduleDue(ctx, time.Now().UTC())
+					if err != nil {
+						r.logger.Error("failed to schedule jobs", "error", err)
+					}
+				}
+				_, err := r.RunDue(jobCtx)
+				if err != nil {
+					r.logger.Error("failed to run jobs", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops claiming jobs and waits for running jobs to finish. Jobs still
+// running after the drain timeout of the policy are cancelled, and run again
+// once their lease runs out. Close is safe to call more than once, but the
+// runner must have been started.
+func (r *JobRunner) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	select {
+	case <-r.done:
+	case <-time.After(r.policy.DrainTimeout):
+		r.logger.Warn("jobs did not finish in time, cancelling them")
+		r.cancel()
+		<-r.done
+	}
+	return nil
+}
+
+// Retry makes a dead job run again right away, such as once the cause of
+// its failures was fixed.
+func (r *JobRunner) Retry(ctx context.Context, id string) error {
+	return r.repo.Retry(ctx, id, time.Now().UTC())
+}
+
+// RunDue runs one batch of due jobs, up to the concurrency of the policy at
+// a time, and returns how many jobs succeeded.
+func (r *JobRunner) RunDue(ctx context.Context) (int, error) {
+	jobs, err := r.repo.ClaimDue(ctx, time.Now().UTC(), r.policy.BatchSize, r.policy.Lease)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to claim jobs")
+	}
+	var succeeded atomic.Int64
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(r.policy.Concurrency, 1))
+	for _, job := range jobs {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if r.run(ctx, job) {
+				succeeded.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	return int(succeeded.Load()), nil
+}
+
+// ScheduleDue enqueues a job for every schedule that is due at a point in
+// time, if no other runner is doing so, and returns how many were enqueued.
+// Jobs are keyed by their schedule and due time, so a run is never enqueued
+// twice.
+func (r *JobRunner) ScheduleDue(ctx context.Context, at time.Time) (int, error) {
+	enqueued := 0
+	_, err := r.repo.WithLeaderLock(ctx, func(ctx context.Context) error {
+		for _, s := range r.schedules {
+			next, err := r.repo.NextRun(ctx, s.name)
+			if errors.Is(err, domain.ErrJobScheduleNotFound) {
+				// A new schedule is first due at its next run.
+				err = r.repo.SetNextRun(ctx, s.name, s.schedule.Next(at))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if next.After(at) {
+				continue
+			}
+
+			job, err := domain.NewJob(s.typ, s.payload, next)
+			if err != nil {
+				return err
+			}
+			job.Key = s.name + "@" + next.UTC().Format(time.RFC3339)
+			err = r.repo.Insert(ctx, job)
+			if err != nil {
+				return errors.Wrapf(err, "failed to enqueue job of schedule %s", s.name)
+			}
+			err = r.repo.SetNextRun(ctx, s.name, s.schedule.Next(at))
+			if err != nil {
+				return err
+			}
+			enqueued++
+		}
+		return nil
+	})
+	return enqueued, err
+}
+
+// run runs a job and records the outcome. It reports whether the job
+// succeeded.
+func (r *JobRunner) run(ctx context.Context, job *domain.Job) bool {
+	start := time.Now()
+	err := r.call(ctx, job)
+	r.metrics.recordDuration(ctx, job.Type, time.Since(start))
+
+	// The outcome is recorded even if the job was cancelled by a drain.
+	ctx = context.WithoutCancel(ctx)
+	now := time.Now().UTC()
+	if err == nil {
+		r.metrics.recordSucceeded(ctx, job.Type)
+		err = r.repo.MarkSucceeded(ctx, job.ID, now)
+		if err != nil {
+			// The job will run again once its lease runs out.
+			r.logger.Error("failed to mark job succeeded", "job_id", job.ID, "error", err)
+		}
+		return true
+	}
+
+	attempts := job.Attempts + 1
+	var next *time.Time
+	if attempts < r.policy.MaxAttempts && !errors.Is(err, domain.ErrJobRejected) {
+		at := now.Add(exponentialBackoff(r.policy.BaseBackoff, r.policy.MaxBackoff, attempts))
+		next = &at
+		r.metrics.recordFailed(ctx, job.Type)
+		r.logger.Warn("job failed, retrying",
+			"job_id", job.ID, "type", job.Type, "attempts", attempts, "next_run_at", at, "error", err)
+	} else {
+		r.metrics.recordDead(ctx, job.Type)
+		r.logger.Error("job failed, giving up",
+			"job_id", job.ID, "type", job.Type, "attempts", attempts, "error", err)
+	}
+
+	err = r.repo.MarkFailed(ctx, job.ID, attempts, next, err.Error(), now)
+	if err != nil {
+		r.logger.Error("failed to mark job failed", "job_id", job.ID, "error", err)
+	}
+	return false
+}
+
+// call runs the handler of a job. A handler that panics fails the job
+// rather than the runner.
+func (r *JobRunner) call(ctx context.Context, job *domain.Job) (err error) {
+	handler, ok := r.handlers[job.Type]
+	if !ok {
+		// Another runner, such as a newer version, may know the type.
+		return errors.Errorf("no handler for job type %s", job.Type)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = errors.Errorf("job panicked: %v", p)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// jobMetrics records job metrics per type of job.
+type jobMetrics struct {
+	succeeded otelmetric.Int64UpDownCounter
+	failed    otelmetric.Int64UpDownCounter
+	dead      otelmetric.Int64UpDownCounter
+	duration  otelmetric.Int64Histogram
+}
+
+// newJobMetrics creates the job instruments. Without instruments, metrics
+// are discarded.
+func newJobMetrics(tel telemetry.Instruments) (*jobMetrics, error) {
+	if tel == nil {
+		meter := noop.NewMeterProvider().Meter("")
+		succeeded, _ := meter.Int64UpDownCounter("")
+		failed, _ := meter.Int64UpDownCounter("")
+		dead, _ := meter.Int64UpDownCounter("")
+		duration, _ := meter.Int64Histogram("")
+		return &jobMetrics{succeeded: succeeded, failed: failed, dead: dead, duration: duration}, nil
+	}
+
+	succeeded, err := tel.UpDownCounter(telemetry.MetricJobsSucceededTotal)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := tel.UpDownCounter(telemetry.MetricJobFailuresTotal)
+	if err != nil {
+		return nil, err
+	}
+	dead, err := tel.UpDownCounter(telemetry.MetricJobsDeadTotal)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := tel.Histogram(telemetry.MetricJobDurationMillis)
+	if err != nil {
+		return nil, err
+	}
+	return &jobMetrics{succeeded: succeeded, failed: failed, dead: dead, duration: duration}, nil
+}
+
+func (m *jobMetrics) recordSucceeded(ctx context.Context, typ string) {
+	m.succeeded.Add(ctx, 1, jobTypeAttribute(typ))
+}
+
+func (m *jobMetrics) recordFailed(ctx context.Context, typ string) {
+	m.failed.Add(ctx, 1, jobTypeAttribute(typ))
+}
+
+func (m *jobMetrics) recordDead(ctx context.Context, typ string) {
+	m.dead.Add(ctx, 1, jobTypeAttribute(typ))
+}
+
+func (m *jobMetrics) recordDuration(ctx context.Context, typ string, d time.Duration) {
+	m.duration.Record(ctx, d.Milliseconds(), jobTypeAttribute(typ))
+}
+
+func jobTypeAttribute(typ string) otelmetric.MeasurementOption {
+	return otelmetric.WithAttributes(attribute.String("job_type", typ))
+}
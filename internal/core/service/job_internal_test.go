@@ -0,0 +1,416 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/cron"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+type testJobPayload struct {
+	CustomerID string `json:"customer_id"`
+}
+
+//nolint:gochecknoglobals // A test fixture.
+var testJobType = NewJobType[testJobPayload]("test.job")
+
+// recordingJobHandler fails the runs it is told to, and records the rest.
+type recordingJobHandler struct {
+	mu       sync.Mutex
+	failures []error
+	payloads []testJobPayload
+}
+
+func (h *recordingJobHandler) handle(_ context.Context, payload testJobPayload) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.failures) > 0 {
+		err := h.failures[0]
+		h.failures = h.failures[1:]
+		return err
+	}
+	h.payloads = append(h.payloads, payload)
+	return nil
+}
+
+type jobFixture struct {
+	repo    *memory.JobRepository
+	runner  *JobRunner
+	handler *recordingJobHandler
+	metrics *fakeInstruments
+}
+
+func newJobFixture(t *testing.T) jobFixture {
+	t.Helper()
+	repo := memory.NewJobRepository()
+	policy := DefaultJobPolicy()
+	policy.MaxAttempts = 3
+	policy.PollInterval = time.Millisecond
+	runner, err := NewJobRunner(NewServiceBase(test.NewMockLogger(), nil), repo, policy)
+	assert.NoError(t, err)
+	metrics := newFakeInstruments()
+	runner.metrics, err = newJobMetrics(metrics)
+	assert.NoError(t, err)
+
+	handler := &recordingJobHandler{}
+	HandleJob(runner, testJobType, handler.handle)
+	return jobFixture{repo: repo, runner: runner, handler: handler, metrics: metrics}
+}
+
+// enqueue adds a job that is due now.
+func (f jobFixture) enqueue(t *testing.T) *domain.Job {
+	t.Helper()
+	job, err := EnqueueJob(context.Background(), f.runner, testJobType, "", testJobPayload{CustomerID: "c1"}, time.Now())
+	assert.NoError(t, err)
+	return job
+}
+
+// due makes a job due now.
+func (f jobFixture) due(t *testing.T, id string) {
+	t.Helper()
+	job, err := f.repo.GetByID(context.Background(), id)
+	assert.NoError(t, err)
+	job.RunAt = time.Now()
+	f.repo.Update(job)
+}
+
+func (f jobFixture) get(t *testing.T, id string) *domain.Job {
+	t.Helper()
+	job, err := f.repo.GetByID(context.Background(), id)
+	assert.NoError(t, err)
+	return job
+}
+
+func TestJobRunner_Runs(t *testing.T) {
+	ctx := context.Background()
+	f := newJobFixture(t)
+
+	job := f.enqueue(t)
+	n, err := f.runner.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, len(f.handler.payloads), 1)
+	assert.Equal(t, f.handler.payloads[0].CustomerID, "c1")
+	assert.Equal(t, f.get(t, job.ID).Status, domain.JobStatusSucceeded)
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricJobsSucceededTotal), int64(1))
+
+	// A job that succeeded is not run again.
+	n, err = f.runner.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+}
+
+func TestJobRunner_DeduplicatesByKey(t *testing.T) {
+	ctx := context.Background()
+	f := newJobFixture(t)
+
+	for range 2 {
+		_, err := EnqueueJob(ctx, f.runner, testJobType, "order-1", testJobPayload{CustomerID: "c1"}, time.Now())
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, len(f.repo.List()), 1)
+}
+
+func TestJobRunner_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	f := newJobFixture(t)
+	f.handler.failures = []error{errors.New("connection refused")}
+
+	job := f.enqueue(t)
+	before := time.Now()
+	n, err := f.runner.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricJobFailuresTotal), int64(1))
+
+	got := f.get(t, job.ID)
+	assert.Equal(t, got.Status, domain.JobStatusPending)
+	assert.Equal(t, got.Attempts, 1)
+	assert.Equal(t, got.LastError, "connection refused")
+	base := f.runner.policy.BaseBackoff
+	assert.False(t, got.RunAt.Before(before.Add(base)))
+	assert.True(t, got.RunAt.Before(time.Now().Add(base+base/5)))
+
+	// The job is not retried before it is due.
+	n, err = f.runner.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+
+	f.due(t, job.ID)
+	n, err = f.runner.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, f.get(t, job.ID).Attempts, 2)
+}
+
+func TestJobRunner_GivesUp(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		test.CaseBase
+		failures []error
+	}{
+		{
+			CaseBase: test.NewCaseBase("rejected", 1, true),
+			failures: []error{errors.Wrap(domain.ErrJobRejected, "customer is gone")},
+		},
+		{
+			CaseBase: test.NewCaseBase("too many attempts", 3, true),
+			failures: []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newJobFixture(t)
+				f.handler.failures = tt.failures
+				job := f.enqueue(t)
+
+				for range tt.failures {
+					f.due(t, job.ID)
+					_, err := f.runner.RunDue(ctx)
+					assert.NoError(t, err)
+				}
+
+				got := f.get(t, job.ID)
+				assert.Equal(t, got.Status, domain.JobStatusDead)
+				assert.Equal(t, got.Attempts, tt.Want.(int))
+				assert.True(t, got.FinishedAt != nil)
+				assert.Equal(t, len(f.handler.payloads), 0)
+				assert.Equal(t, f.metrics.sum(t, telemetry.MetricJobsDeadTotal), int64(1))
+
+				// A dead job is run again once retried.
+				assert.NoError(t, f.runner.Retry(ctx, job.ID))
+				n, err := f.runner.RunDue(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, n, 1)
+				assert.Equal(t, f.get(t, job.ID).Status, domain.JobStatusSucceeded)
+			},
+		)
+	}
+}
+
+func TestJobRunner_RejectsMalformedJobs(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		test.CaseBase
+		typ     string
+		payload string
+	}{
+		{
+			CaseBase: test.NewCaseBase("malformed payload", domain.JobStatusDead, false),
+			typ:      testJobType.Name(),
+			payload:  `"not an object"`,
+		},
+		{
+			CaseBase: test.NewCaseBase("panicking handler", domain.JobStatusPending, false),
+			typ:      "test.panic",
+			payload:  `{}`,
+		},
+		{
+			CaseBase: test.NewCaseBase("unknown type", domain.JobStatusPending, false),
+			typ:      "test.unknown",
+			payload:  `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newJobFixture(t)
+				HandleJob(f.runner, NewJobType[struct{}]("test.panic"), func(context.Context, struct{}) error {
+					panic("boom")
+				})
+				job, err := domain.NewJob(tt.typ, nil, time.Now())
+				assert.NoError(t, err)
+				job.Payload = []byte(tt.payload)
+				assert.NoError(t, f.repo.Insert(ctx, job))
+
+				n, err := f.runner.RunDue(ctx)
+				assert.NoError(t, err)
+				assert.Equal(t, n, 0)
+				got := f.get(t, job.ID)
+				assert.Equal(t, got.Status, tt.Want.(domain.JobStatus))
+				assert.Equal(t, got.Attempts, 1)
+			},
+		)
+	}
+}
+
+func TestJobRunner_ScheduleDue(t *testing.T) {
+	ctx := context.Background()
+	f := newJobFixture(t)
+	assert.NoError(t, ScheduleJob(f.runner, "hourly", "@hourly", testJobType, testJobPayload{CustomerID: "c1"}))
+
+	// A new schedule is first due at its next run.
+	now := time.Date(2025, 6, 1, 10, 30, 0, 0, time.UTC)
+	n, err := f.runner.ScheduleDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+	next, err := f.repo.NextRun(ctx, "hourly")
+	assert.NoError(t, err)
+	assert.Equal(t, next, time.Date(2025, 6, 1, 11, 0, 0, 0, time.UTC))
+
+	// Runs missed while no runner was up are made up for by a single job.
+	now = time.Date(2025, 6, 1, 13, 5, 0, 0, time.UTC)
+	n, err = f.runner.ScheduleDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 1)
+	n, err = f.runner.ScheduleDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+	next, err = f.repo.NextRun(ctx, "hourly")
+	assert.NoError(t, err)
+	assert.Equal(t, next, time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC))
+
+	jobs := f.repo.List()
+	assert.Equal(t, len(jobs), 1)
+	assert.Equal(t, jobs[0].Key, "hourly@2025-06-01T11:00:00Z")
+
+	n, err = f.runner.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 1)
+	assert.Equal(t, f.handler.payloads[0].CustomerID, "c1")
+}
+
+func TestJobRunner_ScheduleDueOnlyOnLeader(t *testing.T) {
+	ctx := context.Background()
+	f := newJobFixture(t)
+	assert.NoError(t, ScheduleJob(f.runner, "hourly", "@hourly", testJobType, testJobPayload{CustomerID: "c1"}))
+	past := time.Now().Add(-time.Hour)
+	assert.NoError(t, f.repo.SetNextRun(ctx, "hourly", past))
+
+	// Another runner is the leader.
+	leader, err := f.repo.WithLeaderLock(ctx, func(ctx context.Context) error {
+		n, err := f.runner.ScheduleDue(ctx, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, n, 0)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, leader)
+	assert.Equal(t, len(f.repo.List()), 0)
+
+	n, err := f.runner.ScheduleDue(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, n, 1)
+}
+
+func TestScheduleJob_Invalid(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		expr string
+	}{
+		{CaseBase: test.NewCaseBase("malformed", nil, true), expr: "every hour"},
+		{CaseBase: test.NewCaseBase("never due", nil, true), expr: "0 0 30 2 *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newJobFixture(t)
+				err := ScheduleJob(f.runner, "invalid", tt.expr, testJobType, testJobPayload{})
+				assert.Error(t, err, cron.ErrInvalidExpression)
+			},
+		)
+	}
+}
+
+func TestScheduleMaintenance(t *testing.T) {
+	ctx := context.Background()
+	f := newJobFixture(t)
+
+	sessions := memory.NewSessionRepository()
+	expired, err := domain.NewUserSession("customer", domain.ClientInfo{}, -time.Minute, time.Hour)
+	assert.NoError(t, err)
+	assert.NoError(t, sessions.Insert(ctx, expired))
+
+	admin := newAdminFixture(t)
+	policy := DefaultPrivacyPolicy()
+	policy.ErasureGracePeriod = 0
+	privacy := NewPrivacyService(
+		NewServiceBase(test.NewMockLogger(), nil),
+		admin.customers, admin.orders, admin.downloads, admin.sessions, admin.authz, policy,
+	)
+	privacy.audit = admin.audit
+	customer, err := admin.customers.GetByID(ctx, admin.buyer.Customer.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, admin.customers.Delete(ctx, customer))
+
+	m := newMailFixture(t)
+	m.send(t)
+
+	err = ScheduleMaintenance(f.runner, MaintenanceJobs{Sessions: sessions, Privacy: privacy, Mail: m.dispatcher})
+	assert.NoError(t, err)
+	now := time.Now().UTC()
+	n, err := f.runner.ScheduleDue(ctx, now.Add(-2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, n, 0)
+	n, err = f.runner.ScheduleDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 3)
+
+	n, err = f.runner.RunDue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, 3)
+	for _, job := range f.repo.List() {
+		assert.Equal(t, job.Status, domain.JobStatusSucceeded)
+	}
+	_, err = sessions.GetByID(ctx, expired.ID)
+	assert.Error(t, err, domain.ErrSessionNotFound)
+	erased, err := admin.customers.GetByIDIncludingDeleted(ctx, customer.ID)
+	assert.NoError(t, err)
+	assert.True(t, erased.ErasedAt != nil)
+	assert.Equal(t, len(m.transport.delivered), 1)
+}
+
+func TestJobRunner_DrainsOnClose(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		drainTimeout time.Duration
+	}{
+		{CaseBase: test.NewCaseBase("finishes", domain.JobStatusSucceeded, false), drainTimeout: time.Minute},
+		{CaseBase: test.NewCaseBase("cancelled", domain.JobStatusPending, false), drainTimeout: time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newJobFixture(t)
+				f.runner.policy.DrainTimeout = tt.drainTimeout
+				started := make(chan struct{})
+				typ := NewJobType[struct{}]("test.slow")
+				HandleJob(f.runner, typ, func(ctx context.Context, _ struct{}) error {
+					close(started)
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(50 * time.Millisecond):
+						return nil
+					}
+				})
+				job, err := EnqueueJob(context.Background(), f.runner, typ, "", struct{}{}, time.Now())
+				assert.NoError(t, err)
+
+				f.runner.Start(context.Background())
+				<-started
+				assert.NoError(t, f.runner.Close())
+				assert.Equal(t, f.get(t, job.ID).Status, tt.Want.(domain.JobStatus))
+			},
+		)
+	}
+}
@@ -0,0 +1,335 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/common/utils/loggers"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// Mailer sends transactional email to customers.
+type Mailer interface {
+	Send(ctx context.Context, msg domain.EmailMessage) error
+}
+
+// logMailer is a mailer that only logs that an email would have been sent.
+// It is meant for local development. The data is not logged, since it
+// usually carries a secret link.
+type logMailer struct {
+	logger loggers.Logger
+}
+
+// NewLogMailer creates a mailer that logs emails instead of sending them.
+func NewLogMailer(logger loggers.Logger) Mailer {
+	return logMailer{logger: logger}
+}
+
+func (l logMailer) Send(_ context.Context, msg domain.EmailMessage) error {
+	l.logger.Info("email not sent, no mailer configured", "to", msg.To, "template", msg.Template.String())
+	return nil
+}
+
+// emailRenderer renders an email with its template.
+type emailRenderer interface {
+	Render(msg domain.EmailMessage) (*domain.RenderedEmail, error)
+}
+
+// emailTransport delivers rendered email, for example over SMTP. Delivery
+// failures that retrying cannot fix wrap domain.ErrEmailRejected.
+type emailTransport interface {
+	Deliver(ctx context.Context, email *domain.RenderedEmail) error
+}
+
+// emailOutboxRepository stores emails waiting to be delivered.
+type emailOutboxRepository interface {
+	Insert(ctx context.Context, email *domain.OutboxEmail) error
+	// ClaimDue returns up to limit pending emails that are due at a point in
+	// time, and pushes their next attempt back by lease, so that no other
+	// dispatcher claims them while they are delivered.
+	ClaimDue(ctx context.Context, at time.Time, limit int, lease time.Duration) ([]*domain.OutboxEmail, error)
+	// MarkSent marks an email as delivered. The body of a sent email is
+	// discarded, since it may carry a secret link.
+	MarkSent(ctx context.Context, id string, at time.Time) error
+	// MarkFailed records a failed attempt. A nil next attempt marks the
+	// email as dead, and it is never attempted again.
+	MarkFailed(ctx context.Context, id string, attempts int, next *time.Time, lastError string) error
+}
+
+// outboxMailer is a mailer that renders email and writes it to the outbox.
+type outboxMailer struct {
+	renderer emailRenderer
+	outbox   emailOutboxRepository
+}
+
+// NewOutboxMailer creates a mailer that renders emails and writes them to an
+// outbox. The emails are delivered later by a MailDispatcher.
+func NewOutboxMailer(renderer emailRenderer, outbox emailOutboxRepository) Mailer {
+	return outboxMailer{renderer: renderer, outbox: outbox}
+}
+
+func (o outboxMailer) Send(ctx context.Context, msg domain.EmailMessage) error {
+	rendered, err := o.renderer.Render(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to render email")
+	}
+	email, err := domain.NewOutboxEmail(msg.Template, rendered)
+	if err != nil {
+		return err
+	}
+	err = o.outbox.Insert(ctx, email)
+	if err != nil {
+		return errors.Wrap(err, "failed to write email to outbox")
+	}
+	return nil
+}
+
+// DeliveryPolicy configures how a MailDispatcher delivers email, and how an
+// EventRelay publishes domain events.
+type DeliveryPolicy struct {
+	// PollInterval is how often the outbox is checked for due email.
+	PollInterval time.Duration
+	// BatchSize is how many emails are claimed at once.
+	BatchSize int
+	// Lease is how long a claimed email is hidden from other dispatchers.
+	// It must be longer than a delivery takes.
+	Lease time.Duration
+	// MaxAttempts is how many times delivery is attempted before the email
+	// is given up on.
+	MaxAttempts int
+	// BaseBackoff is how long to wait after the first failed attempt. The
+	// wait doubles with every attempt, up to MaxBackoff.
+	BaseBackoff time.Duration
+	// MaxBackoff is the longest wait between two attempts.
+	MaxBackoff time.Duration
+}
+
+// DefaultDeliveryPolicy returns a policy that checks the outbox every few
+// seconds, and retries a failed email for about eight hours.
+func DefaultDeliveryPolicy() DeliveryPolicy {
+	return DeliveryPolicy{
+		PollInterval: 5 * time.Second,
+		BatchSize:    20,
+		Lease:        time.Minute,
+		MaxAttempts:  10,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   4 * time.Hour,
+	}
+}
+
+// backoff returns how long to wait after a number of failed attempts. Up to a
+// fifth of the wait is added at random, so that emails that failed together
+// are not retried together.
+func (d DeliveryPolicy) backoff(attempts int) time.Duration {
+	return exponentialBackoff(d.BaseBackoff, d.MaxBackoff, attempts)
+}
+
+// exponentialBackoff returns how long to wait after a number of failed
+// attempts, starting at base and doubling with every attempt, up to max. Up
+// to a fifth of the wait is added at random.
+func exponentialBackoff(base, maxWait time.Duration, attempts int) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < maxWait; i++ {
+		wait *= 2
+	}
+	wait = min(wait, maxWait)
+	const jitterDivisor = 5
+	if jitter := int64(wait / jitterDivisor); jitter > 0 {
+		wait += time.Duration(rand.Int64N(jitter)) //nolint:gosec // Jitter needs no secure randomness.
+	}
+	return wait
+}
+
+// MailDispatcher delivers email from the outbox in the background. Failed
+// deliveries are retried with exponential backoff. MailDispatcher implements
+// io.Closer, so it can take part in a global teardown.
+type MailDispatcher struct {
+	*ServiceBase
+	outbox    emailOutboxRepository
+	transport emailTransport
+	policy    DeliveryPolicy
+	metrics   *mailMetrics
+	stop      chan struct{}
+	done      chan struct{}
+	once      sync.Once
+}
+
+// NewMailDispatcher creates a new MailDispatcher. Delivery metrics are
+// recorded if the service base has telemetry.
+func NewMailDispatcher(
+	svcBase *ServiceBase,
+	outbox emailOutboxRepository,
+	transport emailTransport,
+	policy DeliveryPolicy,
+) (*MailDispatcher, error) {
+	metrics, err := newMailMetrics(svcBase.tel)
+	if err != nil {
+		return nil, err
+	}
+	return &MailDispatcher{
+		ServiceBase: svcBase,
+		outbox:      outbox,
+		transport:   transport,
+		policy:      policy,
+		metrics:     metrics,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		once:        sync.Once{},
+	}, nil
+}
+
+// Start starts delivering in the background until the context is cancelled
+// or the dispatcher is closed.
+func (m *MailDispatcher) Start(ctx context.Context) {
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.policy.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.stop:
+				return
+			case <-ticker.C:
+				_, err := m.Dispatch(ctx)
+				if err != nil {
+					m.logger.Error("failed to dispatch email", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the dispatcher and waits for it to finish. Close is safe to
+// call more than once, but the dispatcher must have been started.
+func (m *MailDispatcher) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	<-m.done
+	return nil
+}
+
+// Dispatch delivers one batch of due email, and returns how many emails were
+// delivered.
+func (m *MailDispatcher) Dispatch(ctx context.Context) (int, error) {
+	emails, err := m.outbox.ClaimDue(ctx, time.Now().UTC(), m.policy.BatchSize, m.policy.Lease)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to claim email")
+	}
+	sent := 0
+	for _, email := range emails {
+		if m.deliver(ctx, email) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// deliver attempts to deliver an email and records the outcome. It reports
+// whether the email was delivered.
+func (m *MailDispatcher) deliver(ctx context.Context, email *domain.OutboxEmail) bool {
+	start := time.Now()
+	err := m.transport.Deliver(ctx, &email.RenderedEmail)
+	m.metrics.recordDuration(ctx, email.Template, time.Since(start))
+
+	now := time.Now().UTC()
+	if err == nil {
+		m.metrics.recordSent(ctx, email.Template)
+		err = m.outbox.MarkSent(ctx, email.ID, now)
+		if err != nil {
+			// The email will be delivered again once its lease runs out.
+			m.logger.Error("failed to mark email sent", "email_id", email.ID, "error", err)
+		}
+		return true
+	}
+
+	attempts := email.Attempts + 1
+	var next *time.Time
+	if attempts < m.policy.MaxAttempts && !errors.Is(err, domain.ErrEmailRejected) {
+		at := now.Add(m.policy.backoff(attempts))
+		next = &at
+		m.metrics.recordFailed(ctx, email.Template)
+		m.logger.Warn("failed to deliver email, retrying",
+			"email_id", email.ID, "attempts", attempts, "next_attempt_at", at, "error", err)
+	} else {
+		m.metrics.recordDead(ctx, email.Template)
+		m.logger.Error("failed to deliver email, giving up",
+			"email_id", email.ID, "attempts", attempts, "error", err)
+	}
+
+	err = m.outbox.MarkFailed(ctx, email.ID, attempts, next, err.Error())
+	if err != nil {
+		m.logger.Error("failed to mark email failed", "email_id", email.ID, "error", err)
+	}
+	return false
+}
+
+// mailMetrics records email delivery metrics.
+type mailMetrics struct {
+	sent     otelmetric.Int64UpDownCounter
+	failed   otelmetric.Int64UpDownCounter
+	dead     otelmetric.Int64UpDownCounter
+	duration otelmetric.Int64Histogram
+}
+
+// newMailMetrics creates the email delivery instruments. Without
+// instruments, metrics are discarded.
+func newMailMetrics(tel telemetry.Instruments) (*mailMetrics, error) {
+	if tel == nil {
+		meter := noop.NewMeterProvider().Meter("")
+		sent, _ := meter.Int64UpDownCounter("")
+		failed, _ := meter.Int64UpDownCounter("")
+		dead, _ := meter.Int64UpDownCounter("")
+		duration, _ := meter.Int64Histogram("")
+		return &mailMetrics{sent: sent, failed: failed, dead: dead, duration: duration}, nil
+	}
+
+	sent, err := tel.UpDownCounter(telemetry.MetricEmailsSentTotal)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := tel.UpDownCounter(telemetry.MetricEmailDeliveryFailuresTotal)
+	if err != nil {
+		return nil, err
+	}
+	dead, err := tel.UpDownCounter(telemetry.MetricEmailsDeadTotal)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := tel.Histogram(telemetry.MetricEmailDeliveryDurationMillis)
+	if err != nil {
+		return nil, err
+	}
+	return &mailMetrics{sent: sent, failed: failed, dead: dead, duration: duration}, nil
+}
+
+func (m *mailMetrics) recordSent(ctx context.Context, template domain.EmailTemplate) {
+	m.sent.Add(ctx, 1, templateAttribute(template))
+}
+
+func (m *mailMetrics) recordFailed(ctx context.Context, template domain.EmailTemplate) {
+	m.failed.Add(ctx, 1, templateAttribute(template))
+}
+
+func (m *mailMetrics) recordDead(ctx context.Context, template domain.EmailTemplate) {
+	m.dead.Add(ctx, 1, templateAttribute(template))
+}
+
+func (m *mailMetrics) recordDuration(ctx context.Context, template domain.EmailTemplate, d time.Duration) {
+	m.duration.Record(ctx, d.Milliseconds(), templateAttribute(template))
+}
+
+func templateAttribute(template domain.EmailTemplate) otelmetric.MeasurementOption {
+	return otelmetric.WithAttributes(attribute.String("template", template.String()))
+}
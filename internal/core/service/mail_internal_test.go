@@ -0,0 +1,302 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/mail"
+	"go.brokedaear.com/internal/adapters/mail/mailtest"
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// fakeTransport fails the deliveries it is told to, and keeps the rest.
+type fakeTransport struct {
+	mu        sync.Mutex
+	failures  []error
+	delivered []domain.RenderedEmail
+}
+
+func (f *fakeTransport) Deliver(_ context.Context, email *domain.RenderedEmail) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		return err
+	}
+	f.delivered = append(f.delivered, *email)
+	return nil
+}
+
+// fakeInstruments records metrics in memory, so that tests can read them.
+type fakeInstruments struct {
+	reader *sdkmetric.ManualReader
+	meter  otelmetric.Meter
+}
+
+func newFakeInstruments() *fakeInstruments {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	return &fakeInstruments{reader: reader, meter: provider.Meter("test")}
+}
+
+func (f *fakeInstruments) Histogram(m telemetry.Metric) (otelmetric.Int64Histogram, error) {
+	return f.meter.Int64Histogram(m.Name)
+}
+
+func (f *fakeInstruments) UpDownCounter(m telemetry.Metric) (otelmetric.Int64UpDownCounter, error) {
+	return f.meter.Int64UpDownCounter(m.Name)
+}
+
+func (f *fakeInstruments) Gauge(m telemetry.Metric) (otelmetric.Int64Gauge, error) {
+	return f.meter.Int64Gauge(m.Name)
+}
+
+func (f *fakeInstruments) TraceStart(
+	ctx context.Context,
+	name string,
+	opts ...oteltrace.SpanStartOption,
+) (context.Context, oteltrace.Span) {
+	return noop.NewTracerProvider().Tracer("test").Start(ctx, name, opts...)
+}
+
+// sum returns the total of a counter.
+func (f *fakeInstruments) sum(t *testing.T, m telemetry.Metric) int64 {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, f.reader.Collect(context.Background(), &rm))
+	var total int64
+	for _, scope := range rm.ScopeMetrics {
+		for _, metric := range scope.Metrics {
+			if metric.Name != m.Name {
+				continue
+			}
+			if data, ok := metric.Data.(metricdata.Sum[int64]); ok {
+				for _, point := range data.DataPoints {
+					total += point.Value
+				}
+			}
+		}
+	}
+	return total
+}
+
+type mailFixture struct {
+	outbox     *memory.EmailOutboxRepository
+	transport  *fakeTransport
+	dispatcher *MailDispatcher
+	mailer     Mailer
+	metrics    *fakeInstruments
+}
+
+func newMailFixture(t *testing.T) mailFixture {
+	t.Helper()
+	branding, err := mail.BrandingFor(domain.EnvProduction)
+	assert.NoError(t, err)
+	renderer, err := mail.NewRenderer(branding)
+	assert.NoError(t, err)
+
+	outbox := memory.NewEmailOutboxRepository()
+	transport := &fakeTransport{}
+	policy := DefaultDeliveryPolicy()
+	policy.MaxAttempts = 3
+	dispatcher, err := NewMailDispatcher(NewServiceBase(test.NewMockLogger(), nil), outbox, transport, policy)
+	assert.NoError(t, err)
+	metrics := newFakeInstruments()
+	dispatcher.metrics, err = newMailMetrics(metrics)
+	assert.NoError(t, err)
+
+	return mailFixture{
+		outbox:     outbox,
+		transport:  transport,
+		dispatcher: dispatcher,
+		mailer:     NewOutboxMailer(renderer, outbox),
+		metrics:    metrics,
+	}
+}
+
+// send writes a password changed email to the outbox.
+func (f mailFixture) send(t *testing.T) {
+	t.Helper()
+	err := f.mailer.Send(context.Background(), domain.EmailMessage{
+		To:       testEmail,
+		Template: domain.EmailTemplatePasswordChanged,
+		Data:     nil,
+	})
+	assert.NoError(t, err)
+}
+
+// only returns the only email in the outbox.
+func (f mailFixture) only(t *testing.T) *domain.OutboxEmail {
+	t.Helper()
+	emails := f.outbox.List()
+	assert.Equal(t, len(emails), 1)
+	return emails[0]
+}
+
+func TestMailDispatcher_Delivers(t *testing.T) {
+	ctx := context.Background()
+	f := newMailFixture(t)
+
+	f.send(t)
+	sent, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, 1)
+	assert.Equal(t, len(f.transport.delivered), 1)
+	assert.Equal(t, f.transport.delivered[0].Subject, "Your password was changed")
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricEmailsSentTotal), int64(1))
+
+	// A sent email is not delivered again, and its body is discarded.
+	sent, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, 0)
+	email := f.only(t)
+	assert.Equal(t, email.Status, domain.EmailStatusSent)
+	assert.Equal(t, email.Text, "")
+	assert.Equal(t, email.HTML, "")
+}
+
+func TestMailDispatcher_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	f := newMailFixture(t)
+	f.transport.failures = []error{errors.New("connection refused")}
+
+	f.send(t)
+	before := time.Now()
+	sent, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, 0)
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricEmailDeliveryFailuresTotal), int64(1))
+
+	email := f.only(t)
+	assert.Equal(t, email.Status, domain.EmailStatusPending)
+	assert.Equal(t, email.Attempts, 1)
+	assert.Equal(t, email.LastError, "connection refused")
+	base := f.dispatcher.policy.BaseBackoff
+	assert.False(t, email.NextAttemptAt.Before(before.Add(base)))
+	assert.True(t, email.NextAttemptAt.Before(time.Now().Add(base+base/5)))
+
+	// The email is not retried before it is due.
+	sent, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, 0)
+
+	email.NextAttemptAt = time.Now()
+	assert.NoError(t, f.outbox.Insert(ctx, email))
+	sent, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, 1)
+}
+
+func TestMailDispatcher_GivesUp(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		test.CaseBase
+		failures []error
+	}{
+		{
+			CaseBase: test.NewCaseBase("rejected", 1, true),
+			failures: []error{errors.Wrap(domain.ErrEmailRejected, "no such user")},
+		},
+		{
+			CaseBase: test.NewCaseBase("too many attempts", 3, true),
+			failures: []error{errors.New("timeout"), errors.New("timeout"), errors.New("timeout")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newMailFixture(t)
+				f.transport.failures = tt.failures
+				f.send(t)
+
+				for range tt.failures {
+					email := f.only(t)
+					email.NextAttemptAt = time.Now()
+					assert.NoError(t, f.outbox.Insert(ctx, email))
+					_, err := f.dispatcher.Dispatch(ctx)
+					assert.NoError(t, err)
+				}
+
+				email := f.only(t)
+				assert.Equal(t, email.Status, domain.EmailStatusDead)
+				assert.Equal(t, email.Attempts, tt.Want.(int))
+				assert.Equal(t, len(f.transport.delivered), 0)
+				assert.Equal(t, f.metrics.sum(t, telemetry.MetricEmailsDeadTotal), int64(1))
+				assert.Equal(
+					t, f.metrics.sum(t, telemetry.MetricEmailDeliveryFailuresTotal), int64(tt.Want.(int)-1),
+				)
+			},
+		)
+	}
+}
+
+func TestDeliveryPolicy_Backoff(t *testing.T) {
+	policy := DefaultDeliveryPolicy()
+
+	tests := []struct {
+		test.CaseBase
+		attempts int
+	}{
+		{CaseBase: test.NewCaseBase("first", 30*time.Second, false), attempts: 1},
+		{CaseBase: test.NewCaseBase("doubles", 2*time.Minute, false), attempts: 3},
+		{CaseBase: test.NewCaseBase("capped", 4*time.Hour, false), attempts: 12},
+		{CaseBase: test.NewCaseBase("no overflow", 4*time.Hour, false), attempts: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				want := tt.Want.(time.Duration)
+				got := policy.backoff(tt.attempts)
+				assert.False(t, got < want)
+				assert.True(t, got < want+want/5)
+			},
+		)
+	}
+}
+
+func TestMailDispatcher_DeliversOverSMTP(t *testing.T) {
+	ctx := context.Background()
+	srv := mailtest.NewServer()
+	defer srv.Close()
+
+	transport, err := mail.NewSMTPTransport(mail.SMTPConfig{
+		Host:      srv.Host(),
+		Port:      srv.Port(),
+		Username:  "",
+		Password:  "",
+		From:      "BROKE DA EAR <no-reply@brokedaear.com>",
+		TLS:       mail.TLSModeNone,
+		TLSConfig: nil,
+		Timeout:   5 * time.Second,
+	})
+	assert.NoError(t, err)
+	f := newMailFixture(t)
+	f.dispatcher.transport = transport
+
+	f.send(t)
+	sent, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, sent, 1)
+	assert.Equal(t, len(srv.Messages()), 1)
+	assert.Equal(t, srv.Messages()[0].To[0], testEmail)
+}
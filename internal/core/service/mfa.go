@@ -0,0 +1,424 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/totp"
+)
+
+// mfaRepository stores the TOTP authenticators and recovery codes of
+// customers.
+type mfaRepository interface {
+	// Get returns domain.ErrMFANotFound if the customer has no
+	// authenticator, pending or enabled.
+	Get(ctx context.Context, userID string) (*domain.UserMFA, error)
+	// Upsert stores the authenticator of a customer, replacing any other.
+	Upsert(ctx context.Context, mfa *domain.UserMFA) error
+	// Enable enables the authenticator of a customer, and records the time
+	// step of the code that confirmed it.
+	Enable(ctx context.Context, userID string, step int64, at time.Time) error
+	// UseStep records that a code of a time step was accepted. It returns
+	// domain.ErrMFACodeUsed if a code of that step or a later one already
+	// was, so that a code presented twice at the same time is only accepted
+	// once.
+	UseStep(ctx context.Context, userID string, step int64) error
+	// Delete removes the authenticator and the recovery codes of a customer.
+	Delete(ctx context.Context, userID string) error
+	// ReplaceRecoveryCodes replaces every recovery code of a customer.
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codes []*domain.RecoveryCode) error
+	// UseRecoveryCode marks the unused recovery code of a customer with a
+	// hash as used. It returns domain.ErrRecoveryCodeNotFound if there is
+	// none.
+	UseRecoveryCode(ctx context.Context, userID string, codeHash []byte, at time.Time) error
+	// CountRecoveryCodes counts the unused recovery codes of a customer.
+	CountRecoveryCodes(ctx context.Context, userID string) (int, error)
+}
+
+// MFAPolicy configures multi-factor authentication.
+type MFAPolicy struct {
+	// Issuer names the store in authenticator apps.
+	Issuer string
+	// EncryptionKey is the AES-256 key that TOTP secrets are encrypted with
+	// at rest. It comes from configuration, see crypto.ParseEncryptionKey.
+	EncryptionKey []byte
+	// TOTP are the parameters of the codes.
+	TOTP totp.Params
+	// RecoveryCodes is how many recovery codes a customer is given.
+	RecoveryCodes int
+	// ChallengeTTL is how long a customer has to enter a code after entering
+	// their password.
+	ChallengeTTL time.Duration
+}
+
+// DefaultMFAPolicy returns a policy with the usual 6 digit, 30 second codes,
+// 10 recovery codes, and 5 minutes to enter a code at sign in.
+func DefaultMFAPolicy(encryptionKey []byte) MFAPolicy {
+	return MFAPolicy{
+		Issuer:        "BROKE DA EAR",
+		EncryptionKey: encryptionKey,
+		TOTP:          totp.DefaultParams(),
+		RecoveryCodes: 10,
+		ChallengeTTL:  5 * time.Minute,
+	}
+}
+
+// MFAEnrollment is what a customer needs to add their account to an
+// authenticator app.
+type MFAEnrollment struct {
+	// Secret is the base32 encoded secret, for customers who type it in.
+	Secret string
+	// URI is the otpauth URI of the secret, meant to be shown as a QR code.
+	URI string
+}
+
+// MFAService lets customers protect their account with a TOTP authenticator
+// app, on top of their password.
+//
+// A customer enrolls, then confirms the enrollment with a first code, and is
+// given recovery codes in return. From then on, SignIn asks for a code after
+// the password, see AuthService.CompleteSignIn. Customers who sign in through
+// an identity provider rely on the provider's own multi-factor
+// authentication.
+type MFAService struct {
+	*ServiceBase
+	customers customerRepository
+	sessions  *SessionService
+	mfa       mfaRepository
+	tokens    tokenRepository
+	policy    MFAPolicy
+	audit     auditRecorder
+}
+
+// NewMFAService creates a new MFAService. It returns crypto.ErrInvalidKey if
+// the encryption key of the policy is not an AES-256 key.
+func NewMFAService(
+	svcBase *ServiceBase,
+	customers customerRepository,
+	sessions *SessionService,
+	mfa mfaRepository,
+	tokens tokenRepository,
+	policy MFAPolicy,
+) (*MFAService, error) {
+	if len(policy.EncryptionKey) != crypto.EncryptionKeyLength {
+		return nil, crypto.ErrInvalidKey
+	}
+	return &MFAService{
+		ServiceBase: svcBase,
+		customers:   customers,
+		sessions:    sessions,
+		mfa:         mfa,
+		tokens:      tokens,
+		policy:      policy,
+		audit:       svcBase.auditRecorder(),
+	}, nil
+}
+
+// Enroll starts the enrollment of a signed-in customer, who must have
+// authenticated recently. A pending enrollment is replaced. The enrollment
+// takes effect once confirmed with Confirm.
+func (m *MFAService) Enroll(ctx context.Context, session *domain.UserSession) (*MFAEnrollment, error) {
+	err := m.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := m.mfa.Get(ctx, session.UserID)
+	switch {
+	case err == nil && existing.Enabled():
+		return nil, ErrMFAAlreadyEnabled
+	case err != nil && !errors.Is(err, domain.ErrMFANotFound):
+		return nil, errors.Wrap(err, "failed to get mfa")
+	}
+	customer, err := m.customers.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get customer")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := crypto.Encrypt(m.policy.EncryptionKey, secret, []byte(customer.ID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to encrypt totp secret")
+	}
+	err = m.mfa.Upsert(ctx, &domain.UserMFA{
+		UserID:          customer.ID,
+		EncryptedSecret: encrypted,
+		LastUsedStep:    0,
+		CreatedAt:       time.Now().UTC(),
+		EnabledAt:       nil,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store mfa")
+	}
+
+	return &MFAEnrollment{
+		Secret: totp.EncodeSecret(secret),
+		URI:    m.policy.TOTP.URI(m.policy.Issuer, customer.Email, secret),
+	}, nil
+}
+
+// Confirm enables the pending enrollment of a signed-in customer with a code
+// from their authenticator app, which proves that the app was set up. It
+// returns the customer's recovery codes, which are only ever shown now.
+func (m *MFAService) Confirm(ctx context.Context, session *domain.UserSession, code string) ([]string, error) {
+	fail := func(reason string, err error) ([]string, error) {
+		m.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionMFAEnroll, domain.AuditOutcomeFailure, session.UserID, reason,
+		))
+		return nil, err
+	}
+
+	err := m.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return fail("reauthentication_required", err)
+	}
+	mfa, err := m.mfa.Get(ctx, session.UserID)
+	switch {
+	case errors.Is(err, domain.ErrMFANotFound):
+		return fail("not_enrolled", ErrMFANotEnrolled)
+	case err != nil:
+		return fail("repository_error", errors.Wrap(err, "failed to get mfa"))
+	case mfa.Enabled():
+		return fail("already_enabled", ErrMFAAlreadyEnabled)
+	}
+
+	secret, err := m.secret(mfa)
+	if err != nil {
+		return fail("decryption_failed", err)
+	}
+	now := time.Now().UTC()
+	step, ok := m.policy.TOTP.Validate(secret, normalizeCode(code), now)
+	if !ok {
+		return fail("invalid_code", ErrInvalidMFACode)
+	}
+	err = m.mfa.Enable(ctx, mfa.UserID, step, now)
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to enable mfa"))
+	}
+
+	codes, err := m.newRecoveryCodes(ctx, mfa.UserID)
+	if err != nil {
+		// MFA is enabled, and the customer can ask for new recovery codes.
+		m.logger.Error("failed to generate recovery codes", "customer_id", mfa.UserID, "error", err)
+	}
+	m.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionMFAEnroll, domain.AuditOutcomeSuccess, mfa.UserID, "",
+	))
+	return codes, err
+}
+
+// Disable turns MFA off for a signed-in customer, who must have
+// authenticated recently and must provide a code or a recovery code.
+func (m *MFAService) Disable(ctx context.Context, session *domain.UserSession, code string) error {
+	fail := func(reason string, err error) error {
+		m.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionMFADisable, domain.AuditOutcomeFailure, session.UserID, reason,
+		))
+		return err
+	}
+
+	err := m.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return fail("reauthentication_required", err)
+	}
+	reason, err := m.verify(ctx, session.UserID, code, time.Now().UTC())
+	if err != nil {
+		return fail(reason, err)
+	}
+	err = m.mfa.Delete(ctx, session.UserID)
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to delete mfa"))
+	}
+	m.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionMFADisable, domain.AuditOutcomeSuccess, session.UserID, "",
+	))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a signed-in
+// customer, who must have authenticated recently, such as when they used up
+// or lost their codes.
+func (m *MFAService) RegenerateRecoveryCodes(ctx context.Context, session *domain.UserSession) ([]string, error) {
+	err := m.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return nil, err
+	}
+	mfa, err := m.mfa.Get(ctx, session.UserID)
+	switch {
+	case errors.Is(err, domain.ErrMFANotFound):
+		return nil, ErrMFANotEnabled
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to get mfa")
+	case !mfa.Enabled():
+		return nil, ErrMFANotEnabled
+	}
+	codes, err := m.newRecoveryCodes(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	m.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionRecoveryCodes, domain.AuditOutcomeSuccess, session.UserID, "",
+	))
+	return codes, nil
+}
+
+// RecoveryCodesLeft counts the unused recovery codes of a customer, so that
+// they can be told to generate new ones before they run out.
+func (m *MFAService) RecoveryCodesLeft(ctx context.Context, customerID string) (int, error) {
+	return m.mfa.CountRecoveryCodes(ctx, customerID)
+}
+
+// reset turns MFA off for a customer who lost both their authenticator and
+// their recovery codes. It is only reachable through AdminService.ResetMFA,
+// which authorizes and audits it.
+func (m *MFAService) reset(ctx context.Context, customerID string) error {
+	err := m.mfa.Delete(ctx, customerID)
+	if errors.Is(err, domain.ErrMFANotFound) {
+		return ErrMFANotEnrolled
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to reset mfa")
+	}
+	return nil
+}
+
+// enabled reports whether a customer must enter a code at sign in.
+func (m *MFAService) enabled(ctx context.Context, customerID string) (bool, error) {
+	mfa, err := m.mfa.Get(ctx, customerID)
+	if errors.Is(err, domain.ErrMFANotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get mfa")
+	}
+	return mfa.Enabled(), nil
+}
+
+// challenge issues the challenge a customer answers with a code to complete
+// a sign in.
+func (m *MFAService) challenge(ctx context.Context, customer *domain.Customer) (string, error) {
+	token, err := domain.NewOneTimeToken(
+		customer.ID, domain.TokenPurposeMFAChallenge, customer.Email, m.policy.ChallengeTTL,
+	)
+	if err != nil {
+		return "", err
+	}
+	err = m.tokens.Insert(ctx, token)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to store mfa challenge")
+	}
+	return token.Token, nil
+}
+
+// verify checks a TOTP code or a recovery code of a customer, and uses it up.
+// It returns an audit reason along with an error if the code is refused.
+func (m *MFAService) verify(ctx context.Context, customerID, code string, at time.Time) (string, error) {
+	mfa, err := m.mfa.Get(ctx, customerID)
+	switch {
+	case errors.Is(err, domain.ErrMFANotFound):
+		return "not_enrolled", ErrMFANotEnabled
+	case err != nil:
+		return "repository_error", errors.Wrap(err, "failed to get mfa")
+	case !mfa.Enabled():
+		return "not_enrolled", ErrMFANotEnabled
+	}
+
+	code = normalizeCode(code)
+	if len(code) != m.policy.TOTP.Digits {
+		err = m.mfa.UseRecoveryCode(ctx, customerID, crypto.HashToken(code), at)
+		switch {
+		case errors.Is(err, domain.ErrRecoveryCodeNotFound):
+			return "invalid_code", ErrInvalidMFACode
+		case err != nil:
+			return "repository_error", errors.Wrap(err, "failed to use recovery code")
+		}
+		m.logger.Info("recovery code used", "customer_id", customerID)
+		return "", nil
+	}
+
+	secret, err := m.secret(mfa)
+	if err != nil {
+		return "decryption_failed", err
+	}
+	step, ok := m.policy.TOTP.Validate(secret, code, at)
+	if !ok {
+		return "invalid_code", ErrInvalidMFACode
+	}
+	err = m.mfa.UseStep(ctx, customerID, step)
+	switch {
+	case errors.Is(err, domain.ErrMFACodeUsed):
+		return "code_used", ErrInvalidMFACode
+	case err != nil:
+		return "repository_error", errors.Wrap(err, "failed to use mfa code")
+	}
+	return "", nil
+}
+
+// secret decrypts the TOTP secret of an authenticator.
+func (m *MFAService) secret(mfa *domain.UserMFA) ([]byte, error) {
+	secret, err := crypto.Decrypt(m.policy.EncryptionKey, mfa.EncryptedSecret, []byte(mfa.UserID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt totp secret")
+	}
+	return secret, nil
+}
+
+// recoveryCodeEncoding spells recovery codes without letters that are easily
+// mistaken for others, like the random strings of package crypto.
+var recoveryCodeEncoding = base32.NewEncoding( //nolint:gochecknoglobals // makes more sense like this.
+	"0123456789ABCDEFGHJKMNPQRSTVWXYZ",
+).WithPadding(base32.NoPadding)
+
+// recoveryCodeBytes is the number of random bytes in a recovery code, which
+// spell 10 characters.
+const recoveryCodeBytes = 6
+
+// newRecoveryCodes replaces the recovery codes of a customer, and returns the
+// new codes, formatted like "3F7KQ-9XM2D".
+func (m *MFAService) newRecoveryCodes(ctx context.Context, customerID string) ([]string, error) {
+	codes := make([]string, m.policy.RecoveryCodes)
+	stored := make([]*domain.RecoveryCode, m.policy.RecoveryCodes)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate recovery code")
+		}
+		code := recoveryCodeEncoding.EncodeToString(b)
+		stored[i], err = domain.NewRecoveryCode(customerID, code)
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	err := m.mfa.ReplaceRecoveryCodes(ctx, customerID, stored)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store recovery codes")
+	}
+	return codes, nil
+}
+
+// normalizeCode removes the separators that customers type or paste along
+// with a code, and uppercases recovery codes.
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+	ErrMFANotEnrolled    = errors.New("mfa not enrolled")
+	ErrMFANotEnabled     = errors.New("mfa not enabled")
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+)
@@ -0,0 +1,227 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+//nolint:gochecknoglobals // makes more sense like this.
+var testEncryptionKey = bytes.Repeat([]byte{0x42}, crypto.EncryptionKeyLength)
+
+// mfaCustomer is a signed up customer who enrolled in MFA.
+type mfaCustomer struct {
+	session       *domain.UserSession
+	secret        []byte
+	recoveryCodes []string
+	// step is the time step of the last code that was used.
+	step int64
+}
+
+// code returns the code of the step after the last used one, which is
+// still accepted thanks to the skew.
+func (c *mfaCustomer) code(f authFixture) string {
+	c.step++
+	return f.mfa.policy.TOTP.Code(c.secret, c.step)
+}
+
+func enrollMFA(t *testing.T, f authFixture) *mfaCustomer {
+	t.Helper()
+	result, err := f.auth.SignUp(context.Background(), testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	return enrollMFAIn(t, f, result.Session)
+}
+
+// enrollMFAIn enrolls the customer a session belongs to in MFA.
+func enrollMFAIn(t *testing.T, f authFixture, session *domain.UserSession) *mfaCustomer {
+	t.Helper()
+	ctx := context.Background()
+	enrollment, err := f.mfa.Enroll(ctx, session)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/"))
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(enrollment.Secret)
+	assert.NoError(t, err)
+
+	step := f.mfa.policy.TOTP.Step(time.Now())
+	codes, err := f.mfa.Confirm(ctx, session, f.mfa.policy.TOTP.Code(secret, step))
+	assert.NoError(t, err)
+	assert.Equal(t, len(codes), f.mfa.policy.RecoveryCodes)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionMFAEnroll)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	return &mfaCustomer{session: session, secret: secret, recoveryCodes: codes, step: step}
+}
+
+func TestMFAService_EnrollAndSignIn(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	c := enrollMFA(t, f)
+
+	// The password only earns a challenge.
+	result, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.True(t, result.Session == nil)
+	assert.NotEqual(t, result.MFAChallenge, "")
+
+	// The code that confirmed the enrollment cannot be replayed.
+	replayed := f.mfa.policy.TOTP.Code(c.secret, c.step)
+	_, err = f.auth.CompleteSignIn(ctx, result.MFAChallenge, replayed, domain.ClientInfo{})
+	assert.Error(t, err, ErrInvalidMFACode)
+	assert.Equal(t, f.audit.last().Reason, "code_used")
+
+	signedIn, err := f.auth.CompleteSignIn(ctx, result.MFAChallenge, c.code(f), domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, signedIn.Customer.ID, c.session.UserID)
+	_, err = f.sessions.Validate(ctx, signedIn.Session.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionSignIn)
+
+	// A challenge completes one sign in only.
+	_, err = f.auth.CompleteSignIn(ctx, result.MFAChallenge, c.code(f), domain.ClientInfo{})
+	assert.Error(t, err, domain.ErrTokenUsed)
+}
+
+func TestMFAService_RecoveryCodes(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	c := enrollMFA(t, f)
+
+	// Recovery codes are accepted however they are typed, once.
+	typed := strings.ToLower(c.recoveryCodes[0])
+	for _, wantErr := range []bool{false, true} {
+		result, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+		assert.NoError(t, err)
+		_, err = f.auth.CompleteSignIn(ctx, result.MFAChallenge, typed, domain.ClientInfo{})
+		assert.ErrorAndWant(t, err, wantErr)
+	}
+	left, err := f.mfa.RecoveryCodesLeft(ctx, c.session.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, left, f.mfa.policy.RecoveryCodes-1)
+
+	codes, err := f.mfa.RegenerateRecoveryCodes(ctx, c.session)
+	assert.NoError(t, err)
+	left, err = f.mfa.RecoveryCodesLeft(ctx, c.session.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, left, f.mfa.policy.RecoveryCodes)
+
+	// Old codes no longer work.
+	result, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	_, err = f.auth.CompleteSignIn(ctx, result.MFAChallenge, c.recoveryCodes[1], domain.ClientInfo{})
+	assert.Error(t, err, ErrInvalidMFACode)
+	_, err = f.auth.CompleteSignIn(ctx, result.MFAChallenge, codes[1], domain.ClientInfo{})
+	assert.NoError(t, err)
+}
+
+func TestMFAService_WrongCodesAreThrottled(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	c := enrollMFA(t, f)
+	f.throttle.policy = lockoutPolicy()
+
+	result, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	for range f.throttle.policy.Account.LockoutAfter {
+		_, err = f.auth.CompleteSignIn(ctx, result.MFAChallenge, "000000", domain.ClientInfo{})
+		assert.Error(t, err, ErrInvalidMFACode)
+	}
+	_, err = f.auth.CompleteSignIn(ctx, result.MFAChallenge, c.code(f), domain.ClientInfo{})
+	assert.Error(t, err, ErrTooManyAttempts)
+}
+
+func TestMFAService_EnrollFailures(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	c := enrollMFA(t, f)
+
+	_, err := f.mfa.Enroll(ctx, c.session)
+	assert.Error(t, err, ErrMFAAlreadyEnabled)
+
+	c.session.AuthenticatedAt = time.Now().Add(-time.Hour)
+	err = f.mfa.Disable(ctx, c.session, c.code(f))
+	assert.Error(t, err, ErrReauthenticationRequired)
+	_, err = f.mfa.Enroll(ctx, c.session)
+	assert.Error(t, err, ErrReauthenticationRequired)
+
+	_, err = NewMFAService(f.mfa.ServiceBase, f.customers, f.sessions, memory.NewMFARepository(), f.tokens,
+		DefaultMFAPolicy([]byte("too short")))
+	assert.Error(t, err, crypto.ErrInvalidKey)
+}
+
+func TestMFAService_DisableAndReset(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		test.CaseBase
+		turnOff func(f adminFixture, admin *domain.Principal, c *mfaCustomer) error
+	}{
+		{
+			CaseBase: test.NewCaseBase("disabled with a code", domain.AuditActionMFADisable, false),
+			turnOff: func(f adminFixture, _ *domain.Principal, c *mfaCustomer) error {
+				if f.mfa.Disable(ctx, c.session, "12345") == nil {
+					return errors.New("disabled with a wrong code")
+				}
+				return f.mfa.Disable(ctx, c.session, c.code(f.authFixture))
+			},
+		},
+		{
+			CaseBase: test.NewCaseBase("reset by an admin", domain.AuditActionMFAReset, false),
+			turnOff: func(f adminFixture, admin *domain.Principal, c *mfaCustomer) error {
+				return f.admin.ResetMFA(ctx, admin, c.session.UserID)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newAdminFixture(t)
+				admin := f.principal(t, "admin@brokedaear.com", domain.RoleAdmin)
+				c := enrollMFAIn(t, f.authFixture, f.buyer.Session)
+				assert.NoError(t, tt.turnOff(f, admin, c))
+				assert.Equal(t, f.audit.last().Action, tt.Want.(domain.AuditAction))
+
+				result, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+				assert.NoError(t, err)
+				assert.NotEqual(t, result.Session, nil)
+				assert.Equal(t, result.MFAChallenge, "")
+
+				err = f.admin.ResetMFA(ctx, admin, c.session.UserID)
+				assert.Error(t, err, ErrMFANotEnrolled)
+				assert.Equal(t, f.audit.last().Reason, "not_enrolled")
+			},
+		)
+	}
+}
+
+func TestMFAService_SecretIsEncrypted(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewMFARepository()
+	f := newAuthFixture(t)
+	f.mfa.mfa = repo
+	c := enrollMFA(t, f)
+
+	stored, err := repo.Get(ctx, c.session.UserID)
+	assert.NoError(t, err)
+	assert.False(t, bytes.Contains(stored.EncryptedSecret, c.secret))
+
+	// The secret is bound to its customer.
+	_, err = crypto.Decrypt(testEncryptionKey, stored.EncryptedSecret, []byte("someone else"))
+	assert.Error(t, err, crypto.ErrDecryptionFailed)
+	secret, err := crypto.Decrypt(testEncryptionKey, stored.EncryptedSecret, []byte(c.session.UserID))
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(secret, c.secret))
+}
@@ -0,0 +1,438 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// identityProvider runs the provider specific half of an OAuth2
+// authorization code flow.
+type identityProvider interface {
+	// Provider returns the provider.
+	Provider() domain.IdentityProvider
+	// AuthCodeURL returns the URL the customer is sent to in order to
+	// authenticate with the provider.
+	AuthCodeURL(flow *domain.OAuthFlow) string
+	// Exchange redeems the code the provider redirected back with, verifies
+	// what the provider returns and returns the identity it asserts.
+	Exchange(ctx context.Context, code string, flow *domain.OAuthFlow) (*domain.ExternalIdentity, error)
+}
+
+// identityRepository stores the external identities linked to customers.
+type identityRepository interface {
+	Insert(ctx context.Context, identity *domain.Identity) error
+	GetBySubject(
+		ctx context.Context,
+		provider domain.IdentityProvider,
+		subject string,
+	) (*domain.Identity, error)
+	ListByCustomer(ctx context.Context, customerID string) ([]*domain.Identity, error)
+	UpdateLastUsed(ctx context.Context, identity *domain.Identity) error
+	Delete(ctx context.Context, id string) error
+}
+
+// oauthFlowRepository stores OAuth2 flows between the redirect to the
+// provider and the callback.
+type oauthFlowRepository interface {
+	Insert(ctx context.Context, flow *domain.OAuthFlow) error
+	// Take removes a flow and returns it, so that a flow can only be
+	// completed once.
+	Take(ctx context.Context, state string) (*domain.OAuthFlow, error)
+}
+
+// oauthFlowTTL is how long a customer has to authenticate with a provider.
+const oauthFlowTTL = 10 * time.Minute
+
+// OAuthService signs customers in and up through external identity
+// providers, and links provider identities to customer accounts. A customer
+// can link one identity per provider.
+//
+// The backend runs the whole authorization code flow itself, and only trusts
+// identities it obtained from the provider. The client never gets to tell
+// the backend who it is.
+type OAuthService struct {
+	*ServiceBase
+	auth       *AuthService
+	customers  customerRepository
+	identities identityRepository
+	flows      oauthFlowRepository
+	providers  map[domain.IdentityProvider]identityProvider
+}
+
+// NewOAuthService creates a new OAuthService that accepts identities from
+// the given providers.
+func NewOAuthService(
+	svcBase *ServiceBase,
+	auth *AuthService,
+	customers customerRepository,
+	identities identityRepository,
+	flows oauthFlowRepository,
+	providers ...identityProvider,
+) *OAuthService {
+	m := make(map[domain.IdentityProvider]identityProvider, len(providers))
+	for _, p := range providers {
+		m[p.Provider()] = p
+	}
+	return &OAuthService{
+		ServiceBase: svcBase,
+		auth:        auth,
+		customers:   customers,
+		identities:  identities,
+		flows:       flows,
+		providers:   m,
+	}
+}
+
+// Begin starts signing a customer in or up with a provider. It returns the
+// URL the customer must be redirected to.
+func (o *OAuthService) Begin(ctx context.Context, provider domain.IdentityProvider) (string, error) {
+	return o.begin(ctx, provider, "")
+}
+
+// BeginLink starts linking a provider identity to the account of a
+// signed-in customer. Linking is sensitive, so the customer must have
+// authenticated recently in the session.
+func (o *OAuthService) BeginLink(
+	ctx context.Context,
+	session *domain.UserSession,
+	provider domain.IdentityProvider,
+) (string, error) {
+	err := o.auth.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return "", err
+	}
+	return o.begin(ctx, provider, session.UserID)
+}
+
+func (o *OAuthService) begin(
+	ctx context.Context,
+	provider domain.IdentityProvider,
+	linkUserID string,
+) (string, error) {
+	p, ok := o.providers[provider]
+	if !ok {
+		return "", ErrUnknownIdentityProvider
+	}
+	flow, err := domain.NewOAuthFlow(provider, linkUserID, oauthFlowTTL)
+	if err != nil {
+		return "", err
+	}
+	err = o.flows.Insert(ctx, flow)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to store oauth flow")
+	}
+	return p.AuthCodeURL(flow), nil
+}
+
+// Complete completes a sign in flow with the state and code the provider
+// redirected back with. The caller must make sure the state came back to the
+// same user agent that started the flow, such as by keeping it in a cookie,
+// or a customer could be tricked into signing in as someone else.
+//
+//   - A customer with the identity linked is signed in.
+//   - If no customer has the identity linked and no account uses its email
+//     address, an account without a password is created and signed in. The
+//     provider must have verified the email address.
+//   - If an account already uses the email address, the flow is rejected
+//     with ErrIdentityNotLinked. Linking it automatically would let anyone
+//     who controls the address at the provider take over the account. The
+//     customer must sign in and link the identity instead.
+func (o *OAuthService) Complete(
+	ctx context.Context,
+	provider domain.IdentityProvider,
+	state, code string,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	flow, external, err := o.complete(ctx, provider, state, code, "")
+	if err != nil {
+		o.auth.signInFailed(ctx, "", "oauth_failed")
+		return nil, err
+	}
+
+	identity, err := o.identities.GetBySubject(ctx, external.Provider, external.Subject)
+	switch {
+	case err == nil:
+		return o.signIn(ctx, identity, external, client)
+	case errors.Is(err, domain.ErrIdentityNotFound):
+		return o.signUp(ctx, external, client)
+	default:
+		o.logger.Error("failed to get identity", "provider", flow.Provider.String(), "error", err)
+		o.auth.signInFailed(ctx, "", "repository_error")
+		return nil, ErrCustomerLoginFailed
+	}
+}
+
+// CompleteLink completes a flow started by BeginLink, and links the identity
+// to the customer. The flow must be completed in a session of the customer
+// who started it, so that a customer cannot be tricked into linking their
+// identity to someone else's account.
+func (o *OAuthService) CompleteLink(
+	ctx context.Context,
+	session *domain.UserSession,
+	provider domain.IdentityProvider,
+	state, code string,
+) (*domain.Customer, error) {
+	_, external, err := o.complete(ctx, provider, state, code, session.UserID)
+	if err != nil {
+		o.auth.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionIdentityLink, domain.AuditOutcomeFailure, session.UserID, "oauth_failed",
+		))
+		return nil, err
+	}
+	return o.link(ctx, session.UserID, external)
+}
+
+// complete takes a flow and exchanges the code for the identity the provider
+// asserts. The flow must have been started by linkUserID, which is empty for
+// sign in flows.
+func (o *OAuthService) complete(
+	ctx context.Context,
+	provider domain.IdentityProvider,
+	state, code, linkUserID string,
+) (*domain.OAuthFlow, *domain.ExternalIdentity, error) {
+	flow, err := o.flows.Take(ctx, state)
+	if err != nil {
+		if errors.Is(err, domain.ErrOAuthFlowNotFound) {
+			return nil, nil, ErrInvalidOAuthState
+		}
+		return nil, nil, errors.Wrap(err, "failed to get oauth flow")
+	}
+	if flow.Provider != provider || flow.LinkUserID != linkUserID || flow.Expired(time.Now()) {
+		return nil, nil, ErrInvalidOAuthState
+	}
+
+	p, ok := o.providers[provider]
+	if !ok {
+		return nil, nil, ErrUnknownIdentityProvider
+	}
+	external, err := p.Exchange(ctx, code, flow)
+	if err != nil {
+		o.logger.Warn("oauth exchange failed", "provider", provider.String(), "error", err)
+		return nil, nil, ErrOAuthFailed
+	}
+	if external.Provider != provider || external.Subject == "" {
+		return nil, nil, ErrOAuthFailed
+	}
+	return flow, external, nil
+}
+
+// signIn signs in the customer an identity is linked to.
+func (o *OAuthService) signIn(
+	ctx context.Context,
+	identity *domain.Identity,
+	external *domain.ExternalIdentity,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	customer, err := o.customers.GetByID(ctx, identity.UserID)
+	if err != nil {
+		o.auth.signInFailed(ctx, identity.UserID, reasonFromLookup(err))
+		return nil, ErrCustomerLoginFailed
+	}
+
+	identity.Email = external.Email
+	identity.LastUsedAt = time.Now().UTC()
+	err = o.identities.UpdateLastUsed(ctx, identity)
+	if err != nil {
+		o.logger.Warn("failed to update identity", "identity_id", identity.ID, "error", err)
+	}
+
+	customer.LastLoginAt = identity.LastUsedAt
+	err = o.customers.UpdateLastLogin(ctx, customer)
+	if err != nil {
+		o.logger.Warn("failed to update last login", "customer_id", customer.ID, "error", err)
+	}
+
+	session, err := o.auth.issueSession(ctx, customer, client)
+	if err != nil {
+		o.auth.signInFailed(ctx, customer.ID, "session_issue_failed")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	o.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignIn, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return &AuthResult{Customer: customer, Session: session, MFAChallenge: ""}, nil
+}
+
+// signUp creates a customer without a password for an identity and signs
+// them in.
+func (o *OAuthService) signUp(
+	ctx context.Context,
+	external *domain.ExternalIdentity,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	if external.Email == "" || !external.EmailVerified {
+		o.auth.signUpFailed(ctx, "", "email_unverified")
+		return nil, ErrIdentityEmailUnverified
+	}
+
+	_, err := o.customers.GetByEmail(ctx, external.Email)
+	switch {
+	case err == nil:
+		o.auth.signUpFailed(ctx, "", "identity_not_linked")
+		return nil, ErrIdentityNotLinked
+	case !errors.Is(err, domain.ErrCustomerNotFound):
+		o.auth.signUpFailed(ctx, "", "repository_error")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	customer, err := domain.NewCustomer(external.Email, "", nil)
+	if err != nil {
+		o.auth.signUpFailed(ctx, "", "invalid_customer")
+		return nil, ErrCustomerSignUpFailed
+	}
+	customer.EmailVerified = true
+
+	err = o.insertCustomer(ctx, o.customers, customer, "oauth")
+	if err != nil {
+		o.logger.Error("signup failed", "error", err)
+		o.auth.signUpFailed(ctx, "", "repository_error")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	identity, err := domain.NewIdentity(customer.ID, *external)
+	if err != nil {
+		return nil, err
+	}
+	err = o.identities.Insert(ctx, identity)
+	if err != nil {
+		o.logger.Error("failed to link identity", "customer_id", customer.ID, "error", err)
+		o.auth.signUpFailed(ctx, customer.ID, "repository_error")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	o.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignUp, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+
+	session, err := o.auth.issueSession(ctx, customer, client)
+	if err != nil {
+		return &AuthResult{Customer: customer, Session: nil, MFAChallenge: ""}, err
+	}
+	return &AuthResult{Customer: customer, Session: session, MFAChallenge: ""}, nil
+}
+
+// link links an identity to a customer.
+func (o *OAuthService) link(
+	ctx context.Context,
+	customerID string,
+	external *domain.ExternalIdentity,
+) (*domain.Customer, error) {
+	fail := func(reason string, err error) error {
+		o.auth.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionIdentityLink, domain.AuditOutcomeFailure, customerID, reason,
+		))
+		return err
+	}
+
+	customer, err := o.customers.GetByID(ctx, customerID)
+	if err != nil {
+		return nil, fail(reasonFromLookup(err), errors.Wrap(err, "failed to get customer"))
+	}
+
+	existing, err := o.identities.GetBySubject(ctx, external.Provider, external.Subject)
+	switch {
+	case err == nil && existing.UserID == customer.ID:
+		return customer, nil
+	case err == nil:
+		return nil, fail("identity_in_use", domain.ErrIdentityExists)
+	case !errors.Is(err, domain.ErrIdentityNotFound):
+		return nil, fail("repository_error", errors.Wrap(err, "failed to get identity"))
+	}
+
+	linked, err := o.identities.ListByCustomer(ctx, customer.ID)
+	if err != nil {
+		return nil, fail("repository_error", errors.Wrap(err, "failed to list identities"))
+	}
+	for _, i := range linked {
+		if i.Provider == external.Provider {
+			return nil, fail("provider_already_linked", domain.ErrIdentityExists)
+		}
+	}
+
+	identity, err := domain.NewIdentity(customer.ID, *external)
+	if err != nil {
+		return nil, fail("invalid_identity", err)
+	}
+	err = o.identities.Insert(ctx, identity)
+	if err != nil {
+		return nil, fail("repository_error", errors.Wrap(err, "failed to link identity"))
+	}
+
+	o.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionIdentityLink, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return customer, nil
+}
+
+// Identities returns the identities linked to a customer.
+func (o *OAuthService) Identities(ctx context.Context, customerID string) ([]*domain.Identity, error) {
+	return o.identities.ListByCustomer(ctx, customerID)
+}
+
+// Unlink unlinks an identity from the customer a session belongs to. The
+// last way a customer can sign in cannot be unlinked, so a customer without
+// a password must keep at least one identity.
+func (o *OAuthService) Unlink(
+	ctx context.Context,
+	session *domain.UserSession,
+	identityID string,
+) error {
+	fail := func(reason string, err error) error {
+		o.auth.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionIdentityUnlink, domain.AuditOutcomeFailure, session.UserID, reason,
+		))
+		return err
+	}
+
+	err := o.auth.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return fail("reauthentication_required", err)
+	}
+
+	customer, err := o.customers.GetByID(ctx, session.UserID)
+	if err != nil {
+		return fail(reasonFromLookup(err), errors.Wrap(err, "failed to get customer"))
+	}
+	linked, err := o.identities.ListByCustomer(ctx, customer.ID)
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to list identities"))
+	}
+
+	found := false
+	for _, i := range linked {
+		found = found || i.ID == identityID
+	}
+	if !found {
+		return fail("unknown_identity", domain.ErrIdentityNotFound)
+	}
+	if !customer.HasPassword() && len(linked) == 1 {
+		return fail("last_sign_in_method", ErrLastSignInMethod)
+	}
+
+	err = o.identities.Delete(ctx, identityID)
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to unlink identity"))
+	}
+
+	o.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionIdentityUnlink, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return nil
+}
+
+var (
+	ErrUnknownIdentityProvider = errors.New("unknown identity provider")
+	ErrInvalidOAuthState       = errors.New("invalid or expired oauth state")
+	ErrOAuthFailed             = errors.New("oauth sign in failed")
+	ErrIdentityNotLinked       = errors.New("an account with this email exists; sign in to link the identity")
+	ErrIdentityEmailUnverified = errors.New("identity provider has not verified the email address")
+	ErrLastSignInMethod        = errors.New("cannot remove the last sign in method")
+)
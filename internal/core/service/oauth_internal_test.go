@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/adapters/oidc"
+	"go.brokedaear.com/internal/adapters/oidc/oidctest"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+// fakeGitHub is an identity provider that asserts whatever identity it is
+// told to, for the code it is given.
+type fakeGitHub struct {
+	identities map[string]domain.ExternalIdentity
+}
+
+func (f *fakeGitHub) Provider() domain.IdentityProvider {
+	return domain.IdentityProviderGitHub
+}
+
+func (f *fakeGitHub) AuthCodeURL(flow *domain.OAuthFlow) string {
+	return "https://github.test/authorize?state=" + url.QueryEscape(flow.State)
+}
+
+func (f *fakeGitHub) Exchange(
+	_ context.Context,
+	code string,
+	_ *domain.OAuthFlow,
+) (*domain.ExternalIdentity, error) {
+	identity, ok := f.identities[code]
+	if !ok {
+		return nil, oidc.ErrExchangeFailed
+	}
+	return &identity, nil
+}
+
+type oauthFixture struct {
+	authFixture
+	oauth  *OAuthService
+	srv    *oidctest.Server
+	github *fakeGitHub
+}
+
+func newOAuthFixture(t *testing.T) oauthFixture {
+	t.Helper()
+	srv := oidctest.NewServer("client", "secret")
+	t.Cleanup(srv.Close)
+
+	auth0, err := oidc.NewAuth0(context.Background(), oidc.Config{
+		Issuer:       srv.Issuer(),
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://brokedaear.com/auth/auth0/callback",
+		Scopes:       nil,
+		HTTPClient:   srv.Client(),
+	})
+	assert.NoError(t, err)
+
+	f := newAuthFixture(t)
+	github := &fakeGitHub{identities: make(map[string]domain.ExternalIdentity)}
+	oauth := NewOAuthService(
+		NewServiceBase(test.NewMockLogger(), nil),
+		f.auth,
+		f.customers,
+		memory.NewIdentityRepository(),
+		memory.NewOAuthFlowRepository(),
+		auth0,
+		github,
+	)
+	return oauthFixture{authFixture: f, oauth: oauth, srv: srv, github: github}
+}
+
+// auth0 runs a sign in flow with the fake Auth0 up to the callback.
+func (f oauthFixture) auth0(t *testing.T) (code, state string) {
+	t.Helper()
+	authURL, err := f.oauth.Begin(context.Background(), domain.IdentityProviderAuth0)
+	assert.NoError(t, err)
+	code, state, err = f.srv.Authorize(authURL)
+	assert.NoError(t, err)
+	return code, state
+}
+
+// githubState extracts the state from an authorization URL of fakeGitHub.
+func githubState(t *testing.T, authURL string) string {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	assert.NoError(t, err)
+	return u.Query().Get("state")
+}
+
+func TestOAuthService_SignUpAndSignIn(t *testing.T) {
+	ctx := context.Background()
+	f := newOAuthFixture(t)
+
+	code, state := f.auth0(t)
+	signedUp, err := f.oauth.Complete(ctx, domain.IdentityProviderAuth0, state, code, domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, signedUp.Customer.Email, f.srv.User.Email)
+	assert.True(t, signedUp.Customer.EmailVerified)
+	assert.False(t, signedUp.Customer.HasPassword())
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionSessionIssued)
+	_, err = f.sessions.Validate(ctx, signedUp.Session.Token)
+	assert.NoError(t, err)
+
+	// A flow can only be completed once.
+	_, err = f.oauth.Complete(ctx, domain.IdentityProviderAuth0, state, code, domain.ClientInfo{})
+	assert.Error(t, err, ErrInvalidOAuthState)
+
+	code, state = f.auth0(t)
+	signedIn, err := f.oauth.Complete(ctx, domain.IdentityProviderAuth0, state, code, domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, signedIn.Customer.ID, signedUp.Customer.ID)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionSignIn)
+
+	// An account without a password cannot be signed into with one.
+	_, err = f.auth.SignIn(ctx, f.srv.User.Email, "", domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerLoginFailed)
+	assert.Equal(t, f.audit.last().Reason, "password_not_set")
+}
+
+func TestOAuthService_CompleteFailures(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		test.CaseBase
+		configure func(*oauthFixture)
+	}{
+		{
+			CaseBase: test.NewCaseBase("existing email", ErrIdentityNotLinked, true),
+			configure: func(f *oauthFixture) {
+				_, err := f.auth.SignUp(ctx, f.srv.User.Email, testPassword, domain.ClientInfo{})
+				assert.NoError(t, err)
+			},
+		},
+		{
+			CaseBase:  test.NewCaseBase("unverified email", ErrIdentityEmailUnverified, true),
+			configure: func(f *oauthFixture) { f.srv.User.EmailVerified = false },
+		},
+		{
+			CaseBase:  test.NewCaseBase("invalid id token", ErrOAuthFailed, true),
+			configure: func(f *oauthFixture) { f.srv.Forge = true },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newOAuthFixture(t)
+				tt.configure(&f)
+				code, state := f.auth0(t)
+				_, err := f.oauth.Complete(ctx, domain.IdentityProviderAuth0, state, code, domain.ClientInfo{})
+				assert.Error(t, err, tt.Want.(error))
+			},
+		)
+	}
+
+	t.Run(
+		"wrong provider", func(t *testing.T) {
+			f := newOAuthFixture(t)
+			code, state := f.auth0(t)
+			_, err := f.oauth.Complete(ctx, domain.IdentityProviderGitHub, state, code, domain.ClientInfo{})
+			assert.Error(t, err, ErrInvalidOAuthState)
+		},
+	)
+}
+
+func TestOAuthService_LinkAndUnlink(t *testing.T) {
+	ctx := context.Background()
+	f := newOAuthFixture(t)
+
+	// The customer signed up with a password, and links both providers.
+	result, err := f.auth.SignUp(ctx, f.srv.User.Email, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	session := result.Session
+
+	authURL, err := f.oauth.BeginLink(ctx, session, domain.IdentityProviderAuth0)
+	assert.NoError(t, err)
+	code, state, err := f.srv.Authorize(authURL)
+	assert.NoError(t, err)
+	_, err = f.oauth.CompleteLink(ctx, session, domain.IdentityProviderAuth0, state, code)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionIdentityLink)
+
+	f.github.identities["gh-code"] = domain.ExternalIdentity{
+		Provider:      domain.IdentityProviderGitHub,
+		Subject:       "5318008",
+		Email:         "someone.else@brokedaear.com",
+		EmailVerified: false,
+	}
+	authURL, err = f.oauth.BeginLink(ctx, session, domain.IdentityProviderGitHub)
+	assert.NoError(t, err)
+	_, err = f.oauth.CompleteLink(ctx, session, domain.IdentityProviderGitHub, githubState(t, authURL), "gh-code")
+	assert.NoError(t, err)
+
+	// Both identities now sign into the same account.
+	code, state = f.auth0(t)
+	signedIn, err := f.oauth.Complete(ctx, domain.IdentityProviderAuth0, state, code, domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.Equal(t, signedIn.Customer.ID, result.Customer.ID)
+
+	authURL, err = f.oauth.Begin(ctx, domain.IdentityProviderGitHub)
+	assert.NoError(t, err)
+	signedIn, err = f.oauth.Complete(
+		ctx, domain.IdentityProviderGitHub, githubState(t, authURL), "gh-code", domain.ClientInfo{},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, signedIn.Customer.ID, result.Customer.ID)
+
+	identities, err := f.oauth.Identities(ctx, result.Customer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, len(identities), 2)
+
+	// The customer still has a password, so every identity can be unlinked.
+	for _, identity := range identities {
+		err = f.oauth.Unlink(ctx, session, identity.ID)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionIdentityUnlink)
+}
+
+func TestOAuthService_LinkFailures(t *testing.T) {
+	ctx := context.Background()
+	f := newOAuthFixture(t)
+
+	victim, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	attacker, err := f.auth.SignUp(ctx, "mallory@brokedaear.com", testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	// A link flow started by one customer cannot be completed by another.
+	authURL, err := f.oauth.BeginLink(ctx, attacker.Session, domain.IdentityProviderAuth0)
+	assert.NoError(t, err)
+	code, state, err := f.srv.Authorize(authURL)
+	assert.NoError(t, err)
+	_, err = f.oauth.CompleteLink(ctx, victim.Session, domain.IdentityProviderAuth0, state, code)
+	assert.Error(t, err, ErrInvalidOAuthState)
+
+	// Nor can it be used to sign in.
+	authURL, err = f.oauth.BeginLink(ctx, attacker.Session, domain.IdentityProviderAuth0)
+	assert.NoError(t, err)
+	code, state, err = f.srv.Authorize(authURL)
+	assert.NoError(t, err)
+	_, err = f.oauth.Complete(ctx, domain.IdentityProviderAuth0, state, code, domain.ClientInfo{})
+	assert.Error(t, err, ErrInvalidOAuthState)
+
+	// An identity linked to one customer cannot be linked to another.
+	authURL, err = f.oauth.BeginLink(ctx, attacker.Session, domain.IdentityProviderAuth0)
+	assert.NoError(t, err)
+	code, state, err = f.srv.Authorize(authURL)
+	assert.NoError(t, err)
+	_, err = f.oauth.CompleteLink(ctx, attacker.Session, domain.IdentityProviderAuth0, state, code)
+	assert.NoError(t, err)
+
+	authURL, err = f.oauth.BeginLink(ctx, victim.Session, domain.IdentityProviderAuth0)
+	assert.NoError(t, err)
+	code, state, err = f.srv.Authorize(authURL)
+	assert.NoError(t, err)
+	_, err = f.oauth.CompleteLink(ctx, victim.Session, domain.IdentityProviderAuth0, state, code)
+	assert.Error(t, err, domain.ErrIdentityExists)
+
+	// Linking requires a recent authentication.
+	victim.Session.AuthenticatedAt = time.Now().Add(-time.Hour)
+	_, err = f.oauth.BeginLink(ctx, victim.Session, domain.IdentityProviderAuth0)
+	assert.Error(t, err, ErrReauthenticationRequired)
+}
+
+func TestOAuthService_UnlinkLastSignInMethod(t *testing.T) {
+	ctx := context.Background()
+	f := newOAuthFixture(t)
+
+	code, state := f.auth0(t)
+	result, err := f.oauth.Complete(ctx, domain.IdentityProviderAuth0, state, code, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	identities, err := f.oauth.Identities(ctx, result.Customer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, len(identities), 1)
+
+	err = f.oauth.Unlink(ctx, result.Session, identities[0].ID)
+	assert.Error(t, err, ErrLastSignInMethod)
+}
@@ -0,0 +1,620 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/uuid"
+	"go.brokedaear.com/pkg/webauthn"
+)
+
+// passkeyRepository stores the passkeys of customers.
+type passkeyRepository interface {
+	// Insert returns domain.ErrPasskeyExists if the credential is already
+	// registered.
+	Insert(ctx context.Context, passkey *domain.Passkey) error
+	GetByCredentialID(ctx context.Context, credentialID []byte) (*domain.Passkey, error)
+	ListByCustomer(ctx context.Context, customerID string) ([]*domain.Passkey, error)
+	// UpdateUsage records the signature counter a passkey last reported and
+	// the time it was used at.
+	UpdateUsage(ctx context.Context, id string, signCount uint32, at time.Time) error
+	Delete(ctx context.Context, id string) error
+}
+
+// passkeyCeremonyRepository stores WebAuthn ceremonies between the options
+// sent to the browser and its response.
+type passkeyCeremonyRepository interface {
+	Insert(ctx context.Context, ceremony *domain.PasskeyCeremony) error
+	// Take removes a ceremony and returns it, so that a ceremony can only be
+	// completed once.
+	Take(ctx context.Context, id string) (*domain.PasskeyCeremony, error)
+}
+
+// PasskeyPolicy configures passkeys.
+type PasskeyPolicy struct {
+	// RelyingParty is the website passkeys are scoped to.
+	RelyingParty webauthn.RelyingParty
+	// CeremonyTTL is how long the browser has to answer a ceremony.
+	CeremonyTTL time.Duration
+	// MaxPasskeys is how many passkeys a customer can register.
+	MaxPasskeys int
+}
+
+// DefaultPasskeyPolicy returns a policy for passkeys scoped to a domain that
+// may be used on the given origins. Authenticators must verify the customer,
+// and the browser has 5 minutes to answer. A customer can register 10
+// passkeys.
+func DefaultPasskeyPolicy(rpID string, origins ...string) PasskeyPolicy {
+	return PasskeyPolicy{
+		RelyingParty: webauthn.RelyingParty{
+			ID:                      rpID,
+			Name:                    "BROKE DA EAR",
+			Origins:                 origins,
+			RequireUserVerification: true,
+		},
+		CeremonyTTL: 5 * time.Minute,
+		MaxPasskeys: 10,
+	}
+}
+
+// PasskeyCreationOptions are the options of a registration ceremony, which
+// the client passes on to navigator.credentials.create.
+type PasskeyCreationOptions struct {
+	// Ceremony is the ID the ceremony is completed with.
+	Ceremony string
+	// Challenge is the challenge the authenticator signs.
+	Challenge []byte
+	// RelyingPartyID is the domain the passkey is scoped to.
+	RelyingPartyID string
+	// RelyingPartyName is the name authenticators show the customer.
+	RelyingPartyName string
+	// UserHandle is the handle the passkey is created for, which is the
+	// customer ID.
+	UserHandle []byte
+	// UserName is the email address of the customer.
+	UserName string
+	// Algorithms are the COSE algorithms the backend accepts, in order of
+	// preference.
+	Algorithms []int64
+	// ExcludeCredentials are the credential IDs of the customer's passkeys,
+	// so that an authenticator is not registered twice.
+	ExcludeCredentials [][]byte
+	// UserVerification is "required" or "preferred".
+	UserVerification string
+	// Timeout is how long the browser has to answer.
+	Timeout time.Duration
+}
+
+// PasskeyRequestOptions are the options of an authentication ceremony,
+// which the client passes on to navigator.credentials.get. No credentials
+// are allowed explicitly, so that the browser offers every passkey of the
+// relying party and the customer does not have to type their email.
+type PasskeyRequestOptions struct {
+	// Ceremony is the ID the ceremony is completed with.
+	Ceremony string
+	// Challenge is the challenge the authenticator signs.
+	Challenge []byte
+	// RelyingPartyID is the domain passkeys are scoped to.
+	RelyingPartyID string
+	// UserVerification is "required" or "preferred".
+	UserVerification string
+	// Timeout is how long the browser has to answer.
+	Timeout time.Duration
+}
+
+// defaultPasskeyName is the name of the passkey a customer signs up with.
+const defaultPasskeyName = "Passkey"
+
+// PasskeyService signs customers in and up with passkeys, and lets them
+// manage the passkeys on their account.
+//
+// Every ceremony is started by the backend, which hands the client the
+// options to pass to the browser, and completed with what the browser
+// returns. A customer can sign up with a passkey instead of a password, in
+// which case the passkey is their only way to sign in until they add
+// another one or link an identity.
+//
+// Authenticators must verify the customer with a PIN or biometrics by
+// default, so a passkey is two factors on its own, and signing in with one
+// skips the MFA challenge. If the policy does not require user
+// verification, customers with MFA enabled are challenged like after a
+// password.
+type PasskeyService struct {
+	*ServiceBase
+	auth       *AuthService
+	customers  customerRepository
+	identities identityRepository
+	passkeys   passkeyRepository
+	ceremonies passkeyCeremonyRepository
+	policy     PasskeyPolicy
+}
+
+// NewPasskeyService creates a new PasskeyService.
+func NewPasskeyService(
+	svcBase *ServiceBase,
+	auth *AuthService,
+	customers customerRepository,
+	identities identityRepository,
+	passkeys passkeyRepository,
+	ceremonies passkeyCeremonyRepository,
+	policy PasskeyPolicy,
+) *PasskeyService {
+	return &PasskeyService{
+		ServiceBase: svcBase,
+		auth:        auth,
+		customers:   customers,
+		identities:  identities,
+		passkeys:    passkeys,
+		ceremonies:  ceremonies,
+		policy:      policy,
+	}
+}
+
+// BeginRegistration starts registering a new passkey for a signed-in
+// customer. Registering is sensitive, so the customer must have
+// authenticated recently in the session.
+func (p *PasskeyService) BeginRegistration(
+	ctx context.Context,
+	session *domain.UserSession,
+) (*PasskeyCreationOptions, error) {
+	err := p.auth.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return nil, err
+	}
+	customer, err := p.customers.GetByID(ctx, session.UserID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get customer")
+	}
+	passkeys, err := p.passkeys.ListByCustomer(ctx, customer.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list passkeys")
+	}
+	if len(passkeys) >= p.policy.MaxPasskeys {
+		return nil, ErrTooManyPasskeys
+	}
+
+	ceremony, err := p.begin(ctx, domain.PasskeyCeremonyRegistration, customer.ID, "")
+	if err != nil {
+		return nil, err
+	}
+	exclude := make([][]byte, len(passkeys))
+	for i, pk := range passkeys {
+		exclude[i] = pk.CredentialID
+	}
+	return p.creationOptions(ceremony, customer.Email, exclude), nil
+}
+
+// FinishRegistration completes a ceremony started by BeginRegistration with
+// what the browser returned, and adds the passkey to the customer's account
+// under a name of their choosing. The ceremony must be completed in the
+// session of the customer who started it.
+func (p *PasskeyService) FinishRegistration(
+	ctx context.Context,
+	session *domain.UserSession,
+	ceremonyID, name string,
+	clientDataJSON, attestationObject []byte,
+) (*domain.Passkey, error) {
+	fail := func(reason string, err error) (*domain.Passkey, error) {
+		p.auth.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionPasskeyRegister, domain.AuditOutcomeFailure, session.UserID, reason,
+		))
+		return nil, err
+	}
+
+	err := p.auth.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return fail("reauthentication_required", err)
+	}
+	ceremony, err := p.take(ctx, ceremonyID, domain.PasskeyCeremonyRegistration)
+	if err != nil {
+		return fail("invalid_ceremony", err)
+	}
+	if ceremony.UserID != session.UserID {
+		return fail("invalid_ceremony", ErrInvalidPasskeyCeremony)
+	}
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	passkey, reason, err := p.register(ctx, ceremony, name, clientDataJSON, attestationObject)
+	if err != nil {
+		return fail(reason, err)
+	}
+	return passkey, nil
+}
+
+// BeginSignUp starts signing up a new customer with a passkey instead of a
+// password.
+func (p *PasskeyService) BeginSignUp(ctx context.Context, email string) (*PasskeyCreationOptions, error) {
+	if email == "" {
+		p.auth.signUpFailed(ctx, "", "missing_credentials")
+		return nil, ErrEmailEmpty
+	}
+	err := p.emailUnused(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	// The passkey is created for the ID the customer will get, since the
+	// user handle cannot change once the passkey exists.
+	customerID, err := uuid.New()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to make customer id")
+	}
+	ceremony, err := p.begin(ctx, domain.PasskeyCeremonySignUp, customerID, email)
+	if err != nil {
+		return nil, err
+	}
+	return p.creationOptions(ceremony, email, [][]byte{}), nil
+}
+
+// FinishSignUp completes a ceremony started by BeginSignUp with what the
+// browser returned. It creates a customer without a password with the
+// passkey, sends them a verification email and signs them in.
+func (p *PasskeyService) FinishSignUp(
+	ctx context.Context,
+	ceremonyID string,
+	clientDataJSON, attestationObject []byte,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	ceremony, err := p.take(ctx, ceremonyID, domain.PasskeyCeremonySignUp)
+	if err != nil {
+		p.auth.signUpFailed(ctx, "", "invalid_ceremony")
+		return nil, err
+	}
+	credential, err := p.policy.RelyingParty.VerifyRegistration(
+		ceremony.Challenge, clientDataJSON, attestationObject,
+	)
+	if err != nil {
+		p.logger.Warn("passkey registration rejected", "error", err)
+		p.auth.signUpFailed(ctx, "", "invalid_attestation")
+		return nil, ErrInvalidPasskey
+	}
+	// Someone may have signed up with the email address in the meantime.
+	err = p.emailUnused(ctx, ceremony.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	customer, err := domain.NewCustomer(ceremony.Email, "", nil)
+	if err != nil {
+		p.auth.signUpFailed(ctx, "", "invalid_customer")
+		return nil, ErrCustomerSignUpFailed
+	}
+	customer.ID = ceremony.UserID
+	passkey, err := domain.NewPasskey(customer.ID, defaultPasskeyName, credential)
+	if err != nil {
+		p.auth.signUpFailed(ctx, "", "invalid_passkey")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	err = p.inTx(ctx, func(ctx context.Context) error {
+		err := p.customers.Insert(ctx, customer)
+		if err != nil {
+			return err
+		}
+		err = p.passkeys.Insert(ctx, passkey)
+		if err != nil {
+			// Without the passkey, nobody could sign in to the account.
+			// Without transactions, the customer is not rolled back.
+			p.logger.Error("failed to insert passkey", "customer_id", customer.ID, "error", err)
+			if err := p.customers.Delete(ctx, customer); err != nil {
+				p.logger.Error("failed to delete customer without passkey", "customer_id", customer.ID, "error", err)
+			}
+			return err
+		}
+		return p.emit(ctx, domain.EventTypeCustomerSignedUp, customer.ID, domain.CustomerSignedUp{
+			CustomerID: customer.ID,
+			Method:     "passkey",
+		})
+	})
+	if err != nil {
+		p.logger.Error("signup failed", "error", err)
+		p.auth.signUpFailed(ctx, "", "repository_error")
+		return nil, ErrCustomerSignUpFailed
+	}
+
+	p.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionSignUp, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	p.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionPasskeyRegister, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+
+	err = p.auth.verification.Send(ctx, customer)
+	if err != nil {
+		p.logger.Error("failed to send verification email", "customer_id", customer.ID, "error", err)
+	}
+
+	session, err := p.auth.issueSession(ctx, customer, client)
+	if err != nil {
+		return &AuthResult{Customer: customer, Session: nil, MFAChallenge: ""}, err
+	}
+	return &AuthResult{Customer: customer, Session: session, MFAChallenge: ""}, nil
+}
+
+// BeginSignIn starts signing a customer in with a passkey.
+func (p *PasskeyService) BeginSignIn(ctx context.Context) (*PasskeyRequestOptions, error) {
+	ceremony, err := p.begin(ctx, domain.PasskeyCeremonySignIn, "", "")
+	if err != nil {
+		return nil, err
+	}
+	return &PasskeyRequestOptions{
+		Ceremony:         ceremony.ID,
+		Challenge:        ceremony.Challenge,
+		RelyingPartyID:   p.policy.RelyingParty.ID,
+		UserVerification: p.userVerification(),
+		Timeout:          p.policy.CeremonyTTL,
+	}, nil
+}
+
+// FinishSignIn completes a ceremony started by BeginSignIn with the
+// assertion the browser returned, and signs in the customer the passkey
+// belongs to.
+//
+// An assertion whose signature counter did not go up is rejected, since
+// the passkey may have been cloned.
+func (p *PasskeyService) FinishSignIn(
+	ctx context.Context,
+	ceremonyID string,
+	assertion webauthn.Assertion,
+	client domain.ClientInfo,
+) (*AuthResult, error) {
+	ceremony, err := p.take(ctx, ceremonyID, domain.PasskeyCeremonySignIn)
+	if err != nil {
+		p.auth.signInFailed(ctx, "", "invalid_ceremony")
+		return nil, err
+	}
+
+	passkey, err := p.passkeys.GetByCredentialID(ctx, assertion.CredentialID)
+	if err != nil {
+		reason := "repository_error"
+		if errors.Is(err, domain.ErrPasskeyNotFound) {
+			reason = "unknown_passkey"
+		} else {
+			p.logger.Error("failed to get passkey", "error", err)
+		}
+		p.auth.signInFailed(ctx, "", reason)
+		return nil, ErrCustomerLoginFailed
+	}
+	if len(assertion.UserHandle) > 0 &&
+		subtle.ConstantTimeCompare(assertion.UserHandle, []byte(passkey.UserID)) != 1 {
+		p.auth.signInFailed(ctx, passkey.UserID, "user_handle_mismatch")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	signCount, err := p.policy.RelyingParty.VerifyAssertion(
+		ceremony.Challenge, passkey.PublicKey, passkey.SignCount, assertion,
+	)
+	if errors.Is(err, webauthn.ErrSignCountRegressed) {
+		p.logger.Warn("passkey signature counter went back, it may be cloned",
+			"customer_id", passkey.UserID, "passkey_id", passkey.ID)
+		p.auth.signInFailed(ctx, passkey.UserID, "sign_count_regressed")
+		return nil, ErrCustomerLoginFailed
+	}
+	if err != nil {
+		p.logger.Warn("passkey assertion rejected", "customer_id", passkey.UserID, "error", err)
+		p.auth.signInFailed(ctx, passkey.UserID, "invalid_assertion")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	now := time.Now().UTC()
+	err = p.passkeys.UpdateUsage(ctx, passkey.ID, signCount, now)
+	if err != nil {
+		// The counter must be stored for cloned passkeys to be detected.
+		p.logger.Error("failed to update passkey", "passkey_id", passkey.ID, "error", err)
+		p.auth.signInFailed(ctx, passkey.UserID, "repository_error")
+		return nil, ErrCustomerLoginFailed
+	}
+
+	customer, err := p.customers.GetByID(ctx, passkey.UserID)
+	if err != nil {
+		p.auth.signInFailed(ctx, passkey.UserID, reasonFromLookup(err))
+		return nil, ErrCustomerLoginFailed
+	}
+
+	if !p.policy.RelyingParty.RequireUserVerification {
+		enabled, err := p.auth.mfa.enabled(ctx, customer.ID)
+		if err != nil {
+			p.logger.Error("failed to check mfa", "customer_id", customer.ID, "error", err)
+			p.auth.signInFailed(ctx, customer.ID, "repository_error")
+			return nil, ErrCustomerLoginFailed
+		}
+		if enabled {
+			challenge, err := p.auth.mfa.challenge(ctx, customer)
+			if err != nil {
+				p.logger.Error("failed to issue mfa challenge", "customer_id", customer.ID, "error", err)
+				p.auth.signInFailed(ctx, customer.ID, "challenge_issue_failed")
+				return nil, ErrCustomerLoginFailed
+			}
+			return &AuthResult{Customer: customer, Session: nil, MFAChallenge: challenge}, nil
+		}
+	}
+
+	return p.auth.completeSignIn(ctx, customer, client, now)
+}
+
+// Passkeys returns the passkeys of a customer.
+func (p *PasskeyService) Passkeys(ctx context.Context, customerID string) ([]*domain.Passkey, error) {
+	return p.passkeys.ListByCustomer(ctx, customerID)
+}
+
+// Remove removes a passkey from the account of the customer a session
+// belongs to. The last way a customer can sign in cannot be removed, so a
+// customer without a password or a linked identity must keep at least one
+// passkey.
+func (p *PasskeyService) Remove(
+	ctx context.Context,
+	session *domain.UserSession,
+	passkeyID string,
+) error {
+	fail := func(reason string, err error) error {
+		p.auth.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionPasskeyRemove, domain.AuditOutcomeFailure, session.UserID, reason,
+		))
+		return err
+	}
+
+	err := p.auth.sessions.RequireRecentAuth(session)
+	if err != nil {
+		return fail("reauthentication_required", err)
+	}
+
+	customer, err := p.customers.GetByID(ctx, session.UserID)
+	if err != nil {
+		return fail(reasonFromLookup(err), errors.Wrap(err, "failed to get customer"))
+	}
+	passkeys, err := p.passkeys.ListByCustomer(ctx, customer.ID)
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to list passkeys"))
+	}
+
+	found := false
+	for _, pk := range passkeys {
+		found = found || pk.ID == passkeyID
+	}
+	if !found {
+		return fail("unknown_passkey", domain.ErrPasskeyNotFound)
+	}
+	if !customer.HasPassword() && len(passkeys) == 1 {
+		identities, err := p.identities.ListByCustomer(ctx, customer.ID)
+		if err != nil {
+			return fail("repository_error", errors.Wrap(err, "failed to list identities"))
+		}
+		if len(identities) == 0 {
+			return fail("last_sign_in_method", ErrLastSignInMethod)
+		}
+	}
+
+	err = p.passkeys.Delete(ctx, passkeyID)
+	if err != nil {
+		return fail("repository_error", errors.Wrap(err, "failed to remove passkey"))
+	}
+
+	p.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionPasskeyRemove, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return nil
+}
+
+// register verifies the result of a registration ceremony and stores the
+// passkey. It returns an audit reason along with an error.
+func (p *PasskeyService) register(
+	ctx context.Context,
+	ceremony *domain.PasskeyCeremony,
+	name string,
+	clientDataJSON, attestationObject []byte,
+) (*domain.Passkey, string, error) {
+	credential, err := p.policy.RelyingParty.VerifyRegistration(
+		ceremony.Challenge, clientDataJSON, attestationObject,
+	)
+	if err != nil {
+		p.logger.Warn("passkey registration rejected", "customer_id", ceremony.UserID, "error", err)
+		return nil, "invalid_attestation", ErrInvalidPasskey
+	}
+	passkey, err := domain.NewPasskey(ceremony.UserID, name, credential)
+	if err != nil {
+		return nil, "invalid_passkey", err
+	}
+	err = p.passkeys.Insert(ctx, passkey)
+	if errors.Is(err, domain.ErrPasskeyExists) {
+		return nil, "passkey_exists", err
+	}
+	if err != nil {
+		return nil, "repository_error", errors.Wrap(err, "failed to insert passkey")
+	}
+
+	p.auth.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionPasskeyRegister, domain.AuditOutcomeSuccess, ceremony.UserID, "",
+	))
+	return passkey, "", nil
+}
+
+// emailUnused checks that no customer uses an email address yet.
+func (p *PasskeyService) emailUnused(ctx context.Context, email string) error {
+	customer, err := p.customers.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		p.auth.signUpFailed(ctx, customer.ID, "customer_exists")
+		return ErrCustomerAlreadyExists
+	case !errors.Is(err, domain.ErrCustomerNotFound):
+		p.logger.Error("signup failed", "error", err)
+		p.auth.signUpFailed(ctx, "", "repository_error")
+		return ErrCustomerSignUpFailed
+	}
+	return nil
+}
+
+func (p *PasskeyService) begin(
+	ctx context.Context,
+	purpose domain.PasskeyCeremonyPurpose,
+	userID, email string,
+) (*domain.PasskeyCeremony, error) {
+	ceremony, err := domain.NewPasskeyCeremony(purpose, userID, email, p.policy.CeremonyTTL)
+	if err != nil {
+		return nil, err
+	}
+	err = p.ceremonies.Insert(ctx, ceremony)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store passkey ceremony")
+	}
+	return ceremony, nil
+}
+
+// take takes a ceremony that was started for a purpose and has not expired.
+func (p *PasskeyService) take(
+	ctx context.Context,
+	id string,
+	purpose domain.PasskeyCeremonyPurpose,
+) (*domain.PasskeyCeremony, error) {
+	ceremony, err := p.ceremonies.Take(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrPasskeyCeremonyNotFound) {
+			return nil, ErrInvalidPasskeyCeremony
+		}
+		return nil, errors.Wrap(err, "failed to get passkey ceremony")
+	}
+	if ceremony.Purpose != purpose || ceremony.Expired(time.Now()) {
+		return nil, ErrInvalidPasskeyCeremony
+	}
+	return ceremony, nil
+}
+
+func (p *PasskeyService) creationOptions(
+	ceremony *domain.PasskeyCeremony,
+	email string,
+	exclude [][]byte,
+) *PasskeyCreationOptions {
+	return &PasskeyCreationOptions{
+		Ceremony:           ceremony.ID,
+		Challenge:          ceremony.Challenge,
+		RelyingPartyID:     p.policy.RelyingParty.ID,
+		RelyingPartyName:   p.policy.RelyingParty.Name,
+		UserHandle:         []byte(ceremony.UserID),
+		UserName:           email,
+		Algorithms:         []int64{webauthn.AlgES256, webauthn.AlgEdDSA},
+		ExcludeCredentials: exclude,
+		UserVerification:   p.userVerification(),
+		Timeout:            p.policy.CeremonyTTL,
+	}
+}
+
+func (p *PasskeyService) userVerification() string {
+	if p.policy.RelyingParty.RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+var (
+	ErrInvalidPasskeyCeremony = errors.New("invalid or expired passkey ceremony")
+	ErrInvalidPasskey         = errors.New("passkey was rejected")
+	ErrTooManyPasskeys        = errors.New("too many passkeys")
+)
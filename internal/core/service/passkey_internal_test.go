@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+	"go.brokedaear.com/pkg/webauthn"
+	"go.brokedaear.com/pkg/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "brokedaear.com"
+	testOrigin = "https://brokedaear.com"
+)
+
+type passkeyFixture struct {
+	authFixture
+	passkeys      *PasskeyService
+	authenticator *webauthntest.Authenticator
+}
+
+func newPasskeyFixture(t *testing.T) passkeyFixture {
+	t.Helper()
+	f := newAuthFixture(t)
+	passkeys := NewPasskeyService(
+		f.auth.ServiceBase, f.auth, f.customers, memory.NewIdentityRepository(),
+		memory.NewPasskeyRepository(), memory.NewPasskeyCeremonyRepository(),
+		DefaultPasskeyPolicy(testRPID, testOrigin),
+	)
+	return passkeyFixture{
+		authFixture:   f,
+		passkeys:      passkeys,
+		authenticator: webauthntest.NewAuthenticator(testRPID, testOrigin),
+	}
+}
+
+// signUp signs up a customer without a password, and returns the credential
+// ID of their passkey.
+func (f passkeyFixture) signUp(t *testing.T) (*AuthResult, []byte) {
+	t.Helper()
+	ctx := context.Background()
+	options, err := f.passkeys.BeginSignUp(ctx, testEmail)
+	assert.NoError(t, err)
+	id, clientData, attestation, err := f.authenticator.Create(options.Challenge, options.UserHandle)
+	assert.NoError(t, err)
+	result, err := f.passkeys.FinishSignUp(ctx, options.Ceremony, clientData, attestation, domain.ClientInfo{})
+	assert.NoError(t, err)
+	return result, id
+}
+
+// signIn signs in with a passkey. The assertion can be changed before it is
+// sent.
+func (f passkeyFixture) signIn(
+	t *testing.T,
+	credentialID []byte,
+	mangle func(*webauthn.Assertion),
+) (*AuthResult, error) {
+	t.Helper()
+	ctx := context.Background()
+	options, err := f.passkeys.BeginSignIn(ctx)
+	assert.NoError(t, err)
+	assertion, err := f.authenticator.Get(options.Challenge, credentialID)
+	assert.NoError(t, err)
+	mangle(&assertion)
+	return f.passkeys.FinishSignIn(ctx, options.Ceremony, assertion, domain.ClientInfo{})
+}
+
+func TestPasskeyService_SignUpAndSignIn(t *testing.T) {
+	ctx := context.Background()
+	f := newPasskeyFixture(t)
+
+	signedUp, id := f.signUp(t)
+	assert.False(t, signedUp.Customer.HasPassword())
+	assert.NotEqual(t, signedUp.Session, nil)
+	assert.Equal(t, f.mailer.count(), 1)
+
+	signedIn, err := f.signIn(t, id, func(*webauthn.Assertion) {})
+	assert.NoError(t, err)
+	assert.Equal(t, signedIn.Customer.ID, signedUp.Customer.ID)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionSignIn)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	_, err = f.sessions.Validate(ctx, signedIn.Session.Token)
+	assert.NoError(t, err)
+
+	passkeys, err := f.passkeys.Passkeys(ctx, signedUp.Customer.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, len(passkeys), 1)
+	assert.NotEqual(t, passkeys[0].LastUsedAt, nil)
+
+	// The account has no password to sign in with.
+	_, err = f.auth.SignIn(ctx, testEmail, "", domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerLoginFailed)
+
+	// The email address is taken now.
+	_, err = f.passkeys.BeginSignUp(ctx, testEmail)
+	assert.Error(t, err, ErrCustomerAlreadyExists)
+}
+
+func TestPasskeyService_CeremonyIsUsedOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newPasskeyFixture(t)
+	_, id := f.signUp(t)
+
+	options, err := f.passkeys.BeginSignIn(ctx)
+	assert.NoError(t, err)
+	assertion, err := f.authenticator.Get(options.Challenge, id)
+	assert.NoError(t, err)
+	_, err = f.passkeys.FinishSignIn(ctx, options.Ceremony, assertion, domain.ClientInfo{})
+	assert.NoError(t, err)
+	_, err = f.passkeys.FinishSignIn(ctx, options.Ceremony, assertion, domain.ClientInfo{})
+	assert.Error(t, err, ErrInvalidPasskeyCeremony)
+
+	// A sign in ceremony cannot complete a sign up.
+	options, err = f.passkeys.BeginSignIn(ctx)
+	assert.NoError(t, err)
+	_, clientData, attestation, err := f.authenticator.Create(options.Challenge, []byte("customer-1"))
+	assert.NoError(t, err)
+	_, err = f.passkeys.FinishSignUp(ctx, options.Ceremony, clientData, attestation, domain.ClientInfo{})
+	assert.Error(t, err, ErrInvalidPasskeyCeremony)
+}
+
+func TestPasskeyService_FinishSignInFailures(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		// prepare runs before the passkey signs in.
+		prepare func(t *testing.T, f passkeyFixture, id []byte)
+		mangle  func(a *webauthn.Assertion)
+	}{
+		{
+			CaseBase: test.NewCaseBase("unknown passkey", "unknown_passkey", true),
+			prepare:  func(*testing.T, passkeyFixture, []byte) {},
+			mangle:   func(a *webauthn.Assertion) { a.CredentialID = []byte("someone else's") },
+		},
+		{
+			CaseBase: test.NewCaseBase("user handle mismatch", "user_handle_mismatch", true),
+			prepare:  func(*testing.T, passkeyFixture, []byte) {},
+			mangle:   func(a *webauthn.Assertion) { a.UserHandle = []byte("someone else") },
+		},
+		{
+			CaseBase: test.NewCaseBase("forged signature", "invalid_assertion", true),
+			prepare:  func(*testing.T, passkeyFixture, []byte) {},
+			mangle:   func(a *webauthn.Assertion) { a.Signature[len(a.Signature)-1]++ },
+		},
+		{
+			CaseBase: test.NewCaseBase("cloned passkey", "sign_count_regressed", true),
+			prepare: func(t *testing.T, f passkeyFixture, id []byte) {
+				// The authenticator reports a counter once, and then goes
+				// back to a lower one.
+				f.authenticator.SignCount = 7
+				_, err := f.signIn(t, id, func(*webauthn.Assertion) {})
+				assert.NoError(t, err)
+				f.authenticator.SignCount = 3
+			},
+			mangle: func(*webauthn.Assertion) {},
+		},
+		{
+			CaseBase: test.NewCaseBase("not verified", "invalid_assertion", true),
+			prepare: func(_ *testing.T, f passkeyFixture, _ []byte) {
+				f.authenticator.SkipUserVerification = true
+			},
+			mangle: func(*webauthn.Assertion) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newPasskeyFixture(t)
+				_, id := f.signUp(t)
+				tt.prepare(t, f, id)
+
+				_, err := f.signIn(t, id, tt.mangle)
+				assert.Error(t, err, ErrCustomerLoginFailed)
+				assert.Equal(t, f.audit.last().Action, domain.AuditActionSignIn)
+				assert.Equal(t, f.audit.last().Reason, tt.Want.(string))
+			},
+		)
+	}
+}
+
+func TestPasskeyService_RegisterAndRemove(t *testing.T) {
+	ctx := context.Background()
+	f := newPasskeyFixture(t)
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	register := func(alg int64, name string) []byte {
+		f.authenticator.Algorithm = alg
+		options, err := f.passkeys.BeginRegistration(ctx, result.Session)
+		assert.NoError(t, err)
+		assert.Equal(t, string(options.UserHandle), result.Customer.ID)
+		id, clientData, attestation, err := f.authenticator.Create(options.Challenge, options.UserHandle)
+		assert.NoError(t, err)
+		passkey, err := f.passkeys.FinishRegistration(ctx, result.Session, options.Ceremony, name, clientData, attestation)
+		assert.NoError(t, err)
+		assert.Equal(t, passkey.Name, name)
+		assert.Equal(t, f.audit.last().Action, domain.AuditActionPasskeyRegister)
+		return id
+	}
+	phone := register(webauthn.AlgES256, "Phone")
+	laptop := register(webauthn.AlgEdDSA, "Laptop")
+
+	// Both passkeys sign in to the same account.
+	for _, id := range [][]byte{phone, laptop} {
+		signedIn, err := f.signIn(t, id, func(*webauthn.Assertion) {})
+		assert.NoError(t, err)
+		assert.Equal(t, signedIn.Customer.ID, result.Customer.ID)
+	}
+
+	// The registered passkeys are excluded from new registrations.
+	options, err := f.passkeys.BeginRegistration(ctx, result.Session)
+	assert.NoError(t, err)
+	assert.Equal(t, len(options.ExcludeCredentials), 2)
+
+	passkeys, err := f.passkeys.Passkeys(ctx, result.Customer.ID)
+	assert.NoError(t, err)
+	err = f.passkeys.Remove(ctx, result.Session, passkeys[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionPasskeyRemove)
+	_, err = f.signIn(t, phone, func(*webauthn.Assertion) {})
+	assert.Error(t, err, ErrCustomerLoginFailed)
+
+	// Registering is sensitive.
+	result.Session.AuthenticatedAt = time.Now().Add(-time.Hour)
+	_, err = f.passkeys.BeginRegistration(ctx, result.Session)
+	assert.Error(t, err, ErrReauthenticationRequired)
+	err = f.passkeys.Remove(ctx, result.Session, passkeys[1].ID)
+	assert.Error(t, err, ErrReauthenticationRequired)
+}
+
+func TestPasskeyService_LastPasskeyCannotBeRemoved(t *testing.T) {
+	ctx := context.Background()
+	f := newPasskeyFixture(t)
+	result, _ := f.signUp(t)
+
+	passkeys, err := f.passkeys.Passkeys(ctx, result.Customer.ID)
+	assert.NoError(t, err)
+	err = f.passkeys.Remove(ctx, result.Session, passkeys[0].ID)
+	assert.Error(t, err, ErrLastSignInMethod)
+
+	err = f.passkeys.Remove(ctx, result.Session, "unknown")
+	assert.Error(t, err, domain.ErrPasskeyNotFound)
+}
+
+func TestPasskeyService_RegistrationIsBoundToSession(t *testing.T) {
+	ctx := context.Background()
+	f := newPasskeyFixture(t)
+	kai, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	other, err := f.auth.SignUp(ctx, "someone@brokedaear.com", testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	options, err := f.passkeys.BeginRegistration(ctx, kai.Session)
+	assert.NoError(t, err)
+	_, clientData, attestation, err := f.authenticator.Create(options.Challenge, options.UserHandle)
+	assert.NoError(t, err)
+	_, err = f.passkeys.FinishRegistration(ctx, other.Session, options.Ceremony, "", clientData, attestation)
+	assert.Error(t, err, ErrInvalidPasskeyCeremony)
+}
+
+func TestPasskeyService_MFA(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		requireUserVerification bool
+	}{
+		{
+			CaseBase:                test.NewCaseBase("verified passkeys skip mfa", false, false),
+			requireUserVerification: true,
+		},
+		{
+			CaseBase:                test.NewCaseBase("unverified passkeys are challenged", true, false),
+			requireUserVerification: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				ctx := context.Background()
+				f := newPasskeyFixture(t)
+				f.passkeys.policy.RelyingParty.RequireUserVerification = tt.requireUserVerification
+				c := enrollMFA(t, f.authFixture)
+
+				options, err := f.passkeys.BeginRegistration(ctx, c.session)
+				assert.NoError(t, err)
+				id, clientData, attestation, err := f.authenticator.Create(options.Challenge, options.UserHandle)
+				assert.NoError(t, err)
+				_, err = f.passkeys.FinishRegistration(ctx, c.session, options.Ceremony, "", clientData, attestation)
+				assert.NoError(t, err)
+
+				result, err := f.signIn(t, id, func(*webauthn.Assertion) {})
+				assert.NoError(t, err)
+				assert.Equal(t, result.MFAChallenge != "", tt.Want.(bool))
+				assert.Equal(t, result.Session == nil, tt.Want.(bool))
+			},
+		)
+	}
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/strength"
+)
+
+// PasswordPolicy configures which passwords customers may choose. How they
+// are hashed is up to the customer repository, see
+// customerRepository.HashParams.
+type PasswordPolicy struct {
+	// MinLength is the least number of characters a password has.
+	MinLength int
+	// MaxLength is the number of bytes a password must be shorter than.
+	MaxLength int
+	// MinScore is the least strength score, from 0 to 4, a password has. See
+	// strength.Estimate.
+	MinScore int
+	// BrandWords are words a password may not contain, such as the name of
+	// the store.
+	BrandWords []string
+}
+
+// DefaultPasswordPolicy returns the password policy of an environment.
+// Production and staging require passwords that take at least 10^8 guesses.
+// Development only turns away the most guessable ones, so that test accounts
+// are easy to make.
+func DefaultPasswordPolicy(env domain.Environment) PasswordPolicy {
+	policy := PasswordPolicy{
+		MinLength:  8,
+		MaxLength:  256,
+		MinScore:   3,
+		BrandWords: []string{"brokedaear", "brokedaearllc"},
+	}
+	if env == domain.EnvDevelopment {
+		policy.MinScore = 1
+	}
+	return policy
+}
+
+// Check checks a new password of a customer with an email address. It
+// returns why the password is rejected, or nil if it is not.
+func (p PasswordPolicy) Check(password, email string) []domain.PasswordFeedback {
+	if len(password) >= p.MaxLength {
+		return []domain.PasswordFeedback{domain.PasswordFeedbackTooLong}
+	}
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return []domain.PasswordFeedback{domain.PasswordFeedbackTooShort}
+	}
+
+	inputs := append(slices.Clone(p.BrandWords), emailWords(email)...)
+	result := strength.Estimate(password, inputs...)
+
+	var feedback []domain.PasswordFeedback
+	add := func(f domain.PasswordFeedback) {
+		if !slices.Contains(feedback, f) {
+			feedback = append(feedback, f)
+		}
+	}
+
+	// Passwords built from the email address or the name of the store are
+	// the first an attacker tries, however long they are.
+	for _, m := range result.Sequence {
+		if m.Pattern != strength.PatternDictionary || !m.UserInput {
+			continue
+		}
+		isBrand := slices.ContainsFunc(p.BrandWords, func(w string) bool {
+			return strings.EqualFold(w, m.Word)
+		})
+		if isBrand {
+			add(domain.PasswordFeedbackContainsBrand)
+		} else {
+			add(domain.PasswordFeedbackContainsEmail)
+		}
+	}
+
+	if result.Score >= p.MinScore {
+		return feedback
+	}
+	add(domain.PasswordFeedbackTooGuessable)
+	for _, m := range result.Sequence {
+		switch m.Pattern {
+		case strength.PatternDictionary:
+			if !m.UserInput {
+				add(domain.PasswordFeedbackCommonPassword)
+			}
+		case strength.PatternSpatial:
+			add(domain.PasswordFeedbackKeyboardPattern)
+		case strength.PatternRepeat:
+			add(domain.PasswordFeedbackRepeatedCharacters)
+		case strength.PatternSequence:
+			add(domain.PasswordFeedbackSequence)
+		case strength.PatternDate:
+			add(domain.PasswordFeedbackDate)
+		case strength.PatternBruteforce:
+		}
+	}
+	return feedback
+}
+
+// emailWords returns the parts of an email address a password could be made
+// of: the local part, its words, and the name of the domain. For
+// `kai.smith@example.com`, they are `kai.smith`, `kai`, `smith` and
+// `example`.
+func emailWords(email string) []string {
+	email = strings.ToLower(email)
+	local, host, _ := strings.Cut(email, "@")
+	domainName, _, _ := strings.Cut(host, ".")
+
+	words := []string{local}
+	words = append(words, strings.FieldsFunc(local, func(r rune) bool {
+		return strings.ContainsRune(".-_+", r)
+	})...)
+	words = append(words, domainName)
+	return slices.DeleteFunc(slices.Compact(words), func(w string) bool { return w == "" })
+}
+
+// PasswordRejectedError is returned when a new password does not meet the
+// password policy. Its feedback says why, so that the customer can be told
+// how to choose a better one.
+type PasswordRejectedError struct {
+	Feedback []domain.PasswordFeedback
+}
+
+func (e *PasswordRejectedError) Error() string {
+	codes := make([]string, len(e.Feedback))
+	for i, f := range e.Feedback {
+		codes[i] = f.String()
+	}
+	return "password rejected: " + strings.Join(codes, ", ")
+}
+
+// Is matches ErrPasswordTooShort or ErrPasswordTooLong if the password was
+// rejected for its length, and ErrPasswordTooWeak otherwise.
+func (e *PasswordRejectedError) Is(target error) bool {
+	switch {
+	case slices.Contains(e.Feedback, domain.PasswordFeedbackTooShort):
+		return errors.Is(ErrPasswordTooShort, target)
+	case slices.Contains(e.Feedback, domain.PasswordFeedbackTooLong):
+		return errors.Is(ErrPasswordTooLong, target)
+	default:
+		return errors.Is(ErrPasswordTooWeak, target)
+	}
+}
+
+// reason returns the audit reason of the rejection.
+func (e *PasswordRejectedError) reason() string {
+	switch {
+	case errors.Is(e, ErrPasswordTooShort):
+		return "password_too_short"
+	case errors.Is(e, ErrPasswordTooLong):
+		return "password_too_long"
+	default:
+		return "password_too_weak"
+	}
+}
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := DefaultPasswordPolicy(domain.EnvProduction)
+
+	tests := []struct {
+		test.CaseBase
+		password string
+	}{
+		{
+			CaseBase: test.NewCaseBase("passphrase", []domain.PasswordFeedback(nil), false),
+			password: testPassword,
+		},
+		{
+			CaseBase: test.NewCaseBase("too short", []domain.PasswordFeedback{domain.PasswordFeedbackTooShort}, true),
+			password: "x7#Lq9!",
+		},
+		{
+			CaseBase: test.NewCaseBase("too long", []domain.PasswordFeedback{domain.PasswordFeedbackTooLong}, true),
+			password: strings.Repeat("x", 256),
+		},
+		{
+			CaseBase: test.NewCaseBase("common password", []domain.PasswordFeedback{
+				domain.PasswordFeedbackTooGuessable, domain.PasswordFeedbackCommonPassword,
+			}, true),
+			password: "P@ssw0rd",
+		},
+		{
+			CaseBase: test.NewCaseBase("keyboard pattern", []domain.PasswordFeedback{
+				domain.PasswordFeedbackTooGuessable, domain.PasswordFeedbackKeyboardPattern,
+			}, true),
+			password: "xsw23edc",
+		},
+		{
+			CaseBase: test.NewCaseBase("repeated characters", []domain.PasswordFeedback{
+				domain.PasswordFeedbackTooGuessable, domain.PasswordFeedbackRepeatedCharacters,
+			}, true),
+			password: "zzzzzzzzzzzz",
+		},
+		{
+			CaseBase: test.NewCaseBase("sequence", []domain.PasswordFeedback{
+				domain.PasswordFeedbackTooGuessable, domain.PasswordFeedbackSequence,
+			}, true),
+			password: "lmnopqrstu",
+		},
+		{
+			CaseBase: test.NewCaseBase("date", []domain.PasswordFeedback{
+				domain.PasswordFeedbackTooGuessable, domain.PasswordFeedbackDate,
+			}, true),
+			password: "19870315",
+		},
+		{
+			CaseBase: test.NewCaseBase("contains email", []domain.PasswordFeedback{
+				domain.PasswordFeedbackContainsEmail,
+			}, true),
+			password: "Kai swims at dawn with otters",
+		},
+		{
+			CaseBase: test.NewCaseBase("contains brand", []domain.PasswordFeedback{
+				domain.PasswordFeedbackContainsBrand,
+			}, true),
+			password: "i love broke da ear records, brokedaear!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				got := policy.Check(tt.password, "kai@example.com")
+				assert.Equal(t, len(got) > 0, tt.WantErr)
+				assert.True(t, slices.Equal(got, tt.Want.([]domain.PasswordFeedback)))
+			},
+		)
+	}
+}
+
+func TestPasswordPolicy_Environments(t *testing.T) {
+	const password = "sunshine1987"
+
+	assert.Equal(t, len(DefaultPasswordPolicy(domain.EnvDevelopment).Check(password, testEmail)), 0)
+	assert.NotEqual(t, len(DefaultPasswordPolicy(domain.EnvStaging).Check(password, testEmail)), 0)
+	assert.NotEqual(t, len(DefaultPasswordPolicy(domain.EnvProduction).Check(password, testEmail)), 0)
+}
+
+func TestEmailWords(t *testing.T) {
+	got := emailWords("Kai.Smith+shop@Example.com")
+	assert.True(t, slices.Equal(got, []string{"kai.smith+shop", "kai", "smith", "shop", "example"}))
+}
+
+func TestAuthService_SignUpRejectsWeakPassword(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	_, err := f.auth.SignUp(ctx, testEmail, "brokedaear2025", domain.ClientInfo{})
+	assert.Error(t, err, ErrPasswordTooWeak)
+	assert.Equal(t, f.audit.last().Reason, "password_too_weak")
+
+	var rejected *PasswordRejectedError
+	assert.True(t, errors.As(err, &rejected))
+	assert.True(t, slices.Contains(rejected.Feedback, domain.PasswordFeedbackContainsBrand))
+	assert.Equal(t, errors.Is(err, ErrPasswordTooShort), false)
+
+	_, err = f.auth.SignUp(ctx, testEmail, "short", domain.ClientInfo{})
+	assert.Error(t, err, ErrPasswordTooShort)
+	assert.Equal(t, f.audit.last().Reason, "password_too_short")
+}
@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// erasableCustomerRepository finds deleted customers and erases their
+// personal data.
+type erasableCustomerRepository interface {
+	GetByIDIncludingDeleted(ctx context.Context, id string) (*domain.Customer, error)
+	// ListErasable retrieves up to limit customers deleted before a point
+	// in time whose personal data was not erased yet.
+	ListErasable(ctx context.Context, deletedBefore time.Time, limit int) ([]*domain.Customer, error)
+	// Erase anonymizes the account of a deleted customer and removes their
+	// credentials. It returns domain.ErrCustomerNotFound if the customer is
+	// not deleted, or was already erased.
+	Erase(ctx context.Context, id string) error
+}
+
+// anonymizableOrderRepository lists and anonymizes the orders of a
+// customer.
+type anonymizableOrderRepository interface {
+	ListByCustomer(ctx context.Context, customerID string) ([]*domain.Order, error)
+	// AnonymizeByCustomer replaces the billing email of every order of a
+	// customer by email, and clears their billing name.
+	AnonymizeByCustomer(ctx context.Context, customerID, email string) error
+}
+
+// downloadLister lists the downloads of a customer.
+type downloadLister interface {
+	ListByCustomer(ctx context.Context, customerID string) ([]*domain.Download, error)
+}
+
+// PrivacyPolicy configures how personal data is erased.
+type PrivacyPolicy struct {
+	// ErasureGracePeriod is how long the account of a deleted customer can
+	// still be restored before their personal data is erased.
+	ErasureGracePeriod time.Duration
+	// ErasureBatch is the largest number of customers erased at a time.
+	ErasureBatch int
+}
+
+// DefaultPrivacyPolicy returns a policy where the personal data of deleted
+// customers is erased after 30 days.
+func DefaultPrivacyPolicy() PrivacyPolicy {
+	return PrivacyPolicy{
+		ErasureGracePeriod: 30 * 24 * time.Hour,
+		ErasureBatch:       100,
+	}
+}
+
+// PrivacyService lets customers exercise their rights over their personal
+// data. Customers can export their data, and deleting an account, through
+// CustomerService.Delete or AdminService.DeleteCustomer, starts its
+// erasure: once the grace period of the policy is over, the personal data
+// of the customer is anonymized in their account and in their orders.
+// Orders themselves, with their amounts and items, are kept for accounting.
+//
+// Erasures are finalized by EraseDue, which is meant to run on a schedule,
+// such as through a Reaper:
+//
+//	NewReaper(base, "customer erasures", ExpiredDeleterFunc(privacy.EraseDue), time.Hour)
+type PrivacyService struct {
+	*ServiceBase
+	customers erasableCustomerRepository
+	orders    anonymizableOrderRepository
+	downloads downloadLister
+	sessions  *SessionService
+	authz     *AuthorizationService
+	policy    PrivacyPolicy
+	audit     auditRecorder
+}
+
+// NewPrivacyService creates a new PrivacyService.
+func NewPrivacyService(
+	svcBase *ServiceBase,
+	customers erasableCustomerRepository,
+	orders anonymizableOrderRepository,
+	downloads downloadLister,
+	sessions *SessionService,
+	authz *AuthorizationService,
+	policy PrivacyPolicy,
+) *PrivacyService {
+	return &PrivacyService{
+		ServiceBase: svcBase,
+		customers:   customers,
+		orders:      orders,
+		downloads:   downloads,
+		sessions:    sessions,
+		authz:       authz,
+		policy:      policy,
+		audit:       svcBase.auditRecorder(),
+	}
+}
+
+// Export assembles the personal data of a customer into an archive.
+// Customers may export their own data, and support staff the data of any
+// customer, such as to answer a request sent by email. The data of a
+// deleted customer can be exported until it is erased.
+func (p *PrivacyService) Export(
+	ctx context.Context,
+	principal *domain.Principal,
+	customerID string,
+) (*domain.CustomerExport, error) {
+	action := domain.AuditActionCustomerExport
+	err := p.authz.Authorize(ctx, principal, domain.AccessRequest{
+		Permission: domain.PermissionCustomersView,
+		OwnerID:    customerID,
+		Amount:     0,
+	})
+	if err != nil {
+		p.record(ctx, principal, action, domain.AuditOutcomeFailure, customerID, "forbidden")
+		return nil, err
+	}
+
+	customer, err := p.customers.GetByIDIncludingDeleted(ctx, customerID)
+	if err == nil && customer.ErasedAt != nil {
+		err = domain.ErrCustomerNotFound
+	}
+	if err != nil {
+		reason := "repository_error"
+		if errors.Is(err, domain.ErrCustomerNotFound) {
+			reason = "customer_not_found"
+		}
+		p.record(ctx, principal, action, domain.AuditOutcomeFailure, customerID, reason)
+		return nil, err
+	}
+	sessions, err := p.sessions.List(ctx, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list sessions")
+	}
+	orders, err := p.orders.ListByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list orders")
+	}
+	downloads, err := p.downloads.ListByCustomer(ctx, customerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list downloads")
+	}
+
+	p.record(ctx, principal, action, domain.AuditOutcomeSuccess, customerID, "")
+	return domain.NewCustomerExport(customer, sessions, orders, downloads), nil
+}
+
+// EraseDue erases the personal data of every customer deleted longer than
+// the grace period of the policy before a point in time, and returns how
+// many were erased. A customer whose erasure fails is left for the next
+// run, and the error is returned once the others are erased.
+func (p *PrivacyService) EraseDue(ctx context.Context, at time.Time) (int64, error) {
+	deletedBefore := at.Add(-p.policy.ErasureGracePeriod)
+	var erased int64
+	var errs []error
+	for {
+		customers, err := p.customers.ListErasable(ctx, deletedBefore, p.policy.ErasureBatch)
+		if err != nil {
+			return erased, errors.Wrap(err, "failed to list erasable customers")
+		}
+		failed := 0
+		for _, c := range customers {
+			err = p.erase(ctx, c.ID)
+			if err != nil {
+				failed++
+				errs = append(errs, err)
+				continue
+			}
+			erased++
+		}
+		// Failed customers are listed again, so stop once a batch makes
+		// no progress.
+		if len(customers) < p.policy.ErasureBatch || failed == len(customers) {
+			return erased, errors.Join(errs...)
+		}
+	}
+}
+
+// erase anonymizes the orders of a customer, then their account, inside one
+// transaction. The account is erased last, so that without transactions a
+// failure still leaves the customer to be erased again by the next run.
+func (p *PrivacyService) erase(ctx context.Context, customerID string) error {
+	action := domain.AuditActionCustomerErase
+	err := p.inTx(ctx, func(ctx context.Context) error {
+		err := p.orders.AnonymizeByCustomer(ctx, customerID, domain.ErasedEmail(customerID))
+		if err != nil {
+			return errors.Wrap(err, "failed to anonymize orders")
+		}
+		return errors.Wrap(p.customers.Erase(ctx, customerID), "failed to erase customer")
+	})
+	if err != nil {
+		p.audit.Record(ctx, domain.NewAuditEvent(action, domain.AuditOutcomeFailure, customerID, "repository_error"))
+		return err
+	}
+	p.audit.Record(ctx, domain.NewAuditEvent(action, domain.AuditOutcomeSuccess, customerID, ""))
+	p.logger.Info("erased customer", "customer_id", customerID)
+	return nil
+}
+
+func (p *PrivacyService) record(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	outcome domain.AuditOutcome,
+	customerID, reason string,
+) {
+	p.audit.Record(ctx, domain.NewActorAuditEvent(action, outcome, actorOf(principal), customerID, reason))
+}
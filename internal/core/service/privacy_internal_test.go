@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+// newPrivacyService creates a PrivacyService over the repositories of the
+// fixture, with a base of its own that uses tx if it is not nil.
+func newPrivacyService(f adminFixture, tx transactor) *PrivacyService {
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	if tx != nil {
+		base.UseTransactions(tx)
+	}
+	privacy := NewPrivacyService(
+		base, f.customers, f.orders, f.downloads, f.sessions, f.authz, DefaultPrivacyPolicy(),
+	)
+	privacy.audit = f.audit
+	return privacy
+}
+
+func TestPrivacyService_Export(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	privacy := newPrivacyService(f, nil)
+	buyer, err := f.authz.Principal(ctx, f.buyer.Session)
+	assert.NoError(t, err)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	other := f.principal(t, "other@brokedaear.com")
+
+	tests := []struct {
+		test.CaseBase
+		principal  *domain.Principal
+		customerID string
+	}{
+		{CaseBase: test.NewCaseBase("own data", nil, false), principal: buyer, customerID: f.buyer.Customer.ID},
+		{CaseBase: test.NewCaseBase("support", nil, false), principal: support, customerID: f.buyer.Customer.ID},
+		{
+			CaseBase:   test.NewCaseBase("someone else's data", domain.ErrForbidden, true),
+			principal:  other,
+			customerID: f.buyer.Customer.ID,
+		},
+		{
+			CaseBase:   test.NewCaseBase("unknown customer", domain.ErrCustomerNotFound, true),
+			principal:  support,
+			customerID: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				export, err := privacy.Export(ctx, tt.principal, tt.customerID)
+				assert.Equal(t, f.audit.last().Action, domain.AuditActionCustomerExport)
+				assert.Equal(t, f.audit.last().ActorID, tt.principal.CustomerID)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeFailure)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, export.Profile.Email, testEmail)
+				assert.True(t, export.Profile.HasPassword)
+				assert.True(t, len(export.Sessions) > 0)
+				assert.Equal(t, len(export.Orders), 1)
+				assert.Equal(t, export.Orders[0].BillingEmail, "billing@brokedaear.com")
+				assert.Equal(t, export.Orders[0].Items[0].ProductName, "Reverb Pack")
+				assert.Equal(t, len(export.Downloads), 1)
+
+				archive, err := json.Marshal(export)
+				assert.NoError(t, err)
+				assert.False(t, strings.Contains(string(archive), "hash"))
+			},
+		)
+	}
+}
+
+func TestPrivacyService_EraseDue(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	privacy := newPrivacyService(f, nil)
+	admin := f.principal(t, "admin@brokedaear.com", domain.RoleAdmin)
+	customerID := f.buyer.Customer.ID
+	customer, err := f.customers.GetByID(ctx, customerID)
+	assert.NoError(t, err)
+	assert.NoError(t, f.customers.Delete(ctx, customer))
+
+	// Deleted accounts can be restored during the grace period.
+	now := time.Now().UTC()
+	n, err := privacy.EraseDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(0))
+
+	n, err = privacy.EraseDue(ctx, now.Add(DefaultPrivacyPolicy().ErasureGracePeriod+time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(1))
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionCustomerErase)
+	assert.Equal(t, f.audit.last().CustomerID, customerID)
+
+	erased, err := f.customers.GetByIDIncludingDeleted(ctx, customerID)
+	assert.NoError(t, err)
+	assert.Equal(t, erased.Email, domain.ErasedEmail(customerID))
+	assert.False(t, erased.HasPassword())
+	assert.True(t, erased.ErasedAt != nil)
+
+	// Orders are kept for accounting, without personal data.
+	order, err := f.orders.GetByID(ctx, f.order.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, order.BillingEmail, domain.ErasedEmail(customerID))
+	assert.Equal(t, order.BillingName, "")
+	assert.Equal(t, order.GrandTotal, 4200)
+
+	_, err = f.admin.RestoreCustomer(ctx, admin, customerID)
+	assert.Error(t, err, domain.ErrCustomerNotFound)
+	_, err = privacy.Export(ctx, admin, customerID)
+	assert.Error(t, err, domain.ErrCustomerNotFound)
+
+	// Erased customers are not erased again.
+	reaper := NewReaper(
+		NewServiceBase(test.NewMockLogger(), nil), "customer erasures", ExpiredDeleterFunc(privacy.EraseDue), time.Hour,
+	)
+	n, err = reaper.Reap(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(0))
+}
+
+// fakeTransactor runs functions as a transaction that either commits, or
+// fails to begin and so leaves the repositories untouched.
+type fakeTransactor struct {
+	calls int
+	err   error
+}
+
+func (f *fakeTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	f.calls++
+	if f.err != nil {
+		return f.err
+	}
+	return fn(ctx)
+}
+
+func TestPrivacyService_EraseDueInTransaction(t *testing.T) {
+	errBegin := errors.New("failed to begin transaction")
+	tests := []struct {
+		test.CaseBase
+		err error
+	}{
+		{CaseBase: test.NewCaseBase("committed", int64(1), false), err: nil},
+		{CaseBase: test.NewCaseBase("failed", errBegin, true), err: errBegin},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				ctx := context.Background()
+				f := newAdminFixture(t)
+				tx := &fakeTransactor{calls: 0, err: tt.err}
+				privacy := newPrivacyService(f, tx)
+				customerID := f.buyer.Customer.ID
+				customer, err := f.customers.GetByID(ctx, customerID)
+				assert.NoError(t, err)
+				assert.NoError(t, f.customers.Delete(ctx, customer))
+
+				n, err := privacy.EraseDue(ctx, time.Now().Add(DefaultPrivacyPolicy().ErasureGracePeriod+time.Minute))
+				assert.Equal(t, tx.calls, 1)
+				assert.Equal(t, f.audit.last().Action, domain.AuditActionCustomerErase)
+				erased, getErr := f.customers.GetByIDIncludingDeleted(ctx, customerID)
+				assert.NoError(t, getErr)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Equal(t, n, int64(0))
+					assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeFailure)
+					assert.True(t, erased.ErasedAt == nil)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, n, tt.Want.(int64))
+				assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+				assert.True(t, erased.ErasedAt != nil)
+			},
+		)
+	}
+}
@@ -5,51 +5,249 @@
 package service
 
 import (
+	"container/list"
 	"context"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
 	"go.brokedaear.com/internal/core/server"
-	"go.brokedaear.com/pkg/collections"
 	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 )
 
 // PwnChecker checks if a password has been pwned.
 type PwnChecker[T any] interface {
-	Check(password string) (bool, error)
+	Check(ctx context.Context, password string) (bool, error)
 }
 
+// ErrPwnCheckUnavailable is returned when no pwn checker could check a
+// password.
+var ErrPwnCheckUnavailable = errors.New("pwn check unavailable")
+
+// PwnCheckPolicy configures how the Pwned Passwords API is asked.
+type PwnCheckPolicy struct {
+	// RangeURL is the URL of the range endpoint. The first five characters
+	// of the SHA-1 hash of the password are appended to it.
+	RangeURL string
+	// Timeout bounds a request when the caller's deadline is later.
+	Timeout time.Duration
+	// CacheSize is how many ranges are cached.
+	CacheSize int
+	// CacheTTL is how long a cached range is used for.
+	CacheTTL time.Duration
+	// FailureThreshold is how many requests in a row must fail before the
+	// API is no longer asked.
+	FailureThreshold int
+	// OpenDuration is how long the API is not asked after it failed, before
+	// it is tried again.
+	OpenDuration time.Duration
+}
+
+// DefaultPwnCheckPolicy returns a policy that waits two seconds for the API,
+// caches ranges for six hours, and stops asking for half a minute after five
+// failures in a row.
+func DefaultPwnCheckPolicy() PwnCheckPolicy {
+	return PwnCheckPolicy{
+		RangeURL:         "https://api.pwnedpasswords.com/range/",
+		Timeout:          2 * time.Second,
+		CacheSize:        4096,
+		CacheTTL:         6 * time.Hour,
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	}
+}
+
+// NewOnlinePwnChecker returns a PwnChecker that asks the Pwned Passwords API.
+// Metrics are recorded if the service base has telemetry.
+func NewOnlinePwnChecker(svcBase *ServiceBase, policy PwnCheckPolicy) (PwnChecker[[]string], error) {
+	metrics, err := newPwnMetrics(svcBase.tel)
+	if err != nil {
+		return nil, err
+	}
+	return &pwnCheckOnline[[]string]{
+		checker: server.NewHTTPRequestClient(
+			svcBase.logger,
+			svcBase.tel,
+			stringSliceParser[[]string]{},
+		),
+		policy:  policy,
+		cache:   newRangeCache(policy.CacheSize, policy.CacheTTL),
+		breaker: newCircuitBreaker(policy.FailureThreshold, policy.OpenDuration),
+		metrics: metrics,
+	}, nil
+}
+
+// PwnCheckFailureMode is what happens to a new password when it cannot be
+// checked at all.
+type PwnCheckFailureMode uint8
+
+const (
+	// PwnCheckFailClosed rejects the password, so that a pwned password is
+	// never accepted.
+	PwnCheckFailClosed PwnCheckFailureMode = iota
+	// PwnCheckFailOpen accepts the password, so that customers can still
+	// sign up.
+	PwnCheckFailOpen
+)
+
+// DefaultPwnCheckFailureMode returns the failure mode of an environment.
+// Production and staging fail closed. Development fails open, so that it
+// works offline without a dataset.
+func DefaultPwnCheckFailureMode(env domain.Environment) PwnCheckFailureMode {
+	if env == domain.EnvDevelopment {
+		return PwnCheckFailOpen
+	}
+	return PwnCheckFailClosed
+}
+
+// pwnCheckChain asks its checkers in order, and moves on to the next one
+// when a checker fails, such as from the API to a local dataset.
+type pwnCheckChain struct {
+	*ServiceBase
+	checkers []PwnChecker[[]string]
+	mode     PwnCheckFailureMode
+}
+
+// NewPwnCheckChain returns a PwnChecker that tries each checker in order
+// until one of them succeeds. If every checker fails, the failure mode
+// decides whether the password is accepted.
+func NewPwnCheckChain(
+	svcBase *ServiceBase,
+	mode PwnCheckFailureMode,
+	checkers ...PwnChecker[[]string],
+) PwnChecker[[]string] {
+	return pwnCheckChain{ServiceBase: svcBase, checkers: checkers, mode: mode}
+}
+
+func (p pwnCheckChain) Check(ctx context.Context, password string) (bool, error) {
+	var errs []error
+	for i, checker := range p.checkers {
+		pwned, err := checker.Check(ctx, password)
+		if err == nil {
+			return pwned, nil
+		}
+		// The caller gave up, so there is nobody to fall back for.
+		if ctx.Err() != nil {
+			return false, errors.Join(ErrPwnCheckUnavailable, ctx.Err())
+		}
+		p.logger.Warn("pwn checker failed", "checker", i, "error", err)
+		errs = append(errs, err)
+	}
+
+	if p.mode == PwnCheckFailOpen {
+		p.logger.Warn("no pwn checker available, accepting password")
+		return false, nil
+	}
+	return false, errors.Join(append([]error{ErrPwnCheckUnavailable}, errs...)...)
+}
+
+// pwnPrefixLength is the number of characters of the hash sent to the API.
+const pwnPrefixLength = 5
+
 type pwnCheckOnline[T any] struct {
 	checker server.HTTPClient[[]string]
+	policy  PwnCheckPolicy
+	cache   *rangeCache
+	breaker *circuitBreaker
+	metrics *pwnMetrics
 }
 
-func (p pwnCheckOnline[T]) Check(password string) (bool, error) {
+func (p *pwnCheckOnline[T]) Check(ctx context.Context, password string) (bool, error) {
 	pwnHash, err := crypto.PwnHash([]byte(password))
 	if err != nil {
 		return false, err
 	}
-	target := pwnHash[:5]
-	pwnURL := "https://api.pwnedpasswords.com/range/" + target
-	// Add request padding in the response. This adds 80% more bytes to the
-	// response, So I don't think this is necessarily needed. However, I
-	// don't think our scale will be monstrous, so this may be fine in terms
-	// of ingress/egress stuff. I think its fine...
+	pwnHash = strings.ToUpper(pwnHash)
+	prefix, suffix := pwnHash[:pwnPrefixLength], pwnHash[pwnPrefixLength:]
+
+	suffixes, ok := p.cache.get(prefix)
+	if ok {
+		p.metrics.hits.Add(ctx, 1)
+		_, pwned := suffixes[suffix]
+		return pwned, nil
+	}
+	p.metrics.misses.Add(ctx, 1)
+
+	suffixes, err = p.fetch(ctx, prefix)
+	if err != nil {
+		return false, err
+	}
+	p.cache.put(prefix, suffixes)
+	_, pwned := suffixes[suffix]
+	return pwned, nil
+}
+
+// fetch asks the API for the suffixes of the hashes that start with a prefix.
+func (p *pwnCheckOnline[T]) fetch(ctx context.Context, prefix string) (map[string]struct{}, error) {
+	err := p.breaker.allow()
+	if err != nil {
+		p.metrics.failures.Add(ctx, 1)
+		return nil, err
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, p.policy.Timeout)
+	defer cancel()
+
+	// Padding makes every response about the same size, so that the prefix
+	// cannot be told from the size of the response. Padding entries have a
+	// count of 0.
 	//
 	// See:
 	// https://www.troyhunt.com/enhancing-pwned-passwords-privacy-with-padding/
-	// TODO: Context.
-	headers := make(map[string]string)
-	headers["Add-Padding"] = "true"
-	leakedHashes, err := p.checker.Get(context.TODO(), pwnURL, headers)
-	if err != nil {
-		return false, err
+	headers := map[string]string{"Add-Padding": "true"}
+	start := time.Now()
+	lines, err := p.checker.Get(reqCtx, p.policy.RangeURL+prefix, headers)
+	p.metrics.duration.Record(ctx, time.Since(start).Milliseconds())
+	if err == nil {
+		var suffixes map[string]struct{}
+		suffixes, err = parseRange(lines)
+		if err == nil {
+			p.breaker.success()
+			p.metrics.recordCircuit(ctx, p.breaker)
+			return suffixes, nil
+		}
+	}
+
+	if ctx.Err() != nil {
+		// The caller gave up, which says nothing about the API.
+		p.breaker.release()
+		return nil, errors.Wrap(ctx.Err(), "pwn check cancelled")
+	}
+	p.breaker.failure()
+	p.metrics.failures.Add(ctx, 1)
+	p.metrics.recordCircuit(ctx, p.breaker)
+	return nil, errors.Wrap(err, "failed to get pwned password range")
+}
+
+// parseRange parses a range response, which has a `SUFFIX:COUNT` line for
+// every hash that starts with the prefix. Padding entries, with a count of
+// 0, are left out.
+func parseRange(lines []string) (map[string]struct{}, error) {
+	suffixes := make(map[string]struct{}, len(lines))
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		suffix, rawCount, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, errors.New("malformed range line")
+		}
+		count, err := strconv.Atoi(rawCount)
+		if err != nil {
+			return nil, errors.New("malformed range count")
+		}
+		if count > 0 {
+			suffixes[strings.ToUpper(suffix)] = struct{}{}
+		}
 	}
-	leakedHashes = collections.Filter(leakedHashes, func(target, item string) bool {
-		return target == item[:5]
-	}, target)
-	_, ok := collections.Find(leakedHashes, func(a1, a2 string) bool {
-		return a1 == a2
-	}, pwnHash)
-	return ok, nil
+	return suffixes, nil
 }
 
 type stringSliceParser[T []string] struct{}
@@ -59,3 +257,127 @@ func (s stringSliceParser[T]) Parse(body []byte) (T, error) {
 	v := strings.Split(string(body), "\n")
 	return v, nil
 }
+
+// rangeCache is a least recently used cache of range responses, keyed by
+// prefix. Entries expire after a while, since the dataset grows.
+type rangeCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List
+	entries map[string]*list.Element
+	now     func() time.Time
+}
+
+type rangeCacheEntry struct {
+	prefix    string
+	suffixes  map[string]struct{}
+	expiresAt time.Time
+}
+
+func newRangeCache(size int, ttl time.Duration) *rangeCache {
+	return &rangeCache{
+		mu:      sync.Mutex{},
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		now:     time.Now,
+	}
+}
+
+func (c *rangeCache) get(prefix string) (map[string]struct{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[prefix]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*rangeCacheEntry) //nolint:errcheck // Only entries are stored.
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, prefix)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.suffixes, true
+}
+
+func (c *rangeCache) put(prefix string, suffixes map[string]struct{}) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &rangeCacheEntry{prefix: prefix, suffixes: suffixes, expiresAt: c.now().Add(c.ttl)}
+	if el, ok := c.entries[prefix]; ok {
+		el.Value = entry
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[prefix] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*rangeCacheEntry).prefix) //nolint:errcheck // Only entries are stored.
+	}
+}
+
+func (c *rangeCache) length() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// pwnMetrics records pwned password check metrics.
+type pwnMetrics struct {
+	hits     otelmetric.Int64UpDownCounter
+	misses   otelmetric.Int64UpDownCounter
+	failures otelmetric.Int64UpDownCounter
+	duration otelmetric.Int64Histogram
+	circuit  otelmetric.Int64Gauge
+}
+
+// newPwnMetrics creates the pwned password check instruments. Without
+// instruments, metrics are discarded.
+func newPwnMetrics(tel telemetry.Instruments) (*pwnMetrics, error) {
+	if tel == nil {
+		meter := noop.NewMeterProvider().Meter("")
+		hits, _ := meter.Int64UpDownCounter("")
+		misses, _ := meter.Int64UpDownCounter("")
+		failures, _ := meter.Int64UpDownCounter("")
+		duration, _ := meter.Int64Histogram("")
+		circuit, _ := meter.Int64Gauge("")
+		return &pwnMetrics{hits: hits, misses: misses, failures: failures, duration: duration, circuit: circuit}, nil
+	}
+
+	hits, err := tel.UpDownCounter(telemetry.MetricPwnCacheHitsTotal)
+	if err != nil {
+		return nil, err
+	}
+	misses, err := tel.UpDownCounter(telemetry.MetricPwnCacheMissesTotal)
+	if err != nil {
+		return nil, err
+	}
+	failures, err := tel.UpDownCounter(telemetry.MetricPwnAPIFailuresTotal)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := tel.Histogram(telemetry.MetricPwnAPIDurationMillis)
+	if err != nil {
+		return nil, err
+	}
+	circuit, err := tel.Gauge(telemetry.MetricPwnCircuitOpen)
+	if err != nil {
+		return nil, err
+	}
+	return &pwnMetrics{hits: hits, misses: misses, failures: failures, duration: duration, circuit: circuit}, nil
+}
+
+func (m *pwnMetrics) recordCircuit(ctx context.Context, breaker *circuitBreaker) {
+	var open int64
+	if breaker.open() {
+		open = 1
+	}
+	m.circuit.Record(ctx, open)
+}
@@ -0,0 +1,306 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"crypto/sha1" //nolint:gosec // The dataset is keyed by SHA-1.
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/pwned"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+var errAPIDown = errors.New("api.pwnedpasswords.com unreachable")
+
+// newTestDataset builds an offline dataset that only knows pwnedTestPassword.
+func newTestDataset(t *testing.T) *pwned.Dataset {
+	t.Helper()
+	sum := sha1.Sum([]byte(pwnedTestPassword)) //nolint:gosec // The dataset is keyed by SHA-1.
+	raw := strings.ToUpper(hex.EncodeToString(sum[:])) + ":42\n"
+
+	path := filepath.Join(t.TempDir(), "pwned.bin")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	_, err = pwned.Import(strings.NewReader(raw), f, pwned.ImportOptions{SuffixLength: 0, MinCount: 0})
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	d, err := pwned.Open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+func TestPwnCheckChain_Check(t *testing.T) {
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	down := fakePwnChecker{pwned: nil, err: errAPIDown}
+	online := fakePwnChecker{pwned: map[string]bool{pwnedTestPassword: true}, err: nil}
+
+	tests := []struct {
+		test.CaseBase
+		mode     PwnCheckFailureMode
+		checkers []PwnChecker[[]string]
+	}{
+		{
+			CaseBase: test.NewCaseBase("online", true, false),
+			mode:     PwnCheckFailClosed,
+			checkers: []PwnChecker[[]string]{online, down},
+		},
+		{
+			CaseBase: test.NewCaseBase("falls back to offline", true, false),
+			mode:     PwnCheckFailClosed,
+			checkers: []PwnChecker[[]string]{down, newTestDataset(t)},
+		},
+		{
+			CaseBase: test.NewCaseBase("fails closed", false, true),
+			mode:     PwnCheckFailClosed,
+			checkers: []PwnChecker[[]string]{down, down},
+		},
+		{
+			CaseBase: test.NewCaseBase("fails open", false, false),
+			mode:     PwnCheckFailOpen,
+			checkers: []PwnChecker[[]string]{down, down},
+		},
+		{
+			CaseBase: test.NewCaseBase("no checkers", false, true),
+			mode:     PwnCheckFailClosed,
+			checkers: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				chain := NewPwnCheckChain(base, tt.mode, tt.checkers...)
+				pwned, err := chain.Check(context.Background(), pwnedTestPassword)
+				assert.ErrorAndWant(t, err, tt.WantErr)
+				assert.Equal(t, pwned, tt.Want.(bool))
+				if tt.WantErr {
+					assert.Error(t, err, ErrPwnCheckUnavailable)
+				}
+			},
+		)
+	}
+}
+
+func TestAuthService_SignUpWithOfflinePwnCheck(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	down := fakePwnChecker{pwned: nil, err: errAPIDown}
+
+	f.auth.pwnChecker = NewPwnCheckChain(f.auth.ServiceBase, PwnCheckFailClosed, down, newTestDataset(t))
+	_, err := f.auth.SignUp(ctx, testEmail, pwnedTestPassword, domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerPasswordFailed)
+	_, err = f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	f.auth.pwnChecker = NewPwnCheckChain(f.auth.ServiceBase, PwnCheckFailClosed, down)
+	_, err = f.auth.SignUp(ctx, "mallory@brokedaear.com", testPassword, domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerSignUpFailed)
+	assert.Equal(t, f.audit.last().Reason, "pwn_check_failed")
+}
+
+// rangeServer is a fake range endpoint of the Pwned Passwords API. It knows
+// the hashes of the passwords it is given, and pads every response with
+// entries that have a count of 0.
+type rangeServer struct {
+	*httptest.Server
+	requests atomic.Int32
+	status   atomic.Int32
+	delay    time.Duration
+}
+
+func newRangeServer(t *testing.T, pwnedPasswords ...string) *rangeServer {
+	t.Helper()
+	ranges := make(map[string][]string)
+	for _, password := range pwnedPasswords {
+		sum := sha1.Sum([]byte(password)) //nolint:gosec // The API is keyed by SHA-1.
+		hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+		ranges[hash[:5]] = append(ranges[hash[:5]], hash[5:]+":"+strconv.Itoa(len(password)))
+	}
+
+	s := &rangeServer{}
+	s.status.Store(http.StatusOK)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		if s.delay > 0 {
+			select {
+			case <-time.After(s.delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		status := int(s.status.Load())
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		prefix := strings.TrimPrefix(r.URL.Path, "/range/")
+		lines := append([]string(nil), ranges[prefix]...)
+		for i := range 3 {
+			lines = append(lines, fmt.Sprintf("%035X:0", i))
+		}
+		_, _ = w.Write([]byte(strings.Join(lines, "\r\n")))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func pwnPrefix(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := crypto.PwnHash([]byte(password))
+	assert.NoError(t, err)
+	return strings.ToUpper(hash[:pwnPrefixLength])
+}
+
+func newTestOnlineChecker(t *testing.T, srv *rangeServer) (*pwnCheckOnline[[]string], *fakeInstruments) {
+	t.Helper()
+	policy := DefaultPwnCheckPolicy()
+	policy.RangeURL = srv.URL + "/range/"
+	policy.Timeout = time.Second
+	policy.CacheSize = 2
+	policy.FailureThreshold = 2
+	checker, err := NewOnlinePwnChecker(NewServiceBase(test.NewMockLogger(), nil), policy)
+	assert.NoError(t, err)
+	online := checker.(*pwnCheckOnline[[]string]) //nolint:errcheck // It is always online.
+
+	metrics := newFakeInstruments()
+	online.metrics, err = newPwnMetrics(metrics)
+	assert.NoError(t, err)
+	return online, metrics
+}
+
+func TestPwnCheckOnline_Check(t *testing.T) {
+	ctx := context.Background()
+	srv := newRangeServer(t, pwnedTestPassword)
+	p, _ := newTestOnlineChecker(t, srv)
+
+	tests := []struct {
+		test.CaseBase
+		password string
+	}{
+		{CaseBase: test.NewCaseBase("pwned", true, false), password: pwnedTestPassword},
+		{CaseBase: test.NewCaseBase("not pwned", false, false), password: testPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				pwned, err := p.Check(ctx, tt.password)
+				assert.ErrorAndWant(t, err, tt.WantErr)
+				assert.Equal(t, pwned, tt.Want.(bool))
+			},
+		)
+	}
+}
+
+func TestParseRange_IgnoresPadding(t *testing.T) {
+	suffixes, err := parseRange([]string{
+		"0018A45C4D1DEF81644B54AB7F969B88D65:10\r",
+		"00D4F6E8FA6EECAD2A3AA415EEC418D38EC:0\r",
+		"",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, len(suffixes), 1)
+	_, ok := suffixes["0018A45C4D1DEF81644B54AB7F969B88D65"]
+	assert.True(t, ok)
+
+	_, err = parseRange([]string{"<html>Service Unavailable</html>"})
+	assert.ErrorAndWant(t, err, true)
+}
+
+func TestPwnCheckOnline_Caches(t *testing.T) {
+	ctx := context.Background()
+	srv := newRangeServer(t, pwnedTestPassword)
+	p, metrics := newTestOnlineChecker(t, srv)
+
+	for range 3 {
+		pwned, err := p.Check(ctx, pwnedTestPassword)
+		assert.NoError(t, err)
+		assert.True(t, pwned)
+	}
+	assert.Equal(t, srv.requests.Load(), int32(1))
+	assert.Equal(t, metrics.sum(t, telemetry.MetricPwnCacheHitsTotal), int64(2))
+	assert.Equal(t, metrics.sum(t, telemetry.MetricPwnCacheMissesTotal), int64(1))
+
+	// Expired ranges are fetched again.
+	p.cache.now = func() time.Time { return time.Now().Add(p.policy.CacheTTL) }
+	_, err := p.Check(ctx, pwnedTestPassword)
+	assert.NoError(t, err)
+	assert.Equal(t, srv.requests.Load(), int32(2))
+
+	// The least recently used range is evicted.
+	for _, password := range []string{"first password", "second password", "third password"} {
+		_, err = p.Check(ctx, password)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, p.cache.length(), p.policy.CacheSize)
+	_, ok := p.cache.get(pwnPrefix(t, pwnedTestPassword))
+	assert.False(t, ok)
+}
+
+func TestPwnCheckOnline_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	srv := newRangeServer(t, pwnedTestPassword)
+	p, metrics := newTestOnlineChecker(t, srv)
+	srv.status.Store(http.StatusServiceUnavailable)
+
+	for range p.policy.FailureThreshold {
+		_, err := p.Check(ctx, pwnedTestPassword)
+		assert.ErrorAndWant(t, err, true)
+	}
+	assert.Equal(t, srv.requests.Load(), int32(p.policy.FailureThreshold))
+
+	// The open circuit fails fast, without asking the API.
+	_, err := p.Check(ctx, pwnedTestPassword)
+	assert.Error(t, err, ErrCircuitOpen)
+	assert.Equal(t, srv.requests.Load(), int32(p.policy.FailureThreshold))
+	assert.Equal(t, metrics.sum(t, telemetry.MetricPwnAPIFailuresTotal), int64(p.policy.FailureThreshold+1))
+
+	// Once the circuit has been open long enough, a trial request is let
+	// through, and closes it if it succeeds.
+	srv.status.Store(http.StatusOK)
+	p.breaker.now = func() time.Time { return time.Now().Add(p.policy.OpenDuration) }
+	pwned, err := p.Check(ctx, pwnedTestPassword)
+	assert.NoError(t, err)
+	assert.True(t, pwned)
+	assert.False(t, p.breaker.open())
+}
+
+func TestPwnCheckOnline_Deadlines(t *testing.T) {
+	srv := newRangeServer(t, pwnedTestPassword)
+	p, _ := newTestOnlineChecker(t, srv)
+	srv.delay = time.Minute
+
+	// The caller's deadline is honoured, and giving up is not held against
+	// the API.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := p.Check(ctx, pwnedTestPassword)
+	assert.Error(t, err, context.DeadlineExceeded)
+	assert.Equal(t, p.breaker.failures, 0)
+
+	// A slow API times out on its own, and is held against it.
+	p.policy.Timeout = 50 * time.Millisecond
+	_, err = p.Check(context.Background(), pwnedTestPassword)
+	assert.ErrorAndWant(t, err, true)
+	assert.Equal(t, p.breaker.failures, 1)
+}
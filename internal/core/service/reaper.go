@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// expiredDeleter deletes records that expired before a point in time, and
+// returns how many were deleted.
+type expiredDeleter interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ExpiredDeleterFunc adapts a function to a repository a Reaper can reap,
+// such as a service method that finalizes expired records.
+type ExpiredDeleterFunc func(ctx context.Context, before time.Time) (int64, error)
+
+// DeleteExpired calls f.
+func (f ExpiredDeleterFunc) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return f(ctx, before)
+}
+
+// Reaper periodically deletes expired records, such as sessions, from a
+// repository in the background. Reaper implements io.Closer, so it can take
+// part in a global teardown.
+type Reaper struct {
+	*ServiceBase
+	name     string
+	repo     expiredDeleter
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NewReaper creates a new Reaper that reaps a repository every interval. The
+// name identifies the reaped records in logs.
+func NewReaper(
+	svcBase *ServiceBase,
+	name string,
+	repo expiredDeleter,
+	interval time.Duration,
+) *Reaper {
+	return &Reaper{
+		ServiceBase: svcBase,
+		name:        name,
+		repo:        repo,
+		interval:    interval,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		once:        sync.Once{},
+	}
+}
+
+// Start starts reaping in the background until the context is cancelled or
+// the reaper is closed.
+func (r *Reaper) Start(ctx context.Context) {
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				_, _ = r.Reap(ctx)
+			}
+		}
+	}()
+}
+
+// Reap deletes every record that has expired by now.
+func (r *Reaper) Reap(ctx context.Context) (int64, error) {
+	n, err := r.repo.DeleteExpired(ctx, time.Now().UTC())
+	if err != nil {
+		r.logger.Error("failed to reap expired records", "records", r.name, "error", err)
+		return n, err
+	}
+	if n > 0 {
+		r.logger.Info("reaped expired records", "records", r.name, "count", n)
+	}
+	return n, nil
+}
+
+// Close stops the reaper and waits for it to finish. Close must only be
+// called after Start.
+func (r *Reaper) Close() error {
+	r.once.Do(func() {
+		close(r.stop)
+	})
+	<-r.done
+	return nil
+}
+
+// purger hard deletes records that were soft deleted before a point in
+// time, and returns how many were purged.
+type purger interface {
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// RetentionPurger hard deletes soft deleted records once they were kept for
+// a retention period. It can be reaped by a Reaper:
+//
+//	NewReaper(base, "deleted customers", NewRetentionPurger(customers, 90*24*time.Hour), time.Hour)
+type RetentionPurger struct {
+	repo      purger
+	retention time.Duration
+}
+
+// NewRetentionPurger creates a new RetentionPurger that purges records
+// deleted longer than retention ago.
+func NewRetentionPurger(repo purger, retention time.Duration) *RetentionPurger {
+	return &RetentionPurger{repo: repo, retention: retention}
+}
+
+// DeleteExpired purges the records whose retention is over at a point in
+// time.
+func (r *RetentionPurger) DeleteExpired(ctx context.Context, at time.Time) (int64, error) {
+	return r.repo.Purge(ctx, at.Add(-r.retention))
+}
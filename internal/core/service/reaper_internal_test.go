@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+// fakePurger records the cutoff of the last purge.
+type fakePurger struct {
+	deletedBefore time.Time
+}
+
+func (f *fakePurger) Purge(_ context.Context, deletedBefore time.Time) (int64, error) {
+	f.deletedBefore = deletedBefore
+	return 3, nil
+}
+
+func TestRetentionPurger(t *testing.T) {
+	repo := &fakePurger{}
+	retention := 90 * 24 * time.Hour
+	reaper := NewReaper(
+		NewServiceBase(test.NewMockLogger(), nil), "deleted customers", NewRetentionPurger(repo, retention), time.Hour,
+	)
+
+	before := time.Now().UTC()
+	n, err := reaper.Reap(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, n, int64(3))
+	assert.False(t, repo.deletedBefore.Before(before.Add(-retention)))
+	assert.True(t, repo.deletedBefore.Before(time.Now().UTC().Add(-retention+time.Second)))
+}
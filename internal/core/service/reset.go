@@ -0,0 +1,223 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// PasswordResetPolicy configures password resets.
+type PasswordResetPolicy struct {
+	// TokenTTL is how long a reset link stays valid.
+	TokenTTL time.Duration
+	// RequestInterval is how long a customer must wait before asking for
+	// another reset email.
+	RequestInterval time.Duration
+	// MaxPerDay is how many reset emails a customer can be sent in a day.
+	MaxPerDay int
+	// LinkURL is the URL of the frontend page where the customer chooses a
+	// new password. The token is appended as the `token` query parameter.
+	LinkURL string
+}
+
+// DefaultPasswordResetPolicy returns a policy where reset links last half an
+// hour, and a customer can ask for one a minute, five a day.
+func DefaultPasswordResetPolicy(linkURL string) PasswordResetPolicy {
+	return PasswordResetPolicy{
+		TokenTTL:        30 * time.Minute,
+		RequestInterval: time.Minute,
+		MaxPerDay:       5,
+		LinkURL:         linkURL,
+	}
+}
+
+// PasswordResetService lets customers who forgot their password choose a new
+// one, by proving that they can read email sent to their address.
+type PasswordResetService struct {
+	*ServiceBase
+	auth      *AuthService
+	customers customerRepository
+	tokens    tokenRepository
+	mailer    Mailer
+	policy    PasswordResetPolicy
+	audit     auditRecorder
+}
+
+// NewPasswordResetService creates a new PasswordResetService. New passwords
+// are checked like the passwords of new customers of auth.
+func NewPasswordResetService(
+	svcBase *ServiceBase,
+	auth *AuthService,
+	customers customerRepository,
+	tokens tokenRepository,
+	mailer Mailer,
+	policy PasswordResetPolicy,
+) *PasswordResetService {
+	return &PasswordResetService{
+		ServiceBase: svcBase,
+		auth:        auth,
+		customers:   customers,
+		tokens:      tokens,
+		mailer:      mailer,
+		policy:      policy,
+		audit:       svcBase.auditRecorder(),
+	}
+}
+
+// Request sends a password reset email to the customer with an email address.
+//
+// Request never tells whether a customer has the address, so that it cannot
+// be used to find out who has an account. Unknown addresses, rate limited
+// customers and failures to send are only logged and audited.
+func (p *PasswordResetService) Request(ctx context.Context, email string) {
+	customerID, reason, err := p.request(ctx, email)
+	if err != nil {
+		if reason == "" {
+			p.logger.Error("failed to request password reset", "customer_id", customerID, "error", err)
+			reason = "request_failed"
+		}
+		p.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionPasswordResetRequest, domain.AuditOutcomeFailure, customerID, reason,
+		))
+		return
+	}
+	p.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionPasswordResetRequest, domain.AuditOutcomeSuccess, customerID, "",
+	))
+}
+
+// request sends a reset email. It returns the ID of the customer, if there is
+// one, and an audit reason if the request was refused.
+func (p *PasswordResetService) request(ctx context.Context, email string) (string, string, error) {
+	if email == "" {
+		return "", "missing_email", ErrEmailEmpty
+	}
+	customer, err := p.customers.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, domain.ErrCustomerNotFound) {
+			return "", "unknown_customer", err
+		}
+		return "", "", errors.Wrap(err, "failed to get customer")
+	}
+
+	err = checkTokenRate(
+		ctx, p.tokens, customer.ID, domain.TokenPurposePasswordReset,
+		p.policy.RequestInterval, p.policy.MaxPerDay,
+	)
+	if errors.Is(err, ErrTooManyRequests) {
+		return customer.ID, "rate_limited", err
+	}
+	if err != nil {
+		return customer.ID, "", err
+	}
+
+	// Only the latest reset link works.
+	err = p.tokens.RevokeByCustomer(ctx, customer.ID, domain.TokenPurposePasswordReset, time.Now().UTC())
+	if err != nil {
+		return customer.ID, "", errors.Wrap(err, "failed to revoke reset tokens")
+	}
+
+	token, err := domain.NewOneTimeToken(
+		customer.ID, domain.TokenPurposePasswordReset, customer.Email, p.policy.TokenTTL,
+	)
+	if err != nil {
+		return customer.ID, "", err
+	}
+	err = p.tokens.Insert(ctx, token)
+	if err != nil {
+		return customer.ID, "", errors.Wrap(err, "failed to store reset token")
+	}
+
+	link, err := tokenLink(p.policy.LinkURL, token)
+	if err != nil {
+		return customer.ID, "", err
+	}
+	err = p.mailer.Send(ctx, domain.EmailMessage{
+		To:       customer.Email,
+		Template: domain.EmailTemplatePasswordReset,
+		Data:     map[string]any{"Link": link, "ExpiresIn": p.policy.TokenTTL},
+	})
+	if err != nil {
+		return customer.ID, "", errors.Wrap(err, "failed to send reset email")
+	}
+	return customer.ID, "", nil
+}
+
+// Reset sets a new password for the customer a reset token was issued to.
+// The new password is checked like the password of a new customer. A
+// rejected password does not use up the token, so the customer can try
+// another.
+//
+// On success, every session of the customer is revoked, so that someone who
+// knew the old password is signed out, and the customer is told by email that
+// their password changed.
+func (p *PasswordResetService) Reset(ctx context.Context, rawToken, newPassword string) error {
+	fail := func(customerID, reason string, err error) error {
+		p.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionPasswordReset, domain.AuditOutcomeFailure, customerID, reason,
+		))
+		return err
+	}
+	failToken := func(customerID string, err error) error {
+		return fail(customerID, tokenFailureReason(err), publicTokenError(err))
+	}
+
+	now := time.Now().UTC()
+	token, err := findToken(ctx, p.tokens, rawToken, domain.TokenPurposePasswordReset, now)
+	if err != nil {
+		if isRepositoryError(err) {
+			p.logger.Error("failed to get reset token", "error", err)
+		}
+		return failToken(tokenUserID(token), err)
+	}
+
+	customer, err := p.customers.GetByID(ctx, token.UserID)
+	if err != nil {
+		return failToken(token.UserID, err)
+	}
+	if customer.Email != token.Email {
+		return failToken(customer.ID, errEmailChanged)
+	}
+
+	reason, err := p.auth.checkNewPassword(ctx, newPassword, customer.Email)
+	if err != nil {
+		return fail(customer.ID, reason, err)
+	}
+
+	err = p.tokens.MarkUsed(ctx, token.ID, now)
+	if err != nil {
+		return failToken(customer.ID, err)
+	}
+
+	// The repository hashes the password before storing it.
+	customer.PasswordHash = []byte(newPassword)
+	err = p.customers.UpdatePassword(ctx, customer)
+	if err != nil {
+		return fail(customer.ID, "repository_error", errors.Wrap(err, "failed to reset password"))
+	}
+
+	err = p.auth.sessions.RevokeAll(ctx, customer.ID)
+	if err != nil {
+		p.logger.Error("failed to revoke sessions after password reset", "customer_id", customer.ID, "error", err)
+	}
+
+	p.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionPasswordReset, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+
+	err = p.mailer.Send(ctx, domain.EmailMessage{
+		To:       customer.Email,
+		Template: domain.EmailTemplatePasswordChanged,
+		Data:     nil,
+	})
+	if err != nil {
+		p.logger.Error("failed to send password reset notice", "customer_id", customer.ID, "error", err)
+	}
+	return nil
+}
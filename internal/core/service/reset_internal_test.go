@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+type resetFixture struct {
+	authFixture
+	reset *PasswordResetService
+}
+
+func newResetFixture(t *testing.T) resetFixture {
+	t.Helper()
+	f := newAuthFixture(t)
+	reset := NewPasswordResetService(
+		NewServiceBase(test.NewMockLogger(), nil),
+		f.auth,
+		f.customers,
+		f.tokens,
+		f.mailer,
+		DefaultPasswordResetPolicy("https://brokedaear.com/reset"),
+	)
+	reset.audit = f.audit
+	return resetFixture{authFixture: f, reset: reset}
+}
+
+const newTestPassword = "a brand new passphrase"
+
+func TestPasswordResetService_Reset(t *testing.T) {
+	ctx := context.Background()
+	f := newResetFixture(t)
+
+	signedUp, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	signedIn, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	f.reset.Request(ctx, testEmail)
+	assert.Equal(t, f.mailer.last().To, testEmail)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionPasswordResetRequest)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	token := verificationToken(t, f.mailer)
+
+	// A rejected password does not use up the token.
+	err = f.reset.Reset(ctx, token, "short")
+	assert.Error(t, err, ErrPasswordTooShort)
+	err = f.reset.Reset(ctx, token, pwnedTestPassword)
+	assert.Error(t, err, ErrCustomerPasswordFailed)
+	assert.Equal(t, f.audit.last().Reason, "password_pwned")
+
+	sent := f.mailer.count()
+	err = f.reset.Reset(ctx, token, newTestPassword)
+	assert.NoError(t, err)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionPasswordReset)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+
+	// The customer is told, and every session is signed out.
+	assert.Equal(t, f.mailer.count(), sent+1)
+	assert.Equal(t, f.mailer.last().Template, domain.EmailTemplatePasswordChanged)
+	for _, session := range []*domain.UserSession{signedUp.Session, signedIn.Session} {
+		_, err = f.sessions.Validate(ctx, session.Token)
+		assert.Error(t, err, ErrInvalidSession)
+	}
+
+	_, err = f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.Error(t, err, ErrCustomerLoginFailed)
+	_, err = f.auth.SignIn(ctx, testEmail, newTestPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	// A token can only be used once.
+	err = f.reset.Reset(ctx, token, "yet another passphrase")
+	assert.Error(t, err, domain.ErrTokenUsed)
+}
+
+func TestPasswordResetService_RequestDoesNotEnumerate(t *testing.T) {
+	ctx := context.Background()
+	f := newResetFixture(t)
+
+	_, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	sent := f.mailer.count()
+
+	tests := []struct {
+		test.CaseBase
+		email string
+	}{
+		{
+			CaseBase: test.NewCaseBase("unknown customer", "unknown_customer", true),
+			email:    "nobody@brokedaear.com",
+		},
+		{
+			CaseBase: test.NewCaseBase("missing email", "missing_email", true),
+			email:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f.reset.Request(ctx, tt.email)
+				assert.Equal(t, f.mailer.count(), sent)
+				assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeFailure)
+				assert.Equal(t, f.audit.last().Reason, tt.Want.(string))
+			},
+		)
+	}
+}
+
+func TestPasswordResetService_RequestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	f := newResetFixture(t)
+
+	_, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	f.reset.Request(ctx, testEmail)
+	sent := f.mailer.count()
+	first := verificationToken(t, f.mailer)
+
+	f.reset.Request(ctx, testEmail)
+	assert.Equal(t, f.mailer.count(), sent)
+	assert.Equal(t, f.audit.last().Reason, "rate_limited")
+
+	// A newer link replaces the previous one.
+	f.reset.policy.RequestInterval = 0
+	f.reset.Request(ctx, testEmail)
+	assert.Equal(t, f.mailer.count(), sent+1)
+	err = f.reset.Reset(ctx, first, newTestPassword)
+	assert.Error(t, err, domain.ErrTokenUsed)
+	err = f.reset.Reset(ctx, verificationToken(t, f.mailer), newTestPassword)
+	assert.NoError(t, err)
+}
+
+func TestPasswordResetService_ResetFailures(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		test.CaseBase
+		// token returns the token to reset with, given the reset token that
+		// was sent.
+		token  func(*testing.T, resetFixture, string) string
+		reason string
+	}{
+		{
+			CaseBase: test.NewCaseBase("verification token", domain.ErrInvalidToken, true),
+			token: func(t *testing.T, f resetFixture, _ string) string {
+				t.Helper()
+				// Sign up sent the verification email first.
+				return linkToken(t, f.mailer.sent[0])
+			},
+			reason: "invalid_token",
+		},
+		{
+			CaseBase: test.NewCaseBase("expired", domain.ErrTokenExpired, true),
+			token: func(t *testing.T, f resetFixture, token string) string {
+				t.Helper()
+				id, _, _ := strings.Cut(token, ".")
+				stored, err := f.tokens.GetByID(context.Background(), id)
+				assert.NoError(t, err)
+				stored.ExpiresAt = time.Now().Add(-time.Minute)
+				assert.NoError(t, f.tokens.Insert(context.Background(), stored))
+				return token
+			},
+			reason: "token_expired",
+		},
+		{
+			CaseBase: test.NewCaseBase("unknown", domain.ErrInvalidToken, true),
+			token: func(*testing.T, resetFixture, string) string {
+				return "0198c0de-0000-7000-8000-000000000000.secret"
+			},
+			reason: "unknown_token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newResetFixture(t)
+				_, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+				assert.NoError(t, err)
+				f.reset.Request(ctx, testEmail)
+
+				err = f.reset.Reset(ctx, tt.token(t, f, verificationToken(t, f.mailer)), newTestPassword)
+				assert.Error(t, err, tt.Want.(error))
+				assert.Equal(t, f.audit.last().Reason, tt.reason)
+
+				_, err = f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+				assert.NoError(t, err)
+			},
+		)
+	}
+}
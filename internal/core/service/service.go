@@ -6,6 +6,8 @@
 package service
 
 import (
+	"context"
+
 	"go.brokedaear.com/internal/common/telemetry"
 	"go.brokedaear.com/internal/common/utils/loggers"
 )
@@ -16,15 +18,59 @@ func NewServices() *Service {
 	return &Service{}
 }
 
+// transactor runs a function inside a transaction, carried through the
+// context passed to the function, so that every repository it uses writes
+// as one unit of work.
+type transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
 // ServiceBase is a base type for all services.
 type ServiceBase struct {
-	logger loggers.Logger
-	tel    telemetry.Telemetry
+	logger   loggers.Logger
+	tel      telemetry.Telemetry
+	auditLog *AuditLogService
+	tx       transactor
+	events   eventWriter
 }
 
 func NewServiceBase(logger loggers.Logger, tel telemetry.Telemetry) *ServiceBase {
 	return &ServiceBase{
-		logger: logger,
-		tel:    tel,
+		logger:   logger,
+		tel:      tel,
+		auditLog: nil,
+		tx:       nil,
+		events:   nil,
+	}
+}
+
+// UseAuditLog makes every service created with the base append its audit
+// events to an audit log, in addition to logging them. It must be called
+// before the services are used.
+func (s *ServiceBase) UseAuditLog(log *AuditLogService) {
+	s.auditLog = log
+}
+
+// UseTransactions makes every service created with the base run operations
+// that write through several repositories inside one transaction. It must
+// be called before the services are used.
+func (s *ServiceBase) UseTransactions(tx transactor) {
+	s.tx = tx
+}
+
+// UseEvents makes every service created with the base write domain events
+// to an outbox, from where an EventRelay publishes them. Events are written
+// in the same transaction as the change they describe if the base also uses
+// transactions. It must be called before the services are used.
+func (s *ServiceBase) UseEvents(outbox eventWriter) {
+	s.events = outbox
+}
+
+// inTx runs fn inside a transaction if the base has a transactor, and
+// directly otherwise. fn must use the context it is passed.
+func (s *ServiceBase) inTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
 	}
+	return s.tx.WithinTx(ctx, fn)
 }
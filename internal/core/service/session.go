@@ -5,50 +5,300 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"time"
 
 	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
 )
 
+// sessionRepository stores customer sessions. Sessions are looked up by their
+// ID; the secret part of a session token is never stored.
 type sessionRepository interface {
-	GetByToken(token string) (session *domain.UserSession, ok bool)
-	GetByCustomer(customer *domain.Customer) (session *domain.UserSession, ok bool)
-	Insert(customerSession *domain.UserSession)
-	Update()
-	Delete(token string) error
+	Insert(ctx context.Context, session *domain.UserSession) error
+	GetByID(ctx context.Context, id string) (*domain.UserSession, error)
+	ListByCustomer(ctx context.Context, customerID string) ([]*domain.UserSession, error)
+	// Touch records when a session was last used, and writes nothing else,
+	// so that it cannot undo a renewal or reauthentication made since the
+	// session was read.
+	Touch(ctx context.Context, id string, lastSeenAt time.Time) error
+	// Rotate writes the renewed secret, expiry and last seen time of a
+	// session, if the stored secret is still the one the session was
+	// renewed from, its PreviousSecretHash. Otherwise, it returns
+	// domain.ErrSessionRotated.
+	Rotate(ctx context.Context, session *domain.UserSession) error
+	// MarkAuthenticated records when the customer last proved their
+	// credentials in a session.
+	MarkAuthenticated(ctx context.Context, id string, at time.Time) error
+	Delete(ctx context.Context, id string) error
+	DeleteByCustomer(ctx context.Context, customerID string) error
 }
 
+// SessionPolicy configures the lifetime of sessions.
+type SessionPolicy struct {
+	// IdleTimeout is how long a session lives without being used. Using a
+	// session past half of its idle timeout extends it by another idle
+	// timeout.
+	IdleTimeout time.Duration
+	// AbsoluteLifetime is how long a session lives at most, no matter how
+	// active it is. Once over, the customer must sign in again.
+	AbsoluteLifetime time.Duration
+	// ReauthWindow is how recently a customer must have proven their
+	// credentials to perform sensitive operations, such as deleting their
+	// account or changing their password.
+	ReauthWindow time.Duration
+	// RotationGrace is how long the previous token of a renewed session is
+	// still accepted, so that requests already in flight are not rejected.
+	RotationGrace time.Duration
+}
+
+// DefaultSessionPolicy returns a policy where sessions idle out after a week
+// and last a month at most.
+func DefaultSessionPolicy() SessionPolicy {
+	const (
+		week  = 7 * 24 * time.Hour
+		month = 30 * 24 * time.Hour
+	)
+	return SessionPolicy{
+		IdleTimeout:      week,
+		AbsoluteLifetime: month,
+		ReauthWindow:     10 * time.Minute,
+		RotationGrace:    time.Minute,
+	}
+}
+
+// lastSeenResolution is how often the last seen time of a session is
+// written back to the repository. Writing on every request would turn every
+// read into a write.
+const lastSeenResolution = time.Minute
+
 // SessionService manages a customer account session.
 type SessionService struct {
 	*ServiceBase
-	repo sessionRepository
+	repo   sessionRepository
+	policy SessionPolicy
 }
 
-func NewSessionService(svcBase *ServiceBase, repo sessionRepository) *SessionService {
+func NewSessionService(
+	svcBase *ServiceBase,
+	repo sessionRepository,
+	policy SessionPolicy,
+) *SessionService {
 	return &SessionService{
 		ServiceBase: svcBase,
 		repo:        repo,
+		policy:      policy,
+	}
+}
+
+// NewSession issues a new session for a user and stores it. The returned
+// session carries the token that must be handed to the client.
+func (s *SessionService) NewSession(
+	ctx context.Context,
+	userID string,
+	client domain.ClientInfo,
+) (*domain.UserSession, error) {
+	session, err := domain.NewUserSession(
+		userID, client, s.policy.IdleTimeout, s.policy.AbsoluteLifetime,
+	)
+	if err != nil {
+		return nil, err
+	}
+	err = s.repo.Insert(ctx, session)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store new session")
+	}
+	return session, nil
+}
+
+// Validate checks if a session is valid, given its token, and returns the
+// session if it is.
+//
+// Sessions slide: when a session is used past half of its idle timeout, it is
+// renewed and its token is rotated. In that case, the returned session's
+// Token is set and the client must be handed the new token. Otherwise, Token
+// is empty and the client keeps its token.
+func (s *SessionService) Validate(
+	ctx context.Context,
+	token string,
+) (*domain.UserSession, error) {
+	session, current, err := s.lookup(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	// Only the current token may renew the session. A previous token is
+	// accepted within its grace period, but cannot rotate the session again.
+	if current && session.NeedsRenewal(now, s.policy.IdleTimeout) {
+		return s.renew(ctx, session, token, now)
+	}
+
+	if now.Sub(session.LastSeenAt) >= lastSeenResolution {
+		session.LastSeenAt = now
+		err = s.repo.Touch(ctx, session.ID, now)
+		if err != nil {
+			s.logger.Warn("failed to update session last seen", "session_id", session.ID, "error", err)
+		}
+	}
+	return session, nil
+}
+
+// renew rotates the token of a session and extends it. If another request
+// with the same token renewed the session first, the token is now the
+// previous token of the session, so it is accepted as one instead, and no
+// new token is handed out.
+func (s *SessionService) renew(
+	ctx context.Context,
+	session *domain.UserSession,
+	token string,
+	now time.Time,
+) (*domain.UserSession, error) {
+	err := session.Renew(now, s.policy.IdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	session.LastSeenAt = now
+	err = s.repo.Rotate(ctx, session)
+	if errors.Is(err, domain.ErrSessionRotated) {
+		renewed, _, err := s.lookup(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return renewed, nil
+	}
+	if errors.Is(err, domain.ErrSessionNotFound) {
+		return nil, ErrInvalidSession
 	}
+	if err != nil {
+		// The rotated token was never stored, so it must not be handed out.
+		return nil, errors.Wrap(err, "failed to renew session")
+	}
+	return session, nil
 }
 
-func (s *SessionService) NewSession(userID string) *domain.UserSession {
-	session := domain.NewUserSession(userID, sessionDuration)
-	s.repo.Insert(session)
-	return session
+// lookup finds the session a token belongs to and verifies the token secret.
+// It reports whether the token is the current token of the session, or a
+// previous one that is still within its rotation grace period.
+func (s *SessionService) lookup(
+	ctx context.Context,
+	token string,
+) (*domain.UserSession, bool, error) {
+	id, secret, err := domain.ParseSessionToken(token)
+	if err != nil {
+		return nil, false, ErrInvalidSession
+	}
+	session, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return nil, false, ErrInvalidSession
+		}
+		return nil, false, errors.Wrap(err, "failed to get session")
+	}
+	now := time.Now()
+	current := session.VerifySecret(secret)
+	if !current && !session.VerifyPreviousSecret(secret, now, s.policy.RotationGrace) {
+		return nil, false, ErrInvalidSession
+	}
+	if session.Expired(now) {
+		err = s.repo.Delete(ctx, session.ID)
+		if err != nil {
+			s.logger.Warn("failed to delete expired session", "session_id", session.ID, "error", err)
+		}
+		return nil, false, ErrSessionExpired
+	}
+	return session, current, nil
 }
 
-// Validate checks if a session is valid, given its token.
-func (s *SessionService) Validate(token string) (bool, error) {
-	session, ok := s.repo.GetByToken(token)
-	if !ok {
-		return false, nil
+// RequireRecentAuth returns ErrReauthenticationRequired if the customer has
+// not proven their credentials in the session recently enough to perform a
+// sensitive operation.
+func (s *SessionService) RequireRecentAuth(session *domain.UserSession) error {
+	if !session.AuthenticatedWithin(time.Now(), s.policy.ReauthWindow) {
+		return ErrReauthenticationRequired
 	}
-	if time.Now().After(session.ExpiresAt) {
-		return false, errors.New("expired session")
+	return nil
+}
+
+// MarkAuthenticated records that the customer just proved their credentials
+// in a session.
+func (s *SessionService) MarkAuthenticated(
+	ctx context.Context,
+	session *domain.UserSession,
+) error {
+	session.AuthenticatedAt = time.Now().UTC()
+	return s.repo.MarkAuthenticated(ctx, session.ID, session.AuthenticatedAt)
+}
+
+// Revoke invalidates the session a token belongs to and returns it.
+func (s *SessionService) Revoke(
+	ctx context.Context,
+	token string,
+) (*domain.UserSession, error) {
+	session, _, err := s.lookup(ctx, token)
+	if err != nil {
+		return nil, err
 	}
-	// if time.Now().After(session.ExpiresAt.Sub(sessionExpiresIn / 2)) {
-	// 	session.ExpiresAt = time.Now().Add(sessionExpiresIn)
-	// }
-	return true, nil
+	return session, s.repo.Delete(ctx, session.ID)
 }
+
+// List returns all sessions of a customer, so they can see where they are
+// signed in.
+func (s *SessionService) List(
+	ctx context.Context,
+	customerID string,
+) ([]*domain.UserSession, error) {
+	return s.repo.ListByCustomer(ctx, customerID)
+}
+
+// RevokeByID invalidates a single session of a customer. The session must
+// belong to the customer.
+func (s *SessionService) RevokeByID(
+	ctx context.Context,
+	customerID, sessionID string,
+) error {
+	session, err := s.repo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return ErrInvalidSession
+		}
+		return err
+	}
+	if session.UserID != customerID {
+		return ErrInvalidSession
+	}
+	return s.repo.Delete(ctx, session.ID)
+}
+
+// RevokeAll invalidates every session of a customer.
+func (s *SessionService) RevokeAll(ctx context.Context, customerID string) error {
+	return s.repo.DeleteByCustomer(ctx, customerID)
+}
+
+// RevokeOthers invalidates every session of a customer except one, such as
+// the session a customer changed their password in.
+func (s *SessionService) RevokeOthers(
+	ctx context.Context,
+	keep *domain.UserSession,
+) error {
+	sessions, err := s.repo.ListByCustomer(ctx, keep.UserID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.ID == keep.ID {
+			continue
+		}
+		err = s.repo.Delete(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	ErrInvalidSession           = errors.New("invalid session")
+	ErrSessionExpired           = errors.New("expired session")
+	ErrReauthenticationRequired = errors.New("reauthentication required")
+)
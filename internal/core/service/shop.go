@@ -4,36 +4,192 @@
 
 package service
 
-//
-// import (
-// 	"go.brokedaear.com/internal/core/domain"
-// )
-//
-// type WebshopCustomerHandler interface {
-// 	CustomerUpdater
-// 	CustomerRetriever
-// }
-//
-// // PaymentProcessor handles and processes payments.
-// type paymentProcessor interface {
-// 	Refund()
-// 	Pay()
-// }
-//
-// // WebshopService enables customers to purchase and refund their
-// // products.
-// type WebshopService struct {
-// 	paymentProcessor paymentProcessor
-// 	repo             WebshopCustomerHandler
-// }
-//
-// func NewWebshopService(processor paymentProcessor, repo WebshopCustomerHandler) *WebshopService {
-// 	return &WebshopService{
-// 		paymentProcessor: processor,
-// 		repo:             repo,
-// 	}
-// }
-//
-// func (w WebshopService) Purchase(_ *domain.Product) error {
-// 	return nil
-// }
+import (
+	"context"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// paymentProcessor handles and processes payments.
+type paymentProcessor interface {
+	Pay(ctx context.Context, order *domain.Order) error
+	Refund(ctx context.Context, order *domain.Order) error
+}
+
+// WebshopService enables customers to purchase and refund their
+// products.
+type WebshopService struct {
+	*ServiceBase
+	paymentProcessor paymentProcessor
+	customers        customerRepository
+	orders           orderRepository
+	downloads        downloadRepository
+	authz            *AuthorizationService
+}
+
+// NewWebshopService creates a new WebshopService.
+func NewWebshopService(
+	svcBase *ServiceBase,
+	processor paymentProcessor,
+	customers customerRepository,
+	orders orderRepository,
+	downloads downloadRepository,
+	authz *AuthorizationService,
+) *WebshopService {
+	return &WebshopService{
+		ServiceBase:      svcBase,
+		paymentProcessor: processor,
+		customers:        customers,
+		orders:           orders,
+		downloads:        downloads,
+		authz:            authz,
+	}
+}
+
+// Purchase checks out an order for the customer a session belongs to. Only
+// customers who have verified their email address can make purchases, since
+// receipts and download links are sent there. Once paid, the order is
+// recorded as completed along with a download of every plugin in it and an
+// OrderPaid event, in one transaction.
+func (w *WebshopService) Purchase(
+	ctx context.Context,
+	session *domain.UserSession,
+	order *domain.Order,
+) error {
+	customer, err := w.customers.GetByID(ctx, session.UserID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get customer")
+	}
+	if !customer.EmailVerified {
+		return ErrEmailNotVerified
+	}
+	order.UserID = customer.ID
+	err = w.paymentProcessor.Pay(ctx, order)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	order.Status = domain.CompletedStatus
+	order.CompletedAt = &now
+	for i := range order.Items {
+		order.Items[i].Status = domain.CompletedStatus
+	}
+	err = w.inTx(ctx, func(ctx context.Context) error {
+		err := w.orders.Insert(ctx, order)
+		if err != nil {
+			return errors.Wrap(err, "failed to record order")
+		}
+		err = w.grantDownloads(ctx, order)
+		if err != nil {
+			return err
+		}
+		return w.emit(ctx, domain.EventTypeOrderPaid, order.ID, domain.OrderPaid{
+			OrderID:    order.ID,
+			CustomerID: order.UserID,
+			GrandTotal: order.GrandTotal,
+			Currency:   order.CurrencyID,
+		})
+	})
+	if err != nil {
+		// The payment processor already took the money, so the order must
+		// be recorded by hand.
+		w.logger.Error("paid for order but failed to record it", "order_id", order.ID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// grantDownloads creates a download of every plugin in an order.
+func (w *WebshopService) grantDownloads(ctx context.Context, order *domain.Order) error {
+	for _, item := range order.Items {
+		if item.Product.ProductType != domain.PluginProduct {
+			continue
+		}
+		download, err := domain.NewDownload(order.UserID, item.Product.ID, order.ID)
+		if err != nil {
+			return err
+		}
+		err = w.downloads.Insert(ctx, download)
+		if err != nil {
+			return errors.Wrap(err, "failed to grant download")
+		}
+	}
+	return nil
+}
+
+// Refund refunds an order on behalf of a principal, such as a support agent.
+// The grants of the principal must allow refunding the grand total stored
+// with the order. The order is claimed first by moving it from completed to
+// refunding, so that of concurrent refunds of an order only one reaches the
+// payment processor. Once the money is back with the customer, the order is
+// marked as refunded along with an OrderRefunded event, in one transaction.
+func (w *WebshopService) Refund(
+	ctx context.Context,
+	principal *domain.Principal,
+	order *domain.Order,
+) error {
+	var stored *domain.Order
+	err := w.inTx(ctx, func(ctx context.Context) error {
+		var err error
+		stored, err = w.orders.GetByID(ctx, order.ID)
+		if err != nil {
+			return errors.Wrap(err, "failed to get order")
+		}
+		err = w.authz.Authorize(ctx, principal, domain.AccessRequest{
+			Permission: domain.PermissionOrdersRefund,
+			OwnerID:    stored.UserID,
+			Amount:     stored.GrandTotal,
+		})
+		if err != nil {
+			return err
+		}
+		err = w.orders.UpdateStatusFrom(ctx, stored.ID, domain.CompletedStatus, domain.RefundingStatus)
+		if errors.Is(err, domain.ErrOrderStatusChanged) {
+			return ErrOrderNotRefundable
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to claim order")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = w.paymentProcessor.Refund(ctx, stored)
+	if err != nil {
+		// Give the order back, so that it can be refunded again.
+		releaseErr := w.orders.UpdateStatusFrom(ctx, stored.ID, domain.RefundingStatus, domain.CompletedStatus)
+		if releaseErr != nil {
+			w.logger.Error("failed to release order", "order_id", stored.ID, "error", releaseErr)
+		}
+		return errors.Wrap(err, "failed to refund order")
+	}
+	w.logger.Info("refunded order", "order_id", stored.ID, "by", principal.CustomerID)
+	err = w.inTx(ctx, func(ctx context.Context) error {
+		err := w.orders.UpdateStatusFrom(ctx, stored.ID, domain.RefundingStatus, domain.RefundedStatus)
+		if err != nil {
+			return errors.Wrap(err, "failed to mark order refunded")
+		}
+		return w.emit(ctx, domain.EventTypeOrderRefunded, stored.ID, domain.OrderRefunded{
+			OrderID:    stored.ID,
+			CustomerID: stored.UserID,
+			GrandTotal: stored.GrandTotal,
+			Currency:   stored.CurrencyID,
+			RefundedBy: principal.CustomerID,
+		})
+	})
+	if err != nil {
+		// The money is already back with the customer, so the order, left
+		// refunding, must be fixed by hand.
+		w.logger.Error("refunded order but failed to record it", "order_id", stored.ID, "error", err)
+		return err
+	}
+	*order = *stored
+	order.Status = domain.RefundedStatus
+	for i := range order.Items {
+		order.Items[i].Status = domain.RefundedStatus
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+// stagedTxKey is the context key of the writes staged in a transaction of a
+// stagingTransactor.
+type stagedTxKey struct{}
+
+// stagedWrites are the writes of a transaction, applied once it commits.
+type stagedWrites struct {
+	writes []func()
+}
+
+// stagingTransactor runs functions as a transaction that applies the writes
+// staged in it only if the function succeeds, and discards them otherwise.
+type stagingTransactor struct{}
+
+func (stagingTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	staged := &stagedWrites{writes: nil}
+	err := fn(context.WithValue(ctx, stagedTxKey{}, staged))
+	if err != nil {
+		return err
+	}
+	for _, write := range staged.writes {
+		write()
+	}
+	return nil
+}
+
+// stage stages a write in the transaction carried by ctx, or applies it
+// right away outside of one.
+func stage(ctx context.Context, write func()) {
+	staged, ok := ctx.Value(stagedTxKey{}).(*stagedWrites)
+	if !ok {
+		write()
+		return
+	}
+	staged.writes = append(staged.writes, write)
+}
+
+// stagedOrders is an order repository that stages its writes in the
+// transaction carried by the context, and fails them with err if it is set.
+type stagedOrders struct {
+	*memory.OrderRepository
+	err error
+}
+
+func (s stagedOrders) Insert(ctx context.Context, order *domain.Order) error {
+	if s.err != nil {
+		return s.err
+	}
+	o := *order
+	stage(ctx, func() {
+		_ = s.OrderRepository.Insert(ctx, &o)
+	})
+	return nil
+}
+
+func (s stagedOrders) UpdateStatusFrom(ctx context.Context, id string, from, to domain.FulfillmentStatus) error {
+	if s.err != nil {
+		return s.err
+	}
+	order, err := s.OrderRepository.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if order.Status != from {
+		return domain.ErrOrderStatusChanged
+	}
+	stage(ctx, func() {
+		_ = s.OrderRepository.UpdateStatusFrom(ctx, id, from, to)
+	})
+	return nil
+}
+
+// stagedDownloads is a download repository that stages its inserts in the
+// transaction carried by the context, and fails them with err if it is set.
+type stagedDownloads struct {
+	*memory.DownloadRepository
+	err error
+}
+
+func (s stagedDownloads) Insert(ctx context.Context, download *domain.Download) error {
+	if s.err != nil {
+		return s.err
+	}
+	stage(ctx, func() {
+		_ = s.DownloadRepository.Insert(ctx, download)
+	})
+	return nil
+}
+
+// stagedEvents is an event outbox that stages its inserts in the
+// transaction carried by the context, and fails them with err if it is set.
+type stagedEvents struct {
+	*memory.EventOutboxRepository
+	err error
+}
+
+func (s stagedEvents) Insert(ctx context.Context, event *domain.Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	stage(ctx, func() {
+		_ = s.EventOutboxRepository.Insert(ctx, event)
+	})
+	return nil
+}
+
+func TestWebshopService_PurchaseRecordsOrderInTransaction(t *testing.T) {
+	errOrders := errors.New("failed to insert order")
+	errDownloads := errors.New("failed to insert download")
+	errEvents := errors.New("failed to insert event")
+	tests := []struct {
+		test.CaseBase
+		ordersErr    error
+		downloadsErr error
+		eventsErr    error
+	}{
+		{CaseBase: test.NewCaseBase("committed", nil, false), ordersErr: nil, downloadsErr: nil, eventsErr: nil},
+		{
+			CaseBase:     test.NewCaseBase("order fails", errOrders, true),
+			ordersErr:    errOrders,
+			downloadsErr: nil,
+			eventsErr:    nil,
+		},
+		{
+			CaseBase:     test.NewCaseBase("download fails", errDownloads, true),
+			ordersErr:    nil,
+			downloadsErr: errDownloads,
+			eventsErr:    nil,
+		},
+		{
+			CaseBase:     test.NewCaseBase("event fails", errEvents, true),
+			ordersErr:    nil,
+			downloadsErr: nil,
+			eventsErr:    errEvents,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				ctx := context.Background()
+				f := newAuthFixture(t)
+				orders := memory.NewOrderRepository()
+				downloads := memory.NewDownloadRepository()
+				outbox := memory.NewEventOutboxRepository()
+				base := NewServiceBase(test.NewMockLogger(), nil)
+				base.UseTransactions(stagingTransactor{})
+				base.UseEvents(stagedEvents{EventOutboxRepository: outbox, err: tt.eventsErr})
+				processor := &fakePaymentProcessor{}
+				shop := NewWebshopService(
+					base,
+					processor,
+					f.customers,
+					stagedOrders{OrderRepository: orders, err: tt.ordersErr},
+					stagedDownloads{DownloadRepository: downloads, err: tt.downloadsErr},
+					f.authz,
+				)
+
+				result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+				assert.NoError(t, err)
+				_, err = f.verification.Confirm(ctx, verificationToken(t, f.mailer))
+				assert.NoError(t, err)
+				product := domain.NewProduct(domain.PluginProduct, "product-1", "Reverb Pack")
+				product.Price = 4200
+				item, err := domain.NewLineItem(*product, 1)
+				assert.NoError(t, err)
+				order, err := domain.NewOrder("USD", *item)
+				assert.NoError(t, err)
+
+				err = shop.Purchase(ctx, result.Session, order)
+				assert.Equal(t, len(processor.paid), 1)
+				recorded, getErr := orders.GetByID(ctx, order.ID)
+				granted, listErr := downloads.ListByCustomer(ctx, result.Customer.ID)
+				assert.NoError(t, listErr)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Error(t, getErr, domain.ErrOrderNotFound)
+					assert.Equal(t, len(granted), 0)
+					assert.Equal(t, len(outbox.List()), 0)
+					return
+				}
+				assert.NoError(t, err)
+				assert.NoError(t, getErr)
+				assert.Equal(t, recorded.Status, domain.CompletedStatus)
+				assert.Equal(t, recorded.Items[0].Status, domain.CompletedStatus)
+				assert.Equal(t, recorded.UserID, result.Customer.ID)
+				assert.Equal(t, len(granted), 1)
+				assert.Equal(t, granted[0].ProductID, product.ID)
+				assert.Equal(t, granted[0].OrderID, order.ID)
+				events := outbox.List()
+				assert.Equal(t, len(events), 1)
+				assert.Equal(t, events[0].Type, domain.EventTypeOrderPaid)
+				assert.Equal(t, events[0].AggregateID, order.ID)
+			},
+		)
+	}
+}
+
+func TestWebshopService_RefundMarksOrderInTransaction(t *testing.T) {
+	errOrders := errors.New("failed to update order")
+	errEvents := errors.New("failed to insert event")
+	// An order that cannot be claimed is not refunded at all. An order
+	// refunded but not recorded is left refunding, to be fixed by hand.
+	tests := []struct {
+		test.CaseBase
+		ordersErr    error
+		eventsErr    error
+		wantStatus   domain.FulfillmentStatus
+		wantRefunded int
+	}{
+		{
+			CaseBase:     test.NewCaseBase("committed", nil, false),
+			ordersErr:    nil,
+			eventsErr:    nil,
+			wantStatus:   domain.RefundedStatus,
+			wantRefunded: 1,
+		},
+		{
+			CaseBase:     test.NewCaseBase("order fails", errOrders, true),
+			ordersErr:    errOrders,
+			eventsErr:    nil,
+			wantStatus:   domain.CompletedStatus,
+			wantRefunded: 0,
+		},
+		{
+			CaseBase:     test.NewCaseBase("event fails", errEvents, true),
+			ordersErr:    nil,
+			eventsErr:    errEvents,
+			wantStatus:   domain.RefundingStatus,
+			wantRefunded: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				ctx := context.Background()
+				f := newAdminFixture(t)
+				outbox := memory.NewEventOutboxRepository()
+				base := NewServiceBase(test.NewMockLogger(), nil)
+				base.UseTransactions(stagingTransactor{})
+				base.UseEvents(stagedEvents{EventOutboxRepository: outbox, err: tt.eventsErr})
+				processor := &fakePaymentProcessor{}
+				shop := NewWebshopService(
+					base, processor, f.customers,
+					stagedOrders{OrderRepository: f.orders, err: tt.ordersErr}, f.downloads, f.authz,
+				)
+				admin := f.principal(t, "admin@brokedaear.com", domain.RoleAdmin)
+
+				err := shop.Refund(ctx, admin, f.order)
+				stored, getErr := f.orders.GetByID(ctx, f.order.ID)
+				assert.NoError(t, getErr)
+				assert.Equal(t, stored.Status, tt.wantStatus)
+				assert.Equal(t, len(processor.refunded), tt.wantRefunded)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Equal(t, len(outbox.List()), 0)
+					return
+				}
+				assert.NoError(t, err)
+				events := outbox.List()
+				assert.Equal(t, len(events), 1)
+				assert.Equal(t, events[0].Type, domain.EventTypeOrderRefunded)
+				var refunded domain.OrderRefunded
+				assert.NoError(t, events[0].Decode(&refunded))
+				assert.Equal(t, refunded.RefundedBy, admin.CustomerID)
+			},
+		)
+	}
+}
@@ -0,0 +1,389 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// loginThrottleRepository stores the recent failed sign in attempts per
+// throttle key.
+type loginThrottleRepository interface {
+	// Get returns domain.ErrThrottleNotFound if there were no recent failed
+	// attempts with a key.
+	Get(ctx context.Context, key string) (*domain.LoginThrottle, error)
+	// RecordFailure counts a failed attempt with a key at a point in time,
+	// and returns the updated throttle. Failures older than the window are
+	// forgotten first. Concurrent failures must all be counted.
+	RecordFailure(ctx context.Context, key string, at time.Time, window time.Duration) (*domain.LoginThrottle, error)
+	// Lock locks a key out until a point in time.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// Reset forgets the failures and the lockout of a key.
+	Reset(ctx context.Context, key string) error
+}
+
+// LoginThrottleLimit configures how failed sign in attempts with one kind of
+// throttle key are slowed down and locked out.
+type LoginThrottleLimit struct {
+	// FreeAttempts is how many attempts may fail before the next attempt
+	// has to wait.
+	FreeAttempts int
+	// LockoutAfter is how many failed attempts lock the key out.
+	LockoutAfter int
+	// LockoutDuration is how long a lockout lasts.
+	LockoutDuration time.Duration
+}
+
+// LoginThrottlePolicy configures brute-force protection of sign in.
+//
+// Failed attempts are counted per account and per IP address. Past the free
+// attempts, every further attempt has to wait twice as long as the one
+// before, starting at the base delay, and enough failures lock the account
+// or the address out for a while. The customer of a locked account is sent
+// a link that unlocks it.
+type LoginThrottlePolicy struct {
+	// Window is how long a failed attempt is remembered.
+	Window time.Duration
+	// BaseDelay is how long to wait after the first failure past the free
+	// attempts.
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts.
+	MaxDelay time.Duration
+	// Account limits the attempts on one account.
+	Account LoginThrottleLimit
+	// IP limits the attempts from one IP address. Many customers can share
+	// an address, so it is usually more lenient than Account.
+	IP LoginThrottleLimit
+	// UnlockTokenTTL is how long an unlock link stays valid.
+	UnlockTokenTTL time.Duration
+	// UnlockLinkURL is the URL of the frontend page that unlocks an
+	// account. The token is appended as the `token` query parameter.
+	UnlockLinkURL string
+}
+
+// DefaultLoginThrottlePolicy returns a policy where an account slows down
+// after 5 failures in an hour and locks for half an hour after 10, and an IP
+// address slows down after 20 and locks for an hour after 100.
+func DefaultLoginThrottlePolicy(unlockLinkURL string) LoginThrottlePolicy {
+	return LoginThrottlePolicy{
+		Window:    time.Hour,
+		BaseDelay: time.Second,
+		MaxDelay:  5 * time.Minute,
+		Account: LoginThrottleLimit{
+			FreeAttempts:    5,
+			LockoutAfter:    10,
+			LockoutDuration: 30 * time.Minute,
+		},
+		IP: LoginThrottleLimit{
+			FreeAttempts:    20,
+			LockoutAfter:    100,
+			LockoutDuration: time.Hour,
+		},
+		UnlockTokenTTL: time.Hour,
+		UnlockLinkURL:  unlockLinkURL,
+	}
+}
+
+// backoff returns how long to wait after a number of failures past the free
+// attempts.
+func (p LoginThrottlePolicy) backoff(extra int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < extra && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// retryAt returns when the next attempt with a throttled key may be made.
+func (p LoginThrottlePolicy) retryAt(l *domain.LoginThrottle, limit LoginThrottleLimit) time.Time {
+	retry := l.LockedUntil
+	extra := l.Failures - limit.FreeAttempts
+	if extra > 0 {
+		next := l.LastFailureAt.Add(p.backoff(extra))
+		if next.After(retry) {
+			retry = next
+		}
+	}
+	return retry
+}
+
+// LoginThrottledError is returned when a sign in attempt is refused because
+// of earlier failures. It matches ErrTooManyAttempts, and ErrCustomerLoginFailed
+// like every other failed sign in.
+type LoginThrottledError struct {
+	// RetryAfter is how long to wait before the next attempt.
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return "too many sign in attempts, retry after " + strconv.Itoa(int(e.RetryAfter.Seconds())) + "s"
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrTooManyAttempts || target == ErrCustomerLoginFailed
+}
+
+// LoginThrottleService protects sign in against password guessing, following
+// a LoginThrottlePolicy.
+type LoginThrottleService struct {
+	*ServiceBase
+	throttles loginThrottleRepository
+	customers customerRepository
+	tokens    tokenRepository
+	mailer    Mailer
+	policy    LoginThrottlePolicy
+	audit     auditRecorder
+	metrics   *throttleMetrics
+}
+
+// NewLoginThrottleService creates a new LoginThrottleService. Metrics are
+// recorded if the service base has telemetry.
+func NewLoginThrottleService(
+	svcBase *ServiceBase,
+	throttles loginThrottleRepository,
+	customers customerRepository,
+	tokens tokenRepository,
+	mailer Mailer,
+	policy LoginThrottlePolicy,
+) (*LoginThrottleService, error) {
+	metrics, err := newThrottleMetrics(svcBase.tel)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginThrottleService{
+		ServiceBase: svcBase,
+		throttles:   throttles,
+		customers:   customers,
+		tokens:      tokens,
+		mailer:      mailer,
+		policy:      policy,
+		audit:       svcBase.auditRecorder(),
+		metrics:     metrics,
+	}, nil
+}
+
+// throttleKey is a throttle key along with its kind and limit.
+type throttleKey struct {
+	key   string
+	kind  string
+	limit LoginThrottleLimit
+}
+
+// keys returns the throttle keys of a sign in attempt. Attempts without an
+// IP address are only throttled per account.
+func (l *LoginThrottleService) keys(email, ip string) []throttleKey {
+	keys := []throttleKey{{key: domain.AccountThrottleKey(email), kind: "account", limit: l.policy.Account}}
+	if ip != "" {
+		keys = append(keys, throttleKey{key: domain.IPThrottleKey(ip), kind: "ip", limit: l.policy.IP})
+	}
+	return keys
+}
+
+// check returns a LoginThrottledError if a sign in attempt with an email
+// from an IP address may not be made at a point in time.
+func (l *LoginThrottleService) check(ctx context.Context, email, ip string, at time.Time) error {
+	var retry time.Time
+	var throttledBy string
+	for _, k := range l.keys(email, ip) {
+		throttle, err := l.throttles.Get(ctx, k.key)
+		if errors.Is(err, domain.ErrThrottleNotFound) {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to get login throttle")
+		}
+		next := l.policy.retryAt(throttle, k.limit)
+		if next.After(retry) {
+			retry, throttledBy = next, k.kind
+		}
+	}
+	if !at.Before(retry) {
+		return nil
+	}
+	l.metrics.throttled.Add(ctx, 1, kindAttribute(throttledBy))
+	return &LoginThrottledError{RetryAfter: retry.Sub(at)}
+}
+
+// failed counts a failed sign in attempt with an email from an IP address,
+// and locks out the account or the address if they failed too often. The
+// customer is the customer with the email, or nil if there is none.
+//
+// Failing to count an attempt does not change the outcome of the attempt, so
+// errors are only logged.
+func (l *LoginThrottleService) failed(
+	ctx context.Context,
+	email, ip string,
+	customer *domain.Customer,
+	at time.Time,
+) {
+	l.metrics.failures.Add(ctx, 1)
+	for _, k := range l.keys(email, ip) {
+		throttle, err := l.throttles.RecordFailure(ctx, k.key, at, l.policy.Window)
+		if err != nil {
+			l.logger.Error("failed to record login failure", "kind", k.kind, "error", err)
+			continue
+		}
+		if throttle.Failures < k.limit.LockoutAfter || throttle.Locked(at) {
+			continue
+		}
+		until := at.Add(k.limit.LockoutDuration)
+		err = l.throttles.Lock(ctx, k.key, until)
+		if err != nil {
+			l.logger.Error("failed to lock login throttle", "kind", k.kind, "error", err)
+			continue
+		}
+		l.metrics.lockouts.Add(ctx, 1, kindAttribute(k.kind))
+		if k.kind == "ip" {
+			l.logger.Warn("ip address locked out", "ip", ip, "until", until)
+			continue
+		}
+		l.accountLocked(ctx, customer, k.limit.LockoutDuration)
+	}
+}
+
+// accountLocked audits the lockout of an account, and sends the customer a
+// link to unlock it if the customer signs in with a password.
+func (l *LoginThrottleService) accountLocked(ctx context.Context, customer *domain.Customer, lockedFor time.Duration) {
+	if customer == nil {
+		return
+	}
+	l.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionAccountLocked, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	if !customer.HasPassword() {
+		return
+	}
+	err := l.sendUnlock(ctx, customer, lockedFor)
+	if err != nil {
+		l.logger.Error("failed to send unlock email", "customer_id", customer.ID, "error", err)
+	}
+}
+
+func (l *LoginThrottleService) sendUnlock(ctx context.Context, customer *domain.Customer, lockedFor time.Duration) error {
+	// Only the latest unlock link works.
+	err := l.tokens.RevokeByCustomer(ctx, customer.ID, domain.TokenPurposeAccountUnlock, time.Now().UTC())
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke unlock tokens")
+	}
+	token, err := domain.NewOneTimeToken(
+		customer.ID, domain.TokenPurposeAccountUnlock, customer.Email, l.policy.UnlockTokenTTL,
+	)
+	if err != nil {
+		return err
+	}
+	err = l.tokens.Insert(ctx, token)
+	if err != nil {
+		return errors.Wrap(err, "failed to store unlock token")
+	}
+	link, err := tokenLink(l.policy.UnlockLinkURL, token)
+	if err != nil {
+		return err
+	}
+	return l.mailer.Send(ctx, domain.EmailMessage{
+		To:       customer.Email,
+		Template: domain.EmailTemplateAccountLocked,
+		Data:     map[string]any{"Link": link, "ExpiresIn": l.policy.UnlockTokenTTL, "LockedFor": lockedFor},
+	})
+}
+
+// succeeded forgets the failed attempts on the account with an email, once
+// its customer has signed in. The failures of the IP address are kept, so
+// that signing in to an account of one's own does not excuse guessing the
+// passwords of others.
+func (l *LoginThrottleService) succeeded(ctx context.Context, email string) {
+	err := l.throttles.Reset(ctx, domain.AccountThrottleKey(email))
+	if err != nil {
+		l.logger.Error("failed to reset login throttle", "error", err)
+	}
+}
+
+// Unlock lifts the lockout of the account an unlock token was sent for, and
+// forgets its failed attempts.
+func (l *LoginThrottleService) Unlock(ctx context.Context, rawToken string) error {
+	fail := func(customerID string, err error) error {
+		l.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionAccountUnlock, domain.AuditOutcomeFailure, customerID, tokenFailureReason(err),
+		))
+		return publicTokenError(err)
+	}
+
+	now := time.Now().UTC()
+	token, err := findToken(ctx, l.tokens, rawToken, domain.TokenPurposeAccountUnlock, now)
+	if err != nil {
+		if isRepositoryError(err) {
+			l.logger.Error("failed to get unlock token", "error", err)
+		}
+		return fail(tokenUserID(token), err)
+	}
+
+	customer, err := l.customers.GetByID(ctx, token.UserID)
+	if err != nil {
+		return fail(token.UserID, err)
+	}
+	if customer.Email != token.Email {
+		return fail(customer.ID, errEmailChanged)
+	}
+
+	err = l.tokens.MarkUsed(ctx, token.ID, now)
+	if err != nil {
+		return fail(customer.ID, err)
+	}
+	err = l.throttles.Reset(ctx, domain.AccountThrottleKey(customer.Email))
+	if err != nil {
+		l.logger.Error("failed to unlock account", "customer_id", customer.ID, "error", err)
+		return fail(customer.ID, err)
+	}
+
+	l.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionAccountUnlock, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return nil
+}
+
+// throttleMetrics records brute-force protection metrics.
+type throttleMetrics struct {
+	failures  otelmetric.Int64UpDownCounter
+	throttled otelmetric.Int64UpDownCounter
+	lockouts  otelmetric.Int64UpDownCounter
+}
+
+// newThrottleMetrics creates the brute-force protection instruments. Without
+// instruments, metrics are discarded.
+func newThrottleMetrics(tel telemetry.Instruments) (*throttleMetrics, error) {
+	if tel == nil {
+		meter := noop.NewMeterProvider().Meter("")
+		failures, _ := meter.Int64UpDownCounter("")
+		throttled, _ := meter.Int64UpDownCounter("")
+		lockouts, _ := meter.Int64UpDownCounter("")
+		return &throttleMetrics{failures: failures, throttled: throttled, lockouts: lockouts}, nil
+	}
+
+	failures, err := tel.UpDownCounter(telemetry.MetricLoginFailuresTotal)
+	if err != nil {
+		return nil, err
+	}
+	throttled, err := tel.UpDownCounter(telemetry.MetricLoginThrottledTotal)
+	if err != nil {
+		return nil, err
+	}
+	lockouts, err := tel.UpDownCounter(telemetry.MetricLoginLockoutsTotal)
+	if err != nil {
+		return nil, err
+	}
+	return &throttleMetrics{failures: failures, throttled: throttled, lockouts: lockouts}, nil
+}
+
+func kindAttribute(kind string) otelmetric.MeasurementOption {
+	return otelmetric.WithAttributes(attribute.String("kind", kind))
+}
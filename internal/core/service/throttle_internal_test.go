@@ -0,0 +1,191 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/test"
+)
+
+func TestLoginThrottlePolicy_Backoff(t *testing.T) {
+	policy := DefaultLoginThrottlePolicy("https://brokedaear.com/unlock")
+
+	tests := []struct {
+		test.CaseBase
+		extra int
+	}{
+		{CaseBase: test.NewCaseBase("first", time.Second, false), extra: 1},
+		{CaseBase: test.NewCaseBase("doubles", 2*time.Second, false), extra: 2},
+		{CaseBase: test.NewCaseBase("keeps doubling", 16*time.Second, false), extra: 5},
+		{CaseBase: test.NewCaseBase("capped", policy.MaxDelay, false), extra: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				assert.Equal(t, policy.backoff(tt.extra), tt.Want.(time.Duration))
+			},
+		)
+	}
+}
+
+// signUpThrottled signs up a customer and swaps in a throttle policy, so that
+// the sign up itself is not throttled.
+func signUpThrottled(t *testing.T, policy LoginThrottlePolicy) (authFixture, *fakeInstruments) {
+	t.Helper()
+	f := newAuthFixture(t)
+	_, err := f.auth.SignUp(context.Background(), testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	f.throttle.policy = policy
+
+	metrics := newFakeInstruments()
+	f.throttle.metrics, err = newThrottleMetrics(metrics)
+	assert.NoError(t, err)
+	return f, metrics
+}
+
+func TestAuthService_SignInBackoff(t *testing.T) {
+	ctx := context.Background()
+	policy := DefaultLoginThrottlePolicy("https://brokedaear.com/unlock")
+	f, metrics := signUpThrottled(t, policy)
+	client := domain.ClientInfo{IP: "198.51.100.4", UserAgent: "test"}
+
+	for range policy.Account.FreeAttempts {
+		_, err := f.auth.SignIn(ctx, testEmail, "wrong password", client)
+		assert.Error(t, err, ErrCustomerLoginFailed)
+		assert.False(t, errors.Is(err, ErrTooManyAttempts))
+	}
+
+	// Past the free attempts, the next attempt has to wait, even with the
+	// right password.
+	_, err := f.auth.SignIn(ctx, testEmail, "wrong password", client)
+	assert.Error(t, err, ErrCustomerLoginFailed)
+	_, err = f.auth.SignIn(ctx, testEmail, testPassword, client)
+	assert.Error(t, err, ErrTooManyAttempts)
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.True(t, throttled.RetryAfter > 0 && throttled.RetryAfter <= policy.BaseDelay)
+	assert.Equal(t, f.audit.last().Reason, "throttled")
+	assert.Equal(t, metrics.sum(t, telemetry.MetricLoginFailuresTotal), int64(policy.Account.FreeAttempts+1))
+	assert.Equal(t, metrics.sum(t, telemetry.MetricLoginThrottledTotal), int64(1))
+
+	// Signing in once the wait is over forgets the failures of the account,
+	// but not those of the address.
+	f.throttle.policy.BaseDelay = 0
+	_, err = f.auth.SignIn(ctx, testEmail, testPassword, client)
+	assert.NoError(t, err)
+	_, err = f.throttles.Get(ctx, domain.AccountThrottleKey(testEmail))
+	assert.Error(t, err, domain.ErrThrottleNotFound)
+	ip, err := f.throttles.Get(ctx, domain.IPThrottleKey(client.IP))
+	assert.NoError(t, err)
+	assert.Equal(t, ip.Failures, policy.Account.FreeAttempts+1)
+}
+
+// lockoutPolicy returns a policy without backoff, that locks an account out
+// after three failures.
+func lockoutPolicy() LoginThrottlePolicy {
+	policy := DefaultLoginThrottlePolicy("https://brokedaear.com/unlock")
+	policy.BaseDelay = 0
+	policy.Account.FreeAttempts = 0
+	policy.Account.LockoutAfter = 3
+	return policy
+}
+
+func TestAuthService_SignInLockoutAndUnlock(t *testing.T) {
+	ctx := context.Background()
+	policy := lockoutPolicy()
+	f, metrics := signUpThrottled(t, policy)
+	mailed := f.mailer.count()
+
+	for range policy.Account.LockoutAfter {
+		_, err := f.auth.SignIn(ctx, testEmail, "wrong password", domain.ClientInfo{})
+		assert.Error(t, err, ErrCustomerLoginFailed)
+	}
+	assert.Equal(t, metrics.sum(t, telemetry.MetricLoginLockoutsTotal), int64(1))
+	assert.Equal(t, f.mailer.count(), mailed+1)
+	msg := f.mailer.last()
+	assert.Equal(t, msg.Template, domain.EmailTemplateAccountLocked)
+	assert.Equal(t, msg.Data["LockedFor"], any(policy.Account.LockoutDuration))
+
+	// The lockout holds against the right password, and lasts until it ends.
+	_, err := f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(err, &throttled))
+	assert.True(t, throttled.RetryAfter > policy.Account.LockoutDuration-time.Minute)
+
+	token := linkToken(t, msg)
+	assert.NoError(t, f.throttle.Unlock(ctx, token))
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionAccountUnlock)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+	_, err = f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	err = f.throttle.Unlock(ctx, token)
+	assert.Error(t, err, domain.ErrTokenUsed)
+	err = f.throttle.Unlock(ctx, "not a token")
+	assert.Error(t, err, domain.ErrInvalidToken)
+}
+
+func TestAuthService_SignInLockoutOfUnknownEmail(t *testing.T) {
+	ctx := context.Background()
+	policy := lockoutPolicy()
+	f, _ := signUpThrottled(t, policy)
+	mailed := f.mailer.count()
+	const unknown = "nobody@brokedaear.com"
+
+	// Unknown addresses are locked out like known ones, so that a lockout
+	// does not tell whether an account exists, but nobody is emailed.
+	for range policy.Account.LockoutAfter {
+		_, err := f.auth.SignIn(ctx, unknown, "wrong password", domain.ClientInfo{})
+		assert.Error(t, err, ErrCustomerLoginFailed)
+		assert.Equal(t, f.audit.last().Reason, "unknown_customer")
+	}
+	_, err := f.auth.SignIn(ctx, unknown, "wrong password", domain.ClientInfo{})
+	assert.Error(t, err, ErrTooManyAttempts)
+	assert.Equal(t, f.mailer.count(), mailed)
+
+	// Account keys ignore case, like email lookups.
+	_, err = f.auth.SignIn(ctx, "Nobody@BrokeDaEar.com", "wrong password", domain.ClientInfo{})
+	assert.Error(t, err, ErrTooManyAttempts)
+}
+
+func TestAuthService_SignInIPLockout(t *testing.T) {
+	ctx := context.Background()
+	policy := lockoutPolicy()
+	policy.Account.LockoutAfter = 100
+	policy.IP.FreeAttempts = 0
+	policy.IP.LockoutAfter = 3
+	f, metrics := signUpThrottled(t, policy)
+	attacker := domain.ClientInfo{IP: "203.0.113.7", UserAgent: "test"}
+
+	for _, email := range []string{"a@brokedaear.com", "b@brokedaear.com", "c@brokedaear.com"} {
+		_, err := f.auth.SignIn(ctx, email, "wrong password", attacker)
+		assert.Error(t, err, ErrCustomerLoginFailed)
+	}
+
+	// Spreading guesses over many accounts does not get around the limit of
+	// the address, but other addresses are not affected.
+	_, err := f.auth.SignIn(ctx, testEmail, testPassword, attacker)
+	assert.Error(t, err, ErrTooManyAttempts)
+	_, err = f.auth.SignIn(ctx, testEmail, testPassword, domain.ClientInfo{IP: "198.51.100.4", UserAgent: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, metrics.sum(t, telemetry.MetricLoginLockoutsTotal), int64(1))
+}
+
+func TestRejectPassword_DummyHash(t *testing.T) {
+	hash, err := dummyPasswordHash()
+	assert.NoError(t, err)
+	ok, err := crypto.ValidatePassword(testPassword, hash)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
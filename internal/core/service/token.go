@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// tokenRepository stores one-time tokens. Like sessions, tokens are looked up
+// by their ID; the secret part of a token is never stored.
+type tokenRepository interface {
+	Insert(ctx context.Context, token *domain.OneTimeToken) error
+	GetByID(ctx context.Context, id string) (*domain.OneTimeToken, error)
+	// MarkUsed marks an unused token as used at a point in time. It returns
+	// domain.ErrTokenUsed if the token was already used, so that a token
+	// presented twice at the same time is only accepted once.
+	MarkUsed(ctx context.Context, id string, at time.Time) error
+	// RevokeByCustomer marks every unused token of a customer for a purpose
+	// as used.
+	RevokeByCustomer(
+		ctx context.Context,
+		customerID string,
+		purpose domain.TokenPurpose,
+		at time.Time,
+	) error
+	// CountSince counts the tokens issued to a customer for a purpose since a
+	// point in time.
+	CountSince(
+		ctx context.Context,
+		customerID string,
+		purpose domain.TokenPurpose,
+		since time.Time,
+	) (int, error)
+}
+
+// tokenLink appends a one-time token to the URL of the frontend page that
+// consumes it, as the `token` query parameter.
+func tokenLink(linkURL string, token *domain.OneTimeToken) (string, error) {
+	link, err := url.Parse(linkURL)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid token link URL")
+	}
+	q := link.Query()
+	q.Set("token", token.Token)
+	link.RawQuery = q.Encode()
+	return link.String(), nil
+}
+
+// checkTokenRate returns ErrTooManyRequests if a customer was issued a token
+// for a purpose within the last interval, or was issued maxPerDay tokens for
+// it within the last day.
+func checkTokenRate(
+	ctx context.Context,
+	tokens tokenRepository,
+	customerID string,
+	purpose domain.TokenPurpose,
+	interval time.Duration,
+	maxPerDay int,
+) error {
+	now := time.Now().UTC()
+	recent, err := tokens.CountSince(ctx, customerID, purpose, now.Add(-interval))
+	if err != nil {
+		return errors.Wrap(err, "failed to count tokens")
+	}
+	const day = 24 * time.Hour
+	today, err := tokens.CountSince(ctx, customerID, purpose, now.Add(-day))
+	if err != nil {
+		return errors.Wrap(err, "failed to count tokens")
+	}
+	if recent > 0 || today >= maxPerDay {
+		return ErrTooManyRequests
+	}
+	return nil
+}
+
+// findToken looks up a one-time token and verifies that it may be used for a
+// purpose at a point in time. If the token exists but cannot be used, it is
+// returned along with the error, so that the failure can be attributed to
+// its customer.
+func findToken(
+	ctx context.Context,
+	tokens tokenRepository,
+	rawToken string,
+	purpose domain.TokenPurpose,
+	at time.Time,
+) (*domain.OneTimeToken, error) {
+	id, secret, err := domain.ParseOneTimeToken(rawToken)
+	if err != nil {
+		return nil, err
+	}
+	token, err := tokens.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = token.Verify(secret, purpose, at)
+	if err != nil {
+		return token, err
+	}
+	return token, nil
+}
+
+// tokenUserID returns the ID of the customer a token was issued to, if there
+// is a token.
+func tokenUserID(token *domain.OneTimeToken) string {
+	if token == nil {
+		return ""
+	}
+	return token.UserID
+}
+
+// isRepositoryError reports whether a token error is not one of the expected
+// ways a token is rejected.
+func isRepositoryError(err error) bool {
+	return tokenFailureReason(err) == "repository_error"
+}
+
+// errEmailChanged is returned when the email address a token was sent to is
+// no longer the customer's address.
+var errEmailChanged = errors.New("email changed")
+
+// tokenFailureReason maps a token error to an audit reason.
+func tokenFailureReason(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrTokenUsed):
+		return "token_used"
+	case errors.Is(err, domain.ErrTokenExpired):
+		return "token_expired"
+	case errors.Is(err, domain.ErrTokenNotFound):
+		return "unknown_token"
+	case errors.Is(err, domain.ErrCustomerNotFound):
+		return "unknown_customer"
+	case errors.Is(err, errEmailChanged):
+		return "email_changed"
+	case errors.Is(err, domain.ErrInvalidToken):
+		return "invalid_token"
+	default:
+		return "repository_error"
+	}
+}
+
+// publicTokenError maps a token error to the error shown to the customer.
+// Used and expired tokens are told apart, so the customer knows to ask for a
+// new link; every other failure looks the same.
+func publicTokenError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrTokenUsed), errors.Is(err, domain.ErrTokenExpired):
+		return err
+	default:
+		return domain.ErrInvalidToken
+	}
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/errors"
+)
+
+// EmailVerificationPolicy configures email verification.
+type EmailVerificationPolicy struct {
+	// TokenTTL is how long a verification link stays valid.
+	TokenTTL time.Duration
+	// ResendInterval is how long a customer must wait before asking for
+	// another verification email.
+	ResendInterval time.Duration
+	// MaxPerDay is how many verification emails a customer can be sent in a
+	// day.
+	MaxPerDay int
+	// LinkURL is the URL of the frontend page that confirms the token. The
+	// token is appended as the `token` query parameter.
+	LinkURL string
+}
+
+// DefaultEmailVerificationPolicy returns a policy where verification links
+// last a day, and a customer can ask for one a minute, five a day.
+func DefaultEmailVerificationPolicy(linkURL string) EmailVerificationPolicy {
+	return EmailVerificationPolicy{
+		TokenTTL:       24 * time.Hour,
+		ResendInterval: time.Minute,
+		MaxPerDay:      5,
+		LinkURL:        linkURL,
+	}
+}
+
+// EmailVerificationService verifies that customers own the email address of
+// their account. Customers with an unverified email address cannot make
+// purchases.
+type EmailVerificationService struct {
+	*ServiceBase
+	customers customerRepository
+	tokens    tokenRepository
+	mailer    Mailer
+	policy    EmailVerificationPolicy
+	audit     auditRecorder
+}
+
+// NewEmailVerificationService creates a new EmailVerificationService.
+func NewEmailVerificationService(
+	svcBase *ServiceBase,
+	customers customerRepository,
+	tokens tokenRepository,
+	mailer Mailer,
+	policy EmailVerificationPolicy,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		ServiceBase: svcBase,
+		customers:   customers,
+		tokens:      tokens,
+		mailer:      mailer,
+		policy:      policy,
+		audit:       svcBase.auditRecorder(),
+	}
+}
+
+// Send sends a verification email to a customer's current email address.
+// Links sent earlier stop working, so that only the latest email verifies
+// the address.
+func (e *EmailVerificationService) Send(ctx context.Context, customer *domain.Customer) error {
+	now := time.Now().UTC()
+	err := e.tokens.RevokeByCustomer(ctx, customer.ID, domain.TokenPurposeEmailVerification, now)
+	if err != nil {
+		return errors.Wrap(err, "failed to revoke verification tokens")
+	}
+
+	token, err := domain.NewOneTimeToken(
+		customer.ID, domain.TokenPurposeEmailVerification, customer.Email, e.policy.TokenTTL,
+	)
+	if err != nil {
+		return err
+	}
+	err = e.tokens.Insert(ctx, token)
+	if err != nil {
+		return errors.Wrap(err, "failed to store verification token")
+	}
+
+	link, err := tokenLink(e.policy.LinkURL, token)
+	if err != nil {
+		return err
+	}
+
+	err = e.mailer.Send(ctx, domain.EmailMessage{
+		To:       customer.Email,
+		Template: domain.EmailTemplateEmailVerification,
+		Data:     map[string]any{"Link": link, "ExpiresIn": e.policy.TokenTTL},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to send verification email")
+	}
+	return nil
+}
+
+// Resend sends another verification email to the customer a session belongs
+// to. Customers can only ask for a limited number of emails, so that the
+// backend cannot be used to flood an inbox.
+func (e *EmailVerificationService) Resend(ctx context.Context, session *domain.UserSession) error {
+	customer, err := e.customers.GetByID(ctx, session.UserID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get customer")
+	}
+	if customer.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+
+	err = checkTokenRate(
+		ctx, e.tokens, customer.ID, domain.TokenPurposeEmailVerification,
+		e.policy.ResendInterval, e.policy.MaxPerDay,
+	)
+	if err != nil {
+		return err
+	}
+
+	return e.Send(ctx, customer)
+}
+
+// Confirm verifies the email address a token was sent to. The token is only
+// accepted if the address is still the customer's address.
+func (e *EmailVerificationService) Confirm(ctx context.Context, rawToken string) (*domain.Customer, error) {
+	fail := func(customerID string, err error) error {
+		e.audit.Record(ctx, domain.NewAuditEvent(
+			domain.AuditActionEmailVerification, domain.AuditOutcomeFailure, customerID, tokenFailureReason(err),
+		))
+		return publicTokenError(err)
+	}
+
+	now := time.Now().UTC()
+	token, err := findToken(ctx, e.tokens, rawToken, domain.TokenPurposeEmailVerification, now)
+	if err != nil {
+		if isRepositoryError(err) {
+			e.logger.Error("failed to get verification token", "error", err)
+		}
+		return nil, fail(tokenUserID(token), err)
+	}
+
+	customer, err := e.customers.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, fail(token.UserID, err)
+	}
+	if customer.Email != token.Email {
+		return nil, fail(customer.ID, errEmailChanged)
+	}
+
+	err = e.tokens.MarkUsed(ctx, token.ID, now)
+	if err != nil {
+		return nil, fail(token.UserID, err)
+	}
+
+	customer.EmailVerified = true
+	err = e.customers.UpdateInformation(ctx, customer)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to verify email")
+	}
+
+	e.audit.Record(ctx, domain.NewAuditEvent(
+		domain.AuditActionEmailVerification, domain.AuditOutcomeSuccess, customer.ID, "",
+	))
+	return customer, nil
+}
+
+var (
+	ErrEmailAlreadyVerified = errors.New("email already verified")
+	ErrEmailNotVerified     = errors.New("email not verified")
+	ErrTooManyRequests      = errors.New("too many requests")
+)
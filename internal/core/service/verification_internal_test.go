@@ -0,0 +1,263 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+// verificationToken extracts the token from the link in the last email sent.
+func verificationToken(t *testing.T, mailer *recordingMailer) string {
+	t.Helper()
+	return linkToken(t, mailer.last())
+}
+
+// linkToken extracts the token from the link in an email.
+func linkToken(t *testing.T, msg domain.EmailMessage) string {
+	t.Helper()
+	link, ok := msg.Data["Link"].(string)
+	if !ok {
+		t.Fatal("no link in email")
+	}
+	u, err := url.Parse(link)
+	assert.NoError(t, err)
+	return u.Query().Get("token")
+}
+
+func TestEmailVerificationService_Confirm(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	assert.False(t, result.Customer.EmailVerified)
+	assert.Equal(t, f.mailer.count(), 1)
+	assert.Equal(t, f.mailer.last().To, testEmail)
+
+	token := verificationToken(t, f.mailer)
+	customer, err := f.verification.Confirm(ctx, token)
+	assert.NoError(t, err)
+	assert.True(t, customer.EmailVerified)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionEmailVerification)
+	assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeSuccess)
+
+	stored, err := f.customers.GetByID(ctx, result.Customer.ID)
+	assert.NoError(t, err)
+	assert.True(t, stored.EmailVerified)
+
+	// A token can only be used once.
+	_, err = f.verification.Confirm(ctx, token)
+	assert.Error(t, err, domain.ErrTokenUsed)
+	assert.Equal(t, f.audit.last().Reason, "token_used")
+}
+
+func TestEmailVerificationService_ConfirmFailures(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		test.CaseBase
+		// tamper returns the token to confirm, given the token that was sent.
+		tamper func(*testing.T, authFixture, *domain.Customer, string) string
+		reason string
+	}{
+		{
+			CaseBase: test.NewCaseBase("malformed", domain.ErrInvalidToken, true),
+			tamper: func(*testing.T, authFixture, *domain.Customer, string) string {
+				return "not-a-token"
+			},
+			reason: "invalid_token",
+		},
+		{
+			CaseBase: test.NewCaseBase("wrong secret", domain.ErrInvalidToken, true),
+			tamper: func(_ *testing.T, _ authFixture, _ *domain.Customer, token string) string {
+				id, _, _ := strings.Cut(token, ".")
+				return id + ".forged"
+			},
+			reason: "invalid_token",
+		},
+		{
+			CaseBase: test.NewCaseBase("expired", domain.ErrTokenExpired, true),
+			tamper: func(t *testing.T, f authFixture, _ *domain.Customer, token string) string {
+				t.Helper()
+				id, _, _ := strings.Cut(token, ".")
+				stored, err := f.tokens.GetByID(context.Background(), id)
+				assert.NoError(t, err)
+				stored.ExpiresAt = time.Now().Add(-time.Minute)
+				assert.NoError(t, f.tokens.Insert(context.Background(), stored))
+				return token
+			},
+			reason: "token_expired",
+		},
+		{
+			CaseBase: test.NewCaseBase("email changed", domain.ErrInvalidToken, true),
+			tamper: func(t *testing.T, f authFixture, customer *domain.Customer, token string) string {
+				t.Helper()
+				customer.Email = "new@brokedaear.com"
+				assert.NoError(t, f.customers.UpdateInformation(ctx, customer))
+				return token
+			},
+			reason: "email_changed",
+		},
+		{
+			CaseBase: test.NewCaseBase("superseded", domain.ErrTokenUsed, true),
+			tamper: func(t *testing.T, f authFixture, customer *domain.Customer, token string) string {
+				t.Helper()
+				assert.NoError(t, f.verification.Send(context.Background(), customer))
+				return token
+			},
+			reason: "token_used",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				f := newAuthFixture(t)
+				result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+				assert.NoError(t, err)
+
+				token := tt.tamper(t, f, result.Customer, verificationToken(t, f.mailer))
+				_, err = f.verification.Confirm(ctx, token)
+				assert.Error(t, err, tt.Want.(error))
+				assert.Equal(t, f.audit.last().Reason, tt.reason)
+
+				stored, err := f.customers.GetByID(ctx, result.Customer.ID)
+				assert.NoError(t, err)
+				assert.False(t, stored.EmailVerified)
+			},
+		)
+	}
+}
+
+func TestEmailVerificationService_Resend(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	f.verification.policy.ResendInterval = 0
+	f.verification.policy.MaxPerDay = 3
+
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	// Sign up sent the first email of the day.
+	for range 2 {
+		err = f.verification.Resend(ctx, result.Session)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, f.mailer.count(), 3)
+
+	err = f.verification.Resend(ctx, result.Session)
+	assert.Error(t, err, ErrTooManyRequests)
+	assert.Equal(t, f.mailer.count(), 3)
+
+	// Only the latest link works.
+	_, err = f.verification.Confirm(ctx, verificationToken(t, f.mailer))
+	assert.NoError(t, err)
+
+	err = f.verification.Resend(ctx, result.Session)
+	assert.Error(t, err, ErrEmailAlreadyVerified)
+}
+
+func TestEmailVerificationService_ResendInterval(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	err = f.verification.Resend(ctx, result.Session)
+	assert.Error(t, err, ErrTooManyRequests)
+}
+
+func TestCustomerService_UpdateInformationReverifiesEmail(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	customers := NewCustomerService(NewServiceBase(test.NewMockLogger(), nil), f.customers, f.sessions, f.verification)
+
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+	_, err = f.verification.Confirm(ctx, verificationToken(t, f.mailer))
+	assert.NoError(t, err)
+
+	// Saving the same address keeps it verified.
+	customer, err := f.customers.GetByID(ctx, result.Customer.ID)
+	assert.NoError(t, err)
+	customer.EmailVerified = false
+	updated, err := customers.UpdateInformation(ctx, customer)
+	assert.NoError(t, err)
+	assert.True(t, updated.EmailVerified)
+	assert.Equal(t, f.mailer.count(), 1)
+
+	// A new address is no longer verified, whatever the client says.
+	customer.Email = "kai.new@brokedaear.com"
+	customer.EmailVerified = true
+	updated, err = customers.UpdateInformation(ctx, customer)
+	assert.NoError(t, err)
+	assert.False(t, updated.EmailVerified)
+	assert.Equal(t, f.mailer.count(), 2)
+	assert.Equal(t, f.mailer.last().To, "kai.new@brokedaear.com")
+
+	_, err = f.verification.Confirm(ctx, verificationToken(t, f.mailer))
+	assert.NoError(t, err)
+}
+
+type fakePaymentProcessor struct {
+	mu       sync.Mutex
+	paid     []*domain.Order
+	refunded []*domain.Order
+}
+
+func (f *fakePaymentProcessor) Pay(_ context.Context, order *domain.Order) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.paid = append(f.paid, order)
+	return nil
+}
+
+func (f *fakePaymentProcessor) Refund(_ context.Context, order *domain.Order) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refunded = append(f.refunded, order)
+	return nil
+}
+
+func TestWebshopService_PurchaseRequiresVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	processor := &fakePaymentProcessor{}
+	shop := NewWebshopService(
+		NewServiceBase(test.NewMockLogger(), nil),
+		processor,
+		f.customers,
+		memory.NewOrderRepository(),
+		memory.NewDownloadRepository(),
+		f.authz,
+	)
+
+	result, err := f.auth.SignUp(ctx, testEmail, testPassword, domain.ClientInfo{})
+	assert.NoError(t, err)
+
+	err = shop.Purchase(ctx, result.Session, &domain.Order{})
+	assert.Error(t, err, ErrEmailNotVerified)
+	assert.Equal(t, len(processor.paid), 0)
+
+	_, err = f.verification.Confirm(ctx, verificationToken(t, f.mailer))
+	assert.NoError(t, err)
+
+	order := &domain.Order{}
+	err = shop.Purchase(ctx, result.Session, order)
+	assert.NoError(t, err)
+	assert.Equal(t, len(processor.paid), 1)
+	assert.Equal(t, order.UserID, result.Customer.ID)
+}
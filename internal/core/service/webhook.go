@@ -0,0 +1,904 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	otelmetric "go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// webhookEndpointRepository stores the endpoints registered for webhooks.
+type webhookEndpointRepository interface {
+	Insert(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	GetByID(ctx context.Context, id string) (*domain.WebhookEndpoint, error)
+	// List retrieves every endpoint, oldest first.
+	List(ctx context.Context) ([]*domain.WebhookEndpoint, error)
+	// ListSubscribed retrieves the enabled endpoints that subscribe to a
+	// type of event.
+	ListSubscribed(ctx context.Context, typ domain.EventType) ([]*domain.WebhookEndpoint, error)
+	Update(ctx context.Context, endpoint *domain.WebhookEndpoint) error
+	Delete(ctx context.Context, id string) error
+	// RecordSuccess forgets the failures of an endpoint.
+	RecordSuccess(ctx context.Context, id string) error
+	// RecordFailure counts a failed attempt at a point in time, and returns
+	// the endpoint with its failures counted.
+	RecordFailure(ctx context.Context, id string, at time.Time) (*domain.WebhookEndpoint, error)
+}
+
+// webhookDeliveryRepository stores deliveries and the logs of their
+// attempts.
+type webhookDeliveryRepository interface {
+	// Insert adds a new delivery, unless the event was already queued for
+	// the endpoint.
+	Insert(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetByID(ctx context.Context, id string) (*domain.WebhookDelivery, error)
+	// ListByEndpoint retrieves up to limit deliveries to an endpoint, most
+	// recent first.
+	ListByEndpoint(ctx context.Context, endpointID string, limit int) ([]*domain.WebhookDelivery, error)
+	// ClaimDue returns up to limit pending deliveries that are due at a
+	// point in time, and pushes their next attempt back by lease, so that
+	// no other dispatcher claims them while they are delivered.
+	ClaimDue(ctx context.Context, at time.Time, limit int, lease time.Duration) ([]*domain.WebhookDelivery, error)
+	// MarkDelivered marks a delivery as accepted by its endpoint. leasedUntil
+	// is the next attempt ClaimDue set: if the delivery was claimed again
+	// or redelivered since, it is left alone and domain.ErrWebhookLeaseExpired
+	// is returned.
+	MarkDelivered(ctx context.Context, id string, leasedUntil, at time.Time) error
+	// MarkFailed records a failed attempt, under the same lease as
+	// MarkDelivered. A nil next attempt marks the delivery as dead, and it is
+	// never attempted again unless redelivered.
+	MarkFailed(
+		ctx context.Context,
+		id string,
+		leasedUntil time.Time,
+		attempts int,
+		next *time.Time,
+		lastError string,
+	) error
+	// Redeliver makes a delivery pending again, due at a point in time,
+	// with its attempts reset.
+	Redeliver(ctx context.Context, id string, at time.Time) error
+	// InsertAttempt logs an attempt at a delivery, and numbers it after the
+	// earlier attempts.
+	InsertAttempt(ctx context.Context, attempt *domain.WebhookAttempt) error
+	// ListAttempts retrieves the attempts at a delivery, in order.
+	ListAttempts(ctx context.Context, deliveryID string) ([]*domain.WebhookAttempt, error)
+}
+
+const (
+	// DefaultWebhookDeliveryLimit is the number of deliveries a listing
+	// returns when it sets no limit.
+	DefaultWebhookDeliveryLimit = 50
+	// MaxWebhookDeliveryLimit is the largest number of deliveries a listing
+	// returns.
+	MaxWebhookDeliveryLimit = 500
+
+	// webhookSecretPrefix makes webhook secrets recognizable, such as by
+	// secret scanners.
+	webhookSecretPrefix = "whsec_"
+	// webhookUserAgent is the user agent deliveries are sent with.
+	webhookUserAgent = "BROKE-DA-EAR-Webhooks/1.0"
+)
+
+// WebhookPolicy configures how webhooks are delivered.
+type WebhookPolicy struct {
+	// EncryptionKey is the AES-256 key that endpoint secrets are encrypted
+	// with at rest. It comes from configuration, see
+	// crypto.ParseEncryptionKey.
+	EncryptionKey []byte
+	// Delivery configures retries. A delivery is given up on after
+	// Delivery.MaxAttempts attempts. Deliveries of a batch are attempted one
+	// after the other, so Delivery.Lease must be longer than
+	// Delivery.BatchSize times Timeout.
+	Delivery DeliveryPolicy
+	// Timeout is how long an endpoint has to answer.
+	Timeout time.Duration
+	// MaxLoggedBody is how many bytes of each response are logged.
+	MaxLoggedBody int64
+	// DisableAfterFailures and DisableAfter decide when an endpoint is
+	// disabled: once at least this many attempts in a row failed, over at
+	// least this long. Requiring both keeps a burst of failures, or a single
+	// failure a day, from disabling a working endpoint.
+	DisableAfterFailures int
+	DisableAfter         time.Duration
+}
+
+// DefaultWebhookPolicy returns a policy that retries a failed delivery for
+// about eight hours, gives endpoints 10 seconds to answer, and disables
+// endpoints that failed 20 times in a row over at least a day. Deliveries
+// are leased for as long as a batch of them takes when every endpoint times
+// out, and a minute more.
+func DefaultWebhookPolicy(encryptionKey []byte) WebhookPolicy {
+	const timeout = 10 * time.Second
+	delivery := DefaultDeliveryPolicy()
+	delivery.Lease = time.Duration(delivery.BatchSize)*timeout + time.Minute
+	return WebhookPolicy{
+		EncryptionKey:        encryptionKey,
+		Delivery:             delivery,
+		Timeout:              timeout,
+		MaxLoggedBody:        4096,
+		DisableAfterFailures: 20,
+		DisableAfter:         24 * time.Hour,
+	}
+}
+
+// shouldDisable reports whether an endpoint failed for long enough, at a
+// point in time, to be disabled.
+func (p WebhookPolicy) shouldDisable(endpoint *domain.WebhookEndpoint, at time.Time) bool {
+	return endpoint.Enabled &&
+		endpoint.FailingSince != nil &&
+		endpoint.ConsecutiveFailures >= p.DisableAfterFailures &&
+		at.Sub(*endpoint.FailingSince) >= p.DisableAfter
+}
+
+// WebhookService lets admins register endpoints that hear about domain
+// events, such as a chat bot announcing sales, and browse what was
+// delivered to them. Every method acts on behalf of a principal who must be
+// allowed to manage webhooks, and every action is recorded as an audit
+// event. Events reach the endpoints once the service is subscribed to an
+// EventRelay, and are delivered by a WebhookDispatcher.
+type WebhookService struct {
+	*ServiceBase
+	endpoints  webhookEndpointRepository
+	deliveries webhookDeliveryRepository
+	authz      *AuthorizationService
+	policy     WebhookPolicy
+	audit      auditRecorder
+}
+
+// NewWebhookService creates a new WebhookService. It returns
+// crypto.ErrInvalidKey if the encryption key of the policy is not an
+// AES-256 key.
+func NewWebhookService(
+	svcBase *ServiceBase,
+	endpoints webhookEndpointRepository,
+	deliveries webhookDeliveryRepository,
+	authz *AuthorizationService,
+	policy WebhookPolicy,
+) (*WebhookService, error) {
+	if len(policy.EncryptionKey) != crypto.EncryptionKeyLength {
+		return nil, crypto.ErrInvalidKey
+	}
+	return &WebhookService{
+		ServiceBase: svcBase,
+		endpoints:   endpoints,
+		deliveries:  deliveries,
+		authz:       authz,
+		policy:      policy,
+		audit:       svcBase.auditRecorder(),
+	}, nil
+}
+
+// Subscribe makes the service queue every event the relay publishes for the
+// endpoints that subscribe to its type.
+func (w *WebhookService) Subscribe(relay *EventRelay) {
+	for _, typ := range domain.EventTypes() {
+		relay.Subscribe(typ, "webhooks", w.enqueue)
+	}
+}
+
+// enqueue queues an event for every enabled endpoint that subscribes to its
+// type. Events handed over twice are only queued once.
+func (w *WebhookService) enqueue(ctx context.Context, event *domain.Event) error {
+	endpoints, err := w.endpoints.ListSubscribed(ctx, event.Type)
+	if err != nil {
+		return errors.Wrap(err, "failed to list webhook endpoints")
+	}
+	for _, endpoint := range endpoints {
+		delivery, err := domain.NewWebhookDelivery(endpoint, event)
+		if err != nil {
+			return err
+		}
+		err = w.deliveries.Insert(ctx, delivery)
+		if err != nil {
+			return errors.Wrap(err, "failed to queue webhook delivery")
+		}
+	}
+	return nil
+}
+
+// CreateEndpoint registers an endpoint for some types of events. It returns
+// the endpoint along with its signing secret, which is never shown again.
+func (w *WebhookService) CreateEndpoint(
+	ctx context.Context,
+	principal *domain.Principal,
+	rawURL, description string,
+	eventTypes []domain.EventType,
+) (*domain.WebhookEndpoint, string, error) {
+	action := domain.AuditActionWebhookCreate
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return nil, "", err
+	}
+	endpoint, err := domain.NewWebhookEndpoint(rawURL, description, eventTypes)
+	if err != nil {
+		return nil, "", w.fail(ctx, principal, action, domain.AuditTargetNone, "", err)
+	}
+	secret, err := w.newSecret(endpoint)
+	if err != nil {
+		return nil, "", w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, endpoint.ID, err)
+	}
+	err = w.endpoints.Insert(ctx, endpoint)
+	if err != nil {
+		return nil, "", w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, endpoint.ID, err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetWebhookEndpoint, endpoint.ID, "")
+	return endpoint, secret, nil
+}
+
+// Endpoints returns every registered endpoint, oldest first.
+func (w *WebhookService) Endpoints(
+	ctx context.Context,
+	principal *domain.Principal,
+) ([]*domain.WebhookEndpoint, error) {
+	action := domain.AuditActionWebhookView
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := w.endpoints.List(ctx)
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetNone, "", err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetNone, "", "")
+	return endpoints, nil
+}
+
+// UpdateEndpoint changes an endpoint, and enables or disables it. Enabling
+// an endpoint forgets its failures, but deliveries given up on while it was
+// disabled must be redelivered one by one.
+func (w *WebhookService) UpdateEndpoint(
+	ctx context.Context,
+	principal *domain.Principal,
+	id, rawURL, description string,
+	eventTypes []domain.EventType,
+	enabled bool,
+) (*domain.WebhookEndpoint, error) {
+	action := domain.AuditActionWebhookUpdate
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return nil, err
+	}
+	endpoint, err := w.endpoints.GetByID(ctx, id)
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, id, err)
+	}
+	err = endpoint.Update(rawURL, description, eventTypes)
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, id, err)
+	}
+	switch {
+	case enabled && !endpoint.Enabled:
+		endpoint.Enable()
+	case !enabled && endpoint.Enabled:
+		endpoint.Disable("disabled by an admin", time.Now().UTC())
+	}
+	err = w.endpoints.Update(ctx, endpoint)
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, id, err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetWebhookEndpoint, id, "")
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes an endpoint along with its deliveries.
+func (w *WebhookService) DeleteEndpoint(ctx context.Context, principal *domain.Principal, id string) error {
+	action := domain.AuditActionWebhookDelete
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return err
+	}
+	err = w.endpoints.Delete(ctx, id)
+	if err != nil {
+		return w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, id, err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetWebhookEndpoint, id, "")
+	return nil
+}
+
+// RotateSecret replaces the signing secret of an endpoint, such as after it
+// leaked, and returns the new secret. Deliveries are signed with the new
+// secret right away, including retries of earlier ones.
+func (w *WebhookService) RotateSecret(ctx context.Context, principal *domain.Principal, id string) (string, error) {
+	action := domain.AuditActionWebhookRotateSecret
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return "", err
+	}
+	endpoint, err := w.endpoints.GetByID(ctx, id)
+	if err != nil {
+		return "", w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, id, err)
+	}
+	secret, err := w.newSecret(endpoint)
+	if err != nil {
+		return "", w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, id, err)
+	}
+	endpoint.UpdatedAt = time.Now().UTC()
+	err = w.endpoints.Update(ctx, endpoint)
+	if err != nil {
+		return "", w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, id, err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetWebhookEndpoint, id, "")
+	return secret, nil
+}
+
+// Deliveries returns the deliveries to an endpoint, most recent first. It
+// returns DefaultWebhookDeliveryLimit deliveries if limit is not positive,
+// and at most MaxWebhookDeliveryLimit.
+func (w *WebhookService) Deliveries(
+	ctx context.Context,
+	principal *domain.Principal,
+	endpointID string,
+	limit int,
+) ([]*domain.WebhookDelivery, error) {
+	action := domain.AuditActionWebhookView
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultWebhookDeliveryLimit
+	}
+	limit = min(limit, MaxWebhookDeliveryLimit)
+	_, err = w.endpoints.GetByID(ctx, endpointID)
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, endpointID, err)
+	}
+	deliveries, err := w.deliveries.ListByEndpoint(ctx, endpointID, limit)
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookEndpoint, endpointID, err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetWebhookEndpoint, endpointID, "")
+	return deliveries, nil
+}
+
+// Delivery returns a delivery along with the log of its attempts, in order.
+func (w *WebhookService) Delivery(
+	ctx context.Context,
+	principal *domain.Principal,
+	id string,
+) (*domain.WebhookDelivery, []*domain.WebhookAttempt, error) {
+	action := domain.AuditActionWebhookView
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return nil, nil, err
+	}
+	delivery, err := w.deliveries.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookDelivery, id, err)
+	}
+	attempts, err := w.deliveries.ListAttempts(ctx, id)
+	if err != nil {
+		return nil, nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookDelivery, id, err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetWebhookDelivery, id, "")
+	return delivery, attempts, nil
+}
+
+// Redeliver attempts a delivery again right away, whatever its status,
+// such as once the endpoint was fixed. The delivery gets a fresh round of
+// retries.
+func (w *WebhookService) Redeliver(
+	ctx context.Context,
+	principal *domain.Principal,
+	id string,
+) (*domain.WebhookDelivery, error) {
+	action := domain.AuditActionWebhookRedeliver
+	err := w.authorize(ctx, principal, action)
+	if err != nil {
+		return nil, err
+	}
+	err = w.deliveries.Redeliver(ctx, id, time.Now().UTC())
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookDelivery, id, err)
+	}
+	delivery, err := w.deliveries.GetByID(ctx, id)
+	if err != nil {
+		return nil, w.fail(ctx, principal, action, domain.AuditTargetWebhookDelivery, id, err)
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeSuccess, domain.AuditTargetWebhookDelivery, id, "")
+	return delivery, nil
+}
+
+// newSecret generates a signing secret for an endpoint and sets it,
+// encrypted, on the endpoint.
+func (w *WebhookService) newSecret(endpoint *domain.WebhookEndpoint) (string, error) {
+	token, err := crypto.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	secret := webhookSecretPrefix + token
+	endpoint.EncryptedSecret, err = crypto.Encrypt(w.policy.EncryptionKey, []byte(secret), []byte(endpoint.ID))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encrypt webhook secret")
+	}
+	return secret, nil
+}
+
+// authorize authorizes managing webhooks and records a failed audit event
+// if the principal may not.
+func (w *WebhookService) authorize(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+) error {
+	err := w.authz.Authorize(ctx, principal, domain.AccessRequest{
+		Permission: domain.PermissionWebhooksManage,
+		OwnerID:    "",
+		Amount:     0,
+	})
+	if err != nil {
+		w.record(ctx, principal, action, domain.AuditOutcomeFailure, domain.AuditTargetNone, "", "forbidden")
+		return err
+	}
+	return nil
+}
+
+// fail records a failed audit event for an error and returns the error.
+func (w *WebhookService) fail(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	target domain.AuditTargetType,
+	targetID string,
+	err error,
+) error {
+	reason := "repository_error"
+	switch {
+	case errors.Is(err, domain.ErrWebhookEndpointNotFound):
+		reason = "endpoint_not_found"
+	case errors.Is(err, domain.ErrWebhookDeliveryNotFound):
+		reason = "delivery_not_found"
+	case errors.Is(err, domain.ErrInvalidWebhookURL), errors.Is(err, domain.ErrInvalidWebhookEventTypes):
+		reason = "invalid_endpoint"
+	}
+	w.record(ctx, principal, action, domain.AuditOutcomeFailure, target, targetID, reason)
+	return err
+}
+
+func (w *WebhookService) record(
+	ctx context.Context,
+	principal *domain.Principal,
+	action domain.AuditAction,
+	outcome domain.AuditOutcome,
+	target domain.AuditTargetType,
+	targetID, reason string,
+) {
+	event := domain.NewActorAuditEvent(action, outcome, actorOf(principal), "", reason)
+	w.audit.Record(ctx, event.WithTarget(target, targetID))
+}
+
+// WebhookDispatcher delivers queued events to webhook endpoints in the
+// background. Each delivery is a POST request signed with the secret of the
+// endpoint, see domain.SignWebhook, and succeeds if the endpoint answers
+// with a 2xx status; redirects are not followed, and only public addresses
+// are connected to, see domain.IsPublicWebhookAddr. Failed deliveries are
+// retried with exponential backoff, and endpoints that keep failing are
+// disabled. Every attempt is logged along with the response.
+//
+// WebhookDispatcher implements io.Closer, so it can take part in a global
+// teardown.
+type WebhookDispatcher struct {
+	*ServiceBase
+	endpoints  webhookEndpointRepository
+	deliveries webhookDeliveryRepository
+	client     *http.Client
+	policy     WebhookPolicy
+	metrics    *webhookMetrics
+	// allowAddr reports whether an endpoint may be connected to at an
+	// address.
+	allowAddr func(addr netip.Addr) bool
+	stop      chan struct{}
+	done      chan struct{}
+	once      sync.Once
+}
+
+// NewWebhookDispatcher creates a new WebhookDispatcher that sends requests
+// with a client. Delivery metrics are recorded if the service base has
+// telemetry. It returns crypto.ErrInvalidKey if the encryption key of the
+// policy is not an AES-256 key, ErrWebhookLeaseTooShort if a batch of
+// deliveries could outlast their lease, and ErrWebhookTransportUnsupported
+// if the transport of the client is not an *http.Transport.
+func NewWebhookDispatcher(
+	svcBase *ServiceBase,
+	endpoints webhookEndpointRepository,
+	deliveries webhookDeliveryRepository,
+	client *http.Client,
+	policy WebhookPolicy,
+) (*WebhookDispatcher, error) {
+	if len(policy.EncryptionKey) != crypto.EncryptionKeyLength {
+		return nil, crypto.ErrInvalidKey
+	}
+	if policy.Delivery.Lease <= time.Duration(policy.Delivery.BatchSize)*policy.Timeout {
+		return nil, ErrWebhookLeaseTooShort
+	}
+	metrics, err := newWebhookMetrics(svcBase.tel)
+	if err != nil {
+		return nil, err
+	}
+	d := &WebhookDispatcher{
+		ServiceBase: svcBase,
+		endpoints:   endpoints,
+		deliveries:  deliveries,
+		client:      nil,
+		policy:      policy,
+		metrics:     metrics,
+		allowAddr:   domain.IsPublicWebhookAddr,
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+		once:        sync.Once{},
+	}
+	transport, err := d.guardTransport(client.Transport)
+	if err != nil {
+		return nil, err
+	}
+	guarded := *client
+	guarded.Transport = transport
+	guarded.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	d.client = &guarded
+	return d, nil
+}
+
+// guardTransport returns a copy of a transport, or of the default one if it
+// is nil, that refuses to connect to addresses d.allowAddr does not allow.
+// The address is checked once the host is resolved, right before
+// connecting, so a host name that resolves or is rebound to an address on
+// our network is refused too. Proxies are not used, since the address of
+// the proxy would be checked instead of the address of the endpoint.
+func (d *WebhookDispatcher) guardTransport(rt http.RoundTripper) (*http.Transport, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, ErrWebhookTransportUnsupported
+	}
+	dialer := &net.Dialer{
+		Timeout: d.policy.Timeout,
+		Control: d.checkAddr,
+	}
+	transport := base.Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	transport.DialTLSContext = nil
+	return transport, nil
+}
+
+// checkAddr refuses to connect to an address d.allowAddr does not allow. It
+// is a net.Dialer control function.
+func (d *WebhookDispatcher) checkAddr(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse webhook address")
+	}
+	if !d.allowAddr(addrPort.Addr()) {
+		return errors.Wrapf(ErrWebhookAddressNotPublic, "%s", addrPort.Addr())
+	}
+	return nil
+}
+
+// Start starts delivering in the background until the context is cancelled
+// or the dispatcher is closed.
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.policy.Delivery.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				_, err := d.Dispatch(ctx)
+				if err != nil {
+					d.logger.Error("failed to dispatch webhooks", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops the dispatcher and waits for it to finish. Close is safe to
+// call more than once, but the dispatcher must have been started.
+func (d *WebhookDispatcher) Close() error {
+	d.once.Do(func() { close(d.stop) })
+	<-d.done
+	return nil
+}
+
+// Dispatch attempts one batch of due deliveries, and returns how many were
+// delivered.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context) (int, error) {
+	deliveries, err := d.deliveries.ClaimDue(
+		ctx, time.Now().UTC(), d.policy.Delivery.BatchSize, d.policy.Delivery.Lease,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to claim webhook deliveries")
+	}
+	delivered := 0
+	for _, delivery := range deliveries {
+		if d.deliver(ctx, delivery) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// deliver attempts a delivery and records the outcome. It reports whether
+// the endpoint accepted it.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *domain.WebhookDelivery) bool {
+	endpoint, err := d.endpoints.GetByID(ctx, delivery.EndpointID)
+	if err != nil {
+		// The delivery will be attempted again once its lease runs out.
+		d.logger.Error("failed to get webhook endpoint", "delivery_id", delivery.ID, "error", err)
+		return false
+	}
+	if !endpoint.Enabled {
+		d.metrics.recordDead(ctx, delivery.EventType)
+		err = d.deliveries.MarkFailed(
+			ctx, delivery.ID, delivery.NextAttemptAt, delivery.Attempts, nil, "endpoint disabled",
+		)
+		if err != nil {
+			d.logMarkError("failed to mark webhook delivery failed", delivery, err)
+		}
+		return false
+	}
+
+	attempt, err := d.attempt(ctx, endpoint, delivery)
+	if err != nil {
+		d.logger.Error("failed to attempt webhook delivery", "delivery_id", delivery.ID, "error", err)
+		return false
+	}
+	d.metrics.recordDuration(ctx, delivery.EventType, attempt.Duration)
+	err = d.deliveries.InsertAttempt(ctx, attempt)
+	if err != nil {
+		d.logger.Error("failed to log webhook attempt", "delivery_id", delivery.ID, "error", err)
+	}
+
+	now := time.Now().UTC()
+	if attempt.Succeeded() {
+		d.metrics.recordDelivered(ctx, delivery.EventType)
+		err = d.deliveries.MarkDelivered(ctx, delivery.ID, delivery.NextAttemptAt, now)
+		if err != nil {
+			// The delivery will be attempted again once its lease runs out.
+			d.logMarkError("failed to mark webhook delivered", delivery, err)
+		}
+		if endpoint.ConsecutiveFailures > 0 {
+			err = d.endpoints.RecordSuccess(ctx, endpoint.ID)
+			if err != nil {
+				d.logger.Error("failed to record webhook success", "endpoint_id", endpoint.ID, "error", err)
+			}
+		}
+		return true
+	}
+
+	attempts := delivery.Attempts + 1
+	var next *time.Time
+	if attempts < d.policy.Delivery.MaxAttempts {
+		at := now.Add(d.policy.Delivery.backoff(attempts))
+		next = &at
+		d.metrics.recordFailed(ctx, delivery.EventType)
+		d.logger.Warn("failed to deliver webhook, retrying",
+			"delivery_id", delivery.ID, "endpoint_id", endpoint.ID, "attempts", attempts,
+			"next_attempt_at", at, "error", attempt.Error)
+	} else {
+		d.metrics.recordDead(ctx, delivery.EventType)
+		d.logger.Error("failed to deliver webhook, giving up",
+			"delivery_id", delivery.ID, "endpoint_id", endpoint.ID, "attempts", attempts, "error", attempt.Error)
+	}
+	err = d.deliveries.MarkFailed(ctx, delivery.ID, delivery.NextAttemptAt, attempts, next, attempt.Error)
+	if err != nil {
+		d.logMarkError("failed to mark webhook delivery failed", delivery, err)
+	}
+	d.recordFailure(ctx, endpoint.ID, now)
+	return false
+}
+
+// logMarkError logs that the outcome of a delivery could not be recorded.
+// A lease that ran out is only a warning: the delivery belongs to whoever
+// claimed or redelivered it since, and their outcome is the one kept.
+func (d *WebhookDispatcher) logMarkError(msg string, delivery *domain.WebhookDelivery, err error) {
+	if errors.Is(err, domain.ErrWebhookLeaseExpired) {
+		d.logger.Warn(msg, "delivery_id", delivery.ID, "leased_until", delivery.NextAttemptAt, "error", err)
+		return
+	}
+	d.logger.Error(msg, "delivery_id", delivery.ID, "error", err)
+}
+
+// attempt sends a delivery to its endpoint, and returns the log of the
+// attempt.
+func (d *WebhookDispatcher) attempt(
+	ctx context.Context,
+	endpoint *domain.WebhookEndpoint,
+	delivery *domain.WebhookDelivery,
+) (*domain.WebhookAttempt, error) {
+	attempt, err := domain.NewWebhookAttempt(delivery)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	err = d.send(ctx, endpoint, delivery, attempt)
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	attempt.Duration = time.Since(start)
+	return attempt, nil
+}
+
+// send posts a delivery to its endpoint, and logs the request and response
+// in the attempt.
+func (d *WebhookDispatcher) send(
+	ctx context.Context,
+	endpoint *domain.WebhookEndpoint,
+	delivery *domain.WebhookDelivery,
+	attempt *domain.WebhookAttempt,
+) error {
+	secret, err := crypto.Decrypt(d.policy.EncryptionKey, endpoint.EncryptedSecret, []byte(endpoint.ID))
+	if err != nil {
+		return errors.Wrap(err, "failed to decrypt webhook secret")
+	}
+	attempt.RequestHeaders = map[string]string{
+		"Content-Type":                "application/json",
+		"User-Agent":                  webhookUserAgent,
+		domain.WebhookHeaderID:        delivery.ID,
+		domain.WebhookHeaderEvent:     delivery.EventType.String(),
+		domain.WebhookHeaderSignature: domain.SignWebhook(string(secret), attempt.AttemptedAt, delivery.Payload),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.policy.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return errors.Wrap(err, "failed to make webhook request")
+	}
+	for k, v := range attempt.RequestHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	attempt.ResponseStatus = resp.StatusCode
+	body, err := io.ReadAll(io.LimitReader(resp.Body, d.policy.MaxLoggedBody))
+	attempt.ResponseBody = string(body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return errors.Errorf("endpoint answered %s", resp.Status)
+	}
+	if err != nil {
+		// The endpoint accepted the delivery, only its answer is cut short.
+		d.logger.Warn("failed to read webhook response", "delivery_id", delivery.ID, "error", err)
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt against an endpoint, and disables
+// the endpoint if it failed for long enough.
+func (d *WebhookDispatcher) recordFailure(ctx context.Context, endpointID string, at time.Time) {
+	endpoint, err := d.endpoints.RecordFailure(ctx, endpointID, at)
+	if err != nil {
+		d.logger.Error("failed to record webhook failure", "endpoint_id", endpointID, "error", err)
+		return
+	}
+	if !d.policy.shouldDisable(endpoint, at) {
+		return
+	}
+	endpoint.Disable(fmt.Sprintf("disabled after %d failed attempts in a row", endpoint.ConsecutiveFailures), at)
+	err = d.endpoints.Update(ctx, endpoint)
+	if err != nil {
+		d.logger.Error("failed to disable webhook endpoint", "endpoint_id", endpointID, "error", err)
+		return
+	}
+	d.metrics.recordDisabled(ctx)
+	d.logger.Warn("disabled failing webhook endpoint",
+		"endpoint_id", endpointID, "failures", endpoint.ConsecutiveFailures, "failing_since", endpoint.FailingSince)
+}
+
+// webhookMetrics records webhook delivery metrics.
+type webhookMetrics struct {
+	delivered otelmetric.Int64UpDownCounter
+	failed    otelmetric.Int64UpDownCounter
+	dead      otelmetric.Int64UpDownCounter
+	disabled  otelmetric.Int64UpDownCounter
+	duration  otelmetric.Int64Histogram
+}
+
+// newWebhookMetrics creates the webhook delivery instruments. Without
+// instruments, metrics are discarded.
+func newWebhookMetrics(tel telemetry.Instruments) (*webhookMetrics, error) {
+	if tel == nil {
+		meter := noop.NewMeterProvider().Meter("")
+		delivered, _ := meter.Int64UpDownCounter("")
+		failed, _ := meter.Int64UpDownCounter("")
+		dead, _ := meter.Int64UpDownCounter("")
+		disabled, _ := meter.Int64UpDownCounter("")
+		duration, _ := meter.Int64Histogram("")
+		return &webhookMetrics{
+			delivered: delivered, failed: failed, dead: dead, disabled: disabled, duration: duration,
+		}, nil
+	}
+
+	delivered, err := tel.UpDownCounter(telemetry.MetricWebhooksDeliveredTotal)
+	if err != nil {
+		return nil, err
+	}
+	failed, err := tel.UpDownCounter(telemetry.MetricWebhookDeliveryFailuresTotal)
+	if err != nil {
+		return nil, err
+	}
+	dead, err := tel.UpDownCounter(telemetry.MetricWebhooksDeadTotal)
+	if err != nil {
+		return nil, err
+	}
+	disabled, err := tel.UpDownCounter(telemetry.MetricWebhookEndpointsDisabledTotal)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := tel.Histogram(telemetry.MetricWebhookDeliveryDurationMillis)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookMetrics{
+		delivered: delivered, failed: failed, dead: dead, disabled: disabled, duration: duration,
+	}, nil
+}
+
+func (m *webhookMetrics) recordDelivered(ctx context.Context, typ domain.EventType) {
+	m.delivered.Add(ctx, 1, eventTypeAttribute(typ))
+}
+
+func (m *webhookMetrics) recordFailed(ctx context.Context, typ domain.EventType) {
+	m.failed.Add(ctx, 1, eventTypeAttribute(typ))
+}
+
+func (m *webhookMetrics) recordDead(ctx context.Context, typ domain.EventType) {
+	m.dead.Add(ctx, 1, eventTypeAttribute(typ))
+}
+
+func (m *webhookMetrics) recordDisabled(ctx context.Context) {
+	m.disabled.Add(ctx, 1)
+}
+
+func (m *webhookMetrics) recordDuration(ctx context.Context, typ domain.EventType, d time.Duration) {
+	m.duration.Record(ctx, d.Milliseconds(), eventTypeAttribute(typ))
+}
+
+func eventTypeAttribute(typ domain.EventType) otelmetric.MeasurementOption {
+	return otelmetric.WithAttributes(attribute.String("event_type", typ.String()))
+}
+
+// ErrWebhookLeaseTooShort is returned when deliveries are leased for less
+// time than a batch of them can take.
+var ErrWebhookLeaseTooShort = errors.New("webhook delivery lease is shorter than a batch takes")
+
+// ErrWebhookTransportUnsupported is returned when the transport of the
+// client deliveries are sent with cannot be guarded against connecting to
+// addresses that are not public.
+var ErrWebhookTransportUnsupported = errors.New("webhook client transport must be an *http.Transport")
+
+// ErrWebhookAddressNotPublic is returned when an endpoint resolves to an
+// address that is not public.
+var ErrWebhookAddressNotPublic = errors.New("webhook endpoint address is not public")
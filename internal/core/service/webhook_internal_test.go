@@ -0,0 +1,598 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/internal/adapters/memory"
+	"go.brokedaear.com/internal/common/telemetry"
+	"go.brokedaear.com/internal/core/domain"
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+)
+
+// webhookReceiver is an endpoint that records the requests it receives, and
+// answers with its statuses first and 204 No Content after. It calls
+// onRequest, if set, before answering.
+type webhookReceiver struct {
+	mu        sync.Mutex
+	statuses  []int
+	requests  []*http.Request
+	bodies    [][]byte
+	onRequest func()
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	if r.onRequest != nil {
+		r.onRequest()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status = r.statuses[0]
+		r.statuses = r.statuses[1:]
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(http.StatusText(status)))
+}
+
+// answer makes the receiver answer with statuses first.
+func (r *webhookReceiver) answer(statuses ...int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = statuses
+}
+
+// request returns the i-th request received, along with its body.
+func (r *webhookReceiver) request(i int) (*http.Request, []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests[i], r.bodies[i]
+}
+
+func (r *webhookReceiver) received() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.requests)
+}
+
+type webhookFixture struct {
+	adminFixture
+	base       *ServiceBase
+	endpoints  *memory.WebhookEndpointRepository
+	deliveries *memory.WebhookDeliveryRepository
+	webhooks   *WebhookService
+	dispatcher *WebhookDispatcher
+	receiver   *webhookReceiver
+	url        string
+	metrics    *fakeInstruments
+	// admin is a principal allowed to manage webhooks.
+	admin *domain.Principal
+}
+
+func newWebhookFixture(t *testing.T) webhookFixture {
+	t.Helper()
+	f := newAdminFixture(t)
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	deliveries := memory.NewWebhookDeliveryRepository()
+	endpoints := memory.NewWebhookEndpointRepository(deliveries)
+
+	receiver := &webhookReceiver{}
+	srv := httptest.NewTLSServer(receiver)
+	t.Cleanup(srv.Close)
+
+	policy := DefaultWebhookPolicy(testEncryptionKey)
+	policy.Delivery.MaxAttempts = 3
+	policy.DisableAfterFailures = 2
+	policy.DisableAfter = time.Hour
+	webhooks, err := NewWebhookService(base, endpoints, deliveries, f.authz, policy)
+	assert.NoError(t, err)
+	webhooks.audit = f.audit
+	dispatcher, err := NewWebhookDispatcher(base, endpoints, deliveries, srv.Client(), policy)
+	assert.NoError(t, err)
+	// Endpoints are registered at hooks.example.com, which the certificate
+	// of the server covers, and reach the server on loopback.
+	transport, ok := dispatcher.client.Transport.(*http.Transport)
+	assert.True(t, ok)
+	dial := transport.DialContext
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dial(ctx, network, srv.Listener.Addr().String())
+	}
+	dispatcher.allowAddr = func(addr netip.Addr) bool {
+		return addr.IsLoopback()
+	}
+	metrics := newFakeInstruments()
+	dispatcher.metrics, err = newWebhookMetrics(metrics)
+	assert.NoError(t, err)
+
+	return webhookFixture{
+		adminFixture: f,
+		base:         base,
+		endpoints:    endpoints,
+		deliveries:   deliveries,
+		webhooks:     webhooks,
+		dispatcher:   dispatcher,
+		receiver:     receiver,
+		url:          "https://hooks.example.com/hooks",
+		metrics:      metrics,
+		admin:        f.principal(t, "admin@brokedaear.com", domain.RoleAdmin),
+	}
+}
+
+// create registers an endpoint for order events, and returns it along with
+// its secret.
+func (f webhookFixture) create(t *testing.T) (*domain.WebhookEndpoint, string) {
+	t.Helper()
+	endpoint, secret, err := f.webhooks.CreateEndpoint(
+		context.Background(), f.admin, f.url, "fulfilment",
+		[]domain.EventType{domain.EventTypeOrderPaid, domain.EventTypeOrderRefunded},
+	)
+	assert.NoError(t, err)
+	return endpoint, secret
+}
+
+// queue queues an order paid event for the subscribed endpoints, and returns
+// the event.
+func (f webhookFixture) queue(t *testing.T) *domain.Event {
+	t.Helper()
+	event, err := domain.NewEvent(domain.EventTypeOrderPaid, "order-1", domain.OrderPaid{
+		OrderID:    "order-1",
+		CustomerID: "customer-1",
+		GrandTotal: 4200,
+		Currency:   "USD",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, f.webhooks.enqueue(context.Background(), event))
+	return event
+}
+
+// only returns the only delivery.
+func (f webhookFixture) only(t *testing.T) *domain.WebhookDelivery {
+	t.Helper()
+	deliveries := f.deliveries.List()
+	assert.Equal(t, len(deliveries), 1)
+	return deliveries[0]
+}
+
+// makeDue makes a delivery due again, as if its backoff had passed.
+func (f webhookFixture) makeDue(t *testing.T) {
+	t.Helper()
+	delivery := f.only(t)
+	delivery.NextAttemptAt = time.Now().UTC().Add(-time.Second)
+	f.deliveries.Update(delivery)
+}
+
+func TestWebhookDispatcher_DeliversSigned(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	_, secret := f.create(t)
+	event := f.queue(t)
+
+	delivered, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 1)
+	assert.Equal(t, f.receiver.received(), 1)
+
+	req, body := f.receiver.request(0)
+	assert.Equal(t, req.Method, http.MethodPost)
+	assert.Equal(t, req.Header.Get("Content-Type"), "application/json")
+	assert.Equal(t, req.Header.Get(domain.WebhookHeaderEvent), "order.paid")
+	assert.Equal(t, req.Header.Get(domain.WebhookHeaderID), f.only(t).ID)
+	assert.NoError(t, domain.VerifyWebhookSignature(
+		secret, req.Header.Get(domain.WebhookHeaderSignature), body, time.Now(), time.Minute,
+	))
+	var payload domain.WebhookPayload
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, payload.ID, event.ID)
+	assert.Equal(t, payload.Type, "order.paid")
+	var paid domain.OrderPaid
+	assert.NoError(t, json.Unmarshal(payload.Data, &paid))
+	assert.Equal(t, paid.GrandTotal, 4200)
+
+	delivery := f.only(t)
+	assert.Equal(t, delivery.Status, domain.WebhookDeliveryStatusDelivered)
+	assert.Equal(t, delivery.Attempts, 1)
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricWebhooksDeliveredTotal), int64(1))
+
+	// A delivered event is not delivered again.
+	delivered, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 0)
+	assert.Equal(t, f.receiver.received(), 1)
+}
+
+func TestWebhookDispatcher_RetriesWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	endpoint, _ := f.create(t)
+	f.queue(t)
+	f.receiver.answer(http.StatusInternalServerError)
+
+	delivered, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 0)
+	delivery := f.only(t)
+	assert.Equal(t, delivery.Status, domain.WebhookDeliveryStatusPending)
+	assert.Equal(t, delivery.Attempts, 1)
+	assert.Equal(t, delivery.LastError, "endpoint answered 500 Internal Server Error")
+	assert.True(t, delivery.NextAttemptAt.After(time.Now()))
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricWebhookDeliveryFailuresTotal), int64(1))
+
+	// The delivery waits for its backoff.
+	delivered, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 0)
+	assert.Equal(t, f.receiver.received(), 1)
+
+	f.makeDue(t)
+	delivered, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 1)
+
+	// Both attempts are logged, and the success forgets the failure.
+	_, attempts, err := f.webhooks.Delivery(ctx, f.admin, delivery.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, len(attempts), 2)
+	assert.Equal(t, attempts[0].Number, 1)
+	assert.Equal(t, attempts[0].ResponseStatus, http.StatusInternalServerError)
+	assert.Equal(t, attempts[0].ResponseBody, "Internal Server Error")
+	assert.Equal(t, attempts[1].Number, 2)
+	assert.True(t, attempts[1].Succeeded())
+	assert.Equal(t, attempts[1].RequestHeaders["User-Agent"], webhookUserAgent)
+	endpoint, err = f.endpoints.GetByID(ctx, endpoint.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, endpoint.ConsecutiveFailures, 0)
+	assert.True(t, endpoint.FailingSince == nil)
+}
+
+func TestWebhookDispatcher_GivesUp(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	f.create(t)
+	f.queue(t)
+	f.receiver.answer(http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+
+	for range 3 {
+		f.makeDue(t)
+		_, err := f.dispatcher.Dispatch(ctx)
+		assert.NoError(t, err)
+	}
+	delivery := f.only(t)
+	assert.Equal(t, delivery.Status, domain.WebhookDeliveryStatusDead)
+	assert.Equal(t, delivery.Attempts, 3)
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricWebhooksDeadTotal), int64(1))
+
+	// A dead delivery is not attempted again until it is redelivered.
+	f.makeDue(t)
+	delivered, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 0)
+	assert.Equal(t, f.receiver.received(), 3)
+
+	redelivered, err := f.webhooks.Redeliver(ctx, f.admin, delivery.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, redelivered.Status, domain.WebhookDeliveryStatusPending)
+	assert.Equal(t, redelivered.Attempts, 0)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionWebhookRedeliver)
+	delivered, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 1)
+	_, attempts, err := f.webhooks.Delivery(ctx, f.admin, delivery.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, len(attempts), 4)
+	assert.Equal(t, attempts[3].Number, 4)
+}
+
+func TestWebhookDispatcher_KeepsRedeliveryDuringAttempt(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	f.create(t)
+	f.queue(t)
+	delivery := f.only(t)
+
+	// An admin redelivers while the dispatcher is attempting the delivery.
+	// The dispatcher's lease is gone, so the redelivery is kept.
+	var redeliverErr error
+	f.receiver.onRequest = func() {
+		_, redeliverErr = f.webhooks.Redeliver(ctx, f.admin, delivery.ID)
+	}
+	delivered, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, redeliverErr)
+	assert.Equal(t, delivered, 1)
+	delivery = f.only(t)
+	assert.Equal(t, delivery.Status, domain.WebhookDeliveryStatusPending)
+	assert.Equal(t, delivery.Attempts, 0)
+	assert.True(t, delivery.DeliveredAt == nil)
+}
+
+func TestWebhookDispatcher_RefusesPrivateAddresses(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	f.create(t)
+	f.queue(t)
+
+	// The host of the endpoint now resolves to loopback, as if its DNS
+	// record had been rebound after it was registered.
+	f.dispatcher.allowAddr = domain.IsPublicWebhookAddr
+	delivered, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, delivered, 0)
+	assert.Equal(t, f.receiver.received(), 0)
+	delivery := f.only(t)
+	assert.Equal(t, delivery.Status, domain.WebhookDeliveryStatusPending)
+	assert.Equal(t, delivery.Attempts, 1)
+	assert.True(t, strings.Contains(delivery.LastError, ErrWebhookAddressNotPublic.Error()))
+}
+
+func TestNewWebhookDispatcher_RequiresTransport(t *testing.T) {
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	deliveries := memory.NewWebhookDeliveryRepository()
+	endpoints := memory.NewWebhookEndpointRepository(deliveries)
+	policy := DefaultWebhookPolicy(testEncryptionKey)
+
+	_, err := NewWebhookDispatcher(base, endpoints, deliveries, &http.Client{}, policy)
+	assert.NoError(t, err)
+	client := &http.Client{Transport: roundTripperFunc(http.DefaultTransport.RoundTrip)}
+	_, err = NewWebhookDispatcher(base, endpoints, deliveries, client, policy)
+	assert.Error(t, err, ErrWebhookTransportUnsupported)
+}
+
+// roundTripperFunc is an http.RoundTripper that is not an *http.Transport.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestNewWebhookDispatcher_LeaseOutlastsBatch(t *testing.T) {
+	base := NewServiceBase(test.NewMockLogger(), nil)
+	deliveries := memory.NewWebhookDeliveryRepository()
+	endpoints := memory.NewWebhookEndpointRepository(deliveries)
+
+	policy := DefaultWebhookPolicy(testEncryptionKey)
+	assert.True(t, policy.Delivery.Lease > time.Duration(policy.Delivery.BatchSize)*policy.Timeout)
+	_, err := NewWebhookDispatcher(base, endpoints, deliveries, http.DefaultClient, policy)
+	assert.NoError(t, err)
+
+	policy.Delivery.Lease = time.Duration(policy.Delivery.BatchSize) * policy.Timeout
+	_, err = NewWebhookDispatcher(base, endpoints, deliveries, http.DefaultClient, policy)
+	assert.Error(t, err, ErrWebhookLeaseTooShort)
+}
+
+func TestWebhookDispatcher_DisablesFailingEndpoint(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	endpoint, _ := f.create(t)
+	f.queue(t)
+	f.receiver.answer(http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+
+	// A burst of failures does not disable the endpoint.
+	_, err := f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	f.makeDue(t)
+	_, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	endpoint, err = f.endpoints.GetByID(ctx, endpoint.ID)
+	assert.NoError(t, err)
+	assert.True(t, endpoint.Enabled)
+	assert.Equal(t, endpoint.ConsecutiveFailures, 2)
+
+	// Failures over long enough do.
+	failingSince := time.Now().UTC().Add(-2 * time.Hour)
+	endpoint.FailingSince = &failingSince
+	assert.NoError(t, f.endpoints.Update(ctx, endpoint))
+	f.receiver.answer(http.StatusServiceUnavailable)
+	f.makeDue(t)
+	_, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	endpoint, err = f.endpoints.GetByID(ctx, endpoint.ID)
+	assert.NoError(t, err)
+	assert.False(t, endpoint.Enabled)
+	assert.True(t, endpoint.DisabledAt != nil)
+	assert.Equal(t, endpoint.DisabledReason, "disabled after 3 failed attempts in a row")
+	assert.Equal(t, f.metrics.sum(t, telemetry.MetricWebhookEndpointsDisabledTotal), int64(1))
+
+	// Events are no longer queued for it, and queued ones are given up on.
+	f.queue(t)
+	assert.Equal(t, len(f.deliveries.List()), 1)
+	event, err := domain.NewEvent(domain.EventTypeOrderRefunded, "order-1", domain.OrderRefunded{
+		OrderID:    "order-1",
+		CustomerID: "customer-1",
+		GrandTotal: 4200,
+		Currency:   "USD",
+		RefundedBy: "support",
+	})
+	assert.NoError(t, err)
+	queued, err := domain.NewWebhookDelivery(endpoint, event)
+	assert.NoError(t, err)
+	assert.NoError(t, f.deliveries.Insert(ctx, queued))
+	_, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, f.receiver.received(), 3)
+	queued, err = f.deliveries.GetByID(ctx, queued.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, queued.Status, domain.WebhookDeliveryStatusDead)
+	assert.Equal(t, queued.LastError, "endpoint disabled")
+
+	// Enabling it again forgets its failures.
+	endpoint, err = f.webhooks.UpdateEndpoint(
+		ctx, f.admin, endpoint.ID, endpoint.URL, endpoint.Description, endpoint.EventTypes, true,
+	)
+	assert.NoError(t, err)
+	assert.True(t, endpoint.Enabled)
+	assert.Equal(t, endpoint.ConsecutiveFailures, 0)
+	assert.Equal(t, endpoint.DisabledReason, "")
+}
+
+func TestWebhookService_FansOutToSubscribedEndpoints(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	orders, _ := f.create(t)
+	signups, _, err := f.webhooks.CreateEndpoint(
+		ctx, f.admin, f.url, "growth", []domain.EventType{domain.EventTypeCustomerSignedUp},
+	)
+	assert.NoError(t, err)
+	disabled, _ := f.create(t)
+	_, err = f.webhooks.UpdateEndpoint(
+		ctx, f.admin, disabled.ID, disabled.URL, disabled.Description, disabled.EventTypes, false,
+	)
+	assert.NoError(t, err)
+
+	outbox := memory.NewEventOutboxRepository()
+	f.base.UseEvents(outbox)
+	relay := NewEventRelay(f.base, outbox, DefaultDeliveryPolicy())
+	f.webhooks.Subscribe(relay)
+	err = f.base.emit(ctx, domain.EventTypeOrderPaid, "order-1", domain.OrderPaid{
+		OrderID:    "order-1",
+		CustomerID: "customer-1",
+		GrandTotal: 4200,
+		Currency:   "USD",
+	})
+	assert.NoError(t, err)
+	published, err := relay.Relay(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, published, 1)
+
+	deliveries := f.deliveries.List()
+	assert.Equal(t, len(deliveries), 1)
+	assert.Equal(t, deliveries[0].EndpointID, orders.ID)
+	listed, err := f.webhooks.Deliveries(ctx, f.admin, signups.ID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, len(listed), 0)
+
+	// An event handed over twice is queued once.
+	event, err := domain.NewEvent(domain.EventTypeOrderPaid, "order-2", domain.OrderPaid{
+		OrderID:    "order-2",
+		CustomerID: "customer-1",
+		GrandTotal: 990,
+		Currency:   "USD",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, f.webhooks.enqueue(ctx, event))
+	assert.NoError(t, f.webhooks.enqueue(ctx, event))
+	assert.Equal(t, len(f.deliveries.List()), 2)
+}
+
+func TestWebhookService_CreateEndpoint(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	support := f.principal(t, "support@brokedaear.com", domain.RoleSupport)
+	orderEvents := []domain.EventType{domain.EventTypeOrderPaid}
+
+	tests := []struct {
+		test.CaseBase
+		principal  *domain.Principal
+		url        string
+		eventTypes []domain.EventType
+	}{
+		{
+			CaseBase:   test.NewCaseBase("ok", nil, false),
+			principal:  f.admin,
+			url:        f.url,
+			eventTypes: orderEvents,
+		},
+		{
+			CaseBase:   test.NewCaseBase("support", domain.ErrForbidden, true),
+			principal:  support,
+			url:        f.url,
+			eventTypes: orderEvents,
+		},
+		{
+			CaseBase:   test.NewCaseBase("plain http", domain.ErrInvalidWebhookURL, true),
+			principal:  f.admin,
+			url:        "http://hooks.example.com",
+			eventTypes: orderEvents,
+		},
+		{
+			CaseBase:   test.NewCaseBase("no event types", domain.ErrInvalidWebhookEventTypes, true),
+			principal:  f.admin,
+			url:        f.url,
+			eventTypes: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				endpoint, secret, err := f.webhooks.CreateEndpoint(ctx, tt.principal, tt.url, "", tt.eventTypes)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Equal(t, f.audit.last().Outcome, domain.AuditOutcomeFailure)
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, secret[:len(webhookSecretPrefix)], webhookSecretPrefix)
+				assert.True(t, endpoint.Enabled)
+				assert.Equal(t, f.audit.last().Action, domain.AuditActionWebhookCreate)
+				assert.Equal(t, f.audit.last().ActorID, tt.principal.CustomerID)
+				assert.Equal(t, f.audit.last().TargetType, domain.AuditTargetWebhookEndpoint)
+				assert.Equal(t, f.audit.last().TargetID, endpoint.ID)
+			},
+		)
+	}
+}
+
+func TestWebhookService_RotateSecret(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	endpoint, oldSecret := f.create(t)
+
+	newSecret, err := f.webhooks.RotateSecret(ctx, f.admin, endpoint.ID)
+	assert.NoError(t, err)
+	assert.True(t, newSecret != oldSecret)
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionWebhookRotateSecret)
+	assert.Equal(t, f.audit.last().TargetType, domain.AuditTargetWebhookEndpoint)
+	assert.Equal(t, f.audit.last().TargetID, endpoint.ID)
+
+	// Deliveries are signed with the new secret.
+	f.queue(t)
+	_, err = f.dispatcher.Dispatch(ctx)
+	assert.NoError(t, err)
+	req, body := f.receiver.request(0)
+	header := req.Header.Get(domain.WebhookHeaderSignature)
+	assert.NoError(t, domain.VerifyWebhookSignature(newSecret, header, body, time.Now(), time.Minute))
+	assert.Error(
+		t,
+		domain.VerifyWebhookSignature(oldSecret, header, body, time.Now(), time.Minute),
+		domain.ErrInvalidWebhookSignature,
+	)
+
+	_, err = f.webhooks.RotateSecret(ctx, f.admin, "missing")
+	assert.Error(t, err, domain.ErrWebhookEndpointNotFound)
+	assert.Equal(t, f.audit.last().Reason, "endpoint_not_found")
+}
+
+func TestWebhookService_DeleteEndpoint(t *testing.T) {
+	ctx := context.Background()
+	f := newWebhookFixture(t)
+	endpoint, _ := f.create(t)
+	f.queue(t)
+
+	assert.NoError(t, f.webhooks.DeleteEndpoint(ctx, f.admin, endpoint.ID))
+	assert.Equal(t, f.audit.last().Action, domain.AuditActionWebhookDelete)
+	assert.Equal(t, f.audit.last().TargetType, domain.AuditTargetWebhookEndpoint)
+	assert.Equal(t, f.audit.last().TargetID, endpoint.ID)
+	endpoints, err := f.webhooks.Endpoints(ctx, f.admin)
+	assert.NoError(t, err)
+	assert.Equal(t, len(endpoints), 0)
+	assert.Equal(t, len(f.deliveries.List()), 0)
+	assert.Error(t, f.webhooks.DeleteEndpoint(ctx, f.admin, endpoint.ID), domain.ErrWebhookEndpointNotFound)
+}
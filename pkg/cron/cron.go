@@ -0,0 +1,230 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cron parses cron expressions and computes when they are due next.
+//
+// An expression has five fields separated by spaces: minute (0-59), hour
+// (0-23), day of month (1-31), month (1-12) and day of week (0-6, Sunday
+// being 0 or 7). A field is a list of comma separated values, ranges such as
+// 1-5, or *, each optionally followed by a step such as */15. As in Vixie
+// cron, when both the day of month and the day of week are restricted, a day
+// matching either one is due.
+//
+// The descriptors @yearly, @monthly, @weekly, @daily and @hourly stand for
+// their usual expressions.
+package cron
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// ErrInvalidExpression is returned when an expression cannot be parsed.
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+//nolint:gochecknoglobals // A lookup table.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// bounds are the smallest and largest values of a field.
+type bounds struct {
+	min, max int
+}
+
+//nolint:gochecknoglobals // A lookup table.
+var (
+	minutes  = bounds{min: 0, max: 59}
+	hours    = bounds{min: 0, max: 23}
+	days     = bounds{min: 1, max: 31}
+	months   = bounds{min: 1, max: 12}
+	weekdays = bounds{min: 0, max: 7}
+)
+
+// Schedule is a parsed cron expression. Each field is a bit set of the
+// values it matches.
+type Schedule struct {
+	expr       string
+	minute     uint64
+	hour       uint64
+	day        uint64
+	month      uint64
+	weekday    uint64
+	anyDay     bool
+	anyWeekday bool
+}
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	const numFields = 5
+	if len(fields) != numFields {
+		return nil, errors.Wrapf(ErrInvalidExpression, "%q has %d fields, want 5", expr, len(fields))
+	}
+
+	s := &Schedule{
+		expr:       expr,
+		minute:     0,
+		hour:       0,
+		day:        0,
+		month:      0,
+		weekday:    0,
+		anyDay:     false,
+		anyWeekday: false,
+	}
+	var err error
+	if s.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, errors.Wrapf(err, "minute of %q", expr)
+	}
+	if s.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, errors.Wrapf(err, "hour of %q", expr)
+	}
+	if s.day, err = parseField(fields[2], days); err != nil {
+		return nil, errors.Wrapf(err, "day of month of %q", expr)
+	}
+	if s.month, err = parseField(fields[3], months); err != nil {
+		return nil, errors.Wrapf(err, "month of %q", expr)
+	}
+	if s.weekday, err = parseField(fields[4], weekdays); err != nil {
+		return nil, errors.Wrapf(err, "day of week of %q", expr)
+	}
+	// Sunday is both 0 and 7.
+	if s.weekday&(1<<7) != 0 {
+		s.weekday |= 1
+	}
+	s.anyDay = strings.HasPrefix(fields[2], "*")
+	s.anyWeekday = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed. It
+// is meant for expressions that are constants.
+func MustParse(expr string) *Schedule {
+	s, err := Parse(expr)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// String returns the expression the schedule was parsed from.
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// parseField parses a comma separated list of values, ranges and steps into
+// a bit set.
+func parseField(field string, b bounds) (uint64, error) {
+	var set uint64
+	for part := range strings.SplitSeq(field, ",") {
+		rng, step, hasStep := strings.Cut(part, "/")
+		lo, hi := b.min, b.max
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			from, to, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = parseValue(from, b); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(to, b); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, errors.Wrapf(ErrInvalidExpression, "range %q is backwards", rng)
+			}
+		default:
+			v, err := parseValue(rng, b)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// A value with a step runs until the end of the field, as in
+			// 5/15.
+			if !hasStep {
+				hi = v
+			}
+		}
+
+		n := 1
+		if hasStep {
+			var err error
+			n, err = strconv.Atoi(step)
+			if err != nil || n <= 0 {
+				return 0, errors.Wrapf(ErrInvalidExpression, "step %q is not a positive number", step)
+			}
+		}
+		for v := lo; v <= hi; v += n {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func parseValue(s string, b bounds) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.Wrapf(ErrInvalidExpression, "%q is not a number", s)
+	}
+	if v < b.min || v > b.max {
+		return 0, errors.Wrapf(ErrInvalidExpression, "%d is not within %d-%d", v, b.min, b.max)
+	}
+	return v, nil
+}
+
+// maxYears is how far ahead Next looks for a due time, so that an
+// expression that is never due, such as 0 0 30 2 *, does not loop forever.
+const maxYears = 5
+
+// Next returns the first time after t, to the minute, at which the schedule
+// is due, in the location of t. It returns the zero time if the schedule is
+// never due.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(maxYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the schedule is due on the day of t.
+func (s *Schedule) dayMatches(t time.Time) bool {
+	day := s.day&(1<<uint(t.Day())) != 0
+	weekday := s.weekday&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
@@ -0,0 +1,116 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/cron"
+	"go.brokedaear.com/pkg/test"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		test.CaseBase
+		expr string
+	}{
+		{
+			CaseBase: test.NewCaseBase("every minute", time.Date(2025, 1, 15, 10, 8, 0, 0, time.UTC), false),
+			expr:     "* * * * *",
+		},
+		{
+			CaseBase: test.NewCaseBase("step", time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC), false),
+			expr:     "*/15 * * * *",
+		},
+		{
+			CaseBase: test.NewCaseBase("value with step", time.Date(2025, 1, 15, 10, 20, 0, 0, time.UTC), false),
+			expr:     "5/15 * * * *",
+		},
+		{
+			CaseBase: test.NewCaseBase("hourly", time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC), false),
+			expr:     "@hourly",
+		},
+		{
+			CaseBase: test.NewCaseBase("daily", time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC), false),
+			expr:     "@daily",
+		},
+		{
+			CaseBase: test.NewCaseBase("list", time.Date(2025, 1, 15, 18, 30, 0, 0, time.UTC), false),
+			expr:     "30 6,18 * * *",
+		},
+		{
+			CaseBase: test.NewCaseBase("weekdays", time.Date(2025, 1, 17, 3, 0, 0, 0, time.UTC), false),
+			expr:     "0 3 * * 5-6",
+		},
+		{
+			CaseBase: test.NewCaseBase("sunday as 7", time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC), false),
+			expr:     "0 0 * * 7",
+		},
+		{
+			CaseBase: test.NewCaseBase("next month", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), false),
+			expr:     "@monthly",
+		},
+		{
+			CaseBase: test.NewCaseBase("next year", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), false),
+			expr:     "@yearly",
+		},
+		{
+			// Either the 20th or a Friday.
+			CaseBase: test.NewCaseBase("day of month or week", time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC), false),
+			expr:     "0 0 20 * 5",
+		},
+		{
+			CaseBase: test.NewCaseBase("leap day", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC), false),
+			expr:     "0 0 29 2 *",
+		},
+		{
+			CaseBase: test.NewCaseBase("never", time.Time{}, false),
+			expr:     "0 0 30 2 *",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				schedule, err := cron.Parse(tt.expr)
+				assert.NoError(t, err)
+				assert.Equal(t, schedule.Next(from), tt.Want.(time.Time))
+			},
+		)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		expr string
+	}{
+		{CaseBase: test.NewCaseBase("valid", nil, false), expr: "0-30/10 1,2 1-31 * 1-5"},
+		{CaseBase: test.NewCaseBase("too few fields", cron.ErrInvalidExpression, true), expr: "* * * *"},
+		{CaseBase: test.NewCaseBase("out of range", cron.ErrInvalidExpression, true), expr: "60 * * * *"},
+		{CaseBase: test.NewCaseBase("not a number", cron.ErrInvalidExpression, true), expr: "a * * * *"},
+		{CaseBase: test.NewCaseBase("backwards range", cron.ErrInvalidExpression, true), expr: "* 5-1 * * *"},
+		{CaseBase: test.NewCaseBase("zero step", cron.ErrInvalidExpression, true), expr: "*/0 * * * *"},
+		{CaseBase: test.NewCaseBase("unknown descriptor", cron.ErrInvalidExpression, true), expr: "@sometimes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				_, err := cron.Parse(tt.expr)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					return
+				}
+				assert.NoError(t, err)
+			},
+		)
+	}
+}
@@ -13,6 +13,7 @@ package crypto
 import (
 	"crypto/rand"
 	"crypto/sha1" //nolint:gosec // This isn't used for storing passwords.
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base32"
 	"encoding/base64"
@@ -24,49 +25,79 @@ import (
 	"golang.org/x/crypto/argon2"
 )
 
-// ValidatePassword checks, in constant time, the equality of two
-// password hashes. It accepts an input hash, which is computed from
-// user input, and a stored hash, which is pre-computed, stored, and
-// retrieved from a repository.
+// ValidatePassword checks, in constant time, whether a password matches a
+// stored hash. The hash is verified by the hasher registered for its prefix,
+// so Argon2id hashes as well as imported bcrypt and scrypt hashes are
+// accepted. Argon2id hashes are verified with the parameters encoded in
+// them, so hashes made with older parameters keep working after the target
+// parameters change. See NeedsRehash.
 func ValidatePassword(password, storedHash string) (bool, error) {
-	salt, storedHashKey, err := DecodeHash(storedHash)
+	h, err := HasherFor(storedHash)
 	if err != nil {
 		return false, err
 	}
-	passwordHashKey := argon2.IDKey(
-		[]byte(password),
-		salt,
-		hashTime,
-		hashMemory,
-		hashThreads,
-		hashKeyLen,
-	)
-	storedHashKeyLen := int32(len(storedHashKey)) //nolint:gosec // Integer overflow irrelevant.
-	passwordKeyLen := int32(len(passwordHashKey)) //nolint:gosec // Integer overflow irrelevant.
-	if subtle.ConstantTimeEq(storedHashKeyLen, passwordKeyLen) == 0 {
-		return false, nil
-	}
-	if subtle.ConstantTimeCompare(storedHashKey, passwordHashKey) == 1 {
-		return true, nil
-	}
-	return false, nil
+	return h.Verify(password, storedHash)
 }
 
-// hashMemory is 19MB. This can be changed given hash time.
-const hashMemory = 19 * 1024
+// Argon2Params are the parameters of an Argon2id password hash. Raising
+// them makes hashes slower to compute, for attackers as well as for the
+// backend.
+type Argon2Params struct {
+	// Memory is the memory used, in KiB.
+	Memory uint32
+	// Iterations is the number of passes over the memory.
+	Iterations uint32
+	// Threads is the number of processing threads to use.
+	Threads uint8
+	// KeyLength is the length of the generated key, in bytes.
+	KeyLength uint32
+}
 
-// hashTime is measured in iterations.
-const hashTime = 2
+// DefaultArgon2Params returns the minimum parameters OWASP recommends: 19
+// MiB of memory, 2 iterations and 1 thread.
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:     19 * 1024,
+		Iterations: 2,
+		Threads:    1,
+		KeyLength:  32,
+	}
+}
 
-// hashThreads is the number of processing threads to use.
-const hashThreads = 1
+// minKeyLength is the shortest key a hash may have.
+const minKeyLength = 16
 
-// hashKeyLen is the length of the generated key.
-const hashKeyLen = 32
+// maxMemory bounds the memory of a hash to 4 GiB, so that a corrupted
+// stored hash cannot make the backend run out of memory.
+const maxMemory = 4 * 1024 * 1024
 
-// GenerateHashedPassword generates a hash password returned as a byte slice.
-// The hash is generated using Argon2.
+// Validate checks that the parameters can be used to hash passwords.
+func (p Argon2Params) Validate() error {
+	switch {
+	case p.Threads == 0 || p.Iterations == 0:
+		return errors.Wrap(ErrInvalidParams, "threads and iterations must be at least 1")
+	case p.Memory < 8*uint32(p.Threads) || p.Memory > maxMemory:
+		return errors.Wrapf(ErrInvalidParams, "memory must be between 8 KiB per thread and %d KiB", maxMemory)
+	case p.KeyLength < minKeyLength:
+		return errors.Wrapf(ErrInvalidParams, "key length must be at least %d bytes", minKeyLength)
+	}
+	return nil
+}
+
+// GenerateHashedPassword generates an Argon2id hash of a password with the
+// default parameters. See GenerateHashedPasswordWithParams.
 func GenerateHashedPassword(password []byte) (string, error) {
+	return GenerateHashedPasswordWithParams(password, DefaultArgon2Params())
+}
+
+// GenerateHashedPasswordWithParams generates an Argon2id hash of a password
+// with the given parameters. The parameters are encoded in the hash, in the
+// PHC string format.
+func GenerateHashedPasswordWithParams(password []byte, params Argon2Params) (string, error) {
+	err := params.Validate()
+	if err != nil {
+		return "", err
+	}
 	s, err := generateSalt()
 	if err != nil {
 		return "", err
@@ -75,25 +106,35 @@ func GenerateHashedPassword(password []byte) (string, error) {
 	key := argon2.IDKey(
 		password,
 		salt,
-		hashTime,
-		hashMemory,
-		hashThreads,
-		hashKeyLen,
+		params.Iterations,
+		params.Memory,
+		params.Threads,
+		params.KeyLength,
 	)
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Key := base64.RawStdEncoding.EncodeToString(key)
 	h := fmt.Sprintf(
 		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
 		argon2.Version,
-		hashMemory,
-		hashTime,
-		hashThreads,
+		params.Memory,
+		params.Iterations,
+		params.Threads,
 		b64Salt,
 		b64Key,
 	)
 	return h, nil
 }
 
+// NeedsRehash reports whether a stored hash was made with parameters other
+// than the target ones, with an algorithm other than Argon2id, or could not
+// be decoded at all. A hash that needs
+// rehashing should be replaced with a new hash of the password the next
+// time the password is known, such as after a successful sign in.
+func NeedsRehash(storedHash string, target Argon2Params) bool {
+	params, _, _, err := DecodeHash(storedHash)
+	return err != nil || params != target
+}
+
 // PwnHash returns a SHA-1 hex encoded hash string that can be used as input to
 // haveibeenpwnd to check a password against past leaks.
 //
@@ -140,46 +181,80 @@ func GenerateRandomString() (string, error) {
 	return customEncoding.EncodeToString(bytes), nil
 }
 
-// invalidHashLength is the valid number total of keys in a stored hash.
+// totalTokenBytes is the total number of random bytes in a token, which is
+// 256 bits of entropy.
+const totalTokenBytes = 32
+
+// GenerateToken generates a random, URL safe token with 256 bits of entropy.
+// Tokens are suitable as session or one-time secrets. They should never be
+// stored as-is; store the result of HashToken instead.
+//
+// See: https://thecopenhagenbook.com/sessions
+func GenerateToken() (string, error) {
+	b := make([]byte, totalTokenBytes)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate token")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 hash of a token. A fast hash is fine here,
+// unlike for passwords, since tokens have enough entropy to make brute force
+// infeasible.
+func HashToken(token string) []byte {
+	h := sha256.Sum256([]byte(token))
+	return h[:]
+}
+
+// CompareTokenHash checks, in constant time, whether a token matches a hash
+// produced by HashToken.
+func CompareTokenHash(token string, hash []byte) bool {
+	return subtle.ConstantTimeCompare(HashToken(token), hash) == 1
+}
+
+// validHashLength is the valid number total of keys in a stored hash.
 // Stored hash values are expected to have this number of keys.
 const validHashLength = 6
 
-// DecodeHash expects a hash created from this package, and parses it to return the params used to
-// create it, as well as the salt and key (password hash).
-func DecodeHash(hash string) ([]byte, []byte, error) {
-	var (
-		err  error
-		salt []byte
-		key  []byte
-	)
+// DecodeHash expects a hash created from this package, and parses it to
+// return the parameters used to create it, as well as the salt and key
+// (password hash).
+func DecodeHash(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
 	vals := strings.Split(hash, "$")
 	if len(vals) != validHashLength {
-		return nil, nil, ErrInvalidHash
+		return params, nil, nil, ErrInvalidHash
 	}
 	if vals[1] != "argon2id" {
-		return nil, nil, ErrIncompatibleVariant
+		return params, nil, nil, ErrIncompatibleVariant
 	}
 	var version int
-	_, err = fmt.Sscanf(vals[2], "v=%d", &version)
+	_, err := fmt.Sscanf(vals[2], "v=%d", &version)
 	if err != nil {
-		return nil, nil, err
+		return params, nil, nil, errors.Wrap(ErrInvalidHash, err.Error())
 	}
 	if version != argon2.Version {
-		return nil, nil, ErrIncompatibleVersion
+		return params, nil, nil, ErrIncompatibleVersion
 	}
-	_, err = fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", hashMemory, hashTime, hashThreads)
+	_, err = fmt.Sscanf(vals[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Threads)
 	if err != nil {
-		return nil, nil, err
+		return params, nil, nil, errors.Wrap(ErrInvalidHash, err.Error())
 	}
-	salt, err = base64.RawStdEncoding.Strict().DecodeString(vals[4])
+	salt, err := base64.RawStdEncoding.Strict().DecodeString(vals[4])
 	if err != nil {
-		return nil, nil, err
+		return params, nil, nil, errors.Wrap(ErrInvalidHash, err.Error())
 	}
-	key, err = base64.RawStdEncoding.Strict().DecodeString(vals[5])
+	key, err := base64.RawStdEncoding.Strict().DecodeString(vals[5])
 	if err != nil {
-		return nil, nil, err
+		return params, nil, nil, errors.Wrap(ErrInvalidHash, err.Error())
 	}
-	return salt, key, nil
+	params.KeyLength = uint32(len(key)) //nolint:gosec // Integer overflow irrelevant.
+	err = params.Validate()
+	if err != nil {
+		return params, nil, nil, err
+	}
+	return params, salt, key, nil
 }
 
 var (
@@ -195,4 +270,13 @@ var (
 	// ErrIncompatibleVersion is returned by ComparePasswordAndHash if the
 	// provided hash was created using a different version of Argon2.
 	ErrIncompatibleVersion = errors.New("argon2id: incompatible version of argon2")
+
+	// ErrInvalidParams is returned if Argon2 parameters cannot be used to
+	// hash passwords, such as when they call for no threads, or if a stored
+	// hash of another algorithm has parameters out of bounds.
+	ErrInvalidParams = errors.New("argon2id: invalid parameters")
+
+	// ErrUnsupportedHash is returned by ValidatePassword if no hasher is
+	// registered for the algorithm of the provided hash.
+	ErrUnsupportedHash = errors.New("crypto: unsupported hash algorithm")
 )
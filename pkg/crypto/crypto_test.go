@@ -0,0 +1,195 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package crypto_test
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/crypto"
+	"go.brokedaear.com/pkg/test"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+func TestValidatePassword_UsesEncodedParams(t *testing.T) {
+	params := crypto.Argon2Params{Memory: 8 * 1024, Iterations: 3, Threads: 2, KeyLength: 24}
+	hash, err := crypto.GenerateHashedPasswordWithParams([]byte("hunter2"), params)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=8192,t=3,p=2$"))
+
+	decoded, _, _, err := crypto.DecodeHash(hash)
+	assert.NoError(t, err)
+	assert.Equal(t, decoded, params)
+
+	ok, err := crypto.ValidatePassword("hunter2", hash)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = crypto.ValidatePassword("hunter3", hash)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	target := crypto.DefaultArgon2Params()
+	current, err := crypto.GenerateHashedPassword([]byte("hunter2"))
+	assert.NoError(t, err)
+	weak, err := crypto.GenerateHashedPasswordWithParams(
+		[]byte("hunter2"),
+		crypto.Argon2Params{Memory: 8 * 1024, Iterations: 1, Threads: 1, KeyLength: 32},
+	)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		test.CaseBase
+		hash string
+	}{
+		{CaseBase: test.NewCaseBase("current", false, false), hash: current},
+		{CaseBase: test.NewCaseBase("weaker", true, false), hash: weak},
+		{CaseBase: test.NewCaseBase("not a hash", true, false), hash: "hunter2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				assert.Equal(t, crypto.NeedsRehash(tt.hash, target), tt.Want.(bool))
+			},
+		)
+	}
+}
+
+func TestDecodeHash_InvalidParams(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		hash string
+	}{
+		{
+			CaseBase: test.NewCaseBase("no threads", crypto.ErrInvalidParams, true),
+			hash:     "$argon2id$v=19$m=19456,t=2,p=0$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		},
+		{
+			CaseBase: test.NewCaseBase("no iterations", crypto.ErrInvalidParams, true),
+			hash:     "$argon2id$v=19$m=19456,t=0,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		},
+		{
+			CaseBase: test.NewCaseBase("short key", crypto.ErrInvalidParams, true),
+			hash:     "$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5",
+		},
+		{
+			CaseBase: test.NewCaseBase("garbled params", crypto.ErrInvalidHash, true),
+			hash:     "$argon2id$v=19$m=lots,t=2,p=1$c2FsdHNhbHRzYWx0$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		},
+		{
+			CaseBase: test.NewCaseBase("bcrypt", crypto.ErrInvalidHash, true),
+			hash:     "$2b$10$abcdefghijklmnopqrstuu",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				_, _, _, err := crypto.DecodeHash(tt.hash)
+				assert.Error(t, err, tt.Want.(error))
+				_, err = crypto.ValidatePassword("hunter2", tt.hash)
+				assert.Error(t, err, tt.Want.(error))
+			},
+		)
+	}
+}
+
+func TestValidatePassword_LegacyHashes(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	salt := []byte("saltsaltsaltsalt")
+	key, err := scrypt.Key([]byte("hunter2"), salt, 1<<10, 8, 1, 32)
+	assert.NoError(t, err)
+	scryptHash := "$scrypt$ln=10,r=8,p=1$" +
+		base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(key)
+
+	tests := []struct {
+		test.CaseBase
+		hash     string
+		password string
+	}{
+		{
+			CaseBase: test.NewCaseBase("bcrypt", true, false),
+			hash:     string(bcryptHash),
+			password: "hunter2",
+		},
+		{
+			CaseBase: test.NewCaseBase("bcrypt wrong password", false, false),
+			hash:     string(bcryptHash),
+			password: "hunter3",
+		},
+		{
+			CaseBase: test.NewCaseBase("bcrypt 2y", true, false),
+			hash:     "$2y$" + string(bcryptHash[4:]),
+			password: "hunter2",
+		},
+		{
+			CaseBase: test.NewCaseBase("scrypt", true, false),
+			hash:     scryptHash,
+			password: "hunter2",
+		},
+		{
+			CaseBase: test.NewCaseBase("scrypt wrong password", false, false),
+			hash:     scryptHash,
+			password: "hunter3",
+		},
+		{
+			CaseBase: test.NewCaseBase("scrypt cost too high", crypto.ErrInvalidParams, true),
+			hash:     strings.Replace(scryptHash, "ln=10", "ln=30", 1),
+			password: "hunter2",
+		},
+		{
+			CaseBase: test.NewCaseBase("unknown algorithm", crypto.ErrUnsupportedHash, true),
+			hash:     "$pbkdf2-sha256$29000$c2FsdA$a2V5",
+			password: "hunter2",
+		},
+		{
+			CaseBase: test.NewCaseBase("plaintext", crypto.ErrUnsupportedHash, true),
+			hash:     "hunter2",
+			password: "hunter2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				ok, err := crypto.ValidatePassword(tt.password, tt.hash)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					assert.Error(t, crypto.CheckHash(tt.hash), tt.Want.(error))
+					return
+				}
+				assert.NoError(t, err)
+				assert.NoError(t, crypto.CheckHash(tt.hash))
+				assert.Equal(t, ok, tt.Want.(bool))
+				assert.True(t, crypto.NeedsRehash(tt.hash, crypto.DefaultArgon2Params()))
+			},
+		)
+	}
+}
+
+type plaintextHasher struct{}
+
+func (plaintextHasher) Verify(password, hash string) (bool, error) {
+	return password == strings.TrimPrefix(hash, "$plain$"), nil
+}
+
+func (plaintextHasher) Check(string) error { return nil }
+
+func TestRegisterHasher(t *testing.T) {
+	_, err := crypto.ValidatePassword("hunter2", "$plain$hunter2")
+	assert.Error(t, err, crypto.ErrUnsupportedHash)
+
+	crypto.RegisterHasher("$plain$", plaintextHasher{})
+	ok, err := crypto.ValidatePassword("hunter2", "$plain$hunter2")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,84 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// EncryptionKeyLength is the length of the keys used by Encrypt and Decrypt,
+// which select AES-256.
+const EncryptionKeyLength = 32
+
+// ParseEncryptionKey decodes a base64 encoded encryption key, such as one
+// read from configuration.
+func ParseEncryptionKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidKey, err.Error())
+	}
+	if len(key) != EncryptionKeyLength {
+		return nil, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Encrypt encrypts and authenticates a plaintext with AES-GCM. The additional
+// data is authenticated but not encrypted, and must be given again to
+// Decrypt. It binds the ciphertext to its context, such as the ID of the
+// record it is stored in, so that it cannot be moved to another record. The
+// random nonce is prepended to the ciphertext.
+func Encrypt(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt decrypts a ciphertext made by Encrypt with the same key and
+// additional data. It returns ErrDecryptionFailed if the ciphertext was
+// tampered with or the key or the additional data differ.
+func Decrypt(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecryptionFailed
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptionKeyLength {
+		return nil, ErrInvalidKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+	return cipher.NewGCM(block)
+}
+
+var (
+	ErrInvalidKey       = errors.New("encryption key must be 32 bytes")
+	ErrDecryptionFailed = errors.New("decryption failed")
+)
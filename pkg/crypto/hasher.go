@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package crypto
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.brokedaear.com/pkg/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Hasher verifies passwords against hashes of one algorithm. New hashes are
+// always Argon2id hashes; other algorithms are only verified, so that
+// customers imported from older stores can sign in and have their hash
+// upgraded. See NeedsRehash.
+type Hasher interface {
+	// Verify checks whether a password matches a hash. A hash that cannot
+	// be parsed returns an error wrapping ErrInvalidHash.
+	Verify(password, hash string) (bool, error)
+	// Check parses a hash without verifying a password, so that hashes can
+	// be validated before they are stored.
+	Check(hash string) error
+}
+
+// hashers maps the prefixes of PHC and modular crypt strings to the hasher
+// of their algorithm.
+var hashers = struct { //nolint:gochecknoglobals // makes more sense like this.
+	sync.RWMutex
+	byPrefix map[string]Hasher
+}{
+	RWMutex: sync.RWMutex{},
+	byPrefix: map[string]Hasher{
+		"$argon2id$": argon2idHasher{},
+		"$2a$":       bcryptHasher{},
+		"$2b$":       bcryptHasher{},
+		"$2y$":       bcryptHasher{},
+		"$scrypt$":   scryptHasher{},
+	},
+}
+
+// RegisterHasher registers the hasher of the hashes starting with a prefix,
+// such as "$pbkdf2-sha256$". It replaces the hasher already registered for
+// the prefix, if any.
+func RegisterHasher(prefix string, h Hasher) {
+	hashers.Lock()
+	defer hashers.Unlock()
+	hashers.byPrefix[prefix] = h
+}
+
+// HasherFor returns the hasher of a hash, found by the longest registered
+// prefix the hash starts with. It returns ErrUnsupportedHash if no prefix
+// matches.
+func HasherFor(hash string) (Hasher, error) {
+	hashers.RLock()
+	defer hashers.RUnlock()
+	var found Hasher
+	longest := 0
+	for prefix, h := range hashers.byPrefix {
+		if len(prefix) > longest && strings.HasPrefix(hash, prefix) {
+			found, longest = h, len(prefix)
+		}
+	}
+	if found == nil {
+		return nil, ErrUnsupportedHash
+	}
+	return found, nil
+}
+
+// CheckHash validates a hash of any registered algorithm without verifying
+// a password against it.
+func CheckHash(hash string) error {
+	h, err := HasherFor(hash)
+	if err != nil {
+		return err
+	}
+	return h.Check(hash)
+}
+
+// argon2idHasher verifies the hashes made by GenerateHashedPassword.
+type argon2idHasher struct{}
+
+func (argon2idHasher) Verify(password, hash string) (bool, error) {
+	params, salt, storedKey, err := DecodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey(
+		[]byte(password),
+		salt,
+		params.Iterations,
+		params.Memory,
+		params.Threads,
+		params.KeyLength,
+	)
+	return constantTimeEqual(storedKey, key), nil
+}
+
+func (argon2idHasher) Check(hash string) error {
+	_, _, _, err := DecodeHash(hash)
+	return err
+}
+
+// bcryptHasher verifies bcrypt hashes in the modular crypt format, such as
+// "$2b$10$...".
+type bcryptHasher struct{}
+
+func (bcryptHasher) Verify(password, hash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, nil
+	default:
+		return false, errors.Wrap(ErrInvalidHash, err.Error())
+	}
+}
+
+func (bcryptHasher) Check(hash string) error {
+	_, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return errors.Wrap(ErrInvalidHash, err.Error())
+	}
+	return nil
+}
+
+// scryptHasher verifies scrypt hashes in the PHC string format, such as
+// "$scrypt$ln=15,r=8,p=1$<salt>$<key>", with the salt and key encoded in
+// unpadded standard base64.
+type scryptHasher struct{}
+
+// These bound the parameters of a stored scrypt hash, so that a corrupted
+// hash cannot make the backend run out of memory or spin for minutes. The
+// largest hash uses 128 * 2^20 * 8 bytes, or 1 GiB.
+const (
+	maxScryptLogN = 20
+	maxScryptR    = 8
+	maxScryptP    = 16
+)
+
+// scryptHashLength is the number of "$" separated fields in a scrypt hash.
+const scryptHashLength = 5
+
+func (s scryptHasher) Verify(password, hash string) (bool, error) {
+	logN, r, p, salt, storedKey, err := s.decode(hash)
+	if err != nil {
+		return false, err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<logN, r, p, len(storedKey))
+	if err != nil {
+		return false, errors.Wrap(ErrInvalidHash, err.Error())
+	}
+	return constantTimeEqual(storedKey, key), nil
+}
+
+func (s scryptHasher) Check(hash string) error {
+	_, _, _, _, _, err := s.decode(hash)
+	return err
+}
+
+func (scryptHasher) decode(hash string) (int, int, int, []byte, []byte, error) {
+	vals := strings.Split(hash, "$")
+	if len(vals) != scryptHashLength || vals[1] != "scrypt" {
+		return 0, 0, 0, nil, nil, ErrInvalidHash
+	}
+	var logN, r, p int
+	_, err := fmt.Sscanf(vals[2], "ln=%d,r=%d,p=%d", &logN, &r, &p)
+	if err != nil {
+		return 0, 0, 0, nil, nil, errors.Wrap(ErrInvalidHash, err.Error())
+	}
+	if logN < 1 || logN > maxScryptLogN || r < 1 || r > maxScryptR || p < 1 || p > maxScryptP {
+		return 0, 0, 0, nil, nil, ErrInvalidParams
+	}
+	salt, err := base64.RawStdEncoding.Strict().DecodeString(vals[3])
+	if err != nil {
+		return 0, 0, 0, nil, nil, errors.Wrap(ErrInvalidHash, err.Error())
+	}
+	key, err := base64.RawStdEncoding.Strict().DecodeString(vals[4])
+	if err != nil {
+		return 0, 0, 0, nil, nil, errors.Wrap(ErrInvalidHash, err.Error())
+	}
+	if len(key) < minKeyLength {
+		return 0, 0, 0, nil, nil, ErrInvalidParams
+	}
+	return logN, r, p, salt, key, nil
+}
+
+// constantTimeEqual checks, in constant time, whether two keys are equal.
+func constantTimeEqual(a, b []byte) bool {
+	aLen := int32(len(a)) //nolint:gosec // Integer overflow irrelevant.
+	bLen := int32(len(b)) //nolint:gosec // Integer overflow irrelevant.
+	if subtle.ConstantTimeEq(aLen, bLen) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
@@ -0,0 +1,559 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package strength
+
+import (
+	_ "embed"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// common is a list of common passwords and words, most common first.
+//
+//go:embed common.txt
+var common string
+
+// commonRanks maps every word in common.txt to its rank, starting at 1.
+var commonRanks = func() map[string]int { //nolint:gochecknoglobals // makes more sense like this.
+	lines := strings.Fields(common)
+	ranks := make(map[string]int, len(lines))
+	for i, word := range lines {
+		word = strings.ToLower(word)
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = i + 1
+		}
+	}
+	return ranks
+}()
+
+// dictionary is a ranked word list.
+type dictionary struct {
+	ranks     map[string]int
+	userInput bool
+}
+
+// minWordLength is the shortest word matched. Shorter words are cheaper to
+// guess as bruteforce.
+const minWordLength = 3
+
+// rankedDictionaries returns the built-in dictionary and a dictionary of the
+// user inputs, in the order they were given.
+func rankedDictionaries(userInputs []string) []dictionary {
+	inputs := make(map[string]int)
+	for _, input := range userInputs {
+		word := strings.ToLower(input)
+		if len([]rune(word)) < minWordLength {
+			continue
+		}
+		if _, ok := inputs[word]; !ok {
+			inputs[word] = len(inputs) + 1
+		}
+	}
+	return []dictionary{
+		{ranks: commonRanks, userInput: false},
+		{ranks: inputs, userInput: true},
+	}
+}
+
+// l33tTable maps characters to the letters they commonly stand in for.
+var l33tTable = map[rune][]rune{ //nolint:gochecknoglobals // makes more sense like this.
+	'4': {'a'},
+	'@': {'a'},
+	'8': {'b'},
+	'(': {'c'},
+	'3': {'e'},
+	'6': {'g'},
+	'1': {'i', 'l'},
+	'!': {'i'},
+	'|': {'i', 'l'},
+	'0': {'o'},
+	'$': {'s'},
+	'5': {'s'},
+	'7': {'t'},
+	'+': {'t'},
+	'2': {'z'},
+}
+
+// dictionaryMatches finds every substring of the password that is a word in
+// one of the dictionaries, as is, reversed, or with l33t substitutions.
+func dictionaryMatches(password []rune, dicts []dictionary) []Match {
+	lower := []rune(strings.ToLower(string(password)))
+	reversed := make([]rune, len(lower))
+	for i, r := range lower {
+		reversed[len(lower)-1-i] = r
+	}
+	n := len(lower)
+
+	var matches []Match
+	for _, dict := range dicts {
+		for i := range n {
+			for j := i + minWordLength - 1; j < n; j++ {
+				token := string(password[i : j+1])
+
+				if rank, ok := dict.ranks[string(lower[i:j+1])]; ok {
+					matches = append(matches, wordMatch(token, string(lower[i:j+1]), i, j, rank, dict))
+				}
+
+				// The same substring of the reversed password, mapped back
+				// to where it is in the password.
+				word := string(reversed[n-1-j : n-i])
+				if rank, ok := dict.ranks[word]; ok && word != string(lower[i:j+1]) {
+					m := wordMatch(token, word, i, j, rank, dict)
+					m.Reversed = true
+					m.Guesses *= 2
+					matches = append(matches, m)
+				}
+
+				for _, word := range unl33t(lower[i : j+1]) {
+					if rank, ok := dict.ranks[word]; ok {
+						m := wordMatch(token, word, i, j, rank, dict)
+						m.L33t = true
+						m.Guesses *= l33tVariations(lower[i:j+1], []rune(word))
+						matches = append(matches, m)
+					}
+				}
+			}
+		}
+	}
+	return matches
+}
+
+func wordMatch(token, word string, i, j, rank int, dict dictionary) Match {
+	return Match{
+		Pattern:   PatternDictionary,
+		Start:     i,
+		End:       j,
+		Token:     token,
+		Guesses:   float64(rank) * uppercaseVariations(token),
+		Word:      word,
+		UserInput: dict.userInput,
+		Reversed:  false,
+		L33t:      false,
+	}
+}
+
+// maxL33tVariants bounds how many substitutions of a token are tried.
+const maxL33tVariants = 16
+
+// unl33t returns the words a token could be with its l33t characters
+// substituted back, if it has any.
+func unl33t(token []rune) []string {
+	variants := [][]rune{nil}
+	substituted := false
+	for _, r := range token {
+		subs, ok := l33tTable[r]
+		if !ok {
+			for k := range variants {
+				variants[k] = append(variants[k], r)
+			}
+			continue
+		}
+		substituted = true
+		var next [][]rune
+		for _, v := range variants {
+			for _, s := range subs {
+				if len(next) >= maxL33tVariants {
+					break
+				}
+				next = append(next, append(append([]rune(nil), v...), s))
+			}
+		}
+		variants = next
+	}
+	if !substituted {
+		return nil
+	}
+	words := make([]string, len(variants))
+	for k, v := range variants {
+		words[k] = string(v)
+	}
+	return words
+}
+
+// uppercaseVariations is how many ways the capitalisation of a token could
+// have been chosen. Common patterns, such as a capitalised first letter,
+// count as only two.
+func uppercaseVariations(token string) float64 {
+	upper, lower := 0, 0
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+	if upper == 0 || strings.ToLower(token) == token {
+		return 1
+	}
+	runes := []rune(token)
+	first, last := runes[0], runes[len(runes)-1]
+	if lower == 0 ||
+		(upper == 1 && unicode.IsUpper(first)) ||
+		(upper == 1 && unicode.IsUpper(last)) {
+		return 2
+	}
+	var variations float64
+	for k := 1; k <= min(upper, lower); k++ {
+		variations += nCk(upper+lower, k)
+	}
+	return variations
+}
+
+// l33tVariations is how many ways the l33t substitutions of a token could
+// have been chosen.
+func l33tVariations(token, word []rune) float64 {
+	variations := 1.0
+	counted := make(map[rune]bool)
+	for k, r := range token {
+		if r == word[k] || counted[r] {
+			continue
+		}
+		counted[r] = true
+		subbed, unsubbed := 0, 0
+		for m := range token {
+			switch {
+			case token[m] == r:
+				subbed++
+			case word[m] == word[k]:
+				unsubbed++
+			}
+		}
+		if unsubbed == 0 {
+			variations *= 2
+			continue
+		}
+		var v float64
+		for i := 1; i <= min(subbed, unsubbed); i++ {
+			v += nCk(subbed+unsubbed, i)
+		}
+		variations *= v
+	}
+	return variations
+}
+
+// keyboard is a keyboard layout, with every key given as its unshifted and
+// shifted character.
+type keyboard struct {
+	// position maps every character to its row and column. Rows are offset
+	// by half a key, as on a real keyboard.
+	position map[rune][2]int
+	shifted  map[rune]bool
+	// degree is the average number of neighbours of a key.
+	degree float64
+	keys   int
+}
+
+var qwerty = newKeyboard([]string{ //nolint:gochecknoglobals // makes more sense like this.
+	"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+",
+	"qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|",
+	"aA sS dD fF gG hH jJ kK lL ;: '\"",
+	"zZ xX cC vV bB nN mM ,< .> /?",
+})
+
+func newKeyboard(rows []string) keyboard {
+	kb := keyboard{position: make(map[rune][2]int), shifted: make(map[rune]bool), degree: 0, keys: 0}
+	for row, keys := range rows {
+		for col, key := range strings.Fields(keys) {
+			runes := []rune(key)
+			kb.position[runes[0]] = [2]int{row, col}
+			kb.position[runes[1]] = [2]int{row, col}
+			kb.shifted[runes[1]] = true
+			kb.keys++
+		}
+	}
+	neighbours := 0
+	for r := range kb.position {
+		if kb.shifted[r] {
+			continue
+		}
+		for s := range kb.position {
+			if !kb.shifted[s] && kb.adjacent(r, s) {
+				neighbours++
+			}
+		}
+	}
+	kb.degree = float64(neighbours) / float64(kb.keys)
+	return kb
+}
+
+// adjacent reports whether two keys are next to each other. Each row is
+// shifted right by half a key from the one above, so a key touches the two
+// keys above it at columns c and c+1, and below it at c-1 and c.
+func (kb keyboard) adjacent(a, b rune) bool {
+	pa, ok := kb.position[a]
+	if !ok {
+		return false
+	}
+	pb, ok := kb.position[b]
+	if !ok || pa == pb {
+		return false
+	}
+	dr, dc := pb[0]-pa[0], pb[1]-pa[1]
+	switch dr {
+	case 0:
+		return dc == -1 || dc == 1
+	case -1:
+		return dc == 0 || dc == 1
+	case 1:
+		return dc == -1 || dc == 0
+	default:
+		return false
+	}
+}
+
+// minSpatialLength is the shortest keyboard walk matched.
+const minSpatialLength = 3
+
+// spatialMatches finds walks of adjacent keys, such as `qwerty` or `zxcvb`.
+func spatialMatches(password []rune) []Match {
+	var matches []Match
+	n := len(password)
+	for i := 0; i < n-1; {
+		j, turns, shifted := i, 0, 0
+		direction := [2]int{}
+		if qwerty.shifted[password[i]] {
+			shifted++
+		}
+		for j+1 < n && qwerty.adjacent(password[j], password[j+1]) {
+			a, b := qwerty.position[password[j]], qwerty.position[password[j+1]]
+			d := [2]int{b[0] - a[0], b[1] - a[1]}
+			if d != direction {
+				turns++
+				direction = d
+			}
+			j++
+			if qwerty.shifted[password[j]] {
+				shifted++
+			}
+		}
+		if j-i+1 >= minSpatialLength {
+			matches = append(matches, Match{
+				Pattern: PatternSpatial,
+				Start:   i,
+				End:     j,
+				Token:   string(password[i : j+1]),
+				Guesses: spatialGuesses(j-i+1, turns, shifted),
+			})
+		}
+		i = max(j, i+1)
+	}
+	return matches
+}
+
+// spatialGuesses is how many keyboard walks of a length with at most a
+// number of turns there are, times the ways the shifted keys could have been
+// chosen.
+func spatialGuesses(length, turns, shifted int) float64 {
+	var guesses float64
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += nCk(i-1, j-1) * float64(qwerty.keys) * math.Pow(qwerty.degree, float64(j))
+		}
+	}
+	if shifted > 0 {
+		unshifted := length - shifted
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			var v float64
+			for i := 1; i <= min(shifted, unshifted); i++ {
+				v += nCk(length, i)
+			}
+			guesses *= v
+		}
+	}
+	return guesses
+}
+
+// repeatMatches finds a character or a group of characters repeated at
+// least twice, such as `aaaa` or `abcabc`.
+func repeatMatches(password []rune, dicts []dictionary) []Match {
+	var matches []Match
+	n := len(password)
+	for i := 0; i < n; {
+		best := Match{}
+		// Try every base length, and keep the longest repeat.
+		for base := 1; i+2*base <= n; base++ {
+			count := 1
+			for i+(count+1)*base <= n &&
+				string(password[i+count*base:i+(count+1)*base]) == string(password[i:i+base]) {
+				count++
+			}
+			if count < 2 || count*base <= best.End-best.Start+1 {
+				continue
+			}
+			unit := password[i : i+base]
+			unitGuesses, _ := mostGuessableSequence(unit, append(
+				dictionaryMatches(unit, dicts),
+				append(spatialMatches(unit), sequenceMatches(unit)...)...,
+			))
+			best = Match{
+				Pattern: PatternRepeat,
+				Start:   i,
+				End:     i + count*base - 1,
+				Token:   string(password[i : i+count*base]),
+				Guesses: unitGuesses * float64(count),
+			}
+		}
+		if best.Pattern == "" {
+			i++
+			continue
+		}
+		matches = append(matches, best)
+		i = best.End + 1
+	}
+	return matches
+}
+
+// minSequenceLength is the shortest sequence matched.
+const minSequenceLength = 3
+
+// sequenceMatches finds runs of characters with a constant step of one, such
+// as `abcd`, `4321` or `mnop`.
+func sequenceMatches(password []rune) []Match {
+	var matches []Match
+	n := len(password)
+	for i := 0; i < n-1; {
+		delta := password[i+1] - password[i]
+		if delta != 1 && delta != -1 {
+			i++
+			continue
+		}
+		j := i + 1
+		for j+1 < n && password[j+1]-password[j] == delta {
+			j++
+		}
+		if j-i+1 >= minSequenceLength {
+			matches = append(matches, Match{
+				Pattern: PatternSequence,
+				Start:   i,
+				End:     j,
+				Token:   string(password[i : j+1]),
+				Guesses: sequenceGuesses(password[i], j-i+1, delta),
+			})
+		}
+		i = j
+	}
+	return matches
+}
+
+// sequenceGuesses makes obvious starts, such as `a` or `1`, cheaper than
+// others, and descending sequences dearer.
+func sequenceGuesses(first rune, length int, delta rune) float64 {
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if delta < 0 {
+		base *= 2
+	}
+	return base * float64(length)
+}
+
+const (
+	minYear          = 1900
+	maxYear          = 2099
+	referenceYear    = 2025
+	minYearSpace     = 20
+	dateMonths       = 12
+	dateDays         = 31
+	compactDateShort = 6
+	compactDateLong  = 8
+	yearLength       = 4
+)
+
+// dateMatches finds years, such as `1987`, and dates written with only
+// digits, such as `150387` or `19870315`.
+func dateMatches(password []rune) []Match {
+	var matches []Match
+	n := len(password)
+	for i := range n {
+		for _, length := range []int{yearLength, compactDateShort, compactDateLong} {
+			if i+length > n || !allDigits(password[i:i+length]) {
+				continue
+			}
+			token := string(password[i : i+length])
+			year, ok := parseDate(token)
+			if !ok {
+				continue
+			}
+			guesses := math.Max(math.Abs(float64(year-referenceYear)), minYearSpace)
+			if length != yearLength {
+				guesses *= dateMonths * dateDays
+			}
+			matches = append(matches, Match{
+				Pattern: PatternDate,
+				Start:   i,
+				End:     i + length - 1,
+				Token:   token,
+				Guesses: guesses,
+			})
+		}
+	}
+	return matches
+}
+
+// parseDate returns the year of a digit token that reads as a year, or as a
+// date in year-month-day, day-month-year or month-day-year order.
+func parseDate(token string) (int, bool) {
+	digits := make([]int, len(token))
+	for i, r := range token {
+		digits[i] = int(r - '0')
+	}
+	num := func(from, to int) int {
+		v := 0
+		for _, d := range digits[from:to] {
+			v = v*10 + d
+		}
+		return v
+	}
+	validYear := func(y int) bool { return y >= minYear && y <= maxYear }
+	validDay := func(d, m int) bool { return m >= 1 && m <= dateMonths && d >= 1 && d <= dateDays }
+	twoDigitYear := func(y int) int {
+		const century, pivot = 100, 50
+		if y > pivot {
+			return minYear + y
+		}
+		return minYear + century + y
+	}
+
+	switch len(token) {
+	case yearLength:
+		y := num(0, 4)
+		return y, validYear(y)
+	case compactDateLong:
+		if y := num(0, 4); validYear(y) && validDay(num(6, 8), num(4, 6)) {
+			return y, true
+		}
+		if y := num(4, 8); validYear(y) &&
+			(validDay(num(0, 2), num(2, 4)) || validDay(num(2, 4), num(0, 2))) {
+			return y, true
+		}
+	case compactDateShort:
+		if validDay(num(0, 2), num(2, 4)) || validDay(num(2, 4), num(0, 2)) {
+			return twoDigitYear(num(4, 6)), true
+		}
+		if validDay(num(4, 6), num(2, 4)) {
+			return twoDigitYear(num(0, 2)), true
+		}
+	}
+	return 0, false
+}
+
+func allDigits(runes []rune) bool {
+	for _, r := range runes {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package strength estimates how many guesses an attacker needs to crack a
+// password. It follows the approach of Dropbox's zxcvbn: the password is
+// split into guessable patterns, such as dictionary words, keyboard walks,
+// repeats, sequences and dates, and the cheapest way to cover the whole
+// password with those patterns is its estimated cost.
+package strength
+
+import (
+	"math"
+)
+
+// Pattern is the kind of guessable pattern a match is.
+type Pattern string
+
+const (
+	PatternDictionary Pattern = "dictionary"
+	PatternSpatial    Pattern = "spatial"
+	PatternRepeat     Pattern = "repeat"
+	PatternSequence   Pattern = "sequence"
+	PatternDate       Pattern = "date"
+	PatternBruteforce Pattern = "bruteforce"
+)
+
+// Match is a part of a password that follows a guessable pattern.
+type Match struct {
+	Pattern Pattern
+	// Start and End are the rune offsets of the match in the password. End
+	// is inclusive.
+	Start, End int
+	// Token is the matched part of the password.
+	Token string
+	// Guesses is how many guesses the match takes on its own.
+	Guesses float64
+	// Word is the dictionary word a dictionary match matched.
+	Word string
+	// UserInput reports whether a dictionary match matched one of the user
+	// inputs rather than the built-in dictionary.
+	UserInput bool
+	// Reversed reports whether a dictionary match is a reversed word.
+	Reversed bool
+	// L33t reports whether a dictionary match uses character substitutions,
+	// such as `p4ssw0rd`.
+	L33t bool
+}
+
+// Result is the estimated strength of a password.
+type Result struct {
+	// Guesses is the estimated number of guesses needed to crack the
+	// password.
+	Guesses float64
+	// Score is a rating from 0, too guessable, to 4, very unguessable.
+	Score int
+	// Sequence is the cheapest way to cover the password with matches.
+	Sequence []Match
+}
+
+// maxLength is the length up to which passwords are analysed. Anything
+// longer is strong enough that the analysis is not worth its cost.
+const maxLength = 100
+
+// Estimate estimates the strength of a password. User inputs, such as parts
+// of the user's email address or the name of the site, are treated as the
+// most guessable dictionary words. They are matched whole and regardless of
+// case.
+func Estimate(password string, userInputs ...string) Result {
+	runes := []rune(password)
+	if len(runes) > maxLength {
+		runes = runes[:maxLength]
+	}
+	if len(runes) == 0 {
+		return Result{Guesses: 1, Score: 0, Sequence: nil}
+	}
+
+	dicts := rankedDictionaries(userInputs)
+	var matches []Match
+	matches = append(matches, dictionaryMatches(runes, dicts)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, dicts)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, dateMatches(runes)...)
+
+	guesses, sequence := mostGuessableSequence(runes, matches)
+	return Result{Guesses: guesses, Score: score(guesses), Sequence: sequence}
+}
+
+// score rates a number of guesses. The thresholds assume an online attack
+// is throttled, and an offline attack is slowed by a strong password hash.
+func score(guesses float64) int {
+	const delta = 5
+	switch {
+	case guesses < 1e3+delta:
+		return 0
+	case guesses < 1e6+delta:
+		return 1
+	case guesses < 1e8+delta:
+		return 2
+	case guesses < 1e10+delta:
+		return 3
+	default:
+		return 4
+	}
+}
+
+const (
+	// bruteforceCardinality is the guesses per character of a part of a
+	// password that follows no pattern.
+	bruteforceCardinality = 10
+	// minSubmatchGuessesSingleChar and minSubmatchGuessesMultiChar are the
+	// least guesses a match that does not cover the whole password takes.
+	minSubmatchGuessesSingleChar = 10
+	minSubmatchGuessesMultiChar  = 50
+	// minGuessesBeforeGrowingSequence penalises covering a password with
+	// more matches, since an attacker must also guess how many there are.
+	minGuessesBeforeGrowingSequence = 10000
+)
+
+// mostGuessableSequence finds the sequence of non-overlapping matches, with
+// bruteforce filling the gaps, that covers the password in the fewest
+// guesses. A sequence of l matches takes
+//
+//	l! * product(guesses of each match) + 10000^(l-1)
+//
+// guesses, since the attacker does not know the order or number of matches.
+func mostGuessableSequence(password []rune, matches []Match) (float64, []Match) {
+	n := len(password)
+	byEnd := make([][]Match, n)
+	for _, m := range matches {
+		byEnd[m.End] = append(byEnd[m.End], m)
+	}
+
+	// For every end position k and sequence length l, the best last match,
+	// the product of its sequence, and the total guesses.
+	type entry struct {
+		match Match
+		pi    float64
+		g     float64
+	}
+	optimal := make([]map[int]entry, n)
+	for k := range optimal {
+		optimal[k] = make(map[int]entry)
+	}
+
+	update := func(m Match, l int) {
+		k := m.End
+		pi := estimateGuesses(m, n)
+		if l > 1 {
+			pi *= optimal[m.Start-1][l-1].pi
+		}
+		g := factorial(l)*pi + math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+		for otherL, other := range optimal[k] {
+			if otherL <= l && other.g <= g {
+				return
+			}
+		}
+		optimal[k][l] = entry{match: m, pi: pi, g: g}
+	}
+
+	bruteforce := func(i, j int) Match {
+		return Match{Pattern: PatternBruteforce, Start: i, End: j, Token: string(password[i : j+1])}
+	}
+
+	for k := range n {
+		for _, m := range byEnd[k] {
+			if m.Start == 0 {
+				update(m, 1)
+				continue
+			}
+			for l := range optimal[m.Start-1] {
+				update(m, l+1)
+			}
+		}
+		update(bruteforce(0, k), 1)
+		for i := 1; i <= k; i++ {
+			for l, last := range optimal[i-1] {
+				// Two bruteforce matches in a row are one bruteforce match.
+				if last.match.Pattern == PatternBruteforce {
+					continue
+				}
+				update(bruteforce(i, k), l+1)
+			}
+		}
+	}
+
+	bestL, best := 0, math.Inf(1)
+	for l, e := range optimal[n-1] {
+		if e.g < best {
+			bestL, best = l, e.g
+		}
+	}
+
+	sequence := make([]Match, bestL)
+	k := n - 1
+	for l := bestL; l > 0; l-- {
+		m := optimal[k][l].match
+		m.Guesses = estimateGuesses(m, n)
+		sequence[l-1] = m
+		k = m.Start - 1
+	}
+	return best, sequence
+}
+
+// estimateGuesses returns the guesses a match takes, given the length of the
+// password it is part of.
+func estimateGuesses(m Match, passwordLength int) float64 {
+	length := m.End - m.Start + 1
+	minGuesses := 1.0
+	if length < passwordLength {
+		minGuesses = minSubmatchGuessesMultiChar
+		if length == 1 {
+			minGuesses = minSubmatchGuessesSingleChar
+		}
+	}
+
+	var guesses float64
+	switch m.Pattern {
+	case PatternBruteforce:
+		guesses = math.Pow(bruteforceCardinality, float64(length))
+		// A bruteforce match is never cheaper than the match it stands in
+		// for.
+		minGuesses++
+	default:
+		guesses = m.Guesses
+	}
+	return math.Max(guesses, minGuesses)
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
+
+// nCk returns the binomial coefficient.
+func nCk(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	r := 1.0
+	for d := 1; d <= k; d++ {
+		r *= float64(n)
+		r /= float64(d)
+		n--
+	}
+	return r
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package strength_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/strength"
+	"go.brokedaear.com/pkg/test"
+)
+
+func TestEstimate_Patterns(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		password string
+	}{
+		{CaseBase: test.NewCaseBase("common password", strength.PatternDictionary, false), password: "password"},
+		{CaseBase: test.NewCaseBase("capitalised", strength.PatternDictionary, false), password: "Password"},
+		{CaseBase: test.NewCaseBase("l33t", strength.PatternDictionary, false), password: "p@55w0rd"},
+		{CaseBase: test.NewCaseBase("reversed", strength.PatternDictionary, false), password: "drowssap"},
+		{CaseBase: test.NewCaseBase("keyboard walk", strength.PatternSpatial, false), password: "xsw23edc"},
+		{CaseBase: test.NewCaseBase("repeated character", strength.PatternRepeat, false), password: "zzzzzzzzzz"},
+		{CaseBase: test.NewCaseBase("repeated group", strength.PatternRepeat, false), password: "xq9xq9xq9xq9"},
+		{CaseBase: test.NewCaseBase("sequence", strength.PatternSequence, false), password: "lmnopqrs"},
+		{CaseBase: test.NewCaseBase("descending sequence", strength.PatternSequence, false), password: "98765432"},
+		{CaseBase: test.NewCaseBase("date", strength.PatternDate, false), password: "19870315"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				result := strength.Estimate(tt.password)
+				assert.Equal(t, len(result.Sequence), 1)
+				assert.Equal(t, result.Sequence[0].Pattern, tt.Want.(strength.Pattern))
+				assert.True(t, result.Score <= 2)
+			},
+		)
+	}
+}
+
+func TestEstimate_Score(t *testing.T) {
+	tests := []struct {
+		test.CaseBase
+		password string
+	}{
+		{CaseBase: test.NewCaseBase("empty", 0, false), password: ""},
+		{CaseBase: test.NewCaseBase("most common", 0, false), password: "123456"},
+		{CaseBase: test.NewCaseBase("common with suffix", 0, false), password: "password123"},
+		{CaseBase: test.NewCaseBase("word with date", 1, false), password: "sunshine1987"},
+		{CaseBase: test.NewCaseBase("passphrase", 4, false), password: "correct horse battery staple"},
+		{CaseBase: test.NewCaseBase("random", 4, false), password: "x7#Lq9!vB2pz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				assert.Equal(t, strength.Estimate(tt.password).Score, tt.Want.(int))
+			},
+		)
+	}
+}
+
+func TestEstimate_UserInputs(t *testing.T) {
+	const password = "Kaimana2019!"
+	without := strength.Estimate(password)
+	with := strength.Estimate(password, "kaimana")
+
+	assert.True(t, with.Guesses < without.Guesses)
+	var matched bool
+	for _, m := range with.Sequence {
+		if m.UserInput {
+			matched = true
+			assert.Equal(t, m.Word, "kaimana")
+		}
+	}
+	assert.True(t, matched)
+}
+
+func TestEstimate_Sequence(t *testing.T) {
+	result := strength.Estimate("qwertydragon1990")
+
+	// The sequence covers the whole password without gaps or overlaps.
+	next := 0
+	var tokens []string
+	for _, m := range result.Sequence {
+		assert.Equal(t, m.Start, next)
+		next = m.End + 1
+		tokens = append(tokens, m.Token)
+	}
+	assert.Equal(t, strings.Join(tokens, ""), "qwertydragon1990")
+}
+
+func TestEstimate_LongPassword(t *testing.T) {
+	// Only the start of a long password is analysed, and a long password
+	// that repeats itself is still guessable.
+	result := strength.Estimate(strings.Repeat("x9!Lq", 1000))
+	last := result.Sequence[len(result.Sequence)-1]
+	assert.Equal(t, last.End, 99)
+	assert.True(t, result.Score < 4)
+}
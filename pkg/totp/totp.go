@@ -0,0 +1,128 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package totp implements time-based one-time passwords as specified by RFC
+// 6238, the codes shown by authenticator apps.
+//
+// A code is the HOTP value (RFC 4226) of a shared secret and the number of
+// periods since the Unix epoch, the time step. Only HMAC-SHA1 is supported,
+// since it is the only algorithm every authenticator app implements.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // RFC 6238 codes are HMAC-SHA1 by default.
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// SecretLength is the length of generated secrets, the length of an SHA-1
+// output as RFC 4226 recommends.
+const SecretLength = 20
+
+// Params are the parameters that a server and an authenticator app agree on.
+type Params struct {
+	// Digits is the number of digits in a code, usually 6.
+	Digits int
+	// Period is how long a code is valid, usually 30 seconds.
+	Period time.Duration
+	// Skew is how many time steps before and after the current one are
+	// accepted as well, to allow for clocks that are off and codes typed in
+	// slowly.
+	Skew int
+}
+
+// DefaultParams returns the parameters that authenticator apps assume: 6
+// digit codes that change every 30 seconds. Codes of the step before and
+// after the current one are accepted.
+func DefaultParams() Params {
+	return Params{Digits: 6, Period: 30 * time.Second, Skew: 1}
+}
+
+// GenerateSecret generates a new random secret.
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, SecretLength)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate totp secret")
+	}
+	return secret, nil
+}
+
+// EncodeSecret encodes a secret in unpadded base32, the form that people type
+// into authenticator apps.
+func EncodeSecret(secret []byte) string {
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(secret)
+}
+
+// Step returns the time step of a point in time.
+func (p Params) Step(at time.Time) int64 {
+	return at.Unix() / int64(p.Period/time.Second)
+}
+
+// Code returns the code of a secret at a time step.
+func (p Params) Code(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step)) //nolint:gosec // Steps are never negative.
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation, RFC 4226 section 5.3.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for range p.Digits {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", p.Digits, value%modulo)
+}
+
+// Validate checks a code against a secret at a point in time, allowing for
+// the skew. It returns the time step the code belongs to, so that the caller
+// can refuse a code, or an earlier one, that was already used.
+func (p Params) Validate(secret []byte, code string, at time.Time) (int64, bool) {
+	if len(code) != p.Digits {
+		return 0, false
+	}
+	current := p.Step(at)
+	matched, ok := int64(0), false
+	// Every step in the window is checked, so that the time taken does not
+	// tell which one matched.
+	for step := current - int64(p.Skew); step <= current+int64(p.Skew); step++ {
+		if subtle.ConstantTimeCompare([]byte(p.Code(secret, step)), []byte(code)) == 1 {
+			matched, ok = step, true
+		}
+	}
+	return matched, ok
+}
+
+// URI returns the otpauth URI of a secret, which authenticator apps read from
+// a QR code. The issuer names the service and the account names the
+// customer, usually by their email address.
+//
+// See: https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func (p Params) URI(issuer, account string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", EncodeSecret(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(p.Digits))
+	q.Set("period", strconv.Itoa(int(p.Period/time.Second)))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package totp_test
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+	"go.brokedaear.com/pkg/totp"
+)
+
+// rfcSecret is the SHA-1 secret of the test vectors of RFC 6238.
+const rfcSecret = "12345678901234567890"
+
+func TestParams_Code(t *testing.T) {
+	params := totp.Params{Digits: 8, Period: 30 * time.Second, Skew: 0}
+
+	// RFC 6238, appendix B.
+	tests := []struct {
+		test.CaseBase
+		unix int64
+	}{
+		{CaseBase: test.NewCaseBase("59", "94287082", false), unix: 59},
+		{CaseBase: test.NewCaseBase("1111111109", "07081804", false), unix: 1111111109},
+		{CaseBase: test.NewCaseBase("1111111111", "14050471", false), unix: 1111111111},
+		{CaseBase: test.NewCaseBase("1234567890", "89005924", false), unix: 1234567890},
+		{CaseBase: test.NewCaseBase("2000000000", "69279037", false), unix: 2000000000},
+		{CaseBase: test.NewCaseBase("20000000000", "65353130", false), unix: 20000000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				step := params.Step(time.Unix(tt.unix, 0))
+				assert.Equal(t, params.Code([]byte(rfcSecret), step), tt.Want.(string))
+			},
+		)
+	}
+}
+
+func TestParams_Validate(t *testing.T) {
+	params := totp.DefaultParams()
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	current := params.Step(now)
+
+	tests := []struct {
+		test.CaseBase
+		code string
+	}{
+		{CaseBase: test.NewCaseBase("current", current, false), code: params.Code(secret, current)},
+		{CaseBase: test.NewCaseBase("previous", current-1, false), code: params.Code(secret, current-1)},
+		{CaseBase: test.NewCaseBase("next", current+1, false), code: params.Code(secret, current+1)},
+		{CaseBase: test.NewCaseBase("too old", nil, true), code: params.Code(secret, current-2)},
+		{CaseBase: test.NewCaseBase("too short", nil, true), code: params.Code(secret, current)[1:]},
+		{CaseBase: test.NewCaseBase("empty", nil, true), code: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				step, ok := params.Validate(secret, tt.code, now)
+				assert.Equal(t, ok, !tt.WantErr)
+				if ok {
+					assert.Equal(t, step, tt.Want.(int64))
+				}
+			},
+		)
+	}
+}
+
+func TestParams_URI(t *testing.T) {
+	params := totp.DefaultParams()
+	uri := params.URI("BROKE DA EAR", "kai@brokedaear.com", []byte(rfcSecret))
+
+	u, err := url.Parse(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, u.Scheme, "otpauth")
+	assert.Equal(t, u.Host, "totp")
+	assert.Equal(t, u.Path, "/BROKE DA EAR:kai@brokedaear.com")
+	assert.Equal(t, u.Query().Get("secret"), "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	assert.Equal(t, u.Query().Get("issuer"), "BROKE DA EAR")
+	assert.Equal(t, u.Query().Get("digits"), "6")
+	assert.Equal(t, u.Query().Get("period"), "30")
+}
@@ -0,0 +1,202 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthn
+
+import (
+	"math"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// CBOR major types, RFC 8949 section 3.1.
+const (
+	cborUnsigned = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// maxCBORDepth bounds how deeply arrays and maps may nest. Authenticators
+// never nest more than a few levels, and the bound keeps a hostile client
+// from exhausting the stack.
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the first CBOR data item of b, and returns it along
+// with the bytes that follow it. Only what authenticators send is
+// supported, which is definite length items:
+//
+//   - Integers decode to int64.
+//   - Byte strings decode to []byte, and text strings to string.
+//   - Arrays decode to []any, and maps to map[any]any. Map keys must be
+//     integers or text strings.
+//   - Tags are dropped, leaving the tagged item.
+//   - false, true, null and undefined decode to bool and nil, and floats to
+//     float64.
+func decodeCBOR(b []byte) (any, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, errors.Wrap(ErrMalformed, "cbor nests too deeply")
+	}
+	major, arg, rest, err := decodeCBORHead(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.Wrap(ErrMalformed, "cbor integer overflows")
+		}
+		return int64(arg), rest, nil
+	case cborNegative:
+		if arg > math.MaxInt64 {
+			return nil, nil, errors.Wrap(ErrMalformed, "cbor integer overflows")
+		}
+		return -1 - int64(arg), rest, nil
+	case cborBytes, cborText:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.Wrap(ErrMalformed, "cbor string is truncated")
+		}
+		s := rest[:arg]
+		if major == cborText {
+			return string(s), rest[arg:], nil
+		}
+		return append([]byte(nil), s...), rest[arg:], nil
+	case cborArray:
+		// Every item takes at least a byte, which bounds the allocation.
+		if arg > uint64(len(rest)) {
+			return nil, nil, errors.Wrap(ErrMalformed, "cbor array is truncated")
+		}
+		items := make([]any, arg)
+		for i := range items {
+			items[i], rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		return items, rest, nil
+	case cborMap:
+		if arg > uint64(len(rest))/2 {
+			return nil, nil, errors.Wrap(ErrMalformed, "cbor map is truncated")
+		}
+		m := make(map[any]any, arg)
+		for range arg {
+			var key, value any
+			key, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.Wrap(ErrMalformed, "unsupported cbor map key")
+			}
+			if _, ok := m[key]; ok {
+				return nil, nil, errors.Wrap(ErrMalformed, "duplicate cbor map key")
+			}
+			value, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = value
+		}
+		return m, rest, nil
+	case cborTag:
+		return decodeCBORItem(rest, depth+1)
+	default:
+		return decodeCBORSimple(b[0]&0x1f, arg, rest)
+	}
+}
+
+// decodeCBORHead decodes the initial byte of a data item and the argument
+// that follows it.
+func decodeCBORHead(b []byte) (byte, uint64, []byte, error) {
+	if len(b) == 0 {
+		return 0, 0, nil, errors.Wrap(ErrMalformed, "cbor is truncated")
+	}
+	major, info, rest := b[0]>>5, b[0]&0x1f, b[1:]
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), rest, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, nil, errors.Wrap(ErrMalformed, "unsupported cbor length")
+	}
+	if len(rest) < size {
+		return 0, 0, nil, errors.Wrap(ErrMalformed, "cbor is truncated")
+	}
+
+	var arg uint64
+	for _, c := range rest[:size] {
+		arg = arg<<8 | uint64(c)
+	}
+	return major, arg, rest[size:], nil
+}
+
+func decodeCBORSimple(info byte, arg uint64, rest []byte) (any, []byte, error) {
+	const (
+		simpleFalse     = 20
+		simpleTrue      = 21
+		simpleNull      = 22
+		simpleUndefined = 23
+		halfFloat       = 25
+		singleFloat     = 26
+		doubleFloat     = 27
+	)
+	switch {
+	case info == halfFloat:
+		return halfToFloat(uint16(arg)), rest, nil
+	case info == singleFloat:
+		return float64(math.Float32frombits(uint32(arg))), rest, nil //nolint:gosec // The argument is 4 bytes long.
+	case info == doubleFloat:
+		return math.Float64frombits(arg), rest, nil
+	case arg == simpleFalse:
+		return false, rest, nil
+	case arg == simpleTrue:
+		return true, rest, nil
+	case arg == simpleNull, arg == simpleUndefined:
+		return nil, rest, nil
+	default:
+		return nil, nil, errors.Wrap(ErrMalformed, "unsupported cbor simple value")
+	}
+}
+
+// halfToFloat converts an IEEE 754 half precision float.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		f = math.Inf(1)
+		if mant != 0 {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,424 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webauthn implements the relying party side of the Web
+// Authentication registration and authentication ceremonies, with which
+// customers sign in with passkeys.
+//
+// Only what a relying party that does not care about attestation needs is
+// implemented:
+//
+//   - Attestation objects must use the "none" format. Browsers use it
+//     whenever the relying party asks for no attestation, so which
+//     authenticator made a credential is never verified.
+//   - Credential public keys must be ES256 (ECDSA on P-256 with SHA-256) or
+//     EdDSA (Ed25519) COSE keys, which is what passkey providers create.
+//
+// The browser side of a ceremony is run by navigator.credentials, with
+// options the backend sends the client, and the client sends back what the
+// browser returns unchanged.
+package webauthn
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"slices"
+
+	"go.brokedaear.com/pkg/errors"
+)
+
+// ChallengeLength is the length of generated challenges. The specification
+// asks for at least 16 random bytes.
+const ChallengeLength = 32
+
+// COSE algorithm identifiers of the supported public keys.
+const (
+	AlgES256 int64 = -7
+	AlgEdDSA int64 = -8
+)
+
+// Authenticator data flags, section 6.1 of the specification.
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagBackupEligible   = 0x08
+	flagBackedUp         = 0x10
+	flagAttestedCredData = 0x40
+	flagExtensionData    = 0x80
+)
+
+// Client data types of the two ceremonies.
+const (
+	typeCreate = "webauthn.create"
+	typeGet    = "webauthn.get"
+)
+
+// RelyingParty is the website credentials are scoped to.
+type RelyingParty struct {
+	// ID is the relying party ID, the domain credentials are bound to, such
+	// as "brokedaear.com".
+	ID string
+	// Name is the name authenticators show the customer.
+	Name string
+	// Origins are the origins ceremonies may run on, such as
+	// "https://brokedaear.com".
+	Origins []string
+	// RequireUserVerification requires the authenticator to verify the
+	// customer, with a PIN or biometrics, on top of testing their presence.
+	RequireUserVerification bool
+}
+
+// Credential is a public key credential that was registered.
+type Credential struct {
+	// ID is the credential ID the authenticator chose.
+	ID []byte
+	// PublicKey is the COSE encoded public key of the credential.
+	PublicKey []byte
+	// Algorithm is the COSE algorithm of the public key.
+	Algorithm int64
+	// SignCount is the signature counter of the authenticator. Authenticators
+	// that do not count signatures, such as most passkey providers, always
+	// report 0.
+	SignCount uint32
+	// AAGUID identifies the model of the authenticator. It is all zeros for
+	// many authenticators.
+	AAGUID []byte
+	// BackupEligible reports whether the credential can be synced to other
+	// devices, which makes it a passkey rather than a device-bound
+	// credential.
+	BackupEligible bool
+	// BackedUp reports whether the credential is synced to other devices.
+	BackedUp bool
+}
+
+// Assertion is what the browser returns at the end of an authentication
+// ceremony.
+type Assertion struct {
+	// CredentialID is the ID of the credential that signed.
+	CredentialID []byte
+	// ClientDataJSON is the client data the browser collected.
+	ClientDataJSON []byte
+	// AuthenticatorData is the authenticator data that was signed.
+	AuthenticatorData []byte
+	// Signature is the signature over the authenticator data and the hash of
+	// the client data.
+	Signature []byte
+	// UserHandle is the user handle the credential was created for. It is
+	// empty if the authenticator does not return it.
+	UserHandle []byte
+}
+
+// NewChallenge generates a new random challenge. A challenge must be used
+// for a single ceremony only.
+func NewChallenge() ([]byte, error) {
+	challenge := make([]byte, ChallengeLength)
+	_, err := rand.Read(challenge)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate webauthn challenge")
+	}
+	return challenge, nil
+}
+
+// VerifyRegistration verifies the result of a registration ceremony started
+// with challenge, as specified by section 7.1 of the specification, and
+// returns the new credential.
+func (rp RelyingParty) VerifyRegistration(
+	challenge, clientDataJSON, attestationObject []byte,
+) (*Credential, error) {
+	err := rp.checkClientData(clientDataJSON, typeCreate, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, rest, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := obj.(map[any]any)
+	if !ok || len(rest) > 0 {
+		return nil, errors.Wrap(ErrMalformed, "invalid attestation object")
+	}
+	format, _ := m["fmt"].(string)
+	statement, _ := m["attStmt"].(map[any]any)
+	raw, ok := m["authData"].([]byte)
+	if !ok {
+		return nil, errors.Wrap(ErrMalformed, "attestation object has no authenticator data")
+	}
+	if format != "none" || len(statement) > 0 {
+		return nil, errors.Wrapf(ErrUnsupportedAttestation, "attestation format %q", format)
+	}
+
+	data, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+	err = rp.checkAuthenticatorData(data)
+	if err != nil {
+		return nil, err
+	}
+	if data.flags&flagAttestedCredData == 0 {
+		return nil, errors.Wrap(ErrMalformed, "authenticator data has no credential")
+	}
+	alg, _, err := ParsePublicKey(data.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credential{
+		ID:             data.credentialID,
+		PublicKey:      data.publicKey,
+		Algorithm:      alg,
+		SignCount:      data.signCount,
+		AAGUID:         data.aaguid,
+		BackupEligible: data.flags&flagBackupEligible != 0,
+		BackedUp:       data.flags&flagBackedUp != 0,
+	}, nil
+}
+
+// VerifyAssertion verifies the result of an authentication ceremony started
+// with challenge, as specified by section 7.2 of the specification. The
+// assertion must be signed by the credential with the COSE public key, whose
+// stored signature counter is signCount. It returns the new signature
+// counter, which must be stored.
+//
+// If the authenticator counts signatures, the counter must have gone up
+// since the last assertion. A counter that did not suggests the credential
+// was cloned, and the assertion is rejected with ErrSignCountRegressed.
+func (rp RelyingParty) VerifyAssertion(
+	challenge, publicKey []byte,
+	signCount uint32,
+	assertion Assertion,
+) (uint32, error) {
+	err := rp.checkClientData(assertion.ClientDataJSON, typeGet, challenge)
+	if err != nil {
+		return 0, err
+	}
+	data, err := parseAuthenticatorData(assertion.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	err = rp.checkAuthenticatorData(data)
+	if err != nil {
+		return 0, err
+	}
+
+	alg, key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(assertion.ClientDataJSON)
+	signed := slices.Concat(assertion.AuthenticatorData, clientDataHash[:])
+	err = verifySignature(alg, key, signed, assertion.Signature)
+	if err != nil {
+		return 0, err
+	}
+
+	if (data.signCount != 0 || signCount != 0) && data.signCount <= signCount {
+		return 0, ErrSignCountRegressed
+	}
+	return data.signCount, nil
+}
+
+// clientData is the part of the client data a relying party checks.
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp RelyingParty) checkClientData(raw []byte, typ string, challenge []byte) error {
+	var data clientData
+	err := json.Unmarshal(raw, &data)
+	if err != nil {
+		return errors.Wrap(ErrMalformed, "invalid client data")
+	}
+	if data.Type != typ {
+		return errors.Wrapf(ErrMalformed, "client data type is %q", data.Type)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(data.Challenge)
+	if err != nil || len(challenge) == 0 || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return ErrChallengeMismatch
+	}
+	if data.CrossOrigin || !slices.Contains(rp.Origins, data.Origin) {
+		return errors.Wrapf(ErrOriginMismatch, "origin %q", data.Origin)
+	}
+	return nil
+}
+
+func (rp RelyingParty) checkAuthenticatorData(data *authenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	switch {
+	case !bytes.Equal(data.rpIDHash, rpIDHash[:]):
+		return ErrRelyingPartyMismatch
+	case data.flags&flagUserPresent == 0:
+		return ErrUserNotPresent
+	case rp.RequireUserVerification && data.flags&flagUserVerified == 0:
+		return ErrUserNotVerified
+	}
+	return nil
+}
+
+// authenticatorData is parsed authenticator data, section 6.1 of the
+// specification. The credential fields are only set if the attested
+// credential data flag is.
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// maxCredentialIDLength is the longest credential ID the specification
+// allows.
+const maxCredentialIDLength = 1023
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	const (
+		rpIDHashLength = sha256.Size
+		headerLength   = rpIDHashLength + 1 + 4
+		aaguidLength   = 16
+	)
+	if len(b) < headerLength {
+		return nil, errors.Wrap(ErrMalformed, "authenticator data is truncated")
+	}
+	data := &authenticatorData{
+		rpIDHash:     b[:rpIDHashLength],
+		flags:        b[rpIDHashLength],
+		signCount:    binary.BigEndian.Uint32(b[rpIDHashLength+1 : headerLength]),
+		aaguid:       nil,
+		credentialID: nil,
+		publicKey:    nil,
+	}
+	rest := b[headerLength:]
+
+	if data.flags&flagAttestedCredData != 0 {
+		if len(rest) < aaguidLength+2 {
+			return nil, errors.Wrap(ErrMalformed, "attested credential data is truncated")
+		}
+		data.aaguid = rest[:aaguidLength]
+		n := int(binary.BigEndian.Uint16(rest[aaguidLength:]))
+		rest = rest[aaguidLength+2:]
+		if n == 0 || n > maxCredentialIDLength || len(rest) < n {
+			return nil, errors.Wrap(ErrMalformed, "invalid credential id")
+		}
+		data.credentialID, rest = rest[:n], rest[n:]
+
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+		data.publicKey, rest = rest[:len(rest)-len(after)], after
+	}
+	if data.flags&flagExtensionData != 0 {
+		var err error
+		_, rest, err = decodeCBOR(rest)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(rest) > 0 {
+		return nil, errors.Wrap(ErrMalformed, "authenticator data has trailing bytes")
+	}
+	return data, nil
+}
+
+// COSE key parameters, RFC 9053.
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2
+	coseY         = -3
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseCurveP256  = 1
+	coseCurveEd25  = 6
+)
+
+// ParsePublicKey parses a COSE encoded public key of a supported algorithm,
+// and returns the algorithm along with the key.
+func ParsePublicKey(cose []byte) (int64, crypto.PublicKey, error) {
+	v, rest, err := decodeCBOR(cose)
+	if err != nil {
+		return 0, nil, err
+	}
+	m, ok := v.(map[any]any)
+	if !ok || len(rest) > 0 {
+		return 0, nil, errors.Wrap(ErrMalformed, "invalid cose key")
+	}
+	kty, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseAlgorithm)].(int64)
+	crv, _ := m[int64(coseCurve)].(int64)
+	x, _ := m[int64(coseX)].([]byte)
+
+	switch {
+	case alg == AlgES256 && kty == coseKeyTypeEC2 && crv == coseCurveP256:
+		const coordinateLength = 32
+		y, _ := m[int64(coseY)].([]byte)
+		if len(x) != coordinateLength || len(y) != coordinateLength {
+			return 0, nil, errors.Wrap(ErrMalformed, "invalid P-256 key")
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		//nolint:staticcheck // There is no replacement that takes coordinates.
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return 0, nil, errors.Wrap(ErrMalformed, "point is not on curve")
+		}
+		return alg, key, nil
+	case alg == AlgEdDSA && kty == coseKeyTypeOKP && crv == coseCurveEd25:
+		if len(x) != ed25519.PublicKeySize {
+			return 0, nil, errors.Wrap(ErrMalformed, "invalid Ed25519 key")
+		}
+		return alg, ed25519.PublicKey(x), nil
+	default:
+		return 0, nil, errors.Wrapf(ErrUnsupportedAlgorithm, "cose algorithm %d", alg)
+	}
+}
+
+// verifySignature verifies an assertion signature. ES256 signatures are
+// ASN.1 encoded, unlike in JWS.
+func verifySignature(alg int64, key crypto.PublicKey, signed, signature []byte) error {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		if alg == AlgES256 && ecdsa.VerifyASN1(k, digest[:], signature) {
+			return nil
+		}
+	case ed25519.PublicKey:
+		if alg == AlgEdDSA && ed25519.Verify(k, signed, signature) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+var (
+	ErrMalformed              = errors.New("malformed webauthn response")
+	ErrChallengeMismatch      = errors.New("webauthn challenge does not match")
+	ErrOriginMismatch         = errors.New("webauthn origin is not allowed")
+	ErrRelyingPartyMismatch   = errors.New("credential is scoped to another relying party")
+	ErrUserNotPresent         = errors.New("authenticator did not test user presence")
+	ErrUserNotVerified        = errors.New("authenticator did not verify the user")
+	ErrUnsupportedAttestation = errors.New("unsupported attestation format")
+	ErrUnsupportedAlgorithm   = errors.New("unsupported public key algorithm")
+	ErrInvalidSignature       = errors.New("invalid webauthn signature")
+	ErrSignCountRegressed     = errors.New("signature counter did not increase")
+)
@@ -0,0 +1,262 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package webauthn_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.brokedaear.com/pkg/assert"
+	"go.brokedaear.com/pkg/test"
+	"go.brokedaear.com/pkg/webauthn"
+	"go.brokedaear.com/pkg/webauthn/webauthntest"
+)
+
+const (
+	testRPID   = "brokedaear.com"
+	testOrigin = "https://brokedaear.com"
+)
+
+func testRelyingParty() webauthn.RelyingParty {
+	return webauthn.RelyingParty{
+		ID:                      testRPID,
+		Name:                    "BROKE DA EAR",
+		Origins:                 []string{testOrigin},
+		RequireUserVerification: true,
+	}
+}
+
+func TestRelyingParty_VerifyRegistration(t *testing.T) {
+	challenge, err := webauthn.NewChallenge()
+	assert.NoError(t, err)
+
+	tests := []struct {
+		test.CaseBase
+		authenticator func() *webauthntest.Authenticator
+		challenge     []byte
+		// mangle changes the attestation object the authenticator returns.
+		mangle func([]byte) []byte
+	}{
+		{
+			CaseBase:      test.NewCaseBase("es256", webauthn.AlgES256, false),
+			authenticator: func() *webauthntest.Authenticator { return webauthntest.NewAuthenticator(testRPID, testOrigin) },
+			challenge:     challenge,
+			mangle:        nil,
+		},
+		{
+			CaseBase: test.NewCaseBase("eddsa", webauthn.AlgEdDSA, false),
+			authenticator: func() *webauthntest.Authenticator {
+				a := webauthntest.NewAuthenticator(testRPID, testOrigin)
+				a.Algorithm = webauthn.AlgEdDSA
+				return a
+			},
+			challenge: challenge,
+			mangle:    nil,
+		},
+		{
+			CaseBase:      test.NewCaseBase("wrong challenge", webauthn.ErrChallengeMismatch, true),
+			authenticator: func() *webauthntest.Authenticator { return webauthntest.NewAuthenticator(testRPID, testOrigin) },
+			challenge:     []byte("some other challenge"),
+			mangle:        nil,
+		},
+		{
+			CaseBase: test.NewCaseBase("wrong origin", webauthn.ErrOriginMismatch, true),
+			authenticator: func() *webauthntest.Authenticator {
+				return webauthntest.NewAuthenticator(testRPID, "https://brokedaear.com.evil.example")
+			},
+			challenge: challenge,
+			mangle:    nil,
+		},
+		{
+			CaseBase: test.NewCaseBase("wrong relying party", webauthn.ErrRelyingPartyMismatch, true),
+			authenticator: func() *webauthntest.Authenticator {
+				return webauthntest.NewAuthenticator("evil.example", testOrigin)
+			},
+			challenge: challenge,
+			mangle:    nil,
+		},
+		{
+			CaseBase: test.NewCaseBase("user not verified", webauthn.ErrUserNotVerified, true),
+			authenticator: func() *webauthntest.Authenticator {
+				a := webauthntest.NewAuthenticator(testRPID, testOrigin)
+				a.SkipUserVerification = true
+				return a
+			},
+			challenge: challenge,
+			mangle:    nil,
+		},
+		{
+			CaseBase: test.NewCaseBase("packed attestation", webauthn.ErrUnsupportedAttestation, true),
+			authenticator: func() *webauthntest.Authenticator {
+				a := webauthntest.NewAuthenticator(testRPID, testOrigin)
+				a.AttestationFormat = "packed"
+				return a
+			},
+			challenge: challenge,
+			mangle:    nil,
+		},
+		{
+			CaseBase:      test.NewCaseBase("truncated", webauthn.ErrMalformed, true),
+			authenticator: func() *webauthntest.Authenticator { return webauthntest.NewAuthenticator(testRPID, testOrigin) },
+			challenge:     challenge,
+			mangle:        func(b []byte) []byte { return b[:len(b)-10] },
+		},
+		{
+			CaseBase:      test.NewCaseBase("trailing bytes", webauthn.ErrMalformed, true),
+			authenticator: func() *webauthntest.Authenticator { return webauthntest.NewAuthenticator(testRPID, testOrigin) },
+			challenge:     challenge,
+			mangle:        func(b []byte) []byte { return append(b, 0) },
+		},
+		{
+			CaseBase:      test.NewCaseBase("deeply nested", webauthn.ErrMalformed, true),
+			authenticator: func() *webauthntest.Authenticator { return webauthntest.NewAuthenticator(testRPID, testOrigin) },
+			challenge:     challenge,
+			mangle:        func([]byte) []byte { return bytes.Repeat([]byte{0x81}, 1000) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				a := tt.authenticator()
+				id, clientData, attestation, err := a.Create(challenge, []byte("customer-1"))
+				assert.NoError(t, err)
+				if tt.mangle != nil {
+					attestation = tt.mangle(attestation)
+				}
+
+				cred, err := testRelyingParty().VerifyRegistration(tt.challenge, clientData, attestation)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					return
+				}
+				assert.NoError(t, err)
+				assert.True(t, bytes.Equal(cred.ID, id))
+				assert.Equal(t, cred.Algorithm, tt.Want.(int64))
+				assert.True(t, cred.BackupEligible)
+				alg, _, err := webauthn.ParsePublicKey(cred.PublicKey)
+				assert.NoError(t, err)
+				assert.Equal(t, alg, cred.Algorithm)
+			},
+		)
+	}
+}
+
+func TestRelyingParty_VerifyAssertion(t *testing.T) {
+	rp := testRelyingParty()
+
+	tests := []struct {
+		test.CaseBase
+		algorithm int64
+		// prepare changes the authenticator before it signs.
+		prepare func(a *webauthntest.Authenticator)
+		// stored is the signature counter stored for the credential.
+		stored uint32
+		// mangle changes the assertion the authenticator returns.
+		mangle func(a *webauthn.Assertion)
+	}{
+		{
+			CaseBase:  test.NewCaseBase("es256", uint32(0), false),
+			algorithm: webauthn.AlgES256,
+			prepare:   func(*webauthntest.Authenticator) {},
+			stored:    0,
+			mangle:    func(*webauthn.Assertion) {},
+		},
+		{
+			CaseBase:  test.NewCaseBase("eddsa", uint32(0), false),
+			algorithm: webauthn.AlgEdDSA,
+			prepare:   func(*webauthntest.Authenticator) {},
+			stored:    0,
+			mangle:    func(*webauthn.Assertion) {},
+		},
+		{
+			CaseBase:  test.NewCaseBase("counted", uint32(1), false),
+			algorithm: webauthn.AlgES256,
+			prepare:   func(a *webauthntest.Authenticator) { a.CountSignatures = true },
+			stored:    0,
+			mangle:    func(*webauthn.Assertion) {},
+		},
+		{
+			CaseBase:  test.NewCaseBase("counter went back", webauthn.ErrSignCountRegressed, true),
+			algorithm: webauthn.AlgES256,
+			prepare:   func(a *webauthntest.Authenticator) { a.SignCount = 4 },
+			stored:    5,
+			mangle:    func(*webauthn.Assertion) {},
+		},
+		{
+			CaseBase:  test.NewCaseBase("counter stopped", webauthn.ErrSignCountRegressed, true),
+			algorithm: webauthn.AlgES256,
+			prepare:   func(*webauthntest.Authenticator) {},
+			stored:    5,
+			mangle:    func(*webauthn.Assertion) {},
+		},
+		{
+			CaseBase:  test.NewCaseBase("user not verified", webauthn.ErrUserNotVerified, true),
+			algorithm: webauthn.AlgEdDSA,
+			prepare:   func(a *webauthntest.Authenticator) { a.SkipUserVerification = true },
+			stored:    0,
+			mangle:    func(*webauthn.Assertion) {},
+		},
+		{
+			CaseBase:  test.NewCaseBase("forged es256", webauthn.ErrInvalidSignature, true),
+			algorithm: webauthn.AlgES256,
+			prepare:   func(*webauthntest.Authenticator) {},
+			stored:    0,
+			mangle:    func(a *webauthn.Assertion) { a.AuthenticatorData[len(a.AuthenticatorData)-1]++ },
+		},
+		{
+			CaseBase:  test.NewCaseBase("forged eddsa", webauthn.ErrInvalidSignature, true),
+			algorithm: webauthn.AlgEdDSA,
+			prepare:   func(*webauthntest.Authenticator) {},
+			stored:    0,
+			mangle:    func(a *webauthn.Assertion) { a.Signature[0]++ },
+		},
+		{
+			CaseBase:  test.NewCaseBase("registration client data", webauthn.ErrMalformed, true),
+			algorithm: webauthn.AlgES256,
+			prepare:   func(*webauthntest.Authenticator) {},
+			stored:    0,
+			mangle: func(a *webauthn.Assertion) {
+				a.ClientDataJSON = bytes.Replace(a.ClientDataJSON, []byte("webauthn.get"), []byte("webauthn.create"), 1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(
+			tt.Name, func(t *testing.T) {
+				a := webauthntest.NewAuthenticator(testRPID, testOrigin)
+				a.Algorithm = tt.algorithm
+				challenge, err := webauthn.NewChallenge()
+				assert.NoError(t, err)
+				_, clientData, attestation, err := a.Create(challenge, []byte("customer-1"))
+				assert.NoError(t, err)
+				cred, err := rp.VerifyRegistration(challenge, clientData, attestation)
+				assert.NoError(t, err)
+
+				challenge, err = webauthn.NewChallenge()
+				assert.NoError(t, err)
+				tt.prepare(a)
+				assertion, err := a.Get(challenge, cred.ID)
+				assert.NoError(t, err)
+				tt.mangle(&assertion)
+
+				count, err := rp.VerifyAssertion(challenge, cred.PublicKey, tt.stored, assertion)
+				if tt.WantErr {
+					assert.Error(t, err, tt.Want.(error))
+					return
+				}
+				assert.NoError(t, err)
+				assert.Equal(t, count, tt.Want.(uint32))
+
+				// A challenge is bound to its ceremony.
+				other, err := webauthn.NewChallenge()
+				assert.NoError(t, err)
+				_, err = rp.VerifyAssertion(other, cred.PublicKey, tt.stored, assertion)
+				assert.Error(t, err, webauthn.ErrChallengeMismatch)
+			},
+		)
+	}
+}
@@ -0,0 +1,239 @@
+// SPDX-FileCopyrightText: 2025 BROKE DA EAR LLC <https://brokedaear.com>
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package webauthntest provides a software authenticator for tests. It runs
+// the browser and authenticator half of the registration and authentication
+// ceremonies, and returns what a browser would.
+package webauthntest
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"go.brokedaear.com/pkg/errors"
+	"go.brokedaear.com/pkg/webauthn"
+)
+
+// Authenticator is a software authenticator. The zero values of the
+// exported knobs make it behave like a passkey provider that verifies the
+// user, does not count signatures and sends no attestation.
+type Authenticator struct {
+	// RPID is the relying party ID credentials are scoped to.
+	RPID string
+	// Origin is the origin the browser reports.
+	Origin string
+
+	mu sync.Mutex
+	// Algorithm is the algorithm of credentials created by Create. It is
+	// webauthn.AlgES256 or webauthn.AlgEdDSA.
+	Algorithm int64
+	// SkipUserVerification leaves the user verified flag unset.
+	SkipUserVerification bool
+	// CountSignatures makes the authenticator count signatures.
+	CountSignatures bool
+	// AttestationFormat overrides the attestation format of Create.
+	AttestationFormat string
+	// SignCount overrides the signature counter of the next assertion when it
+	// is not zero.
+	SignCount uint32
+
+	credentials map[string]*credential
+}
+
+type credential struct {
+	alg        int64
+	key        any
+	userHandle []byte
+	count      uint32
+}
+
+// NewAuthenticator creates a new Authenticator for a relying party.
+func NewAuthenticator(rpID, origin string) *Authenticator {
+	return &Authenticator{
+		RPID:                 rpID,
+		Origin:               origin,
+		mu:                   sync.Mutex{},
+		Algorithm:            webauthn.AlgES256,
+		SkipUserVerification: false,
+		CountSignatures:      false,
+		AttestationFormat:    "",
+		SignCount:            0,
+		credentials:          make(map[string]*credential),
+	}
+}
+
+// Create creates a new credential for a user handle, and returns its ID with
+// the client data and attestation object of the registration.
+func (a *Authenticator) Create(challenge, userHandle []byte) (id, clientDataJSON, attestationObject []byte, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c := &credential{alg: a.Algorithm, key: nil, userHandle: userHandle, count: 0}
+	var coseKey []byte
+	switch a.Algorithm {
+	case webauthn.AlgES256:
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		pub, err := key.PublicKey.ECDH()
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		// The uncompressed point is 0x04 followed by the coordinates.
+		point := pub.Bytes()[1:]
+		c.key = key
+		coseKey = encode(cborMap{
+			{1, 2}, {3, webauthn.AlgES256}, {-1, 1}, {-2, point[:32]}, {-3, point[32:]},
+		})
+	case webauthn.AlgEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		c.key = priv
+		coseKey = encode(cborMap{{1, 1}, {3, webauthn.AlgEdDSA}, {-1, 6}, {-2, []byte(pub)}})
+	default:
+		return nil, nil, nil, errors.New("unsupported algorithm")
+	}
+
+	id = make([]byte, 16)
+	_, err = rand.Read(id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	a.credentials[string(id)] = c
+
+	credData := make([]byte, 16+2, 16+2+len(id)+len(coseKey))
+	binary.BigEndian.PutUint16(credData[16:], uint16(len(id)))
+	credData = slices.Concat(credData, id, coseKey)
+	authData := a.authenticatorData(0x40, c.count, credData)
+
+	format := a.AttestationFormat
+	if format == "" {
+		format = "none"
+	}
+	attestationObject = encode(cborMap{
+		{"fmt", format}, {"attStmt", cborMap{}}, {"authData", authData},
+	})
+	return id, a.clientData("webauthn.create", challenge), attestationObject, nil
+}
+
+// Get signs an assertion with a credential for a challenge.
+func (a *Authenticator) Get(challenge, credentialID []byte) (webauthn.Assertion, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	c, ok := a.credentials[string(credentialID)]
+	if !ok {
+		return webauthn.Assertion{}, errors.New("unknown credential")
+	}
+	if a.CountSignatures {
+		c.count++
+	}
+	count := c.count
+	if a.SignCount != 0 {
+		count = a.SignCount
+	}
+
+	clientData := a.clientData("webauthn.get", challenge)
+	authData := a.authenticatorData(0, count, nil)
+	hash := sha256.Sum256(clientData)
+	signed := slices.Concat(authData, hash[:])
+
+	var signature []byte
+	switch k := c.key.(type) {
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256(signed)
+		var err error
+		signature, err = ecdsa.SignASN1(rand.Reader, k, digest[:])
+		if err != nil {
+			return webauthn.Assertion{}, err
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, signed)
+	}
+
+	return webauthn.Assertion{
+		CredentialID:      credentialID,
+		ClientDataJSON:    clientData,
+		AuthenticatorData: authData,
+		Signature:         signature,
+		UserHandle:        c.userHandle,
+	}, nil
+}
+
+func (a *Authenticator) clientData(typ string, challenge []byte) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":        typ,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	return b
+}
+
+func (a *Authenticator) authenticatorData(flags byte, count uint32, credData []byte) []byte {
+	// User present, backup eligible and backed up, like a synced passkey.
+	flags |= 0x01 | 0x08 | 0x10
+	if !a.SkipUserVerification {
+		flags |= 0x04
+	}
+	rpIDHash := sha256.Sum256([]byte(a.RPID))
+	b := append(rpIDHash[:], flags)
+	b = binary.BigEndian.AppendUint32(b, count)
+	return append(b, credData...)
+}
+
+// cborMap is a CBOR map whose entries are encoded in order.
+type cborMap [][2]any
+
+// encode encodes integers, byte strings, text strings and maps as CBOR.
+func encode(v any) []byte {
+	switch v := v.(type) {
+	case int:
+		return encode(int64(v))
+	case int64:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case cborMap:
+		b := head(5, uint64(len(v)))
+		for _, kv := range v {
+			b = append(b, encode(kv[0])...)
+			b = append(b, encode(kv[1])...)
+		}
+		return b
+	default:
+		panic("webauthntest: cannot encode value")
+	}
+}
+
+func head(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+	}
+}